@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/containifyci/feller/pkg/providers"
+	"github.com/spf13/cobra"
+)
+
+var accessReportJSON bool
+
+// accessReportCmd represents the access-report command
+var accessReportCmd = &cobra.Command{
+	Use:   "access-report",
+	Short: "Report the access each configured key needs from its provider",
+	Long: `Map every configured key to the resource, permission and role its provider
+needs to read it, e.g. the Secret Manager secret accessor role on one secret or
+a Vault read policy on one path. Use the report to grant CI identities access
+to exactly the secrets they read.
+
+Only the config is inspected, so the report works in any environment and
+covers teller-only provider kinds too. Maps without key mappings read every
+key and are listed as *.
+
+Examples:
+  feller access-report
+  feller access-report --providers gsm --json`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		cfg, err := loadConfig()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		return writeAccessReport(cmd.OutOrStdout(), providers.AccessReport(cfg), accessReportJSON)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(accessReportCmd)
+	accessReportCmd.Flags().BoolVar(&accessReportJSON, "json", false, "Output as JSON")
+}
+
+// writeAccessReport prints access requirements as a table or JSON array
+func writeAccessReport(out io.Writer, report []providers.AccessRequirement, asJSON bool) error {
+	if asJSON {
+		if report == nil {
+			report = []providers.AccessRequirement{}
+		}
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			return fmt.Errorf("failed to encode access report: %w", err)
+		}
+		return nil
+	}
+
+	w := newTable(out)
+	fmt.Fprintln(w, "KEY\tPROVIDER\tKIND\tRESOURCE\tPERMISSION\tROLE")
+	for _, req := range report {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", req.Key, req.Provider, req.Kind, req.Resource, req.Permission, req.Role)
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to write access report: %w", err)
+	}
+	return nil
+}
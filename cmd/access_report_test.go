@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/containifyci/feller/pkg/providers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteAccessReport(t *testing.T) {
+	t.Parallel()
+	report := []providers.AccessRequirement{{
+		Key: "DB_PASSWORD", Provider: "gsm", Kind: "google_secretmanager", Resource: "projects/p/secrets/db",
+		Permission: "secretmanager.versions.access", Role: "roles/secretmanager.secretAccessor",
+	}}
+
+	t.Run("table", func(t *testing.T) {
+		t.Parallel()
+		var buf bytes.Buffer
+		require.NoError(t, writeAccessReport(&buf, report, false))
+		expected := "KEY          PROVIDER  KIND                  RESOURCE               PERMISSION                     ROLE\n" +
+			"DB_PASSWORD  gsm       google_secretmanager  projects/p/secrets/db  secretmanager.versions.access  roles/secretmanager.secretAccessor\n"
+		assert.Equal(t, expected, buf.String())
+	})
+
+	t.Run("empty json", func(t *testing.T) {
+		t.Parallel()
+		var buf bytes.Buffer
+		require.NoError(t, writeAccessReport(&buf, nil, true))
+		assert.Equal(t, "[]\n", buf.String())
+	})
+}
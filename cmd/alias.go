@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/containifyci/feller/pkg/config"
+	"github.com/containifyci/feller/pkg/logger"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// expandAlias replaces the command name in args with the arguments of the config alias of
+// that name, keeping global flags before it and appending the arguments after it. Built-in
+// commands take precedence over aliases, and the expansion is not applied recursively.
+// args are returned unchanged when they do not name an alias.
+func expandAlias(args []string) ([]string, error) {
+	flags, name, rest := splitCommandName(args)
+	if name == "" || isBuiltinCommand(name) {
+		return args, nil
+	}
+
+	// The config flag is not parsed yet, so read it from the arguments before the name
+	cfg, err := config.LoadConfig(aliasConfigPath(flags))
+	if err != nil {
+		// Without a config there are no aliases and cobra reports the unknown command
+		logger.Debug("Not expanding aliases: %v", err)
+		return args, nil
+	}
+	aliasArgs, ok, err := cfg.AliasArgs(name)
+	if !ok || err != nil {
+		return args, err //nolint:wrapcheck // already names the alias
+	}
+
+	logger.Debug("Expanding alias '%s' to: %v", name, aliasArgs)
+	expanded := slices.Concat(flags, aliasArgs, rest)
+	return expanded, nil
+}
+
+// splitCommandName splits args into the global flags before the command name, the name
+// and the arguments after it. name is empty when args contain no command name.
+func splitCommandName(args []string) (flags []string, name string, rest []string) {
+	fs := globalFlagSet()
+	if err := fs.Parse(args); err != nil || fs.NArg() == 0 || fs.ArgsLenAtDash() == 0 {
+		return args, "", nil
+	}
+	positional := fs.Args()
+	consumed := len(args) - len(positional)
+	return args[:consumed], positional[0], positional[1:]
+}
+
+// globalFlagSet returns a flag set with the persistent flags of rootCmd but separate
+// values, so args can be inspected before cobra parses them
+func globalFlagSet() *pflag.FlagSet {
+	fs := pflag.NewFlagSet("feller", pflag.ContinueOnError)
+	fs.SetInterspersed(false)
+	fs.ParseErrorsWhitelist.UnknownFlags = true
+	fs.Usage = func() {}
+	rootCmd.PersistentFlags().VisitAll(func(f *pflag.Flag) {
+		if f.Value.Type() == "bool" {
+			fs.BoolP(f.Name, f.Shorthand, false, f.Usage)
+		} else {
+			fs.StringP(f.Name, f.Shorthand, "", f.Usage)
+		}
+		fs.Lookup(f.Name).NoOptDefVal = f.NoOptDefVal
+	})
+	return fs
+}
+
+// aliasConfigPath returns the config the global flags select: the last --config flag, or
+// the config of the selected workspace
+func aliasConfigPath(flags []string) string {
+	fs := globalFlagSet()
+	if err := fs.Parse(flags); err != nil {
+		return ""
+	}
+	if path, _ := fs.GetString("config"); path != "" {
+		return path
+	}
+	flag, _ := fs.GetString("workspace")
+	name, err := selectedWorkspace(flag)
+	if err != nil || name == "" {
+		return ""
+	}
+	registry, _, err := loadWorkspaces()
+	if err != nil {
+		return ""
+	}
+	ws, err := registry.Lookup(name)
+	if err != nil {
+		return ""
+	}
+	return ws.Config
+}
+
+// isBuiltinCommand reports whether name is a command or command alias of rootCmd
+func isBuiltinCommand(name string) bool {
+	// Cobra adds its help and completion commands only while executing
+	if name == "help" || name == "completion" || strings.HasPrefix(name, "__") {
+		return true
+	}
+	for _, c := range rootCmd.Commands() {
+		if c.Name() == name || c.HasAlias(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// completeAliases completes the config aliases as commands of rootCmd
+func completeAliases(_ *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	cfg, err := config.LoadConfig(cfgFile)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	var names []string
+	for name, line := range cfg.Aliases {
+		if strings.HasPrefix(name, toComplete) && !isBuiltinCommand(name) {
+			names = append(names, name+"\t"+line)
+		}
+	}
+	slices.Sort(names)
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/containifyci/feller/pkg/config"
+	"github.com/containifyci/feller/pkg/fellertest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitCommandName(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name          string
+		args          []string
+		expectedFlags []string
+		expectedName  string
+		expectedRest  []string
+	}{
+		{name: "command only", args: []string{"deploy"}, expectedName: "deploy", expectedFlags: []string{}, expectedRest: []string{}},
+		{
+			name:          "global flags and arguments",
+			args:          []string{"--config", "x.yml", "--debug", "deploy", "--force", "a"},
+			expectedFlags: []string{"--config", "x.yml", "--debug"},
+			expectedName:  "deploy",
+			expectedRest:  []string{"--force", "a"},
+		},
+		{
+			name:          "flag values are not the command",
+			args:          []string{"-c", "deploy", "--providers=a,b", "--exclude-providers", "c", "ship"},
+			expectedFlags: []string{"-c", "deploy", "--providers=a,b", "--exclude-providers", "c"},
+			expectedName:  "ship",
+			expectedRest:  []string{},
+		},
+		{name: "optional flag value", args: []string{"--pprof", "ship"}, expectedFlags: []string{"--pprof"}, expectedName: "ship", expectedRest: []string{}},
+		{name: "no command", args: []string{"--debug"}, expectedFlags: []string{"--debug"}},
+		{name: "after dash", args: []string{"--", "ship"}, expectedFlags: []string{"--", "ship"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			flags, name, rest := splitCommandName(tt.args)
+			assert.Equal(t, tt.expectedFlags, flags)
+			assert.Equal(t, tt.expectedName, name)
+			assert.Equal(t, tt.expectedRest, rest)
+		})
+	}
+}
+
+func TestExpandAlias(t *testing.T) {
+	t.Parallel()
+	cfg := fellertest.NewConfig().Build()
+	cfg.Aliases = map[string]string{
+		"deploy": `run -- ./deploy.sh "prod env"`,
+		"run":    "export json",
+		"broken": "run 'x",
+	}
+	path := fellertest.WriteConfig(t, cfg)
+	empty := fellertest.WriteConfig(t, &config.TellerConfig{})
+
+	tests := []struct {
+		name        string
+		errContains string
+		args        []string
+		expected    []string
+	}{
+		{
+			name:     "alias",
+			args:     []string{"-c", path, "deploy", "--dry-run"},
+			expected: []string{"-c", path, "run", "--", "./deploy.sh", "prod env", "--dry-run"},
+		},
+		{name: "builtin wins", args: []string{"-c", path, "run", "--", "true"}, expected: []string{"-c", path, "run", "--", "true"}},
+		{name: "unknown command", args: []string{"-c", path, "nope"}, expected: []string{"-c", path, "nope"}},
+		{name: "no aliases", args: []string{"--config=" + empty, "deploy"}, expected: []string{"--config=" + empty, "deploy"}},
+		{name: "missing config", args: []string{"-c", "/nonexistent/.teller.yml", "deploy"}, expected: []string{"-c", "/nonexistent/.teller.yml", "deploy"}},
+		{name: "completion request", args: []string{"__complete", "-c", path, "deploy"}, expected: []string{"__complete", "-c", path, "deploy"}},
+		{name: "help", args: []string{"help", "deploy"}, expected: []string{"help", "deploy"}},
+		{name: "invalid alias", args: []string{"-c", path, "broken"}, errContains: "invalid alias broken"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			result, err := expandAlias(tt.args)
+
+			if tt.errContains != "" {
+				require.ErrorContains(t, err, tt.errContains)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
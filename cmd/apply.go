@@ -0,0 +1,365 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	"github.com/containifyci/feller/pkg/apply"
+	"github.com/containifyci/feller/pkg/config"
+	"github.com/containifyci/feller/pkg/logger"
+	"github.com/containifyci/feller/pkg/plan"
+	"github.com/containifyci/feller/pkg/providers"
+	"github.com/spf13/cobra"
+)
+
+var (
+	applyFile   string
+	applyDryRun bool
+)
+
+// applyCmd represents the apply command
+var applyCmd = &cobra.Command{
+	Use:   "apply --file FILE",
+	Short: "Apply a reviewed list of secret changes",
+	Long: `Apply the operations listed in an operations file, so secret changes can be
+reviewed like code and repeated in every environment. Every operation is
+checked and planned first; the combined plan is printed before anything is
+changed, as a diff (+ create, ~ update, - delete) or with --json as JSON, and
+--dry-run stops there.
+
+Operations:
+  put KEY       set KEY in a dotenv provider, from an environment variable
+                (from_env), a file (from_file) or a generated value
+                (generate: password, hex, base64 or uuid, with length)
+  delete KEY    remove KEY from a dotenv provider
+  sync REPO     upload the Google Secret Manager secrets to the GitHub
+                repository, like 'feller github-secret add'
+
+KEY is the key applications see; maps that rename keys are written under the
+source name. provider names the provider and map its path map, when it has
+several. Values are never written in the operations file itself.
+
+Deleting keys must be confirmed: --confirm names every provider keys are
+deleted from, or --force-destructive confirms any deletion. On a terminal,
+feller asks for the provider names instead.
+
+Changes to files are made together: when any operation fails, every file is
+put back as it was. Syncs run after the files are written, and secrets they
+already uploaded to GitHub cannot be rolled back.
+
+Example ops.yml:
+  operations:
+    - put: API_KEY
+      provider: local
+      from_env: NEW_API_KEY
+    - put: SESSION_SECRET
+      provider: local
+      generate: hex
+      length: 48
+    - delete: OLD_TOKEN
+      provider: local
+      map: app
+    - sync: owner/repo
+      dependabot: true
+
+Examples:
+  feller apply --file ops.yml --dry-run
+  feller apply --file ops.yml --dry-run --json
+  feller apply --file ops.yml --confirm local`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		if err := loadGitHubToken(cmd.InOrStdin()); err != nil {
+			return err
+		}
+		return runApply(cmd.InOrStdin(), cmd.OutOrStdout())
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(applyCmd)
+	applyCmd.Flags().StringVarP(&applyFile, "file", "f", "", "Operations file to apply")
+	applyCmd.Flags().BoolVar(&applyDryRun, "dry-run", false, "Print the plan without changing anything")
+	applyCmd.Flags().BoolVar(&planJSON, "json", false, "Print the plan of --dry-run as JSON")
+	addDestructiveFlags(applyCmd)
+	addTokenStdinFlag(applyCmd)
+	_ = applyCmd.MarkFlagRequired("file")
+}
+
+// applyStep is a planned operation
+type applyStep struct {
+	op     apply.Operation
+	path   string // dotenv file written by put and delete
+	action string // create, update, delete, absent or sync
+}
+
+// appliedFile is the planned content of a dotenv file, with the original kept for rollback
+type appliedFile struct {
+	path     string
+	original []byte
+	existed  bool
+	data     []byte
+}
+
+func runApply(in io.Reader, out io.Writer) error {
+	if planJSON && !applyDryRun {
+		return errors.New("--json requires --dry-run")
+	}
+	manifest, err := apply.Load(applyFile)
+	if err != nil {
+		return err //nolint:wrapcheck // names the operations file
+	}
+
+	unlock, err := lockUnlessDryRun("apply", applyDryRun)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	cfg, err := config.LoadConfig(cfgFile)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	steps, files, err := planApply(cfg, manifest)
+	if err != nil {
+		return err
+	}
+	if err := writePlan(out, applyPlan(steps)); err != nil {
+		return err
+	}
+	if applyDryRun {
+		if !planJSON {
+			fmt.Fprintln(out, "Dry run, nothing was changed")
+		}
+		return nil
+	}
+	targets, deletions := applyDeletions(steps)
+	if err := confirmDestructive(in, out, targets, deletions); err != nil {
+		return err
+	}
+
+	var syncs []apply.Operation
+	for _, step := range steps {
+		if step.action == apply.OpSync {
+			syncs = append(syncs, step.op)
+		}
+	}
+	if len(syncs) > 0 {
+		// Fail before any file is written when the syncs cannot run
+		if err := validateRequiredTools(); err != nil {
+			return err
+		}
+	}
+
+	if err := writeAppliedFiles(files); err != nil {
+		return err
+	}
+	for _, op := range syncs {
+		if err := syncApply(out, op); err != nil {
+			rollbackAppliedFiles(files)
+			return fmt.Errorf("sync %s failed, the files were rolled back: %w", op.Sync, err)
+		}
+	}
+	for _, file := range files {
+		recordDotenvFile(file.path)
+	}
+	fmt.Fprintf(out, "Applied %d operation(s) from %s\n", len(steps), applyFile)
+	return nil
+}
+
+// planApply checks every operation against cfg, resolves the values of puts and computes
+// the new content of the dotenv files, without changing anything
+func planApply(cfg *config.TellerConfig, manifest *apply.Manifest) ([]applyStep, []*appliedFile, error) {
+	var files []*appliedFile
+	byPath := make(map[string]*appliedFile)
+	steps := make([]applyStep, 0, len(manifest.Operations))
+
+	for i, op := range manifest.Operations {
+		if op.Type() == apply.OpSync {
+			steps = append(steps, applyStep{op: op, action: apply.OpSync})
+			continue
+		}
+
+		path, source, err := applyTarget(cfg, op)
+		if err != nil {
+			return nil, nil, fmt.Errorf("operation %d: %w", i+1, err)
+		}
+		file, ok := byPath[path]
+		if !ok {
+			file = &appliedFile{path: path}
+			// #nosec G304 - Dotenv paths come from the user's config
+			data, err := os.ReadFile(path)
+			switch {
+			case err == nil:
+				file.original, file.existed = data, true
+			case !errors.Is(err, os.ErrNotExist):
+				return nil, nil, fmt.Errorf("failed to read %s: %w", path, err)
+			}
+			file.data = file.original
+			byPath[path] = file
+			files = append(files, file)
+		}
+
+		step := applyStep{op: op, path: path}
+		if step.action, err = applyOperation(file, op, source); err != nil {
+			return nil, nil, fmt.Errorf("operation %d: %w", i+1, err)
+		}
+		steps = append(steps, step)
+	}
+	return steps, files, nil
+}
+
+// applyTarget returns the dotenv file written by a put or delete operation and the name the
+// key is written under
+func applyTarget(cfg *config.TellerConfig, op apply.Operation) (string, string, error) {
+	provider, ok := cfg.Providers[op.Provider]
+	if !ok {
+		return "", "", fmt.Errorf("unknown provider %q", op.Provider)
+	}
+	if provider.Kind != providers.KindDotenv {
+		return "", "", fmt.Errorf("provider %s is of kind %s, apply can only write %s providers", op.Provider, provider.Kind, providers.KindDotenv)
+	}
+	pathMap, err := selectMap(op.Provider, provider, op.Map, "map")
+	if err != nil {
+		return "", "", err
+	}
+	restored, skipped := providers.RestoreKeys(pathMap, providers.SecretMap{op.Key(): ""})
+	if len(skipped) > 0 {
+		return "", "", fmt.Errorf("map %s of provider %s does not read %s", pathMap.ID, op.Provider, op.Key())
+	}
+	for source := range restored {
+		return config.LocalPath(pathMap.Path), source, nil
+	}
+	return "", "", fmt.Errorf("map %s of provider %s does not read %s", pathMap.ID, op.Provider, op.Key())
+}
+
+// applyOperation updates the planned content of file with a put or delete of the key source
+// and returns the action
+func applyOperation(file *appliedFile, op apply.Operation, source string) (string, error) {
+	without, removed, err := providers.RemoveDotenvKeys(file.data, []string{source})
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", file.path, err)
+	}
+	if op.Type() == apply.OpDelete {
+		if len(removed) == 0 {
+			return "absent", nil
+		}
+		file.data = without
+		return apply.OpDelete, nil
+	}
+
+	value, err := op.Value()
+	if err != nil {
+		return "", fmt.Errorf("put %s: %w", op.Put, err)
+	}
+	if file.data, err = providers.UpdateDotenv(file.data, providers.SecretMap{source: value}); err != nil {
+		return "", fmt.Errorf("put %s: %w", op.Put, err)
+	}
+	if len(removed) == 0 {
+		return "create", nil
+	}
+	return "update", nil
+}
+
+// applyPlan returns the plan of the operations, in order. Syncs upload every secret, which
+// are only known when the sync runs, so they are planned as a change of all keys.
+func applyPlan(steps []applyStep) *plan.Plan {
+	p := plan.New("apply")
+	for _, step := range steps {
+		op := step.op
+		switch step.action {
+		case apply.OpSync:
+			scopes := []string{"repository"}
+			if op.Dependabot {
+				scopes = append(scopes, "Dependabot")
+			}
+			for _, scope := range scopes {
+				p.Add(plan.Change{Action: plan.ActionUpdate, Target: githubTarget(op.Sync), Scope: scope, Key: plan.AllKeys, Reason: "Google Secret Manager secrets"})
+			}
+		case "absent":
+			p.Add(plan.Change{Action: plan.ActionNoop, Target: "dotenv:" + step.path, Scope: op.Provider, Key: op.Delete, Reason: "not set"})
+		case apply.OpDelete:
+			p.Add(plan.Change{Action: plan.ActionDelete, Target: "dotenv:" + step.path, Scope: op.Provider, Key: op.Delete})
+		default:
+			p.Add(plan.Change{Action: step.action, Target: "dotenv:" + step.path, Scope: op.Provider, Key: op.Put, Reason: "from " + op.Source()})
+		}
+	}
+	return p
+}
+
+// applyDeletions returns the providers the steps delete keys from, in order, and the number
+// of keys they delete
+func applyDeletions(steps []applyStep) ([]string, int) {
+	var targets []string
+	count := 0
+	for _, step := range steps {
+		if step.action != apply.OpDelete {
+			continue
+		}
+		count++
+		if !slices.Contains(targets, step.op.Provider) {
+			targets = append(targets, step.op.Provider)
+		}
+	}
+	return targets, count
+}
+
+// writeAppliedFiles writes the planned content of every changed file, putting the files
+// already written back when one fails
+func writeAppliedFiles(files []*appliedFile) error {
+	for i, file := range files {
+		if string(file.data) == string(file.original) {
+			continue
+		}
+		if err := writeFileAtomic(file.path, file.data, 0o600); err != nil {
+			rollbackAppliedFiles(files[:i])
+			return fmt.Errorf("%w, the files were rolled back", err)
+		}
+		logger.Verbose("Wrote %s", file.path)
+	}
+	return nil
+}
+
+// rollbackAppliedFiles puts files back as they were before apply
+func rollbackAppliedFiles(files []*appliedFile) {
+	for _, file := range files {
+		if string(file.data) == string(file.original) {
+			continue
+		}
+		var err error
+		if file.existed {
+			err = writeFileAtomic(file.path, file.original, 0o600)
+		} else {
+			err = os.Remove(file.path)
+		}
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			logger.Error("Failed to roll back %s: %v", file.path, err)
+		}
+	}
+}
+
+// syncApply uploads the Google Secret Manager secrets to the repository of a sync
+// operation, like 'feller github-secret add'
+func syncApply(out io.Writer, op apply.Operation) error {
+	target := githubRepository{Repo: op.Sync, Dependabot: op.Dependabot}
+	if err := loadUploads(); err != nil {
+		return err
+	}
+	defer saveUploads()
+	secrets, err := getSecretsFromTeller()
+	if err != nil {
+		return fmt.Errorf("failed to get secrets from teller: %w", err)
+	}
+	existing, err := getExistingGitHubSecrets(target)
+	if err != nil {
+		return fmt.Errorf("failed to get existing GitHub secrets: %w", err)
+	}
+	stats, err := setGitHubSecrets(target, secrets, existing)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Synced %s: %d created, %d updated, %d skipped, %d unchanged\n", op.Sync, stats.Created, stats.Updated, stats.Skipped, stats.Unchanged)
+	return nil
+}
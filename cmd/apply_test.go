@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/containifyci/feller/pkg/config"
+	"github.com/containifyci/feller/pkg/fellertest"
+	"github.com/containifyci/feller/pkg/plan"
+	"github.com/containifyci/feller/pkg/providers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//nolint:paralleltest // modifies environment variables and global flag variables
+func TestApply(t *testing.T) {
+	t.Setenv("NEW_API_KEY", "new-key")
+	originalTerminal := stdinIsTerminal
+	t.Cleanup(func() {
+		cfgFile, applyFile, applyDryRun, planJSON, confirmTargets = "", "", false, false, nil
+		stdinIsTerminal = originalTerminal
+	})
+	stdinIsTerminal = func() bool { return false }
+
+	dir := t.TempDir()
+	appPath := filepath.Join(dir, "app.env")
+	require.NoError(t, os.WriteFile(appPath, []byte("# app\napi_key=old\nOLD_TOKEN=stale\n"), 0o600))
+	workerPath := filepath.Join(dir, "worker.env")
+	local := config.Provider{Kind: providers.KindDotenv, Maps: []config.PathMap{
+		{ID: "app", Path: appPath, Keys: map[string]string{"api_key": "API_KEY", "OLD_TOKEN": "OLD_TOKEN"}},
+		{ID: "worker", Path: workerPath},
+	}}
+	cfgFile = fellertest.WriteConfig(t, fellertest.NewConfig().Provider("local", local).Provider("gsm", fellertest.FakeGSM(t, map[string]string{"A": "1"})).Build())
+
+	applyFile = filepath.Join(dir, "ops.yml")
+	require.NoError(t, os.WriteFile(applyFile, []byte(`operations:
+  - put: API_KEY
+    provider: local
+    map: app
+    from_env: NEW_API_KEY
+  - delete: OLD_TOKEN
+    provider: local
+    map: app
+  - put: SESSION_SECRET
+    provider: local
+    map: worker
+    generate: hex
+    length: 8
+  - delete: MISSING
+    provider: local
+    map: worker
+`), 0o600))
+
+	applyDryRun = true
+	var out bytes.Buffer
+	require.NoError(t, runApply(strings.NewReader(""), &out))
+	assert.Equal(t, "dotenv:"+appPath+" (local)\n"+
+		"  ~ API_KEY    from environment variable NEW_API_KEY\n"+
+		"  - OLD_TOKEN\n"+
+		"dotenv:"+workerPath+" (local)\n"+
+		"  + SESSION_SECRET  from generated hex\n"+
+		"    MISSING         not set\n"+
+		"Plan: 1 to create, 1 to update, 1 to delete.\n"+
+		"Dry run, nothing was changed\n", out.String())
+	assert.NoFileExists(t, workerPath)
+
+	planJSON = true
+	out.Reset()
+	require.NoError(t, runApply(strings.NewReader(""), &out))
+	var p plan.Plan
+	require.NoError(t, json.Unmarshal(out.Bytes(), &p))
+	assert.Equal(t, "apply", p.Command)
+	require.Len(t, p.Changes, 4)
+	assert.Equal(t, plan.Change{Action: plan.ActionUpdate, Target: "dotenv:" + appPath, Scope: "local", Key: "API_KEY", Reason: "from environment variable NEW_API_KEY"}, p.Changes[0])
+	assert.Equal(t, plan.ActionNoop, p.Changes[3].Action)
+
+	applyDryRun = false
+	assert.EqualError(t, runApply(strings.NewReader(""), &out), "--json requires --dry-run")
+	planJSON = false
+	assert.NoFileExists(t, workerPath)
+
+	// Deleting OLD_TOKEN must name the provider
+	assert.EqualError(t, runApply(strings.NewReader(""), &out), "deleting 1 secret(s) from local needs --confirm local or --force-destructive")
+	assert.NoFileExists(t, workerPath)
+
+	confirmTargets = []string{"local"}
+	out.Reset()
+	require.NoError(t, runApply(strings.NewReader(""), &out))
+	assert.Contains(t, out.String(), "Applied 4 operation(s) from "+applyFile+"\n")
+	data, err := os.ReadFile(appPath)
+	require.NoError(t, err)
+	assert.Equal(t, "# app\napi_key=\"new-key\"\n", string(data))
+	data, err = os.ReadFile(workerPath)
+	require.NoError(t, err)
+	assert.Regexp(t, `^SESSION_SECRET="[0-9a-f]{16}"\n$`, string(data))
+}
+
+//nolint:paralleltest // modifies global flag variables
+func TestApplyPlanErrorsChangeNothing(t *testing.T) {
+	t.Cleanup(func() { cfgFile, applyFile = "", "" })
+
+	dir := t.TempDir()
+	envPath := filepath.Join(dir, ".env")
+	require.NoError(t, os.WriteFile(envPath, []byte("A=1\n"), 0o600))
+	local := config.Provider{Kind: providers.KindDotenv, Maps: []config.PathMap{{ID: "app", Path: envPath}}}
+	cfgFile = fellertest.WriteConfig(t, fellertest.NewConfig().Provider("local", local).Provider("gsm", fellertest.FakeGSM(t, map[string]string{"A": "1"})).Build())
+
+	tests := []struct {
+		name        string
+		ops         string
+		errContains string
+	}{
+		{name: "unset env", ops: "  - delete: A\n    provider: local\n  - put: B\n    provider: local\n    from_env: APPLY_TEST_UNSET\n", errContains: "operation 2: put B: environment variable APPLY_TEST_UNSET is not set"},
+		{name: "unknown provider", ops: "  - delete: A\n    provider: nope\n", errContains: `operation 1: unknown provider "nope"`},
+		{name: "not writable", ops: "  - delete: A\n    provider: gsm\n", errContains: "apply can only write dotenv providers"},
+		{name: "unknown map", ops: "  - delete: A\n    provider: local\n    map: nope\n", errContains: `provider local has no map "nope"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			applyFile = filepath.Join(t.TempDir(), "ops.yml")
+			require.NoError(t, os.WriteFile(applyFile, []byte("operations:\n"+tt.ops), 0o600))
+
+			err := runApply(strings.NewReader(""), &bytes.Buffer{})
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errContains)
+			data, err := os.ReadFile(envPath)
+			require.NoError(t, err)
+			assert.Equal(t, "A=1\n", string(data))
+		})
+	}
+}
+
+func TestRollbackAppliedFiles(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "existing.env")
+	created := filepath.Join(dir, "created.env")
+	require.NoError(t, os.WriteFile(existing, []byte("A=new\n"), 0o600))
+	require.NoError(t, os.WriteFile(created, []byte("B=new\n"), 0o600))
+
+	rollbackAppliedFiles([]*appliedFile{
+		{path: existing, original: []byte("A=old\n"), existed: true, data: []byte("A=new\n")},
+		{path: created, data: []byte("B=new\n")},
+	})
+	data, err := os.ReadFile(existing)
+	require.NoError(t, err)
+	assert.Equal(t, "A=old\n", string(data))
+	assert.NoFileExists(t, created)
+}
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/containifyci/feller/pkg/providers"
+)
+
+// secretRefPattern matches {{secret.KEY}} placeholders in command arguments
+var secretRefPattern = regexp.MustCompile(`\{\{\s*secret\.([^{}\s]+)\s*\}\}`)
+
+// hasSecretRefs reports whether any argument contains a {{secret.KEY}} placeholder
+func hasSecretRefs(args []string) bool {
+	return slices.ContainsFunc(args, secretRefPattern.MatchString)
+}
+
+// checkSecretRefs rejects placeholders where their value would be recorded or run as shell code
+func checkSecretRefs(args []string, specs []processSpec) error {
+	if !hasSecretRefs(args) {
+		return nil
+	}
+	switch {
+	case detach:
+		return errors.New("{{secret.KEY}} placeholders are not supported with --detach, which records the command line")
+	case specs != nil:
+		return errors.New("{{secret.KEY}} placeholders are not supported with --parallel; reference $KEY in the commands instead")
+	case shell && len(args) == 1:
+		return errors.New("{{secret.KEY}} placeholders in a shell script would run the secret as shell code; reference $KEY instead")
+	}
+	return nil
+}
+
+// checkSecretRefKeys fails if a placeholder references a key that was not collected, so the
+// command and its hooks never start with a half-expanded command line
+func checkSecretRefKeys(args []string, secrets providers.SecretMap) error {
+	var unknown []string
+	for _, arg := range args {
+		for _, match := range secretRefPattern.FindAllStringSubmatch(arg, -1) {
+			if _, ok := secrets[match[1]]; !ok && !slices.Contains(unknown, match[1]) {
+				unknown = append(unknown, match[1])
+			}
+		}
+	}
+	if len(unknown) > 0 {
+		return fmt.Errorf("command references unknown secrets: %s", strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// expandSecretRefs returns a copy of args with every {{secret.KEY}} placeholder replaced by the
+// value of KEY. The result must never be logged.
+func expandSecretRefs(args []string, secrets providers.SecretMap) ([]string, error) {
+	if err := checkSecretRefKeys(args, secrets); err != nil {
+		return nil, err
+	}
+	expanded := make([]string, len(args))
+	for i, arg := range args {
+		expanded[i] = secretRefPattern.ReplaceAllStringFunc(arg, func(ref string) string {
+			return secrets[secretRefPattern.FindStringSubmatch(ref)[1]]
+		})
+	}
+	return expanded, nil
+}
@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/containifyci/feller/pkg/providers"
+)
+
+func TestExpandSecretRefs(t *testing.T) {
+	t.Parallel()
+	secrets := providers.SecretMap{"API_TOKEN": "s3cr3t", "USER": "bob", "EMPTY": ""}
+	tests := []struct {
+		name        string
+		errContains string
+		args        []string
+		expected    []string
+		wantErr     bool
+	}{
+		{
+			name:     "no placeholders",
+			args:     []string{"curl", "https://example.com"},
+			expected: []string{"curl", "https://example.com"},
+		},
+		{
+			name:     "placeholder inside an argument",
+			args:     []string{"curl", "-H", "Authorization: Bearer {{secret.API_TOKEN}}"},
+			expected: []string{"curl", "-H", "Authorization: Bearer s3cr3t"},
+		},
+		{
+			name:     "several placeholders and spaces",
+			args:     []string{"{{ secret.USER }}:{{secret.API_TOKEN}}", "x{{secret.EMPTY}}y"},
+			expected: []string{"bob:s3cr3t", "xy"},
+		},
+		{
+			name:     "other templates are kept",
+			args:     []string{"{{.Name}}", "{{ env.HOME }}", "{{secret.}}", "${API_TOKEN}"},
+			expected: []string{"{{.Name}}", "{{ env.HOME }}", "{{secret.}}", "${API_TOKEN}"},
+		},
+		{
+			name:        "unknown secrets",
+			args:        []string{"{{secret.NOPE}}", "{{secret.API_TOKEN}}{{secret.NOPE}}", "{{secret.OTHER}}"},
+			wantErr:     true,
+			errContains: "command references unknown secrets: NOPE, OTHER",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := expandSecretRefs(tt.args, secrets)
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Errorf("expandSecretRefs() error = %v, expected to contain %q", err, tt.errContains)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expandSecretRefs() unexpected error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expandSecretRefs() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+//nolint:paralleltest // sets global flag variables
+func TestCheckSecretRefs(t *testing.T) {
+	refs := []string{"deploy", "--token={{secret.TOKEN}}"}
+	tests := []struct {
+		name        string
+		errContains string
+		args        []string
+		specs       []processSpec
+		detach      bool
+		shell       bool
+	}{
+		{name: "direct command", args: refs},
+		{name: "quoted shell arguments", args: refs, shell: true},
+		{name: "shell script without placeholders", args: []string{"echo $TOKEN"}, shell: true},
+		{name: "detach without placeholders", args: []string{"deploy"}, detach: true},
+		{
+			name:        "shell script",
+			args:        []string{"deploy --token={{secret.TOKEN}}"},
+			shell:       true,
+			errContains: "reference $KEY instead",
+		},
+		{name: "detach", args: refs, detach: true, errContains: "not supported with --detach"},
+		{
+			name:        "parallel",
+			args:        refs,
+			specs:       []processSpec{{Name: "deploy", Command: "deploy"}},
+			errContains: "not supported with --parallel",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldDetach, oldShell := detach, shell
+			t.Cleanup(func() { detach, shell = oldDetach, oldShell })
+			detach, shell = tt.detach, tt.shell
+
+			err := checkSecretRefs(tt.args, tt.specs)
+			if tt.errContains == "" {
+				if err != nil {
+					t.Errorf("checkSecretRefs() unexpected error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+				t.Errorf("checkSecretRefs() error = %v, expected to contain %q", err, tt.errContains)
+			}
+		})
+	}
+}
+
+func TestExecuteCommandExpandsSecretRefs(t *testing.T) {
+	t.Parallel()
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	secrets := providers.SecretMap{"API_TOKEN": "it's s3cr3t"}
+
+	t.Run("direct", func(t *testing.T) {
+		t.Parallel()
+		out := filepath.Join(t.TempDir(), "out")
+		args := []string{"/bin/sh", "-c", `printf %s "$1" > "$2"`, "sh", "Bearer {{secret.API_TOKEN}}", out}
+		if err := executeDirectCommand(args, nil, secrets); err != nil {
+			t.Fatalf("executeDirectCommand() unexpected error = %v", err)
+		}
+		if got, _ := os.ReadFile(out); string(got) != "Bearer it's s3cr3t" {
+			t.Errorf("command received %q", got)
+		}
+	})
+
+	t.Run("shell arguments are quoted after expansion", func(t *testing.T) {
+		t.Parallel()
+		out := filepath.Join(t.TempDir(), "out")
+		args := []string{"/bin/sh", "-c", `printf %s "$1" > "$2"`, "sh", "{{secret.API_TOKEN}}", out}
+		if err := executeShellCommand(args, nil, secrets); err != nil {
+			t.Fatalf("executeShellCommand() unexpected error = %v", err)
+		}
+		if got, _ := os.ReadFile(out); string(got) != "it's s3cr3t" {
+			t.Errorf("command received %q", got)
+		}
+	})
+
+	t.Run("unknown secret", func(t *testing.T) {
+		t.Parallel()
+		err := executeDirectCommand([]string{"/bin/true", "{{secret.NOPE}}"}, nil, secrets)
+		if err == nil || !strings.Contains(err.Error(), "unknown secrets: NOPE") {
+			t.Errorf("executeDirectCommand() error = %v", err)
+		}
+	})
+}
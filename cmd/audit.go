@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	"os/user"
+	"time"
+
+	"github.com/containifyci/feller/pkg/config"
+	"github.com/containifyci/feller/pkg/logger"
+	"github.com/containifyci/feller/pkg/state"
+)
+
+// recordAudit appends entry, stamped with the time and the current user, to the audit log in
+// the state directory of the config. The change was already made, so failures are reported
+// without failing the command.
+func recordAudit(entry state.AuditEntry) {
+	entry.Time = time.Now().UTC()
+	if current, err := user.Current(); err == nil {
+		entry.User = current.Username
+	}
+	configPath, err := config.ResolveConfigPath(cfgFile)
+	if err == nil {
+		err = state.AppendAudit(state.Dir(configPath), entry)
+	}
+	if err != nil {
+		logger.Error("Failed to record the %s of %d key(s) in the audit log: %v", entry.Command, len(entry.Keys), err)
+	}
+}
@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/containifyci/feller/pkg/config"
+	"github.com/containifyci/feller/pkg/logger"
+)
+
+// cloudflareTokenEnv holds the token feller writes the secrets of cloudflare targets with
+const cloudflareTokenEnv = "CLOUDFLARE_API_TOKEN"
+
+// cloudflareTimeout bounds each request to the Cloudflare API
+const cloudflareTimeout = 30 * time.Second
+
+// cloudflareAPI is the base URL of the Cloudflare API, replaced in tests
+var cloudflareAPI = "https://api.cloudflare.com/client/v4"
+
+// cloudflareTarget is the plan target of a Cloudflare Worker
+func cloudflareTarget(script string) string {
+	return "cloudflare:" + script
+}
+
+// cloudflareSecretsPath is the path of the secrets of the Worker of target
+func cloudflareSecretsPath(target config.Target) string {
+	return "/accounts/" + url.PathEscape(target.Account) + "/workers/scripts/" + url.PathEscape(target.Script) + "/secrets"
+}
+
+// listCloudflareSecrets returns the names of the secrets of the Worker of target. Cloudflare
+// never returns secret values.
+func listCloudflareSecrets(target config.Target) (map[string]bool, error) {
+	var secrets []struct {
+		Name string `json:"name"`
+	}
+	if err := cloudflareRequest(http.MethodGet, cloudflareSecretsPath(target), nil, &secrets); err != nil {
+		return nil, err
+	}
+	existing := make(map[string]bool, len(secrets))
+	for _, secret := range secrets {
+		existing[secret.Name] = true
+	}
+	logger.Debug("Cloudflare Worker %s has %d secret(s)", target.Script, len(existing))
+	return existing, nil
+}
+
+// setCloudflareSecret creates or updates the secret key of the Worker of target, which
+// deploys a new version of the Worker
+func setCloudflareSecret(target config.Target, key, value string) error {
+	logger.Debug("Setting Cloudflare secret %s of Worker %s", key, target.Script)
+	body := map[string]string{"name": key, "text": value, "type": "secret_text"}
+	if err := cloudflareRequest(http.MethodPut, cloudflareSecretsPath(target), body, nil); err != nil {
+		return fmt.Errorf("failed to set Cloudflare secret %s: %w", key, err)
+	}
+	return nil
+}
+
+// deleteCloudflareSecret removes the secret key from the Worker of target
+func deleteCloudflareSecret(target config.Target, key string) error {
+	logger.Debug("Deleting Cloudflare secret %s of Worker %s", key, target.Script)
+	path := cloudflareSecretsPath(target) + "/" + url.PathEscape(key)
+	if err := cloudflareRequest(http.MethodDelete, path, nil, nil); err != nil {
+		return fmt.Errorf("failed to delete Cloudflare secret %s: %w", key, err)
+	}
+	return nil
+}
+
+// cloudflareRequest sends a request to the Cloudflare API, decoding the result of the
+// response into out when it is not nil
+func cloudflareRequest(method, path string, body, out any) error {
+	token := os.Getenv(cloudflareTokenEnv)
+	if token == "" {
+		return fmt.Errorf("%s is not set, create a token at https://dash.cloudflare.com/profile/api-tokens", cloudflareTokenEnv)
+	}
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode Cloudflare request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cloudflareTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, method, cloudflareAPI+path, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create Cloudflare request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("cloudflare request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Every response, failed or not, is an envelope holding the errors and the result
+	var envelope struct {
+		Success bool `json:"success"`
+		Errors  []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+		Result json.RawMessage `json:"result"`
+	}
+	decodeErr := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&envelope)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 || (decodeErr == nil && !envelope.Success) {
+		messages := make([]string, 0, len(envelope.Errors))
+		for _, e := range envelope.Errors {
+			messages = append(messages, e.Message)
+		}
+		if len(messages) > 0 {
+			return fmt.Errorf("cloudflare returned status %d: %s", resp.StatusCode, strings.Join(messages, "; "))
+		}
+		return fmt.Errorf("cloudflare returned status %d", resp.StatusCode)
+	}
+	if out == nil {
+		return nil
+	}
+	if decodeErr != nil {
+		return fmt.Errorf("failed to decode Cloudflare response: %w", decodeErr)
+	}
+	if err := json.Unmarshal(envelope.Result, out); err != nil {
+		return fmt.Errorf("failed to decode Cloudflare response: %w", err)
+	}
+	return nil
+}
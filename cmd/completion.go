@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/containifyci/feller/pkg/config"
+	"github.com/containifyci/feller/pkg/providers"
+	"github.com/spf13/cobra"
+)
+
+// Completion functions never resolve secret values; they only read the config and the key
+// names of dotenv files. Flags such as --config and --providers are already parsed when
+// they run, so completions follow the selected config and providers.
+
+// completeKeyList completes comma-separated output keys, e.g. for --only and --exclude
+func completeKeyList(_ *cobra.Command, _ []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	cfg, err := loadConfig()
+	if err != nil {
+		cobra.CompDebugln("failed to load config: "+err.Error(), true)
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return completeList(providers.OutputKeys(cfg), toComplete)
+}
+
+// completeProviderList completes comma-separated provider names for --providers and
+// --exclude-providers. The unfiltered config is used so every provider can be offered.
+func completeProviderList(_ *cobra.Command, _ []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	cfg, err := config.LoadConfig(cfgFile)
+	if err != nil {
+		cobra.CompDebugln("failed to load config: "+err.Error(), true)
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	names := make([]string, 0, len(cfg.Providers))
+	for name := range cfg.Providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return completeList(names, toComplete)
+}
+
+// completeTagList completes comma-separated tags for --tags. The unfiltered config is used
+// so every tag can be offered.
+func completeTagList(_ *cobra.Command, _ []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	cfg, err := config.LoadConfig(cfgFile)
+	if err != nil {
+		cobra.CompDebugln("failed to load config: "+err.Error(), true)
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return completeList(cfg.Tags(), toComplete)
+}
+
+// completeList completes the last element of a comma-separated list, offering each
+// candidate not already in the list prefixed with the elements before it
+func completeList(candidates []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	var done []string
+	current := toComplete
+	if i := strings.LastIndex(toComplete, ","); i >= 0 {
+		done = strings.Split(toComplete[:i], ",")
+		current = toComplete[i+1:]
+	}
+	prefix := strings.TrimSuffix(toComplete, current)
+
+	var completions []string
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, current) && !slices.Contains(done, candidate) {
+			completions = append(completions, prefix+candidate)
+		}
+	}
+	// No space after a completion so another element can be appended with a comma
+	return completions, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveNoSpace
+}
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/containifyci/feller/pkg/config"
+	"github.com/containifyci/feller/pkg/fellertest"
+	"github.com/containifyci/feller/pkg/providers"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompleteList(t *testing.T) {
+	t.Parallel()
+	candidates := []string{"API_KEY", "API_TOKEN", "DATABASE_URL"}
+
+	tests := []struct {
+		name       string
+		toComplete string
+		expected   []string
+	}{
+		{name: "empty", toComplete: "", expected: candidates},
+		{name: "prefix", toComplete: "API", expected: []string{"API_KEY", "API_TOKEN"}},
+		{name: "second element", toComplete: "API_KEY,", expected: []string{"API_KEY,API_TOKEN", "API_KEY,DATABASE_URL"}},
+		{name: "second element with prefix", toComplete: "DATABASE_URL,API_T", expected: []string{"DATABASE_URL,API_TOKEN"}},
+		{name: "no match", toComplete: "X", expected: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			result, directive := completeList(candidates, tt.toComplete)
+			assert.Equal(t, tt.expected, result)
+			assert.Equal(t, cobra.ShellCompDirectiveNoFileComp|cobra.ShellCompDirectiveNoSpace, directive)
+		})
+	}
+}
+
+//nolint:paralleltest // modifies global flag variables
+func TestCompleteFromConfig(t *testing.T) {
+	cfg := fellertest.NewConfig().
+		Provider("gsm", config.Provider{
+			Kind: providers.KindGoogleSecretManager,
+			Maps: []config.PathMap{{ID: "ci", Keys: map[string]string{"GSM_API": "API_KEY", "GSM_DB": "DATABASE_URL"}}},
+		}).
+		Provider("local", fellertest.FakeDotenv(t, map[string]string{"LOCAL_ONLY": "x"})).
+		Build()
+	path := fellertest.WriteConfig(t, cfg)
+
+	originalCfg, originalInclude := cfgFile, includeProviders
+	t.Cleanup(func() { cfgFile, includeProviders = originalCfg, originalInclude })
+	cfgFile = path
+
+	keys, directive := completeKeyList(exportCmd, nil, "DATABASE_URL,")
+	assert.Equal(t, []string{"DATABASE_URL,API_KEY", "DATABASE_URL,LOCAL_ONLY"}, keys)
+	assert.Equal(t, cobra.ShellCompDirectiveNoFileComp|cobra.ShellCompDirectiveNoSpace, directive)
+
+	// Provider names are offered even when --providers already narrows the config
+	includeProviders = []string{"gsm"}
+	names, _ := completeProviderList(rootCmd, nil, "")
+	assert.Equal(t, []string{"gsm", "local"}, names)
+
+	keys, _ = completeKeyList(exportCmd, nil, "")
+	assert.Equal(t, []string{"API_KEY", "DATABASE_URL"}, keys)
+
+	cfgFile = filepath.Join(t.TempDir(), "missing.yml")
+	keys, directive = completeKeyList(exportCmd, nil, "")
+	assert.Nil(t, keys)
+	assert.Equal(t, cobra.ShellCompDirectiveNoFileComp, directive)
+}
@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/containifyci/feller/pkg/config"
+	"github.com/containifyci/feller/pkg/logger"
+	"github.com/containifyci/feller/pkg/lsp"
+	"github.com/spf13/cobra"
+)
+
+var fmtCheck bool
+
+// configCmd represents the config command group
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Work with teller configuration files",
+	Long: `Work with teller configuration files.
+
+Available subcommands:
+  fmt        Format configuration files canonically
+  serve-lsp  Run a language server for .teller.yml files
+
+Examples:
+  feller config fmt --check
+  feller config serve-lsp`,
+}
+
+// configFmtCmd represents the config fmt command
+var configFmtCmd = &cobra.Command{
+	Use:   "fmt [file...]",
+	Short: "Format configuration files canonically",
+	Long: `Format teller configuration files canonically: known fields in a fixed
+order (kind, maps, options; id, path, keys), provider names and keys sorted
+alphabetically, two space indentation, block style, and quotes only where
+YAML requires them. Comments and the order of lists are preserved.
+
+Without arguments the config found via --config or the directory search is
+formatted. With --check files are not modified; the command lists files that
+are not formatted and fails, which is useful in CI.
+
+Examples:
+  feller config fmt
+  feller config fmt --check
+  feller config fmt ci/.teller.yml deploy/.teller.yml`,
+	RunE: func(_ *cobra.Command, args []string) error {
+		return formatConfigs(os.Stdout, args, fmtCheck)
+	},
+}
+
+// configServeLSPCmd represents the config serve-lsp command
+var configServeLSPCmd = &cobra.Command{
+	Use:   "serve-lsp",
+	Short: "Run a language server for .teller.yml files over stdio",
+	Long: `Run a Language Server Protocol server over stdin/stdout that provides
+diagnostics (the same checks as 'feller validate'), hover documentation,
+and completions for .teller.yml files.
+
+Configure your editor to start 'feller config serve-lsp' for YAML files
+named .teller.yml. Logs are written to stderr, so --debug is safe to use.
+
+Examples:
+  feller config serve-lsp`,
+	Args: cobra.NoArgs,
+	RunE: func(_ *cobra.Command, _ []string) error {
+		if err := lsp.NewServer(os.Stdin, os.Stdout).Run(); err != nil {
+			return fmt.Errorf("language server failed: %w", err)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configFmtCmd)
+	configCmd.AddCommand(configServeLSPCmd)
+	configFmtCmd.Flags().BoolVar(&fmtCheck, "check", false, "Report unformatted files without modifying them")
+}
+
+// formatConfigs formats each file in place, or only reports unformatted files in check mode
+func formatConfigs(out io.Writer, paths []string, check bool) error {
+	if len(paths) == 0 {
+		path, err := config.ResolveConfigPath(cfgFile)
+		if err != nil {
+			return fmt.Errorf("failed to find config: %w", err)
+		}
+		paths = []string{path}
+	}
+
+	unformatted := 0
+	for _, path := range paths {
+		changed, err := formatConfigFile(path, check)
+		if err != nil {
+			return err
+		}
+		if !changed {
+			logger.Debug("%s is already formatted", path)
+			continue
+		}
+		unformatted++
+		fmt.Fprintln(out, path)
+	}
+
+	if check && unformatted > 0 {
+		return fmt.Errorf("%d file(s) are not formatted, run 'feller config fmt' to fix", unformatted)
+	}
+	return nil
+}
+
+// formatConfigFile formats a single file and reports whether its content changed
+func formatConfigFile(path string, check bool) (bool, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+
+	// #nosec G304 - Config path is provided by the user
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	formatted, err := config.Format(data)
+	if err != nil {
+		return false, fmt.Errorf("failed to format %s: %w", path, err)
+	}
+	if bytes.Equal(data, formatted) {
+		return false, nil
+	}
+
+	if !check {
+		if err := os.WriteFile(path, formatted, info.Mode().Perm()); err != nil {
+			return false, fmt.Errorf("failed to write %s: %w", path, err)
+		}
+	}
+	return true, nil
+}
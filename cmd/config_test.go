@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatConfigs(t *testing.T) {
+	t.Parallel()
+	unformatted := "providers:\n    local:\n        maps: [{path: .env, id: dev}]\n        kind: dotenv\n"
+	formatted := "providers:\n  local:\n    kind: dotenv\n    maps:\n      - id: dev\n        path: .env\n"
+
+	tests := []struct {
+		name        string
+		content     string
+		expected    string
+		output      string
+		errContains string
+		check       bool
+	}{
+		{name: "formats in place", content: unformatted, expected: formatted, output: "teller.yml\n"},
+		{name: "check reports without writing", content: unformatted, expected: unformatted, output: "teller.yml\n", check: true, errContains: "1 file(s) are not formatted"},
+		{name: "already formatted", content: formatted, expected: formatted, output: "", check: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			dir := t.TempDir()
+			path := filepath.Join(dir, "teller.yml")
+			require.NoError(t, os.WriteFile(path, []byte(tt.content), 0o640))
+
+			var out bytes.Buffer
+			err := formatConfigs(&out, []string{path}, tt.check)
+			if tt.errContains != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errContains)
+			} else {
+				require.NoError(t, err)
+			}
+
+			data, err := os.ReadFile(path)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, string(data))
+
+			if tt.output == "" {
+				assert.Empty(t, out.String())
+			} else {
+				assert.Equal(t, path+"\n", out.String())
+			}
+
+			info, err := os.Stat(path)
+			require.NoError(t, err)
+			assert.Equal(t, os.FileMode(0o640), info.Mode().Perm())
+		})
+	}
+}
+
+func TestFormatConfigsInvalidFile(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "teller.yml")
+	require.NoError(t, os.WriteFile(path, []byte("providers: ["), 0o600))
+
+	err := formatConfigs(&bytes.Buffer{}, []string{path}, false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to format")
+}
@@ -0,0 +1,196 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/containifyci/feller/pkg/logger"
+	"github.com/containifyci/feller/pkg/plan"
+	"github.com/containifyci/feller/pkg/providers"
+	"github.com/spf13/cobra"
+)
+
+var (
+	copyFrom   string
+	copyTo     string
+	copyMap    string
+	copyKeys   []string
+	copyDryRun bool
+	copyYes    bool
+)
+
+var missingCopy = providers.MissingContext{
+	Command: "copy",
+	Prefix:  "Cannot copy secrets: ",
+	Step:    "Copy secrets",
+	Run:     "feller copy",
+	Hint:    "Resolve every secret of the source provider before copying, or use --silent or --keys to copy the available secrets only.",
+}
+
+// copyCmd represents the copy command
+var copyCmd = &cobra.Command{
+	Use:   "copy --from PROVIDER --to PROVIDER",
+	Short: "Copy secrets from one provider to another",
+	Long: `Resolve the secrets of the provider named by --from and write them into the
+backend of the provider named by --to, for migrations between backends of the
+same config, e.g. from a dotenv file to HashiCorp Vault.
+
+The source provider is resolved like 'feller export' resolves it, with its
+maps, key renames and transforms; --keys limits the copy to the keys
+applications see under those names. The target must be a provider feller can
+write, as for 'feller put': dotenv, hashicorp_vault or google_secretmanager.
+Secrets are written to its only map, or the map named by --map, under the
+source names of maps that rename keys. Keys the target map does not read are
+listed and skipped, unless --keys names them.
+
+The changes are printed as a plan (+ create, ~ update) and applied after a
+[y/N] confirmation, or right away with --yes. Keys the target already holds
+with the same value are left alone. --dry-run stops after the plan, and
+--json prints it as JSON. Nothing is deleted from either provider.
+
+Examples:
+  feller copy --from local --to vault --map app --dry-run
+  feller copy --from staging --to gsm --keys API_KEY,DATABASE_URL --yes`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		return runCopy(cmd.InOrStdin(), cmd.OutOrStdout())
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(copyCmd)
+	copyCmd.Flags().StringVar(&copyFrom, "from", "", "Provider to read the secrets from")
+	copyCmd.Flags().StringVar(&copyTo, "to", "", "Provider to write the secrets to")
+	copyCmd.Flags().StringVar(&copyMap, "map", "", "Path map id of --to to write to, when the provider has several")
+	copyCmd.Flags().StringSliceVar(&copyKeys, "keys", nil, "Only copy these keys (comma-separated)")
+	copyCmd.Flags().BoolVar(&copyDryRun, "dry-run", false, "Print the plan without changing anything")
+	copyCmd.Flags().BoolVarP(&copyYes, "yes", "y", false, "Write without asking for confirmation")
+	copyCmd.Flags().BoolVar(&planJSON, "json", false, "Print the plan of --dry-run as JSON")
+	_ = copyCmd.MarkFlagRequired("from")
+	_ = copyCmd.MarkFlagRequired("to")
+	_ = copyCmd.RegisterFlagCompletionFunc("from", completeProviderList)
+	_ = copyCmd.RegisterFlagCompletionFunc("to", completeProviderList)
+}
+
+func runCopy(in io.Reader, out io.Writer) error {
+	if planJSON && !copyDryRun {
+		return errors.New("--json requires --dry-run")
+	}
+	if copyFrom == copyTo {
+		return fmt.Errorf("--from and --to both name provider %s", copyFrom)
+	}
+
+	unlock, err := lockUnlessDryRun("copy", copyDryRun)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	secrets, err := copySecrets()
+	if err != nil {
+		return err
+	}
+	target, err := openWriteTarget(copyTo, copyMap)
+	if err != nil {
+		return err
+	}
+	keys := slices.Sorted(maps.Keys(secrets))
+	sources, unread := target.readable(keys)
+	if len(unread) > 0 {
+		if len(copyKeys) > 0 {
+			return fmt.Errorf("map %s of provider %s does not read %s", target.pathMap.ID, target.name, strings.Join(unread, ", "))
+		}
+		logger.Info("Skipping %d key(s) map %s of provider %s does not read: %s", len(unread), target.pathMap.ID, target.name, strings.Join(unread, ", "))
+	}
+	if len(sources) == 0 {
+		return fmt.Errorf("provider %s supplies no key map %s of provider %s reads", copyFrom, target.pathMap.ID, target.name)
+	}
+	before, err := target.writer.Read(sourceNames(sources))
+	if err != nil {
+		return err //nolint:wrapcheck // names the provider
+	}
+
+	p := plan.New("copy")
+	pending := make(providers.SecretMap, len(sources))
+	var copied []string
+	for _, key := range slices.Sorted(maps.Keys(sources)) {
+		source := sources[key]
+		change := target.change(plan.ActionCreate, key, source)
+		if value, ok := before[source]; ok {
+			change.Action = plan.ActionUpdate
+			if value == secrets[key] {
+				change.Action, change.Reason = plan.ActionNoop, strings.TrimPrefix(change.Reason+", unchanged", ", ")
+			}
+		}
+		if change.Action != plan.ActionNoop {
+			pending[source] = secrets[key]
+			copied = append(copied, key)
+		}
+		p.Add(change)
+	}
+	if err := writePlan(out, p); err != nil {
+		return err
+	}
+	if copyDryRun {
+		if !planJSON {
+			fmt.Fprintln(out, "Dry run, nothing was changed")
+		}
+		return nil
+	}
+	if len(pending) == 0 {
+		fmt.Fprintf(out, "Nothing to copy, %s already holds the secrets of provider %s\n", target.writer.Target(), copyFrom)
+		return nil
+	}
+	if !copyYes && !confirm(in, out, fmt.Sprintf("Copy %d secret(s) from provider %s to %s?", len(pending), copyFrom, target.writer.Target())) {
+		return errors.New("copy cancelled, nothing was changed")
+	}
+
+	if err := target.backup("copy", slices.Sorted(maps.Keys(pending)), before); err != nil {
+		return err
+	}
+	if err := target.writer.Put(pending); err != nil {
+		return err //nolint:wrapcheck // names the provider or file
+	}
+	target.record("copy", copied)
+	fmt.Fprintf(out, "Copied %d secret(s) from provider %s to %s (provider %s, map %s)\n", len(pending), copyFrom, target.writer.Target(), target.name, target.pathMap.ID)
+	return nil
+}
+
+// copySecrets resolves the secrets of the --from provider, only those of --keys when given
+func copySecrets() (providers.SecretMap, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	if err := cfg.SelectProviders([]string{copyFrom}, nil); err != nil {
+		return nil, fmt.Errorf("invalid --from: %w", err)
+	}
+	if err := checkNativeKinds(cfg); err != nil {
+		return nil, err
+	}
+	result, err := collectSecrets(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if len(copyKeys) == 0 {
+		if err := checkMissing(result, missingCopy, cfg.Messages); err != nil {
+			return nil, err
+		}
+		return result.Secrets, nil
+	}
+
+	var unsupplied []string
+	for _, key := range copyKeys {
+		if _, ok := result.Secrets[key]; !ok {
+			unsupplied = append(unsupplied, key)
+		}
+	}
+	if len(unsupplied) > 0 {
+		return nil, fmt.Errorf("provider %s does not supply %s", copyFrom, strings.Join(unsupplied, ", "))
+	}
+	return filterSecrets(result.Secrets, copyKeys, nil), nil
+}
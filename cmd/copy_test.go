@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/containifyci/feller/pkg/config"
+	"github.com/containifyci/feller/pkg/fellertest"
+	"github.com/containifyci/feller/pkg/state"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//nolint:paralleltest // modifies global flag variables
+func TestCopy(t *testing.T) {
+	t.Cleanup(func() {
+		cfgFile, copyFrom, copyTo, copyMap, copyKeys, copyDryRun, copyYes, planJSON = "", "", "", "", nil, false, false, false
+	})
+
+	source := fellertest.FakeDotenv(t, map[string]string{"API_KEY": "k", "DATABASE_URL": "postgres://db", "OTHER": "o"})
+	target := fellertest.FakeDotenv(t, map[string]string{"api_key": "old", "db": "postgres://db"})
+	target.Maps[0].Keys = map[string]string{"api_key": "API_KEY", "db": "DATABASE_URL"}
+	path := config.LocalPath(target.Maps[0].Path)
+	cfgFile = fellertest.WriteConfig(t, fellertest.NewConfig().Provider("source", source).Provider("target", target).Build())
+	copyFrom, copyTo = "source", "target"
+
+	copyDryRun = true
+	var out bytes.Buffer
+	require.NoError(t, runCopy(strings.NewReader(""), &out))
+	assert.Equal(t, "dotenv:"+path+" (target)\n"+
+		"  ~ API_KEY       as api_key\n"+
+		"    DATABASE_URL  as db, unchanged\n"+
+		"Plan: 0 to create, 1 to update, 0 to delete.\n"+
+		"Dry run, nothing was changed\n", out.String())
+
+	copyDryRun = false
+	out.Reset()
+	err := runCopy(strings.NewReader("n\n"), &out)
+	require.EqualError(t, err, "copy cancelled, nothing was changed")
+	assert.Contains(t, out.String(), "Copy 1 secret(s) from provider source to dotenv:"+path+"? [y/N]: ")
+
+	out.Reset()
+	require.NoError(t, runCopy(strings.NewReader("y\n"), &out))
+	assert.Contains(t, out.String(), "Copied 1 secret(s) from provider source to dotenv:"+path+" (provider target, map "+fellertest.FakeMapID+")\n")
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "api_key=\"k\"\ndb=\"postgres://db\"\n", string(data))
+
+	out.Reset()
+	require.NoError(t, runCopy(strings.NewReader(""), &out))
+	assert.Contains(t, out.String(), "Nothing to copy, dotenv:"+path+" already holds the secrets of provider source\n")
+
+	entries, err := state.ReadAudit(state.Dir(cfgFile))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "copy", entries[0].Command)
+	assert.Equal(t, []string{"API_KEY"}, entries[0].Keys)
+
+	tests := []struct {
+		name    string
+		from    string
+		keys    []string
+		wantErr string
+	}{
+		{name: "key the target does not read", from: "source", keys: []string{"OTHER"}, wantErr: "map " + fellertest.FakeMapID + " of provider target does not read OTHER"},
+		{name: "key the source does not supply", from: "source", keys: []string{"MISSING"}, wantErr: "provider source does not supply MISSING"},
+		{name: "same provider", from: "target", wantErr: "--from and --to both name provider target"},
+		{name: "unknown provider", from: "missing", wantErr: `unknown provider "missing"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			copyFrom, copyKeys = tt.from, tt.keys
+			err := runCopy(strings.NewReader(""), &bytes.Buffer{})
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+
+	"github.com/containifyci/feller/pkg/plan"
+	"github.com/spf13/cobra"
+)
+
+var (
+	deleteProvider string
+	deleteMap      string
+	deleteDryRun   bool
+	deleteYes      bool
+)
+
+// deleteCmd represents the delete command
+var deleteCmd = &cobra.Command{
+	Use:   "delete KEY... --provider NAME",
+	Short: "Delete secrets from a provider",
+	Long: `Delete secrets from the backend of a provider: a dotenv file, a HashiCorp
+Vault KV secret or a Google Secret Manager project. It is the counterpart of
+'feller put' and writes to the same map.
+
+KEY is the key applications see; maps that rename keys are deleted under the
+source name. Keys the backend does not hold are listed and skipped.
+
+The changes are printed as a plan (- delete). Deleting must be confirmed: on a
+terminal feller asks for the provider name, or --yes skips the question.
+Without a terminal, --confirm NAME or --force-destructive is required, so a
+pasted command cannot delete secrets by accident. --dry-run stops after the
+plan, and --json prints it as JSON.
+
+  dotenv                 the lines of the keys are removed, the file is
+                         rewritten atomically with 0600 permissions
+  hashicorp_vault        the secret is written without the keys, as a new
+                         version on KV version 2
+  google_secretmanager   the secrets are deleted with every version, which
+                         cannot be undone
+
+Every deletion is recorded, without values, in .feller/audit/audit.log next
+to the config.
+
+Examples:
+  feller delete OLD_TOKEN --provider local
+  feller delete OLD_TOKEN LEGACY_KEY --provider vault --map app --dry-run
+  feller delete OLD_TOKEN --provider gsm --confirm gsm`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runDelete(cmd.InOrStdin(), cmd.OutOrStdout(), args)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(deleteCmd)
+	deleteCmd.Flags().StringVar(&deleteProvider, "provider", "", "Provider to delete the secrets from")
+	deleteCmd.Flags().StringVar(&deleteMap, "map", "", "Path map id to delete from, when the provider has several")
+	deleteCmd.Flags().BoolVar(&deleteDryRun, "dry-run", false, "Print the plan without changing anything")
+	deleteCmd.Flags().BoolVarP(&deleteYes, "yes", "y", false, "Delete without asking for confirmation on a terminal")
+	deleteCmd.Flags().BoolVar(&planJSON, "json", false, "Print the plan of --dry-run as JSON")
+	addDestructiveFlags(deleteCmd)
+	_ = deleteCmd.MarkFlagRequired("provider")
+	_ = deleteCmd.RegisterFlagCompletionFunc("provider", completeProviderList)
+}
+
+func runDelete(in io.Reader, out io.Writer, keys []string) error {
+	if planJSON && !deleteDryRun {
+		return errors.New("--json requires --dry-run")
+	}
+	seen := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		if seen[key] {
+			return fmt.Errorf("key %s is given more than once", key)
+		}
+		seen[key] = true
+	}
+
+	unlock, err := lockUnlessDryRun("delete", deleteDryRun)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	target, err := openWriteTarget(deleteProvider, deleteMap)
+	if err != nil {
+		return err
+	}
+	sources, err := target.sources(keys)
+	if err != nil {
+		return err
+	}
+	before, err := target.writer.Read(sourceNames(sources))
+	if err != nil {
+		return err //nolint:wrapcheck // names the provider
+	}
+
+	p := plan.New("delete")
+	var deleted, names []string
+	for _, key := range slices.Sorted(maps.Keys(seen)) {
+		change := target.change(plan.ActionDelete, key, sources[key])
+		if _, ok := before[sources[key]]; ok {
+			deleted = append(deleted, key)
+			names = append(names, sources[key])
+		} else {
+			change.Action, change.Reason = plan.ActionNoop, "not set"
+		}
+		p.Add(change)
+	}
+	if err := writePlan(out, p); err != nil {
+		return err
+	}
+	if deleteDryRun {
+		if !planJSON {
+			fmt.Fprintln(out, "Dry run, nothing was changed")
+		}
+		return nil
+	}
+	if len(deleted) == 0 {
+		fmt.Fprintf(out, "Nothing to delete from %s\n", target.writer.Target())
+		return nil
+	}
+	// --yes answers the question on a terminal; scripts still name the provider
+	if !deleteYes || !stdinIsTerminal() {
+		if err := confirmDestructive(in, out, []string{target.name}, len(deleted)); err != nil {
+			return err
+		}
+	}
+
+	if err := target.backup("delete", names, before); err != nil {
+		return err
+	}
+	// Backends that fail part way have deleted some keys, which the audit log must hold too
+	removed, err := target.writer.Delete(names)
+	if len(removed) > 0 {
+		target.record("delete", appliedKeys(removed, names, deleted))
+	}
+	if err != nil {
+		return err //nolint:wrapcheck // names the provider or file
+	}
+	fmt.Fprintf(out, "Deleted %d secret(s) from %s (provider %s, map %s)\n", len(deleted), target.writer.Target(), target.name, target.pathMap.ID)
+	return nil
+}
+
+// appliedKeys returns the keys applications see of the source names a writer removed, where
+// keys[i] is the key of names[i]
+func appliedKeys(removed, names, keys []string) []string {
+	applied := make([]string, 0, len(removed))
+	for _, name := range removed {
+		if i := slices.Index(names, name); i >= 0 {
+			applied = append(applied, keys[i])
+		}
+	}
+	slices.Sort(applied)
+	return applied
+}
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/containifyci/feller/pkg/config"
+	"github.com/containifyci/feller/pkg/fellertest"
+	"github.com/containifyci/feller/pkg/state"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//nolint:paralleltest // modifies global flag variables
+func TestDelete(t *testing.T) {
+	originalTerminal := stdinIsTerminal
+	t.Cleanup(func() {
+		cfgFile, deleteProvider, deleteMap, deleteDryRun, deleteYes, planJSON = "", "", "", false, false, false
+		confirmTargets, forceDestructive, stdinIsTerminal = nil, false, originalTerminal
+	})
+	stdinIsTerminal = func() bool { return false }
+
+	local := fellertest.FakeDotenv(t, map[string]string{"api_key": "k", "KEEP": "v"})
+	local.Maps[0].Keys = map[string]string{"api_key": "API_KEY", "KEEP": "KEEP", "GONE": "GONE"}
+	path := config.LocalPath(local.Maps[0].Path)
+	cfgFile = fellertest.WriteConfig(t, fellertest.NewConfig().Provider("local", local).Build())
+	deleteProvider = "local"
+
+	deleteDryRun = true
+	var out bytes.Buffer
+	require.NoError(t, runDelete(strings.NewReader(""), &out, []string{"API_KEY", "GONE"}))
+	assert.Equal(t, "dotenv:"+path+" (local)\n"+
+		"  - API_KEY  as api_key\n"+
+		"    GONE     not set\n"+
+		"Plan: 0 to create, 0 to update, 1 to delete.\n"+
+		"Dry run, nothing was changed\n", out.String())
+
+	// Without a terminal, --yes does not replace naming the provider
+	deleteDryRun, deleteYes = false, true
+	err := runDelete(strings.NewReader(""), &bytes.Buffer{}, []string{"API_KEY"})
+	require.EqualError(t, err, "deleting 1 secret(s) from local needs --confirm local or --force-destructive")
+
+	confirmTargets = []string{"local"}
+	out.Reset()
+	require.NoError(t, runDelete(strings.NewReader(""), &out, []string{"API_KEY", "GONE"}))
+	assert.Contains(t, out.String(), "Deleted 1 secret(s) from dotenv:"+path+" (provider local, map "+fellertest.FakeMapID+")\n")
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "KEEP=\"v\"\n", string(data))
+
+	out.Reset()
+	require.NoError(t, runDelete(strings.NewReader(""), &out, []string{"API_KEY"}))
+	assert.Contains(t, out.String(), "Nothing to delete from dotenv:"+path+"\n")
+
+	entries, err := state.ReadAudit(state.Dir(cfgFile))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "delete", entries[0].Command)
+	assert.Equal(t, []string{"API_KEY"}, entries[0].Keys)
+	assert.Equal(t, "dotenv:"+path, entries[0].Target)
+	assert.FileExists(t, filepath.Join(state.Dir(cfgFile), state.AuditDir, state.AuditFile))
+
+	assert.EqualError(t, runDelete(strings.NewReader(""), &bytes.Buffer{}, []string{"OTHER"}), "map "+fellertest.FakeMapID+" of provider local does not read OTHER")
+	assert.EqualError(t, runDelete(strings.NewReader(""), &bytes.Buffer{}, []string{"KEEP", "KEEP"}), "key KEEP is given more than once")
+}
+
+func TestAppliedKeys(t *testing.T) {
+	t.Parallel()
+	names, keys := []string{"SRC_B", "A", "SRC_C"}, []string{"B", "A", "C"}
+	assert.Equal(t, []string{"A", "B"}, appliedKeys([]string{"SRC_B", "A"}, names, keys))
+	assert.Empty(t, appliedKeys(nil, names, keys))
+}
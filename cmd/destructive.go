@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	confirmTargets   []string
+	forceDestructive bool
+)
+
+// stdinIsTerminal reports whether someone can answer prompts, replaced in tests
+var stdinIsTerminal = func() bool {
+	info, err := os.Stdin.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// addDestructiveFlags adds the flags that confirm deletions to a command
+func addDestructiveFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVar(&confirmTargets, "confirm", nil, "Confirm deleting secrets by naming every provider or repository they are deleted from")
+	cmd.Flags().BoolVar(&forceDestructive, "force-destructive", false, "Delete secrets without naming their providers or repositories")
+}
+
+// confirmDestructive checks that deleting count secrets from the named targets, providers
+// or repositories, was confirmed. --force-destructive confirms any deletion, --confirm one
+// that names every target. Otherwise each target name is asked for on a terminal, and
+// deletions without one are refused, so a pasted command cannot wipe secrets by accident.
+func confirmDestructive(in io.Reader, out io.Writer, targets []string, count int) error {
+	if forceDestructive || len(targets) == 0 {
+		return nil
+	}
+	var unconfirmed []string
+	for _, target := range targets {
+		if !slices.Contains(confirmTargets, target) {
+			unconfirmed = append(unconfirmed, target)
+		}
+	}
+	if len(unconfirmed) == 0 {
+		return nil
+	}
+	if len(confirmTargets) > 0 || !stdinIsTerminal() {
+		return fmt.Errorf("deleting %d secret(s) from %s needs --confirm %s or --force-destructive",
+			count, strings.Join(targets, ", "), strings.Join(targets, ","))
+	}
+
+	scanner := bufio.NewScanner(in)
+	for _, target := range unconfirmed {
+		fmt.Fprintf(out, "This deletes secrets from %s. Type %s to confirm: ", target, target)
+		if !scanner.Scan() {
+			fmt.Fprintln(out)
+			return fmt.Errorf("deletion from %s not confirmed, nothing was changed; without a terminal, pass --confirm %s or --force-destructive", target, target)
+		}
+		if strings.TrimSpace(scanner.Text()) != target {
+			return fmt.Errorf("deletion from %s not confirmed, nothing was changed", target)
+		}
+	}
+	return nil
+}
+
+// confirm asks question with a [y/N] answer; anything but yes, including the end of the
+// input, declines
+func confirm(in io.Reader, out io.Writer, question string) bool {
+	fmt.Fprintf(out, "%s [y/N]: ", question)
+	scanner := bufio.NewScanner(in)
+	if !scanner.Scan() {
+		fmt.Fprintln(out)
+		return false
+	}
+	switch strings.ToLower(strings.TrimSpace(scanner.Text())) {
+	case "y", "yes":
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//nolint:paralleltest // modifies global flag variables
+func TestConfirmDestructive(t *testing.T) {
+	originalTerminal := stdinIsTerminal
+	t.Cleanup(func() {
+		confirmTargets, forceDestructive, stdinIsTerminal = nil, false, originalTerminal
+	})
+
+	tests := []struct {
+		name     string
+		confirm  []string
+		force    bool
+		terminal bool
+		input    string
+		wantErr  string
+		prompts  int
+	}{
+		{name: "nothing deleted"},
+		{name: "forced", force: true},
+		{name: "every target named", confirm: []string{"vault", "local"}},
+		{name: "target missing from --confirm", confirm: []string{"local"}, terminal: true, wantErr: "deleting 3 secret(s) from local, vault needs --confirm local,vault or --force-destructive"},
+		{name: "no terminal", wantErr: "deleting 3 secret(s) from local, vault needs --confirm local,vault or --force-destructive"},
+		{name: "typed names", terminal: true, input: "local\nvault\n", prompts: 2},
+		{name: "wrong name", terminal: true, input: "local\nvalut\n", prompts: 2, wantErr: "deletion from vault not confirmed, nothing was changed"},
+		{name: "end of input", terminal: true, input: "local\n", prompts: 2, wantErr: "pass --confirm vault or --force-destructive"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			confirmTargets, forceDestructive = tt.confirm, tt.force
+			stdinIsTerminal = func() bool { return tt.terminal }
+			targets := []string{"local", "vault"}
+			if tt.name == "nothing deleted" {
+				targets = nil
+			}
+
+			var out bytes.Buffer
+			err := confirmDestructive(strings.NewReader(tt.input), &out, targets, 3)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tt.prompts, strings.Count(out.String(), "Type "))
+		})
+	}
+}
@@ -0,0 +1,199 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/containifyci/feller/pkg/config"
+	"github.com/containifyci/feller/pkg/logger"
+	"github.com/containifyci/feller/pkg/state"
+)
+
+// DetachedProcess describes a background process started with feller run --detach
+type DetachedProcess struct {
+	Name    string
+	PID     int
+	PIDFile string
+	LogFile string
+	Running bool
+}
+
+// runStateDir returns the directory holding pidfiles and logs of detached processes, in the
+// state directory of the config, so ps and stop find them from any directory
+func runStateDir() (string, error) {
+	configPath, err := config.ResolveConfigPath(cfgFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve config path: %w", err)
+	}
+	return filepath.Join(state.Dir(configPath), "run"), nil
+}
+
+// detachedPaths returns the pidfile and log file paths for a named process in dir
+func detachedPaths(dir, name string) (pidFile, logFile string) {
+	return filepath.Join(dir, name+".pid"), filepath.Join(dir, name+".log")
+}
+
+// defaultDetachName derives a process name from the command
+func defaultDetachName(args []string) string {
+	if len(args) == 0 {
+		return ""
+	}
+	fields := strings.Fields(args[0])
+	if len(fields) == 0 {
+		return ""
+	}
+	return filepath.Base(fields[0])
+}
+
+// startDetached starts the command in the background, writing its pidfile and redirecting output to a log file
+func startDetached(name string, args, env []string, shellMode bool) (*DetachedProcess, error) {
+	if len(args) == 0 {
+		return nil, errors.New("no command specified")
+	}
+	if name == "" {
+		name = defaultDetachName(args)
+	}
+	if name == "" || strings.ContainsAny(name, `/\`) {
+		return nil, fmt.Errorf("invalid process name: %q", name)
+	}
+
+	dir, err := runStateDir()
+	if err != nil {
+		return nil, err
+	}
+	pidFile, logFile := detachedPaths(dir, name)
+	logger.Debug("Detached process '%s': pidfile=%s, log=%s", name, pidFile, logFile)
+
+	if existing, err := readDetachedProcess(dir, name); err == nil && existing.Running {
+		return nil, fmt.Errorf("process %q is already running with PID %d", name, existing.PID)
+	}
+
+	if err := state.Ensure(filepath.Dir(dir), filepath.Base(dir)); err != nil {
+		return nil, fmt.Errorf("failed to create run state directory: %w", err)
+	}
+
+	// #nosec G304 - log path is derived from the validated process name
+	log, err := os.OpenFile(logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open log file %s: %w", logFile, err)
+	}
+	defer log.Close()
+
+	var cmd *exec.Cmd
+	if shellMode {
+		cmd = exec.CommandContext(context.Background(), resolveShell(), "-c", shellCommandString(args))
+	} else {
+		// #nosec G204 - This is intentional: tool designed to execute user-provided commands with secrets
+		cmd = exec.CommandContext(context.Background(), args[0], args[1:]...)
+	}
+	cmd.Env = env
+	cmd.Stdout = log
+	cmd.Stderr = log
+	setDetachAttributes(cmd)
+
+	logger.Verbose("Starting detached process '%s': %s", name, strings.Join(args, " "))
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start detached process: %w", err)
+	}
+
+	pid := cmd.Process.Pid
+	if err := os.WriteFile(pidFile, []byte(strconv.Itoa(pid)+"\n"), 0o600); err != nil {
+		return nil, fmt.Errorf("failed to write pidfile %s: %w", pidFile, err)
+	}
+
+	if err := cmd.Process.Release(); err != nil {
+		logger.Debug("Failed to release detached process: %v", err)
+	}
+
+	return &DetachedProcess{Name: name, PID: pid, PIDFile: pidFile, LogFile: logFile, Running: true}, nil
+}
+
+// readDetachedProcess loads the state of a named detached process from its pidfile in dir
+func readDetachedProcess(dir, name string) (*DetachedProcess, error) {
+	pidFile, logFile := detachedPaths(dir, name)
+
+	data, err := os.ReadFile(pidFile)
+	if err != nil {
+		return nil, fmt.Errorf("no detached process named %q: %w", name, err)
+	}
+
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid pidfile %s: %w", pidFile, err)
+	}
+
+	return &DetachedProcess{
+		Name:    name,
+		PID:     pid,
+		PIDFile: pidFile,
+		LogFile: logFile,
+		Running: state.ProcessAlive(pid),
+	}, nil
+}
+
+// listDetachedProcesses returns all detached processes with a pidfile, sorted by name
+func listDetachedProcesses() ([]*DetachedProcess, error) {
+	dir, err := runStateDir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read run state directory: %w", err)
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".pid") {
+			names = append(names, strings.TrimSuffix(entry.Name(), ".pid"))
+		}
+	}
+	sort.Strings(names)
+
+	processes := make([]*DetachedProcess, 0, len(names))
+	for _, name := range names {
+		process, err := readDetachedProcess(dir, name)
+		if err != nil {
+			logger.Debug("Skipping unreadable pidfile for '%s': %v", name, err)
+			continue
+		}
+		processes = append(processes, process)
+	}
+	return processes, nil
+}
+
+// stopDetachedProcess terminates a named detached process and removes its pidfile
+func stopDetachedProcess(name string) error {
+	dir, err := runStateDir()
+	if err != nil {
+		return err
+	}
+	process, err := readDetachedProcess(dir, name)
+	if err != nil {
+		return err
+	}
+
+	if process.Running {
+		logger.Debug("Stopping detached process '%s' (PID %d)", name, process.PID)
+		if err := terminateProcess(process.PID); err != nil {
+			return fmt.Errorf("failed to stop process %q: %w", name, err)
+		}
+	} else {
+		logger.Debug("Detached process '%s' (PID %d) is no longer running", name, process.PID)
+	}
+
+	if err := os.Remove(process.PIDFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove pidfile %s: %w", process.PIDFile, err)
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/containifyci/feller/pkg/state"
+)
+
+func TestDefaultDetachName(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{name: "no args", args: nil, expected: ""},
+		{name: "absolute path", args: []string{"/usr/local/bin/api-server", "--port", "80"}, expected: "api-server"},
+		{name: "shell string", args: []string{"./server --flag"}, expected: "server"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := defaultDetachName(tt.args); got != tt.expected {
+				t.Errorf("defaultDetachName(%v) = %q, want %q", tt.args, got, tt.expected)
+			}
+		})
+	}
+}
+
+//nolint:paralleltest // modifies the global config path
+func TestDetachedProcessLifecycle(t *testing.T) {
+	originalCfgFile := cfgFile
+	t.Cleanup(func() {
+		cfgFile = originalCfgFile
+	})
+	root := t.TempDir()
+	cfgFile = filepath.Join(root, ".teller.yml")
+	if err := os.WriteFile(cfgFile, []byte("providers: {}\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	env := []string{"PATH=" + PATH, "DETACH_SECRET=hello"}
+	process, err := startDetached("worker", []string{`echo "$DETACH_SECRET"; sleep 30`}, env, true)
+	if err != nil {
+		t.Fatalf("startDetached() unexpected error = %v", err)
+	}
+	if process.PID <= 0 {
+		t.Fatalf("startDetached() returned invalid PID %d", process.PID)
+	}
+	if expected := filepath.Join(state.Dir(cfgFile), "run", "worker.pid"); process.PIDFile != expected {
+		t.Errorf("startDetached() pidfile = %s, want %s next to the config", process.PIDFile, expected)
+	}
+
+	if _, err := startDetached("worker", []string{"sleep 1"}, env, true); err == nil ||
+		!strings.Contains(err.Error(), "already running") {
+		t.Errorf("startDetached() with duplicate name error = %v, expected 'already running'", err)
+	}
+
+	// ps and stop find the process from another directory below the config
+	subdir := filepath.Join(root, "service")
+	if err := os.Mkdir(subdir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(subdir)
+	cfgFile = ""
+	processes, err := listDetachedProcesses()
+	if err != nil {
+		t.Fatalf("listDetachedProcesses() unexpected error = %v", err)
+	}
+	if len(processes) != 1 || processes[0].Name != "worker" || !processes[0].Running {
+		t.Errorf("listDetachedProcesses() = %+v, want one running 'worker'", processes)
+	}
+
+	// Wait for the child to write its output
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if data, _ := os.ReadFile(process.LogFile); strings.Contains(string(data), "hello") {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if data, _ := os.ReadFile(process.LogFile); !strings.Contains(string(data), "hello") {
+		t.Errorf("log file content = %q, expected secret-injected output", string(data))
+	}
+
+	if err := stopDetachedProcess("worker"); err != nil {
+		t.Fatalf("stopDetachedProcess() unexpected error = %v", err)
+	}
+	if _, err := os.Stat(process.PIDFile); !os.IsNotExist(err) {
+		t.Errorf("pidfile should be removed after stop, stat error = %v", err)
+	}
+}
+
+//nolint:paralleltest // modifies the global config path
+func TestStopUnknownProcess(t *testing.T) {
+	originalCfgFile := cfgFile
+	t.Cleanup(func() {
+		cfgFile = originalCfgFile
+	})
+	cfgFile = filepath.Join(t.TempDir(), ".teller.yml")
+
+	err := stopDetachedProcess("missing")
+	if err == nil || !strings.Contains(err.Error(), `no detached process named "missing"`) {
+		t.Errorf("stopDetachedProcess() error = %v, expected unknown process error", err)
+	}
+
+	processes, err := listDetachedProcesses()
+	if err != nil || len(processes) != 0 {
+		t.Errorf("listDetachedProcesses() = %v, %v; want empty list", processes, err)
+	}
+}
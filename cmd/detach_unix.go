@@ -0,0 +1,22 @@
+//go:build !windows
+
+package cmd
+
+import (
+	"fmt"
+	"os/exec"
+	"syscall"
+)
+
+// setDetachAttributes starts the child in its own process group so it outlives feller
+func setDetachAttributes(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// terminateProcess sends SIGTERM to the process group of a detached process
+func terminateProcess(pid int) error {
+	if err := syscall.Kill(-pid, syscall.SIGTERM); err != nil {
+		return fmt.Errorf("failed to signal process group %d: %w", pid, err)
+	}
+	return nil
+}
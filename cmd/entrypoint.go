@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/containifyci/feller/pkg/logger"
+	"github.com/containifyci/feller/pkg/providers"
+	"github.com/spf13/cobra"
+)
+
+// Config lookup of the entrypoint, for configs mounted into the container
+const (
+	configEnv            = "FELLER_CONFIG"
+	defaultMountedConfig = "/etc/feller/.teller.yml"
+)
+
+var missingEntrypoint = providers.MissingContext{
+	Command: "entrypoint",
+	Prefix:  "Cannot start the container: ",
+	Step:    "Run with secrets",
+	Run:     "feller entrypoint -- your-command",
+	Hint:    "In a container, set them in its environment, e.g. from a Kubernetes Secret with envFrom, or use --silent to start with the available secrets only.",
+}
+
+// entrypointCmd represents the entrypoint command
+var entrypointCmd = &cobra.Command{
+	Use:   "entrypoint [--] command [args...]",
+	Short: "Resolve secrets and exec the container command, for use as an image ENTRYPOINT",
+	Long: `Resolve secrets and replace feller with the command, for use as the ENTRYPOINT of an
+OCI image. The command is usually the image CMD:
+
+  ENTRYPOINT ["feller", "entrypoint", "--"]
+  CMD ["./server"]
+
+The config is read from --config, the file named by FELLER_CONFIG, or
+/etc/feller/.teller.yml (e.g. a mounted ConfigMap), in that order, and is
+otherwise searched from the working directory.
+
+Secrets are always resolved by feller itself, since images do not contain
+teller. On Unix the command replaces feller, so it runs as the same process
+and receives signals such as SIGTERM directly.
+
+Examples:
+  docker run -e DATABASE_URL -v ./.teller.yml:/etc/feller/.teller.yml app
+  feller entrypoint -- nginx -g 'daemon off;'`,
+	RunE: runEntrypoint,
+}
+
+func init() {
+	rootCmd.AddCommand(entrypointCmd)
+	// Stop flag parsing at the first positional argument, since CMD may have flags of its own
+	entrypointCmd.Flags().SetInterspersed(false)
+}
+
+func runEntrypoint(_ *cobra.Command, args []string) error {
+	if len(args) == 0 {
+		return errors.New("no command specified: set the image CMD or pass it after 'feller entrypoint --'")
+	}
+
+	secrets, err := resolveContainerSecrets(missingEntrypoint)
+	if err != nil {
+		return err
+	}
+
+	env := os.Environ()
+	for key, value := range secrets {
+		env = append(env, key+"="+value)
+	}
+
+	path, err := exec.LookPath(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to find command %s: %w", args[0], err)
+	}
+	logger.Debug("Executing %s with %d environment variables", path, len(env))
+	return execReplace(path, args, env)
+}
+
+// resolveContainerSecrets resolves secrets natively with the config found by
+// entrypointConfig, since images do not contain teller
+func resolveContainerSecrets(missing providers.MissingContext) (providers.SecretMap, error) {
+	if cfgFile == "" {
+		cfgFile = entrypointConfig()
+	}
+	logger.Debug("Container config: %q", cfgFile)
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	if err := checkNativeKinds(cfg); err != nil {
+		return nil, err
+	}
+
+	result, err := collectSecrets(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkMissing(result, missing, cfg.Messages); err != nil {
+		return nil, err
+	}
+	logger.Verbose("Collected %d secrets", len(result.Secrets))
+	return result.Secrets, nil
+}
+
+// entrypointConfig returns the config named by FELLER_CONFIG or the mounted default config,
+// or "" to search for one
+func entrypointConfig() string {
+	if path := os.Getenv(configEnv); path != "" {
+		return path
+	}
+	if _, err := os.Stat(defaultMountedConfig); err == nil {
+		return defaultMountedConfig
+	}
+	return ""
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/containifyci/feller/pkg/config"
+	"github.com/containifyci/feller/pkg/fellertest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//nolint:paralleltest // Modifies environment variables and the global config path
+func TestRunEntrypointErrors(t *testing.T) {
+	aws := config.Provider{Kind: "aws_secretsmanager", Maps: []config.PathMap{{ID: "aws", Path: "prod/app", Keys: map[string]string{"token": "AWS_TOKEN"}}}}
+
+	tests := []struct {
+		name        string
+		cfg         *config.TellerConfig
+		args        []string
+		errContains string
+	}{
+		{
+			name:        "no command",
+			cfg:         fellertest.NewConfig().Build(),
+			errContains: "set the image CMD",
+		},
+		{
+			name:        "unsupported kind",
+			cfg:         fellertest.NewConfig().Provider("aws", aws).Build(),
+			args:        []string{"true"},
+			errContains: `provider "aws" has kind "aws_secretsmanager"`,
+		},
+		{
+			name:        "missing variables",
+			cfg:         fellertest.NewConfig().Provider("gsm", fellertest.MissingGSM(t, "DB_PASSWORD")).Build(),
+			args:        []string{"true"},
+			errContains: "Cannot start the container: Missing 1 required environment variable(s)",
+		},
+		{
+			name:        "unknown command",
+			cfg:         fellertest.NewConfig().Provider("env", fellertest.FakeDotenv(t, map[string]string{"A": "1"})).Build(),
+			args:        []string{"feller-entrypoint-test-missing-command"},
+			errContains: "failed to find command feller-entrypoint-test-missing-command",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The config is found through FELLER_CONFIG like in a container
+			t.Setenv(configEnv, fellertest.WriteConfig(t, tt.cfg))
+			t.Cleanup(func() { cfgFile = "" })
+
+			err := runEntrypoint(nil, tt.args)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errContains)
+		})
+	}
+}
+
+//nolint:paralleltest // Modifies environment variables
+func TestEntrypointConfig(t *testing.T) {
+	t.Setenv(configEnv, "/config/.teller.yml")
+	assert.Equal(t, "/config/.teller.yml", entrypointConfig())
+}
@@ -0,0 +1,17 @@
+//go:build !windows
+
+package cmd
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// execReplace replaces feller with the command, so it keeps the PID and receives signals directly
+func execReplace(path string, args, env []string) error {
+	// #nosec G204 - This is intentional: the entrypoint runs the container command
+	if err := syscall.Exec(path, args, env); err != nil {
+		return fmt.Errorf("failed to exec %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+//go:build windows
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// execReplace runs the command and exits with its exit code, since Windows cannot replace a process
+func execReplace(path string, args, env []string) error {
+	// #nosec G204 - This is intentional: the entrypoint runs the container command
+	cmd := exec.CommandContext(context.Background(), path, args[1:]...)
+	cmd.Env = env
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.ExitCode())
+		}
+		return fmt.Errorf("failed to run %s: %w", path, err)
+	}
+	os.Exit(0)
+	return nil
+}
@@ -11,11 +11,22 @@ var envCmd = &cobra.Command{
 	Long: `Export secrets in environment variable format suitable for sourcing
 or using with tools like docker --env-file.
 
-This is equivalent to 'feller export env'.
+This is equivalent to 'feller export env' and accepts the same flags, but
+defaults to --quote auto: values are written as KEY=value and only quoted
+when they cannot be represented otherwise (newlines, surrounding
+whitespace, leading quotes). This output works with docker --env-file,
+which does not strip quotes from values.
+
+Quote modes:
+  always - KEY="value" with escaped quotes and newlines
+  never  - KEY=value, failing on values containing newlines
+  auto   - unquoted unless quoting is required (default)
 
 Examples:
   feller env
-  feller env > .env.secrets
+  feller env --out .env.secrets
+  feller env --only DATABASE_URL,API_KEY
+  feller env --quote always
   docker run --env-file <(feller env) myapp`,
 	RunE: func(cmd *cobra.Command, _ []string) error {
 		return exportSecrets(cmd, []string{"env"})
@@ -24,4 +35,5 @@ Examples:
 
 func init() {
 	rootCmd.AddCommand(envCmd)
+	addExportFlags(envCmd, quoteAuto)
 }
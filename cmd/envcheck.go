@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/containifyci/feller/pkg/logger"
+	"github.com/containifyci/feller/pkg/providers"
+)
+
+var (
+	assertEnv []string
+	denyEnv   []string
+)
+
+// checkDenyEnv validates the --deny-env globs
+func checkDenyEnv() error {
+	for _, pattern := range denyEnv {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid --deny-env glob %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// denyInherited removes the inherited variables matching a --deny-env glob from env, so the
+// command cannot pick up credentials of the calling shell, and returns the rest
+func denyInherited(env []string) []string {
+	if len(denyEnv) == 0 {
+		return env
+	}
+	kept := make([]string, 0, len(env))
+	var denied []string
+	for _, entry := range env {
+		name, _, _ := strings.Cut(entry, "=")
+		if matchesAny(denyEnv, name) {
+			denied = append(denied, name)
+			continue
+		}
+		kept = append(kept, entry)
+	}
+	if len(denied) > 0 {
+		sort.Strings(denied)
+		logger.Verbose("Removed %d inherited variable(s) matching --deny-env: %s", len(denied), strings.Join(denied, ", "))
+	}
+	return kept
+}
+
+// assertEnvSet checks that every --assert-env key reaches the command with a value, in env
+// or on the --env-fd descriptor, and confirms each with its masked value on w
+func assertEnvSet(w io.Writer, env []string, secrets providers.SecretMap) error {
+	if len(assertEnv) == 0 {
+		return nil
+	}
+	values := make(map[string]string, len(env))
+	for _, entry := range env {
+		name, value, _ := strings.Cut(entry, "=")
+		values[name] = value
+	}
+	where := ""
+	if envFD != 0 {
+		for key, value := range secrets {
+			values[key] = value
+		}
+		where = fmt.Sprintf(" (on fd %d)", envFD)
+	}
+
+	var failed []string
+	for _, key := range assertEnv {
+		value, ok := values[key]
+		switch {
+		case !ok:
+			failed = append(failed, key+" is not set")
+		case value == "":
+			failed = append(failed, key+" is empty")
+		default:
+			fmt.Fprintf(w, "feller: %s is set%s: %s\n", key, where, maskSecret(value))
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("--assert-env failed: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// matchesAny reports whether name matches one of the checked globs
+func matchesAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
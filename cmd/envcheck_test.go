@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/containifyci/feller/pkg/providers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//nolint:paralleltest // Modifies the global run flags
+func TestDenyInherited(t *testing.T) {
+	t.Cleanup(func() { denyEnv = nil })
+
+	env := []string{"AWS_ACCESS_KEY_ID=a", "AWS_SECRET_ACCESS_KEY=b", "HOME=/root", "GOOGLE_APPLICATION_CREDENTIALS=/key.json", "PATH=/bin"}
+	assert.Equal(t, env, denyInherited(env))
+
+	denyEnv = []string{"AWS_*", "GOOGLE_APPLICATION_CREDENTIALS"}
+	require.NoError(t, checkDenyEnv())
+	assert.Equal(t, []string{"HOME=/root", "PATH=/bin"}, denyInherited(env))
+
+	denyEnv = []string{"AWS_["}
+	require.ErrorContains(t, checkDenyEnv(), `invalid --deny-env glob "AWS_["`)
+}
+
+//nolint:paralleltest // Modifies the global run flags
+func TestAssertEnvSet(t *testing.T) {
+	t.Cleanup(func() { assertEnv, envFD = nil, 0 })
+	env := []string{"API_KEY=0123456789", "EMPTY=", "HOME=/root"}
+	secrets := providers.SecretMap{"API_KEY": "0123456789", "FD_ONLY": "abcdef"}
+
+	var out bytes.Buffer
+	require.NoError(t, assertEnvSet(&out, env, secrets))
+	assert.Empty(t, out.String(), "nothing is asserted without --assert-env")
+
+	assertEnv = []string{"API_KEY", "HOME"}
+	require.NoError(t, assertEnvSet(&out, env, secrets))
+	assert.Equal(t, "feller: API_KEY is set: 01******89\nfeller: HOME is set: /r*ot\n", out.String())
+
+	out.Reset()
+	assertEnv = []string{"API_KEY", "EMPTY", "FD_ONLY", "NOPE"}
+	err := assertEnvSet(&out, env, secrets)
+	require.EqualError(t, err, "--assert-env failed: EMPTY is empty, FD_ONLY is not set, NOPE is not set")
+	assert.Equal(t, "feller: API_KEY is set: 01******89\n", out.String())
+
+	// With --env-fd the secrets reach the command on the descriptor
+	out.Reset()
+	envFD = 3
+	assertEnv = []string{"FD_ONLY"}
+	require.NoError(t, assertEnvSet(&out, nil, secrets))
+	assert.Equal(t, "feller: FD_ONLY is set (on fd 3): ab**ef\n", out.String())
+}
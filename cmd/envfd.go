@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/containifyci/feller/pkg/logger"
+	"github.com/containifyci/feller/pkg/providers"
+)
+
+// envFDEnv tells the child the descriptor its secrets can be read from
+const envFDEnv = "FELLER_ENV_FD"
+
+// envFD is the descriptor --env-fd passes the secrets on, 0 when they are passed in the
+// environment
+var envFD int
+
+// checkEnvFD validates --env-fd against the other run flags
+func checkEnvFD() error {
+	if envFD == 0 {
+		return nil
+	}
+	if envFD < 3 {
+		return fmt.Errorf("--env-fd must be 3 or higher, descriptors 0 to 2 are stdin, stdout and stderr (got %d)", envFD)
+	}
+	if detach || parallel || procfile != "" {
+		return errors.New("--env-fd cannot be combined with --detach, --parallel or --procfile")
+	}
+	return nil
+}
+
+// encodeEnviron encodes secrets like /proc/PID/environ: KEY=value entries sorted by key,
+// each terminated by a NUL byte, so values may hold line breaks
+func encodeEnviron(secrets providers.SecretMap) []byte {
+	keys := make([]string, 0, len(secrets))
+	for key := range secrets {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, key := range keys {
+		b.WriteString(key)
+		b.WriteByte('=')
+		b.WriteString(secrets[key])
+		b.WriteByte(0)
+	}
+	return []byte(b.String())
+}
+
+// envFDFiles returns the exec.Cmd ExtraFiles that make file descriptor fd of the child.
+// ExtraFiles start at descriptor 3; the nil entries before fd are closed in the child.
+func envFDFiles(fd int, file *os.File) []*os.File {
+	files := make([]*os.File, fd-2)
+	files[fd-3] = file
+	return files
+}
+
+// pipeEnvFile returns the read end of a pipe that data is written to in the background.
+// The writer stops when the child has read everything or the read end is closed.
+func pipeEnvFile(data []byte) (*os.File, error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the secrets pipe: %w", err)
+	}
+	go func() {
+		if _, err := w.Write(data); err != nil {
+			logger.Debug("Secrets pipe closed before the child read it: %v", err)
+		}
+		_ = w.Close()
+	}()
+	return r, nil
+}
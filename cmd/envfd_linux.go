@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"syscall"
+	"unsafe"
+
+	"github.com/containifyci/feller/pkg/logger"
+)
+
+// memfdCreate are the memfd_create system call numbers, which the syscall package does not
+// define for every architecture
+var memfdCreate = map[string]uintptr{
+	"386":   356,
+	"amd64": 319,
+	"arm":   385,
+	"arm64": 279,
+}
+
+// newEnvFile returns an in-memory file holding data, falling back to a pipe where
+// memfd_create is unavailable
+func newEnvFile(data []byte) (*os.File, error) {
+	file, err := memfdEnvFile(data)
+	if err != nil {
+		logger.Debug("Passing secrets through a pipe: %v", err)
+		return pipeEnvFile(data)
+	}
+	return file, nil
+}
+
+// memfdEnvFile writes data to an anonymous memory file, never stored on disk, and rewinds
+// it for the child
+func memfdEnvFile(data []byte) (*os.File, error) {
+	nr, ok := memfdCreate[runtime.GOARCH]
+	if !ok {
+		return nil, fmt.Errorf("memfd_create is not known on %s", runtime.GOARCH)
+	}
+	name, err := syscall.BytePtrFromString("feller-env")
+	if err != nil {
+		return nil, fmt.Errorf("failed to name the memfd: %w", err)
+	}
+	const mfdCloexec = 0x1 // The child receives a duplicate of the descriptor
+	// #nosec G103 - memfd_create takes the name as a C string
+	fd, _, errno := syscall.Syscall(nr, uintptr(unsafe.Pointer(name)), mfdCloexec, 0)
+	if errno != 0 {
+		return nil, fmt.Errorf("memfd_create failed: %w", errno)
+	}
+	file := os.NewFile(fd, "feller-env")
+	if _, err := file.Write(data); err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("failed to write the secrets memfd: %w", err)
+	}
+	if _, err := file.Seek(0, 0); err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("failed to rewind the secrets memfd: %w", err)
+	}
+	return file, nil
+}
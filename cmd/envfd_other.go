@@ -0,0 +1,10 @@
+//go:build !linux && !windows
+
+package cmd
+
+import "os"
+
+// newEnvFile returns the read end of a pipe holding data
+func newEnvFile(data []byte) (*os.File, error) {
+	return pipeEnvFile(data)
+}
@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/containifyci/feller/pkg/fellertest"
+	"github.com/containifyci/feller/pkg/providers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// envFDHelperEnv names the file TestEnvFDHelperProcess writes what it received to
+const envFDHelperEnv = "FELLER_ENVFD_HELPER_OUT"
+
+// TestEnvFDHelperProcess is the command run by TestRunEnvFD. It writes the secrets read from
+// FELLER_ENV_FD, then whether API_KEY was in its environment.
+func TestEnvFDHelperProcess(t *testing.T) {
+	t.Parallel()
+	out := os.Getenv(envFDHelperEnv)
+	if out == "" {
+		return
+	}
+	fd, err := strconv.Atoi(os.Getenv(envFDEnv))
+	require.NoError(t, err)
+	data, err := io.ReadAll(os.NewFile(uintptr(fd), "secrets"))
+	require.NoError(t, err)
+	_, inEnv := os.LookupEnv("API_KEY")
+	data = append(data, []byte(strconv.FormatBool(inEnv))...)
+	require.NoError(t, os.WriteFile(out, data, 0o600))
+}
+
+func TestEncodeEnviron(t *testing.T) {
+	t.Parallel()
+	data := encodeEnviron(providers.SecretMap{"B": "two\nlines", "A": "x=y", "EMPTY": ""})
+	assert.Equal(t, "A=x=y\x00B=two\nlines\x00EMPTY=\x00", string(data))
+	assert.Equal(t, map[string]string{"A": "x=y", "B": "two\nlines", "EMPTY": ""}, parseEnviron(data))
+}
+
+func TestEnvFDFiles(t *testing.T) {
+	t.Parallel()
+	files := envFDFiles(5, os.Stdin)
+	assert.Equal(t, []*os.File{nil, nil, os.Stdin}, files)
+	assert.Equal(t, []*os.File{os.Stdin}, envFDFiles(3, os.Stdin))
+}
+
+//nolint:paralleltest // Modifies the global run flags
+func TestCheckEnvFD(t *testing.T) {
+	t.Cleanup(func() { envFD, detach = 0, false })
+
+	envFD = 2
+	require.ErrorContains(t, checkEnvFD(), "--env-fd must be 3 or higher")
+	envFD, detach = 3, true
+	require.ErrorContains(t, checkEnvFD(), "cannot be combined with --detach")
+	detach = false
+	require.NoError(t, checkEnvFD())
+}
+
+//nolint:paralleltest // Modifies the environment, global config path and run flags
+func TestRunEnvFD(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows processes cannot inherit extra file descriptors")
+	}
+	t.Setenv("GITHUB_ACTIONS", "true")
+	t.Cleanup(func() { cfgFile, envFD = "", 0 })
+
+	secrets := map[string]string{"API_KEY": "abc", "DB_URL": "postgres://db"}
+	cfgFile = fellertest.WriteConfig(t, fellertest.NewConfig().Provider("local", fellertest.FakeDotenv(t, secrets)).Build())
+	out := filepath.Join(t.TempDir(), "received")
+	t.Setenv(envFDHelperEnv, out)
+
+	// The test binary itself is the command, so the test does not depend on PATH
+	envFD = 5
+	require.NoError(t, runCommand(runCmd, []string{os.Args[0], "-test.run=^TestEnvFDHelperProcess$"}))
+
+	data, err := os.ReadFile(out)
+	require.NoError(t, err)
+	assert.Equal(t, "API_KEY=abc\x00DB_URL=postgres://db\x00false", string(data))
+}
+
+func TestPipeEnvFile(t *testing.T) {
+	t.Parallel()
+	// Larger than a pipe buffer, so the writer must not block the caller
+	data := []byte(strings.Repeat("KEY=value\x00", 20000))
+	file, err := pipeEnvFile(data)
+	require.NoError(t, err)
+	defer file.Close()
+	read, err := io.ReadAll(file)
+	require.NoError(t, err)
+	assert.Equal(t, data, read)
+}
@@ -0,0 +1,11 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+)
+
+// newEnvFile fails: Windows processes cannot inherit extra file descriptors
+func newEnvFile(_ []byte) (*os.File, error) {
+	return nil, errors.New("--env-fd is not supported on Windows")
+}
@@ -1,13 +1,18 @@
 package cmd
 
 import (
+	"bytes"
+	"encoding/csv"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"os"
+	"slices"
 	"sort"
 	"strings"
 
-	"github.com/containifyci/feller/pkg/config"
+	"github.com/containifyci/feller/pkg/bundle"
 	"github.com/containifyci/feller/pkg/logger"
 	"github.com/containifyci/feller/pkg/providers"
 
@@ -15,6 +20,50 @@ import (
 	"gopkg.in/yaml.v3"
 )
 
+// formatBundle is the export format of age-encrypted secret bundles
+const formatBundle = "bundle"
+
+// formatJSONPatch is the export format of RFC 7386 merge patches against a --base document
+const formatJSONPatch = "json-patch"
+
+// formatEnvExport is the export format of env files with export statements for sourcing by shells
+const formatEnvExport = "env-export"
+
+// Export formats of config files for JVM, Rust and Python projects
+const (
+	formatProperties = "properties"
+	formatTOML       = "toml"
+)
+
+// tellerUnsupportedFormats are the export formats only feller's own providers can write
+var tellerUnsupportedFormats = []string{formatBundle, formatEnvExport, formatJSONPatch, formatProperties, formatTOML}
+
+const (
+	quoteAlways = "always"
+	quoteNever  = "never"
+	quoteAuto   = "auto"
+)
+
+var (
+	exportOut      string
+	exportOnly     []string
+	exportExclude  []string
+	exportNoQuotes bool
+
+	exportSign       string
+	exportSignKey    string
+	exportRecipients []string
+	exportWithStatus bool
+	exportBase       string
+
+	csvDelimiter    string
+	csvNoHeader     bool
+	csvExtraColumns []string
+
+	// exportQuote is the quoting mode used by the env format, resolved from the command flags
+	exportQuote = quoteAlways
+)
+
 // exportCmd represents the export command
 var exportCmd = &cobra.Command{
 	Use:   "export [format]",
@@ -23,52 +72,201 @@ var exportCmd = &cobra.Command{
 
 Available formats:
   json - Export as JSON object
-  yaml - Export as YAML document  
+  json-patch - Export as JSON merge patch (RFC 7386) turning --base into the secrets
+  yaml - Export as YAML document (quoted values, literal blocks for multiline)
   env  - Export as environment variable format
+  env-export - Export as "export KEY=value" lines for sourcing by shells
   csv  - Export as CSV (key,value pairs)
+  properties - Export as Java .properties file (ISO-8859-1 with \uXXXX escapes)
+  toml - Export as TOML document of string values
+  bundle - Export as an age-encrypted bundle with the origin of every key,
+           readable by the bundle provider (see 'feller import bundle')
+
+Use --only and --exclude to select keys, and -o/--out (or --output) to write
+the result to a file instead of stdout. The file is replaced atomically with
+one created with 0600 permissions, so readers never see partial output and no
+shell redirection leaves it readable by others. The env format
+accepts --quote always|never|auto (default always, see 'feller env').
+
+The env-export format quotes values for POSIX shells instead, so sourcing
+the file never expands $ or backticks: --quote always writes KEY='value',
+auto quotes only values that need it, and never fails on such values.
+
+The csv format accepts --delimiter, --no-header, and --extra-columns to add
+the source provider and path map id of each key.
+
+--sign cosign|minisign signs the --out file so consumers can check its
+provenance with 'feller verify'. cosign signs keylessly with the workflow's
+OIDC identity unless --sign-key is given; minisign requires --sign-key.
+
+--with-status wraps the json format in a document with the source of every
+key and the missing variables, which are reported there instead of failing
+the command, so CI tooling can tell what is missing without parsing errors.
+
+The json-patch format requires --base, a JSON document such as the current
+configuration of a tool. The patch sets the keys that are new or changed and
+removes (sets to null) the keys of --base that are not exported, so applying
+it to --base yields the exported secrets. Unchanged keys are left out.
+
+The bundle format requires --recipients, the age public keys (age1...) or SSH
+public keys allowed to decrypt it. The age tool must be installed.
 
 Examples:
   feller export json
   feller export yaml
-  feller export env`,
+  feller export env
+  feller export env-export --quote auto --out .env.sh
+  feller export json --only DATABASE_URL,API_KEY --out secrets.json
+  feller export json --with-status | jq -r '.missing[].variable'
+  feller export json-patch --base current.json
+  feller export csv --delimiter ';' --extra-columns provider,map_id
+  feller export properties --out application-secrets.properties
+  feller export json --out secrets.json --sign cosign
+  feller export bundle --recipients age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p --out secrets.age`,
 	Args:      cobra.ExactArgs(1),
-	ValidArgs: []string{"json", "yaml", "env", "csv"},
+	ValidArgs: []string{"json", formatJSONPatch, "yaml", "env", formatEnvExport, "csv", formatProperties, formatTOML, "bundle"},
 	RunE:      exportSecrets,
 }
 
 func init() {
 	rootCmd.AddCommand(exportCmd)
+	addExportFlags(exportCmd, quoteAlways)
+	exportCmd.Flags().StringVar(&csvDelimiter, "delimiter", ",", "Field delimiter for csv format")
+	exportCmd.Flags().BoolVar(&csvNoHeader, "no-header", false, "Omit the header row in csv format")
+	exportCmd.Flags().StringSliceVar(&csvExtraColumns, "extra-columns", nil, "Extra csv columns to include (provider, map_id)")
+	exportCmd.Flags().StringVar(&exportSign, "sign", "", "Sign the --out file (cosign, minisign)")
+	exportCmd.Flags().StringVar(&exportSignKey, "sign-key", "", "Private key to sign with (required for minisign, optional for cosign)")
+	exportCmd.Flags().StringSliceVar(&exportRecipients, "recipients", nil, "age recipients allowed to decrypt the bundle format (comma-separated)")
+	exportCmd.Flags().BoolVar(&exportWithStatus, "with-status", false, "Export json with the source of each key and the missing variables")
+	exportCmd.Flags().StringVar(&exportBase, "base", "", "JSON document the json-patch format computes the merge patch against")
+}
+
+// addExportFlags registers the output and filtering flags shared by export and env
+func addExportFlags(cmd *cobra.Command, defaultQuote string) {
+	addOutputFlag(cmd)
+	cmd.Flags().StringSliceVar(&exportOnly, "only", nil, "Only export these keys (comma-separated)")
+	cmd.Flags().StringSliceVar(&exportExclude, "exclude", nil, "Do not export these keys (comma-separated)")
+	_ = cmd.RegisterFlagCompletionFunc("only", completeKeyList)
+	_ = cmd.RegisterFlagCompletionFunc("exclude", completeKeyList)
+	// The default differs between export and env, so the value is read from the command instead of a shared variable
+	cmd.Flags().String("quote", defaultQuote, "Quoting of env format values (always, never, auto)")
+	cmd.Flags().BoolVar(&printSummary, "summary", false, "Print the number of keys and a checksum of their names to stderr")
+	cmd.Flags().BoolVar(&exportNoQuotes, "no-quotes", false, "Shorthand for --quote never")
 }
 
-func exportSecrets(_ *cobra.Command, args []string) error {
+// addOutputFlag registers -o/--out, with --output as an alias, writing the output of cmd to
+// a file instead of stdout
+func addOutputFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&exportOut, "out", "o", "", "Write output atomically to a file with 0600 permissions instead of stdout")
+	cmd.Flags().StringVar(&exportOut, "output", "", "Alias of --out")
+	_ = cmd.Flags().MarkHidden("output")
+}
+
+// resolveQuoteMode sets exportQuote from the --quote and --no-quotes flags of the command
+func resolveQuoteMode(cmd *cobra.Command) error {
+	mode := quoteAlways
+	if cmd != nil {
+		if flag := cmd.Flags().Lookup("quote"); flag != nil {
+			mode = flag.Value.String()
+			if exportNoQuotes {
+				if flag.Changed && mode != quoteNever {
+					return fmt.Errorf("--no-quotes conflicts with --quote %s", mode)
+				}
+				mode = quoteNever
+			}
+		}
+	}
+
+	switch mode {
+	case quoteAlways, quoteNever, quoteAuto:
+		exportQuote = mode
+		logger.Debug("Env quoting mode: %s", mode)
+		return nil
+	default:
+		return fmt.Errorf("unsupported quote mode: %s (expected always, never or auto)", mode)
+	}
+}
+
+func exportSecrets(cmd *cobra.Command, args []string) error {
 	format := args[0]
 	logger.Debug("Starting export command with format: %s", format)
 
-	// Check if we're in GitHub Actions
-	if !isGitHubActions() {
-		logger.Debug("Not in GitHub Actions, falling back to teller")
-		return fallbackToTeller(append([]string{"export"}, args...))
+	if err := resolveQuoteMode(cmd); err != nil {
+		return err
+	}
+
+	if format == formatBundle && len(exportRecipients) == 0 {
+		return errors.New("the bundle format requires --recipients")
+	}
+	if format != formatBundle && len(exportRecipients) > 0 {
+		return errors.New("--recipients is only supported by the bundle format")
+	}
+
+	if exportWithStatus && format != "json" {
+		return errors.New("--with-status is only supported by the json format")
+	}
+
+	if format == formatJSONPatch && exportBase == "" {
+		return errors.New("the json-patch format requires --base")
+	}
+	if format != formatJSONPatch && exportBase != "" {
+		return errors.New("--base is only supported by the json-patch format")
+	}
+
+	if exportSign != "" {
+		if exportOut == "" {
+			return errors.New("--sign requires --out, since only files can be signed")
+		}
+		if _, err := signatureFile(exportSign, exportOut); err != nil {
+			return err
+		}
+	}
+
+	// Check if we resolve secrets natively, as on CI
+	native, err := resolveNatively()
+	if err != nil {
+		return err
+	}
+	if !native {
+		logger.Debug("Not in CI, falling back to teller")
+		if exportSign != "" {
+			return errors.New("--sign is not supported by teller fallback mode")
+		}
+		if slices.Contains(tellerUnsupportedFormats, format) {
+			return fmt.Errorf("the %s format is not supported by teller fallback mode", format)
+		}
+		if printSummary {
+			return errors.New("--summary is not supported by teller fallback mode")
+		}
+		if exportWithStatus {
+			return errors.New("--with-status is not supported by teller fallback mode")
+		}
+		if len(exportOnly) > 0 || len(exportExclude) > 0 {
+			return errors.New("--only and --exclude are not supported by teller fallback mode")
+		}
+		return fallbackToTeller(tellerCommandArgs(cmd, append([]string{"export"}, args...)...), exportOut)
 	}
 
-	logger.Debug("In GitHub Actions mode, processing secrets for export")
+	logger.Debug("In CI mode, processing secrets for export")
 
 	// Load configuration
-	cfg, err := config.LoadConfig(cfgFile)
+	cfg, err := loadConfig()
 	if err != nil {
 		logger.Debug("Failed to load config: %v", err)
 		return fmt.Errorf("failed to load config: %w", err)
 	}
 
 	// Collect all secrets and check for missing variables
-	result, err := providers.CollectSecretsWithResult(cfg, silent)
+	result, err := collectSecrets(cfg)
 	if err != nil {
-		logger.Debug("Failed to collect secrets: %v", err)
-		return fmt.Errorf("failed to collect secrets: %w", err)
+		return err
 	}
 
-	// Handle missing environment variables
-	if result.HasMissingVars && !silent {
-		return handleMissingVariablesExport(result.MissingVars)
+	// Handle missing environment variables, which the status document reports itself
+	if !exportWithStatus {
+		if err := checkMissing(result, missingExport, cfg.Messages); err != nil {
+			return err
+		}
 	}
 
 	logger.Debug("Collected %d secrets for export in format: %s", len(result.Secrets), format)
@@ -76,44 +274,273 @@ func exportSecrets(_ *cobra.Command, args []string) error {
 		logger.Debug("Missing %d environment variables (silent mode: %v)", len(result.MissingVars), silent)
 	}
 
+	secrets := filterSecrets(result.Secrets, exportOnly, exportExclude)
+
+	var buf bytes.Buffer
+	if format == formatBundle {
+		encrypted, err := bundle.Encrypt(providers.NewBundle(secrets, result.Sources), exportRecipients)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt bundle: %w", err)
+		}
+		buf.Write(encrypted)
+	} else if exportWithStatus {
+		if err := exportJSONWithStatus(&buf, secrets, result); err != nil {
+			return err
+		}
+	} else if format == formatJSONPatch {
+		// #nosec G304 - base path is provided by the user
+		base, err := os.ReadFile(exportBase)
+		if err != nil {
+			return fmt.Errorf("failed to read base document: %w", err)
+		}
+		if err := exportJSONPatch(&buf, base, secrets); err != nil {
+			return err
+		}
+	} else if err := writeExport(&buf, format, secrets, result.Sources); err != nil {
+		return err
+	}
+	if err := writeOutput(cmd.OutOrStdout(), exportOut, buf.Bytes()); err != nil {
+		return err
+	}
+	if printSummary {
+		if err := reportSummary(cmd.ErrOrStderr(), secrets); err != nil {
+			return err
+		}
+	}
+	if exportSign == "" {
+		return nil
+	}
+	sig, err := signFile(exportSign, exportOut, exportSignKey)
+	if err != nil {
+		return err
+	}
+	logger.Info("Signed %s, signature written to %s", exportOut, sig)
+	return nil
+}
+
+// writeExport renders secrets in the given format
+func writeExport(w io.Writer, format string, secrets providers.SecretMap, sources map[string]providers.SecretSource) error {
 	switch format {
 	case "json":
 		logger.Debug("Exporting in JSON format")
-		return exportJSON(result.Secrets)
+		return exportJSON(w, secrets)
 	case "yaml":
 		logger.Debug("Exporting in YAML format")
-		return exportYAML(result.Secrets)
+		return exportYAML(w, secrets)
 	case "env":
 		logger.Debug("Exporting in ENV format")
-		return exportEnv(result.Secrets)
+		return exportEnv(w, secrets)
+	case formatEnvExport:
+		logger.Debug("Exporting in ENV format with export statements")
+		return exportEnvExport(w, secrets)
 	case "csv":
 		logger.Debug("Exporting in CSV format")
-		return exportCSV(result.Secrets)
+		return exportCSV(w, secrets, sources)
+	case formatProperties:
+		logger.Debug("Exporting in properties format")
+		return exportProperties(w, secrets)
+	case formatTOML:
+		logger.Debug("Exporting in TOML format")
+		return exportTOML(w, secrets)
 	default:
 		logger.Debug("Unsupported format requested: %s", format)
 		return fmt.Errorf("unsupported format: %s", format)
 	}
 }
 
-func exportJSON(secrets providers.SecretMap) error {
+// filterSecrets keeps only the keys listed in only (when set) and drops the keys listed in exclude
+func filterSecrets(secrets providers.SecretMap, only, exclude []string) providers.SecretMap {
+	if len(only) == 0 && len(exclude) == 0 {
+		return secrets
+	}
+
+	excluded := make(map[string]bool, len(exclude))
+	for _, key := range exclude {
+		excluded[key] = true
+	}
+
+	filtered := make(providers.SecretMap)
+	if len(only) > 0 {
+		for _, key := range only {
+			value, exists := secrets[key]
+			if !exists {
+				logger.Debug("Requested key '%s' was not resolved by any provider", key)
+				continue
+			}
+			if !excluded[key] {
+				filtered[key] = value
+			}
+		}
+	} else {
+		for key, value := range secrets {
+			if !excluded[key] {
+				filtered[key] = value
+			}
+		}
+	}
+
+	logger.Debug("Filtered secrets from %d to %d keys", len(secrets), len(filtered))
+	return filtered
+}
+
+// writeOutput writes data to w, or atomically to path with owner-only permissions when set.
+// Existing files readable by group or others may have leaked secrets already, so they are
+// reported, or refused with --strict.
+func writeOutput(w io.Writer, path string, data []byte) error {
+	if path == "" {
+		if _, err := w.Write(data); err != nil {
+			return fmt.Errorf("failed to write output: %w", err)
+		}
+		return nil
+	}
+
+	if err := checkPermissions([]string{path}); err != nil {
+		return err
+	}
+	logger.Debug("Writing %d bytes to %s", len(data), path)
+	if err := writeFileAtomic(path, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+	logger.Verbose("Wrote secrets to %s", path)
+	return nil
+}
+
+func exportJSON(w io.Writer, secrets providers.SecretMap) error {
 	output, err := json.MarshalIndent(secrets, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal JSON: %w", err)
 	}
-	fmt.Println(string(output))
+	fmt.Fprintln(w, string(output))
 	return nil
 }
 
-func exportYAML(secrets providers.SecretMap) error {
-	output, err := yaml.Marshal(secrets)
+// exportJSONPatch writes the RFC 7386 merge patch that turns the JSON document base into
+// secrets: changed and new keys are set, keys missing from secrets are set to null. A base
+// that is not an object is replaced as a whole, as the RFC merges an object patch into {}.
+func exportJSONPatch(w io.Writer, base []byte, secrets providers.SecretMap) error {
+	var document any
+	if err := json.Unmarshal(base, &document); err != nil {
+		return fmt.Errorf("failed to parse base document: %w", err)
+	}
+	current, _ := document.(map[string]any)
+
+	patch := make(map[string]any, len(secrets))
+	for key := range current {
+		if _, ok := secrets[key]; !ok {
+			patch[key] = nil
+		}
+	}
+	for key, value := range secrets {
+		if existing, ok := current[key].(string); ok && existing == value {
+			continue
+		}
+		patch[key] = value
+	}
+	logger.Debug("Merge patch has %d entries against %d base keys", len(patch), len(current))
+
+	output, err := json.MarshalIndent(patch, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+	fmt.Fprintln(w, string(output))
+	return nil
+}
+
+// exportStatus is the document of export json --with-status
+type exportStatus struct {
+	Complete bool                    `json:"complete"` // No variable is missing
+	Secrets  providers.SecretMap     `json:"secrets"`
+	Sources  map[string]exportSource `json:"sources"`
+	Missing  []missingEntry          `json:"missing"`
+}
+
+// exportSource is the provider that supplied a key in the status document
+type exportSource struct {
+	Provider string `json:"provider"`
+	Kind     string `json:"kind"`
+	MapID    string `json:"map_id"`
+}
+
+// exportJSONWithStatus writes secrets with their sources and the missing variables of
+// result. Missing variables mapping to keys --only or --exclude drop are left out.
+func exportJSONWithStatus(w io.Writer, secrets providers.SecretMap, result *providers.CollectionResult) error {
+	status := exportStatus{Secrets: secrets, Sources: make(map[string]exportSource, len(secrets)), Missing: []missingEntry{}}
+	for key := range secrets {
+		source := result.Sources[key]
+		status.Sources[key] = exportSource{Provider: source.Provider, Kind: source.Kind, MapID: source.MapID}
+	}
+
+	excluded := make(map[string]bool, len(exportExclude))
+	for _, key := range exportExclude {
+		excluded[key] = true
+	}
+	for _, entry := range missingEntries(result.MissingVars) {
+		if excluded[entry.MapsTo] || (len(exportOnly) > 0 && !slices.Contains(exportOnly, entry.MapsTo)) {
+			continue
+		}
+		status.Missing = append(status.Missing, entry)
+	}
+	status.Complete = len(status.Missing) == 0
+
+	output, err := json.MarshalIndent(status, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+	fmt.Fprintln(w, string(output))
+	return nil
+}
+
+// exportYAML writes secrets as a YAML mapping. Values are always quoted so strings such as
+// "on", "null" or "0755" keep their type, and multiline values use literal block scalars.
+func exportYAML(w io.Writer, secrets providers.SecretMap) error {
+	keys := make([]string, 0, len(secrets))
+	for k := range secrets {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	doc := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	for _, key := range keys {
+		value := secrets[key]
+		valueNode := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value, Style: yaml.DoubleQuotedStyle}
+		// Literal blocks cannot represent a leading line break, so such values stay double-quoted
+		if strings.Contains(value, "\n") && !strings.HasPrefix(value, "\n") {
+			valueNode.Style = yaml.LiteralStyle
+		}
+		keyNode := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}
+		if !isPlainYAMLKey(key) {
+			keyNode.Style = yaml.DoubleQuotedStyle
+		}
+		doc.Content = append(doc.Content, keyNode, valueNode)
+	}
+
+	output, err := yaml.Marshal(doc)
 	if err != nil {
 		return fmt.Errorf("failed to marshal YAML: %w", err)
 	}
-	fmt.Print(string(output))
+	fmt.Fprint(w, string(output))
 	return nil
 }
 
-func exportEnv(secrets providers.SecretMap) error {
+// isPlainYAMLKey reports whether a key can be written unquoted without being read as a
+// non-string by YAML 1.1 or 1.2 parsers
+func isPlainYAMLKey(key string) bool {
+	switch strings.ToLower(key) {
+	case "", "y", "n", "yes", "no", "on", "off", "true", "false", "null", "~":
+		return false
+	}
+	for i, r := range key {
+		switch {
+		case r == '_', r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+		case r >= '0' && r <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func exportEnv(w io.Writer, secrets providers.SecretMap) error {
 	// Sort keys for consistent output
 	keys := make([]string, 0, len(secrets))
 	for k := range secrets {
@@ -123,70 +550,142 @@ func exportEnv(secrets providers.SecretMap) error {
 
 	for _, key := range keys {
 		value := secrets[key]
-		// Escape quotes and newlines for env format
+		if key == "" || strings.ContainsAny(key, "=\x00\r\n") {
+			return fmt.Errorf("cannot write %q in env format: keys must be non-empty without '=' or line breaks", key)
+		}
+		if strings.ContainsRune(value, 0) {
+			return fmt.Errorf("value of %s contains a NUL byte and cannot be written in env format", key)
+		}
+
+		// Strict env-file parsers (e.g. docker --env-file) take everything after '=' literally
+		switch {
+		case exportQuote == quoteNever:
+			if strings.ContainsAny(value, "\r\n") {
+				return fmt.Errorf("value of %s contains a newline and cannot be written without quotes", key)
+			}
+			fmt.Fprintf(w, "%s=%s\n", key, value)
+			continue
+		case exportQuote == quoteAuto && !envValueNeedsQuotes(value):
+			fmt.Fprintf(w, "%s=%s\n", key, value)
+			continue
+		}
+
+		// Escape quotes and line breaks for env format
 		value = strings.ReplaceAll(value, `\`, `\\`)
 		value = strings.ReplaceAll(value, `"`, `\"`)
 		value = strings.ReplaceAll(value, "\n", `\n`)
+		value = strings.ReplaceAll(value, "\r", `\r`)
 
-		fmt.Printf("%s=\"%s\"\n", key, value)
+		fmt.Fprintf(w, "%s=\"%s\"\n", key, value)
 	}
 	return nil
 }
 
-func exportCSV(secrets providers.SecretMap) error {
-	// Sort keys for consistent output
+// exportEnvExport writes one "export KEY=value" line per secret, sorted by key. Values are
+// single-quoted as by 'feller sh' according to the quote mode, so sourcing the file never
+// expands or runs anything inside them.
+func exportEnvExport(w io.Writer, secrets providers.SecretMap) error {
 	keys := make([]string, 0, len(secrets))
-	for k := range secrets {
-		keys = append(keys, k)
+	for key, value := range secrets {
+		if !isShellName(key) {
+			return fmt.Errorf("cannot write %q in env-export format: not a valid shell variable name", key)
+		}
+		if strings.ContainsRune(value, 0) {
+			return fmt.Errorf("value of %s contains a NUL byte and cannot be written in env-export format", key)
+		}
+		keys = append(keys, key)
 	}
 	sort.Strings(keys)
 
-	// CSV header
-	fmt.Println("key,value")
-
 	for _, key := range keys {
 		value := secrets[key]
-		// Escape quotes for CSV format
-		value = strings.ReplaceAll(value, `"`, `""`)
-
-		fmt.Printf("\"%s\",\"%s\"\n", key, value)
+		switch exportQuote {
+		case quoteNever:
+			if value != "" && quoteShellArg(value) != value {
+				return fmt.Errorf("value of %s cannot be sourced without quotes; use --quote auto", key)
+			}
+		case quoteAuto:
+			value = quoteShellArg(value)
+		default:
+			value = "'" + shellEscape(value) + "'"
+		}
+		fmt.Fprintf(w, "export %s=%s\n", key, value)
 	}
 	return nil
 }
 
-// handleMissingVariablesExport generates an error for missing environment variables during export
-func handleMissingVariablesExport(missingVars []providers.MissingVariable) error {
-	if len(missingVars) == 0 {
-		return nil
+// envValueNeedsQuotes reports whether a value cannot be represented unquoted in env format
+func envValueNeedsQuotes(value string) bool {
+	if value == "" {
+		return false
+	}
+	if strings.ContainsAny(value, "\r\n") {
+		return true
 	}
+	if strings.TrimSpace(value) != value {
+		return true
+	}
+	return value[0] == '"' || value[0] == '\''
+}
 
-	var errorMsg strings.Builder
-	errorMsg.WriteString(fmt.Sprintf("Cannot export: Missing %d required environment variable(s) in GitHub Actions:\n\n", len(missingVars)))
+func exportCSV(w io.Writer, secrets providers.SecretMap, sources map[string]providers.SecretSource) error {
+	delimiter, err := parseCSVDelimiter(csvDelimiter)
+	if err != nil {
+		return err
+	}
+	for _, column := range csvExtraColumns {
+		if column != "provider" && column != "map_id" {
+			return fmt.Errorf("unsupported csv column: %s (expected provider or map_id)", column)
+		}
+	}
 
-	// Group by provider for better organization
-	providerGroups := make(map[string][]providers.MissingVariable)
-	for _, mv := range missingVars {
-		providerGroups[mv.Provider] = append(providerGroups[mv.Provider], mv)
+	// Sort keys for consistent output
+	keys := make([]string, 0, len(secrets))
+	for k := range secrets {
+		keys = append(keys, k)
 	}
+	sort.Strings(keys)
 
-	for provider, vars := range providerGroups {
-		errorMsg.WriteString(fmt.Sprintf("Provider '%s':\n", provider))
-		for _, mv := range vars {
-			errorMsg.WriteString(fmt.Sprintf("  • %s (maps to: %s)\n", mv.VariableName, mv.MappedTo))
+	writer := csv.NewWriter(w)
+	writer.Comma = delimiter
+
+	if !csvNoHeader {
+		header := append([]string{"key", "value"}, csvExtraColumns...)
+		if err := writer.Write(header); err != nil {
+			return fmt.Errorf("failed to write CSV header: %w", err)
+		}
+	}
+
+	for _, key := range keys {
+		record := []string{key, secrets[key]}
+		source := sources[key]
+		for _, column := range csvExtraColumns {
+			if column == "provider" {
+				record = append(record, source.Provider)
+			} else {
+				record = append(record, source.MapID)
+			}
+		}
+		if err := writer.Write(record); err != nil {
+			return fmt.Errorf("failed to write CSV record for %s: %w", key, err)
 		}
-		errorMsg.WriteString("\n")
 	}
 
-	errorMsg.WriteString("To fix this, add the missing environment variables to your GitHub Actions workflow:\n\n")
-	errorMsg.WriteString("```yaml\n")
-	errorMsg.WriteString("- name: Export with secrets\n")
-	errorMsg.WriteString("  env:\n")
-	for _, mv := range missingVars {
-		errorMsg.WriteString(fmt.Sprintf("    %s: ${{ secrets.%s }}\n", mv.VariableName, mv.VariableName))
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to write CSV: %w", err)
 	}
-	errorMsg.WriteString("  run: feller export json\n")
-	errorMsg.WriteString("```\n\n")
-	errorMsg.WriteString("Or use --silent flag to export only available secrets.")
+	return nil
+}
 
-	return errors.New(errorMsg.String())
+// parseCSVDelimiter validates a single-character CSV delimiter; "\t" is accepted for tabs
+func parseCSVDelimiter(delimiter string) (rune, error) {
+	if delimiter == `\t` {
+		return '\t', nil
+	}
+	runes := []rune(delimiter)
+	if len(runes) != 1 || runes[0] == '"' || runes[0] == '\r' || runes[0] == '\n' {
+		return 0, fmt.Errorf("invalid csv delimiter: %q (must be a single character other than a quote or newline)", delimiter)
+	}
+	return runes[0], nil
 }
@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/containifyci/feller/pkg/providers"
+)
+
+// tomlBareKey matches the keys TOML allows without quotes
+var tomlBareKey = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// exportProperties writes secrets as a Java .properties file, escaped like
+// java.util.Properties.store so Properties.load reads every value back unchanged. Characters
+// outside printable ASCII are written as \uXXXX escapes, since load reads ISO-8859-1.
+func exportProperties(w io.Writer, secrets providers.SecretMap) error {
+	keys, err := sortedUTF8Keys(secrets, formatProperties)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		fmt.Fprintf(w, "%s=%s\n", escapeProperty(key, true), escapeProperty(secrets[key], false))
+	}
+	return nil
+}
+
+// escapeProperty escapes s for a .properties file. Spaces are escaped everywhere in keys, but
+// only at the start of values, where load would otherwise strip them.
+func escapeProperty(s string, key bool) string {
+	var b strings.Builder
+	for i, r := range s {
+		switch {
+		case r == ' ' && (key || i == 0):
+			b.WriteString(`\ `)
+		case r == '\\', r == '=', r == ':', r == '#', r == '!':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r == '\f':
+			b.WriteString(`\f`)
+		case r < 0x20 || r > 0x7e:
+			for _, unit := range utf16.Encode([]rune{r}) {
+				fmt.Fprintf(&b, `\u%04X`, unit)
+			}
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// exportTOML writes secrets as a TOML document with one basic string per key. Keys that are
+// not bare keys are quoted, so dotted names stay single keys instead of becoming tables.
+func exportTOML(w io.Writer, secrets providers.SecretMap) error {
+	keys, err := sortedUTF8Keys(secrets, formatTOML)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		name := key
+		if !tomlBareKey.MatchString(key) {
+			name = quoteTOML(key)
+		}
+		fmt.Fprintf(w, "%s = %s\n", name, quoteTOML(secrets[key]))
+	}
+	return nil
+}
+
+// quoteTOML returns s as a TOML basic string
+func quoteTOML(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '\r':
+			b.WriteString(`\r`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&b, `\u%04X`, r)
+				continue
+			}
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// sortedUTF8Keys returns the keys of secrets in order, failing on keys or values that are not
+// valid UTF-8 and so cannot be represented in format
+func sortedUTF8Keys(secrets providers.SecretMap, format string) ([]string, error) {
+	keys := make([]string, 0, len(secrets))
+	for key, value := range secrets {
+		if key == "" || !utf8.ValidString(key) {
+			return nil, fmt.Errorf("cannot write %q in %s format: keys must be non-empty UTF-8", key, format)
+		}
+		if !utf8.ValidString(value) {
+			return nil, fmt.Errorf("value of %s is not valid UTF-8 and cannot be written in %s format", key, format)
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/containifyci/feller/pkg/providers"
+)
+
+func TestExportProperties(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		secrets  providers.SecretMap
+		expected string
+	}{
+		{
+			name:     "plain values",
+			secrets:  providers.SecretMap{"db.url": "jdbc:postgresql://db/app", "API_KEY": "abc"},
+			expected: "API_KEY=abc\ndb.url=jdbc\\:postgresql\\://db/app\n",
+		},
+		{
+			name:     "spaces in keys and leading spaces in values",
+			secrets:  providers.SecretMap{"my key": "  two words "},
+			expected: "my\\ key=\\  two words \n",
+		},
+		{
+			name:     "separators, comments and backslashes",
+			secrets:  providers.SecretMap{"#A=B": `!x\y`},
+			expected: "\\#A\\=B=\\!x\\\\y\n",
+		},
+		{
+			name:     "control characters and non-ASCII",
+			secrets:  providers.SecretMap{"K": "a\tb\nc\r\fd\x01é😀"},
+			expected: "K=a\\tb\\nc\\r\\fd\\u0001\\u00E9\\uD83D\\uDE00\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var buf bytes.Buffer
+			if err := writeExport(&buf, formatProperties, tt.secrets, nil); err != nil {
+				t.Fatalf("writeExport() unexpected error = %v", err)
+			}
+			if buf.String() != tt.expected {
+				t.Errorf("writeExport() = %q, want %q", buf.String(), tt.expected)
+			}
+		})
+	}
+}
+
+func TestExportTOML(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		secrets  providers.SecretMap
+		expected string
+	}{
+		{
+			name:     "bare keys",
+			secrets:  providers.SecretMap{"API_KEY": "abc", "db-url": "postgres://db"},
+			expected: "API_KEY = \"abc\"\ndb-url = \"postgres://db\"\n",
+		},
+		{
+			name:     "quoted keys",
+			secrets:  providers.SecretMap{"db.url": "x", "my key": "y", `a"b`: "z"},
+			expected: "\"a\\\"b\" = \"z\"\n\"db.url\" = \"x\"\n\"my key\" = \"y\"\n",
+		},
+		{
+			name:     "escapes",
+			secrets:  providers.SecretMap{"K": "q\"\\\b\t\n\f\r\x01\x7fé"},
+			expected: "K = \"q\\\"\\\\\\b\\t\\n\\f\\r\\u0001\\u007Fé\"\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var buf bytes.Buffer
+			if err := writeExport(&buf, formatTOML, tt.secrets, nil); err != nil {
+				t.Fatalf("writeExport() unexpected error = %v", err)
+			}
+			if buf.String() != tt.expected {
+				t.Errorf("writeExport() = %q, want %q", buf.String(), tt.expected)
+			}
+		})
+	}
+}
+
+func TestExportConfigFormatsRejectInvalidUTF8(t *testing.T) {
+	t.Parallel()
+	for _, format := range []string{formatProperties, formatTOML} {
+		for _, secrets := range []providers.SecretMap{{"": "x"}, {"K\xff": "x"}, {"K": "a\xffb"}} {
+			err := writeExport(&bytes.Buffer{}, format, secrets, nil)
+			if err == nil || !strings.Contains(err.Error(), format+" format") {
+				t.Errorf("writeExport(%s, %q) error = %v", format, secrets, err)
+			}
+		}
+	}
+}
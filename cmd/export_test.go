@@ -4,9 +4,15 @@ import (
 	"bytes"
 	"encoding/json"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"runtime"
 	"strings"
 	"testing"
 
+	"github.com/containifyci/feller/pkg/config"
+	"github.com/containifyci/feller/pkg/fellertest"
 	"github.com/containifyci/feller/pkg/providers"
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v3"
@@ -28,8 +34,8 @@ const (
 `
 )
 
-//nolint:paralleltest // Cannot run in parallel due to stdout manipulation
 func TestExportJSON(t *testing.T) {
+	t.Parallel()
 	tests := []struct {
 		secrets  providers.SecretMap
 		validate func(t *testing.T, output string)
@@ -97,20 +103,9 @@ func TestExportJSON(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			//nolint:paralleltest // Cannot run in parallel due to stdout manipulation
-			// Capture stdout
-			oldStdout := os.Stdout
-			r, w, _ := os.Pipe()
-			os.Stdout = w
-
-			err := exportJSON(tt.secrets)
-
-			// Restore stdout and read output
-			w.Close()
-			os.Stdout = oldStdout
-
+			t.Parallel()
 			var buf bytes.Buffer
-			buf.ReadFrom(r)
+			err := exportJSON(&buf, tt.secrets)
 			output := buf.String()
 
 			if tt.wantErr {
@@ -134,8 +129,8 @@ func TestExportJSON(t *testing.T) {
 	}
 }
 
-//nolint:paralleltest // Cannot run in parallel due to stdout manipulation
 func TestExportYAML(t *testing.T) {
+	t.Parallel()
 	tests := []struct {
 		secrets  providers.SecretMap
 		validate func(t *testing.T, output string)
@@ -203,20 +198,9 @@ func TestExportYAML(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			//nolint:paralleltest // Cannot run in parallel due to stdout manipulation
-			// Capture stdout
-			oldStdout := os.Stdout
-			r, w, _ := os.Pipe()
-			os.Stdout = w
-
-			err := exportYAML(tt.secrets)
-
-			// Restore stdout and read output
-			w.Close()
-			os.Stdout = oldStdout
-
+			t.Parallel()
 			var buf bytes.Buffer
-			buf.ReadFrom(r)
+			err := exportYAML(&buf, tt.secrets)
 			output := buf.String()
 
 			if tt.wantErr {
@@ -238,7 +222,7 @@ func TestExportYAML(t *testing.T) {
 	}
 }
 
-//nolint:paralleltest // Cannot run in parallel due to stdout manipulation
+//nolint:paralleltest // reads the global quote mode flag
 func TestExportEnv(t *testing.T) {
 	tests := []struct {
 		secrets   providers.SecretMap
@@ -282,20 +266,8 @@ func TestExportEnv(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			//nolint:paralleltest // Cannot run in parallel due to stdout manipulation
-			// Capture stdout
-			oldStdout := os.Stdout
-			r, w, _ := os.Pipe()
-			os.Stdout = w
-
-			err := exportEnv(tt.secrets)
-
-			// Restore stdout and read output
-			w.Close()
-			os.Stdout = oldStdout
-
 			var buf bytes.Buffer
-			buf.ReadFrom(r)
+			err := exportEnv(&buf, tt.secrets)
 			output := buf.String()
 
 			if tt.wantErr {
@@ -339,7 +311,7 @@ func TestExportEnv(t *testing.T) {
 	}
 }
 
-//nolint:paralleltest // Cannot run in parallel due to stdout manipulation
+//nolint:paralleltest // reads the global csv flag variables
 func TestExportCSV(t *testing.T) {
 	tests := []struct {
 		secrets   providers.SecretMap
@@ -356,8 +328,8 @@ func TestExportCSV(t *testing.T) {
 			wantErr: false,
 			wantLines: []string{
 				"key,value",
-				`"KEY1","value1"`,
-				`"KEY2","value2"`,
+				"KEY1,value1",
+				"KEY2,value2",
 			},
 		},
 		{
@@ -376,27 +348,15 @@ func TestExportCSV(t *testing.T) {
 			wantErr: false,
 			wantLines: []string{
 				"key,value",
-				`"KEY_WITH_QUOTES","value with ""quotes"""`,
+				`KEY_WITH_QUOTES,"value with ""quotes"""`,
 			},
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			//nolint:paralleltest // Cannot run in parallel due to stdout manipulation
-			// Capture stdout
-			oldStdout := os.Stdout
-			r, w, _ := os.Pipe()
-			os.Stdout = w
-
-			err := exportCSV(tt.secrets)
-
-			// Restore stdout and read output
-			w.Close()
-			os.Stdout = oldStdout
-
 			var buf bytes.Buffer
-			buf.ReadFrom(r)
+			err := exportCSV(&buf, tt.secrets, nil)
 			output := buf.String()
 
 			if tt.wantErr {
@@ -504,22 +464,22 @@ func TestHandleMissingVariablesExport(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
-			err := handleMissingVariablesExport(tt.missingVars)
+			err := missingVariablesError(tt.missingVars, missingExport, config.Messages{})
 
 			if tt.wantErr {
 				if err == nil {
-					t.Errorf("handleMissingVariablesExport() expected error but got none")
+					t.Errorf("missingVariablesError() expected error but got none")
 					return
 				}
 
 				errMsg := err.Error()
 				for _, contains := range tt.errContains {
 					if !strings.Contains(errMsg, contains) {
-						t.Errorf("handleMissingVariablesExport() error should contain %q, got: %v", contains, err)
+						t.Errorf("missingVariablesError() error should contain %q, got: %v", contains, err)
 					}
 				}
 			} else if err != nil {
-				t.Errorf("handleMissingVariablesExport() unexpected error = %v", err)
+				t.Errorf("missingVariablesError() unexpected error = %v", err)
 			}
 		})
 	}
@@ -748,23 +708,11 @@ func TestExportSecrets(t *testing.T) {
 			// Set silent mode
 			silent = tt.silent
 
-			// Capture stdout
-			oldStdout := os.Stdout
-			r, w, _ := os.Pipe()
-			os.Stdout = w
-
-			// Create a cobra command for testing
+			var buf bytes.Buffer
 			cmd := &cobra.Command{}
+			cmd.SetOut(&buf)
 
-			// Run the function
 			err := exportSecrets(cmd, tt.args)
-
-			// Restore stdout and read output
-			w.Close()
-			os.Stdout = oldStdout
-
-			var buf bytes.Buffer
-			buf.ReadFrom(r)
 			output := buf.String()
 
 			if tt.wantErr {
@@ -789,3 +737,583 @@ func TestExportSecrets(t *testing.T) {
 		})
 	}
 }
+
+func TestFilterSecrets(t *testing.T) {
+	t.Parallel()
+	secrets := providers.SecretMap{"A": "1", "B": "2", "C": "3"}
+
+	tests := []struct {
+		name     string
+		only     []string
+		exclude  []string
+		expected providers.SecretMap
+	}{
+		{name: "no filters", expected: secrets},
+		{name: "only", only: []string{"A", "C", "MISSING"}, expected: providers.SecretMap{"A": "1", "C": "3"}},
+		{name: "exclude", exclude: []string{"B"}, expected: providers.SecretMap{"A": "1", "C": "3"}},
+		{name: "only and exclude", only: []string{"A", "B"}, exclude: []string{"B"}, expected: providers.SecretMap{"A": "1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got := filterSecrets(secrets, tt.only, tt.exclude)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("filterSecrets() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestWriteOutputFile(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "secrets.env")
+
+	if err := writeOutput(&bytes.Buffer{}, path, []byte("KEY=value\n")); err != nil {
+		t.Fatalf("writeOutput() unexpected error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read output file: %v", err)
+	}
+	if string(data) != "KEY=value\n" {
+		t.Errorf("writeOutput() wrote %q, want %q", string(data), "KEY=value\n")
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Failed to stat output file: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("writeOutput() file permissions = %o, want 600", perm)
+	}
+
+	// Overwriting replaces the file instead of keeping its permissions
+	if runtime.GOOS != "windows" {
+		if err := os.Chmod(path, 0o644); err != nil {
+			t.Fatalf("Failed to chmod output file: %v", err)
+		}
+		if err := writeOutput(&bytes.Buffer{}, path, []byte("KEY=new\n")); err != nil {
+			t.Fatalf("writeOutput() overwriting error = %v", err)
+		}
+		if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o600 {
+			t.Errorf("writeOutput() overwritten file = %v, %v, want permissions 600", info, err)
+		}
+	}
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil || len(entries) != 1 {
+		t.Errorf("writeOutput() left %v, %v, expected only the output file", entries, err)
+	}
+}
+
+//nolint:paralleltest // modifies global flag variables
+func TestExportEnvQuoteModes(t *testing.T) {
+	originalQuote := exportQuote
+	t.Cleanup(func() {
+		exportQuote = originalQuote
+	})
+
+	secrets := providers.SecretMap{
+		"A": "plain value",
+		"B": `say "hi"`,
+		"C": " padded",
+		"D": `"quoted"`,
+	}
+
+	tests := []struct {
+		name     string
+		mode     string
+		expected string
+	}{
+		{
+			name:     "always",
+			mode:     quoteAlways,
+			expected: "A=\"plain value\"\nB=\"say \\\"hi\\\"\"\nC=\" padded\"\nD=\"\\\"quoted\\\"\"\n",
+		},
+		{
+			name:     "never",
+			mode:     quoteNever,
+			expected: "A=plain value\nB=say \"hi\"\nC= padded\nD=\"quoted\"\n",
+		},
+		{
+			name:     "auto",
+			mode:     quoteAuto,
+			expected: "A=plain value\nB=say \"hi\"\nC=\" padded\"\nD=\"\\\"quoted\\\"\"\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exportQuote = tt.mode
+			var buf bytes.Buffer
+			if err := exportEnv(&buf, secrets); err != nil {
+				t.Fatalf("exportEnv() unexpected error = %v", err)
+			}
+			if buf.String() != tt.expected {
+				t.Errorf("exportEnv() = %q, want %q", buf.String(), tt.expected)
+			}
+		})
+	}
+
+	t.Run("never rejects newlines", func(t *testing.T) {
+		exportQuote = quoteNever
+		var buf bytes.Buffer
+		err := exportEnv(&buf, providers.SecretMap{"MULTI": "line1\nline2"})
+		if err == nil || !strings.Contains(err.Error(), "cannot be written without quotes") {
+			t.Errorf("exportEnv() error = %v, expected newline error", err)
+		}
+	})
+
+	t.Run("auto quotes newlines", func(t *testing.T) {
+		exportQuote = quoteAuto
+		var buf bytes.Buffer
+		if err := exportEnv(&buf, providers.SecretMap{"MULTI": "line1\nline2"}); err != nil {
+			t.Fatalf("exportEnv() unexpected error = %v", err)
+		}
+		if buf.String() != "MULTI=\"line1\\nline2\"\n" {
+			t.Errorf("exportEnv() = %q, want quoted multiline value", buf.String())
+		}
+	})
+
+	t.Run("carriage returns are escaped", func(t *testing.T) {
+		exportQuote = quoteAlways
+		var buf bytes.Buffer
+		if err := exportEnv(&buf, providers.SecretMap{"CRLF": "a\r\nb"}); err != nil {
+			t.Fatalf("exportEnv() unexpected error = %v", err)
+		}
+		if buf.String() != "CRLF=\"a\\r\\nb\"\n" {
+			t.Errorf("exportEnv() = %q, want escaped carriage return", buf.String())
+		}
+	})
+
+	t.Run("invalid keys and nul values", func(t *testing.T) {
+		exportQuote = quoteAuto
+		for _, secrets := range []providers.SecretMap{{"": "x"}, {"A=B": "x"}, {"A\nB": "x"}, {"NUL": "a\x00b"}} {
+			if err := exportEnv(&bytes.Buffer{}, secrets); err == nil {
+				t.Errorf("exportEnv(%q) expected error but got none", secrets)
+			}
+		}
+	})
+}
+
+//nolint:paralleltest // modifies global flag variables
+func TestExportEnvExport(t *testing.T) {
+	originalQuote := exportQuote
+	t.Cleanup(func() {
+		exportQuote = originalQuote
+	})
+
+	secrets := providers.SecretMap{
+		"A": "plain",
+		"B": "it's $HOME `id`",
+		"C": "",
+	}
+
+	tests := []struct {
+		name     string
+		mode     string
+		expected string
+	}{
+		{
+			name:     "always",
+			mode:     quoteAlways,
+			expected: "export A='plain'\nexport B='it'\\''s $HOME `id`'\nexport C=''\n",
+		},
+		{
+			name:     "auto",
+			mode:     quoteAuto,
+			expected: "export A=plain\nexport B='it'\\''s $HOME `id`'\nexport C=''\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exportQuote = tt.mode
+			var buf bytes.Buffer
+			if err := writeExport(&buf, formatEnvExport, secrets, nil); err != nil {
+				t.Fatalf("writeExport() unexpected error = %v", err)
+			}
+			if buf.String() != tt.expected {
+				t.Errorf("writeExport() = %q, want %q", buf.String(), tt.expected)
+			}
+		})
+	}
+
+	t.Run("never", func(t *testing.T) {
+		exportQuote = quoteNever
+		var buf bytes.Buffer
+		if err := exportEnvExport(&buf, providers.SecretMap{"A": "plain", "C": ""}); err != nil {
+			t.Fatalf("exportEnvExport() unexpected error = %v", err)
+		}
+		if buf.String() != "export A=plain\nexport C=\n" {
+			t.Errorf("exportEnvExport() = %q, want unquoted values", buf.String())
+		}
+		err := exportEnvExport(&buf, providers.SecretMap{"B": "two words"})
+		if err == nil || !strings.Contains(err.Error(), "cannot be sourced without quotes") {
+			t.Errorf("exportEnvExport() error = %v, expected quoting error", err)
+		}
+	})
+
+	t.Run("sourced by sh", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("uses sh")
+		}
+		exportQuote = quoteAuto
+		path := filepath.Join(t.TempDir(), "secrets.sh")
+		var buf bytes.Buffer
+		if err := exportEnvExport(&buf, secrets); err != nil {
+			t.Fatalf("exportEnvExport() unexpected error = %v", err)
+		}
+		if err := os.WriteFile(path, buf.Bytes(), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		// #nosec G204 - test runs a fixed script
+		out, err := exec.Command("/bin/sh", "-c", `. "$1" && printf '%s|%s|%s' "$A" "$B" "$C"`, "sh", path).Output()
+		if err != nil {
+			t.Fatalf("sourcing failed: %v", err)
+		}
+		if string(out) != "plain|it's $HOME `id`|" {
+			t.Errorf("sourced values = %q", out)
+		}
+	})
+
+	t.Run("invalid keys and nul values", func(t *testing.T) {
+		exportQuote = quoteAlways
+		for _, secrets := range []providers.SecretMap{{"": "x"}, {"A-B": "x"}, {"1A": "x"}, {"NUL": "a\x00b"}} {
+			if err := exportEnvExport(&bytes.Buffer{}, secrets); err == nil {
+				t.Errorf("exportEnvExport(%q) expected error but got none", secrets)
+			}
+		}
+	})
+}
+
+//nolint:paralleltest // modifies global flag variables
+func TestResolveQuoteMode(t *testing.T) {
+	originalQuote := exportQuote
+	originalNoQuotes := exportNoQuotes
+	t.Cleanup(func() {
+		exportQuote = originalQuote
+		exportNoQuotes = originalNoQuotes
+	})
+
+	tests := []struct {
+		name         string
+		defaultQuote string
+		errContains  string
+		expected     string
+		args         []string
+		noQuotes     bool
+		nilCommand   bool
+		wantErr      bool
+	}{
+		{name: "nil command defaults to always", nilCommand: true, expected: quoteAlways},
+		{name: "command default", defaultQuote: quoteAuto, expected: quoteAuto},
+		{name: "explicit mode", defaultQuote: quoteAuto, args: []string{"--quote", "never"}, expected: quoteNever},
+		{name: "no-quotes shorthand", defaultQuote: quoteAlways, noQuotes: true, expected: quoteNever},
+		{
+			name:         "no-quotes conflicts with explicit mode",
+			defaultQuote: quoteAuto,
+			args:         []string{"--quote", "always"},
+			noQuotes:     true,
+			wantErr:      true,
+			errContains:  "--no-quotes conflicts with --quote always",
+		},
+		{
+			name:         "invalid mode",
+			defaultQuote: quoteAuto,
+			args:         []string{"--quote", "sometimes"},
+			wantErr:      true,
+			errContains:  "unsupported quote mode: sometimes",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cmd *cobra.Command
+			if !tt.nilCommand {
+				cmd = &cobra.Command{}
+				addExportFlags(cmd, tt.defaultQuote)
+				if err := cmd.Flags().Parse(tt.args); err != nil {
+					t.Fatalf("Failed to parse flags: %v", err)
+				}
+			}
+			exportNoQuotes = tt.noQuotes
+
+			err := resolveQuoteMode(cmd)
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Errorf("resolveQuoteMode() error = %v, expected to contain %q", err, tt.errContains)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveQuoteMode() unexpected error = %v", err)
+			}
+			if exportQuote != tt.expected {
+				t.Errorf("resolveQuoteMode() mode = %q, want %q", exportQuote, tt.expected)
+			}
+		})
+	}
+}
+
+//nolint:paralleltest // modifies global flag variables
+func TestExportCSVOptions(t *testing.T) {
+	originalDelimiter := csvDelimiter
+	originalNoHeader := csvNoHeader
+	originalColumns := csvExtraColumns
+	t.Cleanup(func() {
+		csvDelimiter = originalDelimiter
+		csvNoHeader = originalNoHeader
+		csvExtraColumns = originalColumns
+	})
+
+	secrets := providers.SecretMap{
+		"DB_URL": "postgres://u:p@host/db?a=1,b=2",
+		"MULTI":  "line1\nline2",
+	}
+	sources := map[string]providers.SecretSource{
+		"DB_URL": {Provider: "gha", Kind: "google_secretmanager", MapID: "ci"},
+		"MULTI":  {Provider: "local", Kind: "dotenv", MapID: "dev"},
+	}
+
+	tests := []struct {
+		name        string
+		delimiter   string
+		errContains string
+		expected    string
+		columns     []string
+		noHeader    bool
+		wantErr     bool
+	}{
+		{
+			name:      "default delimiter escapes commas and newlines",
+			delimiter: ",",
+			expected:  "key,value\nDB_URL,\"postgres://u:p@host/db?a=1,b=2\"\nMULTI,\"line1\nline2\"\n",
+		},
+		{
+			name:      "custom delimiter without header",
+			delimiter: ";",
+			noHeader:  true,
+			expected:  "DB_URL;postgres://u:p@host/db?a=1,b=2\nMULTI;\"line1\nline2\"\n",
+		},
+		{
+			name:      "tab delimiter with extra columns",
+			delimiter: `\t`,
+			columns:   []string{"provider", "map_id"},
+			expected:  "key\tvalue\tprovider\tmap_id\nDB_URL\tpostgres://u:p@host/db?a=1,b=2\tgha\tci\nMULTI\t\"line1\nline2\"\tlocal\tdev\n",
+		},
+		{
+			name:        "invalid delimiter",
+			delimiter:   "::",
+			wantErr:     true,
+			errContains: "invalid csv delimiter",
+		},
+		{
+			name:        "unknown column",
+			delimiter:   ",",
+			columns:     []string{"owner"},
+			wantErr:     true,
+			errContains: "unsupported csv column: owner",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			csvDelimiter = tt.delimiter
+			csvNoHeader = tt.noHeader
+			csvExtraColumns = tt.columns
+
+			var buf bytes.Buffer
+			err := exportCSV(&buf, secrets, sources)
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Errorf("exportCSV() error = %v, expected to contain %q", err, tt.errContains)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("exportCSV() unexpected error = %v", err)
+			}
+			if buf.String() != tt.expected {
+				t.Errorf("exportCSV() = %q, want %q", buf.String(), tt.expected)
+			}
+		})
+	}
+}
+
+func TestExportYAMLRoundTrip(t *testing.T) {
+	t.Parallel()
+	secrets := providers.SecretMap{
+		"on":          "on",
+		"NULLABLE":    "null",
+		"OCTAL":       "0755",
+		"BOOL":        "yes",
+		"EMPTY":       "",
+		"CERT":        "-----BEGIN-----\nabc\n-----END-----\n",
+		"NO_NEWLINE":  "line1\nline2",
+		"TRAILING_WS": "x  \ny",
+		"LEADING":     "\n  indented",
+		"CRLF":        "a\r\nb",
+		"1KEY":        "numeric key",
+	}
+
+	var buf bytes.Buffer
+	if err := exportYAML(&buf, secrets); err != nil {
+		t.Fatalf("exportYAML() unexpected error = %v", err)
+	}
+	output := buf.String()
+
+	var decoded map[string]any
+	if err := yaml.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("exportYAML() produced invalid YAML: %v\n%s", err, output)
+	}
+	for key, want := range secrets {
+		got, ok := decoded[key].(string)
+		if !ok || got != want {
+			t.Errorf("round trip of %q = %#v, want %q", key, decoded[key], want)
+		}
+	}
+
+	for _, want := range []string{
+		"CERT: |\n    -----BEGIN-----\n",
+		"NO_NEWLINE: |-\n    line1\n    line2\n",
+		`"on": "on"`,
+		`NULLABLE: "null"`,
+		`"1KEY": "numeric key"`,
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("exportYAML() output should contain %q, got:\n%s", want, output)
+		}
+	}
+}
+
+//nolint:paralleltest // modifies environment variables and global flag variables
+func TestExportJSONWithStatus(t *testing.T) {
+	t.Setenv("GITHUB_ACTIONS", "true")
+	originalCfg, originalExclude := cfgFile, exportExclude
+	t.Cleanup(func() { cfgFile, exportExclude, exportWithStatus = originalCfg, originalExclude, false })
+	cfg := fellertest.NewConfig().
+		Provider("set", fellertest.FakeGSM(t, map[string]string{"API_KEY": "abc"})).
+		Provider("unset", fellertest.MissingGSM(t, "DB_URL", "SKIPPED")).
+		Build()
+	cfgFile = fellertest.WriteConfig(t, cfg)
+	exportWithStatus, exportExclude = true, []string{"SKIPPED"}
+
+	var stdout bytes.Buffer
+	cmd := &cobra.Command{}
+	cmd.SetOut(&stdout)
+	if err := exportSecrets(cmd, []string{"json"}); err != nil {
+		t.Fatalf("exportSecrets() error = %v, expected missing variables in the document", err)
+	}
+	expected := `{
+  "complete": false,
+  "secrets": {
+    "API_KEY": "abc"
+  },
+  "sources": {
+    "API_KEY": {
+      "provider": "set",
+      "kind": "google_secretmanager",
+      "map_id": "fake"
+    }
+  },
+  "missing": [
+    {
+      "variable": "DB_URL",
+      "maps_to": "DB_URL",
+      "provider": "unset"
+    }
+  ]
+}
+`
+	if stdout.String() != expected {
+		t.Errorf("exportSecrets() output =\n%s\nexpected\n%s", stdout.String(), expected)
+	}
+
+	if err := exportSecrets(cmd, []string{"yaml"}); err == nil || err.Error() != "--with-status is only supported by the json format" {
+		t.Errorf("exportSecrets(yaml) error = %v", err)
+	}
+}
+
+func TestExportJSONPatch(t *testing.T) {
+	t.Parallel()
+	secrets := providers.SecretMap{"API_KEY": "new", "DB_URL": "postgres://db", "ADDED": "x"}
+	tests := []struct {
+		name     string
+		base     string
+		expected string
+	}{
+		{
+			name:     "changes against an object",
+			base:     `{"API_KEY": "old", "DB_URL": "postgres://db", "REMOVED": "y", "NESTED": {"a": 1}}`,
+			expected: "{\n  \"ADDED\": \"x\",\n  \"API_KEY\": \"new\",\n  \"NESTED\": null,\n  \"REMOVED\": null\n}\n",
+		},
+		{
+			name:     "non-string values are replaced",
+			base:     `{"API_KEY": "new", "DB_URL": 1, "ADDED": "x"}`,
+			expected: "{\n  \"DB_URL\": \"postgres://db\"\n}\n",
+		},
+		{
+			name:     "no differences",
+			base:     `{"API_KEY": "new", "DB_URL": "postgres://db", "ADDED": "x"}`,
+			expected: "{}\n",
+		},
+		{
+			name:     "base that is not an object",
+			base:     `["API_KEY"]`,
+			expected: "{\n  \"ADDED\": \"x\",\n  \"API_KEY\": \"new\",\n  \"DB_URL\": \"postgres://db\"\n}\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var buf bytes.Buffer
+			if err := exportJSONPatch(&buf, []byte(tt.base), secrets); err != nil {
+				t.Fatalf("exportJSONPatch() unexpected error = %v", err)
+			}
+			if buf.String() != tt.expected {
+				t.Errorf("exportJSONPatch() = %q, want %q", buf.String(), tt.expected)
+			}
+		})
+	}
+
+	t.Run("invalid base", func(t *testing.T) {
+		t.Parallel()
+		err := exportJSONPatch(&bytes.Buffer{}, []byte("{"), secrets)
+		if err == nil || !strings.Contains(err.Error(), "failed to parse base document") {
+			t.Errorf("exportJSONPatch() error = %v", err)
+		}
+	})
+}
+
+//nolint:paralleltest // sets environment variables and global flag variables
+func TestExportSecretsJSONPatch(t *testing.T) {
+	t.Setenv("GITHUB_ACTIONS", "true")
+	originalCfg := cfgFile
+	t.Cleanup(func() { cfgFile, exportBase = originalCfg, "" })
+	cfgFile = fellertest.WriteConfig(t, fellertest.NewConfig().Provider("gsm", fellertest.FakeGSM(t, map[string]string{"API_KEY": "abc"})).Build())
+
+	cmd := &cobra.Command{}
+	if err := exportSecrets(cmd, []string{formatJSONPatch}); err == nil || err.Error() != "the json-patch format requires --base" {
+		t.Errorf("exportSecrets() without --base error = %v", err)
+	}
+
+	exportBase = filepath.Join(t.TempDir(), "current.json")
+	if err := os.WriteFile(exportBase, []byte(`{"API_KEY": "old", "STALE": "s"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := exportSecrets(cmd, []string{"json"}); err == nil || err.Error() != "--base is only supported by the json-patch format" {
+		t.Errorf("exportSecrets(json) with --base error = %v", err)
+	}
+
+	var stdout bytes.Buffer
+	cmd.SetOut(&stdout)
+	if err := exportSecrets(cmd, []string{formatJSONPatch}); err != nil {
+		t.Fatalf("exportSecrets() error = %v", err)
+	}
+	if expected := "{\n  \"API_KEY\": \"abc\",\n  \"STALE\": null\n}\n"; stdout.String() != expected {
+		t.Errorf("exportSecrets() output = %q, want %q", stdout.String(), expected)
+	}
+}
@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/containifyci/feller/pkg/config"
+	"github.com/containifyci/feller/pkg/logger"
+)
+
+// flyTokenEnv holds the token feller writes the secrets of fly targets with
+const flyTokenEnv = "FLY_API_TOKEN"
+
+// flyTimeout bounds each request to the Fly.io API
+const flyTimeout = 30 * time.Second
+
+// flyAPI is the GraphQL endpoint of the Fly.io API, replaced in tests
+var flyAPI = "https://api.fly.io/graphql"
+
+// flyTarget is the plan target of a Fly.io app
+func flyTarget(app string) string {
+	return "fly:" + app
+}
+
+// listFlySecrets returns the names of the secrets of the app of target. Fly.io never returns
+// secret values.
+func listFlySecrets(target config.Target) (map[string]bool, error) {
+	var data struct {
+		App struct {
+			Secrets []struct {
+				Name string `json:"name"`
+			} `json:"secrets"`
+		} `json:"app"`
+	}
+	query := `query($app: String!) { app(name: $app) { secrets { name } } }`
+	if err := flyRequest(query, map[string]any{"app": target.App}, &data); err != nil {
+		return nil, err
+	}
+	existing := make(map[string]bool, len(data.App.Secrets))
+	for _, secret := range data.App.Secrets {
+		existing[secret.Name] = true
+	}
+	logger.Debug("Fly.io app %s has %d secret(s)", target.App, len(existing))
+	return existing, nil
+}
+
+// setFlySecret creates or updates the secret key of the app of target. Every change of the
+// secrets creates a release of the app, which restarts its machines.
+func setFlySecret(target config.Target, key, value string) error {
+	logger.Debug("Setting Fly.io secret %s of app %s", key, target.App)
+	query := `mutation($input: SetSecretsInput!) { setSecrets(input: $input) { app { name } } }`
+	input := map[string]any{
+		"appId":   target.App,
+		"secrets": []map[string]string{{"key": key, "value": value}},
+	}
+	if err := flyRequest(query, map[string]any{"input": input}, nil); err != nil {
+		return fmt.Errorf("failed to set Fly.io secret %s: %w", key, err)
+	}
+	return nil
+}
+
+// deleteFlySecret removes the secret key from the app of target
+func deleteFlySecret(target config.Target, key string) error {
+	logger.Debug("Deleting Fly.io secret %s of app %s", key, target.App)
+	query := `mutation($input: UnsetSecretsInput!) { unsetSecrets(input: $input) { app { name } } }`
+	input := map[string]any{"appId": target.App, "keys": []string{key}}
+	if err := flyRequest(query, map[string]any{"input": input}, nil); err != nil {
+		return fmt.Errorf("failed to delete Fly.io secret %s: %w", key, err)
+	}
+	return nil
+}
+
+// flyRequest sends a GraphQL query to the Fly.io API, decoding the data of the response into
+// out when it is not nil
+func flyRequest(query string, variables map[string]any, out any) error {
+	token := os.Getenv(flyTokenEnv)
+	if token == "" {
+		return fmt.Errorf("%s is not set, create a token with 'fly tokens create deploy'", flyTokenEnv)
+	}
+	// Tokens of 'fly tokens create' carry their scheme, older personal tokens do not
+	if !strings.HasPrefix(token, "FlyV1 ") {
+		token = "Bearer " + token
+	}
+
+	data, err := json.Marshal(map[string]any{"query": query, "variables": variables})
+	if err != nil {
+		return fmt.Errorf("failed to encode Fly.io request: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), flyTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, flyAPI, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create Fly.io request: %w", err)
+	}
+	req.Header.Set("Authorization", token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("fly.io request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// GraphQL reports failed queries in the errors of a successful response
+	var response struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	decodeErr := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&response)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 || len(response.Errors) > 0 {
+		messages := make([]string, 0, len(response.Errors))
+		for _, e := range response.Errors {
+			messages = append(messages, e.Message)
+		}
+		switch {
+		case len(messages) > 0 && resp.StatusCode < 300:
+			return fmt.Errorf("fly.io query failed: %s", strings.Join(messages, "; "))
+		case len(messages) > 0:
+			return fmt.Errorf("fly.io returned status %d: %s", resp.StatusCode, strings.Join(messages, "; "))
+		}
+		return fmt.Errorf("fly.io returned status %d", resp.StatusCode)
+	}
+	if out == nil {
+		return nil
+	}
+	if decodeErr != nil {
+		return fmt.Errorf("failed to decode Fly.io response: %w", decodeErr)
+	}
+	if err := json.Unmarshal(response.Data, out); err != nil {
+		return fmt.Errorf("failed to decode Fly.io response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,219 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"testing"
+
+	"github.com/containifyci/feller/pkg/providers"
+)
+
+// unquoteShell reverses single-quote shell quoting as produced by shellEscape, returning
+// false for input a POSIX shell would not read back as one word
+func unquoteShell(s string) (string, bool) {
+	var out strings.Builder
+	for s != "" {
+		switch {
+		case strings.HasPrefix(s, `\'`):
+			out.WriteByte('\'')
+			s = s[2:]
+		case s[0] == '\'':
+			end := strings.IndexByte(s[1:], '\'')
+			if end < 0 {
+				return "", false
+			}
+			out.WriteString(s[1 : end+1])
+			s = s[end+2:]
+		default:
+			return "", false
+		}
+	}
+	return out.String(), true
+}
+
+// unquoteEnv reverses the double-quoted env format written by exportEnv
+func unquoteEnv(s string) (string, bool) {
+	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
+		return "", false
+	}
+	var out strings.Builder
+	body := s[1 : len(s)-1]
+	for i := 0; i < len(body); i++ {
+		c := body[i]
+		if c == '"' {
+			return "", false
+		}
+		if c != '\\' {
+			out.WriteByte(c)
+			continue
+		}
+		i++
+		if i == len(body) {
+			return "", false
+		}
+		switch body[i] {
+		case 'n':
+			out.WriteByte('\n')
+		case 'r':
+			out.WriteByte('\r')
+		case '\\', '"':
+			out.WriteByte(body[i])
+		default:
+			return "", false
+		}
+	}
+	return out.String(), true
+}
+
+func FuzzShellEscape(f *testing.F) {
+	for _, seed := range []string{"", "plain", "it's", "'''", `\'`, "$(rm -rf /)", "a\nb", "\x00", "\xff\xfe"} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, value string) {
+		quoted := "'" + shellEscape(value) + "'"
+		if got, ok := unquoteShell(quoted); !ok || got != value {
+			t.Errorf("shellEscape(%q) = %s, reads back as %q (ok %v)", value, quoted, got, ok)
+		}
+
+		arg := quoteShellArg(value)
+		if value != "" && isShellWord(value) {
+			if arg != value {
+				t.Errorf("quoteShellArg(%q) = %s, want the plain word", value, arg)
+			}
+		} else if got, ok := unquoteShell(arg); !ok || got != value {
+			t.Errorf("quoteShellArg(%q) = %s, reads back as %q (ok %v)", value, arg, got, ok)
+		}
+	})
+}
+
+// isShellWord reports whether every rune of s is safe unquoted
+func isShellWord(s string) bool {
+	for _, r := range s {
+		if !isShellSafeRune(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func FuzzWriteShellExports(f *testing.F) {
+	f.Add("KEY", "value")
+	f.Add("A_1", "it's $HOME")
+	f.Add("1BAD", "x")
+	f.Add("X;rm -rf /", "x")
+	f.Add("NUL", "a\x00b")
+
+	f.Fuzz(func(t *testing.T, key, value string) {
+		var buf bytes.Buffer
+		err := writeShellExports(&buf, providers.SecretMap{key: value})
+		if !isShellName(key) || strings.ContainsRune(value, 0) {
+			if err == nil {
+				t.Errorf("writeShellExports(%q, %q) expected error but wrote %q", key, value, buf.String())
+			}
+			return
+		}
+		if err != nil {
+			t.Fatalf("writeShellExports() unexpected error = %v", err)
+		}
+
+		line, ok := strings.CutPrefix(buf.String(), "export "+key+"=")
+		if !ok {
+			t.Fatalf("writeShellExports() = %q, want export of %s", buf.String(), key)
+		}
+		if got, ok := unquoteShell(strings.TrimSuffix(line, "\n")); !ok || got != value {
+			t.Errorf("writeShellExports() value reads back as %q (ok %v), want %q", got, ok, value)
+		}
+	})
+}
+
+//nolint:paralleltest // modifies the global quote mode flag
+func FuzzExportEnv(f *testing.F) {
+	originalQuote := exportQuote
+	f.Cleanup(func() {
+		exportQuote = originalQuote
+	})
+
+	for _, seed := range []string{"value", "", "  padded ", `"quoted"`, "a\nb", "a\r\nb", `back\slash`, "\x00", "\xff"} {
+		f.Add("KEY", seed)
+	}
+	f.Add("", "x")
+	f.Add("A=B", "x")
+
+	f.Fuzz(func(t *testing.T, key, value string) {
+		invalid := key == "" || strings.ContainsAny(key, "=\x00\r\n") || strings.ContainsRune(value, 0)
+		for _, mode := range []string{quoteAlways, quoteAuto, quoteNever} {
+			exportQuote = mode
+			var buf bytes.Buffer
+			err := exportEnv(&buf, providers.SecretMap{key: value})
+			if invalid || (mode == quoteNever && strings.ContainsAny(value, "\r\n")) {
+				if err == nil {
+					t.Errorf("exportEnv(%q, %q) in %s mode expected error but wrote %q", key, value, mode, buf.String())
+				}
+				continue
+			}
+			if err != nil {
+				t.Fatalf("exportEnv() in %s mode unexpected error = %v", mode, err)
+			}
+
+			// Every entry must stay on one line so line-based parsers read it back
+			line, ok := strings.CutSuffix(buf.String(), "\n")
+			if !ok || strings.ContainsAny(line, "\r\n") {
+				t.Fatalf("exportEnv() in %s mode = %q, want a single line", mode, buf.String())
+			}
+			written, _ := strings.CutPrefix(line, key+"=")
+
+			got := written
+			if mode == quoteAlways || (mode == quoteAuto && envValueNeedsQuotes(value)) {
+				got, ok = unquoteEnv(written)
+				if !ok {
+					t.Fatalf("exportEnv() in %s mode wrote malformed quoted value %q", mode, written)
+				}
+			}
+			if got != value {
+				t.Errorf("exportEnv() in %s mode reads back as %q, want %q", mode, got, value)
+			}
+		}
+	})
+}
+
+//nolint:paralleltest // modifies the global csv flag variables
+func FuzzExportCSV(f *testing.F) {
+	originalDelimiter, originalNoHeader, originalColumns := csvDelimiter, csvNoHeader, csvExtraColumns
+	f.Cleanup(func() {
+		csvDelimiter, csvNoHeader, csvExtraColumns = originalDelimiter, originalNoHeader, originalColumns
+	})
+
+	for _, seed := range []string{"value", "", " padded", `a "quoted" value`, "a,b", "a;b", "multi\nline", "\x00", `\.`} {
+		f.Add("KEY", seed, ",")
+	}
+	f.Add("KEY", "tab\tseparated", `\t`)
+	f.Add("KEY", "semi;colon", ";")
+
+	f.Fuzz(func(t *testing.T, key, value, delimiter string) {
+		comma, err := parseCSVDelimiter(delimiter)
+		if err != nil {
+			t.Skip("invalid delimiter")
+		}
+		csvDelimiter, csvNoHeader, csvExtraColumns = delimiter, false, nil
+
+		var buf bytes.Buffer
+		if err := exportCSV(&buf, providers.SecretMap{key: value}, nil); err != nil {
+			// encoding/csv rejects delimiters it cannot write unambiguously
+			return
+		}
+
+		reader := csv.NewReader(&buf)
+		reader.Comma = comma
+		records, err := reader.ReadAll()
+		if err != nil {
+			t.Fatalf("exportCSV() output %q does not parse: %v", buf.String(), err)
+		}
+		// csv.Reader normalizes \r\n inside quoted fields to \n
+		want := [][]string{{"key", "value"}, {strings.ReplaceAll(key, "\r\n", "\n"), strings.ReplaceAll(value, "\r\n", "\n")}}
+		if len(records) != 2 || records[1][0] != want[1][0] || records[1][1] != want[1][1] {
+			t.Errorf("exportCSV() reads back as %q, want %q", records, want)
+		}
+	})
+}
@@ -0,0 +1,186 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/containifyci/feller/pkg/generate"
+	"github.com/spf13/cobra"
+)
+
+// Default lengths: characters of passwords, random bytes of hex and base64 values
+const (
+	defaultPasswordLength = 32
+	defaultBytesLength    = 32
+)
+
+// maxGenerateLength bounds --length, far above what any system accepts as a secret
+const maxGenerateLength = 4096
+
+var (
+	generateLength    lengthFlag
+	generateNoSymbols bool
+	generateURLSafe   bool
+	generateComment   string
+	generateOut       string
+)
+
+// generateCmd represents the generate command
+var generateCmd = &cobra.Command{
+	Use:   "generate",
+	Short: "Generate cryptographically secure secret values",
+	Long: `Generate secret values from the operating system's secure random source,
+instead of ad-hoc openssl one-liners. Values are printed to stdout, so they can
+be stored with other tools or captured in a variable; ssh-keypair can write
+files instead.
+
+--length is the number of characters of a password (default 32) and the
+number of random bytes of hex and base64 values (default 32), at most 4096.
+
+Examples:
+  feller generate password --length 24 --no-symbols
+  feller generate hex
+  feller generate base64 --length 48 --url
+  feller generate uuid
+  feller generate ssh-keypair --comment deploy@ci --out deploy_key`,
+}
+
+var generatePasswordCmd = &cobra.Command{
+	Use:   "password",
+	Short: "Generate a password of letters, digits and symbols",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		charset := generate.Alphanumeric
+		if !generateNoSymbols {
+			charset += generate.Symbols
+		}
+		return printGenerated(cmd.OutOrStdout(), func() (string, error) {
+			return generate.Password(lengthOr(cmd, defaultPasswordLength), charset)
+		})
+	},
+}
+
+var generateHexCmd = &cobra.Command{
+	Use:   "hex",
+	Short: "Generate random bytes encoded as hex",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		return printGenerated(cmd.OutOrStdout(), func() (string, error) {
+			return generate.Hex(lengthOr(cmd, defaultBytesLength))
+		})
+	},
+}
+
+var generateBase64Cmd = &cobra.Command{
+	Use:   "base64",
+	Short: "Generate random bytes encoded as base64",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		return printGenerated(cmd.OutOrStdout(), func() (string, error) {
+			return generate.Base64(lengthOr(cmd, defaultBytesLength), generateURLSafe)
+		})
+	},
+}
+
+var generateUUIDCmd = &cobra.Command{
+	Use:   "uuid",
+	Short: "Generate a random (version 4) UUID",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		return printGenerated(cmd.OutOrStdout(), generate.UUID)
+	},
+}
+
+var generateSSHKeyPairCmd = &cobra.Command{
+	Use:   "ssh-keypair",
+	Short: "Generate an Ed25519 SSH key pair",
+	Long: `Generate an Ed25519 SSH key pair. The private key is unencrypted, in the
+OpenSSH format. With --out the private key is written to that file (0600) and
+the public key to the file with .pub appended, neither of which may exist;
+without it both are printed, the private key first.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		return runGenerateSSHKeyPair(cmd.OutOrStdout())
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(generateCmd)
+	generateCmd.AddCommand(generatePasswordCmd, generateHexCmd, generateBase64Cmd, generateUUIDCmd, generateSSHKeyPairCmd)
+	for _, c := range []*cobra.Command{generatePasswordCmd, generateHexCmd, generateBase64Cmd} {
+		c.Flags().VarP(&generateLength, "length", "l", "Characters of a password, random bytes of hex and base64 values (default 32)")
+	}
+	generatePasswordCmd.Flags().BoolVar(&generateNoSymbols, "no-symbols", false, "Only use letters and digits")
+	generateBase64Cmd.Flags().BoolVar(&generateURLSafe, "url", false, "Use the URL-safe alphabet without padding")
+	generateSSHKeyPairCmd.Flags().StringVar(&generateComment, "comment", "", "Comment of the public key, e.g. deploy@ci")
+	generateSSHKeyPairCmd.Flags().StringVar(&generateOut, "out", "", "Write the private key to this file and the public key to FILE.pub")
+}
+
+// lengthFlag is the value of --length, which is checked against its bounds as the flags are
+// parsed, before anything is generated
+type lengthFlag int
+
+func (l *lengthFlag) String() string {
+	return strconv.Itoa(int(*l))
+}
+
+func (l *lengthFlag) Type() string {
+	return "int"
+}
+
+func (l *lengthFlag) Set(value string) error {
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("%q is not a number", value)
+	}
+	if n < 1 || n > maxGenerateLength {
+		return fmt.Errorf("must be between 1 and %d, got %d", maxGenerateLength, n)
+	}
+	*l = lengthFlag(n)
+	return nil
+}
+
+// lengthOr returns --length when given, otherwise def
+func lengthOr(cmd *cobra.Command, def int) int {
+	if cmd.Flags().Changed("length") {
+		return int(generateLength)
+	}
+	return def
+}
+
+// printGenerated prints the value produced by gen on its own line
+func printGenerated(out io.Writer, gen func() (string, error)) error {
+	value, err := gen()
+	if err != nil {
+		return err //nolint:wrapcheck // describes the invalid length or the random source
+	}
+	fmt.Fprintln(out, value)
+	return nil
+}
+
+func runGenerateSSHKeyPair(out io.Writer) error {
+	private, public, err := generate.SSHKeyPair(generateComment)
+	if err != nil {
+		return err //nolint:wrapcheck // describes the random source
+	}
+	if generateOut == "" {
+		_, err := out.Write(append(private, public...))
+		return err //nolint:wrapcheck // writing to stdout
+	}
+	// Never replace a key that may be in use
+	for _, path := range []string{generateOut, generateOut + ".pub"} {
+		if _, err := os.Stat(path); err == nil {
+			return fmt.Errorf("refusing to overwrite %s", path)
+		}
+	}
+	if err := writeFileAtomic(generateOut, private, 0o600); err != nil {
+		return err
+	}
+	if err := writeFileAtomic(generateOut+".pub", public, 0o644); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Wrote the private key to %s and the public key to %s.pub\n", generateOut, generateOut)
+	return nil
+}
@@ -0,0 +1,223 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/containifyci/feller/pkg/logger"
+	"github.com/containifyci/feller/pkg/providers"
+	"github.com/spf13/cobra"
+)
+
+// Files of GitHub Actions that expose values to the later steps of a job
+const (
+	githubEnvFile    = "GITHUB_ENV"
+	githubOutputFile = "GITHUB_OUTPUT"
+)
+
+// Destinations of feller gha-env
+const (
+	ghaToEnv    = "env"
+	ghaToOutput = "output"
+)
+
+var ghaDestinations = []string{ghaToEnv, ghaToOutput}
+
+var (
+	ghaTo     []string
+	ghaNoMask bool
+)
+
+var missingGHAEnv = providers.MissingContext{
+	Command: "gha-env",
+	Prefix:  "Cannot expose secrets to later steps: ",
+	Step:    "Expose secrets",
+	Run:     "feller gha-env",
+	Hint:    "Or use --silent flag to expose only available secrets.",
+}
+
+// ghaEnvCmd represents the gha-env command
+var ghaEnvCmd = &cobra.Command{
+	Use:   "gha-env",
+	Short: "Expose secrets to later steps of a GitHub Actions job",
+	Long: `Write the secrets to the files GitHub Actions reads after a step, so later
+steps of the job get them without evaluating any output:
+
+  env    - $GITHUB_ENV, setting environment variables of later steps (default)
+  output - $GITHUB_OUTPUT, setting outputs of the step, read as
+           ${{ steps.<id>.outputs.KEY }}
+
+Every value is written in the multiline heredoc format with a random
+delimiter, so values with line breaks or that look like other entries are
+kept as they are. Before writing, every line of every value is registered
+with ::add-mask:: so it is masked in the logs of the job; --no-mask skips that
+for values that are not secret.
+
+Examples:
+  feller gha-env
+  feller gha-env --to env,output --only DATABASE_URL,API_KEY
+
+  - id: secrets
+    run: feller gha-env --to output
+  - run: ./deploy.sh
+    env:
+      API_KEY: ${{ steps.secrets.outputs.API_KEY }}`,
+	Args: cobra.NoArgs,
+	RunE: ghaEnv,
+}
+
+func init() {
+	rootCmd.AddCommand(ghaEnvCmd)
+	ghaEnvCmd.Flags().StringSliceVar(&ghaTo, "to", []string{ghaToEnv}, "Where to write the secrets: env, output or both (comma-separated)")
+	ghaEnvCmd.Flags().BoolVar(&ghaNoMask, "no-mask", false, "Do not mask the values in the logs of the job")
+	ghaEnvCmd.Flags().StringSliceVar(&exportOnly, "only", nil, "Only expose these keys (comma-separated)")
+	ghaEnvCmd.Flags().StringSliceVar(&exportExclude, "exclude", nil, "Do not expose these keys (comma-separated)")
+	_ = ghaEnvCmd.RegisterFlagCompletionFunc("to", cobra.FixedCompletions(ghaDestinations, cobra.ShellCompDirectiveNoFileComp))
+}
+
+func ghaEnv(cmd *cobra.Command, _ []string) error {
+	files := make([]string, 0, len(ghaTo))
+	for _, to := range ghaTo {
+		if !slices.Contains(ghaDestinations, to) {
+			return fmt.Errorf("unsupported --to destination: %s (expected %s)", to, strings.Join(ghaDestinations, ", "))
+		}
+		file := githubEnvFile
+		if to == ghaToOutput {
+			file = githubOutputFile
+		}
+		if os.Getenv(file) == "" {
+			return fmt.Errorf("%s is not set, feller gha-env must run in a GitHub Actions step", file)
+		}
+		if !slices.Contains(files, file) {
+			files = append(files, file)
+		}
+	}
+	if len(files) == 0 {
+		return errors.New("--to needs at least one destination")
+	}
+
+	native, err := resolveNatively()
+	if err != nil {
+		return err
+	}
+	if !native {
+		return errors.New("feller gha-env is not supported by teller fallback mode")
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	result, err := collectSecrets(cfg)
+	if err != nil {
+		return err
+	}
+	if err := checkMissing(result, missingGHAEnv, cfg.Messages); err != nil {
+		return err
+	}
+	secrets := filterSecrets(result.Secrets, exportOnly, exportExclude)
+
+	var buf bytes.Buffer
+	if err := writeGitHubFileCommands(&buf, secrets); err != nil {
+		return err
+	}
+	// Masks must be registered before the values can reach any log
+	if !ghaNoMask {
+		writeMasks(cmd.OutOrStdout(), secrets)
+	}
+	for _, file := range files {
+		if err := appendGitHubFile(os.Getenv(file), buf.Bytes()); err != nil {
+			return fmt.Errorf("failed to write %s: %w", file, err)
+		}
+		logger.Info("Exposed %d secret(s) to later steps via %s", len(secrets), file)
+	}
+	return nil
+}
+
+// writeGitHubFileCommands writes one entry per secret, sorted by key, in the heredoc format
+// GITHUB_ENV and GITHUB_OUTPUT share:
+//
+//	KEY<<DELIMITER
+//	value
+//	DELIMITER
+//
+// Each entry has its own random delimiter that does not occur in the value. Keys must be
+// plain variable names, which are valid as environment variables and step outputs alike.
+func writeGitHubFileCommands(w io.Writer, secrets providers.SecretMap) error {
+	keys := make([]string, 0, len(secrets))
+	for key, value := range secrets {
+		if !isShellName(key) {
+			return fmt.Errorf("cannot expose %q: not a valid variable name", key)
+		}
+		if strings.ContainsRune(value, 0) {
+			return fmt.Errorf("cannot expose %s: value contains a NUL byte", key)
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := secrets[key]
+		delimiter, err := heredocDelimiter(value)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%s<<%s\n%s\n%s\n", key, delimiter, value, delimiter)
+	}
+	return nil
+}
+
+// heredocDelimiter returns a random delimiter that does not occur in value
+func heredocDelimiter(value string) (string, error) {
+	for {
+		random := make([]byte, 16)
+		if _, err := rand.Read(random); err != nil {
+			return "", fmt.Errorf("failed to generate delimiter: %w", err)
+		}
+		delimiter := "ghadelimiter_" + hex.EncodeToString(random)
+		if !strings.Contains(value, delimiter) {
+			return delimiter, nil
+		}
+	}
+}
+
+// writeMasks registers every line of the values of secrets with the runner, so it replaces
+// them with *** in the logs. Lines are masked one by one, since the runner matches masks
+// against single log lines.
+func writeMasks(w io.Writer, secrets providers.SecretMap) {
+	masked := make(map[string]bool)
+	for _, value := range secrets {
+		for line := range strings.SplitSeq(strings.ReplaceAll(value, "\r\n", "\n"), "\n") {
+			if strings.TrimSpace(line) != "" {
+				masked[line] = true
+			}
+		}
+	}
+	escape := strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A")
+	for _, line := range slices.Sorted(maps.Keys(masked)) {
+		fmt.Fprintf(w, "::add-mask::%s\n", escape.Replace(line))
+	}
+}
+
+// appendGitHubFile appends data to a file of the GitHub Actions runner
+func appendGitHubFile(path string, data []byte) error {
+	// #nosec G304 - Path is provided by the GitHub Actions runner
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err //nolint:wrapcheck // callers name the file
+	}
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err //nolint:wrapcheck // callers name the file
+}
@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/containifyci/feller/pkg/fellertest"
+	"github.com/containifyci/feller/pkg/providers"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// heredocEntry matches an entry of a GitHub Actions file, capturing the key and the value
+var heredocEntry = regexp.MustCompile(`(?s)([A-Za-z_][A-Za-z0-9_]*)<<(ghadelimiter_[0-9a-f]{32})\n(.*?)\n(ghadelimiter_[0-9a-f]{32})\n`)
+
+// parseGitHubFile returns the entries of a GitHub Actions file, failing on anything else
+func parseGitHubFile(t *testing.T, data string) map[string]string {
+	t.Helper()
+	entries := make(map[string]string)
+	rest := heredocEntry.ReplaceAllStringFunc(data, func(entry string) string {
+		match := heredocEntry.FindStringSubmatch(entry)
+		require.Equal(t, match[2], match[4], "entries end with their own delimiter")
+		entries[match[1]] = match[3]
+		return ""
+	})
+	require.Empty(t, rest)
+	return entries
+}
+
+func TestWriteGitHubFileCommands(t *testing.T) {
+	t.Parallel()
+	secrets := providers.SecretMap{"B": "line 1\nEVIL<<EOF\nline 3", "A": "plain", "EMPTY": ""}
+	var buf bytes.Buffer
+	require.NoError(t, writeGitHubFileCommands(&buf, secrets))
+	assert.Regexp(t, `^A<<`, buf.String(), "entries are sorted by key")
+	assert.Equal(t, map[string]string(secrets), parseGitHubFile(t, buf.String()))
+
+	// Every entry gets its own delimiter
+	delimiters := regexp.MustCompile(`ghadelimiter_[0-9a-f]{32}`).FindAllString(buf.String(), -1)
+	assert.Len(t, delimiters, 6)
+	assert.NotEqual(t, delimiters[0], delimiters[2])
+
+	err := writeGitHubFileCommands(&bytes.Buffer{}, providers.SecretMap{"A=B": "x"})
+	require.EqualError(t, err, `cannot expose "A=B": not a valid variable name`)
+	err = writeGitHubFileCommands(&bytes.Buffer{}, providers.SecretMap{"A": "a\x00b"})
+	require.EqualError(t, err, "cannot expose A: value contains a NUL byte")
+}
+
+func TestWriteMasks(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	writeMasks(&buf, providers.SecretMap{"A": "first\r\nsecond\n\n", "B": "50%", "C": "first", "D": "  "})
+	assert.Equal(t, "::add-mask::50%25\n::add-mask::first\n::add-mask::second\n", buf.String())
+}
+
+//nolint:paralleltest // sets environment variables and global flag variables
+func TestGHAEnv(t *testing.T) {
+	t.Setenv("GITHUB_ACTIONS", "true")
+	dir := t.TempDir()
+	envFile, outputFile := filepath.Join(dir, "env"), filepath.Join(dir, "output")
+	require.NoError(t, os.WriteFile(envFile, []byte("EARLIER=1\n"), 0o600))
+	t.Setenv(githubEnvFile, envFile)
+	t.Setenv(githubOutputFile, "")
+	t.Cleanup(func() { cfgFile, ghaTo, ghaNoMask, exportOnly, exportExclude = "", []string{ghaToEnv}, false, nil, nil })
+	cfgFile = fellertest.WriteConfig(t, fellertest.NewConfig().
+		Provider("gsm", fellertest.FakeGSM(t, map[string]string{"API_KEY": "k", "CERT": "-----BEGIN-----\nabc\n-----END-----"})).
+		Build())
+
+	run := func() (string, error) {
+		var stdout bytes.Buffer
+		cmd := &cobra.Command{}
+		cmd.SetOut(&stdout)
+		err := ghaEnv(cmd, nil)
+		return stdout.String(), err
+	}
+
+	ghaTo = []string{ghaToEnv, ghaToOutput}
+	_, err := run()
+	require.EqualError(t, err, "GITHUB_OUTPUT is not set, feller gha-env must run in a GitHub Actions step")
+
+	t.Setenv(githubOutputFile, outputFile)
+	out, err := run()
+	require.NoError(t, err)
+	assert.Equal(t, "::add-mask::-----BEGIN-----\n::add-mask::-----END-----\n::add-mask::abc\n::add-mask::k\n", out)
+	data, err := os.ReadFile(envFile)
+	require.NoError(t, err)
+	assert.Equal(t, "EARLIER=1\n", string(data[:10]), "the file is appended to")
+	expected := map[string]string{"API_KEY": "k", "CERT": "-----BEGIN-----\nabc\n-----END-----"}
+	assert.Equal(t, expected, parseGitHubFile(t, string(data[10:])))
+	data, err = os.ReadFile(outputFile)
+	require.NoError(t, err)
+	assert.Equal(t, expected, parseGitHubFile(t, string(data)))
+
+	ghaTo, ghaNoMask, exportOnly = []string{ghaToOutput}, true, []string{"API_KEY"}
+	require.NoError(t, os.Remove(outputFile))
+	out, err = run()
+	require.NoError(t, err)
+	assert.Empty(t, out)
+	data, err = os.ReadFile(outputFile)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"API_KEY": "k"}, parseGitHubFile(t, string(data)))
+
+	ghaTo = []string{"summary"}
+	_, err = run()
+	require.EqualError(t, err, "unsupported --to destination: summary (expected env, output)")
+}
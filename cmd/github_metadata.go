@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/containifyci/feller/pkg/config"
+	"github.com/containifyci/feller/pkg/logger"
+	"github.com/containifyci/feller/pkg/plan"
+	"github.com/containifyci/feller/pkg/providers"
+)
+
+// defaultMetadataVariable is the GitHub Actions variable feller records the provenance of the
+// secrets it manages in
+const defaultMetadataVariable = "FELLER_METADATA"
+
+// managedBy marks metadata written by feller
+const managedBy = "feller"
+
+// variableNamePattern matches the names GitHub accepts for Actions variables
+var variableNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// secretMetadata is the provenance of the secrets feller manages in a repository. GitHub never
+// returns secret values, so it is kept as JSON in a variable, which can be read back.
+type secretMetadata struct {
+	ManagedBy  string                      `json:"managed_by"`
+	ConfigHash string                      `json:"config_hash,omitempty"` // Of the config of the last sync
+	SyncedAt   time.Time                   `json:"synced_at"`             // Of the last sync
+	Secrets    map[string]secretProvenance `json:"secrets"`
+}
+
+// secretProvenance is where a managed secret came from and when feller last wrote it
+type secretProvenance struct {
+	Provider string    `json:"provider,omitempty"`
+	Scopes   []string  `json:"scopes"` // repository, Dependabot and environments
+	SyncedAt time.Time `json:"synced_at"`
+}
+
+// checkVariableName validates the name of the metadata variable
+func checkVariableName(name string) error {
+	if !variableNamePattern.MatchString(name) || strings.HasPrefix(strings.ToUpper(name), "GITHUB_") {
+		return fmt.Errorf("invalid variable name %q: use letters, digits and underscores, not starting with a digit or GITHUB_", name)
+	}
+	return nil
+}
+
+// readSecretMetadata returns the metadata in the variable of the repository, or nil when the
+// variable does not exist
+func readSecretMetadata(name string) (*secretMetadata, error) {
+	cmd := ghCommand("variable", "get", name, "--repo", repo)
+	output, err := cmd.Output()
+	if err != nil {
+		var exitError *exec.ExitError
+		if errors.As(err, &exitError) && strings.Contains(string(exitError.Stderr), "not found") {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read variable %s: %w", name, err)
+	}
+	return parseSecretMetadata(name, output)
+}
+
+// parseSecretMetadata decodes the value of the metadata variable, nil when it is empty
+func parseSecretMetadata(name string, value []byte) (*secretMetadata, error) {
+	if len(strings.TrimSpace(string(value))) == 0 {
+		return nil, nil
+	}
+	var metadata secretMetadata
+	if err := json.Unmarshal(value, &metadata); err != nil || metadata.ManagedBy != managedBy {
+		return nil, fmt.Errorf("variable %s does not hold feller metadata, choose another name with --metadata-variable", name)
+	}
+	if metadata.Secrets == nil {
+		metadata.Secrets = make(map[string]secretProvenance)
+	}
+	return &metadata, nil
+}
+
+// mergeSecretMetadata adds the secrets written by one sync to the previous metadata. Secrets
+// that were skipped keep their previous entry.
+func mergeSecretMetadata(previous *secretMetadata, written map[string][]string, providerOf map[string]string, configHash string, now time.Time) *secretMetadata {
+	metadata := &secretMetadata{ManagedBy: managedBy, ConfigHash: configHash, SyncedAt: now.UTC(), Secrets: make(map[string]secretProvenance)}
+	if previous != nil {
+		for key, entry := range previous.Secrets {
+			metadata.Secrets[key] = entry
+		}
+	}
+	for key, scopes := range written {
+		entry := metadata.Secrets[key]
+		for _, scope := range scopes {
+			if !slices.Contains(entry.Scopes, scope) {
+				entry.Scopes = append(entry.Scopes, scope)
+			}
+		}
+		slices.Sort(entry.Scopes)
+		entry.Provider, entry.SyncedAt = providerOf[key], now.UTC()
+		metadata.Secrets[key] = entry
+	}
+	return metadata
+}
+
+// recordSecretMetadata adds the secrets written to the repository to the metadata variable
+func recordSecretMetadata(name string, written map[string][]string) error {
+	previous, err := readSecretMetadata(name)
+	if err != nil {
+		return err
+	}
+	providerOf, configHash, err := secretProviders()
+	if err != nil {
+		return err
+	}
+	metadata := mergeSecretMetadata(previous, written, providerOf, configHash, time.Now())
+	body, err := json.Marshal(metadata)
+	if err != nil {
+		return fmt.Errorf("failed to encode metadata: %w", err)
+	}
+
+	if err := throttleGitHub(); err != nil {
+		return err
+	}
+	cmd := ghCommand("variable", "set", name, "--repo", repo, "--body", string(body))
+	if output, err := cmd.CombinedOutput(); err != nil {
+		logger.Debug("gh output: %s", string(output))
+		return fmt.Errorf("failed to set variable %s: %w", name, err)
+	}
+	logger.Verbose("Recorded the metadata of %d secret(s) in variable %s", len(written), name)
+	return nil
+}
+
+// secretProviders returns the Google Secret Manager provider of every secret name uploaded by
+// github-secret add, and the hash of the config
+func secretProviders() (map[string]string, string, error) {
+	path, err := config.ResolveConfigPath(cfgFile)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to resolve config path: %w", err)
+	}
+	// #nosec G304 - The config path is chosen by the user
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read config: %w", err)
+	}
+	sum := sha256.Sum256(data)
+
+	cfg, err := config.LoadConfig(cfgFile)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to load config: %w", err)
+	}
+	providerOf := make(map[string]string)
+	for providerName, provider := range cfg.GetProvidersByKind(providers.KindGoogleSecretManager) {
+		for _, pathMap := range provider.Maps {
+			for gsmKey := range pathMap.Keys {
+				providerOf[gsmKey] = providerName
+			}
+		}
+	}
+	return providerOf, "sha256:" + hex.EncodeToString(sum[:]), nil
+}
+
+// planSecretMetadata returns the change of the metadata variable by a sync
+func planSecretMetadata(name string) (plan.Change, error) {
+	previous, err := readSecretMetadata(name)
+	if err != nil {
+		return plan.Change{}, err
+	}
+	change := plan.Change{Action: plan.ActionUpdate, Target: githubTarget(repo), Scope: "variables", Key: name, Reason: "feller metadata"}
+	if previous == nil {
+		change.Action = plan.ActionCreate
+	}
+	return change, nil
+}
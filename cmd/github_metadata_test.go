@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/containifyci/feller/pkg/fellertest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckVariableName(t *testing.T) {
+	t.Parallel()
+	for _, name := range []string{"FELLER_METADATA", "_meta", "meta2"} {
+		assert.NoError(t, checkVariableName(name), name)
+	}
+	for _, name := range []string{"", "2META", "FELLER-METADATA", "GITHUB_META", "github_meta"} {
+		assert.Error(t, checkVariableName(name), name)
+	}
+}
+
+func TestParseSecretMetadata(t *testing.T) {
+	t.Parallel()
+	metadata, err := parseSecretMetadata("META", []byte("\n"))
+	require.NoError(t, err)
+	assert.Nil(t, metadata)
+
+	metadata, err = parseSecretMetadata("META", []byte(`{"managed_by":"feller","secrets":{"API_KEY":{"provider":"gsm","scopes":["repository"]}}}`))
+	require.NoError(t, err)
+	assert.Equal(t, "gsm", metadata.Secrets["API_KEY"].Provider)
+
+	for _, value := range []string{"plain text", `{"managed_by":"terraform"}`} {
+		_, err = parseSecretMetadata("META", []byte(value))
+		assert.EqualError(t, err, "variable META does not hold feller metadata, choose another name with --metadata-variable", value)
+	}
+}
+
+func TestMergeSecretMetadata(t *testing.T) {
+	t.Parallel()
+	before := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	now := before.Add(time.Hour)
+	previous := &secretMetadata{ManagedBy: managedBy, ConfigHash: "sha256:old", SyncedAt: before, Secrets: map[string]secretProvenance{
+		"API_KEY": {Provider: "gsm", Scopes: []string{"repository"}, SyncedAt: before},
+		"SKIPPED": {Provider: "gsm", Scopes: []string{"repository"}, SyncedAt: before},
+	}}
+
+	metadata := mergeSecretMetadata(previous, map[string][]string{
+		"API_KEY": {"Dependabot"},
+		"NEW":     {"repository"},
+	}, map[string]string{"API_KEY": "gsm", "NEW": "other"}, "sha256:new", now)
+
+	assert.Equal(t, &secretMetadata{ManagedBy: managedBy, ConfigHash: "sha256:new", SyncedAt: now, Secrets: map[string]secretProvenance{
+		"API_KEY": {Provider: "gsm", Scopes: []string{"Dependabot", "repository"}, SyncedAt: now},
+		"NEW":     {Provider: "other", Scopes: []string{"repository"}, SyncedAt: now},
+		"SKIPPED": {Provider: "gsm", Scopes: []string{"repository"}, SyncedAt: before},
+	}}, metadata)
+	// The previous metadata is not modified
+	assert.Equal(t, []string{"repository"}, previous.Secrets["API_KEY"].Scopes)
+}
+
+//nolint:paralleltest // modifies environment variables and global flag variables
+func TestRecordSecretMetadata(t *testing.T) {
+	log := fakeGH(t)
+	t.Cleanup(func() { cfgFile, repo = "", "" })
+	cfg := fellertest.NewConfig().Provider("gsm", fellertest.FakeGSM(t, map[string]string{"API_KEY": "abc"})).Build()
+	cfgFile = fellertest.WriteConfig(t, cfg)
+	repo = "owner/repo"
+
+	require.NoError(t, recordSecretMetadata("FELLER_METADATA", map[string][]string{"API_KEY": {"repository"}}))
+
+	calls, err := os.ReadFile(log)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(calls)), "\n")
+	require.Len(t, lines, 2)
+	assert.Equal(t, "variable get FELLER_METADATA --repo owner/repo", lines[0])
+	assert.Equal(t, "variable set FELLER_METADATA --repo owner/repo", lines[1])
+
+	t.Setenv("GH_FAKE_VARIABLE", "not json")
+	assert.ErrorContains(t, recordSecretMetadata("FELLER_METADATA", map[string][]string{"API_KEY": {"repository"}}), "does not hold feller metadata")
+}
+
+//nolint:paralleltest // modifies environment variables and global flag variables
+func TestListManagedSecrets(t *testing.T) {
+	fakeGH(t)
+	t.Cleanup(func() { repo, dependabot, plainOutput = "", false, false })
+	repo, dependabot, plainOutput = "owner/repo", true, true
+	t.Setenv("GH_FAKE_SECRETS", `[{"name":"API_KEY"},{"name":"MANUAL"}]`)
+	t.Setenv("GH_FAKE_VARIABLE", `{"managed_by":"feller","config_hash":"sha256:abc","synced_at":"2026-01-02T03:04:05Z",`+
+		`"secrets":{"API_KEY":{"provider":"gsm","scopes":["repository"],"synced_at":"2026-01-02T03:04:05Z"}}}`)
+
+	var out bytes.Buffer
+	require.NoError(t, listManagedSecrets(&out))
+	assert.Equal(t, "Last synced 2026-01-02T03:04:05Z from config sha256:abc\n\n"+
+		"NAME\tSCOPE\tPROVIDER\tSYNCED\n"+
+		"API_KEY\trepository\tgsm\t2026-01-02T03:04:05Z\n"+
+		"MANUAL\trepository\t-\t-\n", out.String())
+
+	t.Setenv("GH_FAKE_VARIABLE", "")
+	out.Reset()
+	require.NoError(t, listManagedSecrets(&out))
+	assert.Contains(t, out.String(), "No metadata in variable FELLER_METADATA")
+}
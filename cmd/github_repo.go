@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// detectGitHubRepo returns the GitHub repository (owner/repo) feller runs for and where it
+// was found: GITHUB_REPOSITORY in GitHub Actions, otherwise the origin remote of the git
+// repository of the working directory
+func detectGitHubRepo() (string, string, error) {
+	if repository := os.Getenv("GITHUB_REPOSITORY"); repository != "" {
+		if !isGitHubRepo(repository) {
+			return "", "", fmt.Errorf("GITHUB_REPOSITORY %q is not a GitHub repository (owner/repo)", repository)
+		}
+		return repository, "GITHUB_REPOSITORY", nil
+	}
+
+	output, err := exec.CommandContext(context.Background(), "git", "remote", "get-url", "origin").Output()
+	if err != nil {
+		return "", "", errors.New("no --repo given and no git remote origin found, pass --repo owner/repo")
+	}
+	remote := strings.TrimSpace(string(output))
+	repository, ok := parseGitHubRemote(remote, os.Getenv("GH_HOST"))
+	if !ok {
+		return "", "", fmt.Errorf("no --repo given and the git remote origin %s is not a GitHub repository, pass --repo owner/repo", remote)
+	}
+	return repository, "git remote origin", nil
+}
+
+// parseGitHubRemote returns the owner/repo of a git remote URL on github.com or on host, the
+// GitHub Enterprise host of the GitHub CLI, in HTTPS, SSH or scp-like form
+func parseGitHubRemote(remote, host string) (string, bool) {
+	var remoteHost, path string
+	if u, err := url.Parse(remote); err == nil && u.Scheme != "" && u.Host != "" {
+		remoteHost, path = u.Hostname(), u.Path
+	} else if userHost, scpPath, ok := strings.Cut(remote, ":"); ok && !strings.Contains(userHost, "/") {
+		// scp-like syntax: git@github.com:owner/repo.git
+		_, remoteHost, _ = strings.Cut(userHost, "@")
+		if remoteHost == "" {
+			remoteHost = userHost
+		}
+		path = scpPath
+	} else {
+		return "", false
+	}
+	if !strings.EqualFold(remoteHost, "github.com") && (host == "" || !strings.EqualFold(remoteHost, host)) {
+		return "", false
+	}
+
+	repository := strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+	if !isGitHubRepo(repository) {
+		return "", false
+	}
+	return repository, true
+}
+
+// isGitHubRepo reports whether repository has the form owner/repo
+func isGitHubRepo(repository string) bool {
+	owner, name, ok := strings.Cut(repository, "/")
+	return ok && owner != "" && name != "" && !strings.Contains(name, "/")
+}
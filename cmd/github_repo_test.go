@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseGitHubRemote(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		remote   string
+		host     string
+		expected string
+	}{
+		{remote: "https://github.com/owner/repo.git", expected: "owner/repo"},
+		{remote: "https://github.com/owner/repo", expected: "owner/repo"},
+		{remote: "https://token@github.com/owner/repo/", expected: "owner/repo"},
+		{remote: "git@github.com:owner/repo.git", expected: "owner/repo"},
+		{remote: "ssh://git@github.com:22/owner/repo.git", expected: "owner/repo"},
+		{remote: "git@github.example.com:team/app.git", host: "github.example.com", expected: "team/app"},
+		{remote: "git@github.example.com:team/app.git"},
+		{remote: "https://gitlab.com/owner/repo.git"},
+		{remote: "https://github.com/owner"},
+		{remote: "https://github.com/owner/repo/extra"},
+		{remote: "/srv/git/repo.git"},
+	}
+	for _, tt := range tests {
+		got, ok := parseGitHubRemote(tt.remote, tt.host)
+		assert.Equal(t, tt.expected, got, tt.remote)
+		assert.Equal(t, tt.expected != "", ok, tt.remote)
+	}
+}
+
+//nolint:paralleltest // modifies environment variables
+func TestDetectGitHubRepoFromActions(t *testing.T) {
+	t.Setenv("GITHUB_REPOSITORY", "owner/repo")
+	repository, source, err := detectGitHubRepo()
+	require.NoError(t, err)
+	assert.Equal(t, "owner/repo", repository)
+	assert.Equal(t, "GITHUB_REPOSITORY", source)
+
+	t.Setenv("GITHUB_REPOSITORY", "owner")
+	_, _, err = detectGitHubRepo()
+	assert.EqualError(t, err, `GITHUB_REPOSITORY "owner" is not a GitHub repository (owner/repo)`)
+}
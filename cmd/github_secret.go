@@ -15,10 +15,12 @@ Teller configuration with GitHub repository secrets using the GitHub CLI.
 
 Available subcommands:
   add    Add/update secrets from teller configuration to GitHub repository
+  list   List repository secrets and the provenance feller recorded for them
 
 Examples:
   feller github-secret add --repo owner/repo
-  feller github-secret add --repo owner/repo --dependabot`,
+  feller github-secret add --repo owner/repo --dependabot
+  feller github-secret list --repo owner/repo`,
 }
 
 func init() {
@@ -2,30 +2,56 @@ package cmd
 
 import (
 	"bufio"
+	"cmp"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"maps"
 	"os"
 	"os/exec"
+	"slices"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/containifyci/feller/pkg/config"
 	"github.com/containifyci/feller/pkg/logger"
+	"github.com/containifyci/feller/pkg/plan"
+	"github.com/containifyci/feller/pkg/providers"
+	"github.com/containifyci/feller/pkg/state"
 	"github.com/spf13/cobra"
 )
 
 var (
 	repo             string
 	dependabot       bool
+	environments     []string
 	dryRun           bool
 	force            bool
 	skipExisting     bool
 	confirmOverwrite bool
 
+	// Upload secrets whose value is the one uploaded last
+	uploadUnchanged bool
+
+	// Digests of the values last uploaded, nil with --upload-unchanged
+	uploads *state.Uploads
+
+	// Actions variable the provenance of the uploaded secrets is recorded in, empty for none
+	metadataVariable string
+
 	// Interactive confirmation state
 	yesToAll bool
 	noToAll  bool
+
+	// Client-side rate limiting of the GitHub API, reported with --timings
+	githubRateLimit float64
+	githubBurst     int
+	githubLimiter   *providers.Limiter
+	githubTiming    providers.Timing
+	githubTimingMu  sync.Mutex // Scopes are uploaded concurrently
 )
 
 // SecretOperationStats tracks statistics for secret operations
@@ -34,12 +60,79 @@ type SecretOperationStats struct {
 	Updated int
 	Skipped int
 	Failed  int
+
+	Unchanged int // Not uploaded, the value is the one uploaded last
+
+	Scopes map[string]*ScopeStats // Counts by scope, "repository", "Dependabot" or "environment NAME"
+
+	written map[string][]string // secret name -> scopes it was created or updated in
+}
+
+// ScopeStats counts the operations of one scope
+type ScopeStats struct {
+	Created   int `json:"created"`
+	Updated   int `json:"updated"`
+	Skipped   int `json:"skipped"`
+	Unchanged int `json:"unchanged"`
+	Failed    int `json:"failed"`
+	Deleted   int `json:"deleted,omitempty"` // Only counted by sync rollbacks
+}
+
+// String summarizes the counts, e.g. "2 created, 1 updated, 0 skipped, 0 unchanged, 0 failed"
+func (s ScopeStats) String() string {
+	return fmt.Sprintf("%d created, %d updated, %d skipped, %d unchanged, %d failed", s.Created, s.Updated, s.Skipped, s.Unchanged, s.Failed)
+}
+
+// githubRepository is a repository secrets are uploaded to, with the scopes of it they go to
+// besides its repository secrets
+type githubRepository struct {
+	Repo         string   // owner/repo
+	Dependabot   bool     // Also the Dependabot secrets
+	Environments []string // Also the secrets of these deployment environments
+	DryRun       bool     // Log the changes instead of making them
+}
+
+// flagRepository returns the repository of the --repo, --dependabot, --environment and
+// --dry-run flags
+func flagRepository() githubRepository {
+	return githubRepository{Repo: repo, Dependabot: dependabot, Environments: environments, DryRun: dryRun}
 }
 
 // ExistingSecrets represents existing secrets in GitHub
 type ExistingSecrets struct {
-	Repository map[string]bool // repository secret names -> exists
-	Dependabot map[string]bool // dependabot secret names -> exists
+	Repository   map[string]bool            // repository secret names -> exists
+	Dependabot   map[string]bool            // dependabot secret names -> exists
+	Environments map[string]map[string]bool // environment -> secret names -> exists
+}
+
+// scope returns the names of the secrets of scope
+func (e *ExistingSecrets) scope(scope string) map[string]bool {
+	if environment, ok := strings.CutPrefix(scope, environmentScopePrefix); ok {
+		return e.Environments[environment]
+	}
+	if scope == "Dependabot" {
+		return e.Dependabot
+	}
+	return e.Repository
+}
+
+// environmentScopePrefix starts the scopes of the deployment environments of the repository
+const environmentScopePrefix = "environment "
+
+// environmentScope is the scope of the secrets of a deployment environment
+func environmentScope(environment string) string {
+	return environmentScopePrefix + environment
+}
+
+// githubScopeArgs returns the arguments selecting scope in gh secret commands
+func githubScopeArgs(scope string) []string {
+	if environment, ok := strings.CutPrefix(scope, environmentScopePrefix); ok {
+		return []string{"--env", environment}
+	}
+	if scope == "Dependabot" {
+		return []string{"--app", "dependabot"}
+	}
+	return nil
 }
 
 // GitHubSecret represents a secret returned by gh secret list
@@ -70,20 +163,34 @@ this behavior with the following flags:
 
 Only one overwrite strategy can be specified at a time.
 
+Without --repo, the repository is GITHUB_REPOSITORY in GitHub Actions and
+otherwise the origin remote of the current git repository; the detected
+repository is printed before anything is uploaded.
+
 The command requires:
-- GitHub CLI (gh) to be installed and authenticated
+- GitHub CLI (gh) to be installed and authenticated, with 'gh auth login', a
+  GH_TOKEN or GITHUB_TOKEN variable, or a token passed with --token-stdin
 - Original teller binary to be available in PATH
 - Repository access permissions for the target repository
 
 Examples:
   # Basic usage (overwrites existing secrets)
   feller github-secret add --repo owner/repo
+
+  # Upload to the repository of GITHUB_REPOSITORY or the git remote origin
+  feller github-secret add
   
   # Include Dependabot secrets
   feller github-secret add --repo owner/repo --dependabot
+
+  # Also upload to the secrets of deployment environments
+  feller github-secret add --repo owner/repo --environment staging,production
   
   # Preview changes without making them
   feller github-secret add --repo owner/repo --dry-run
+
+  # Preview changes as a JSON plan for tools
+  feller github-secret add --repo owner/repo --dry-run --json
   
   # Skip existing secrets instead of overwriting
   feller github-secret add --repo owner/repo --skip-existing
@@ -92,22 +199,46 @@ Examples:
   feller github-secret add --repo owner/repo --confirm-overwrite
   
   # Force overwrite (explicit default behavior)
-  feller github-secret add --repo owner/repo --force`,
+  feller github-secret add --repo owner/repo --force
+
+  # Stay below GitHub's secondary rate limits for large configs
+  feller github-secret add --repo owner/repo --rate-limit 2 --burst 5 --timings
+
+  # Choose the secrets to upload one by one
+  feller github-secret add --repo owner/repo --interactive
+
+  # Record which secrets feller manages in the FELLER_METADATA variable
+  feller github-secret add --repo owner/repo --metadata-variable
+
+  # Authenticate with a token in a CI container without a gh login
+  echo "$DEPLOY_TOKEN" | feller github-secret add --repo owner/repo --token-stdin
+
+  # Notify a Slack channel about the changes
+  feller github-secret add --repo owner/repo --notify-webhook "$SLACK_WEBHOOK_URL" --notify-format slack`,
 	RunE: addGitHubSecrets,
 }
 
 func init() {
 	githubSecretCmd.AddCommand(githubSecretAddCmd)
-	githubSecretAddCmd.Flags().StringVarP(&repo, "repo", "r", "", "GitHub repository (owner/repo) (required)")
+	githubSecretAddCmd.Flags().StringVarP(&repo, "repo", "r", "", "GitHub repository (owner/repo) (default: GITHUB_REPOSITORY or the git remote origin)")
 	githubSecretAddCmd.Flags().BoolVar(&dependabot, "dependabot", false, "Also set secrets for Dependabot app")
+	githubSecretAddCmd.Flags().StringSliceVar(&environments, "environment", nil, "Also set secrets for these deployment environments (comma-separated)")
 	githubSecretAddCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show what would be executed without making changes")
+	githubSecretAddCmd.Flags().BoolVar(&planJSON, "json", false, "Print the plan of --dry-run as JSON")
 	githubSecretAddCmd.Flags().BoolVar(&force, "force", false, "Force overwrite existing secrets without prompting")
 	githubSecretAddCmd.Flags().BoolVar(&skipExisting, "skip-existing", false, "Skip existing secrets instead of overwriting them")
 	githubSecretAddCmd.Flags().BoolVar(&confirmOverwrite, "confirm-overwrite", false, "Prompt for confirmation before overwriting existing secrets")
-	githubSecretAddCmd.MarkFlagRequired("repo")
+	addNotifyFlags(githubSecretAddCmd)
+	githubSecretAddCmd.Flags().Float64Var(&githubRateLimit, "rate-limit", 0, "Maximum GitHub API requests per second (0 for no limit)")
+	githubSecretAddCmd.Flags().IntVar(&githubBurst, "burst", 0, "GitHub API requests sent without waiting (default: one second's worth)")
+	addTokenStdinFlag(githubSecretAddCmd)
+	githubSecretAddCmd.Flags().BoolVar(&interactive, "interactive", false, "Choose the secrets to upload one by one, seeing their masked value and GitHub status")
+	githubSecretAddCmd.Flags().BoolVar(&uploadUnchanged, "upload-unchanged", false, "Also upload secrets whose value did not change since feller last uploaded them")
+	githubSecretAddCmd.Flags().StringVar(&metadataVariable, "metadata-variable", "", "Record the provenance of the secrets in this Actions variable (default name "+defaultMetadataVariable+" when given without a value)")
+	githubSecretAddCmd.Flags().Lookup("metadata-variable").NoOptDefVal = defaultMetadataVariable
 }
 
-func addGitHubSecrets(_ *cobra.Command, _ []string) error {
+func addGitHubSecrets(cmd *cobra.Command, _ []string) error {
 	logger.Debug("Starting github-secret add command")
 	logger.Debug("Repository: %s, Dependabot: %v, Dry run: %v", repo, dependabot, dryRun)
 
@@ -115,6 +246,49 @@ func addGitHubSecrets(_ *cobra.Command, _ []string) error {
 	if err := validateOverwriteFlags(); err != nil {
 		return err
 	}
+	if err := validateNotifyFlags(); err != nil {
+		return err
+	}
+	if planJSON && !dryRun {
+		return errors.New("--json requires --dry-run")
+	}
+	if metadataVariable != "" {
+		if err := checkVariableName(metadataVariable); err != nil {
+			return err
+		}
+	}
+	if slices.Contains(environments, "") {
+		return errors.New("--environment needs non-empty environment names")
+	}
+	if interactive {
+		switch {
+		case force || skipExisting || confirmOverwrite:
+			return errors.New("--interactive cannot be used with --force, --skip-existing or --confirm-overwrite, it asks for every secret")
+		case tokenStdin:
+			return errors.New("--interactive cannot be used with --token-stdin, it reads answers from stdin")
+		case planJSON:
+			return errors.New("--interactive cannot be used with --json")
+		}
+	}
+	if tokenStdin {
+		if confirmOverwrite {
+			return errors.New("--token-stdin cannot be used with --confirm-overwrite, which reads answers from stdin")
+		}
+	}
+	if err := loadGitHubToken(cmd.InOrStdin()); err != nil {
+		return err
+	}
+	if repo == "" {
+		detected, source, err := detectGitHubRepo()
+		if err != nil {
+			return err
+		}
+		repo = detected
+		logger.Info("Using repository %s from %s, pass --repo to choose another", repo, source)
+	}
+	if err := setupGitHubRateLimit(); err != nil {
+		return err
+	}
 
 	// Validate required tools
 	if err := validateRequiredTools(); err != nil {
@@ -122,6 +296,24 @@ func addGitHubSecrets(_ *cobra.Command, _ []string) error {
 		return err
 	}
 
+	unlock, err := lockUnlessDryRun("github-secret add", dryRun)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if err := loadUploads(); err != nil {
+		return err
+	}
+	defer func() {
+		// Dry runs change nothing, not even the record of the uploads
+		if dryRun {
+			uploads = nil
+			return
+		}
+		saveUploads()
+	}()
+	target := flagRepository()
+
 	// Get secrets using teller
 	secrets, err := getSecretsFromTeller()
 	if err != nil {
@@ -132,31 +324,106 @@ func addGitHubSecrets(_ *cobra.Command, _ []string) error {
 	logger.Debug("Retrieved %d secrets from teller", len(secrets))
 
 	// Get existing secrets for comparison
-	existingSecrets, err := getExistingGitHubSecrets()
+	existingSecrets, err := getExistingGitHubSecrets(target)
 	if err != nil {
 		logger.Debug("Failed to get existing GitHub secrets: %v", err)
 		return fmt.Errorf("failed to get existing GitHub secrets: %w", err)
 	}
 
-	logger.Debug("Found %d existing repository secrets", len(existingSecrets.Repository))
-	if dependabot {
-		logger.Debug("Found %d existing Dependabot secrets", len(existingSecrets.Dependabot))
+	for _, scope := range uploadScopes(target) {
+		logger.Debug("Found %d existing %s secrets", len(existingSecrets.scope(scope)), scope)
+	}
+
+	all := secrets
+	if interactive {
+		secrets, err = selectSecretsInteractively(cmd.InOrStdin(), cmd.OutOrStdout(), target, secrets, existingSecrets)
+		if err != nil {
+			return err
+		}
+	}
+
+	if dryRun {
+		p := plan.New("github-secret add")
+		planGitHubSecrets(p, target, secrets, existingSecrets)
+		if metadataVariable != "" {
+			change, err := planSecretMetadata(metadataVariable)
+			if err != nil {
+				return err
+			}
+			p.Add(change)
+		}
+		if err := writePlan(cmd.OutOrStdout(), p); err != nil {
+			return err
+		}
+		if planJSON {
+			return nil
+		}
 	}
 
 	// Set secrets in GitHub
-	stats, err := setGitHubSecrets(secrets, existingSecrets)
+	start := time.Now()
+	stats, err := setGitHubSecrets(target, secrets, existingSecrets)
+	countDeselected(stats, target, all, secrets)
+	githubTiming.Elapsed = time.Since(start)
+	reportTimings(cmd.ErrOrStderr(), []providers.Timing{githubTiming})
 	if err != nil {
 		logger.Debug("Failed to set GitHub secrets: %v", err)
+		sendNotification(githubNotification("github-secret add", stats))
 		return fmt.Errorf("failed to set GitHub secrets: %w", err)
 	}
 
+	if metadataVariable != "" && !dryRun && len(stats.written) > 0 {
+		if err := recordSecretMetadata(metadataVariable, stats.written); err != nil {
+			sendNotification(githubNotification("github-secret add", stats))
+			return fmt.Errorf("secrets were uploaded, but their metadata was not recorded: %w", err)
+		}
+	}
+
 	// Print summary report
-	printOperationSummary(stats)
+	printOperationSummary(cmd.OutOrStdout(), stats)
+	sendNotification(githubNotification("github-secret add", stats))
 
 	logger.Verbose("Successfully configured %d GitHub secrets for repository %s", len(secrets), repo)
 	return nil
 }
 
+// setupGitHubRateLimit creates the limiter of the GitHub API from --rate-limit and --burst
+func setupGitHubRateLimit() error {
+	githubTiming = providers.Timing{Provider: "github", Kind: "github"}
+	githubLimiter = nil
+	if githubRateLimit < 0 {
+		return errors.New("--rate-limit must not be negative")
+	}
+	if githubBurst < 0 {
+		return errors.New("--burst must not be negative")
+	}
+	if githubRateLimit == 0 {
+		if githubBurst != 0 {
+			return errors.New("--burst requires --rate-limit")
+		}
+		return nil
+	}
+	githubLimiter = providers.NewLimiter(providers.RateLimit{RequestsPerSecond: githubRateLimit, Burst: githubBurst})
+	logger.Debug("Limiting GitHub API requests to %g per second", githubRateLimit)
+	return nil
+}
+
+// throttleGitHub waits for the GitHub API limiter before a request and records it
+func throttleGitHub() error {
+	waited, err := githubLimiter.Wait(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to wait for the GitHub rate limit: %w", err)
+	}
+	githubTimingMu.Lock()
+	defer githubTimingMu.Unlock()
+	githubTiming.Requests++
+	if waited > 0 {
+		githubTiming.Throttled++
+		githubTiming.Waited += waited
+	}
+	return nil
+}
+
 // validateOverwriteFlags ensures only one overwrite strategy is selected
 func validateOverwriteFlags() error {
 	flagCount := 0
@@ -178,29 +445,44 @@ func validateOverwriteFlags() error {
 }
 
 // printOperationSummary prints a summary of secret operations
-func printOperationSummary(stats *SecretOperationStats) {
+func printOperationSummary(w io.Writer, stats *SecretOperationStats) {
 	if dryRun {
-		fmt.Println("\nDry-run summary:")
+		fmt.Fprintln(w, "\nDry-run summary:")
 	} else {
-		fmt.Println("\nOperation summary:")
+		fmt.Fprintln(w, "\nOperation summary:")
 	}
 
 	if stats.Created > 0 {
-		fmt.Printf("  Created: %d secrets\n", stats.Created)
+		fmt.Fprintf(w, "  Created: %d secrets\n", stats.Created)
 	}
 	if stats.Updated > 0 {
-		fmt.Printf("  Updated: %d secrets\n", stats.Updated)
+		fmt.Fprintf(w, "  Updated: %d secrets\n", stats.Updated)
 	}
 	if stats.Skipped > 0 {
-		fmt.Printf("  Skipped: %d secrets\n", stats.Skipped)
+		fmt.Fprintf(w, "  Skipped: %d secrets\n", stats.Skipped)
+	}
+	if stats.Unchanged > 0 {
+		fmt.Fprintf(w, "  Unchanged: %d secrets\n", stats.Unchanged)
 	}
 	if stats.Failed > 0 {
-		fmt.Printf("  Failed:  %d secrets\n", stats.Failed)
+		fmt.Fprintf(w, "  Failed:  %d secrets\n", stats.Failed)
 	}
 
-	total := stats.Created + stats.Updated + stats.Skipped + stats.Failed
+	total := stats.Created + stats.Updated + stats.Skipped + stats.Unchanged + stats.Failed
 	if total == 0 {
-		fmt.Println("  No secrets processed")
+		fmt.Fprintln(w, "  No secrets processed")
+	}
+
+	// Scopes are only worth listing when there are several
+	if len(stats.Scopes) > 1 {
+		// The repository and Dependabot come first, then the environments by name
+		rank := map[string]int{"repository": -2, "Dependabot": -1}
+		scopes := slices.SortedFunc(maps.Keys(stats.Scopes), func(a, b string) int {
+			return cmp.Or(cmp.Compare(rank[a], rank[b]), cmp.Compare(a, b))
+		})
+		for _, scope := range scopes {
+			fmt.Fprintf(w, "  %s: %s\n", scope, stats.Scopes[scope])
+		}
 	}
 }
 
@@ -262,12 +544,15 @@ func validateRequiredTools() error {
 		return errors.New("GitHub CLI (gh) not found - please install and authenticate with GitHub CLI")
 	}
 
-	// Check GitHub CLI authentication (skip in dry-run mode for testing)
-	if !dryRun {
+	// Check GitHub CLI authentication (skip in dry-run mode for testing, and with a token,
+	// which gh uses without a stored login)
+	if source := githubTokenSource(); source != "" {
+		logger.Debug("Authenticating gh with the token of %s", source)
+	} else if !dryRun {
 		cmd := exec.Command("gh", "auth", "status")
 		if err := cmd.Run(); err != nil {
 			logger.Debug("GitHub CLI authentication failed: %v", err)
-			return errors.New("GitHub CLI not authenticated - run 'gh auth login' first")
+			return errors.New("GitHub CLI not authenticated - run 'gh auth login' first, or set GH_TOKEN or pass --token-stdin")
 		}
 	}
 
@@ -289,14 +574,14 @@ func getSecretsFromTeller() (map[string]string, error) {
 	logger.Debug("Retrieving GSM secrets from teller")
 
 	// Load configuration to identify GSM secrets
-	cfg, err := config.LoadConfig(cfgFile)
+	cfg, err := loadConfig()
 	if err != nil {
 		logger.Debug("Failed to load config: %v", err)
 		return nil, fmt.Errorf("failed to load config: %w", err)
 	}
 
 	// Get GSM providers to determine which secrets we want
-	gsmProviders := cfg.GetProvidersByKind("google_secretmanager")
+	gsmProviders := cfg.GetProvidersByKind(providers.KindGoogleSecretManager)
 	if len(gsmProviders) == 0 {
 		logger.Debug("No GSM providers found in configuration")
 		return map[string]string{}, nil
@@ -323,6 +608,9 @@ func getSecretsFromTeller() (map[string]string, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err := checkTellerSubcommand(tellerPath, "export"); err != nil {
+		return nil, err
+	}
 
 	// Build teller command arguments
 	args := []string{"export", "json"}
@@ -336,8 +624,15 @@ func getSecretsFromTeller() (map[string]string, error) {
 
 	logger.Debug("Executing: %s %s", tellerPath, strings.Join(args, " "))
 
+	env, cleanup, err := tellerEnv()
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
 	// Execute teller export json
 	cmd := exec.CommandContext(context.Background(), tellerPath, args...)
+	cmd.Env = env
 	output, err := cmd.Output()
 	if err != nil {
 		var exitError *exec.ExitError
@@ -375,47 +670,37 @@ func getSecretsFromTeller() (map[string]string, error) {
 	return gsmSecrets, nil
 }
 
-// getExistingGitHubSecrets retrieves existing secrets from GitHub repository
-func getExistingGitHubSecrets() (*ExistingSecrets, error) {
+// getExistingGitHubSecrets retrieves the existing secrets of the scopes of r
+func getExistingGitHubSecrets(r githubRepository) (*ExistingSecrets, error) {
 	logger.Debug("Retrieving existing GitHub secrets")
 
 	existing := &ExistingSecrets{
-		Repository: make(map[string]bool),
-		Dependabot: make(map[string]bool),
+		Repository:   make(map[string]bool),
+		Dependabot:   make(map[string]bool),
+		Environments: make(map[string]map[string]bool),
 	}
-
-	// Get repository secrets
-	if secrets, err := listGitHubSecrets(false); err != nil {
-		return nil, fmt.Errorf("failed to list repository secrets: %w", err)
-	} else {
-		for _, secret := range secrets {
-			existing.Repository[secret] = true
-			logger.Debug("Found existing repository secret: %s", secret)
-		}
+	for _, environment := range r.Environments {
+		existing.Environments[environment] = make(map[string]bool)
 	}
 
-	// Get Dependabot secrets if needed
-	if dependabot {
-		if secrets, err := listGitHubSecrets(true); err != nil {
-			return nil, fmt.Errorf("failed to list Dependabot secrets: %w", err)
-		} else {
-			for _, secret := range secrets {
-				existing.Dependabot[secret] = true
-				logger.Debug("Found existing Dependabot secret: %s", secret)
-			}
+	// Get the secrets of the repository and of the scopes uploaded to besides it
+	for _, scope := range uploadScopes(r) {
+		secrets, err := listGitHubSecrets(r, scope)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s secrets: %w", scope, err)
+		}
+		names := existing.scope(scope)
+		for _, secret := range secrets {
+			names[secret] = true
+			logger.Debug("Found existing %s secret: %s", scope, secret)
 		}
 	}
 
 	return existing, nil
 }
 
-// listGitHubSecrets lists secrets for repository or Dependabot
-func listGitHubSecrets(isDependabot bool) ([]string, error) {
-	target := "repository"
-	if isDependabot {
-		target = "Dependabot"
-	}
-
+// listGitHubSecrets lists the secrets of a scope of r: repository, Dependabot or an environment
+func listGitHubSecrets(r githubRepository, target string) ([]string, error) {
 	logger.Debug("Listing %s secrets", target)
 
 	// Skip actual listing in dry-run mode to avoid API calls
@@ -425,15 +710,12 @@ func listGitHubSecrets(isDependabot bool) ([]string, error) {
 	// }
 
 	// Build gh command
-	args := []string{"secret", "list", "--repo", repo, "--json", "name"}
-	if isDependabot {
-		args = append(args[:2], append([]string{"--app", "dependabot"}, args[2:]...)...)
-	}
+	args := append(append([]string{"secret", "list"}, githubScopeArgs(target)...), "--repo", r.Repo, "--json", "name")
 
 	logger.Debug("Executing: gh %s", strings.Join(args, " "))
 
 	// Execute gh secret list
-	cmd := exec.CommandContext(context.Background(), "gh", args...)
+	cmd := ghCommand(args...)
 	output, err := cmd.Output()
 	if err != nil {
 		var exitError *exec.ExitError
@@ -460,35 +742,75 @@ func listGitHubSecrets(isDependabot bool) ([]string, error) {
 	return names, nil
 }
 
-// setGitHubSecrets uploads secrets to GitHub repository and returns operation statistics
-func setGitHubSecrets(secrets map[string]string, existing *ExistingSecrets) (*SecretOperationStats, error) {
-	logger.Debug("Setting GitHub secrets for repository: %s", repo)
+// setGitHubSecrets uploads secrets to the scopes of r and returns operation statistics
+func setGitHubSecrets(r githubRepository, secrets map[string]string, existing *ExistingSecrets) (*SecretOperationStats, error) {
+	logger.Debug("Setting GitHub secrets for repository: %s", r.Repo)
 
 	stats := &SecretOperationStats{}
+	scopes := uploadScopes(r)
 
 	for key, value := range secrets {
-		// Check and set repository secret
-		if result, err := setGitHubSecretIfNeeded(key, value, false, existing); err != nil {
-			stats.Failed++
-			return stats, fmt.Errorf("failed to set secret %s: %w", key, err)
-		} else {
-			updateStats(stats, result)
+		// The scopes of a key are uploaded concurrently; prompts to confirm overwrites must
+		// not interleave, so they ask one scope after the other
+		results := make([]string, len(scopes))
+		errs := make([]error, len(scopes))
+		var wg sync.WaitGroup
+		for i, scope := range scopes {
+			upload := func() {
+				results[i], errs[i] = setGitHubSecretIfNeeded(r, key, value, scope, existing)
+			}
+			if confirmOverwrite {
+				upload()
+				continue
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				upload()
+			}()
 		}
+		wg.Wait()
 
-		// Also set for Dependabot if requested
-		if dependabot {
-			if result, err := setGitHubSecretIfNeeded(key, value, true, existing); err != nil {
+		var failed error
+		for i, scope := range scopes {
+			if errs[i] != nil {
 				stats.Failed++
-				return stats, fmt.Errorf("failed to set Dependabot secret %s: %w", key, err)
-			} else {
-				updateStats(stats, result)
+				stats.countScope(scope, "failed")
+				if failed == nil {
+					failed = errs[i]
+					if scope == "repository" {
+						failed = fmt.Errorf("failed to set secret %s: %w", key, errs[i])
+					} else {
+						failed = fmt.Errorf("failed to set %s secret %s: %w", scope, key, errs[i])
+					}
+				}
+				continue
 			}
+			updateStats(stats, results[i])
+			stats.countScope(scope, results[i])
+			stats.recordWritten(key, scope, results[i])
+		}
+		if failed != nil {
+			return stats, failed
 		}
 	}
 
 	return stats, nil
 }
 
+// uploadScopes returns the scopes of r secrets are uploaded to: the repository, then
+// Dependabot and the environments when r includes them
+func uploadScopes(r githubRepository) []string {
+	scopes := []string{"repository"}
+	if r.Dependabot {
+		scopes = append(scopes, "Dependabot")
+	}
+	for _, environment := range r.Environments {
+		scopes = append(scopes, environmentScope(environment))
+	}
+	return scopes
+}
+
 // updateStats updates the statistics based on the operation result
 func updateStats(stats *SecretOperationStats, result string) {
 	switch result {
@@ -498,16 +820,55 @@ func updateStats(stats *SecretOperationStats, result string) {
 		stats.Updated++
 	case "skipped":
 		stats.Skipped++
+	case "unchanged":
+		stats.Unchanged++
 	}
 }
 
+// countScope counts the result of an operation, or "failed", in its scope
+func (s *SecretOperationStats) countScope(scope, result string) {
+	if s.Scopes == nil {
+		s.Scopes = make(map[string]*ScopeStats)
+	}
+	if s.Scopes[scope] == nil {
+		s.Scopes[scope] = &ScopeStats{}
+	}
+	counts := s.Scopes[scope]
+	switch result {
+	case "created":
+		counts.Created++
+	case "updated":
+		counts.Updated++
+	case "skipped":
+		counts.Skipped++
+	case "unchanged":
+		counts.Unchanged++
+	case "failed":
+		counts.Failed++
+	}
+}
+
+// recordWritten remembers the scope a secret was created or updated in, for the metadata
+// variable
+func (s *SecretOperationStats) recordWritten(key, scope, result string) {
+	if result != "created" && result != "updated" {
+		return
+	}
+	if s.written == nil {
+		s.written = make(map[string][]string)
+	}
+	s.written[key] = append(s.written[key], scope)
+}
+
 // setGitHubSecretIfNeeded sets a secret based on the selected overwrite strategy and returns the operation type
-func setGitHubSecretIfNeeded(key, value string, isDependabot bool, existing *ExistingSecrets) (string, error) {
-	target := "repository"
-	existingSecrets := existing.Repository
-	if isDependabot {
-		target = "Dependabot"
-		existingSecrets = existing.Dependabot
+func setGitHubSecretIfNeeded(r githubRepository, key, value, target string, existing *ExistingSecrets) (string, error) {
+	existingSecrets := existing.scope(target)
+
+	// Values GitHub still holds are not uploaded again
+	if existingSecrets[key] && uploads.Unchanged(uploadTarget(r, target), key, value) {
+		logger.Debug("%s secret '%s' is unchanged since it was last uploaded", target, key)
+		logger.Verbose("Skipped unchanged %s secret: %s", target, key)
+		return "unchanged", nil
 	}
 
 	// Check if secret already exists
@@ -535,7 +896,7 @@ func setGitHubSecretIfNeeded(key, value string, isDependabot bool, existing *Exi
 			logger.Verbose("Updating existing %s secret: %s", target, key)
 		}
 
-		if err := setGitHubSecret(key, value, isDependabot); err != nil {
+		if err := setGitHubSecret(r, key, value, target); err != nil {
 			return "", err
 		}
 		return "updated", nil
@@ -543,41 +904,34 @@ func setGitHubSecretIfNeeded(key, value string, isDependabot bool, existing *Exi
 		logger.Debug("%s secret '%s' does not exist, creating it", target, key)
 		logger.Verbose("Creating new %s secret: %s", target, key)
 
-		if err := setGitHubSecret(key, value, isDependabot); err != nil {
+		if err := setGitHubSecret(r, key, value, target); err != nil {
 			return "", err
 		}
 		return "created", nil
 	}
 }
 
-// setGitHubSecret sets a single secret in GitHub
-func setGitHubSecret(key, value string, isDependabot bool) error {
-	target := "repository"
-	if isDependabot {
-		target = "Dependabot"
-	}
-
+// setGitHubSecret sets a single secret in a scope of r: repository, Dependabot or an
+// environment
+func setGitHubSecret(r githubRepository, key, value, target string) error {
 	logger.Debug("Setting %s secret: %s", target, key)
 
-	if dryRun {
-		if isDependabot {
-			fmt.Printf("Would execute: gh secret set %s --app dependabot --repo %s --body \"<redacted>\"\n", key, repo)
-		} else {
-			fmt.Printf("Would execute: gh secret set %s --repo %s --body \"<redacted>\"\n", key, repo)
-		}
+	// Build gh command
+	args := append(append([]string{"secret", "set", key}, githubScopeArgs(target)...), "--repo", r.Repo, "--body", value)
+
+	if r.DryRun {
+		logger.Verbose("Would execute: gh %s \"<redacted>\"", strings.Join(args[:len(args)-1], " "))
 		return nil
 	}
 
-	// Build gh command
-	args := []string{"secret", "set", key, "--repo", repo, "--body", value}
-	if isDependabot {
-		args = append(args[:3], append([]string{"--app", "dependabot"}, args[3:]...)...)
+	if err := throttleGitHub(); err != nil {
+		return err
 	}
 
 	logger.Debug("Executing: gh %s", strings.Join(args[:len(args)-1], " ")+" --body <redacted>")
 
 	// Execute gh secret set
-	cmd := exec.CommandContext(context.Background(), "gh", args...)
+	cmd := ghCommand(args...)
 	if output, err := cmd.CombinedOutput(); err != nil {
 		var exitError *exec.ExitError
 		if errors.As(err, &exitError) {
@@ -586,6 +940,64 @@ func setGitHubSecret(key, value string, isDependabot bool) error {
 		return fmt.Errorf("failed to set %s secret %s: %w", target, key, err)
 	}
 
+	uploads.Record(uploadTarget(r, target), key, value)
 	logger.Verbose("Set %s secret: %s", target, key)
 	return nil
 }
+
+// deleteGitHubSecret deletes a single secret from a scope of r
+func deleteGitHubSecret(r githubRepository, key, target string) error {
+	logger.Debug("Deleting %s secret: %s", target, key)
+	if err := throttleGitHub(); err != nil {
+		return err
+	}
+
+	args := append([]string{"secret", "delete", key, "--repo", r.Repo}, githubScopeArgs(target)...)
+	if output, err := ghCommand(args...).CombinedOutput(); err != nil {
+		logger.Debug("gh output: %s", string(output))
+		return fmt.Errorf("failed to delete %s secret %s: %w", target, key, err)
+	}
+
+	uploads.Forget(uploadTarget(r, target), key)
+	logger.Verbose("Deleted %s secret: %s", target, key)
+	return nil
+}
+
+// uploadTarget returns the target the uploads to scope of r are recorded as
+func uploadTarget(r githubRepository, scope string) string {
+	return githubTarget(r.Repo) + " (" + scope + ")"
+}
+
+// loadUploads reads the digests of the values last uploaded from the state directory of the
+// config, unless --upload-unchanged is set
+func loadUploads() error {
+	uploads = nil
+	if uploadUnchanged {
+		return nil
+	}
+	configPath, err := config.ResolveConfigPath(cfgFile)
+	if err != nil {
+		return fmt.Errorf("failed to resolve config path: %w", err)
+	}
+	uploads, err = state.LoadUploads(state.Dir(configPath))
+	if err != nil {
+		return err //nolint:wrapcheck // names the file
+	}
+	return nil
+}
+
+// saveUploads records the digests of the values uploaded. A failure only costs uploading the
+// secrets again next time, so it is reported without failing the command.
+func saveUploads() {
+	if uploads == nil {
+		return
+	}
+	configPath, err := config.ResolveConfigPath(cfgFile)
+	if err == nil {
+		err = uploads.Write(state.Dir(configPath))
+	}
+	if err != nil {
+		logger.Info("Not recording the uploaded values, the next run uploads them again: %v", err)
+	}
+	uploads = nil
+}
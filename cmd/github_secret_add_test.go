@@ -3,6 +3,8 @@ package cmd
 import (
 	"bytes"
 	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 )
@@ -198,25 +200,34 @@ func TestPrintOperationSummary(t *testing.T) {
 				"Failed:  3 secrets",
 			},
 		},
+		{
+			name: "counts by scope",
+			stats: &SecretOperationStats{
+				Created:   1,
+				Unchanged: 1,
+				Scopes: map[string]*ScopeStats{
+					"repository":             {Created: 1},
+					"Dependabot":             {Unchanged: 1},
+					"environment production": {Failed: 1},
+				},
+			},
+			dryRun: false,
+			expectedLines: []string{
+				"Created: 1 secrets",
+				"Unchanged: 1 secrets",
+				"repository: 1 created, 0 updated, 0 skipped, 0 unchanged, 0 failed\n" +
+					"  Dependabot: 0 created, 0 updated, 0 skipped, 1 unchanged, 0 failed\n" +
+					"  environment production: 0 created, 0 updated, 0 skipped, 0 unchanged, 1 failed",
+			},
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			dryRun = tt.dryRun
 
-			// Capture stdout
-			oldStdout := os.Stdout
-			r, w, _ := os.Pipe()
-			os.Stdout = w
-
-			printOperationSummary(tt.stats)
-
-			// Restore stdout and read output
-			w.Close()
-			os.Stdout = oldStdout
-
 			var buf bytes.Buffer
-			buf.ReadFrom(r)
+			printOperationSummary(&buf, tt.stats)
 			output := buf.String()
 
 			// Check that all expected lines are present
@@ -284,6 +295,23 @@ func TestUpdateStats(t *testing.T) {
 				Failed:  4,
 			},
 		},
+		{
+			name: "increment unchanged",
+			initialStats: &SecretOperationStats{
+				Created: 1,
+				Updated: 2,
+				Skipped: 3,
+				Failed:  4,
+			},
+			operation: "unchanged",
+			expectedStats: &SecretOperationStats{
+				Created:   1,
+				Updated:   2,
+				Skipped:   3,
+				Failed:    4,
+				Unchanged: 1,
+			},
+		},
 		{
 			name: "unknown operation (no change)",
 			initialStats: &SecretOperationStats{
@@ -342,6 +370,9 @@ func TestUpdateStats(t *testing.T) {
 			if stats.Failed != tt.expectedStats.Failed {
 				t.Errorf("updateStats() Failed = %d, want %d", stats.Failed, tt.expectedStats.Failed)
 			}
+			if stats.Unchanged != tt.expectedStats.Unchanged {
+				t.Errorf("updateStats() Unchanged = %d, want %d", stats.Unchanged, tt.expectedStats.Unchanged)
+			}
 		})
 	}
 }
@@ -416,3 +447,87 @@ func TestValidateRequiredTools(t *testing.T) {
 		})
 	}
 }
+
+//nolint:paralleltest // modifies environment variables and global flag variables
+func TestSetGitHubSecretsSkipsUnchanged(t *testing.T) {
+	log := fakeGH(t)
+	t.Cleanup(func() { cfgFile, uploadUnchanged, uploads = "", false, nil })
+	cfgFile = filepath.Join(t.TempDir(), ".teller.yml")
+	target := githubRepository{Repo: "owner/repo", Dependabot: true}
+
+	// upload sets the secrets like one run of github-secret add and returns the gh calls
+	upload := func(secrets map[string]string, existing *ExistingSecrets) (*SecretOperationStats, []string) {
+		t.Helper()
+		_ = os.Remove(log)
+		if err := loadUploads(); err != nil {
+			t.Fatalf("loadUploads() error = %v", err)
+		}
+		stats, err := setGitHubSecrets(target, secrets, existing)
+		saveUploads()
+		if err != nil {
+			t.Fatalf("setGitHubSecrets() error = %v", err)
+		}
+		data, _ := os.ReadFile(log)
+		return stats, strings.Fields(strings.ReplaceAll(string(data), " ", "_"))
+	}
+	all := &ExistingSecrets{Repository: map[string]bool{"API_KEY": true}, Dependabot: map[string]bool{"API_KEY": true}}
+
+	if stats, calls := upload(map[string]string{"API_KEY": "abc"}, all); stats.Updated != 2 || len(calls) != 2 {
+		t.Fatalf("first upload = %+v, calls %v, expected both scopes updated", stats, calls)
+	}
+	if stats, calls := upload(map[string]string{"API_KEY": "abc"}, all); stats.Unchanged != 2 || len(calls) != 0 {
+		t.Errorf("upload of the same value = %+v, calls %v, expected nothing uploaded", stats, calls)
+	}
+
+	// Secrets deleted on GitHub are uploaded again
+	deleted := &ExistingSecrets{Repository: map[string]bool{}, Dependabot: map[string]bool{"API_KEY": true}}
+	if stats, calls := upload(map[string]string{"API_KEY": "abc"}, deleted); stats.Created != 1 || stats.Unchanged != 1 || len(calls) != 1 {
+		t.Errorf("upload after deletion = %+v, calls %v, expected the repository secret created", stats, calls)
+	} else if *stats.Scopes["repository"] != (ScopeStats{Created: 1}) || *stats.Scopes["Dependabot"] != (ScopeStats{Unchanged: 1}) {
+		t.Errorf("upload after deletion counted %v in repository and %v in Dependabot", stats.Scopes["repository"], stats.Scopes["Dependabot"])
+	}
+
+	if stats, calls := upload(map[string]string{"API_KEY": "new"}, all); stats.Updated != 2 || len(calls) != 2 {
+		t.Errorf("upload of a new value = %+v, calls %v, expected both scopes updated", stats, calls)
+	}
+
+	uploadUnchanged = true
+	if stats, calls := upload(map[string]string{"API_KEY": "new"}, all); stats.Updated != 2 || len(calls) != 2 {
+		t.Errorf("upload with --upload-unchanged = %+v, calls %v, expected both scopes updated", stats, calls)
+	}
+}
+
+//nolint:paralleltest // modifies environment variables
+func TestSetGitHubSecretsEnvironments(t *testing.T) {
+	log := fakeGH(t)
+	t.Setenv("GH_FAKE_SECRETS", `[{"name":"API_KEY"}]`)
+	target := githubRepository{Repo: "owner/repo", Environments: []string{"staging", "production"}}
+
+	existing, err := getExistingGitHubSecrets(target)
+	if err != nil {
+		t.Fatalf("getExistingGitHubSecrets() error = %v", err)
+	}
+	if !existing.scope("environment staging")["API_KEY"] || !existing.scope("environment production")["API_KEY"] {
+		t.Errorf("getExistingGitHubSecrets() = %+v, expected the secrets of both environments", existing)
+	}
+	existing.Environments["production"] = map[string]bool{}
+
+	stats, err := setGitHubSecrets(target, map[string]string{"API_KEY": "abc"}, existing)
+	if err != nil {
+		t.Fatalf("setGitHubSecrets() error = %v", err)
+	}
+	if *stats.Scopes["environment staging"] != (ScopeStats{Updated: 1}) || *stats.Scopes["environment production"] != (ScopeStats{Created: 1}) {
+		t.Errorf("setGitHubSecrets() counted %v in staging and %v in production", stats.Scopes["environment staging"], stats.Scopes["environment production"])
+	}
+	data, _ := os.ReadFile(log)
+	calls := strings.Split(strings.TrimSpace(string(data)), "\n")
+	slices.Sort(calls)
+	expected := []string{
+		"secret set API_KEY --env production",
+		"secret set API_KEY --env staging",
+		"secret set API_KEY --repo owner/repo",
+	}
+	if !slices.Equal(calls, expected) {
+		t.Errorf("gh calls = %q, expected %q", calls, expected)
+	}
+}
@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"slices"
+	"time"
+
+	"github.com/containifyci/feller/pkg/logger"
+	"github.com/spf13/cobra"
+)
+
+// listMetadataVariable is the variable github-secret list reads the provenance from
+var listMetadataVariable string
+
+// githubSecretListCmd represents the github-secret list command
+var githubSecretListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List GitHub repository secrets and whether feller manages them",
+	Long: `List the secrets of a GitHub repository with the provider feller uploaded them
+from and when, as recorded by 'feller github-secret add --metadata-variable'.
+Secrets without an entry in the metadata variable are shown as unmanaged.
+
+Secret values are never read; GitHub does not return them.
+
+Examples:
+  feller github-secret list --repo owner/repo
+  feller github-secret list --dependabot
+  echo "$GITHUB_PAT" | feller github-secret list --repo owner/repo --token-stdin`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		if err := loadGitHubToken(cmd.InOrStdin()); err != nil {
+			return err
+		}
+		return listManagedSecrets(cmd.OutOrStdout())
+	},
+}
+
+func init() {
+	githubSecretCmd.AddCommand(githubSecretListCmd)
+	githubSecretListCmd.Flags().StringVarP(&repo, "repo", "r", "", "GitHub repository (owner/repo) (default: GITHUB_REPOSITORY or the git remote origin)")
+	githubSecretListCmd.Flags().BoolVar(&dependabot, "dependabot", false, "Also list Dependabot secrets")
+	githubSecretListCmd.Flags().StringVar(&listMetadataVariable, "metadata-variable", defaultMetadataVariable, "Actions variable the provenance is read from")
+	addTokenStdinFlag(githubSecretListCmd)
+}
+
+func listManagedSecrets(out io.Writer) error {
+	if err := checkVariableName(listMetadataVariable); err != nil {
+		return err
+	}
+	if _, err := exec.LookPath("gh"); err != nil {
+		return errors.New("GitHub CLI (gh) not found - please install and authenticate with GitHub CLI")
+	}
+	if repo == "" {
+		detected, source, err := detectGitHubRepo()
+		if err != nil {
+			return err
+		}
+		repo = detected
+		logger.Info("Using repository %s from %s, pass --repo to choose another", repo, source)
+	}
+
+	existing, err := getExistingGitHubSecrets(githubRepository{Repo: repo, Dependabot: dependabot})
+	if err != nil {
+		return err
+	}
+	metadata, err := readSecretMetadata(listMetadataVariable)
+	if err != nil {
+		return err
+	}
+	if metadata == nil {
+		metadata = &secretMetadata{}
+	}
+
+	if metadata.ManagedBy != "" {
+		fmt.Fprintf(out, "Last synced %s", metadata.SyncedAt.Format(time.RFC3339))
+		if metadata.ConfigHash != "" {
+			fmt.Fprintf(out, " from config %s", metadata.ConfigHash)
+		}
+		fmt.Fprint(out, "\n\n")
+	} else {
+		fmt.Fprintf(out, "No metadata in variable %s, no secret is known to be managed by feller\n\n", listMetadataVariable)
+	}
+
+	w := newTable(out)
+	fmt.Fprintln(w, "NAME\tSCOPE\tPROVIDER\tSYNCED")
+	writeRows := func(names map[string]bool, scope string) {
+		for _, name := range sortedNames(names) {
+			provider, synced := "-", "-"
+			if entry, ok := metadata.Secrets[name]; ok && slices.Contains(entry.Scopes, scope) {
+				provider, synced = entry.Provider, entry.SyncedAt.Format(time.RFC3339)
+				if provider == "" {
+					provider = managedBy
+				}
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", name, scope, provider, synced)
+		}
+	}
+	writeRows(existing.Repository, "repository")
+	if dependabot {
+		writeRows(existing.Dependabot, "Dependabot")
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to write secrets: %w", err)
+	}
+	return nil
+}
+
+// sortedNames returns the names of the set in order
+func sortedNames(names map[string]bool) []string {
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	slices.Sort(sorted)
+	return sorted
+}
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	// tokenStdin reads the GitHub token from stdin, set by --token-stdin
+	tokenStdin bool
+	// githubToken is the token read with --token-stdin, passed to every gh call
+	githubToken string
+)
+
+// readGitHubToken reads a GitHub token from r, ignoring surrounding whitespace
+func readGitHubToken(r io.Reader) (string, error) {
+	data, err := io.ReadAll(io.LimitReader(r, 64*1024))
+	if err != nil {
+		return "", fmt.Errorf("failed to read the GitHub token from stdin: %w", err)
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", errors.New("--token-stdin was given but stdin is empty")
+	}
+	if strings.ContainsAny(token, "\r\n") {
+		return "", errors.New("--token-stdin expects a single token, stdin has several lines")
+	}
+	return token, nil
+}
+
+// addTokenStdinFlag registers --token-stdin on a command that calls gh
+func addTokenStdinFlag(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&tokenStdin, "token-stdin", false, "Read the GitHub token from stdin instead of using the gh login")
+}
+
+// loadGitHubToken reads the token of --token-stdin from in, when it was given
+func loadGitHubToken(in io.Reader) error {
+	if !tokenStdin {
+		return nil
+	}
+	token, err := readGitHubToken(in)
+	if err != nil {
+		return err
+	}
+	githubToken = token
+	return nil
+}
+
+// githubTokenSource names where gh gets its token from without a login: --token-stdin,
+// GH_TOKEN or GITHUB_TOKEN. It is empty when gh relies on its stored login.
+func githubTokenSource() string {
+	switch {
+	case githubToken != "":
+		return "--token-stdin"
+	case os.Getenv("GH_TOKEN") != "":
+		return "GH_TOKEN"
+	case os.Getenv("GITHUB_TOKEN") != "":
+		return "GITHUB_TOKEN"
+	}
+	return ""
+}
+
+// ghCommand returns a gh call that authenticates with the token of --token-stdin, if any.
+// gh reads GH_TOKEN and GITHUB_TOKEN itself; GH_ENTERPRISE_TOKEN covers GitHub Enterprise
+// Server hosts selected with GH_HOST.
+func ghCommand(args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(context.Background(), "gh", args...)
+	if githubToken != "" {
+		cmd.Env = append(os.Environ(), "GH_TOKEN="+githubToken, "GH_ENTERPRISE_TOKEN="+githubToken)
+	}
+	return cmd
+}
@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadGitHubToken(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		input   string
+		token   string
+		wantErr string
+	}{
+		{name: "token", input: "ghp_abc", token: "ghp_abc"},
+		{name: "trailing newline", input: "  ghp_abc\n", token: "ghp_abc"},
+		{name: "empty", input: "\n", wantErr: "--token-stdin was given but stdin is empty"},
+		{name: "several lines", input: "ghp_abc\nghp_def\n", wantErr: "--token-stdin expects a single token, stdin has several lines"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			token, err := readGitHubToken(strings.NewReader(tt.input))
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.token, token)
+		})
+	}
+}
+
+//nolint:paralleltest // modifies environment variables and global flag variables
+func TestGitHubTokenSource(t *testing.T) {
+	t.Cleanup(func() { githubToken = "" })
+	t.Setenv("GH_TOKEN", "")
+	t.Setenv("GITHUB_TOKEN", "")
+	assert.Empty(t, githubTokenSource())
+	assert.Nil(t, ghCommand("secret", "list").Env, "gh inherits the environment")
+
+	t.Setenv("GITHUB_TOKEN", "from-actions")
+	assert.Equal(t, "GITHUB_TOKEN", githubTokenSource())
+	t.Setenv("GH_TOKEN", "from-gh")
+	assert.Equal(t, "GH_TOKEN", githubTokenSource())
+
+	githubToken = "from-stdin"
+	assert.Equal(t, "--token-stdin", githubTokenSource())
+	env := ghCommand("secret", "list").Env
+	assert.Contains(t, env, "GH_TOKEN=from-stdin")
+	assert.Contains(t, env, "GH_ENTERPRISE_TOKEN=from-stdin")
+	// The token passed last wins over the inherited GH_TOKEN
+	assert.Equal(t, "GH_TOKEN=from-stdin", lastWithPrefix(env, "GH_TOKEN="))
+}
+
+// lastWithPrefix returns the last entry of env starting with prefix
+func lastWithPrefix(env []string, prefix string) string {
+	var last string
+	for _, entry := range env {
+		if strings.HasPrefix(entry, prefix) {
+			last = entry
+		}
+	}
+	return last
+}
+
+//nolint:paralleltest // modifies global flag variables
+func TestLoadGitHubToken(t *testing.T) {
+	t.Cleanup(func() { githubToken, tokenStdin = "", false })
+	for _, c := range []*cobra.Command{githubSecretAddCmd, githubSecretListCmd, applyCmd, reconcileCmd, syncCmd} {
+		assert.NotNil(t, c.Flags().Lookup("token-stdin"), "%s calls gh, so it takes --token-stdin", c.CommandPath())
+	}
+
+	require.NoError(t, loadGitHubToken(strings.NewReader("ignored\n")))
+	assert.Empty(t, githubToken, "stdin is only read with --token-stdin")
+
+	tokenStdin = true
+	require.NoError(t, loadGitHubToken(strings.NewReader("ghp_abc\n")))
+	assert.Equal(t, "ghp_abc", githubToken)
+	require.EqualError(t, loadGitHubToken(strings.NewReader("")), "--token-stdin was given but stdin is empty")
+}
@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// interactive selects the secrets to upload with a wizard instead of an overwrite strategy
+var interactive bool
+
+// errWizardQuit is returned when the user quits the wizard, before anything is uploaded
+var errWizardQuit = errors.New("cancelled, no secrets were uploaded")
+
+// Bulk choices of the wizard, applied to every remaining key
+const (
+	bulkNone    = ""
+	bulkAll     = "all"
+	bulkSkip    = "skip"
+	bulkMissing = "missing"
+)
+
+// wizardChoices explains the answers of the wizard prompt
+const wizardChoices = `  u  upload this secret
+  s  skip this secret
+  a  upload this and all remaining secrets
+  n  skip this and all remaining secrets
+  m  upload this and the remaining secrets only where they are missing
+  q  quit without uploading anything
+`
+
+// scopeStatus describes a secret in one scope: missing, exists or, when the value is the
+// one feller uploaded last, unchanged
+func scopeStatus(r githubRepository, key, value, scope string, existing map[string]bool) string {
+	switch {
+	case !existing[key]:
+		return "missing"
+	case uploads.Unchanged(uploadTarget(r, scope), key, value):
+		return "unchanged"
+	default:
+		return "exists"
+	}
+}
+
+// selectSecretsInteractively walks through the secrets in order, showing the masked value and
+// the status in every scope, and returns the secrets the user chose to upload
+func selectSecretsInteractively(in io.Reader, out io.Writer, r githubRepository, secrets map[string]string, existing *ExistingSecrets) (map[string]string, error) {
+	keys := make([]string, 0, len(secrets))
+	for key := range secrets {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	fmt.Fprintf(out, "%d secret(s) for %s. For each, choose:\n%s\n", len(keys), r.Repo, wizardChoices)
+	scanner := bufio.NewScanner(in)
+	selected := make(map[string]string)
+	bulk := bulkNone
+	for i, key := range keys {
+		value := secrets[key]
+		order := uploadScopes(r)
+
+		// Secrets that every scope holds unchanged are skipped unless chosen explicitly
+		var statuses []string
+		missing, upload := false, false
+		for _, scope := range order {
+			status := scopeStatus(r, key, value, scope, existing.scope(scope))
+			statuses = append(statuses, scope+": "+status)
+			missing = missing || status == "missing"
+			upload = upload || status != "unchanged"
+		}
+		defaultUpload := upload
+		switch bulk {
+		case bulkAll:
+			upload = true
+		case bulkSkip:
+			upload = false
+		case bulkMissing:
+			upload = missing
+		default:
+			fmt.Fprintf(out, "[%d/%d] %s = %s (%s)\n", i+1, len(keys), key, maskSecret(value), strings.Join(statuses, ", "))
+			answer, err := askWizard(scanner, out, upload)
+			if err != nil {
+				return nil, err
+			}
+			switch answer {
+			case "u":
+				upload = true
+			case "s":
+				upload = false
+			case "a":
+				upload, bulk = true, bulkAll
+			case "n":
+				upload, bulk = false, bulkSkip
+			case "m":
+				upload, bulk = missing, bulkMissing
+			}
+		}
+		if upload {
+			selected[key] = value
+		}
+		// Uploading a secret that is unchanged everywhere was chosen explicitly
+		if upload && !defaultUpload {
+			for _, scope := range order {
+				uploads.Forget(uploadTarget(r, scope), key)
+			}
+		}
+	}
+
+	fmt.Fprintf(out, "\nUploading %d secret(s), skipping %d\n", len(selected), len(keys)-len(selected))
+	return selected, nil
+}
+
+// countDeselected counts the secrets of all that were not selected as skipped in every scope
+// of r
+func countDeselected(stats *SecretOperationStats, r githubRepository, all, selected map[string]string) {
+	for key := range all {
+		if _, ok := selected[key]; ok {
+			continue
+		}
+		for _, scope := range uploadScopes(r) {
+			updateStats(stats, "skipped")
+			stats.countScope(scope, "skipped")
+		}
+	}
+}
+
+// askWizard reads one answer to the prompt of a secret; an empty answer takes the default
+func askWizard(scanner *bufio.Scanner, out io.Writer, upload bool) (string, error) {
+	prompt := "Upload? [U/s/a/n/m/q/?]: "
+	if !upload {
+		prompt = "Upload? [u/S/a/n/m/q/?]: "
+	}
+	for {
+		fmt.Fprint(out, prompt)
+		if !scanner.Scan() {
+			fmt.Fprintln(out)
+			return "", errWizardQuit
+		}
+		answer := strings.ToLower(strings.TrimSpace(scanner.Text()))
+		switch answer {
+		case "":
+			if upload {
+				return "u", nil
+			}
+			return "s", nil
+		case "u", "s", "a", "n", "m":
+			return answer, nil
+		case "q":
+			return "", errWizardQuit
+		default:
+			fmt.Fprint(out, wizardChoices)
+		}
+	}
+}
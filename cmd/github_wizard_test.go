@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/containifyci/feller/pkg/state"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//nolint:paralleltest // modifies the global uploads
+func TestSelectSecretsInteractively(t *testing.T) {
+	t.Cleanup(func() { uploads = nil })
+	target := githubRepository{Repo: "owner/repo", Dependabot: true}
+	secrets := map[string]string{"A": "value-a", "B": "value-b", "C": "value-c", "D": "value-d"}
+	existing := &ExistingSecrets{
+		Repository: map[string]bool{"A": true, "B": true, "C": true, "D": true},
+		Dependabot: map[string]bool{"A": true, "C": true, "D": true},
+	}
+
+	tests := []struct {
+		name     string
+		input    string
+		expected []string
+		wantErr  string
+	}{
+		{name: "defaults", input: "\n\n\n\n", expected: []string{"A", "B", "C"}},
+		{name: "one by one", input: "s\nu\n\nu\n", expected: []string{"B", "C", "D"}},
+		{name: "all", input: "s\na\n", expected: []string{"B", "C", "D"}},
+		{name: "none", input: "u\nn\n", expected: []string{"A"}},
+		{name: "missing", input: "m\n", expected: []string{"B"}},
+		{name: "help", input: "?\nn\n", expected: []string{}},
+		{name: "quit", input: "u\nq\n", wantErr: "cancelled, no secrets were uploaded"},
+		{name: "end of input", input: "u\n", wantErr: "cancelled, no secrets were uploaded"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// D was uploaded unchanged to both scopes before
+			uploads, _ = state.LoadUploads(t.TempDir())
+			uploads.Record("github:owner/repo (repository)", "D", "value-d")
+			uploads.Record("github:owner/repo (Dependabot)", "D", "value-d")
+
+			var out bytes.Buffer
+			selected, err := selectSecretsInteractively(strings.NewReader(tt.input), &out, target, secrets, existing)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			keys := make([]string, 0, len(selected))
+			for key := range selected {
+				keys = append(keys, key)
+			}
+			assert.ElementsMatch(t, tt.expected, keys)
+			// Chosen explicitly, D is uploaded again
+			_, uploadD := selected["D"]
+			assert.Equal(t, !uploadD, uploads.Unchanged("github:owner/repo (repository)", "D", "value-d"))
+		})
+	}
+
+	uploads = nil
+	var out bytes.Buffer
+	_, err := selectSecretsInteractively(strings.NewReader("s\nn\n"), &out, target, secrets, existing)
+	require.NoError(t, err)
+	assert.Contains(t, out.String(), "[1/4] A = va***-a (repository: exists, Dependabot: exists)\nUpload? [U/s/a/n/m/q/?]: ")
+	assert.Contains(t, out.String(), "[2/4] B = va***-b (repository: exists, Dependabot: missing)")
+	assert.Contains(t, out.String(), "Uploading 0 secret(s), skipping 4")
+}
+
+func TestCountDeselected(t *testing.T) {
+	t.Parallel()
+	stats := &SecretOperationStats{}
+	countDeselected(stats, githubRepository{Repo: "owner/repo", Dependabot: true}, map[string]string{"A": "1", "B": "2"}, map[string]string{"A": "1"})
+	assert.Equal(t, 2, stats.Skipped)
+	assert.Equal(t, ScopeStats{Skipped: 1}, *stats.Scopes["Dependabot"])
+}
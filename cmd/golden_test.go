@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/containifyci/feller/pkg/ci"
+	"github.com/containifyci/feller/pkg/config"
+	"github.com/containifyci/feller/pkg/fellertest"
+	"github.com/containifyci/feller/pkg/providers"
+	"github.com/stretchr/testify/require"
+)
+
+// goldenSecrets covers the values that are hard to get right in every output format. The
+// golden files under testdata/golden lock in the exact output; regenerate them with
+// UPDATE_GOLDEN=1 go test ./cmd/ only for intentional format changes.
+var goldenSecrets = providers.SecretMap{
+	"API_TOKEN":    "abc123",
+	"DATABASE_URL": "postgres://user:p@ss w0rd@db:5432/app?sslmode=require",
+	"EMPTY":        "",
+	"JSON_BLOB":    `{"a": "b", "n": 1}`,
+	"MULTILINE":    "line one\nline two\n",
+	"OCTAL":        "0755",
+	"PADDED":       "  spaced  ",
+	"QUOTED":       `say "hi" and 'bye'`,
+	"SHELL":        `$(rm -rf /) && echo $HOME \ backslash`,
+	"TYPED":        "on",
+	"UNICODE":      "grüße 🚀",
+}
+
+var goldenSources = map[string]providers.SecretSource{
+	"API_TOKEN":    {Provider: "gsm", Kind: providers.KindGoogleSecretManager, MapID: "ci"},
+	"DATABASE_URL": {Provider: "gsm", Kind: providers.KindGoogleSecretManager, MapID: "ci"},
+	"JSON_BLOB":    {Provider: "local", Kind: providers.KindDotenv, MapID: "dev"},
+}
+
+// goldenMissing is out of order to check that messages sort providers and variables
+var goldenMissing = []providers.MissingVariable{
+	{VariableName: "GSM_DB_URL", MappedTo: "DATABASE_URL", Provider: "gsm"},
+	{VariableName: "SHARED_TOKEN", MappedTo: "TOKEN", Provider: "other"},
+	{VariableName: "GSM_API_TOKEN", MappedTo: "API_TOKEN", Provider: "gsm"},
+	{VariableName: "SHARED_TOKEN", MappedTo: "TOKEN", Provider: "gsm"},
+}
+
+//nolint:paralleltest // modifies global flag variables
+func TestGoldenExport(t *testing.T) {
+	originalQuote, originalDelimiter := exportQuote, csvDelimiter
+	originalNoHeader, originalColumns := csvNoHeader, csvExtraColumns
+	t.Cleanup(func() {
+		exportQuote, csvDelimiter = originalQuote, originalDelimiter
+		csvNoHeader, csvExtraColumns = originalNoHeader, originalColumns
+	})
+
+	tests := []struct {
+		name      string
+		format    string
+		quote     string
+		delimiter string
+		columns   []string
+		secrets   providers.SecretMap
+	}{
+		{name: "json", format: "json"},
+		{name: "yaml", format: "yaml"},
+		{name: "env-always", format: "env", quote: quoteAlways},
+		{name: "env-auto", format: "env", quote: quoteAuto},
+		{name: "env-never", format: "env", quote: quoteNever, secrets: filterSecrets(goldenSecrets, nil, []string{"MULTILINE"})},
+		{name: "csv", format: "csv"},
+		{name: "csv-sources", format: "csv", delimiter: ";", columns: []string{"provider", "map_id"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exportQuote = tt.quote
+			csvDelimiter = ","
+			if tt.delimiter != "" {
+				csvDelimiter = tt.delimiter
+			}
+			csvNoHeader = false
+			csvExtraColumns = tt.columns
+			secrets := tt.secrets
+			if secrets == nil {
+				secrets = goldenSecrets
+			}
+
+			var buf bytes.Buffer
+			require.NoError(t, writeExport(&buf, tt.format, secrets, goldenSources))
+			fellertest.AssertGolden(t, "golden/export/"+tt.name, buf.Bytes())
+		})
+	}
+}
+
+func TestGoldenShell(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	require.NoError(t, writeShellExports(&buf, goldenSecrets))
+	fellertest.AssertGolden(t, "golden/export/sh", buf.Bytes())
+}
+
+func TestGoldenMissingVariables(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		ctx  providers.MissingContext
+	}{
+		{name: "run", ctx: missingRun},
+		{name: "export", ctx: missingExport},
+		{name: "sh", ctx: missingShell},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			err := missingVariablesError(goldenMissing, tt.ctx, config.Messages{})
+			require.Error(t, err)
+			fellertest.AssertGolden(t, "golden/missing/"+tt.name, []byte(err.Error()+"\n"))
+		})
+	}
+}
+
+func TestGoldenMissingVariablesPlatforms(t *testing.T) {
+	t.Parallel()
+	for _, id := range []string{ci.GitLab, ci.CircleCI, ci.Buildkite, ci.Jenkins} {
+		t.Run(id, func(t *testing.T) {
+			t.Parallel()
+			ctx := missingRun
+			ctx.Platform, _ = ci.Lookup(id)
+			msg, err := providers.FormatMissing(goldenMissing, ctx, "")
+			require.NoError(t, err)
+			fellertest.AssertGolden(t, "golden/missing/run-"+id, []byte(msg+"\n"))
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/containifyci/feller/pkg/logger"
+)
+
+// runHooks executes each hook through the shell with the given environment, stopping at the first failure
+func runHooks(stage string, hooks, env []string) error {
+	if len(hooks) == 0 {
+		return nil
+	}
+
+	logger.Debug("Running %d %s hook(s)", len(hooks), stage)
+	shell := resolveShell()
+
+	for i, hook := range hooks {
+		logger.Verbose("Running %s hook %d: %s", stage, i+1, hook)
+
+		// #nosec G204 - Hooks are user-provided commands by design
+		cmd := exec.CommandContext(context.Background(), shell, "-c", hook)
+		cmd.Env = env
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		cmd.Stdin = os.Stdin
+
+		if err := cmd.Run(); err != nil {
+			logger.Debug("%s hook %d failed: %v", stage, i+1, err)
+			return fmt.Errorf("%s hook %q failed: %w", stage, hook, err)
+		}
+	}
+
+	logger.Debug("Completed %s hooks", stage)
+	return nil
+}
+
+// runPostHooks runs post-run hooks regardless of the command result so teardown steps can
+// clean up after failures; the command error takes precedence over a hook error
+func runPostHooks(hooks, env []string, cmdErr error) error {
+	if err := runHooks("post-run", hooks, env); err != nil {
+		if cmdErr != nil {
+			logger.Error("%v", err)
+			return cmdErr
+		}
+		return err
+	}
+	return cmdErr
+}
@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunHooks(t *testing.T) {
+	t.Setenv("SHELL", "/bin/sh")
+
+	tests := []struct {
+		name        string
+		errContains string
+		hooks       []string
+		env         []string
+		wantErr     bool
+	}{
+		{
+			name:    "no hooks",
+			hooks:   nil,
+			wantErr: false,
+		},
+		{
+			name:    "successful hooks",
+			hooks:   []string{"true", "exit 0"},
+			env:     []string{"PATH=" + PATH},
+			wantErr: false,
+		},
+		{
+			name:    "hook sees injected environment",
+			hooks:   []string{`test "$HOOK_SECRET" = "s3cret"`},
+			env:     []string{"PATH=" + PATH, "HOOK_SECRET=s3cret"},
+			wantErr: false,
+		},
+		{
+			name:        "failing hook stops execution",
+			hooks:       []string{"exit 3", "true"},
+			env:         []string{"PATH=" + PATH},
+			wantErr:     true,
+			errContains: `pre-run hook "exit 3" failed`,
+		},
+	}
+
+	for _, tt := range tests { //nolint:paralleltest // parent uses t.Setenv()
+		t.Run(tt.name, func(t *testing.T) {
+			err := runHooks("pre-run", tt.hooks, tt.env)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("runHooks() expected error but got none")
+				} else if tt.errContains != "" && !strings.Contains(err.Error(), tt.errContains) {
+					t.Errorf("runHooks() error = %v, expected to contain %q", err, tt.errContains)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("runHooks() unexpected error = %v", err)
+			}
+		})
+	}
+}
+
+func TestRunCommandHooks(t *testing.T) {
+	originalCfgFile := cfgFile
+	originalPre := preHooks
+	originalPost := postHooks
+	t.Cleanup(func() {
+		cfgFile = originalCfgFile
+		preHooks = originalPre
+		postHooks = originalPost
+	})
+
+	t.Setenv("GITHUB_ACTIONS", "true")
+	t.Setenv("SHELL", "/bin/sh")
+	t.Setenv("HOOK_VAR", "hook_value")
+
+	dir := t.TempDir()
+	marker := filepath.Join(dir, "marker")
+	configPath := filepath.Join(dir, "teller.yml")
+	content := `providers:
+  test-gsm:
+    kind: google_secretmanager
+    maps:
+      - id: test
+        path: projects/test/secrets/test
+        keys:
+          HOOK_VAR: MAPPED_HOOK_VAR
+hooks:
+  pre_run:
+    - echo "pre:$MAPPED_HOOK_VAR" >> ` + marker + `
+`
+	if err := os.WriteFile(configPath, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	cfgFile = configPath
+	preHooks = nil
+	postHooks = []string{`echo "post" >> ` + marker}
+
+	// The post hook must still run when the command itself fails
+	err := runCommand(nil, []string{"nonexistent-command-12345"})
+	if err == nil || !strings.Contains(err.Error(), "direct command execution failed") {
+		t.Errorf("runCommand() error = %v, expected command failure", err)
+	}
+
+	data, err := os.ReadFile(marker)
+	if err != nil {
+		t.Fatalf("Failed to read hook marker: %v", err)
+	}
+	if got := string(data); got != "pre:hook_value\npost\n" {
+		t.Errorf("hooks wrote %q, want %q", got, "pre:hook_value\npost\n")
+	}
+}
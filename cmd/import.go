@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/containifyci/feller/pkg/bundle"
+	"github.com/containifyci/feller/pkg/config"
+	"github.com/containifyci/feller/pkg/logger"
+	"github.com/containifyci/feller/pkg/providers"
+	"github.com/spf13/cobra"
+)
+
+var (
+	importName     string
+	importIdentity string
+)
+
+// bundleProviderConfig is the provider entry added by import bundle, in the field order
+// of formatted configs
+type bundleProviderConfig struct {
+	Kind    string               `yaml:"kind"`
+	Maps    []config.PathMap     `yaml:"maps"`
+	Options *bundleImportOptions `yaml:"options,omitempty"`
+}
+
+type bundleImportOptions struct {
+	Identity string `yaml:"identity"`
+}
+
+// importCmd represents the import command
+var importCmd = &cobra.Command{
+	Use:   "import bundle FILE",
+	Short: "Add an encrypted secret bundle to the config as a provider",
+	Long: `Add a bundle written by 'feller export bundle' to the config as a provider
+of kind bundle, so later commands resolve its secrets like any other.
+
+The bundle is decrypted once to check it can be read, with the identity file
+given by --identity or the age identity in the FELLER_AGE_KEY environment
+variable. Without either the check is skipped. --identity is stored in the
+provider options; FELLER_AGE_KEY must also be set when the bundle is used.
+
+Comments and formatting of the config are kept. The age tool must be installed.
+
+Examples:
+  feller import bundle secrets.age
+  feller import bundle secrets.age --name build --identity key.txt`,
+	Args:      cobra.ExactArgs(2),
+	ValidArgs: []string{formatBundle},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if args[0] != formatBundle {
+			return fmt.Errorf("unsupported import format: %s (expected bundle)", args[0])
+		}
+		return importBundle(cmd, args[1])
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(importCmd)
+	importCmd.Flags().StringVar(&importName, "name", formatBundle, "Name of the provider to add")
+	importCmd.Flags().StringVar(&importIdentity, "identity", "", "age identity file that decrypts the bundle")
+}
+
+// importBundle adds the bundle at path to the config as a provider named importName
+func importBundle(cmd *cobra.Command, path string) error {
+	configPath, err := config.ResolveConfigPath(cfgFile)
+	if err != nil {
+		return fmt.Errorf("failed to resolve config path: %w", err)
+	}
+	doc, err := config.LoadDocument(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if doc.Lookup("providers", importName) != nil {
+		return fmt.Errorf("provider %s already exists in %s, choose another with --name", importName, configPath)
+	}
+
+	provider := bundleProviderConfig{
+		Kind: providers.KindBundle,
+		Maps: []config.PathMap{{ID: importName, Path: path}},
+	}
+	if importIdentity != "" {
+		provider.Options = &bundleImportOptions{Identity: importIdentity}
+	}
+
+	if importIdentity != "" || os.Getenv(bundle.KeyEnv) != "" {
+		b, err := providers.LoadBundle(path, importIdentity)
+		if err != nil {
+			return err //nolint:wrapcheck // names the bundle
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Bundle %s holds %d secret(s) created %s\n", path, len(b.Secrets), b.Created.Local().Format(time.DateTime))
+	} else {
+		logger.Info("No age identity given, adding %s without checking that it can be decrypted", path)
+	}
+
+	if err := doc.Set(provider, "providers", importName); err != nil {
+		return fmt.Errorf("failed to add provider: %w", err)
+	}
+	if err := doc.WriteFile(configPath); err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Added provider %s to %s\n", importName, configPath)
+	return nil
+}
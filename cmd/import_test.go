@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/containifyci/feller/pkg/config"
+	"github.com/containifyci/feller/pkg/fellertest"
+	"github.com/containifyci/feller/pkg/providers"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//nolint:paralleltest // modifies environment variables and global flag variables
+func TestExportImportBundle(t *testing.T) {
+	fellertest.FakeAge(t)
+	t.Setenv("GITHUB_ACTIONS", "true")
+
+	originalCfg, originalOut, originalRecipients := cfgFile, exportOut, exportRecipients
+	originalName, originalIdentity := importName, importIdentity
+	t.Cleanup(func() {
+		cfgFile, exportOut, exportRecipients = originalCfg, originalOut, originalRecipients
+		importName, importIdentity = originalName, originalIdentity
+	})
+
+	// Stage one exports its secrets as a bundle
+	source := fellertest.NewConfig().Provider("gsm", fellertest.FakeGSM(t, map[string]string{"API_KEY": "abc", "DB_URL": "postgres://db"})).Build()
+	cfgFile = fellertest.WriteConfig(t, source)
+	dir := t.TempDir()
+	exportOut = filepath.Join(dir, "secrets.age")
+	exportRecipients = []string{fellertest.FakeAgeRecipient}
+	require.NoError(t, exportSecrets(&cobra.Command{}, []string{formatBundle}))
+
+	// Stage two imports it into its own config, keeping existing content
+	target := filepath.Join(dir, ".teller.yml")
+	require.NoError(t, os.WriteFile(target, []byte("# stage two\nproviders:\n  local:\n    kind: dotenv # local overrides\n    maps: []\n"), 0o600))
+	identity := filepath.Join(dir, "key.txt")
+	require.NoError(t, os.WriteFile(identity, []byte("AGE-SECRET-KEY-1\n"), 0o600))
+	cfgFile, importName, importIdentity = target, "handoff", identity
+
+	var out bytes.Buffer
+	cmd := &cobra.Command{}
+	cmd.SetOut(&out)
+	require.NoError(t, importBundle(cmd, exportOut))
+	assert.Contains(t, out.String(), "holds 2 secret(s)")
+	assert.Contains(t, out.String(), "Added provider handoff to "+target)
+
+	data, err := os.ReadFile(target)
+	require.NoError(t, err)
+	assert.Equal(t, "# stage two\nproviders:\n  local:\n    kind: dotenv # local overrides\n    maps: []\n"+
+		"  handoff:\n    kind: bundle\n    maps:\n      - id: handoff\n        path: "+exportOut+"\n    options:\n      identity: "+identity+"\n", string(data))
+
+	cfg, err := config.LoadConfig(target)
+	require.NoError(t, err)
+	result := fellertest.Collect(t, cfg)
+	assert.Equal(t, providers.SecretMap{"API_KEY": "abc", "DB_URL": "postgres://db"}, result.Secrets)
+
+	// The bundle keeps where each secret originally came from
+	b, err := providers.LoadBundle(exportOut, identity)
+	require.NoError(t, err)
+	assert.Equal(t, "gsm", b.Secrets["API_KEY"].Provider)
+
+	// Importing under the same name again is refused
+	require.ErrorContains(t, importBundle(cmd, exportOut), "provider handoff already exists")
+}
+
+//nolint:paralleltest // modifies global flag variables
+func TestExportBundleFlagErrors(t *testing.T) {
+	originalRecipients := exportRecipients
+	t.Cleanup(func() { exportRecipients = originalRecipients })
+
+	exportRecipients = nil
+	require.ErrorContains(t, exportSecrets(&cobra.Command{}, []string{formatBundle}), "requires --recipients")
+
+	exportRecipients = []string{fellertest.FakeAgeRecipient}
+	require.ErrorContains(t, exportSecrets(&cobra.Command{}, []string{"json"}), "only supported by the bundle format")
+
+	t.Setenv("GITHUB_ACTIONS", "")
+	require.ErrorContains(t, exportSecrets(&cobra.Command{}, []string{formatBundle}), "not supported by teller fallback mode")
+}
@@ -0,0 +1,184 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/containifyci/feller/pkg/providers"
+	"github.com/spf13/cobra"
+)
+
+var (
+	inspectPID  int
+	inspectJSON bool
+)
+
+// Statuses of a key in the inspected environment
+const (
+	inspectPresent    = "present"
+	inspectAbsent     = "absent"
+	inspectMismatched = "mismatched"
+)
+
+var missingInspect = providers.MissingContext{
+	Command: "inspect",
+	Prefix:  "Cannot inspect the process: ",
+	Step:    "Inspect process environment",
+	Run:     "feller inspect",
+	Hint:    "Resolve every secret to compare all of them, or use --silent to compare the available secrets only.",
+}
+
+// inspectResult is the status of one key in the environment of the inspected process
+type inspectResult struct {
+	Key      string `json:"key"`
+	Status   string `json:"status"`
+	Provider string `json:"provider"`
+	Map      string `json:"map,omitempty"`
+}
+
+// inspectCmd represents the inspect command
+var inspectCmd = &cobra.Command{
+	Use:   "inspect --pid N",
+	Short: "Check which secrets a running process received",
+	Long: `Resolve secrets and compare them with the environment of a running process,
+reporting every key as present, absent or mismatched. This answers "did my
+service get the secret?" without printing any value: values are compared by
+their SHA-256 digest.
+
+The environment is read from /proc/N/environ, so this is only supported on
+Linux, and only for processes of the same user unless run as root. It is the
+environment the process was started with; changes the process made to its
+own environment afterwards are not visible.
+
+The command fails when a key is absent or mismatched. Secrets are resolved by
+feller itself, so providers must be of kinds feller supports.
+
+Examples:
+  feller inspect --pid "$(pgrep -f api-server)"
+  feller inspect --pid 4242 --json | jq -r '.[] | select(.status != "present") | .key'`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		return runInspect(cmd.OutOrStdout())
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(inspectCmd)
+	inspectCmd.Flags().IntVar(&inspectPID, "pid", 0, "ID of the process to inspect")
+	inspectCmd.Flags().BoolVar(&inspectJSON, "json", false, "Output as JSON")
+	_ = inspectCmd.MarkFlagRequired("pid")
+}
+
+func runInspect(out io.Writer) error {
+	if inspectPID <= 0 {
+		return fmt.Errorf("invalid --pid %d", inspectPID)
+	}
+	environ, err := readProcessEnviron(inspectPID)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if err := checkNativeKinds(cfg); err != nil {
+		return err
+	}
+	result, err := collectSecrets(cfg)
+	if err != nil {
+		return err
+	}
+	if err := checkMissing(result, missingInspect, cfg.Messages); err != nil {
+		return err
+	}
+
+	if len(result.Secrets) == 0 {
+		return errors.New("no secrets resolved, nothing to inspect")
+	}
+
+	results := inspectEnviron(result, environ)
+	if err := writeInspect(out, results); err != nil {
+		return err
+	}
+	var failed int
+	for _, r := range results {
+		if r.Status != inspectPresent {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d key(s) absent or mismatched in process %d", failed, len(results), inspectPID)
+	}
+	return nil
+}
+
+// inspectEnviron compares the collected secrets with environ, sorted by key
+func inspectEnviron(result *providers.CollectionResult, environ map[string]string) []inspectResult {
+	keys := make([]string, 0, len(result.Secrets))
+	for key := range result.Secrets {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	results := make([]inspectResult, len(keys))
+	for i, key := range keys {
+		source := result.Sources[key]
+		status := inspectPresent
+		if value, ok := environ[key]; !ok {
+			status = inspectAbsent
+		} else if !sameDigest(value, result.Secrets[key]) {
+			status = inspectMismatched
+		}
+		results[i] = inspectResult{Key: key, Status: status, Provider: source.Provider, Map: source.MapID}
+	}
+	return results
+}
+
+// sameDigest compares the SHA-256 digests of two values, so the comparison never handles
+// the values beyond hashing them and takes the same time wherever they differ
+func sameDigest(a, b string) bool {
+	x, y := sha256.Sum256([]byte(a)), sha256.Sum256([]byte(b))
+	return subtle.ConstantTimeCompare(x[:], y[:]) == 1
+}
+
+// parseEnviron parses the NUL-separated KEY=VALUE entries of /proc/N/environ
+func parseEnviron(data []byte) map[string]string {
+	env := make(map[string]string)
+	for _, entry := range bytes.Split(data, []byte{0}) {
+		key, value, found := strings.Cut(string(entry), "=")
+		// Windows-style "=C:" entries have an empty name and are skipped
+		if found && key != "" {
+			env[key] = value
+		}
+	}
+	return env
+}
+
+// writeInspect prints the results as a table or JSON
+func writeInspect(out io.Writer, results []inspectResult) error {
+	if inspectJSON {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(results); err != nil {
+			return fmt.Errorf("failed to encode results: %w", err)
+		}
+		return nil
+	}
+	w := newTable(out)
+	fmt.Fprintln(w, "KEY\tSTATUS\tPROVIDER\tMAP")
+	for _, r := range results {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", r.Key, r.Status, r.Provider, r.Map)
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to write results: %w", err)
+	}
+	return nil
+}
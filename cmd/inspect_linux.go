@@ -0,0 +1,23 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// readProcessEnviron reads the environment process pid was started with from /proc
+func readProcessEnviron(pid int) (map[string]string, error) {
+	path := fmt.Sprintf("/proc/%d/environ", pid)
+	// #nosec G304 - The path is built from a process ID
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return nil, fmt.Errorf("no process with PID %d", pid)
+	case errors.Is(err, os.ErrPermission):
+		return nil, fmt.Errorf("cannot read the environment of process %d: it belongs to another user (run as that user or root)", pid)
+	case err != nil:
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return parseEnviron(data), nil
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package cmd
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// readProcessEnviron is only supported on Linux, where /proc exposes the environment
+func readProcessEnviron(int) (map[string]string, error) {
+	return nil, fmt.Errorf("inspecting the environment of a process is not supported on %s, only on linux", runtime.GOOS)
+}
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/containifyci/feller/pkg/fellertest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// inspectHelperEnv makes TestInspectHelperProcess wait instead of returning at once
+const inspectHelperEnv = "FELLER_INSPECT_HELPER"
+
+// TestInspectHelperProcess is the process inspected by TestRunInspect
+func TestInspectHelperProcess(t *testing.T) {
+	t.Parallel()
+	if os.Getenv(inspectHelperEnv) == "" {
+		return
+	}
+	time.Sleep(time.Minute)
+}
+
+func TestParseEnviron(t *testing.T) {
+	t.Parallel()
+	env := parseEnviron([]byte("A=1\x00B=x=y\x00EMPTY=\x00=C:=C:\\\x00junk\x00"))
+	assert.Equal(t, map[string]string{"A": "1", "B": "x=y", "EMPTY": ""}, env)
+}
+
+//nolint:paralleltest // Modifies the global config path and inspect flags
+func TestRunInspect(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("inspect reads /proc")
+	}
+	t.Cleanup(func() { cfgFile, inspectPID, inspectJSON = "", 0, false })
+	dotenv := fellertest.FakeDotenv(t, map[string]string{"API_KEY": "abc", "DB_URL": "postgres://db", "TOKEN": "t"})
+	cfgFile = fellertest.WriteConfig(t, fellertest.NewConfig().Provider("local", dotenv).Build())
+
+	// The test binary itself is the inspected process, so the test does not depend on PATH
+	ctx, cancel := context.WithCancel(context.Background())
+	process := exec.CommandContext(ctx, os.Args[0], "-test.run=^TestInspectHelperProcess$")
+	process.Env = []string{inspectHelperEnv + "=1", "API_KEY=abc", "DB_URL=postgres://other"}
+	require.NoError(t, process.Start())
+	t.Cleanup(func() {
+		cancel()
+		_ = process.Wait()
+	})
+	inspectPID = process.Process.Pid
+
+	var out bytes.Buffer
+	err := runInspect(&out)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "2 of 3 key(s) absent or mismatched")
+	assert.Regexp(t, `API_KEY\s+present\s+local\s+\w+\nDB_URL\s+mismatched\s+local\s+\w+\nTOKEN\s+absent\s+local`, out.String())
+	assert.NotContains(t, out.String(), "postgres")
+
+	inspectJSON = true
+	out.Reset()
+	require.Error(t, runInspect(&out))
+	var results []inspectResult
+	require.NoError(t, json.Unmarshal(out.Bytes(), &results))
+	require.Len(t, results, 3)
+	assert.Equal(t, inspectMismatched, results[1].Status)
+
+	inspectPID = 1 << 30
+	err = runInspect(&out)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no process with PID")
+}
@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/containifyci/feller/pkg/config"
+	"github.com/containifyci/feller/pkg/logger"
+	"github.com/containifyci/feller/pkg/providers"
+	"github.com/containifyci/feller/pkg/state"
+)
+
+// strictPermissions fails commands when secret files are readable by group or others,
+// instead of warning
+var strictPermissions bool
+
+// checkDotenvFiles checks the permissions of the dotenv files of cfg and warns about files
+// that changed since feller last read them. Changes are only reported, while exposed files
+// fail the command with --strict.
+func checkDotenvFiles(cfg *config.TellerConfig) error {
+	paths := providers.DotenvFiles(cfg)
+	if err := checkPermissions(paths); err != nil {
+		return err
+	}
+	for _, change := range dotenvChanges(paths) {
+		logger.Error("WARNING: %s", change)
+	}
+	return nil
+}
+
+// checkPermissions reports the secret files at paths that users other than their owner can
+// read, as an error with --strict and as warnings otherwise
+func checkPermissions(paths []string) error {
+	var exposed []string
+	for _, path := range paths {
+		if mode, ok := providers.ExposedMode(path); ok {
+			exposed = append(exposed, fmt.Sprintf("%s holds secrets and is readable by group or others (mode %04o). Restrict it with: chmod 600 %s", path, mode, path))
+		}
+	}
+	if len(exposed) == 0 {
+		return nil
+	}
+	if strictPermissions {
+		return fmt.Errorf("refusing to use exposed secret files (--strict):\n  %s", strings.Join(exposed, "\n  "))
+	}
+	for _, problem := range exposed {
+		logger.Error("WARNING: %s", problem)
+	}
+	return nil
+}
+
+// dotenvChanges records the checksums of the dotenv files at paths for the next run and
+// describes those that changed since the last one
+func dotenvChanges(paths []string) []string {
+	if len(paths) == 0 {
+		return nil
+	}
+	var changes []string
+	updateChecksums(func(checksums *state.Checksums) {
+		for _, path := range paths {
+			if previous, changed := recordChecksum(checksums, path); changed {
+				changes = append(changes, fmt.Sprintf("%s changed since feller read it on %s. If you did not edit it, check who did before trusting its secrets.",
+					path, previous.Recorded.Local().Format(time.DateTime)))
+			}
+		}
+	})
+	return changes
+}
+
+// recordDotenvFile records the checksum of a dotenv file feller wrote itself, so the next
+// run does not report the change
+func recordDotenvFile(path string) {
+	updateChecksums(func(checksums *state.Checksums) {
+		recordChecksum(checksums, path)
+	})
+}
+
+// recordChecksum records the checksum of the file at path and returns the previous one,
+// with changed set when the file changed since it was recorded
+func recordChecksum(checksums *state.Checksums, path string) (previous state.FileChecksum, changed bool) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return state.FileChecksum{}, false
+	}
+	sum, err := state.FileSHA256(abs)
+	if err != nil {
+		logger.Debug("Not recording the checksum of %s: %v", path, err)
+		return state.FileChecksum{}, false
+	}
+	return checksums.Record(abs, sum)
+}
+
+// updateChecksums applies update to the checksums in the state directory of the config
+func updateChecksums(update func(*state.Checksums)) {
+	configPath, err := config.ResolveConfigPath(cfgFile)
+	if err != nil {
+		logger.Debug("Not recording checksums: %v", err)
+		return
+	}
+	dir := state.Dir(configPath)
+	checksums, err := state.LoadChecksums(dir)
+	if err != nil {
+		logger.Debug("Not recording checksums: %v", err)
+		return
+	}
+	update(checksums)
+	if err := checksums.Write(dir); err != nil {
+		logger.Debug("Not recording checksums: %v", err)
+	}
+}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/containifyci/feller/pkg/fellertest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//nolint:paralleltest // Modifies the global config path
+func TestDotenvChanges(t *testing.T) {
+	t.Cleanup(func() { cfgFile = "" })
+	dotenv := fellertest.FakeDotenv(t, map[string]string{"API_KEY": "abc"})
+	cfgFile = fellertest.WriteConfig(t, fellertest.NewConfig().Provider("local", dotenv).Build())
+	path := dotenv.Maps[0].Path
+	paths := []string{path}
+
+	// The first run records the checksum, the second finds it unchanged
+	assert.Empty(t, dotenvChanges(paths))
+	assert.Empty(t, dotenvChanges(paths))
+
+	require.NoError(t, os.WriteFile(path, []byte("API_KEY=tampered\n"), 0o600))
+	changes := dotenvChanges(paths)
+	require.Len(t, changes, 1)
+	assert.Contains(t, changes[0], path+" changed since feller read it on ")
+	assert.Empty(t, dotenvChanges(paths), "a change is reported once")
+
+	// Files written by feller itself are not reported
+	require.NoError(t, restoreDotenv(path, map[string]string{"API_KEY": "restored"}))
+	assert.Empty(t, dotenvChanges(paths))
+}
+
+//nolint:paralleltest // Modifies the global strict flag
+func TestCheckPermissions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows has no group and other permission bits")
+	}
+	t.Cleanup(func() { strictPermissions = false })
+	dir := t.TempDir()
+	private := filepath.Join(dir, "private.env")
+	shared := filepath.Join(dir, "shared.env")
+	require.NoError(t, os.WriteFile(private, []byte("A=1\n"), 0o600))
+	require.NoError(t, os.WriteFile(shared, []byte("A=1\n"), 0o600))
+	require.NoError(t, os.Chmod(shared, 0o640))
+
+	// Without --strict exposed files are only reported
+	require.NoError(t, checkPermissions([]string{private, shared, filepath.Join(dir, "missing.env")}))
+
+	strictPermissions = true
+	require.NoError(t, checkPermissions([]string{private}))
+	err := checkPermissions([]string{private, shared})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), shared+" holds secrets and is readable by group or others (mode 0640). Restrict it with: chmod 600 "+shared)
+	assert.NotContains(t, err.Error(), private)
+
+	// An existing --out target keeps its permissions, so it is checked too
+	err = writeOutput(nil, shared, []byte("A=2\n"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "refusing to use exposed secret files (--strict)")
+	require.NoError(t, writeOutput(nil, filepath.Join(dir, "new.env"), []byte("A=2\n")))
+}
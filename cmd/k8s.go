@@ -0,0 +1,165 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"github.com/containifyci/feller/pkg/logger"
+	"github.com/containifyci/feller/pkg/providers"
+	"github.com/spf13/cobra"
+)
+
+// formatFiles writes every secret to a file named after its key, like a Kubernetes secret volume
+const formatFiles = "files"
+
+var (
+	k8sOutDir  string
+	k8sFormats []string
+	k8sMode    string
+)
+
+var missingK8sInit = providers.MissingContext{
+	Command: "k8s init",
+	Prefix:  "Cannot write secrets for the pod: ",
+	Step:    "Write secrets",
+	Run:     "feller k8s init --out-dir /shared",
+	Hint:    "In a pod, set them in the init container's environment, e.g. from a Kubernetes Secret with envFrom, or use --silent to write the available secrets only.",
+}
+
+// k8sCmd represents the k8s command group
+var k8sCmd = &cobra.Command{
+	Use:   "k8s",
+	Short: "Kubernetes integration",
+	Long: `Commands for running feller in Kubernetes pods.
+
+Available subcommands:
+  init  Write secrets into a shared volume from an init container
+
+Examples:
+  feller k8s init --out-dir /shared`,
+}
+
+// k8sInitCmd represents the k8s init command
+var k8sInitCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Write secrets into a shared volume from an init container",
+	Long: `Resolve secrets and write them into a directory shared with the main
+container, typically an emptyDir volume mounted by an init container.
+
+Formats (--format, comma-separated, default env):
+  env    secrets.env with KEY="value" lines, e.g. for 'set -a; . /shared/secrets.env'
+  json   secrets.json with a JSON object
+  yaml   secrets.yaml with a YAML document
+  files  one file per key named after the key, like a Kubernetes secret volume
+
+Files are written atomically with --mode permissions (default 0400). Give the
+pod an fsGroup, or use --mode 0440, when the main container runs as another user.
+
+The config is found like 'feller entrypoint' does: --config, FELLER_CONFIG,
+or /etc/feller/.teller.yml. Secrets are always resolved by feller itself.
+
+Examples:
+  feller k8s init --out-dir /shared
+  feller k8s init --out-dir /shared --format env,files --mode 0440`,
+	Args: cobra.NoArgs,
+	RunE: func(_ *cobra.Command, _ []string) error {
+		return runK8sInit()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(k8sCmd)
+	k8sCmd.AddCommand(k8sInitCmd)
+	k8sInitCmd.Flags().StringVar(&k8sOutDir, "out-dir", "", "Directory shared with the main container, e.g. an emptyDir mount")
+	k8sInitCmd.Flags().StringSliceVar(&k8sFormats, "format", []string{"env"}, "Formats to write (env, json, yaml, files)")
+	k8sInitCmd.Flags().StringVar(&k8sMode, "mode", "0400", "Permissions of the written files (octal)")
+	_ = k8sInitCmd.MarkFlagRequired("out-dir")
+}
+
+func runK8sInit() error {
+	mode, err := strconv.ParseUint(k8sMode, 8, 32)
+	if err != nil || mode > 0o777 {
+		return fmt.Errorf("invalid --mode %q (expected octal permissions such as 0400)", k8sMode)
+	}
+	for _, format := range k8sFormats {
+		switch format {
+		case "env", "json", "yaml", formatFiles:
+		default:
+			return fmt.Errorf("unsupported format: %s (expected env, json, yaml or files)", format)
+		}
+	}
+
+	secrets, err := resolveContainerSecrets(missingK8sInit)
+	if err != nil {
+		return err
+	}
+	// Env files are sourced by shells, so values are always quoted
+	exportQuote = quoteAlways
+
+	if err := os.MkdirAll(k8sOutDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	for _, format := range k8sFormats {
+		if err := writeK8sFormat(k8sOutDir, format, secrets, os.FileMode(mode)); err != nil {
+			return err
+		}
+	}
+	logger.Info("Wrote %d secret(s) to %s", len(secrets), k8sOutDir)
+	return nil
+}
+
+// writeK8sFormat writes secrets into dir in one format
+func writeK8sFormat(dir, format string, secrets providers.SecretMap, mode os.FileMode) error {
+	if format != formatFiles {
+		var buf bytes.Buffer
+		if err := writeExport(&buf, format, secrets, nil); err != nil {
+			return err
+		}
+		return writeFileAtomic(filepath.Join(dir, "secrets."+format), buf.Bytes(), mode)
+	}
+
+	keys := make([]string, 0, len(secrets))
+	for key := range secrets {
+		if key == "" || key == "." || key == ".." || filepath.Base(key) != key {
+			return fmt.Errorf("key %q cannot be used as a file name", key)
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if err := writeFileAtomic(filepath.Join(dir, key), []byte(secrets[key]), mode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeFileAtomic replaces path with data, so a reader never sees a partially written file
+func writeFileAtomic(path string, data []byte, mode os.FileMode) error {
+	f, err := os.CreateTemp(filepath.Dir(path), ".feller-*")
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	defer os.Remove(f.Name())
+
+	// Restrict the file before writing the secret into it
+	err = f.Chmod(mode)
+	if err == nil {
+		_, err = f.Write(data)
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	logger.Debug("Wrote %s", path)
+	return nil
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/containifyci/feller/pkg/fellertest"
+	"github.com/containifyci/feller/pkg/providers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//nolint:paralleltest // Modifies environment variables and the global k8s flags
+func TestRunK8sInit(t *testing.T) {
+	cfg := fellertest.NewConfig().Provider("env", fellertest.FakeDotenv(t, map[string]string{"API_KEY": "abc", "DB_URL": "postgres://db"})).Build()
+	t.Setenv(configEnv, fellertest.WriteConfig(t, cfg))
+	t.Cleanup(func() { cfgFile, k8sOutDir, k8sFormats, k8sMode = "", "", []string{"env"}, "0400" })
+
+	t.Run("env and files", func(t *testing.T) {
+		k8sOutDir = filepath.Join(t.TempDir(), "shared")
+		k8sFormats, k8sMode = []string{"env", "files"}, "0440"
+		require.NoError(t, runK8sInit())
+
+		env, err := os.ReadFile(filepath.Join(k8sOutDir, "secrets.env"))
+		require.NoError(t, err)
+		assert.Equal(t, "API_KEY=\"abc\"\nDB_URL=\"postgres://db\"\n", string(env))
+
+		value, err := os.ReadFile(filepath.Join(k8sOutDir, "DB_URL"))
+		require.NoError(t, err)
+		assert.Equal(t, "postgres://db", string(value))
+
+		if runtime.GOOS != "windows" {
+			info, err := os.Stat(filepath.Join(k8sOutDir, "API_KEY"))
+			require.NoError(t, err)
+			assert.Equal(t, os.FileMode(0o440), info.Mode().Perm())
+		}
+
+		// Running again replaces the read-only files
+		require.NoError(t, runK8sInit())
+	})
+
+	t.Run("invalid mode", func(t *testing.T) {
+		k8sOutDir, k8sFormats, k8sMode = t.TempDir(), []string{"env"}, "rw"
+		err := runK8sInit()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `invalid --mode "rw"`)
+	})
+
+	t.Run("unsupported format", func(t *testing.T) {
+		k8sOutDir, k8sFormats, k8sMode = t.TempDir(), []string{"csv"}, "0400"
+		err := runK8sInit()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "unsupported format: csv")
+	})
+}
+
+func TestWriteK8sFilesRejectsPaths(t *testing.T) {
+	t.Parallel()
+	err := writeK8sFormat(t.TempDir(), formatFiles, providers.SecretMap{"../escape": "x"}, 0o400)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `key "../escape" cannot be used as a file name`)
+}
@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/containifyci/feller/pkg/config"
+	"github.com/containifyci/feller/pkg/logger"
+	"github.com/containifyci/feller/pkg/state"
+)
+
+// lockConfig takes the lock of the selected config for a mutating operation, so two feller
+// processes cannot change the same secrets at once. The returned function releases it.
+func lockConfig(operation string) (func(), error) {
+	path, err := config.ResolveConfigPath(cfgFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve config path: %w", err)
+	}
+
+	lock, err := state.Acquire(state.LockPath(path), operation)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // describes the lock holder
+	}
+	logger.Debug("Acquired lock for %s on %s", operation, path)
+
+	return func() {
+		if err := lock.Release(); err != nil {
+			logger.Error("%v", err)
+		}
+	}, nil
+}
+
+// lockUnlessDryRun takes the lock of the selected config like lockConfig, unless dryRun is
+// set: dry runs change nothing, so they neither wait for nor block other processes.
+func lockUnlessDryRun(operation string, dryRun bool) (func(), error) {
+	if dryRun {
+		return func() {}, nil
+	}
+	return lockConfig(operation)
+}
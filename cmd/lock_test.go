@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/containifyci/feller/pkg/state"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//nolint:paralleltest // modifies global flag variables
+func TestLockConfig(t *testing.T) {
+	originalCfg := cfgFile
+	t.Cleanup(func() { cfgFile = originalCfg })
+	cfgFile = filepath.Join(t.TempDir(), ".teller.yml")
+
+	unlock, err := lockConfig("github-secret add")
+	require.NoError(t, err)
+	assert.FileExists(t, state.LockPath(cfgFile))
+
+	_, err = lockConfig("github-secret add")
+	require.ErrorIs(t, err, state.ErrLocked)
+
+	unlock()
+	assert.NoFileExists(t, state.LockPath(cfgFile))
+}
@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/containifyci/feller/pkg/config"
+	"github.com/containifyci/feller/pkg/lockfile"
+	"github.com/containifyci/feller/pkg/logger"
+	"github.com/containifyci/feller/pkg/providers"
+	"github.com/spf13/cobra"
+)
+
+var (
+	locked    bool
+	lockCheck bool
+)
+
+var missingLock = providers.MissingContext{
+	Command: "lock",
+	Prefix:  "Cannot lock secrets: ",
+	Step:    "Lock secrets",
+	Run:     "feller lock",
+	Hint:    "Resolve every secret before locking, or use --silent to lock the available secrets only.",
+}
+
+// lockCmd represents the lock command
+var lockCmd = &cobra.Command{
+	Use:   "lock",
+	Short: "Record the resolved secrets in " + lockfile.FileName,
+	Long: `Resolve secrets and record them in ` + lockfile.FileName + ` next to the config: the
+provider and map that supplied each key, and a salted, slow fingerprint of its
+value. Values are not stored, so the lockfile can be committed.
+
+Commands run with --locked then fail when a secret changed, appeared or
+disappeared since it was locked, making builds reproducible with respect to
+secrets. Run 'feller lock' again to accept the changes.
+
+Secrets are resolved by feller itself, so providers must be of kinds feller
+supports. 'feller lock --check' compares without writing.
+
+Examples:
+  feller lock
+  feller lock --check
+  feller --locked --no-fallback run -- make build`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		return runLock(cmd)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(lockCmd)
+	lockCmd.Flags().BoolVar(&lockCheck, "check", false, "Compare the resolved secrets with the lockfile without writing it")
+}
+
+func runLock(cmd *cobra.Command) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if err := checkNativeKinds(cfg); err != nil {
+		return err
+	}
+	result, err := providers.CollectSecretsWithResult(cfg, silent)
+	if err != nil {
+		return fmt.Errorf("failed to collect secrets: %w", err)
+	}
+	reportTimings(os.Stderr, result.Timings)
+	if err := checkMissing(result, missingLock, cfg.Messages); err != nil {
+		return err
+	}
+
+	if lockCheck {
+		if err := checkLockfile(result); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "%d secret(s) match %s\n", len(result.Secrets), lockfile.FileName)
+		return nil
+	}
+
+	path, err := lockfilePath()
+	if err != nil {
+		return err
+	}
+	f, err := lockfile.New(result)
+	if err != nil {
+		return err //nolint:wrapcheck // describes the failed fingerprint
+	}
+	if err := f.Write(path); err != nil {
+		return err //nolint:wrapcheck // names the lockfile
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Locked %d secret(s) in %s\n", len(result.Secrets), path)
+	return nil
+}
+
+// checkLockfile fails when the collected secrets differ from the lockfile
+func checkLockfile(result *providers.CollectionResult) error {
+	path, err := lockfilePath()
+	if err != nil {
+		return err
+	}
+	f, err := lockfile.Load(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("no %s next to the config; run 'feller lock' first", lockfile.FileName)
+	}
+	if err != nil {
+		return err //nolint:wrapcheck // names the lockfile
+	}
+	diff, err := f.Diff(result)
+	if err != nil {
+		return err //nolint:wrapcheck // describes the failed fingerprint
+	}
+	if len(diff) > 0 {
+		return fmt.Errorf("secrets differ from %s:\n  %s\nRun 'feller lock' to accept the changes", path, strings.Join(diff, "\n  "))
+	}
+	logger.Debug("Secrets match %s", path)
+	return nil
+}
+
+// lockfilePath returns the lockfile of the selected config
+func lockfilePath() (string, error) {
+	path, err := config.ResolveConfigPath(cfgFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve config path: %w", err)
+	}
+	return lockfile.PathFor(path), nil
+}
@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/containifyci/feller/pkg/fellertest"
+	"github.com/containifyci/feller/pkg/lockfile"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//nolint:paralleltest // Modifies the global config path and lock flags
+func TestRunLock(t *testing.T) {
+	t.Cleanup(func() { cfgFile, locked, lockCheck = "", false, false })
+	dotenv := fellertest.FakeDotenv(t, map[string]string{"API_KEY": "abc"})
+	cfgFile = fellertest.WriteConfig(t, fellertest.NewConfig().Provider("local", dotenv).Build())
+	cfg, err := loadConfig()
+	require.NoError(t, err)
+
+	// Without a lockfile, --locked fails with guidance
+	locked = true
+	_, err = collectSecrets(cfg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "run 'feller lock' first")
+	locked = false
+
+	var out bytes.Buffer
+	lockCmd.SetOut(&out)
+	require.NoError(t, runLock(lockCmd))
+	assert.Contains(t, out.String(), "Locked 1 secret(s)")
+	data, err := os.ReadFile(filepath.Join(filepath.Dir(cfgFile), lockfile.FileName))
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "abc")
+
+	locked = true
+	_, err = collectSecrets(cfg)
+	require.NoError(t, err)
+
+	// A rotated value fails --locked and --check
+	require.NoError(t, os.WriteFile(dotenv.Maps[0].Path, []byte("API_KEY=rotated\n"), 0o600))
+	_, err = collectSecrets(cfg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "API_KEY: value changed")
+
+	lockCheck = true
+	err = runLock(lockCmd)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Run 'feller lock' to accept the changes")
+}
@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/containifyci/feller/pkg/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServeMetrics(t *testing.T) {
+	t.Parallel()
+	reg := metrics.NewRegistry()
+	reg.Add("feller_reconcile_runs_total", "Runs of feller reconcile.", 1)
+
+	addr, shutdown, err := serveMetrics("127.0.0.1:0", reg)
+	require.NoError(t, err)
+	defer shutdown()
+
+	resp, err := http.Get("http://" + addr + "/metrics") //nolint:noctx // local test server
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, string(body), "feller_reconcile_runs_total 1\n")
+
+	resp, err = http.Get("http://" + addr + "/") //nolint:noctx // local test server
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	_, _, err = serveMetrics(addr, reg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to listen for metrics")
+}
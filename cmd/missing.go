@@ -0,0 +1,239 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/containifyci/feller/pkg/ci"
+	"github.com/containifyci/feller/pkg/config"
+	"github.com/containifyci/feller/pkg/logger"
+	"github.com/containifyci/feller/pkg/providers"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// errorTemplateEnv names a template file used when the config sets no missing variable template
+const errorTemplateEnv = "FELLER_ERROR_TEMPLATE"
+
+// Context of the missing variable error of each command
+var (
+	missingRun = providers.MissingContext{
+		Command: "run",
+		Step:    "Run with secrets",
+		Run:     "feller run -- your-command",
+		Hint:    "Or use --silent flag to suppress this error and continue with available secrets only.",
+	}
+	missingExport = providers.MissingContext{
+		Command: "export",
+		Prefix:  "Cannot export: ",
+		Step:    "Export with secrets",
+		Run:     "feller export json",
+		Hint:    "Or use --silent flag to export only available secrets.",
+	}
+	missingShell = providers.MissingContext{
+		Command: "sh",
+		Prefix:  "Cannot generate shell exports: ",
+		Step:    "Set shell variables",
+		Run:     `eval "$(feller sh)"`,
+		Hint:    "Or use --silent flag to export only available secrets.",
+	}
+)
+
+// checkMissing returns the missing variable error of the command, nil with --silent or when
+// nothing is missing. With --missing-as-warning the error is printed as a warning instead, and
+// the command proceeds with the available secrets.
+func checkMissing(result *providers.CollectionResult, ctx providers.MissingContext, messages config.Messages) error {
+	if !result.HasMissingVars || silent {
+		return nil
+	}
+	err := missingVariablesError(result.MissingVars, ctx, messages)
+	if !missingAsWarning {
+		return err
+	}
+	warnMissing(os.Stderr, err.Error())
+	return nil
+}
+
+// warnMissing prints the missing variable report as a warning, and on GitHub Actions also
+// as a warning annotation of the run
+func warnMissing(w io.Writer, report string) {
+	fmt.Fprintf(w, "Warning: %s\n\nContinuing with the available secrets (--missing-as-warning)\n", report)
+	if platform, ok := currentPlatform(); ok && platform.ID == ci.GitHubActions {
+		fmt.Fprintf(w, "::warning title=feller: missing environment variables::%s\n", escapeAnnotationData(report))
+	}
+}
+
+// missingVariablesError renders the error for missing environment variables with the
+// configured template, falling back to the default one when the template is broken
+func missingVariablesError(missingVars []providers.MissingVariable, ctx providers.MissingContext, messages config.Messages) error {
+	if len(missingVars) == 0 {
+		return nil
+	}
+
+	ctx.Platform, _ = currentPlatform()
+	ctx.Plain = isPlain()
+
+	var msg string
+	text, err := missingTemplate(messages)
+	if err == nil {
+		msg, err = providers.FormatMissing(missingVars, ctx, text)
+	}
+	if err != nil {
+		// The variables are still missing, so report them rather than the template problem alone
+		logger.Error("%v", err)
+		if msg, err = providers.FormatMissing(missingVars, ctx, ""); err != nil {
+			return err //nolint:wrapcheck // the default template always renders
+		}
+	}
+	return errors.New(msg)
+}
+
+// missingTemplate returns the configured missing variable template: the inline template, the
+// template file, or the file named by FELLER_ERROR_TEMPLATE, in that order. It returns an
+// empty template for the default message.
+func missingTemplate(messages config.Messages) (string, error) {
+	if messages.MissingVariables != "" {
+		return messages.MissingVariables, nil
+	}
+	path := messages.MissingVariablesFile
+	if path == "" {
+		path = os.Getenv(errorTemplateEnv)
+	}
+	if path == "" {
+		return "", nil
+	}
+
+	// #nosec G304 - Template path is provided by the user
+	data, err := os.ReadFile(config.LocalPath(path))
+	if err != nil {
+		return "", fmt.Errorf("failed to read missing variables template: %w", err)
+	}
+	return string(data), nil
+}
+
+// Output formats of feller missing
+const (
+	missingFormatText      = "text"
+	missingFormatYAML      = "yaml"
+	missingFormatJSON      = "json"
+	missingFormatGitHubEnv = "github-env"
+)
+
+var missingFormat string
+
+// missingEntry is a missing variable in the YAML and JSON output of feller missing
+type missingEntry struct {
+	Variable string `json:"variable" yaml:"variable"`
+	MapsTo   string `json:"maps_to" yaml:"maps_to"`
+	Provider string `json:"provider" yaml:"provider"`
+}
+
+// missingEntries returns the missing variables sorted by variable and provider
+func missingEntries(missingVars []providers.MissingVariable) []missingEntry {
+	msg := providers.NewMissingMessage(missingVars, providers.MissingContext{})
+	entries := make([]missingEntry, 0, len(msg.Variables))
+	for _, mv := range msg.Variables {
+		entries = append(entries, missingEntry{Variable: mv.VariableName, MapsTo: mv.MappedTo, Provider: mv.Provider})
+	}
+	return entries
+}
+
+// missingCmd represents the missing command
+var missingCmd = &cobra.Command{
+	Use:   "missing",
+	Short: "List the environment variables the providers expect but are not set",
+	Long: `List the environment variables google_secretmanager providers read that are not
+set, without failing like the other commands do. The github-env format prints
+the env block of a GitHub Actions step reading each variable from the repository
+secret of the same name, ready to paste into a workflow.
+
+Formats:
+  text        a table of the variables (default)
+  yaml, json  the variables with the key they map to and their provider
+  github-env  the env block of a workflow step
+
+Examples:
+  feller missing
+  feller missing --format github-env
+  feller missing --format json | jq -r '.[].variable'`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		return runMissing(cmd.OutOrStdout())
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(missingCmd)
+	missingCmd.Flags().StringVar(&missingFormat, "format", missingFormatText, "Output format (text, yaml, json or github-env)")
+}
+
+func runMissing(out io.Writer) error {
+	switch missingFormat {
+	case missingFormatText, missingFormatYAML, missingFormatJSON, missingFormatGitHubEnv:
+	default:
+		return fmt.Errorf("unsupported format: %s (expected text, yaml, json or github-env)", missingFormat)
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if err := checkNativeKinds(cfg); err != nil {
+		return err
+	}
+	result, err := collectSecrets(cfg)
+	if err != nil {
+		return err
+	}
+	return writeMissing(out, result.MissingVars)
+}
+
+// writeMissing prints the missing variables in the selected format
+func writeMissing(out io.Writer, missingVars []providers.MissingVariable) error {
+	msg := providers.NewMissingMessage(missingVars, providers.MissingContext{})
+	entries := missingEntries(missingVars)
+
+	switch missingFormat {
+	case missingFormatJSON:
+		data, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode missing variables: %w", err)
+		}
+		fmt.Fprintln(out, string(data))
+	case missingFormatYAML:
+		if len(entries) == 0 {
+			fmt.Fprintln(out, "[]")
+			return nil
+		}
+		data, err := yaml.Marshal(entries)
+		if err != nil {
+			return fmt.Errorf("failed to encode missing variables: %w", err)
+		}
+		fmt.Fprint(out, string(data))
+	case missingFormatGitHubEnv:
+		if len(msg.EnvNames) == 0 {
+			return nil
+		}
+		fmt.Fprintln(out, "env:")
+		for _, name := range msg.EnvNames {
+			fmt.Fprintf(out, "  %s: ${{ secrets.%s }}\n", name, name)
+		}
+	default:
+		if msg.Count == 0 {
+			fmt.Fprintln(out, "No missing environment variables")
+			return nil
+		}
+		fmt.Fprintf(out, "%d missing environment variable(s)\n\n", msg.Count)
+		w := newTable(out)
+		fmt.Fprintln(w, "VARIABLE\tMAPS TO\tPROVIDER")
+		for _, entry := range entries {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", entry.Variable, entry.MapsTo, entry.Provider)
+		}
+		if err := w.Flush(); err != nil {
+			return fmt.Errorf("failed to write missing variables: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/containifyci/feller/pkg/config"
+	"github.com/containifyci/feller/pkg/fellertest"
+	"github.com/containifyci/feller/pkg/i18n"
+	"github.com/containifyci/feller/pkg/providers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMissingVariablesTemplateFallback(t *testing.T) {
+	t.Parallel()
+	err := missingVariablesError(goldenMissing, missingRun, config.Messages{MissingVariables: "{{.Unknown}}"})
+	require.Error(t, err)
+	assert.True(t, strings.HasPrefix(err.Error(), "Missing 4 required environment variable(s)"), "a broken template falls back to the default message")
+
+	err = missingVariablesError(goldenMissing, missingRun, config.Messages{MissingVariables: "Ask in #platform for {{len .EnvNames}} secrets"})
+	require.EqualError(t, err, "Ask in #platform for 3 secrets")
+}
+
+//nolint:paralleltest // modifies environment variables
+func TestMissingTemplate(t *testing.T) {
+	dir := t.TempDir()
+	orgTemplate := filepath.Join(dir, "org.tmpl")
+	repoTemplate := filepath.Join(dir, "repo.tmpl")
+	require.NoError(t, os.WriteFile(orgTemplate, []byte("org: {{len .EnvNames}}\n"), 0o600))
+	require.NoError(t, os.WriteFile(repoTemplate, []byte("repo: {{len .EnvNames}}\n"), 0o600))
+
+	tests := []struct {
+		name     string
+		env      string
+		expected string
+		messages config.Messages
+	}{
+		{name: "default", expected: "Missing 4 required environment variable(s)"},
+		{name: "environment", env: orgTemplate, expected: "org: 3"},
+		{name: "config file over environment", env: orgTemplate, messages: config.Messages{MissingVariablesFile: repoTemplate}, expected: "repo: 3"},
+		{
+			name:     "inline over file",
+			env:      orgTemplate,
+			messages: config.Messages{MissingVariables: "inline", MissingVariablesFile: repoTemplate},
+			expected: "inline",
+		},
+		{name: "unreadable file", messages: config.Messages{MissingVariablesFile: filepath.Join(dir, "nope.tmpl")}, expected: "Missing 4 required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(errorTemplateEnv, tt.env)
+			err := missingVariablesError(goldenMissing, missingExport, tt.messages)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.expected)
+		})
+	}
+}
+
+//nolint:paralleltest // Changes the selected locale
+func TestMissingVariablesLocalized(t *testing.T) {
+	t.Cleanup(func() { _ = i18n.SetLocale(i18n.English) })
+	require.NoError(t, i18n.SetLocale(i18n.German))
+
+	contexts := []providers.MissingContext{missingRun, missingExport, missingShell, missingEntrypoint, missingInspect, missingK8sInit, missingLock, missingSnapshot, missingReconcile, missingCopy}
+	for _, ctx := range contexts {
+		for _, msg := range []string{ctx.Prefix, ctx.Step, ctx.Hint} {
+			if msg != "" {
+				assert.NotEqual(t, msg, i18n.T(msg), "no German translation of %q (%s)", msg, ctx.Command)
+			}
+		}
+	}
+
+	err := missingVariablesError(goldenMissing, missingExport, config.Messages{})
+	require.Error(t, err)
+	assert.True(t, strings.HasPrefix(err.Error(), "Export nicht möglich: 4 erforderliche Umgebungsvariable(n) fehlen in"), err.Error())
+	assert.Contains(t, err.Error(), "Oder exportieren Sie mit --silent nur die verfügbaren Secrets.")
+	assert.Contains(t, err.Error(), "- name: Mit Secrets exportieren")
+}
+
+//nolint:paralleltest // modifies global flag variables
+func TestWriteMissing(t *testing.T) {
+	t.Cleanup(func() { missingFormat, plainOutput = missingFormatText, false })
+	plainOutput = true
+	tests := []struct {
+		format   string
+		vars     []providers.MissingVariable
+		expected string
+	}{
+		{
+			format: missingFormatText,
+			vars:   goldenMissing,
+			expected: "4 missing environment variable(s)\n\nVARIABLE\tMAPS TO\tPROVIDER\n" +
+				"GSM_API_TOKEN\tAPI_TOKEN\tgsm\nGSM_DB_URL\tDATABASE_URL\tgsm\nSHARED_TOKEN\tTOKEN\tgsm\nSHARED_TOKEN\tTOKEN\tother\n",
+		},
+		{format: missingFormatText, expected: "No missing environment variables\n"},
+		{
+			format:   missingFormatGitHubEnv,
+			vars:     goldenMissing,
+			expected: "env:\n  GSM_API_TOKEN: ${{ secrets.GSM_API_TOKEN }}\n  GSM_DB_URL: ${{ secrets.GSM_DB_URL }}\n  SHARED_TOKEN: ${{ secrets.SHARED_TOKEN }}\n",
+		},
+		{format: missingFormatGitHubEnv},
+		{
+			format:   missingFormatYAML,
+			vars:     goldenMissing[:1],
+			expected: "- variable: GSM_DB_URL\n  maps_to: DATABASE_URL\n  provider: gsm\n",
+		},
+		{format: missingFormatYAML, expected: "[]\n"},
+		{
+			format:   missingFormatJSON,
+			vars:     goldenMissing[:1],
+			expected: "[\n  {\n    \"variable\": \"GSM_DB_URL\",\n    \"maps_to\": \"DATABASE_URL\",\n    \"provider\": \"gsm\"\n  }\n]\n",
+		},
+		{format: missingFormatJSON, expected: "[]\n"},
+	}
+	for _, tt := range tests {
+		missingFormat = tt.format
+		var out strings.Builder
+		require.NoError(t, writeMissing(&out, tt.vars))
+		assert.Equal(t, tt.expected, out.String(), "%s with %d variable(s)", tt.format, len(tt.vars))
+	}
+}
+
+//nolint:paralleltest // modifies environment variables and global flag variables
+func TestRunMissing(t *testing.T) {
+	t.Cleanup(func() { cfgFile, missingFormat = "", missingFormatText })
+	cfg := fellertest.NewConfig().
+		Provider("set", fellertest.FakeGSM(t, map[string]string{"SET_KEY": "value"})).
+		Provider("unset", fellertest.MissingGSM(t, "UNSET_KEY")).
+		Build()
+	cfgFile = fellertest.WriteConfig(t, cfg)
+
+	missingFormat = missingFormatGitHubEnv
+	var out strings.Builder
+	require.NoError(t, runMissing(&out), "missing variables are listed, not an error")
+	assert.Equal(t, "env:\n  UNSET_KEY: ${{ secrets.UNSET_KEY }}\n", out.String())
+
+	missingFormat = "toml"
+	assert.EqualError(t, runMissing(&out), "unsupported format: toml (expected text, yaml, json or github-env)")
+}
+
+//nolint:paralleltest // modifies environment variables and global flag variables
+func TestCheckMissing(t *testing.T) {
+	t.Cleanup(func() { silent, missingAsWarning = false, false })
+	t.Setenv("GITHUB_ACTIONS", "true")
+	result := &providers.CollectionResult{MissingVars: goldenMissing, HasMissingVars: true}
+
+	require.ErrorContains(t, checkMissing(result, missingRun, config.Messages{}), "Missing 4 required environment variable(s)")
+	require.NoError(t, checkMissing(&providers.CollectionResult{}, missingRun, config.Messages{}))
+
+	missingAsWarning = true
+	require.NoError(t, checkMissing(result, missingRun, config.Messages{}), "warnings do not fail the command")
+
+	silent, missingAsWarning = true, false
+	require.NoError(t, checkMissing(result, missingRun, config.Messages{}))
+}
+
+//nolint:paralleltest // modifies environment variables
+func TestWarnMissing(t *testing.T) {
+	var out strings.Builder
+	t.Setenv("GITHUB_ACTIONS", "true")
+	warnMissing(&out, "Missing 1 variable:\n  - A")
+	assert.Equal(t, "Warning: Missing 1 variable:\n  - A\n\nContinuing with the available secrets (--missing-as-warning)\n"+
+		"::warning title=feller: missing environment variables::Missing 1 variable:%0A  - A\n", out.String())
+
+	out.Reset()
+	t.Setenv("GITHUB_ACTIONS", "")
+	t.Setenv("FELLER_MODE", "local")
+	warnMissing(&out, "Missing")
+	assert.NotContains(t, out.String(), "::warning")
+}
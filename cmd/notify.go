@@ -0,0 +1,199 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/containifyci/feller/pkg/logger"
+	"github.com/spf13/cobra"
+)
+
+const (
+	notifyFormatGeneric = "generic"
+	notifyFormatSlack   = "slack"
+
+	notifyTimeout = 10 * time.Second
+)
+
+var (
+	notifyWebhook string
+	notifyFormat  string
+)
+
+// Outcomes of a secret counted in a notification
+const (
+	notifyCreated = "created"
+	notifyUpdated = "updated"
+	notifySkipped = "skipped"
+	notifyFailed  = "failed"
+	notifyDeleted = "deleted"
+)
+
+// NotificationPayload is the generic JSON body sent to a notification webhook
+type NotificationPayload struct {
+	Repository string   `json:"repository,omitempty"` // Repository of github-secret add
+	Targets    []string `json:"targets,omitempty"`    // Targets of the config written by sync
+	Operation  string   `json:"operation"`
+	DryRun     bool     `json:"dry_run"`
+	Created    int      `json:"created"`
+	Updated    int      `json:"updated"`
+	Skipped    int      `json:"skipped"`
+	Unchanged  int      `json:"unchanged"` // Not uploaded, the value is the one uploaded last
+	Failed     int      `json:"failed"`
+
+	Deleted []string               `json:"deleted,omitempty"` // Deleted secrets, as "KEY (scope)"
+	Scopes  map[string]*ScopeStats `json:"scopes,omitempty"`  // Counts by scope
+}
+
+// slackPayload is the minimal Slack incoming webhook message body
+type slackPayload struct {
+	Text string `json:"text"`
+}
+
+// addNotifyFlags adds the flags that send an operation summary to a webhook to a command
+func addNotifyFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&notifyWebhook, "notify-webhook", "", "Send an operation summary to this webhook URL")
+	cmd.Flags().StringVar(&notifyFormat, "notify-format", notifyFormatGeneric, "Notification payload format (generic, slack)")
+}
+
+// validateNotifyFlags ensures the notification format is supported
+func validateNotifyFlags() error {
+	switch notifyFormat {
+	case notifyFormatGeneric, notifyFormatSlack:
+		return nil
+	default:
+		return fmt.Errorf("unsupported notification format: %s (expected %s or %s)", notifyFormat, notifyFormatGeneric, notifyFormatSlack)
+	}
+}
+
+// githubNotification summarizes an operation of github-secret add
+func githubNotification(operation string, stats *SecretOperationStats) NotificationPayload {
+	return NotificationPayload{
+		Repository: repo,
+		Operation:  operation,
+		DryRun:     dryRun,
+		Created:    stats.Created,
+		Updated:    stats.Updated,
+		Skipped:    stats.Skipped,
+		Unchanged:  stats.Unchanged,
+		Failed:     stats.Failed,
+		Scopes:     stats.Scopes,
+	}
+}
+
+// count adds the secret key of scope with one of the notify outcomes to the summary
+func (n *NotificationPayload) count(scope, key, outcome string) {
+	if n.Scopes == nil {
+		n.Scopes = make(map[string]*ScopeStats)
+	}
+	if n.Scopes[scope] == nil {
+		n.Scopes[scope] = &ScopeStats{}
+	}
+	stats := n.Scopes[scope]
+	switch outcome {
+	case notifyCreated:
+		n.Created++
+		stats.Created++
+	case notifyUpdated:
+		n.Updated++
+		stats.Updated++
+	case notifySkipped:
+		n.Skipped++
+		stats.Skipped++
+	case notifyFailed:
+		n.Failed++
+		stats.Failed++
+	case notifyDeleted:
+		n.Deleted = append(n.Deleted, key+" ("+scope+")")
+		stats.Deleted++
+	}
+}
+
+// buildNotificationBody renders the webhook request body for the selected format
+func buildNotificationBody(payload NotificationPayload) ([]byte, error) {
+	var body any = payload
+	if notifyFormat == notifyFormatSlack {
+		body = slackPayload{Text: formatSlackSummary(payload)}
+	}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal notification payload: %w", err)
+	}
+	return data, nil
+}
+
+// formatSlackSummary renders a human readable summary line for Slack
+func formatSlackSummary(payload NotificationPayload) string {
+	subject := payload.Repository
+	if subject == "" {
+		subject = strings.Join(payload.Targets, ", ")
+	}
+	var text strings.Builder
+	text.WriteString(fmt.Sprintf("feller %s for `%s`", payload.Operation, subject))
+	if payload.DryRun {
+		text.WriteString(" (dry-run)")
+	}
+	text.WriteString(fmt.Sprintf(": %d created, %d updated, %d skipped, %d failed",
+		payload.Created, payload.Updated, payload.Skipped, payload.Failed))
+	if payload.Unchanged > 0 {
+		text.WriteString(fmt.Sprintf(", %d unchanged", payload.Unchanged))
+	}
+	if len(payload.Deleted) > 0 {
+		text.WriteString(fmt.Sprintf(", %d deleted: %s", len(payload.Deleted), strings.Join(payload.Deleted, ", ")))
+	}
+	return text.String()
+}
+
+// sendNotification posts an operation summary to the configured webhook.
+// Notification failures are reported but never fail the secret operation itself.
+func sendNotification(payload NotificationPayload) {
+	if notifyWebhook == "" {
+		return
+	}
+
+	logger.Debug("Sending %s notification to webhook", notifyFormat)
+
+	body, err := buildNotificationBody(payload)
+	if err != nil {
+		logger.Error("Failed to build notification: %v", err)
+		return
+	}
+
+	if err := postWebhook(notifyWebhook, body); err != nil {
+		logger.Error("Failed to send notification: %v", err)
+		return
+	}
+
+	logger.Verbose("Sent %s notification to webhook", notifyFormat)
+}
+
+// postWebhook sends a JSON body to the given URL
+func postWebhook(url string, body []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+
+	logger.Debug("Webhook responded with status %d", resp.StatusCode)
+	return nil
+}
@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/containifyci/feller/pkg/reconcile"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateNotifyFlags(t *testing.T) {
+	originalFormat := notifyFormat
+	t.Cleanup(func() {
+		notifyFormat = originalFormat
+	})
+
+	tests := []struct {
+		name    string
+		format  string
+		wantErr bool
+	}{
+		{name: "generic format", format: "generic", wantErr: false},
+		{name: "slack format", format: "slack", wantErr: false},
+		{name: "unknown format", format: "teams", wantErr: true},
+	}
+
+	for _, tt := range tests { //nolint:paralleltest // sub-tests modify global variables
+		t.Run(tt.name, func(t *testing.T) {
+			notifyFormat = tt.format
+			err := validateNotifyFlags()
+			if tt.wantErr {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), "unsupported notification format")
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestBuildNotificationBody(t *testing.T) {
+	originalFormat := notifyFormat
+	originalRepo := repo
+	originalDryRun := dryRun
+	t.Cleanup(func() {
+		notifyFormat = originalFormat
+		repo = originalRepo
+		dryRun = originalDryRun
+	})
+
+	repo = "owner/repo"
+	stats := &SecretOperationStats{Created: 2, Updated: 1, Skipped: 3, Failed: 0}
+
+	t.Run("generic payload", func(t *testing.T) {
+		notifyFormat = notifyFormatGeneric
+		dryRun = false
+
+		body, err := buildNotificationBody(githubNotification("github-secret add", stats))
+		require.NoError(t, err)
+
+		var payload NotificationPayload
+		require.NoError(t, json.Unmarshal(body, &payload))
+		assert.Equal(t, "owner/repo", payload.Repository)
+		assert.Equal(t, "github-secret add", payload.Operation)
+		assert.Equal(t, 2, payload.Created)
+		assert.Equal(t, 1, payload.Updated)
+		assert.Equal(t, 3, payload.Skipped)
+		assert.False(t, payload.DryRun)
+	})
+
+	t.Run("slack payload", func(t *testing.T) {
+		notifyFormat = notifyFormatSlack
+		dryRun = true
+
+		body, err := buildNotificationBody(githubNotification("github-secret add", stats))
+		require.NoError(t, err)
+
+		var payload map[string]string
+		require.NoError(t, json.Unmarshal(body, &payload))
+		assert.Contains(t, payload["text"], "`owner/repo`")
+		assert.Contains(t, payload["text"], "(dry-run)")
+		assert.Contains(t, payload["text"], "2 created, 1 updated, 3 skipped, 0 failed")
+	})
+
+	t.Run("slack payload of deleted secrets", func(t *testing.T) {
+		notifyFormat = notifyFormatSlack
+		n := NotificationPayload{Operation: "sync rollback", Targets: []string{"ci", "web"}}
+		n.count("ci repository", "API_KEY", notifyDeleted)
+		n.count("web production", "API_KEY", notifyUpdated)
+
+		body, err := buildNotificationBody(n)
+		require.NoError(t, err)
+
+		var payload map[string]string
+		require.NoError(t, json.Unmarshal(body, &payload))
+		assert.Equal(t, "feller sync rollback for `ci, web`: 0 created, 1 updated, 0 skipped, 0 failed, 1 deleted: API_KEY (ci repository)", payload["text"])
+	})
+}
+
+//nolint:paralleltest // reads global flag variables
+func TestSyncNotification(t *testing.T) {
+	results := []reconcile.Result{
+		{Target: "ci", Fixed: 2, Drift: []reconcile.Drift{
+			{Key: "A", Scope: "repository", Status: reconcile.StatusMissing},
+			{Key: "B", Scope: "repository", Status: reconcile.StatusChanged},
+		}},
+		{Target: "web", Fixed: 1, Err: errors.New("boom"), Drift: []reconcile.Drift{
+			{Key: "A", Scope: "production", Status: reconcile.StatusChanged},
+			{Key: "B", Scope: "production", Status: reconcile.StatusChanged},
+			{Key: "C", Scope: "production", Status: reconcile.StatusMissing},
+		}},
+	}
+	n := syncNotification(results)
+	assert.Equal(t, []string{"ci", "web"}, n.Targets)
+	assert.Equal(t, []int{1, 2, 1, 1}, []int{n.Created, n.Updated, n.Skipped, n.Failed})
+	assert.Equal(t, &ScopeStats{Updated: 1, Failed: 1, Skipped: 1}, n.Scopes["web production"])
+	assert.Empty(t, n.Deleted)
+
+	t.Cleanup(func() { syncDryRun = false })
+	syncDryRun = true
+	n = syncNotification(results)
+	assert.True(t, n.DryRun)
+	assert.Equal(t, []int{2, 3, 0, 0}, []int{n.Created, n.Updated, n.Skipped, n.Failed})
+}
+
+func TestPostWebhook(t *testing.T) {
+	t.Parallel()
+
+	t.Run("successful post", func(t *testing.T) {
+		t.Parallel()
+		var received string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, http.MethodPost, r.Method)
+			assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+			data, _ := io.ReadAll(r.Body)
+			received = string(data)
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		err := postWebhook(server.URL, []byte(`{"ok":true}`))
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"ok":true}`, received)
+	})
+
+	t.Run("non-2xx response", func(t *testing.T) {
+		t.Parallel()
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer server.Close()
+
+		err := postWebhook(server.URL, []byte(`{}`))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "status 500")
+	})
+}
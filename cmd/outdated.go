@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/containifyci/feller/pkg/config"
+	"github.com/containifyci/feller/pkg/lockfile"
+	"github.com/containifyci/feller/pkg/providers"
+	"github.com/spf13/cobra"
+)
+
+var outdatedJSON bool
+
+// outdatedChange is a change of the lockfile with the time its source was last modified
+type outdatedChange struct {
+	lockfile.Change
+	Modified *time.Time `json:"modified,omitempty"` // Nil when the provider does not expose it
+}
+
+// outdatedReport is the JSON output of feller outdated
+type outdatedReport struct {
+	Locked  time.Time        `json:"locked"`
+	Changes []outdatedChange `json:"changes"`
+}
+
+// outdatedCmd represents the outdated command
+var outdatedCmd = &cobra.Command{
+	Use:   "outdated",
+	Short: "List secrets that changed since " + lockfile.FileName + " was written",
+	Long: `Resolve secrets and compare them with ` + lockfile.FileName + `, listing every key whose
+value changed, that another provider or map supplies now, that was added or
+that is no longer resolved.
+
+When the provider exposes it, the time the source was last modified is shown:
+the modification time of dotenv and bundle files. Environment variables read by
+google_secretmanager providers have none.
+
+Unlike --locked, outdated succeeds when secrets changed; run 'feller lock' to
+accept the changes.
+
+Examples:
+  feller outdated
+  feller outdated --json | jq -r '.changes[].key'`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		return runOutdated(cmd.OutOrStdout())
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(outdatedCmd)
+	outdatedCmd.Flags().BoolVar(&outdatedJSON, "json", false, "Output as JSON")
+}
+
+func runOutdated(out io.Writer) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if err := checkNativeKinds(cfg); err != nil {
+		return err
+	}
+	path, err := lockfilePath()
+	if err != nil {
+		return err
+	}
+	f, err := lockfile.Load(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("no %s next to the config; run 'feller lock' first", lockfile.FileName)
+	}
+	if err != nil {
+		return err //nolint:wrapcheck // names the lockfile
+	}
+
+	// Missing variables show up as removed keys
+	result, err := providers.CollectSecretsWithResult(cfg, true)
+	if err != nil {
+		return fmt.Errorf("failed to collect secrets: %w", err)
+	}
+	reportTimings(os.Stderr, result.Timings)
+	changes, err := f.Changes(result)
+	if err != nil {
+		return err //nolint:wrapcheck // describes the failed fingerprint
+	}
+
+	report := outdatedReport{Locked: f.Locked, Changes: make([]outdatedChange, len(changes))}
+	for i, change := range changes {
+		report.Changes[i] = outdatedChange{Change: change, Modified: sourceModified(cfg, change)}
+	}
+	return writeOutdated(out, report, len(result.Secrets))
+}
+
+// sourceModified returns when the current source of a change was last modified, if known
+func sourceModified(cfg *config.TellerConfig, change lockfile.Change) *time.Time {
+	if change.Current == nil {
+		return nil
+	}
+	modified, ok := providers.SourceModified(cfg, change.Current.Source())
+	if !ok {
+		return nil
+	}
+	modified = modified.UTC().Truncate(time.Second)
+	return &modified
+}
+
+// writeOutdated prints the changes as a table or JSON
+func writeOutdated(out io.Writer, report outdatedReport, resolved int) error {
+	if outdatedJSON {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			return fmt.Errorf("failed to encode changes: %w", err)
+		}
+		return nil
+	}
+
+	locked := report.Locked.Format(time.RFC3339)
+	if len(report.Changes) == 0 {
+		fmt.Fprintf(out, "All %d secret(s) match %s, locked %s\n", resolved, lockfile.FileName, locked)
+		return nil
+	}
+	fmt.Fprintf(out, "%d secret(s) changed since %s was locked %s\n\n", len(report.Changes), lockfile.FileName, locked)
+
+	w := newTable(out)
+	fmt.Fprintln(w, "KEY\tSTATUS\tSOURCE\tMODIFIED")
+	for _, change := range report.Changes {
+		entry := change.Current
+		if entry == nil {
+			entry = change.Locked
+		}
+		modified := "-"
+		if change.Modified != nil {
+			modified = change.Modified.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", change.Key, change.Status, entry.Source(), modified)
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to write changes: %w", err)
+	}
+	return nil
+}
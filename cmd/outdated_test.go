@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/containifyci/feller/pkg/fellertest"
+	"github.com/containifyci/feller/pkg/lockfile"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//nolint:paralleltest // Modifies the global config path and outdated flags
+func TestRunOutdated(t *testing.T) {
+	t.Cleanup(func() { cfgFile, outdatedJSON = "", false })
+	dotenv := fellertest.FakeDotenv(t, map[string]string{"API_KEY": "abc", "OLD": "x"})
+	cfgFile = fellertest.WriteConfig(t, fellertest.NewConfig().Provider("local", dotenv).Build())
+
+	var out bytes.Buffer
+	err := runOutdated(&out)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "run 'feller lock' first")
+
+	lockCmd.SetOut(&out)
+	require.NoError(t, runLock(lockCmd))
+
+	out.Reset()
+	require.NoError(t, runOutdated(&out))
+	assert.Contains(t, out.String(), "All 2 secret(s) match feller.lock, locked ")
+
+	require.NoError(t, os.WriteFile(dotenv.Maps[0].Path, []byte("API_KEY=rotated\nNEW=y\n"), 0o600))
+	out.Reset()
+	require.NoError(t, runOutdated(&out))
+	assert.Contains(t, out.String(), "3 secret(s) changed since feller.lock was locked")
+	assert.Regexp(t, `API_KEY\s+changed\s+provider 'local' \(map '\w+'\)\s+\d{4}-\d\d-\d\dT`, out.String())
+	assert.Regexp(t, `OLD\s+removed\s+provider 'local' \(map '\w+'\)\s+-`, out.String())
+
+	outdatedJSON = true
+	out.Reset()
+	require.NoError(t, runOutdated(&out))
+	var report outdatedReport
+	require.NoError(t, json.Unmarshal(out.Bytes(), &report))
+	require.Len(t, report.Changes, 3)
+	assert.Equal(t, lockfile.StatusAdded, report.Changes[1].Status)
+	assert.NotNil(t, report.Changes[1].Modified)
+	assert.Nil(t, report.Changes[2].Modified)
+}
@@ -0,0 +1,200 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/containifyci/feller/pkg/logger"
+)
+
+// processSpec describes one command run by feller run --parallel or --procfile
+type processSpec struct {
+	Name    string
+	Command string
+}
+
+// splitParallelArgs splits run arguments on "--" separators into one process per group
+func splitParallelArgs(args []string) []processSpec {
+	var specs []processSpec
+	var group []string
+
+	flush := func() {
+		if len(group) == 0 {
+			return
+		}
+		specs = append(specs, processSpec{
+			Name:    defaultDetachName(group),
+			Command: shellCommandString(group),
+		})
+		group = nil
+	}
+
+	for _, arg := range args {
+		if arg == "--" {
+			flush()
+			continue
+		}
+		group = append(group, arg)
+	}
+	flush()
+
+	return uniqueProcessNames(specs)
+}
+
+// parseProcfile reads "name: command" entries from a Procfile
+func parseProcfile(path string) ([]processSpec, error) {
+	logger.Debug("Loading Procfile: %s", path)
+
+	// #nosec G304 - Procfile path is provided by the user
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open Procfile %s: %w", path, err)
+	}
+	defer file.Close()
+
+	var specs []processSpec
+	scanner := bufio.NewScanner(file)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		name, command, found := strings.Cut(line, ":")
+		name = strings.TrimSpace(name)
+		command = strings.TrimSpace(command)
+		if !found || name == "" || command == "" {
+			return nil, fmt.Errorf("invalid Procfile entry on line %d: %s", lineNum, line)
+		}
+		specs = append(specs, processSpec{Name: name, Command: command})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading Procfile %s: %w", path, err)
+	}
+
+	if len(specs) == 0 {
+		return nil, fmt.Errorf("no processes defined in Procfile %s", path)
+	}
+
+	logger.Debug("Loaded %d processes from Procfile", len(specs))
+	return uniqueProcessNames(specs), nil
+}
+
+// uniqueProcessNames suffixes duplicate process names so output prefixes stay distinguishable
+func uniqueProcessNames(specs []processSpec) []processSpec {
+	seen := make(map[string]int)
+	for i := range specs {
+		seen[specs[i].Name]++
+		if count := seen[specs[i].Name]; count > 1 {
+			specs[i].Name = fmt.Sprintf("%s-%d", specs[i].Name, count)
+		}
+	}
+	return specs
+}
+
+// executeParallel runs all processes through the shell concurrently with prefixed output and
+// returns an error summarizing every process that failed
+func executeParallel(specs []processSpec, env []string) error {
+	if len(specs) == 0 {
+		return errors.New("no command specified")
+	}
+
+	shell := resolveShell()
+	width := 0
+	for _, spec := range specs {
+		width = max(width, len(spec.Name))
+	}
+
+	var outMu sync.Mutex
+	errs := make([]error, len(specs))
+	var wg sync.WaitGroup
+
+	for i, spec := range specs {
+		prefix := fmt.Sprintf("%-*s | ", width, spec.Name)
+		stdout := &prefixWriter{prefix: prefix, out: os.Stdout, mu: &outMu}
+		stderr := &prefixWriter{prefix: prefix, out: os.Stderr, mu: &outMu}
+
+		// #nosec G204 - This is intentional: tool designed to execute user-provided commands with secrets
+		cmd := exec.CommandContext(context.Background(), shell, "-c", spec.Command)
+		cmd.Env = env
+		cmd.Stdout = stdout
+		cmd.Stderr = stderr
+
+		logger.Verbose("Starting process %s: %s", spec.Name, spec.Command)
+
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			err := cmd.Run()
+			stdout.Flush()
+			stderr.Flush()
+			if err != nil {
+				logger.Debug("Process %s failed: %v", name, err)
+				errs[i] = fmt.Errorf("%s: %w", name, err)
+				return
+			}
+			logger.Debug("Process %s completed successfully", name)
+		}(i, spec.Name)
+	}
+
+	wg.Wait()
+
+	var failed []string
+	for _, err := range errs {
+		if err != nil {
+			failed = append(failed, err.Error())
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%d of %d processes failed: %s", len(failed), len(specs), strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// prefixWriter prefixes every complete line with a process name before writing it to out
+type prefixWriter struct {
+	mu     *sync.Mutex
+	out    io.Writer
+	prefix string
+	buf    bytes.Buffer
+}
+
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	w.buf.Write(p)
+	for {
+		line, err := w.buf.ReadBytes('\n')
+		if err != nil {
+			// Keep the incomplete line buffered until more output arrives
+			w.buf.Write(line)
+			break
+		}
+		w.writeLine(line)
+	}
+	return len(p), nil
+}
+
+// Flush writes any buffered partial line
+func (w *prefixWriter) Flush() {
+	if w.buf.Len() == 0 {
+		return
+	}
+	line := append(w.buf.Bytes(), '\n')
+	w.buf.Reset()
+	w.writeLine(line)
+}
+
+func (w *prefixWriter) writeLine(line []byte) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	fmt.Fprintf(w.out, "%s%s", w.prefix, line)
+}
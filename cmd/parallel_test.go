@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestSplitParallelArgs(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		args     []string
+		expected []processSpec
+	}{
+		{
+			name:     "single command",
+			args:     []string{"npm run api"},
+			expected: []processSpec{{Name: "npm", Command: "npm run api"}},
+		},
+		{
+			name: "multiple commands",
+			args: []string{"./api", "--port", "80", "--", "./worker"},
+			expected: []processSpec{
+				{Name: "api", Command: "./api --port 80"},
+				{Name: "worker", Command: "./worker"},
+			},
+		},
+		{
+			name: "duplicate names and empty groups",
+			args: []string{"--", "echo a", "--", "--", "echo b"},
+			expected: []processSpec{
+				{Name: "echo", Command: "echo a"},
+				{Name: "echo-2", Command: "echo b"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got := splitParallelArgs(tt.args)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("splitParallelArgs(%v) = %+v, want %+v", tt.args, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseProcfile(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name        string
+		content     string
+		errContains string
+		expected    []processSpec
+		wantErr     bool
+	}{
+		{
+			name:    "valid procfile",
+			content: "# services\nweb: ./server --port 8080\n\nworker:  ./worker\n",
+			expected: []processSpec{
+				{Name: "web", Command: "./server --port 8080"},
+				{Name: "worker", Command: "./worker"},
+			},
+		},
+		{
+			name:        "malformed entry",
+			content:     "web ./server\n",
+			wantErr:     true,
+			errContains: "invalid Procfile entry on line 1",
+		},
+		{
+			name:        "empty procfile",
+			content:     "# nothing here\n",
+			wantErr:     true,
+			errContains: "no processes defined",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			path := filepath.Join(t.TempDir(), "Procfile")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatalf("Failed to write Procfile: %v", err)
+			}
+
+			got, err := parseProcfile(path)
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Errorf("parseProcfile() error = %v, expected to contain %q", err, tt.errContains)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseProcfile() unexpected error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("parseProcfile() = %+v, want %+v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestPrefixWriter(t *testing.T) {
+	t.Parallel()
+	var out bytes.Buffer
+	w := &prefixWriter{prefix: "web | ", out: &out, mu: &sync.Mutex{}}
+
+	w.Write([]byte("first line\nsecond "))
+	w.Write([]byte("line\npartial"))
+	w.Flush()
+
+	expected := "web | first line\nweb | second line\nweb | partial\n"
+	if out.String() != expected {
+		t.Errorf("prefixWriter output = %q, want %q", out.String(), expected)
+	}
+}
+
+func TestExecuteParallel(t *testing.T) {
+	t.Setenv("SHELL", "/bin/sh")
+	env := []string{"PATH=" + PATH, "PARALLEL_SECRET=shared"}
+
+	t.Run("all processes succeed", func(t *testing.T) {
+		specs := []processSpec{
+			{Name: "a", Command: `test "$PARALLEL_SECRET" = shared`},
+			{Name: "b", Command: "true"},
+		}
+		if err := executeParallel(specs, env); err != nil {
+			t.Errorf("executeParallel() unexpected error = %v", err)
+		}
+	})
+
+	t.Run("failures are aggregated", func(t *testing.T) {
+		specs := []processSpec{
+			{Name: "ok", Command: "true"},
+			{Name: "bad1", Command: "exit 2"},
+			{Name: "bad2", Command: "exit 3"},
+		}
+		err := executeParallel(specs, env)
+		if err == nil {
+			t.Fatal("executeParallel() expected error but got none")
+		}
+		for _, want := range []string{"2 of 3 processes failed", "bad1", "bad2"} {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("executeParallel() error = %v, expected to contain %q", err, want)
+			}
+		}
+	})
+
+	t.Run("no processes", func(t *testing.T) {
+		if err := executeParallel(nil, env); err == nil {
+			t.Error("executeParallel() expected error for empty process list")
+		}
+	})
+}
@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+// plainEnv turns on plain output like --plain, e.g. for screen reader users
+const plainEnv = "FELLER_PLAIN"
+
+var plainOutput bool
+
+// table writes tabular output, tab-separated rows with a header
+type table interface {
+	io.Writer
+	Flush() error
+}
+
+// plainTable writes the tab-separated rows as they are, without padding them into columns
+type plainTable struct {
+	io.Writer
+}
+
+// Flush implements table; plain rows are never buffered
+func (plainTable) Flush() error {
+	return nil
+}
+
+// newTable returns a table writer for out: aligned columns, or with plain output the
+// tab-separated rows, which screen readers read without runs of padding and tools like
+// cut and awk split reliably
+func newTable(out io.Writer) table {
+	if isPlain() {
+		return plainTable{out}
+	}
+	return tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+}
+
+// isPlain reports whether output must be plain, selected by --plain or FELLER_PLAIN
+func isPlain() bool {
+	if plainOutput {
+		return true
+	}
+	switch strings.ToLower(os.Getenv(plainEnv)) {
+	case "1", "true", "yes":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/containifyci/feller/pkg/config"
+	"github.com/containifyci/feller/pkg/providers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//nolint:paralleltest // Sets the global --plain flag and environment variables
+func TestPlainOutput(t *testing.T) {
+	t.Setenv(plainEnv, "")
+	t.Cleanup(func() { plainOutput = false })
+
+	kinds := []providers.KindInfo{{Kind: "dotenv", Description: "Local .env files", Capabilities: providers.Capabilities{Read: true}}}
+	var out bytes.Buffer
+	require.NoError(t, writeKinds(&out, kinds, false))
+	assert.Contains(t, out.String(), "KIND    READ  WRITE", "tables are aligned by default")
+	assert.Equal(t, "01************ef", maskSecret("0123456789abcdef"))
+
+	plainOutput = true
+	out.Reset()
+	require.NoError(t, writeKinds(&out, kinds, false))
+	assert.Equal(t, "KIND\tREAD\tWRITE\tDISCOVERY\tAUTH\tDESCRIPTION\ndotenv\tyes\tno\tno\t\tLocal .env files\n", out.String())
+	assert.Equal(t, "01[12 hidden]ef", maskSecret("0123456789abcdef"))
+	assert.Equal(t, "[3 hidden]", maskSecret("abc"))
+
+	err := missingVariablesError(goldenMissing, missingExport, config.Messages{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "  - GSM_API_TOKEN (maps to: API_TOKEN)")
+	assert.NotContains(t, err.Error(), "•")
+	assert.NotContains(t, err.Error(), "```")
+	assert.Contains(t, err.Error(), "- name: Export with secrets\n  env:\n")
+
+	// FELLER_PLAIN selects plain output without the flag
+	plainOutput = false
+	t.Setenv(plainEnv, "1")
+	assert.True(t, isPlain())
+}
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"io"
+	"sort"
+
+	"github.com/containifyci/feller/pkg/plan"
+)
+
+// planJSON prints plans as JSON instead of a diff, set by --json of the commands that plan
+var planJSON bool
+
+// writePlan prints p as JSON with --json and as a diff otherwise
+func writePlan(out io.Writer, p *plan.Plan) error {
+	if planJSON {
+		return p.WriteJSON(out) //nolint:wrapcheck // already describes the failure
+	}
+	p.Render(out)
+	return nil
+}
+
+// githubTarget is the plan target of a GitHub repository
+func githubTarget(repository string) string {
+	return "github:" + repository
+}
+
+// planGitHubSecrets adds the changes of uploading secrets to the scopes of r, following the
+// overwrite flags
+func planGitHubSecrets(p *plan.Plan, r githubRepository, secrets map[string]string, existing *ExistingSecrets) {
+	keys := make([]string, 0, len(secrets))
+	for key := range secrets {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, scope := range uploadScopes(r) {
+		names := existing.scope(scope)
+		for _, key := range keys {
+			change := plan.Change{Action: plan.ActionCreate, Target: githubTarget(r.Repo), Scope: scope, Key: key}
+			if names[key] {
+				switch {
+				case uploads.Unchanged(uploadTarget(r, scope), key, secrets[key]):
+					change.Action, change.Reason = plan.ActionNoop, "unchanged since the last upload"
+				case skipExisting:
+					change.Action, change.Reason = plan.ActionNoop, "exists, skipped"
+				case confirmOverwrite:
+					change.Action, change.Reason = plan.ActionUpdate, "exists, after confirmation"
+				default:
+					change.Action, change.Reason = plan.ActionUpdate, "exists"
+				}
+			}
+			p.Add(change)
+		}
+	}
+}
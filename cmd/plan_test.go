@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/containifyci/feller/pkg/plan"
+	"github.com/containifyci/feller/pkg/state"
+	"github.com/stretchr/testify/assert"
+)
+
+//nolint:paralleltest // modifies global flag variables
+func TestPlanGitHubSecrets(t *testing.T) {
+	t.Cleanup(func() { skipExisting, uploads = false, nil })
+	target := githubRepository{Repo: "owner/repo", Dependabot: true}
+	secrets := map[string]string{"B": "2", "A": "1"}
+	existing := &ExistingSecrets{Repository: map[string]bool{"A": true}, Dependabot: map[string]bool{}}
+
+	p := plan.New("github-secret add")
+	planGitHubSecrets(p, target, secrets, existing)
+	assert.Equal(t, []plan.Change{
+		{Action: plan.ActionUpdate, Target: "github:owner/repo", Scope: "repository", Key: "A", Reason: "exists"},
+		{Action: plan.ActionCreate, Target: "github:owner/repo", Scope: "repository", Key: "B"},
+		{Action: plan.ActionCreate, Target: "github:owner/repo", Scope: "Dependabot", Key: "A"},
+		{Action: plan.ActionCreate, Target: "github:owner/repo", Scope: "Dependabot", Key: "B"},
+	}, p.Changes)
+
+	skipExisting, target.Dependabot = true, false
+	p = plan.New("github-secret add")
+	planGitHubSecrets(p, target, secrets, existing)
+	assert.Equal(t, plan.Change{Action: plan.ActionNoop, Target: "github:owner/repo", Scope: "repository", Key: "A", Reason: "exists, skipped"}, p.Changes[0])
+	assert.Len(t, p.Changes, 2)
+
+	// Values uploaded before are no-ops while GitHub holds them
+	uploads, _ = state.LoadUploads(t.TempDir())
+	uploads.Record("github:owner/repo (repository)", "A", "1")
+	uploads.Record("github:owner/repo (repository)", "B", "2")
+	p = plan.New("github-secret add")
+	planGitHubSecrets(p, target, secrets, existing)
+	assert.Equal(t, plan.Change{Action: plan.ActionNoop, Target: "github:owner/repo", Scope: "repository", Key: "A", Reason: "unchanged since the last upload"}, p.Changes[0])
+	assert.Equal(t, plan.ActionCreate, p.Changes[1].Action)
+}
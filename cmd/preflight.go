@@ -0,0 +1,200 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/containifyci/feller/pkg/ci"
+	"github.com/containifyci/feller/pkg/config"
+	"github.com/containifyci/feller/pkg/providers"
+	"github.com/containifyci/feller/pkg/validate"
+	"github.com/spf13/cobra"
+)
+
+var preflightTools []string
+
+// preflightCmd represents the preflight command
+var preflightCmd = &cobra.Command{
+	Use:   "preflight",
+	Short: "Check that secrets can be resolved before the expensive steps run",
+	Long: `Check everything later feller steps depend on and report all problems at once,
+so a workflow fails in its first seconds instead of after the build.
+
+Checks:
+  - the config is valid
+  - on CI, every mapped environment variable is set and every provider can
+    be read (dotenv files exist, bundles decrypt), and the configured
+    transforms and schema accept the values
+  - outside CI, the teller binary used for fallback is installed
+  - the tools given with --tools (e.g. gh for github-secret add) are installed
+
+In GitHub Actions each problem is reported as an error annotation, pointing at
+the config line where possible.
+
+Examples:
+  feller preflight
+  feller preflight --tools gh,cosign`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		findings := runPreflight()
+		platform, _ := currentPlatform()
+		writePreflight(cmd.OutOrStdout(), findings, platform.ID == ci.GitHubActions)
+
+		errorCount := 0
+		for _, f := range findings {
+			if f.Severity == validate.SeverityError {
+				errorCount++
+			}
+		}
+		if errorCount > 0 {
+			return fmt.Errorf("preflight found %d problem(s)", errorCount)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(preflightCmd)
+	preflightCmd.Flags().StringSliceVar(&preflightTools, "tools", nil, "Additional tools that must be installed (comma-separated)")
+}
+
+// preflightFinding is one problem found by preflight
+type preflightFinding struct {
+	validate.Diagnostic
+	File string // Config file the finding points at, if any
+}
+
+// runPreflight runs every check and returns the findings in a stable order
+func runPreflight() []preflightFinding {
+	var findings []preflightFinding
+	fail := func(format string, args ...any) {
+		findings = append(findings, preflightFinding{
+			Diagnostic: validate.Diagnostic{Severity: validate.SeverityError, Message: fmt.Sprintf(format, args...)},
+		})
+	}
+
+	native, err := resolveNatively()
+	if err != nil {
+		fail("%v", err)
+	}
+	findings = append(findings, preflightConfig(fail, native)...)
+
+	if !native {
+		if _, err := findTellerBinary(); err != nil {
+			fail("teller is required outside CI: %v", err)
+		}
+	}
+	for _, tool := range preflightTools {
+		if _, err := exec.LookPath(tool); err != nil {
+			fail("%s is required but not installed: %v", tool, err)
+		}
+	}
+	return findings
+}
+
+// preflightConfig validates the config and, when resolving natively, resolves every provider
+func preflightConfig(fail func(format string, args ...any), native bool) []preflightFinding {
+	path, err := config.ResolveConfigPath(cfgFile)
+	if err != nil {
+		fail("failed to find config: %v", err)
+		return nil
+	}
+	diagnostics, err := validate.FileWithOptions(path, validateOptions())
+	if err != nil {
+		fail("failed to validate config: %v", err)
+		return nil
+	}
+
+	findings := make([]preflightFinding, 0, len(diagnostics))
+	for _, d := range diagnostics {
+		findings = append(findings, preflightFinding{Diagnostic: d, File: path})
+	}
+	if validate.HasErrors(diagnostics) || !native {
+		return findings
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		fail("failed to load config: %v", err)
+		return findings
+	}
+
+	// Resolve providers one by one so a failing provider does not hide problems of the others
+	names := make([]string, 0, len(cfg.Providers))
+	for name := range cfg.Providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	healthy := true
+	for _, name := range names {
+		single := &config.TellerConfig{Providers: map[string]config.Provider{name: cfg.Providers[name]}, OnCI: cfg.OnCI}
+		result, err := providers.CollectSecretsWithResult(single, true)
+		if err != nil {
+			fail("provider %s cannot be read: %v", name, err)
+			healthy = false
+			continue
+		}
+		platform, _ := currentPlatform()
+		for _, mv := range result.MissingVars {
+			hint := ""
+			if platform.ID == ci.GitHubActions {
+				hint = fmt.Sprintf("; add `%s: ${{ secrets.%s }}` to the step env", mv.VariableName, mv.VariableName)
+			}
+			fail("environment variable %s (maps to %s) of provider %s is not set%s", mv.VariableName, mv.MappedTo, mv.Provider, hint)
+			healthy = false
+		}
+	}
+
+	if healthy {
+		if _, err := providers.CollectSecretsWithResult(cfg, true); err != nil {
+			fail("secrets cannot be resolved: %v", err)
+		}
+	}
+	return findings
+}
+
+// writePreflight prints the findings, as workflow annotations when running in GitHub Actions
+func writePreflight(out io.Writer, findings []preflightFinding, annotate bool) {
+	for _, f := range findings {
+		switch {
+		case annotate:
+			fmt.Fprintln(out, annotation(f))
+		case f.File != "":
+			fmt.Fprintf(out, "%s:%s\n", f.File, f.Diagnostic)
+		default:
+			fmt.Fprintf(out, "%s: %s\n", f.Severity, f.Message)
+		}
+	}
+	if len(findings) == 0 {
+		fmt.Fprintln(out, "preflight passed")
+	}
+}
+
+// annotation formats a finding as a GitHub Actions workflow command
+func annotation(f preflightFinding) string {
+	properties := []string{"title=feller preflight"}
+	if f.File != "" {
+		properties = append(properties, "file="+escapeAnnotationProperty(f.File))
+		if f.Line > 0 {
+			properties = append(properties, fmt.Sprintf("line=%d", f.Line))
+		}
+		if f.Column > 0 {
+			properties = append(properties, fmt.Sprintf("col=%d", f.Column))
+		}
+	}
+	return fmt.Sprintf("::%s %s::%s", f.Severity, strings.Join(properties, ","), escapeAnnotationData(f.Message))
+}
+
+// escapeAnnotationData escapes the message of a workflow command
+func escapeAnnotationData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// escapeAnnotationProperty escapes a property value of a workflow command
+func escapeAnnotationProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/containifyci/feller/pkg/config"
+	"github.com/containifyci/feller/pkg/fellertest"
+	"github.com/containifyci/feller/pkg/providers"
+	"github.com/containifyci/feller/pkg/validate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//nolint:paralleltest // modifies global flag variables and environment variables
+func TestRunPreflight(t *testing.T) {
+	t.Setenv("GITHUB_ACTIONS", "true")
+	cfg := fellertest.NewConfig().
+		Provider("gsm", fellertest.MissingGSM(t, "PREFLIGHT_TOKEN")).
+		Provider("local", fellertest.FakeDotenv(t, map[string]string{"LOCAL": "x"})).
+		Provider("stale", config.Provider{
+			Kind: providers.KindDotenv,
+			Maps: []config.PathMap{{ID: "gone", Path: filepath.Join(t.TempDir(), "gone.env"), Keys: map[string]string{"A": "A"}}},
+		}).
+		Build()
+	path := fellertest.WriteConfig(t, cfg)
+
+	originalCfg, originalTools := cfgFile, preflightTools
+	t.Cleanup(func() { cfgFile, preflightTools = originalCfg, originalTools })
+	cfgFile = path
+	preflightTools = []string{"feller-preflight-missing-tool"}
+
+	var messages []string
+	for _, f := range runPreflight() {
+		if f.Severity == validate.SeverityError && f.File == "" {
+			messages = append(messages, f.Message)
+		}
+	}
+	require.Len(t, messages, 3)
+	assert.Contains(t, messages[0], "environment variable PREFLIGHT_TOKEN (maps to PREFLIGHT_TOKEN) of provider gsm is not set")
+	assert.Contains(t, messages[1], "provider stale cannot be read")
+	assert.Contains(t, messages[2], "feller-preflight-missing-tool is required but not installed")
+}
+
+//nolint:paralleltest // modifies global flag variables and environment variables
+func TestRunPreflightPasses(t *testing.T) {
+	t.Setenv("GITHUB_ACTIONS", "true")
+	cfg := fellertest.NewConfig().
+		Provider("gsm", fellertest.FakeGSM(t, map[string]string{"PREFLIGHT_TOKEN": "t"})).
+		Build()
+
+	originalCfg := cfgFile
+	t.Cleanup(func() { cfgFile = originalCfg })
+	cfgFile = fellertest.WriteConfig(t, cfg)
+
+	findings := runPreflight()
+	assert.Empty(t, findings)
+
+	var buf bytes.Buffer
+	writePreflight(&buf, findings, true)
+	assert.Equal(t, "preflight passed\n", buf.String())
+}
+
+func TestWritePreflight(t *testing.T) {
+	t.Parallel()
+	findings := []preflightFinding{
+		{Diagnostic: validate.Diagnostic{Severity: "warning", Message: "unknown field", Line: 3, Column: 5}, File: "ci/a,b.yml"},
+		{Diagnostic: validate.Diagnostic{Severity: "error", Message: "100% broken\nreally"}},
+	}
+
+	t.Run("annotations", func(t *testing.T) {
+		t.Parallel()
+		var buf bytes.Buffer
+		writePreflight(&buf, findings, true)
+		expected := "::warning title=feller preflight,file=ci/a%2Cb.yml,line=3,col=5::unknown field\n" +
+			"::error title=feller preflight::100%25 broken%0Areally\n"
+		assert.Equal(t, expected, buf.String())
+	})
+
+	t.Run("plain", func(t *testing.T) {
+		t.Parallel()
+		var buf bytes.Buffer
+		writePreflight(&buf, findings, false)
+		assert.Equal(t, "ci/a,b.yml:3:5: warning: unknown field\nerror: 100% broken\nreally\n", buf.String())
+	})
+}
@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"runtime"
+	runtimepprof "runtime/pprof"
+	"time"
+
+	"github.com/containifyci/feller/pkg/logger"
+)
+
+// defaultPprofAddr is used when --pprof is given without an address
+const defaultPprofAddr = "localhost:6060"
+
+var (
+	pprofAddr     string
+	cpuProfile    string
+	memProfile    string
+	activeProfile *profiler
+)
+
+// profiler holds the profiling resources started for one command invocation
+type profiler struct {
+	cpuFile *os.File
+	server  *http.Server
+	addr    string // Address the pprof server listens on
+	memPath string
+}
+
+// startProfiling starts the profiles requested by --pprof, --profile and --memprofile
+func startProfiling() (*profiler, error) {
+	p := &profiler{memPath: memProfile}
+
+	if cpuProfile != "" {
+		// #nosec G304 - Profile path is provided by the user
+		f, err := os.Create(cpuProfile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create CPU profile: %w", err)
+		}
+		if err := runtimepprof.StartCPUProfile(f); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("failed to start CPU profile: %w", err)
+		}
+		p.cpuFile = f
+		logger.Debug("Writing CPU profile to %s", cpuProfile)
+	}
+
+	if pprofAddr != "" {
+		listener, err := net.Listen("tcp", pprofAddr)
+		if err != nil {
+			_ = p.stop()
+			return nil, fmt.Errorf("failed to listen for pprof on %s: %w", pprofAddr, err)
+		}
+		p.addr = listener.Addr().String()
+		p.server = &http.Server{Handler: pprofHandler(), ReadHeaderTimeout: 10 * time.Second}
+		go func() {
+			if err := p.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logger.Error("pprof server failed: %v", err)
+			}
+		}()
+		logger.Info("pprof endpoints available at http://%s/debug/pprof/", p.addr)
+	}
+
+	return p, nil
+}
+
+// stopProfiling stops the profiles of the current invocation, if any. It runs before the
+// process exits, so failures are logged rather than returned.
+func stopProfiling() {
+	if activeProfile == nil {
+		return
+	}
+	if err := activeProfile.stop(); err != nil {
+		logger.Error("%v", err)
+	}
+	activeProfile = nil
+}
+
+// pprofHandler serves the pprof endpoints on a private mux so nothing else is exposed
+func pprofHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	return mux
+}
+
+// stop finishes the CPU profile, writes the allocation profile and shuts the pprof server down
+func (p *profiler) stop() error {
+	var errs []error
+
+	if p.cpuFile != nil {
+		runtimepprof.StopCPUProfile()
+		if err := p.cpuFile.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to write CPU profile: %w", err))
+		}
+		p.cpuFile = nil
+	}
+
+	if p.memPath != "" {
+		if err := writeAllocProfile(p.memPath); err != nil {
+			errs = append(errs, err)
+		}
+		p.memPath = ""
+	}
+
+	if p.server != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := p.server.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("failed to stop pprof server: %w", err))
+		}
+		p.server = nil
+	}
+
+	return errors.Join(errs...)
+}
+
+// writeAllocProfile writes every allocation since the program started to path
+func writeAllocProfile(path string) error {
+	// #nosec G304 - Profile path is provided by the user
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create memory profile: %w", err)
+	}
+
+	// Collect garbage first so the in-use figures are up to date
+	runtime.GC()
+	if err := runtimepprof.Lookup("allocs").WriteTo(f, 0); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write memory profile: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write memory profile: %w", err)
+	}
+	logger.Debug("Wrote memory profile to %s", path)
+	return nil
+}
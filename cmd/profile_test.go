@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setProfileFlags sets the profiling flags for one test and restores them afterwards
+func setProfileFlags(t *testing.T, addr, cpu, mem string) {
+	t.Helper()
+	originalAddr, originalCPU, originalMem := pprofAddr, cpuProfile, memProfile
+	t.Cleanup(func() {
+		pprofAddr, cpuProfile, memProfile = originalAddr, originalCPU, originalMem
+	})
+	pprofAddr, cpuProfile, memProfile = addr, cpu, mem
+}
+
+//nolint:paralleltest // modifies global flag variables
+func TestProfilingFiles(t *testing.T) {
+	dir := t.TempDir()
+	cpu, mem := filepath.Join(dir, "cpu.out"), filepath.Join(dir, "mem.out")
+	setProfileFlags(t, "", cpu, mem)
+
+	p, err := startProfiling()
+	require.NoError(t, err)
+	require.NoError(t, p.stop())
+
+	for _, path := range []string{cpu, mem} {
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		assert.Positive(t, info.Size(), "%s is empty", path)
+	}
+
+	// Stopping twice is harmless
+	require.NoError(t, p.stop())
+}
+
+//nolint:paralleltest // modifies global flag variables
+func TestProfilingServer(t *testing.T) {
+	setProfileFlags(t, "127.0.0.1:0", "", "")
+
+	p, err := startProfiling()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = p.stop() })
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, "http://"+p.addr+"/debug/pprof/cmdline", nil)
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.NotEmpty(t, body)
+
+	require.NoError(t, p.stop())
+	_, err = http.DefaultClient.Do(req)
+	assert.Error(t, err, "pprof server still answering after stop")
+}
+
+//nolint:paralleltest // modifies global flag variables
+func TestProfilingErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		addr        string
+		cpu         string
+		errContains string
+	}{
+		{name: "cpu profile in missing directory", cpu: filepath.Join(t.TempDir(), "missing", "cpu.out"), errContains: "failed to create CPU profile"},
+		{name: "invalid pprof address", addr: "not-an-address", errContains: "failed to listen for pprof"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setProfileFlags(t, tt.addr, tt.cpu, "")
+			_, err := startProfiling()
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errContains)
+		})
+	}
+}
+
+func TestProfilingFlagsHidden(t *testing.T) {
+	t.Parallel()
+	for _, name := range []string{"pprof", "profile", "memprofile"} {
+		flag := rootCmd.PersistentFlags().Lookup(name)
+		require.NotNil(t, flag, name)
+		assert.True(t, flag.Hidden, "--%s should be hidden", name)
+	}
+	assert.Equal(t, defaultPprofAddr, rootCmd.PersistentFlags().Lookup("pprof").NoOptDefVal)
+}
@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/containifyci/feller/pkg/providers"
+	"github.com/spf13/cobra"
+)
+
+var (
+	kindsJSON   bool
+	pluginsJSON bool
+)
+
+// providersCmd represents the providers command group
+var providersCmd = &cobra.Command{
+	Use:   "providers",
+	Short: "Inspect supported secret providers",
+	Long: `Inspect the secret providers supported by feller.
+
+Available subcommands:
+  kinds    List supported provider kinds and their capabilities
+  plugins  List the provider plugins installed in PATH
+
+Examples:
+  feller providers kinds
+  feller providers kinds --json
+  feller providers plugins`,
+}
+
+// providersKindsCmd represents the providers kinds command
+var providersKindsCmd = &cobra.Command{
+	Use:   "kinds",
+	Short: "List supported provider kinds and their capabilities",
+	Long: `List every provider kind supported by feller with its capabilities
+(read, write, discovery), authentication methods, and required options.
+
+Use --json for machine-readable output, e.g. for editor tooling.
+
+Examples:
+  feller providers kinds
+  feller providers kinds --json | jq '.[].kind'`,
+	Args: cobra.NoArgs,
+	RunE: func(_ *cobra.Command, _ []string) error {
+		return writeKinds(os.Stdout, providers.Kinds(), kindsJSON)
+	},
+}
+
+// providersPluginsCmd represents the providers plugins command
+var providersPluginsCmd = &cobra.Command{
+	Use:   "plugins",
+	Short: "List the provider plugins installed in PATH",
+	Long: `List the provider plugins in PATH: executables named feller-provider-NAME, used by
+providers of kind plugin/NAME. Each plugin is started for the handshake, which shows the
+protocol version it speaks and what it supports; plugins failing the handshake are listed
+with the error.
+
+Examples:
+  feller providers plugins
+  feller providers plugins --json`,
+	Args: cobra.NoArgs,
+	RunE: func(_ *cobra.Command, _ []string) error {
+		return writePlugins(os.Stdout, probePlugins(providers.DiscoverPlugins()), pluginsJSON)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(providersCmd)
+	providersCmd.AddCommand(providersKindsCmd, providersPluginsCmd)
+	providersKindsCmd.Flags().BoolVar(&kindsJSON, "json", false, "Output as JSON")
+	providersPluginsCmd.Flags().BoolVar(&pluginsJSON, "json", false, "Output as JSON")
+}
+
+// pluginStatus is a plugin found in PATH with the result of its handshake
+type pluginStatus struct {
+	providers.PluginInfo
+	Kind      string                     `json:"kind"`
+	Handshake *providers.PluginHandshake `json:"handshake,omitempty"`
+	Error     string                     `json:"error,omitempty"`
+}
+
+// probePlugins performs the handshake with every plugin
+func probePlugins(plugins []providers.PluginInfo) []pluginStatus {
+	statuses := make([]pluginStatus, 0, len(plugins))
+	for _, plugin := range plugins {
+		status := pluginStatus{PluginInfo: plugin, Kind: providers.PluginKindPrefix + plugin.Name}
+		if handshake, err := providers.ProbePlugin(plugin.Name); err != nil {
+			status.Error = err.Error()
+		} else {
+			status.Handshake = &handshake
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// writePlugins prints plugins as a table or JSON array
+func writePlugins(out io.Writer, plugins []pluginStatus, asJSON bool) error {
+	if asJSON {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(plugins); err != nil {
+			return fmt.Errorf("failed to encode provider plugins: %w", err)
+		}
+		return nil
+	}
+	if len(plugins) == 0 {
+		fmt.Fprintf(out, "No provider plugins in PATH (executables named %sNAME)\n", providers.PluginBinaryPrefix)
+		return nil
+	}
+
+	w := newTable(out)
+	fmt.Fprintln(w, "KIND\tPROTOCOL\tDISCOVERY\tVERSIONS\tPATH\tDESCRIPTION")
+	for _, plugin := range plugins {
+		if plugin.Handshake == nil {
+			fmt.Fprintf(w, "%s\t-\t-\t-\t%s\terror: %s\n", plugin.Kind, plugin.Path, plugin.Error)
+			continue
+		}
+		h := plugin.Handshake
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t%s\n", plugin.Kind, h.Protocol,
+			yesNo(h.Capabilities.Discovery), yesNo(h.Versions), plugin.Path, h.Description)
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to write provider plugins: %w", err)
+	}
+	return nil
+}
+
+// writeKinds prints provider kinds as a table or JSON array
+func writeKinds(out io.Writer, kinds []providers.KindInfo, asJSON bool) error {
+	if asJSON {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(kinds); err != nil {
+			return fmt.Errorf("failed to encode provider kinds: %w", err)
+		}
+		return nil
+	}
+
+	w := newTable(out)
+	fmt.Fprintln(w, "KIND\tREAD\tWRITE\tDISCOVERY\tAUTH\tDESCRIPTION")
+	for _, kind := range kinds {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", kind.Kind,
+			yesNo(kind.Capabilities.Read), yesNo(kind.Capabilities.Write), yesNo(kind.Capabilities.Discovery),
+			strings.Join(kind.AuthMethods, ","), kind.Description)
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to write provider kinds: %w", err)
+	}
+	return nil
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/containifyci/feller/pkg/providers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteKinds(t *testing.T) {
+	t.Parallel()
+	kinds := []providers.KindInfo{{
+		Kind:              "dotenv",
+		Description:       "Reads secrets from a local .env file",
+		Capabilities:      providers.Capabilities{Read: true, Discovery: true},
+		AuthMethods:       []string{"none"},
+		RequiredOptions:   []string{},
+		RequiredMapFields: []string{"id", "path"},
+	}}
+
+	t.Run("table", func(t *testing.T) {
+		t.Parallel()
+		var buf bytes.Buffer
+		require.NoError(t, writeKinds(&buf, kinds, false))
+		expected := "KIND    READ  WRITE  DISCOVERY  AUTH  DESCRIPTION\n" +
+			"dotenv  yes   no     yes        none  Reads secrets from a local .env file\n"
+		assert.Equal(t, expected, buf.String())
+	})
+
+	t.Run("json", func(t *testing.T) {
+		t.Parallel()
+		var buf bytes.Buffer
+		require.NoError(t, writeKinds(&buf, kinds, true))
+
+		var decoded []map[string]any
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+		require.Len(t, decoded, 1)
+		assert.Equal(t, "dotenv", decoded[0]["kind"])
+		assert.Equal(t, map[string]any{"read": true, "write": false, "discovery": true}, decoded[0]["capabilities"])
+		assert.Equal(t, []any{"id", "path"}, decoded[0]["required_map_fields"])
+		assert.Equal(t, []any{}, decoded[0]["required_options"])
+	})
+}
+
+func TestWritePlugins(t *testing.T) {
+	t.Parallel()
+	plugins := []pluginStatus{
+		{
+			PluginInfo: providers.PluginInfo{Name: "onepassword", Path: "/usr/local/bin/feller-provider-onepassword"},
+			Kind:       "plugin/onepassword",
+			Handshake: &providers.PluginHandshake{
+				Type: "handshake", Protocol: 1, Description: "1Password vaults",
+				Capabilities: providers.Capabilities{Read: true, Discovery: true},
+			},
+		},
+		{
+			PluginInfo: providers.PluginInfo{Name: "old", Path: "/usr/local/bin/feller-provider-old"},
+			Kind:       "plugin/old",
+			Error:      "plugin old speaks protocol version 0, feller speaks 1; update feller or the plugin",
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, writePlugins(&buf, plugins, false))
+	expected := "KIND                PROTOCOL  DISCOVERY  VERSIONS  PATH                                        DESCRIPTION\n" +
+		"plugin/onepassword  1         yes        no        /usr/local/bin/feller-provider-onepassword  1Password vaults\n" +
+		"plugin/old          -         -          -         /usr/local/bin/feller-provider-old          error: plugin old speaks protocol version 0, feller speaks 1; update feller or the plugin\n"
+	assert.Equal(t, expected, buf.String())
+
+	buf.Reset()
+	require.NoError(t, writePlugins(&buf, nil, false))
+	assert.Equal(t, "No provider plugins in PATH (executables named feller-provider-NAME)\n", buf.String())
+
+	buf.Reset()
+	require.NoError(t, writePlugins(&buf, plugins, true))
+	var decoded []map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	require.Len(t, decoded, 2)
+	assert.Equal(t, "plugin/onepassword", decoded[0]["kind"])
+	assert.Equal(t, "/usr/local/bin/feller-provider-onepassword", decoded[0]["path"])
+	assert.NotContains(t, decoded[0], "error")
+	assert.Contains(t, decoded[1]["error"], "protocol version 0")
+}
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// psCmd represents the ps command
+var psCmd = &cobra.Command{
+	Use:   "ps",
+	Short: "List processes started with feller run --detach",
+	Long: `List background processes started with 'feller run --detach'.
+
+Shows the name, PID, status, and log file of every detached process that
+still has a pidfile in the run state directory.
+
+Examples:
+  feller ps`,
+	Args: cobra.NoArgs,
+	RunE: listProcesses,
+}
+
+func init() {
+	rootCmd.AddCommand(psCmd)
+}
+
+func listProcesses(_ *cobra.Command, _ []string) error {
+	processes, err := listDetachedProcesses()
+	if err != nil {
+		return err
+	}
+
+	if len(processes) == 0 {
+		fmt.Println("No detached processes")
+		return nil
+	}
+
+	w := newTable(os.Stdout)
+	fmt.Fprintln(w, "NAME\tPID\tSTATUS\tLOG")
+	for _, process := range processes {
+		status := "exited"
+		if process.Running {
+			status = "running"
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", process.Name, process.PID, status, process.LogFile)
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to write process list: %w", err)
+	}
+	return nil
+}
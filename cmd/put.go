@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/containifyci/feller/pkg/plan"
+	"github.com/containifyci/feller/pkg/providers"
+	"github.com/spf13/cobra"
+)
+
+var (
+	putProvider string
+	putMap      string
+	putDryRun   bool
+	putYes      bool
+)
+
+// putCmd represents the put command
+var putCmd = &cobra.Command{
+	Use:   "put KEY[=VALUE]... --provider NAME",
+	Short: "Create or update secrets in a provider",
+	Long: `Create or update secrets in the backend of a provider: a dotenv file, a
+HashiCorp Vault KV secret or a Google Secret Manager project.
+
+KEY is the key applications see; maps that rename keys are written under the
+source name, so the provider resolves them to the same key. A KEY without
+=VALUE takes its value from the environment variable of the same name, which
+keeps the value out of the shell history.
+
+The changes are printed as a plan (+ create, ~ update) and applied after a
+[y/N] confirmation, or right away with --yes. --dry-run stops after the plan,
+and --json prints it as JSON.
+
+Secrets are written to the provider's only map, or the map named by --map:
+  dotenv                 the file is rewritten atomically with 0600
+                         permissions, keeping its other lines
+  hashicorp_vault        the secret at the map's path keeps its other keys,
+                         using the provider's Vault options and login
+  google_secretmanager   the map's path must be projects/PROJECT; missing
+                         secrets are created with automatic replication and
+                         every value is added as a new version. The access
+                         token comes from GOOGLE_OAUTH_ACCESS_TOKEN or gcloud.
+
+Examples:
+  feller put API_KEY=abc123 --provider local
+  DATABASE_URL=postgres://... feller put DATABASE_URL --provider vault --map app
+  feller put API_KEY --provider gsm --dry-run --json`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runPut(cmd.InOrStdin(), cmd.OutOrStdout(), args)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(putCmd)
+	putCmd.Flags().StringVar(&putProvider, "provider", "", "Provider to write the secrets to")
+	putCmd.Flags().StringVar(&putMap, "map", "", "Path map id to write to, when the provider has several")
+	putCmd.Flags().BoolVar(&putDryRun, "dry-run", false, "Print the plan without changing anything")
+	putCmd.Flags().BoolVarP(&putYes, "yes", "y", false, "Write without asking for confirmation")
+	putCmd.Flags().BoolVar(&planJSON, "json", false, "Print the plan of --dry-run as JSON")
+	_ = putCmd.MarkFlagRequired("provider")
+	_ = putCmd.RegisterFlagCompletionFunc("provider", completeProviderList)
+}
+
+func runPut(in io.Reader, out io.Writer, args []string) error {
+	if planJSON && !putDryRun {
+		return errors.New("--json requires --dry-run")
+	}
+	values, err := parsePutArgs(args)
+	if err != nil {
+		return err
+	}
+
+	unlock, err := lockUnlessDryRun("put", putDryRun)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	target, err := openWriteTarget(putProvider, putMap)
+	if err != nil {
+		return err
+	}
+	sources, err := target.sources(slices.Sorted(maps.Keys(values)))
+	if err != nil {
+		return err
+	}
+	before, err := target.writer.Read(sourceNames(sources))
+	if err != nil {
+		return err //nolint:wrapcheck // names the provider
+	}
+
+	p := plan.New("put")
+	restored := make(providers.SecretMap, len(values))
+	for _, key := range slices.Sorted(maps.Keys(values)) {
+		restored[sources[key]] = values[key]
+		change := target.change(plan.ActionCreate, key, sources[key])
+		if _, ok := before[sources[key]]; ok {
+			change.Action = plan.ActionUpdate
+		}
+		p.Add(change)
+	}
+	if err := writePlan(out, p); err != nil {
+		return err
+	}
+	if putDryRun {
+		if !planJSON {
+			fmt.Fprintln(out, "Dry run, nothing was changed")
+		}
+		return nil
+	}
+	if !putYes && !confirm(in, out, fmt.Sprintf("Write %d secret(s) to %s?", len(restored), target.writer.Target())) {
+		return errors.New("put cancelled, nothing was changed")
+	}
+
+	if err := target.backup("put", sourceNames(sources), before); err != nil {
+		return err
+	}
+	if err := target.writer.Put(restored); err != nil {
+		return err //nolint:wrapcheck // names the provider or file
+	}
+	target.record("put", slices.Sorted(maps.Keys(values)))
+	fmt.Fprintf(out, "Wrote %d secret(s) to %s (provider %s, map %s)\n", len(restored), target.writer.Target(), target.name, target.pathMap.ID)
+	return nil
+}
+
+// parsePutArgs returns the values of KEY=VALUE arguments, taking the value of a bare KEY
+// from the environment
+func parsePutArgs(args []string) (providers.SecretMap, error) {
+	values := make(providers.SecretMap, len(args))
+	for _, arg := range args {
+		key, value, hasValue := strings.Cut(arg, "=")
+		if key == "" {
+			return nil, fmt.Errorf("invalid argument %q, expected KEY=VALUE or KEY", arg)
+		}
+		if _, duplicate := values[key]; duplicate {
+			return nil, fmt.Errorf("key %s is given more than once", key)
+		}
+		if !hasValue {
+			var ok bool
+			if value, ok = os.LookupEnv(key); !ok {
+				return nil, fmt.Errorf("no value for %s: pass %s=VALUE or set the environment variable %s", key, key, key)
+			}
+		}
+		values[key] = value
+	}
+	return values, nil
+}
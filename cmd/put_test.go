@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/containifyci/feller/pkg/config"
+	"github.com/containifyci/feller/pkg/fellertest"
+	"github.com/containifyci/feller/pkg/providers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//nolint:paralleltest // modifies environment variables and global flag variables
+func TestPut(t *testing.T) {
+	t.Setenv("DATABASE_URL", "postgres://db")
+	t.Cleanup(func() {
+		cfgFile, putProvider, putMap, putDryRun, putYes, planJSON = "", "", "", false, false, false
+	})
+
+	local := fellertest.FakeDotenv(t, map[string]string{"api_key": "old"})
+	local.Maps[0].Keys = map[string]string{"api_key": "API_KEY", "DATABASE_URL": "DATABASE_URL"}
+	path := config.LocalPath(local.Maps[0].Path)
+	cfgFile = fellertest.WriteConfig(t, fellertest.NewConfig().Provider("local", local).Provider("gh", config.Provider{
+		Kind: providers.KindGitHub, Maps: []config.PathMap{{ID: "ci"}},
+	}).Build())
+	putProvider = "local"
+
+	putDryRun = true
+	var out bytes.Buffer
+	require.NoError(t, runPut(strings.NewReader(""), &out, []string{"API_KEY=new", "DATABASE_URL"}))
+	assert.Equal(t, "dotenv:"+path+" (local)\n"+
+		"  ~ API_KEY       as api_key\n"+
+		"  + DATABASE_URL\n"+
+		"Plan: 1 to create, 1 to update, 0 to delete.\n"+
+		"Dry run, nothing was changed\n", out.String())
+
+	// Declining the confirmation writes nothing
+	putDryRun = false
+	out.Reset()
+	err := runPut(strings.NewReader("n\n"), &out, []string{"API_KEY=new"})
+	require.EqualError(t, err, "put cancelled, nothing was changed")
+	assert.Contains(t, out.String(), "Write 1 secret(s) to dotenv:"+path+"? [y/N]: ")
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "api_key=\"old\"\n", string(data))
+
+	out.Reset()
+	require.NoError(t, runPut(strings.NewReader("y\n"), &out, []string{"API_KEY=new", "DATABASE_URL"}))
+	assert.Contains(t, out.String(), "Wrote 2 secret(s) to dotenv:"+path+" (provider local, map "+fellertest.FakeMapID+")\n")
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "api_key=\"new\"\nDATABASE_URL=\"postgres://db\"\n", string(data))
+
+	tests := []struct {
+		name     string
+		provider string
+		args     []string
+		wantErr  string
+	}{
+		{name: "key the map does not read", provider: "local", args: []string{"OTHER=1"}, wantErr: "map " + fellertest.FakeMapID + " of provider local does not read OTHER"},
+		{name: "unset variable", provider: "local", args: []string{"NO_SUCH_VARIABLE"}, wantErr: "no value for NO_SUCH_VARIABLE: pass NO_SUCH_VARIABLE=VALUE or set the environment variable NO_SUCH_VARIABLE"},
+		{name: "duplicate key", provider: "local", args: []string{"API_KEY=1", "API_KEY=2"}, wantErr: "key API_KEY is given more than once"},
+		{name: "read-only provider", provider: "gh", args: []string{"API_KEY=1"}, wantErr: "which feller cannot write"},
+		{name: "unknown provider", provider: "missing", args: []string{"API_KEY=1"}, wantErr: `unknown provider "missing"`},
+	}
+	putYes = true
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			putProvider = tt.provider
+			err := runPut(strings.NewReader(""), &bytes.Buffer{}, tt.args)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
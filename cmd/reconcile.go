@@ -0,0 +1,550 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"os/signal"
+	"slices"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/containifyci/feller/pkg/config"
+	"github.com/containifyci/feller/pkg/logger"
+	"github.com/containifyci/feller/pkg/metrics"
+	"github.com/containifyci/feller/pkg/plan"
+	"github.com/containifyci/feller/pkg/providers"
+	"github.com/containifyci/feller/pkg/reconcile"
+	"github.com/spf13/cobra"
+)
+
+var (
+	reconcileInterval    time.Duration
+	reconcileCheck       bool
+	reconcileTargets     []string
+	reconcileMetricsFile string
+	reconcileMetricsAddr string
+
+	// reconcileMetrics holds the metrics of the runs of this process
+	reconcileMetrics = metrics.NewRegistry()
+	// reconcileSharedReads is providers.SharedReads at the end of the previous run
+	reconcileSharedReads int64
+)
+
+var missingReconcile = providers.MissingContext{
+	Command: "reconcile",
+	Prefix:  "Cannot reconcile targets: ",
+	Step:    "Reconcile targets",
+	Run:     "feller reconcile",
+	Hint:    "Resolve every secret before reconciling, or use --silent to reconcile the available secrets only.",
+}
+
+// reconcileCmd represents the reconcile command
+var reconcileCmd = &cobra.Command{
+	Use:   "reconcile",
+	Short: "Keep external targets in line with the config",
+	Long: `Make the targets of the config hold its secrets, and report and fix drift:
+secrets a target is missing and secrets whose value changed since feller wrote
+them. Targets are listed in the targets section of the config:
+
+  targets:
+    ci:
+      kind: github
+      repo: owner/repo
+      dependabot: true      # also the Dependabot secrets
+      providers: [gsm]      # only secrets of these providers (default: all)
+      keys: [API_KEY]       # only these keys (default: all)
+    web:
+      kind: vercel
+      project: web          # Vercel project, written with VERCEL_TOKEN
+      environments: [production]
+    edge:
+      kind: cloudflare
+      account: 0123abcd     # Cloudflare account, written with CLOUDFLARE_API_TOKEN
+      script: edge          # Worker name
+    api:
+      kind: fly
+      app: api              # Fly.io app, written with FLY_API_TOKEN
+
+Keys listed in the sync section of the config are written to the targets named
+there under per-target names instead (see 'feller sync').
+
+GitHub, Cloudflare and Fly.io never return secret values, so feller records
+salted fingerprints of the values it wrote in the .feller directory next to the
+config. Secrets feller did not write yet are reported as unverified and written
+once. Secrets of the target that the config does not hold are left alone.
+GitHub secrets are written with the GitHub CLI (gh), Vercel variables,
+Cloudflare Worker secrets and Fly.io app secrets with the APIs of these
+platforms. Every change of Fly.io secrets creates a release of the app.
+--token-stdin reads the token gh uses from stdin.
+
+Secrets are resolved by feller itself, so providers must be of kinds feller
+supports. Without --interval every target is reconciled once; with it the config
+is reloaded and the targets reconciled on every interval until interrupted, for
+a small controller deployment. --check reports drift without fixing it and fails
+when there is any, for a scheduled workflow; with --json the drift is printed as
+a JSON plan of the changes that fix it, for tools. --metrics-file writes the outcome
+of every run in the Prometheus text format, e.g. for the node exporter's
+textfile collector. With --interval, --metrics-addr serves the same metrics on
+/metrics for Prometheus to scrape: the requests, throttled requests and time of
+every provider, env file reads shared by concurrent loads, errors, resolved
+secrets, secrets written to targets, and the drift of every target. Providers
+with a cache option are served from the cache while they are refreshed, so a
+short outage does not fail the runs, and their health is added to the metrics.
+
+Examples:
+  feller reconcile --check
+  feller reconcile --check --json
+  feller reconcile --target ci
+  feller reconcile --config . --interval 5m --metrics-file /var/lib/node_exporter/feller.prom
+  feller reconcile --interval 5m --metrics-addr :9090`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		if err := loadGitHubToken(cmd.InOrStdin()); err != nil {
+			return err
+		}
+		if reconcileInterval < 0 {
+			return errors.New("--interval must not be negative")
+		}
+		if planJSON && (!reconcileCheck || reconcileInterval > 0) {
+			return errors.New("--json requires --check and cannot be used with --interval")
+		}
+		if reconcileInterval == 0 {
+			if reconcileMetricsAddr != "" {
+				return errors.New("--metrics-addr requires --interval")
+			}
+			return runReconcile(cmd.OutOrStdout())
+		}
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		if reconcileMetricsAddr != "" {
+			_, shutdown, err := serveMetrics(reconcileMetricsAddr, reconcileMetrics)
+			if err != nil {
+				return err
+			}
+			defer shutdown()
+		}
+		return watchReconcile(ctx, cmd.OutOrStdout(), reconcileInterval)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(reconcileCmd)
+	reconcileCmd.Flags().DurationVar(&reconcileInterval, "interval", 0, "Reconcile on every interval until interrupted, e.g. 5m (default: once)")
+	reconcileCmd.Flags().BoolVar(&reconcileCheck, "check", false, "Report drift without fixing it and fail when there is any")
+	reconcileCmd.Flags().BoolVar(&planJSON, "json", false, "With --check, print the drift as a JSON plan of the changes that fix it")
+	reconcileCmd.Flags().StringSliceVar(&reconcileTargets, "target", nil, "Only reconcile these targets (comma-separated names)")
+	reconcileCmd.Flags().StringVar(&reconcileMetricsFile, "metrics-file", "", "Write the metrics in the Prometheus text format to this file after every run")
+	reconcileCmd.Flags().StringVar(&reconcileMetricsAddr, "metrics-addr", "", "Serve the metrics on /metrics at this address with --interval, e.g. :9090")
+	addTokenStdinFlag(reconcileCmd)
+}
+
+// runReconcile reconciles the targets once, failing when a target failed or, with --check,
+// drifted
+func runReconcile(out io.Writer) error {
+	results, err := reconcileTargetsOnce(out)
+	if err != nil {
+		return err
+	}
+	var failed, drifted int
+	for _, result := range results {
+		switch {
+		case result.Err != nil:
+			failed++
+		case len(result.Drift) > 0:
+			drifted++
+		}
+	}
+	switch {
+	case failed > 0:
+		return fmt.Errorf("failed to reconcile %d target(s)", failed)
+	case reconcileCheck && drifted > 0:
+		return fmt.Errorf("%d target(s) drifted from the config", drifted)
+	}
+	return nil
+}
+
+// watchReconcile reconciles the targets on every interval until ctx is done. Failures are
+// reported and retried on the next interval.
+func watchReconcile(ctx context.Context, out io.Writer, interval time.Duration) error {
+	logger.Info("Reconciling every %s, press Ctrl+C to stop", interval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := reconcileTargetsOnce(out); err != nil {
+			logger.Error("%v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// reconcileTargetsOnce loads the config, resolves the secrets and reconciles every selected
+// target, writing the metrics file when requested
+func reconcileTargetsOnce(out io.Writer) ([]reconcile.Result, error) {
+	reconcileMetrics.Add("feller_reconcile_runs_total", "Runs of feller reconcile.", 1)
+	results, err := reconcileRun(out)
+	if err != nil {
+		reconcileMetrics.Add("feller_errors_total", "Errors by stage: resolving the secrets or reconciling a target.", 1, "stage", "resolve")
+	}
+	reconcile.Observe(reconcileMetrics, results)
+	reconcile.ObserveProviders(reconcileMetrics, providers.CacheHealth())
+
+	if reconcileMetricsFile != "" {
+		var b bytes.Buffer
+		if _, err := reconcileMetrics.WriteTo(&b); err != nil {
+			return results, fmt.Errorf("failed to write metrics: %w", err)
+		}
+		// #nosec G306 - Metrics hold no secrets and are read by the metrics collector
+		if err := writeFileAtomic(reconcileMetricsFile, b.Bytes(), 0o644); err != nil {
+			return results, err
+		}
+	}
+	return results, err
+}
+
+// reconcileRun resolves the secrets and reconciles every selected target
+func reconcileRun(out io.Writer) ([]reconcile.Result, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	names, err := selectTargets(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkNativeKinds(cfg); err != nil {
+		return nil, err
+	}
+	result, err := collectSecrets(cfg)
+	if err != nil {
+		return nil, err
+	}
+	observeCollection(result)
+	if err := checkMissing(result, missingReconcile, cfg.Messages); err != nil {
+		return nil, err
+	}
+	configPath, err := config.ResolveConfigPath(cfgFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve config path: %w", err)
+	}
+
+	// Fixing drift changes shared state, so only one process may do it per config
+	if !reconcileCheck {
+		unlock, err := lockConfig("reconcile")
+		if err != nil {
+			return nil, err
+		}
+		defer unlock()
+
+		// Secrets written here must not be skipped as unchanged by github-secret add later
+		if err := loadUploads(); err != nil {
+			return nil, err
+		}
+		defer saveUploads()
+	}
+
+	// With --json the drift is only printed as the plan of fixing it
+	report, p := out, plan.New("reconcile")
+	if planJSON {
+		report = io.Discard
+	}
+	results := make([]reconcile.Result, 0, len(names))
+	for _, name := range names {
+		target := cfg.Targets[name]
+		r := reconcile.Result{Target: name, Time: time.Now()}
+		desired, err := targetSecrets(cfg, name, target, result)
+		if err == nil {
+			r.Secrets = len(desired.Secrets)
+			err = reconcileTarget(report, &r, target, desired, reconcile.Path(configPath, name))
+		}
+		for _, drift := range r.Drift {
+			p.Add(drift.Change(planTarget(target)))
+		}
+		if err != nil {
+			r.Err = err
+			reconcileMetrics.Add("feller_errors_total", "Errors by stage: resolving the secrets or reconciling a target.", 1, "stage", "target")
+			fmt.Fprintf(report, "%s (%s): %v\n", name, targetLabel(target), err)
+		}
+		results = append(results, r)
+	}
+	if planJSON {
+		if err := p.WriteJSON(out); err != nil {
+			return results, err //nolint:wrapcheck // already describes the failure
+		}
+	}
+	return results, nil
+}
+
+// observeCollection counts the provider requests of a collection and sets the number of
+// resolved secrets
+func observeCollection(result *providers.CollectionResult) {
+	for _, timing := range result.Timings {
+		labels := []string{"provider", timing.Provider, "kind", timing.Kind}
+		reconcileMetrics.Add("feller_provider_requests_total", "Requests sent to each provider.", float64(timing.Requests), labels...)
+		reconcileMetrics.Add("feller_provider_throttled_requests_total", "Requests delayed by the rate limit of each provider.", float64(timing.Throttled), labels...)
+		reconcileMetrics.Add("feller_provider_seconds_total", "Time spent collecting from each provider, including rate limit delays.", timing.Elapsed.Seconds(), labels...)
+		hits := 0.0
+		if timing.CacheHit {
+			hits = 1
+		}
+		reconcileMetrics.Add("feller_provider_cache_hits_total", "Collections of each provider served from its cache option.", hits, labels...)
+	}
+	shared := providers.SharedReads()
+	reconcileMetrics.Add("feller_provider_shared_reads_total", "Env file loads that shared one read with a concurrent load of the same file.", float64(shared-reconcileSharedReads))
+	reconcileSharedReads = shared
+	reconcileMetrics.Set("feller_secrets", "Secrets resolved by the last run.", float64(len(result.Secrets)))
+}
+
+// selectTargets returns the names of the targets selected by --target, sorted
+func selectTargets(cfg *config.TellerConfig) ([]string, error) {
+	if len(cfg.Targets) == 0 {
+		return nil, errors.New("the config has no targets, add them to its targets section")
+	}
+	for _, key := range slices.Sorted(maps.Keys(cfg.Sync)) {
+		for _, name := range slices.Sorted(maps.Keys(cfg.Sync[key])) {
+			if _, ok := cfg.Targets[name]; !ok {
+				return nil, fmt.Errorf("sync of %s uses unknown target %q", key, name)
+			}
+		}
+	}
+	var names []string
+	for name := range cfg.Targets {
+		if len(reconcileTargets) == 0 || slices.Contains(reconcileTargets, name) {
+			names = append(names, name)
+		}
+	}
+	for _, name := range reconcileTargets {
+		if _, ok := cfg.Targets[name]; !ok {
+			return nil, fmt.Errorf("unknown target %q", name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// targetSecrets returns the secrets of result the target should hold, under the names they
+// have there. Keys of the sync section of the config are only written to their targets;
+// the providers and keys of the target select the others.
+func targetSecrets(cfg *config.TellerConfig, name string, target config.Target, result *providers.CollectionResult) (*providers.CollectionResult, error) {
+	if err := checkTarget(name, target); err != nil {
+		return nil, err
+	}
+	for _, provider := range target.Providers {
+		if _, ok := cfg.Providers[provider]; !ok {
+			return nil, fmt.Errorf("target %s uses provider %q, which is not configured or not selected", name, provider)
+		}
+	}
+
+	desired := &providers.CollectionResult{Secrets: make(providers.SecretMap), Sources: make(map[string]providers.SecretSource)}
+	keys := make(map[string]string)
+	for _, key := range slices.Sorted(maps.Keys(result.Secrets)) {
+		source, remote := result.Sources[key], key
+		if targets, ok := cfg.Sync[key]; ok {
+			if remote, ok = targets[name]; !ok {
+				continue
+			}
+			if remote == "" {
+				remote = key
+			}
+		} else {
+			if len(target.Keys) > 0 && !slices.Contains(target.Keys, key) {
+				continue
+			}
+			if len(target.Providers) > 0 && !slices.Contains(target.Providers, source.Provider) {
+				continue
+			}
+		}
+		if other, ok := keys[remote]; ok {
+			return nil, fmt.Errorf("target %s would receive %s from both %s and %s, rename one in the sync section", name, remote, other, key)
+		}
+		keys[remote] = key
+		desired.Secrets[remote], desired.Sources[remote] = result.Secrets[key], source
+	}
+	return desired, nil
+}
+
+// checkTarget fails when feller cannot write to the target name
+func checkTarget(name string, target config.Target) error {
+	switch {
+	case !slices.Contains(config.TargetKinds, target.Kind):
+		return fmt.Errorf("target %s is of kind %q, feller can only write %s targets", name, target.Kind, strings.Join(config.TargetKinds, ", "))
+	case target.Kind == config.TargetKindGitHub && target.Repo == "":
+		return fmt.Errorf("target %s has no repo", name)
+	case target.Kind == config.TargetKindVercel && target.Project == "":
+		return fmt.Errorf("target %s has no project", name)
+	case target.Kind == config.TargetKindCloudflare && target.Account == "":
+		return fmt.Errorf("target %s has no account", name)
+	case target.Kind == config.TargetKindCloudflare && target.Script == "":
+		return fmt.Errorf("target %s has no script", name)
+	case target.Kind == config.TargetKindFly && target.App == "":
+		return fmt.Errorf("target %s has no app", name)
+	}
+	return nil
+}
+
+// targetScope is where a target keeps secrets: the keys it holds and how to write and delete
+// one. Read returns the values of the scope and is nil when the target never returns them.
+type targetScope struct {
+	Name     string
+	Existing map[string]bool
+	Set      func(key, value string) error
+	Delete   func(key string) error
+	Read     func() (map[string]string, error)
+}
+
+// targetScopes lists the scopes of target with the keys they hold
+func targetScopes(target config.Target) ([]targetScope, error) {
+	switch target.Kind {
+	case config.TargetKindVercel:
+		existing, err := listVercelEnv(target)
+		if err != nil {
+			return nil, err
+		}
+		return []targetScope{{
+			Name:     "project",
+			Existing: existing,
+			Set:      func(key, value string) error { return setVercelEnv(target, key, value) },
+			Delete:   func(key string) error { return deleteVercelEnv(target, key) },
+			Read:     func() (map[string]string, error) { return readVercelEnv(target) },
+		}}, nil
+	case config.TargetKindCloudflare:
+		existing, err := listCloudflareSecrets(target)
+		if err != nil {
+			return nil, err
+		}
+		return []targetScope{{
+			Name:     "worker",
+			Existing: existing,
+			Set:      func(key, value string) error { return setCloudflareSecret(target, key, value) },
+			Delete:   func(key string) error { return deleteCloudflareSecret(target, key) },
+		}}, nil
+	case config.TargetKindFly:
+		existing, err := listFlySecrets(target)
+		if err != nil {
+			return nil, err
+		}
+		return []targetScope{{
+			Name:     "app",
+			Existing: existing,
+			Set:      func(key, value string) error { return setFlySecret(target, key, value) },
+			Delete:   func(key string) error { return deleteFlySecret(target, key) },
+		}}, nil
+	}
+
+	github := githubRepository{Repo: target.Repo, Dependabot: target.Dependabot}
+	existing, err := getExistingGitHubSecrets(github)
+	if err != nil {
+		return nil, err
+	}
+	scopes := []targetScope{{
+		Name:     "repository",
+		Existing: existing.Repository,
+		Set:      func(key, value string) error { return setGitHubSecret(github, key, value, "repository") },
+		Delete:   func(key string) error { return deleteGitHubSecret(github, key, "repository") },
+	}}
+	if target.Dependabot {
+		scopes = append(scopes, targetScope{
+			Name:     "Dependabot",
+			Existing: existing.Dependabot,
+			Set:      func(key, value string) error { return setGitHubSecret(github, key, value, "Dependabot") },
+			Delete:   func(key string) error { return deleteGitHubSecret(github, key, "Dependabot") },
+		})
+	}
+	return scopes, nil
+}
+
+// targetLabel names the external system of a target in reports, e.g. its GitHub repository
+func targetLabel(target config.Target) string {
+	switch target.Kind {
+	case config.TargetKindVercel:
+		return target.Project
+	case config.TargetKindCloudflare:
+		return target.Script
+	case config.TargetKindFly:
+		return target.App
+	}
+	return target.Repo
+}
+
+// planTarget is the plan target of a target, e.g. "github:owner/repo"
+func planTarget(target config.Target) string {
+	switch target.Kind {
+	case config.TargetKindVercel:
+		return vercelTarget(target.Project)
+	case config.TargetKindCloudflare:
+		return cloudflareTarget(target.Script)
+	case config.TargetKindFly:
+		return flyTarget(target.App)
+	}
+	return githubTarget(target.Repo)
+}
+
+// reconcileTarget compares a target with the desired secrets and, unless --check is set,
+// writes the drifted secrets and records their fingerprints at recordPath
+func reconcileTarget(out io.Writer, r *reconcile.Result, target config.Target, desired *providers.CollectionResult, recordPath string) error {
+	recorded, err := reconcile.LoadRecorded(recordPath)
+	if err != nil {
+		return err //nolint:wrapcheck // names the file
+	}
+	scopes, err := targetScopes(target)
+	if err != nil {
+		return err
+	}
+
+	set := make(map[string]func(key, value string) error, len(scopes))
+	for _, scope := range scopes {
+		drift, err := reconcile.Compare(desired, recorded, scope.Name, scope.Existing)
+		if err != nil {
+			return err //nolint:wrapcheck // describes the failed fingerprint
+		}
+		r.Drift = append(r.Drift, drift...)
+		set[scope.Name] = scope.Set
+	}
+
+	label := targetLabel(target)
+	if len(r.Drift) == 0 {
+		fmt.Fprintf(out, "%s (%s): in sync, %d secret(s)\n", r.Target, label, r.Secrets)
+		return nil
+	}
+	fmt.Fprintf(out, "%s (%s): %d drifted secret(s) of %d\n", r.Target, label, len(r.Drift), r.Secrets)
+	for _, drift := range r.Drift {
+		fmt.Fprintf(out, "  %s\n", drift)
+	}
+	if reconcileCheck {
+		return nil
+	}
+	// feller sync records the state before writing, so the run can be rolled back
+	if syncRecord != nil {
+		if err := syncRecord.capture(r.Target, target, scopes, r.Drift, desired); err != nil {
+			return err
+		}
+	}
+
+	for _, drift := range r.Drift {
+		err := set[drift.Scope](drift.Key, desired.Secrets[drift.Key])
+		outcome := "written"
+		if err != nil {
+			outcome = "failed"
+		}
+		reconcileMetrics.Add("feller_sync_operations_total", "Secrets written to targets, by outcome.", 1, "target", r.Target, "scope", drift.Scope, "outcome", outcome)
+		if err != nil {
+			return err
+		}
+		r.Fixed++
+	}
+	if err := reconcile.Record(recordPath, desired); err != nil {
+		return err //nolint:wrapcheck // names the file
+	}
+	fmt.Fprintf(out, "%s (%s): fixed %d secret(s)\n", r.Target, label, r.Fixed)
+	return nil
+}
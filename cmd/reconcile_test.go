@@ -0,0 +1,269 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/containifyci/feller/pkg/config"
+	"github.com/containifyci/feller/pkg/fellertest"
+	"github.com/containifyci/feller/pkg/metrics"
+	"github.com/containifyci/feller/pkg/plan"
+	"github.com/containifyci/feller/pkg/reconcile"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeGH installs a gh script that lists the repository secrets in GH_FAKE_SECRETS, prints
+// GH_FAKE_VARIABLE for variables and appends the arguments of every other call to the
+// returned log
+func fakeGH(t *testing.T) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake gh is a shell script")
+	}
+	dir := t.TempDir()
+	script := `#!/bin/sh
+if [ "$2" = "list" ]; then
+  if [ "$3" = "--app" ]; then echo '[]'; else echo "$GH_FAKE_SECRETS"; fi
+  exit 0
+fi
+if [ "$1 $2" = "variable get" ]; then echo "$GH_FAKE_VARIABLE"; fi
+echo "$1 $2 $3 $4 $5" >> "$GH_FAKE_LOG"
+`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "gh"), []byte(script), 0o700)) // #nosec G306 - test executable
+	log := filepath.Join(dir, "calls.log")
+	t.Setenv("PATH", dir)
+	t.Setenv("GH_FAKE_LOG", log)
+	return log
+}
+
+//nolint:paralleltest // modifies environment variables and global flag variables
+func TestReconcile(t *testing.T) {
+	log := fakeGH(t)
+	t.Setenv("GITHUB_ACTIONS", "true")
+	t.Cleanup(func() {
+		cfgFile, reconcileCheck, reconcileTargets, reconcileMetricsFile, planJSON = "", false, nil, "", false
+		reconcileMetrics = metrics.NewRegistry()
+	})
+
+	cfg := fellertest.NewConfig().
+		Provider("local", fellertest.FakeDotenv(t, map[string]string{"API_KEY": "abc", "DB_URL": "db", "LOCAL_ONLY": "x"})).
+		Build()
+	cfg.Targets = map[string]config.Target{"ci": {Kind: config.TargetKindGitHub, Repo: "owner/repo", Keys: []string{"API_KEY", "DB_URL"}}}
+	cfgFile = fellertest.WriteConfig(t, cfg)
+	reconcileMetricsFile = filepath.Join(t.TempDir(), "feller.prom")
+	t.Setenv("GH_FAKE_SECRETS", `[{"name":"API_KEY"},{"name":"OTHER"}]`)
+
+	// Nothing was written by feller yet, so the existing secret cannot be verified
+	reconcileCheck = true
+	var out bytes.Buffer
+	err := runReconcile(&out)
+	require.Error(t, err)
+	assert.Equal(t, "1 target(s) drifted from the config", err.Error())
+	assert.Equal(t, "ci (owner/repo): 2 drifted secret(s) of 2\n  API_KEY (repository): unverified\n  DB_URL (repository): missing\n", out.String())
+	assert.NoFileExists(t, log)
+	metrics, err := os.ReadFile(reconcileMetricsFile)
+	require.NoError(t, err)
+	assert.Contains(t, string(metrics), `feller_reconcile_drifted_secrets{target="ci"} 2`+"\n")
+
+	reconcileCheck = false
+	out.Reset()
+	require.NoError(t, runReconcile(&out))
+	assert.Contains(t, out.String(), "ci (owner/repo): fixed 2 secret(s)\n")
+	calls, err := os.ReadFile(log)
+	require.NoError(t, err)
+	assert.Equal(t, "secret set API_KEY --repo owner/repo\nsecret set DB_URL --repo owner/repo\n", string(calls))
+	metrics, err = os.ReadFile(reconcileMetricsFile)
+	require.NoError(t, err)
+	assert.Contains(t, string(metrics), `feller_sync_operations_total{outcome="written",scope="repository",target="ci"} 2`+"\n")
+	assert.Contains(t, string(metrics), `feller_reconcile_fixed_secrets{target="ci"} 2`+"\n")
+	assert.Contains(t, string(metrics), "feller_secrets 3\n")
+	assert.Contains(t, string(metrics), `feller_provider_cache_hits_total{kind="dotenv",provider="local"} 0`+"\n", "providers without a cache option never hit it")
+
+	// Once written, unchanged secrets are in sync and changed ones drift
+	t.Setenv("GH_FAKE_SECRETS", `[{"name":"API_KEY"},{"name":"DB_URL"}]`)
+	reconcileCheck = true
+	out.Reset()
+	require.NoError(t, runReconcile(&out))
+	assert.Equal(t, "ci (owner/repo): in sync, 2 secret(s)\n", out.String())
+
+	envPath := cfg.Providers["local"].Maps[0].Path
+	require.NoError(t, os.WriteFile(envPath, []byte("API_KEY=rotated\nDB_URL=db\n"), 0o600))
+	out.Reset()
+	require.Error(t, runReconcile(&out))
+	assert.Equal(t, "ci (owner/repo): 1 drifted secret(s) of 2\n  API_KEY (repository): changed\n", out.String())
+
+	planJSON = true
+	out.Reset()
+	require.Error(t, runReconcile(&out))
+	var p plan.Plan
+	require.NoError(t, json.Unmarshal(out.Bytes(), &p))
+	assert.Equal(t, []plan.Change{{Action: plan.ActionUpdate, Target: "github:owner/repo", Scope: "repository", Key: "API_KEY", Reason: reconcile.StatusChanged}}, p.Changes)
+}
+
+//nolint:paralleltest // modifies global flag variables
+func TestReconcileTargetErrors(t *testing.T) {
+	t.Cleanup(func() { cfgFile, reconcileTargets = "", nil })
+	cfg := fellertest.NewConfig().Provider("local", fellertest.FakeDotenv(t, map[string]string{"A": "1"})).Build()
+	cfgFile = fellertest.WriteConfig(t, cfg)
+
+	err := runReconcile(&bytes.Buffer{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "the config has no targets")
+
+	cfg.Targets = map[string]config.Target{"ci": {Kind: config.TargetKindGitHub, Repo: "o/r", Providers: []string{"gsm"}}}
+	cfgFile = fellertest.WriteConfig(t, cfg)
+	reconcileTargets = []string{"prod"}
+	err = runReconcile(&bytes.Buffer{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `unknown target "prod"`)
+
+	reconcileTargets = nil
+	var out bytes.Buffer
+	err = runReconcile(&out)
+	require.Error(t, err)
+	assert.Equal(t, "failed to reconcile 1 target(s)", err.Error())
+	assert.Contains(t, out.String(), `ci (o/r): target ci uses provider "gsm", which is not configured or not selected`)
+}
+
+// fakeCloudflare serves the secrets endpoints of Worker edge of account acc, recording the
+// names of the secrets written
+type fakeCloudflare struct {
+	mu      sync.Mutex
+	names   []string
+	written []string
+}
+
+func (f *fakeCloudflare) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	const path = "/accounts/acc/workers/scripts/edge/secrets"
+	if r.Header.Get("Authorization") != "Bearer cf-token" {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"success":false,"errors":[{"message":"Authentication error"}],"result":null}`))
+		return
+	}
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == path:
+		result := make([]map[string]string, 0, len(f.names))
+		for _, name := range f.names {
+			result = append(result, map[string]string{"name": name, "type": "secret_text"})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"success": true, "errors": []any{}, "result": result})
+	case r.Method == http.MethodPut && r.URL.Path == path:
+		var secret map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&secret)
+		f.written = append(f.written, secret["name"]+"="+secret["text"]+" ("+secret["type"]+")")
+		if !slices.Contains(f.names, secret["name"]) {
+			f.names = append(f.names, secret["name"])
+		}
+		_, _ = w.Write([]byte(`{"success":true,"errors":[],"result":{}}`))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"success":false,"errors":[{"message":"not found"}]}`))
+	}
+}
+
+// fakeFly serves the secret queries and mutations of the GraphQL API for app api, recording
+// the secrets written
+type fakeFly struct {
+	mu      sync.Mutex
+	names   []string
+	written []string
+}
+
+func (f *fakeFly) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if r.Header.Get("Authorization") != "FlyV1 fly-token" {
+		_, _ = w.Write([]byte(`{"data":null,"errors":[{"message":"You must be authenticated to view this."}]}`))
+		return
+	}
+	var request struct {
+		Query     string `json:"query"`
+		Variables struct {
+			App   string `json:"app"`
+			Input struct {
+				AppID   string              `json:"appId"`
+				Secrets []map[string]string `json:"secrets"`
+			} `json:"input"`
+		} `json:"variables"`
+	}
+	_ = json.NewDecoder(r.Body).Decode(&request)
+	switch {
+	case strings.Contains(request.Query, "setSecrets") && request.Variables.Input.AppID == "api":
+		for _, secret := range request.Variables.Input.Secrets {
+			f.written = append(f.written, secret["key"]+"="+secret["value"])
+			f.names = append(f.names, secret["key"])
+		}
+		_, _ = w.Write([]byte(`{"data":{"setSecrets":{"app":{"name":"api"}}}}`))
+	case strings.Contains(request.Query, "app(name:") && request.Variables.App == "api":
+		secrets := make([]map[string]string, 0, len(f.names))
+		for _, name := range f.names {
+			secrets = append(secrets, map[string]string{"name": name})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"app": map[string]any{"secrets": secrets}}})
+	default:
+		_, _ = w.Write([]byte(`{"data":null,"errors":[{"message":"Could not find App"}]}`))
+	}
+}
+
+//nolint:paralleltest // modifies environment variables and global flag variables
+func TestReconcileCloudflareAndFly(t *testing.T) {
+	t.Setenv(cloudflareTokenEnv, "cf-token")
+	t.Setenv(flyTokenEnv, "FlyV1 fly-token")
+	cloudflare, fly := &fakeCloudflare{names: []string{"API_KEY"}}, &fakeFly{}
+	cloudflareServer, flyServer := httptest.NewServer(cloudflare), httptest.NewServer(fly)
+	t.Cleanup(cloudflareServer.Close)
+	t.Cleanup(flyServer.Close)
+	originalCloudflare, originalFly := cloudflareAPI, flyAPI
+	cloudflareAPI, flyAPI = cloudflareServer.URL, flyServer.URL
+	t.Cleanup(func() {
+		cfgFile, reconcileCheck, cloudflareAPI, flyAPI = "", false, originalCloudflare, originalFly
+		reconcileMetrics = metrics.NewRegistry()
+	})
+
+	cfg := fellertest.NewConfig().
+		Provider("local", fellertest.FakeDotenv(t, map[string]string{"API_KEY": "abc", "DB_URL": "db"})).
+		Build()
+	cfg.Targets = map[string]config.Target{
+		"edge": {Kind: config.TargetKindCloudflare, Account: "acc", Script: "edge"},
+		"api":  {Kind: config.TargetKindFly, App: "api", Keys: []string{"DB_URL"}},
+	}
+	cfgFile = fellertest.WriteConfig(t, cfg)
+
+	reconcileCheck = true
+	var out bytes.Buffer
+	require.Error(t, runReconcile(&out))
+	assert.Equal(t, "api (api): 1 drifted secret(s) of 1\n  DB_URL (app): missing\n"+
+		"edge (edge): 2 drifted secret(s) of 2\n  API_KEY (worker): unverified\n  DB_URL (worker): missing\n", out.String())
+	assert.Empty(t, cloudflare.written)
+	assert.Empty(t, fly.written)
+
+	reconcileCheck = false
+	out.Reset()
+	require.NoError(t, runReconcile(&out))
+	assert.Equal(t, []string{"API_KEY=abc (secret_text)", "DB_URL=db (secret_text)"}, cloudflare.written)
+	assert.Equal(t, []string{"DB_URL=db"}, fly.written)
+
+	reconcileCheck = true
+	out.Reset()
+	require.NoError(t, runReconcile(&out))
+	assert.Equal(t, "api (api): in sync, 1 secret(s)\nedge (edge): in sync, 2 secret(s)\n", out.String())
+
+	t.Setenv(cloudflareTokenEnv, "wrong")
+	t.Setenv(flyTokenEnv, "wrong")
+	out.Reset()
+	require.Error(t, runReconcile(&out))
+	assert.Contains(t, out.String(), "api (api): fly.io query failed: You must be authenticated to view this.\n")
+	assert.Contains(t, out.String(), "edge (edge): cloudflare returned status 403: Authentication error\n")
+}
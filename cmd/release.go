@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/containifyci/feller/pkg/release"
+	"github.com/spf13/cobra"
+)
+
+var (
+	releaseVersion   string
+	releaseChecksums string
+	releaseArch      string
+)
+
+// releaseCmd represents the release command group
+var releaseCmd = &cobra.Command{
+	Use:   "release",
+	Short: "Generate release artifacts",
+	Long: `Generate artifacts for distributing feller releases.
+
+Available subcommands:
+  manifest  Print a packaging descriptor for brew, scoop or nfpm
+
+Examples:
+  feller release manifest brew --checksums checksums.txt`,
+}
+
+// releaseManifestCmd represents the release manifest command
+var releaseManifestCmd = &cobra.Command{
+	Use:   "manifest <brew|scoop|nfpm>",
+	Short: "Print a packaging descriptor for brew, scoop or nfpm",
+	Long: `Print a packaging descriptor for a feller release:
+
+  brew   Homebrew formula installing the macOS and Linux binaries
+  scoop  Scoop manifest installing the Windows binary
+  nfpm   nfpm config packaging the Linux binary of --arch as deb or rpm
+
+The version defaults to the version the running binary was built from, so a released
+binary describes itself. Formulas and manifests pin the sha256 of every binary, read
+from the checksums.txt of the release.
+
+Examples:
+  feller release manifest brew --checksums checksums.txt > Formula/feller.rb
+  feller release manifest scoop --version v1.2.3 --checksums checksums.txt > bucket/feller.json
+  feller release manifest nfpm --arch arm64 > nfpm.yaml && nfpm pkg --packager deb`,
+	Args:      cobra.ExactArgs(1),
+	ValidArgs: []string{"brew", "scoop", "nfpm"},
+	RunE: func(_ *cobra.Command, args []string) error {
+		return writeReleaseManifest(os.Stdout, args[0])
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(releaseCmd)
+	releaseCmd.AddCommand(releaseManifestCmd)
+	releaseManifestCmd.Flags().StringVar(&releaseVersion, "version", "", "Release version (default: version of this binary)")
+	releaseManifestCmd.Flags().StringVar(&releaseChecksums, "checksums", "", "checksums.txt of the release (required for brew and scoop)")
+	releaseManifestCmd.Flags().StringVar(&releaseArch, "arch", "amd64", "Architecture of the nfpm package")
+}
+
+// writeReleaseManifest writes the descriptor of format for the selected release
+func writeReleaseManifest(out io.Writer, format string) error {
+	version := releaseVersion
+	if version == "" {
+		version = release.BuildVersion()
+	}
+	if version == "" {
+		return errors.New("cannot determine the version of this build; pass --version")
+	}
+
+	checksums := map[string]string{}
+	if releaseChecksums != "" {
+		// #nosec G304 - Checksums path is provided by the user
+		f, err := os.Open(releaseChecksums)
+		if err != nil {
+			return fmt.Errorf("failed to open checksums: %w", err)
+		}
+		defer f.Close()
+		if checksums, err = release.ParseChecksums(f); err != nil {
+			return fmt.Errorf("invalid checksums %s: %w", releaseChecksums, err)
+		}
+	}
+
+	info, err := release.NewInfo(version, checksums)
+	if err != nil {
+		return err //nolint:wrapcheck // names the invalid version
+	}
+	//nolint:wrapcheck // the writers name the descriptor or the missing checksum
+	switch format {
+	case "brew":
+		return release.WriteBrew(out, info)
+	case "scoop":
+		return release.WriteScoop(out, info)
+	case "nfpm":
+		return release.WriteNFPM(out, info, releaseArch)
+	default:
+		return fmt.Errorf("unknown manifest format %q (expected brew, scoop or nfpm)", format)
+	}
+}
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//nolint:paralleltest // Modifies the global release flags
+func TestWriteReleaseManifest(t *testing.T) {
+	sum := strings.Repeat("f", 64)
+	checksums := filepath.Join(t.TempDir(), "checksums.txt")
+	require.NoError(t, os.WriteFile(checksums, []byte(sum+"  feller-windows-amd64.exe\n"), 0o600))
+	t.Cleanup(func() { releaseVersion, releaseChecksums, releaseArch = "", "", "amd64" })
+
+	tests := []struct {
+		name        string
+		format      string
+		version     string
+		checksums   string
+		contains    string
+		errContains string
+	}{
+		{name: "scoop", format: "scoop", version: "v1.2.3", checksums: checksums, contains: `"hash": "` + sum + `"`},
+		{name: "nfpm without checksums", format: "nfpm", version: "v1.2.3", contains: "version: 1.2.3"},
+		{name: "brew with partial checksums", format: "brew", version: "v1.2.3", checksums: checksums, errContains: "checksum of feller-darwin-amd64 not found"},
+		{name: "missing checksums file", format: "scoop", version: "v1.2.3", checksums: filepath.Join(t.TempDir(), "missing.txt"), errContains: "failed to open checksums"},
+		{name: "unknown format", format: "snap", version: "v1.2.3", errContains: `unknown manifest format "snap"`},
+		// Test binaries are not built from a tagged module, so there is no build version
+		{name: "no version", format: "nfpm", errContains: "pass --version"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			releaseVersion, releaseChecksums, releaseArch = tt.version, tt.checksums, "amd64"
+
+			var buf bytes.Buffer
+			err := writeReleaseManifest(&buf, tt.format)
+			if tt.errContains != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errContains)
+				return
+			}
+			require.NoError(t, err)
+			assert.Contains(t, buf.String(), tt.contains)
+		})
+	}
+}
@@ -1,14 +1,28 @@
 package cmd
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"maps"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
+	"time"
 
+	"github.com/containifyci/feller/pkg/ci"
+	"github.com/containifyci/feller/pkg/config"
+	"github.com/containifyci/feller/pkg/credentials"
+	"github.com/containifyci/feller/pkg/i18n"
+	"github.com/containifyci/feller/pkg/lockfile"
 	"github.com/containifyci/feller/pkg/logger"
+	"github.com/containifyci/feller/pkg/providers"
+	"github.com/containifyci/feller/pkg/teller"
 	"github.com/spf13/cobra"
 )
 
@@ -17,6 +31,30 @@ var (
 	verbose bool
 	debug   bool
 	silent  bool
+
+	// Report missing variables as a warning, a middle ground between failing and --silent
+	missingAsWarning bool
+
+	includeProviders []string
+	excludeProviders []string
+	noProxyProviders []string
+	selectedTags     []string // --tags, the secret sets to resolve
+
+	collisionStrategy string // --collision-strategy, replacing collisions of the config
+
+	forceLocal   bool
+	forceActions bool
+	noFallback   bool
+
+	tellerVersion string
+)
+
+// Execution modes, selected with --force-local, --force-actions or FELLER_MODE
+const (
+	modeEnv     = "FELLER_MODE"
+	modeAuto    = "auto"
+	modeLocal   = "local"
+	modeActions = "actions"
 )
 
 // rootCmd represents the base command when called without any subcommands
@@ -25,43 +63,268 @@ var rootCmd = &cobra.Command{
 	Short: "A GitHub Actions optimized secret management tool",
 	Long: `Feller is a lightweight secret management tool optimized for GitHub Actions.
 It can parse Teller configuration files and handle secrets in GitHub Actions
-environments, with fallback to the original Teller binary when not in GitHub Actions.`,
-	PersistentPreRun: func(_ *cobra.Command, _ []string) {
+environments, with fallback to the original Teller binary when not in GitHub Actions.
+GitLab CI, CircleCI, Buildkite and Jenkins are detected as well.
+
+Aliases defined in the config's aliases section run as commands of their own.`,
+	PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
 		// Initialize logging based on flags
 		logger.SetDebug(debug)
 		logger.SetVerbose(verbose)
 
+		locale, err := i18n.Detect()
+		if err != nil {
+			logger.Error("WARNING: %v, using English", err)
+		}
+		_ = i18n.SetLocale(locale) // Detect only returns supported locales
+
+		if err := enterWorkspace(cmd); err != nil {
+			return err
+		}
+
+		mode, err := executionMode()
+		if err != nil {
+			return err
+		}
+		if noFallback && mode == modeLocal {
+			return fmt.Errorf("--no-fallback cannot be used with %s=%s", modeEnv, modeLocal)
+		}
+
 		logger.Debug("Debug logging enabled")
-		logger.Debug("GitHub Actions environment: %v", isGitHubActions())
+		logger.Debug("Execution mode: %s", mode)
+		platform, inCI := currentPlatform()
+		logger.Debug("CI environment: %v %s", inCI, platform.Name)
 		logger.Debug("Config file: %s", cfgFile)
 		logger.Debug("Silent mode: %v", silent)
+
+		profile, err := startProfiling()
+		if err != nil {
+			return err
+		}
+		activeProfile = profile
+		return nil
 	},
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() error {
-	if err := rootCmd.Execute(); err != nil {
+	args, err := expandAlias(os.Args[1:])
+	if err != nil {
+		logger.Error("%v", err)
+		return fmt.Errorf("failed to expand alias: %w", err)
+	}
+	rootCmd.SetArgs(args)
+
+	start := time.Now()
+	cmd, err := rootCmd.ExecuteC()
+	stopProfiling()
+	recordTelemetry(cmd, start, err == nil)
+	if err != nil {
 		return fmt.Errorf("failed to execute command: %w", err)
 	}
 	return nil
 }
 
 func init() {
-	rootCmd.PersistentFlags().StringVarP(&cfgFile, "config", "c", "", "Path to your teller.yml config")
+	// Set here rather than in the literal, since the function refers back to rootCmd
+	rootCmd.ValidArgsFunction = completeAliases
+
+	rootCmd.PersistentFlags().StringVarP(&cfgFile, "config", "c", "", "Path to your teller.yml config, or the directory holding .teller.yml")
+	rootCmd.PersistentFlags().StringVar(&workspaceName, "workspace", "", "Use the config of this workspace (see 'feller workspace')")
+	_ = rootCmd.RegisterFlagCompletionFunc("workspace", completeWorkspaces)
 	rootCmd.PersistentFlags().BoolVar(&verbose, "verbose", false, "Enable verbose output")
 	rootCmd.PersistentFlags().BoolVar(&debug, "debug", false, "Enable debug logging")
 	rootCmd.PersistentFlags().BoolVar(&silent, "silent", false, "Suppress missing environment variable errors (not recommended)")
+	rootCmd.PersistentFlags().BoolVar(&missingAsWarning, "missing-as-warning", false, "Report missing environment variables as a warning and continue with the available secrets")
+	rootCmd.PersistentFlags().StringSliceVar(&includeProviders, "providers", nil, "Only resolve these providers (comma-separated names)")
+	rootCmd.PersistentFlags().StringSliceVar(&excludeProviders, "exclude-providers", nil, "Do not resolve these providers (comma-separated names)")
+	_ = rootCmd.RegisterFlagCompletionFunc("providers", completeProviderList)
+	_ = rootCmd.RegisterFlagCompletionFunc("exclude-providers", completeProviderList)
+	rootCmd.PersistentFlags().StringSliceVar(&selectedTags, "tags", nil, "Only resolve the maps and keys with one of these tags (comma-separated)")
+	_ = rootCmd.RegisterFlagCompletionFunc("tags", completeTagList)
+	rootCmd.PersistentFlags().StringVar(&collisionStrategy, "collision-strategy", "", "Resolve keys supplied by several providers: "+strings.Join(providers.CollisionStrategies, ", ")+" (default last-wins, or collisions of the config)")
+	_ = rootCmd.RegisterFlagCompletionFunc("collision-strategy", cobra.FixedCompletions(providers.CollisionStrategies, cobra.ShellCompDirectiveNoFileComp))
+	rootCmd.PersistentFlags().StringSliceVar(&noProxyProviders, "no-proxy-providers", nil, "Reach these providers without the proxy (comma-separated names)")
+	_ = rootCmd.RegisterFlagCompletionFunc("no-proxy-providers", completeProviderList)
+	rootCmd.PersistentFlags().BoolVar(&forceLocal, "force-local", false, "Fall back to teller even on CI")
+	rootCmd.PersistentFlags().BoolVar(&forceActions, "force-actions", false, "Use the CI code path outside CI, as in GitHub Actions")
+	rootCmd.MarkFlagsMutuallyExclusive("force-local", "force-actions")
+	rootCmd.PersistentFlags().BoolVar(&noFallback, "no-fallback", false, "Resolve secrets with feller's own providers outside CI too, never running teller")
+	rootCmd.MarkFlagsMutuallyExclusive("force-local", "no-fallback")
+	rootCmd.PersistentFlags().BoolVar(&locked, "locked", false, "Fail when resolved secrets differ from "+lockfile.FileName)
+	rootCmd.PersistentFlags().BoolVar(&strictPermissions, "strict", false, "Fail instead of warning when secret files are readable by group or others")
+	rootCmd.PersistentFlags().BoolVar(&plainOutput, "plain", false, "Plain output for screen readers and grep: no aligned tables, bullets or Markdown (also FELLER_PLAIN=1)")
+	rootCmd.PersistentFlags().BoolVar(&showTimings, "timings", false, "Print the requests, rate limit delays and time of each provider to stderr")
+	rootCmd.PersistentFlags().StringVar(&tellerVersion, "teller-version", "", "Download and cache this teller release when teller is not in PATH (e.g. v2.0.7)")
+
+	// Profiling flags are for performance investigations and are not shown in help
+	rootCmd.PersistentFlags().StringVar(&pprofAddr, "pprof", "", "Serve pprof endpoints on this address while the command runs")
+	rootCmd.PersistentFlags().Lookup("pprof").NoOptDefVal = defaultPprofAddr
+	rootCmd.PersistentFlags().StringVar(&cpuProfile, "profile", "", "Write a CPU profile to this file")
+	rootCmd.PersistentFlags().StringVar(&memProfile, "memprofile", "", "Write a memory allocation profile to this file on exit")
+	for _, name := range []string{"pprof", "profile", "memprofile"} {
+		_ = rootCmd.PersistentFlags().MarkHidden(name)
+	}
 }
 
-// isGitHubActions checks if we're running in a GitHub Actions environment
-func isGitHubActions() bool {
-	return os.Getenv("GITHUB_ACTIONS") == "true"
+// loadConfig loads the teller config and applies the --providers, --exclude-providers and
+// --tags selection, --collision-strategy and whether feller runs on CI
+func loadConfig() (*config.TellerConfig, error) {
+	cfg, err := config.LoadConfig(cfgFile)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // callers add context
+	}
+	for _, field := range cfg.Deprecated() {
+		logger.Error("WARNING: line %d: %s", field.Node.Line, field)
+	}
+	if err := cfg.SelectProviders(includeProviders, excludeProviders); err != nil {
+		return nil, fmt.Errorf("invalid provider selection: %w", err)
+	}
+	if err := cfg.SelectTags(selectedTags); err != nil {
+		return nil, fmt.Errorf("invalid tag selection: %w", err)
+	}
+	if collisionStrategy != "" {
+		if err := providers.CheckCollisionStrategy(collisionStrategy); err != nil {
+			return nil, fmt.Errorf("invalid --collision-strategy: %w", err)
+		}
+		cfg.Collisions = collisionStrategy
+	}
+	cfg.OnCI = isCI()
+	setTelemetryKinds(cfg)
+	return cfg, nil
+}
+
+// isCI checks if we're running on a supported CI system such as GitHub Actions, or were told
+// to behave as if
+func isCI() bool {
+	_, ok := currentPlatform()
+	return ok
+}
+
+// resolveNatively reports whether feller resolves secrets with its own providers, which it
+// does on CI and, with --no-fallback or fallback: false in the config, everywhere else
+func resolveNatively() (bool, error) {
+	if isCI() {
+		return true, nil
+	}
+	if mode, _ := executionMode(); mode == modeLocal {
+		return false, nil
+	}
+
+	cfg, err := loadConfig()
+	if !noFallback && (err != nil || !cfg.FallbackDisabled()) {
+		// Config errors are left to teller, as before
+		return false, nil
+	}
+	if err != nil {
+		// Reported when the command loads the config
+		return true, nil
+	}
+	logger.Debug("Teller fallback disabled, resolving secrets natively")
+	return true, checkNativeKinds(cfg)
 }
 
-// fallbackToTeller executes the original teller binary with the same arguments
-func fallbackToTeller(args []string) error {
-	logger.Verbose("Not in GitHub Actions environment, falling back to teller")
+// checkNativeKinds fails for providers whose kind only teller can resolve
+func checkNativeKinds(cfg *config.TellerConfig) error {
+	names := make([]string, 0, len(cfg.Providers))
+	for name := range cfg.Providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		kind := cfg.Providers[name].Kind
+		if _, ok := providers.LookupKind(kind); !ok {
+			return fmt.Errorf("provider %q has kind %q, which feller cannot resolve without teller (supported: %s); remove it, exclude it with --exclude-providers or allow the teller fallback",
+				name, kind, strings.Join(nativeKindNames(), ", "))
+		}
+	}
+	return nil
+}
+
+// collectSecrets resolves secrets with feller's own providers, reporting --timings, checking
+// the dotenv files read and checking the lockfile in --locked mode
+func collectSecrets(cfg *config.TellerConfig) (*providers.CollectionResult, error) {
+	result, err := providers.CollectSecretsWithResult(cfg, silent)
+	if err != nil {
+		logger.Debug("Failed to collect secrets: %v", err)
+		return nil, fmt.Errorf("failed to collect secrets: %w", err)
+	}
+	reportTimings(os.Stderr, result.Timings)
+	if err := checkDotenvFiles(cfg); err != nil {
+		return nil, err
+	}
+	if locked {
+		if err := checkLockfile(result); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// nativeKindNames returns the names of the provider kinds feller resolves itself
+func nativeKindNames() []string {
+	kinds := providers.Kinds()
+	names := make([]string, len(kinds))
+	for i, kind := range kinds {
+		names[i] = kind.Kind
+	}
+	return names
+}
+
+// currentPlatform returns the CI platform whose pipeline maps secrets to environment
+// variables. Forcing the actions mode outside CI behaves like GitHub Actions.
+func currentPlatform() (ci.Platform, bool) {
+	mode, err := executionMode()
+	if err != nil || mode == modeAuto {
+		return ci.Detect(os.Getenv)
+	}
+	if mode == modeLocal {
+		return ci.Platform{}, false
+	}
+	if platform, ok := ci.Detect(os.Getenv); ok {
+		return platform, true
+	}
+	return ci.Lookup(ci.GitHubActions)
+}
+
+// executionMode returns the mode forced by flags or FELLER_MODE, or auto to detect it
+func executionMode() (string, error) {
+	switch {
+	case forceLocal:
+		return modeLocal, nil
+	case forceActions:
+		return modeActions, nil
+	}
+
+	switch mode := os.Getenv(modeEnv); mode {
+	case "", modeAuto:
+		return modeAuto, nil
+	case modeLocal, modeActions:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("invalid %s %q (expected %s, %s or %s)", modeEnv, mode, modeAuto, modeLocal, modeActions)
+	}
+}
+
+// fallbackToTeller executes the original teller binary with the given arguments, writing its
+// output to the file out when set
+func fallbackToTeller(args []string, out string) error {
+	logger.Verbose("Not in a CI environment, falling back to teller")
+
+	if len(includeProviders) > 0 || len(excludeProviders) > 0 {
+		return errors.New("--providers and --exclude-providers are not supported by teller fallback mode")
+	}
+	if len(selectedTags) > 0 {
+		return errors.New("--tags needs feller to resolve secrets itself; add --no-fallback")
+	}
+	if locked {
+		return errors.New("--locked needs feller to resolve secrets itself; add --no-fallback")
+	}
+	if collisionStrategy != "" {
+		return errors.New("--collision-strategy needs feller to resolve secrets itself; add --no-fallback")
+	}
 	logger.Debug("Building teller command arguments")
 
 	// Build the full argument list
@@ -90,12 +353,25 @@ func fallbackToTeller(args []string) error {
 	}
 
 	logger.Debug("Found teller binary at: %s", tellerPath)
+	if len(args) > 0 {
+		if err := checkTellerSubcommand(tellerPath, args[0]); err != nil {
+			return err
+		}
+	}
 
-	// Execute teller with syscall.Exec for complete replacement
-	return execTeller(tellerPath, tellerArgs)
+	if out == "" {
+		return execTeller(tellerPath, tellerArgs, os.Stdout)
+	}
+	// Teller exits the process when it fails, so only complete output replaces the file
+	var buf bytes.Buffer
+	if err := execTeller(tellerPath, tellerArgs, &buf); err != nil {
+		return err
+	}
+	return writeOutput(os.Stdout, out, buf.Bytes())
 }
 
-// findTellerBinary locates the teller binary in the system PATH
+// findTellerBinary locates the teller binary in the system PATH, or installs the release
+// pinned with --teller-version into the user cache
 func findTellerBinary() (string, error) {
 	// Look for common teller binary names
 	candidates := []string{"teller", "teller-original"}
@@ -112,30 +388,51 @@ func findTellerBinary() (string, error) {
 	}
 
 	logger.Debug("No teller binary found in PATH")
-	return "", errors.New("teller binary not found in PATH")
+	if tellerVersion == "" {
+		return "", errors.New("teller binary not found in PATH (use --teller-version to download a release)")
+	}
+
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate cache directory for teller: %w", err)
+	}
+	path, err := teller.Install(context.Background(), filepath.Join(cacheDir, "feller", "teller"), tellerVersion)
+	if err != nil {
+		return "", fmt.Errorf("failed to install teller %s: %w", tellerVersion, err)
+	}
+	return path, nil
 }
 
-// execTeller executes the teller binary, replacing the current process
-func execTeller(tellerPath string, args []string) error {
+// execTeller executes the teller binary, exiting with its exit code when it fails
+func execTeller(tellerPath string, args []string, stdout io.Writer) error {
 	logger.Debug("Setting up teller execution")
 	logger.Debug("Binary path: %s", tellerPath)
 	logger.Debug("Arguments: %v", args)
 
+	env, cleanup, err := tellerEnv()
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
 	// Use exec.CommandContext for compatibility and proper error handling
 	cmd := exec.CommandContext(context.Background(), tellerPath, args...)
-	cmd.Stdout = os.Stdout
+	cmd.Env = env
+	cmd.Stdout = stdout
 	cmd.Stderr = os.Stderr
 	cmd.Stdin = os.Stdin
 
 	logger.Verbose("Executing: %s %s", tellerPath, strings.Join(args, " "))
 	logger.Debug("Starting teller execution...")
 
-	err := cmd.Run()
+	err = cmd.Run()
 	if err != nil {
 		logger.Debug("Teller execution failed: %v", err)
 		exitError := &exec.ExitError{}
 		if errors.As(err, &exitError) {
 			logger.Debug("Teller exited with code: %d", exitError.ExitCode())
+			cleanup()
+			stopProfiling()
 			os.Exit(exitError.ExitCode())
 		}
 		return fmt.Errorf("teller execution failed: %w", err)
@@ -144,3 +441,55 @@ func execTeller(tellerPath string, args []string) error {
 	logger.Debug("Teller execution completed successfully")
 	return nil
 }
+
+// tellerEnv returns the environment of the teller process with the short-lived credentials
+// requested by provider options, and a function removing them once teller has exited
+func tellerEnv() ([]string, func(), error) {
+	cfg, err := config.LoadConfig(cfgFile)
+	if err != nil {
+		// Teller reports config problems itself
+		logger.Debug("Not preparing credentials: %v", err)
+		return nil, func() {}, nil
+	}
+	// Teller ignores the version fields, so a pin would silently read the latest version
+	if pinned := providers.PinnedMaps(cfg); len(pinned) > 0 {
+		return nil, nil, fmt.Errorf("cannot resolve %s: teller always reads the latest version", strings.Join(pinned, ", "))
+	}
+	if err := checkPermissions(providers.DotenvFiles(cfg)); err != nil {
+		return nil, nil, err
+	}
+	plan, err := credentials.PlanFor(cfg)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid credential options: %w", err)
+	}
+	if err := plan.BypassProxy(cfg, noProxyProviders, os.Environ()); err != nil {
+		return nil, nil, err
+	}
+	if plan.Empty() {
+		return nil, func() {}, nil
+	}
+
+	env, cleanup, err := credentials.Prepare(plan, os.Environ())
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to prepare credentials: %w", err)
+	}
+	if plan.GCP != nil {
+		logger.Verbose("Teller impersonates service account %s", plan.GCP.ImpersonateServiceAccount)
+	}
+	if plan.AWS != nil {
+		logger.Verbose("Teller assumes roles %s", strings.Join(plan.AWS.AssumeRoles, " -> "))
+	}
+	if vault := plan.TLS["hashicorp_vault"]; vault != nil && vault.InsecureSkipVerify {
+		logger.Error("WARNING: insecure_skip_verify is set, so TLS certificates of Vault are not verified and its traffic can be intercepted. Use ca_cert instead.")
+	}
+	if len(plan.NoProxy) > 0 {
+		logger.Verbose("Teller reaches %s without the proxy", strings.Join(plan.NoProxy, ", "))
+	}
+	for _, variable := range slices.Sorted(maps.Keys(plan.Endpoints)) {
+		logger.Verbose("Teller reaches %s through %s", plan.Endpoints[variable], variable)
+	}
+	if plan.Vault != nil {
+		logger.Verbose("Teller uses a Vault token of role %s, obtained with a SPIFFE identity", plan.Vault.VaultAuthRole)
+	}
+	return env, cleanup, nil
+}
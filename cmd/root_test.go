@@ -2,8 +2,13 @@ package cmd
 
 import (
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/containifyci/feller/pkg/fellertest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 //nolint:paralleltest // Cannot run in parallel due to global rootCmd manipulation
@@ -88,7 +93,7 @@ func TestExecTeller(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
-			err := execTeller(tt.tellerPath, tt.args)
+			err := execTeller(tt.tellerPath, tt.args, os.Stdout)
 
 			if tt.wantErr {
 				if err == nil {
@@ -158,7 +163,7 @@ func TestFallbackToTellerEdgeCases(t *testing.T) {
 				tt.setupPath()
 			}
 
-			err := fallbackToTeller(tt.args)
+			err := fallbackToTeller(tt.args, "")
 
 			if tt.wantErr {
 				if err == nil {
@@ -236,3 +241,106 @@ func TestFindTellerBinaryEdgeCases(t *testing.T) {
 		})
 	}
 }
+
+//nolint:paralleltest // modifies global flag variables
+func TestLoadConfigProviderSelection(t *testing.T) {
+	originalCfgFile := cfgFile
+	originalInclude := includeProviders
+	originalExclude := excludeProviders
+	t.Cleanup(func() {
+		cfgFile = originalCfgFile
+		includeProviders = originalInclude
+		excludeProviders = originalExclude
+	})
+
+	cfgFile = filepath.Join(t.TempDir(), ".teller.yml")
+	content := `providers:
+  gha:
+    kind: google_secretmanager
+  local:
+    kind: dotenv
+`
+	if err := os.WriteFile(cfgFile, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	includeProviders = []string{"local"}
+	excludeProviders = nil
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatalf("loadConfig() unexpected error = %v", err)
+	}
+	if _, exists := cfg.Providers["gha"]; exists || len(cfg.Providers) != 1 {
+		t.Errorf("loadConfig() providers = %v, want only local", cfg.Providers)
+	}
+
+	includeProviders = []string{"missing"}
+	if _, err := loadConfig(); err == nil || !strings.Contains(err.Error(), "invalid provider selection") {
+		t.Errorf("loadConfig() error = %v, want invalid provider selection", err)
+	}
+
+	excludeProviders = []string{"gha"}
+	includeProviders = nil
+	if err := fallbackToTeller([]string{"export", "json"}, ""); err == nil || !strings.Contains(err.Error(), "not supported by teller fallback mode") {
+		t.Errorf("fallbackToTeller() error = %v, want provider selection rejection", err)
+	}
+}
+
+//nolint:paralleltest // modifies global flag variables
+func TestLoadConfigTagSelection(t *testing.T) {
+	originalCfgFile, originalTags := cfgFile, selectedTags
+	t.Cleanup(func() { cfgFile, selectedTags = originalCfgFile, originalTags })
+
+	gsm := fellertest.FakeGSM(t, map[string]string{"API_KEY": "a", "E2E_TOKEN": "e"})
+	gsm.Maps[0].Tags = []string{"backend"}
+	gsm.Maps[0].KeyTags = map[string][]string{"E2E_TOKEN": {"e2e"}}
+	cfgFile = fellertest.WriteConfig(t, fellertest.NewConfig().Provider("gsm", gsm).Build())
+
+	selectedTags = []string{"e2e"}
+	cfg, err := loadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"E2E_TOKEN": "E2E_TOKEN"}, cfg.Providers["gsm"].Maps[0].Keys)
+
+	names, _ := completeTagList(rootCmd, nil, "")
+	assert.Equal(t, []string{"backend", "e2e"}, names)
+
+	selectedTags = []string{"frontend"}
+	_, err = loadConfig()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `invalid tag selection: unknown tag "frontend" (available: backend, e2e)`)
+
+	selectedTags = []string{"e2e"}
+	err = fallbackToTeller([]string{"export", "json"}, "")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "--tags needs feller to resolve secrets itself")
+}
+
+//nolint:paralleltest // modifies global flag variables and environment variables
+func TestLoadConfigOnCI(t *testing.T) {
+	originalCfgFile := cfgFile
+	t.Cleanup(func() { cfgFile = originalCfgFile })
+	cfgFile = fellertest.WriteConfig(t, fellertest.NewConfig().Provider("gsm", fellertest.FakeGSM(t, map[string]string{"API_KEY": "a"})).Build())
+
+	// Google Secret Manager maps keep to the environment the workflow passes through on CI
+	t.Setenv(modeEnv, modeActions)
+	cfg, err := loadConfig()
+	require.NoError(t, err)
+	assert.True(t, cfg.OnCI)
+
+	t.Setenv(modeEnv, modeLocal)
+	cfg, err = loadConfig()
+	require.NoError(t, err)
+	assert.False(t, cfg.OnCI)
+}
+
+//nolint:paralleltest // Modifies the global config path
+func TestTellerEnvRefusesPinnedVersions(t *testing.T) {
+	t.Cleanup(func() { cfgFile = "" })
+	gsm := fellertest.MissingGSM(t, "CERT")
+	gsm.Maps[0].Version = "3"
+	cfgFile = fellertest.WriteConfig(t, fellertest.NewConfig().Provider("gsm", gsm).Build())
+
+	_, _, err := tellerEnv()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "(version 3): teller always reads the latest version")
+}
@@ -16,8 +16,15 @@ import (
 )
 
 var (
-	resetEnv bool
-	shell    bool
+	resetEnv   bool
+	shell      bool
+	preHooks   []string
+	postHooks  []string
+	detach     bool
+	detachName string
+	parallel   bool
+	procfile   string
+	argFile    string
 )
 
 // runCmd represents the run command
@@ -29,11 +36,57 @@ var runCmd = &cobra.Command{
 The command will be executed with all secrets from the configured providers
 injected into the environment.
 
+Hooks configured under 'hooks.pre_run' and 'hooks.post_run' in the config,
+or passed with --pre and --post, run through the shell with the same
+environment as the command. Post-run hooks run even if the command fails.
+
+The command must follow a "--" separator. Everything after it is passed to
+the command untouched, so its flags are never interpreted by feller. Very
+long commands can be read from a file with --argfile (one argument per line).
+
+In shell mode a single argument is run as a shell script. When several
+arguments are given, each one is quoted so it reaches the command unchanged.
+
+With --detach the command is started in the background: its PID is written
+to .feller/run/<name>.pid and its output to .feller/run/<name>.log, next to
+the config. Use 'feller ps' and 'feller stop' to manage detached processes
+from any directory using that config.
+
+With --env-fd N the secrets are not added to the environment, where other
+processes of the user can read them from /proc/PID/environ. The command reads
+them from file descriptor N instead, an in-memory file on Linux and a pipe
+elsewhere, as KEY=value entries each terminated by a NUL byte; FELLER_ENV_FD
+holds N. Hooks run without the secrets. Not supported on Windows.
+
+--deny-env removes inherited variables whose names match a glob, e.g. 'AWS_*'
+for the credentials of the calling shell, before the command starts; secrets
+are never removed. --assert-env checks that the listed keys reach the command
+with a value and confirms each with its masked value on stderr.
+
+Arguments may reference secrets as {{secret.KEY}}, e.g. for tools that only
+accept credentials as flags. The placeholders are replaced just before the
+command starts and logs show them unexpanded. Note that other processes of
+the user can read command arguments from /proc/PID/cmdline. Placeholders are
+not supported in shell scripts, with --detach or with --parallel; use $KEY.
+
+With --parallel every group of arguments separated by "--" is run as a
+separate shell command; --procfile reads "name: command" entries instead.
+All processes share the same environment, their output is prefixed with
+the process name, and the run fails if any process fails.
+
 Examples:
   feller run -- node app.js
   feller run --reset -- ./deploy.sh
-  feller run --shell -- "echo $DATABASE_URL | head -c 10"`,
-	Args: cobra.MinimumNArgs(1),
+  feller run --shell -- 'echo $DATABASE_URL | head -c 10'
+  feller run --pre "./fetch-kubeconfig.sh" --post "rm -f kubeconfig" -- kubectl get pods
+  feller run --detach --name api -- ./api-server
+  feller run --parallel -- "npm run api" -- "npm run worker"
+  feller run --procfile Procfile
+  feller run --argfile deploy.args
+  feller run -- curl -H "Authorization: Bearer {{secret.API_TOKEN}}" https://api.example.com
+  feller run --env-fd 3 -- ./server
+  feller run --deny-env 'AWS_*' --assert-env DATABASE_URL,API_KEY -- ./deploy.sh`,
+	Args: validateRunArgs,
 	RunE: runCommand,
 }
 
@@ -41,56 +94,107 @@ func init() {
 	rootCmd.AddCommand(runCmd)
 	runCmd.Flags().BoolVarP(&resetEnv, "reset", "r", false, "Reset environment variables before running")
 	runCmd.Flags().BoolVarP(&shell, "shell", "s", false, "Run command as shell command")
+	runCmd.Flags().StringArrayVar(&preHooks, "pre", nil, "Shell command to run before the command (repeatable)")
+	runCmd.Flags().StringArrayVar(&postHooks, "post", nil, "Shell command to run after the command (repeatable)")
+	runCmd.Flags().BoolVarP(&detach, "detach", "d", false, "Run the command in the background and return immediately")
+	runCmd.Flags().StringVar(&detachName, "name", "", "Name of the detached process (defaults to the command name)")
+	runCmd.Flags().BoolVar(&parallel, "parallel", false, "Run each \"--\" separated command concurrently")
+	runCmd.Flags().StringVar(&procfile, "procfile", "", "Run all processes defined in a Procfile concurrently")
+	runCmd.Flags().BoolVar(&printSummary, "summary", false, "Print the number of keys and a checksum of their names to stderr")
+	runCmd.Flags().IntVar(&envFD, "env-fd", 0, "Pass the secrets on this file descriptor (3 or higher) instead of the environment")
+	runCmd.Flags().StringSliceVar(&assertEnv, "assert-env", nil, "Fail unless these keys reach the command with a value, confirming each masked on stderr (comma-separated)")
+	runCmd.Flags().StringSliceVar(&denyEnv, "deny-env", nil, "Remove inherited variables matching these globs, e.g. 'AWS_*' (comma-separated)")
+	runCmd.Flags().StringVar(&argFile, "argfile", "", "Read command arguments from a file (one per line)")
+	// Stop flag parsing at the first positional argument so child flags are never consumed
+	runCmd.Flags().SetInterspersed(false)
 }
 
-func runCommand(_ *cobra.Command, args []string) error {
+func runCommand(cmd *cobra.Command, args []string) error {
 	logger.Debug("Starting run command with args: %v", args)
 	logger.Debug("Run flags: resetEnv=%v, shell=%v", resetEnv, shell)
 
-	// Check if we're in GitHub Actions
-	if !isGitHubActions() {
-		logger.Debug("Not in GitHub Actions, preparing fallback to teller")
+	if argFile != "" {
+		fileArgs, err := readArgFile(argFile)
+		if err != nil {
+			return err
+		}
+		args = append(fileArgs, args...)
+		logger.Debug("Command args after reading argfile: %d", len(args))
+	}
 
-		// Build the run command with proper flags and separator
-		runArgs := []string{"run"}
+	// Check if we resolve secrets natively, as on CI
+	native, err := resolveNatively()
+	if err != nil {
+		return err
+	}
+	if !native {
+		logger.Debug("Not in CI, preparing fallback to teller")
 
-		// Add run-specific flags
-		if resetEnv {
-			runArgs = append(runArgs, "--reset")
-			logger.Debug("Added --reset flag to teller command")
+		if detach || parallel || procfile != "" {
+			return errors.New("--detach, --parallel and --procfile are not supported by teller fallback mode")
+		}
+		if printSummary {
+			return errors.New("--summary is not supported by teller fallback mode")
 		}
-		if shell {
-			runArgs = append(runArgs, "--shell")
-			logger.Debug("Added --shell flag to teller command")
+		if envFD != 0 || len(assertEnv) > 0 || len(denyEnv) > 0 {
+			return errors.New("--env-fd, --assert-env and --deny-env are not supported by teller fallback mode")
+		}
+		if hasSecretRefs(args) {
+			return errors.New("{{secret.KEY}} placeholders in the command need feller to resolve secrets itself; add --no-fallback")
+		}
+		// Hooks get the secrets feller resolves, which teller keeps to its own child process
+		if len(preHooks) > 0 || len(postHooks) > 0 {
+			return errors.New("--pre and --post need feller to resolve secrets itself; add --no-fallback")
+		}
+		if cfg, err := config.LoadConfig(cfgFile); err == nil && (len(cfg.Hooks.PreRun) > 0 || len(cfg.Hooks.PostRun) > 0) {
+			return errors.New("the hooks of the config need feller to resolve secrets itself; add --no-fallback")
 		}
 
-		// Add the separator and command args
+		// Add the separator and command args after the translated run flags
+		runArgs := tellerCommandArgs(cmd, "run")
 		runArgs = append(runArgs, "--")
 		runArgs = append(runArgs, args...)
 
 		logger.Debug("Teller fallback args: %v", runArgs)
-		return fallbackToTeller(runArgs)
+		return fallbackToTeller(runArgs, "")
+	}
+
+	logger.Debug("In CI mode, processing secrets")
+
+	if err := checkEnvFD(); err != nil {
+		return err
+	}
+	if err := checkDenyEnv(); err != nil {
+		return err
 	}
 
-	logger.Debug("In GitHub Actions mode, processing secrets")
+	specs, err := resolveParallelSpecs(args)
+	if err != nil {
+		return err
+	}
+	if err := checkSecretRefs(args, specs); err != nil {
+		return err
+	}
 
 	// Load configuration
-	cfg, err := config.LoadConfig(cfgFile)
+	cfg, err := loadConfig()
 	if err != nil {
 		logger.Debug("Failed to load config: %v", err)
 		return fmt.Errorf("failed to load config: %w", err)
 	}
 
 	// Collect all secrets and check for missing variables
-	result, err := providers.CollectSecretsWithResult(cfg, silent)
+	result, err := collectSecrets(cfg)
 	if err != nil {
-		logger.Debug("Failed to collect secrets: %v", err)
-		return fmt.Errorf("failed to collect secrets: %w", err)
+		return err
 	}
 
 	// Handle missing environment variables
-	if result.HasMissingVars && !silent {
-		return handleMissingVariables(result.MissingVars)
+	if err := checkMissing(result, missingRun, cfg.Messages); err != nil {
+		return err
+	}
+	if err := checkSecretRefKeys(args, result.Secrets); err != nil {
+		return err
 	}
 
 	logger.Verbose("Collected %d secrets", len(result.Secrets))
@@ -99,11 +203,17 @@ func runCommand(_ *cobra.Command, args []string) error {
 		logger.Debug("Missing %d environment variables (silent mode: %v)", len(result.MissingVars), silent)
 	}
 
+	if printSummary {
+		if err := reportSummary(os.Stderr, result.Secrets); err != nil {
+			return err
+		}
+	}
+
 	// Prepare environment with pre-allocation
 	var env []string
 	if !resetEnv {
 		// Start with current environment - pre-allocate for current env + secrets
-		currentEnv := os.Environ()
+		currentEnv := denyInherited(os.Environ())
 		logger.Debug("Starting with current environment (%d vars)", len(currentEnv))
 		env = make([]string, 0, len(currentEnv)+len(result.Secrets))
 		env = append(env, currentEnv...)
@@ -113,80 +223,157 @@ func runCommand(_ *cobra.Command, args []string) error {
 		env = make([]string, 0, len(result.Secrets))
 	}
 
-	// Add secrets to environment
-	logger.Debug("Adding %d secrets to environment", len(result.Secrets))
-	for key, value := range result.Secrets {
-		envVar := fmt.Sprintf("%s=%s", key, value)
-		env = append(env, envVar)
-		logger.Debug("Added env var: %s=%s", key, maskSecret(value))
+	// Add secrets to environment, or only the descriptor the command reads them from
+	if envFD != 0 {
+		logger.Debug("Passing %d secrets on file descriptor %d", len(result.Secrets), envFD)
+		env = append(env, fmt.Sprintf("%s=%d", envFDEnv, envFD))
+	} else {
+		logger.Debug("Adding %d secrets to environment", len(result.Secrets))
+		for key, value := range result.Secrets {
+			envVar := fmt.Sprintf("%s=%s", key, value)
+			env = append(env, envVar)
+			logger.Debug("Added env var: %s=%s", key, maskSecret(value))
+		}
 	}
 
 	logger.Debug("Final environment has %d variables", len(env))
 
+	if err := assertEnvSet(os.Stderr, env, result.Secrets); err != nil {
+		return err
+	}
+
+	pre := append(append([]string{}, cfg.Hooks.PreRun...), preHooks...)
+	post := append(append([]string{}, cfg.Hooks.PostRun...), postHooks...)
+
+	if err := runHooks("pre-run", pre, env); err != nil {
+		return err
+	}
+
+	if specs != nil {
+		return runPostHooks(post, env, executeParallel(specs, env))
+	}
+
+	if detach {
+		if len(post) > 0 {
+			logger.Info("Post-run hooks are not executed for detached processes")
+		}
+		process, err := startDetached(detachName, args, env, shell)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Started %s (PID %d), logs: %s\n", process.Name, process.PID, process.LogFile)
+		return nil
+	}
+
+	var extraFiles []*os.File
+	if envFD != 0 {
+		file, err := newEnvFile(encodeEnviron(result.Secrets))
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		extraFiles = envFDFiles(envFD, file)
+	}
+
 	// Execute the command
+	var cmdErr error
 	if shell {
 		logger.Debug("Executing command in shell mode")
-		return executeShellCommand(args, env)
+		cmdErr = executeShellCommand(args, env, result.Secrets, extraFiles...)
+	} else {
+		logger.Debug("Executing command in direct mode")
+		cmdErr = executeDirectCommand(args, env, result.Secrets, extraFiles...)
 	}
-	logger.Debug("Executing command in direct mode")
-	return executeDirectCommand(args, env)
+
+	return runPostHooks(post, env, cmdErr)
 }
 
-// getSecretKeys returns a slice of keys from the secret map for logging
-func getSecretKeys(secrets providers.SecretMap) []string {
-	keys := make([]string, 0, len(secrets))
-	for k := range secrets {
-		keys = append(keys, k)
+// validateRunArgs requires the command to follow a "--" separator so its flags are never
+// mistaken for feller flags
+func validateRunArgs(cmd *cobra.Command, args []string) error {
+	if len(args) == 0 {
+		if procfile != "" || argFile != "" {
+			return nil
+		}
+		return errors.New("no command specified: usage 'feller run [flags] -- command [args...]'")
 	}
-	return keys
+	if cmd.ArgsLenAtDash() != 0 {
+		return fmt.Errorf("missing \"--\" separator before the command: use 'feller run [flags] -- %s'", strings.Join(args, " "))
+	}
+	return nil
 }
 
-// handleMissingVariables generates an error for missing environment variables
-func handleMissingVariables(missingVars []providers.MissingVariable) error {
-	if len(missingVars) == 0 {
-		return nil
+// readArgFile reads command arguments from a file, one argument per line; empty lines are ignored
+func readArgFile(path string) ([]string, error) {
+	logger.Debug("Reading command arguments from: %s", path)
+
+	// #nosec G304 - argfile path is provided by the user
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read argfile %s: %w", path, err)
 	}
 
-	var errorMsg strings.Builder
-	errorMsg.WriteString(fmt.Sprintf("Missing %d required environment variable(s) in GitHub Actions:\n\n", len(missingVars)))
+	var args []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if line == "" {
+			continue
+		}
+		args = append(args, line)
+	}
 
-	// Group by provider for better organization
-	providerGroups := make(map[string][]providers.MissingVariable)
-	for _, mv := range missingVars {
-		providerGroups[mv.Provider] = append(providerGroups[mv.Provider], mv)
+	if len(args) == 0 {
+		return nil, fmt.Errorf("argfile %s contains no arguments", path)
 	}
+	return args, nil
+}
 
-	for provider, vars := range providerGroups {
-		errorMsg.WriteString(fmt.Sprintf("Provider '%s':\n", provider))
-		for _, mv := range vars {
-			errorMsg.WriteString(fmt.Sprintf("  • %s (maps to: %s)\n", mv.VariableName, mv.MappedTo))
+// resolveParallelSpecs returns the processes to run concurrently, or nil for a single command
+func resolveParallelSpecs(args []string) ([]processSpec, error) {
+	if procfile == "" && !parallel {
+		return nil, nil
+	}
+	if detach {
+		return nil, errors.New("--detach cannot be combined with --parallel or --procfile")
+	}
+	if procfile != "" {
+		if len(args) > 0 {
+			return nil, errors.New("--procfile cannot be combined with command arguments")
 		}
-		errorMsg.WriteString("\n")
+		return parseProcfile(procfile)
 	}
+	return splitParallelArgs(args), nil
+}
 
-	errorMsg.WriteString("To fix this, add the missing environment variables to your GitHub Actions workflow:\n\n")
-	errorMsg.WriteString("```yaml\n")
-	errorMsg.WriteString("- name: Run with secrets\n")
-	errorMsg.WriteString("  env:\n")
-	for _, mv := range missingVars {
-		errorMsg.WriteString(fmt.Sprintf("    %s: ${{ secrets.%s }}\n", mv.VariableName, mv.VariableName))
+// getSecretKeys returns a slice of keys from the secret map for logging
+func getSecretKeys(secrets providers.SecretMap) []string {
+	keys := make([]string, 0, len(secrets))
+	for k := range secrets {
+		keys = append(keys, k)
 	}
-	errorMsg.WriteString("  run: feller run -- your-command\n")
-	errorMsg.WriteString("```\n\n")
-	errorMsg.WriteString("Or use --silent flag to suppress this error and continue with available secrets only.")
-
-	return errors.New(errorMsg.String())
+	return keys
 }
 
 // maskSecret masks a secret value for debug logging (same as in providers package)
 func maskSecret(value string) string {
 	if len(value) <= 4 {
-		return strings.Repeat("*", len(value))
+		return mask(len(value))
+	}
+	return value[:2] + mask(len(value)-4) + value[len(value)-2:]
+}
+
+// mask hides n characters: asterisks, or with plain output a count that screen readers do
+// not spell out character by character
+func mask(n int) string {
+	if isPlain() {
+		return fmt.Sprintf("[%d hidden]", n)
 	}
-	return value[:2] + strings.Repeat("*", len(value)-4) + value[len(value)-2:]
+	return strings.Repeat("*", n)
 }
 
-func executeDirectCommand(args, env []string) error {
+// executeDirectCommand runs args with env, passing extraFiles as descriptors 3 and up.
+// {{secret.KEY}} placeholders are expanded from secrets only after args have been logged.
+func executeDirectCommand(args, env []string, secrets providers.SecretMap, extraFiles ...*os.File) error {
 	if len(args) == 0 {
 		logger.Debug("No command specified for direct execution")
 		return errors.New("no command specified")
@@ -196,19 +383,24 @@ func executeDirectCommand(args, env []string) error {
 	logger.Debug("Command: %s", args[0])
 	logger.Debug("Arguments: %v", args[1:])
 	logger.Debug("Environment variables: %d", len(env))
+	logger.Verbose("Executing: %s", strings.Join(args, " "))
+
+	argv, err := expandSecretRefs(args, secrets)
+	if err != nil {
+		return err
+	}
 
 	// #nosec G204 - This is intentional: tool designed to execute user-provided commands with secrets
-	cmd := exec.CommandContext(context.Background(), args[0], args[1:]...)
+	cmd := exec.CommandContext(context.Background(), argv[0], argv[1:]...)
 	cmd.Env = env
+	cmd.ExtraFiles = extraFiles
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	cmd.Stdin = os.Stdin
 
-	logger.Verbose("Executing: %s", strings.Join(args, " "))
 	logger.Debug("Starting command execution...")
 
-	err := cmd.Run()
-	if err != nil {
+	if err := cmd.Run(); err != nil {
 		logger.Debug("Command execution failed: %v", err)
 		return fmt.Errorf("direct command execution failed: %w", err)
 	}
@@ -217,13 +409,22 @@ func executeDirectCommand(args, env []string) error {
 	return nil
 }
 
-func executeShellCommand(args, env []string) error {
-	if len(args) == 0 {
-		logger.Debug("No command specified for shell execution")
-		return errors.New("no command specified")
+// shellCommandString builds the command string passed to the shell. A single argument is used
+// verbatim as a script (like sh -c); multiple arguments are quoted individually so spaces and
+// shell metacharacters inside an argument cannot change the command's meaning.
+func shellCommandString(args []string) string {
+	if len(args) == 1 {
+		return args[0]
 	}
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = quoteShellArg(arg)
+	}
+	return strings.Join(quoted, " ")
+}
 
-	// Determine shell
+// resolveShell determines the shell used for shell mode and hooks
+func resolveShell() string {
 	shell := os.Getenv("SHELL")
 	if shell == "" {
 		shell = "/bin/sh"
@@ -231,23 +432,40 @@ func executeShellCommand(args, env []string) error {
 	} else {
 		logger.Debug("Using shell from SHELL environment variable: %s", shell)
 	}
+	return shell
+}
 
-	// Join all arguments as a single command string
-	cmdStr := strings.Join(args, " ")
+// executeShellCommand runs args through the shell with env, passing extraFiles as
+// descriptors 3 and up. {{secret.KEY}} placeholders are expanded from secrets after
+// quoting is decided and the command string has been logged.
+func executeShellCommand(args, env []string, secrets providers.SecretMap, extraFiles ...*os.File) error {
+	if len(args) == 0 {
+		logger.Debug("No command specified for shell execution")
+		return errors.New("no command specified")
+	}
+
+	shell := resolveShell()
+
+	cmdStr := shellCommandString(args)
 	logger.Debug("Shell command string: %s", cmdStr)
 	logger.Debug("Environment variables: %d", len(env))
+	logger.Verbose("Executing shell: %s -c %s", shell, cmdStr)
+
+	argv, err := expandSecretRefs(args, secrets)
+	if err != nil {
+		return err
+	}
 
-	cmd := exec.CommandContext(context.Background(), shell, "-c", cmdStr)
+	cmd := exec.CommandContext(context.Background(), shell, "-c", shellCommandString(argv))
 	cmd.Env = env
+	cmd.ExtraFiles = extraFiles
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	cmd.Stdin = os.Stdin
 
-	logger.Verbose("Executing shell: %s -c %s", shell, cmdStr)
 	logger.Debug("Starting shell command execution...")
 
-	err := cmd.Run()
-	if err != nil {
+	if err := cmd.Run(); err != nil {
 		logger.Debug("Shell command execution failed: %v", err)
 		return fmt.Errorf("shell command execution failed: %w", err)
 	}
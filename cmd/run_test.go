@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/containifyci/feller/pkg/fellertest"
+	"github.com/spf13/cobra"
+)
+
+//nolint:paralleltest // reads global flag variables
+func TestValidateRunArgs(t *testing.T) {
+	tests := []struct {
+		name        string
+		errContains string
+		args        []string
+		gotArgs     []string
+		wantErr     bool
+	}{
+		{
+			name:    "command after separator",
+			args:    []string{"--", "mytool", "--verbose", "-x"},
+			gotArgs: []string{"mytool", "--verbose", "-x"},
+		},
+		{
+			name:    "feller flags before separator",
+			args:    []string{"--reset", "--", "mytool", "--reset"},
+			gotArgs: []string{"mytool", "--reset"},
+		},
+		{
+			name:        "missing separator",
+			args:        []string{"mytool", "--verbose"},
+			wantErr:     true,
+			errContains: `missing "--" separator before the command: use 'feller run [flags] -- mytool --verbose'`,
+		},
+		{
+			name:        "no command",
+			args:        []string{"--"},
+			wantErr:     true,
+			errContains: "no command specified",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			var reset bool
+			cmd := &cobra.Command{
+				Use:  "run",
+				Args: validateRunArgs,
+				RunE: func(_ *cobra.Command, args []string) error {
+					got = args
+					return nil
+				},
+			}
+			cmd.Flags().BoolVarP(&reset, "reset", "r", false, "")
+			cmd.Flags().SetInterspersed(false)
+			cmd.SetArgs(tt.args)
+			cmd.SilenceUsage = true
+			cmd.SilenceErrors = true
+
+			err := cmd.Execute()
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Errorf("validateRunArgs() error = %v, expected to contain %q", err, tt.errContains)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("validateRunArgs() unexpected error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.gotArgs) {
+				t.Errorf("command received args %v, want %v", got, tt.gotArgs)
+			}
+		})
+	}
+}
+
+func TestReadArgFile(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name        string
+		content     string
+		errContains string
+		expected    []string
+		wantErr     bool
+	}{
+		{
+			name:     "one argument per line",
+			content:  "curl\n-H\nAuthorization: Bearer x\n\nhttps://example.com\n",
+			expected: []string{"curl", "-H", "Authorization: Bearer x", "https://example.com"},
+		},
+		{
+			name:     "windows line endings",
+			content:  "echo\r\nhello world\r\n",
+			expected: []string{"echo", "hello world"},
+		},
+		{
+			name:        "empty file",
+			content:     "\n\n",
+			wantErr:     true,
+			errContains: "contains no arguments",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			path := filepath.Join(t.TempDir(), "cmd.args")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatalf("Failed to write argfile: %v", err)
+			}
+
+			got, err := readArgFile(path)
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Errorf("readArgFile() error = %v, expected to contain %q", err, tt.errContains)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("readArgFile() unexpected error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("readArgFile() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+
+	t.Run("missing file", func(t *testing.T) {
+		t.Parallel()
+		if _, err := readArgFile("/nonexistent/cmd.args"); err == nil {
+			t.Error("readArgFile() expected error for missing file")
+		}
+	})
+}
+
+//nolint:paralleltest // modifies global flag variables and environment variables
+func TestRunHooksFallback(t *testing.T) {
+	t.Setenv("GITHUB_ACTIONS", "")
+	t.Cleanup(func() { cfgFile, preHooks, postHooks = "", nil, nil })
+	local := fellertest.FakeDotenv(t, map[string]string{"API_KEY": "abc"})
+	cfgFile = fellertest.WriteConfig(t, fellertest.NewConfig().Provider("local", local).Hooks([]string{"./migrate.sh"}, nil).Build())
+
+	err := runCommand(runCmd, []string{"true"})
+	if err == nil || err.Error() != "the hooks of the config need feller to resolve secrets itself; add --no-fallback" {
+		t.Errorf("runCommand() with config hooks error = %v", err)
+	}
+
+	cfgFile = fellertest.WriteConfig(t, fellertest.NewConfig().Provider("local", local).Build())
+	postHooks = []string{"rm -f kubeconfig"}
+	err = runCommand(runCmd, []string{"true"})
+	if err == nil || err.Error() != "--pre and --post need feller to resolve secrets itself; add --no-fallback" {
+		t.Errorf("runCommand() with --post error = %v", err)
+	}
+}
@@ -1,12 +1,15 @@
 package cmd
 
 import (
-	"errors"
+	"bytes"
 	"fmt"
+	"io"
+	"os"
+	"slices"
 	"sort"
 	"strings"
 
-	"github.com/containifyci/feller/pkg/config"
+	"github.com/containifyci/feller/pkg/logger"
 	"github.com/containifyci/feller/pkg/providers"
 	"github.com/spf13/cobra"
 )
@@ -18,57 +21,181 @@ var shCmd = &cobra.Command{
 	Long: `Export secrets as shell export statements that can be evaluated
 to set environment variables in the current shell.
 
+--shell selects the syntax of the statements:
+  bash       - export KEY='value' (POSIX shells such as bash, zsh and sh)
+  fish       - set -gx KEY 'value'
+  powershell - $env:KEY = 'value'
+  cmd        - set "KEY=value", for batch files run with call
+
+By default the shell is detected from SHELL, or from COMSPEC on Windows where
+SHELL is not set, falling back to bash. Values are quoted so evaluating the
+statements never runs anything inside them; cmd cannot quote line breaks or
+double quotes, so such values fail.
+
+Use -o/--out to write the statements atomically to a file with 0600
+permissions instead of stdout.
+
 Examples:
   eval "$(feller sh)"
-  feller sh > secrets.sh && source secrets.sh`,
+  feller sh -o secrets.sh && source secrets.sh
+  feller sh --shell fish | source
+  feller sh --shell powershell | Invoke-Expression
+  feller sh --shell cmd -o secrets.cmd && call secrets.cmd`,
 	RunE: exportShell,
 }
 
+// Syntaxes of the statements written by feller sh
+const (
+	shellBash       = "bash"
+	shellFish       = "fish"
+	shellPowerShell = "powershell"
+	shellCmd        = "cmd"
+)
+
+var shellSyntaxes = []string{shellBash, shellFish, shellPowerShell, shellCmd}
+
+var shSyntax string
+
 func init() {
 	rootCmd.AddCommand(shCmd)
+	addOutputFlag(shCmd)
+	shCmd.Flags().StringVar(&shSyntax, "shell", "", "Syntax of the statements: bash, fish, powershell or cmd (default detected from SHELL or COMSPEC)")
+	_ = shCmd.RegisterFlagCompletionFunc("shell", cobra.FixedCompletions(shellSyntaxes, cobra.ShellCompDirectiveNoFileComp))
+}
+
+// resolveShellSyntax returns the syntax selected by --shell, or the one of the shell
+// detected from SHELL, or COMSPEC when SHELL is not set
+func resolveShellSyntax() (string, error) {
+	if shSyntax != "" {
+		if !slices.Contains(shellSyntaxes, shSyntax) {
+			return "", fmt.Errorf("unsupported shell: %s (expected %s)", shSyntax, strings.Join(shellSyntaxes, ", "))
+		}
+		return shSyntax, nil
+	}
+
+	path := os.Getenv("SHELL")
+	if path == "" {
+		path = os.Getenv("COMSPEC")
+	}
+	// Both separators, since COMSPEC holds a Windows path also when read elsewhere
+	name := strings.ToLower(path[strings.LastIndexAny(path, `/\`)+1:])
+	name = strings.TrimSuffix(name, ".exe")
+	syntax := shellBash
+	switch name {
+	case "fish":
+		syntax = shellFish
+	case "pwsh", "powershell":
+		syntax = shellPowerShell
+	case "cmd":
+		syntax = shellCmd
+	}
+	logger.Debug("Detected shell syntax %s from %q", syntax, path)
+	return syntax, nil
 }
 
-func exportShell(_ *cobra.Command, args []string) error {
-	// Check if we're in GitHub Actions
-	if !isGitHubActions() {
-		return fallbackToTeller(append([]string{"sh"}, args...))
+func exportShell(cmd *cobra.Command, args []string) error {
+	syntax, err := resolveShellSyntax()
+	if err != nil {
+		return err
+	}
+
+	// Check if we resolve secrets natively, as on CI
+	native, err := resolveNatively()
+	if err != nil {
+		return err
+	}
+	if !native {
+		if shSyntax != "" && shSyntax != shellBash {
+			return fmt.Errorf("--shell %s is not supported by teller fallback mode", shSyntax)
+		}
+		return fallbackToTeller(tellerCommandArgs(cmd, append([]string{"sh"}, args...)...), exportOut)
 	}
 
 	// Load configuration
-	cfg, err := config.LoadConfig(cfgFile)
+	cfg, err := loadConfig()
 	if err != nil {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
 
 	// Collect all secrets and check for missing variables
-	result, err := providers.CollectSecretsWithResult(cfg, silent)
+	result, err := collectSecrets(cfg)
 	if err != nil {
-		return fmt.Errorf("failed to collect secrets: %w", err)
+		return err
 	}
 
 	// Handle missing environment variables
-	if result.HasMissingVars && !silent {
-		return handleMissingVariablesShell(result.MissingVars)
+	if err := checkMissing(result, missingShell, cfg.Messages); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if err := writeShellStatements(&buf, result.Secrets, syntax); err != nil {
+		return err
 	}
+	return writeOutput(cmd.OutOrStdout(), exportOut, buf.Bytes())
+}
+
+// writeShellExports writes one POSIX export statement per secret, see writeShellStatements
+func writeShellExports(w io.Writer, secrets providers.SecretMap) error {
+	return writeShellStatements(w, secrets, shellBash)
+}
 
-	// Sort keys for consistent output
-	keys := make([]string, 0, len(result.Secrets))
-	for k := range result.Secrets {
+// writeShellStatements writes one statement per secret in the syntax of a shell, sorted by
+// key, with values quoted so they are safe to eval. Keys are written unquoted, so anything
+// other than a plain shell variable name is rejected rather than risk command injection.
+func writeShellStatements(w io.Writer, secrets providers.SecretMap, syntax string) error {
+	keys := make([]string, 0, len(secrets))
+	for k, value := range secrets {
+		if !isShellName(k) {
+			return fmt.Errorf("cannot export %q: not a valid shell variable name", k)
+		}
+		if strings.ContainsRune(value, 0) {
+			return fmt.Errorf("cannot export %s: value contains a NUL byte", k)
+		}
+		if syntax == shellCmd && strings.ContainsAny(value, "\"\r\n") {
+			return fmt.Errorf("cannot export %s for cmd: value contains a double quote or line break", k)
+		}
 		keys = append(keys, k)
 	}
 	sort.Strings(keys)
 
 	for _, key := range keys {
-		value := result.Secrets[key]
-		// Shell-escape the value
-		// For safety, we'll single-quote the value and escape any single quotes within it
-		escapedValue := "'" + shellEscape(value) + "'"
-		fmt.Printf("export %s=%s\n", key, escapedValue)
+		value := secrets[key]
+		switch syntax {
+		case shellFish:
+			// Only backslashes and single quotes are escapes inside fish single quotes
+			value = strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value)
+			fmt.Fprintf(w, "set -gx %s '%s'\n", key, value)
+		case shellPowerShell:
+			// PowerShell ends single-quoted strings at typographic single quotes as well
+			value = strings.NewReplacer("'", "''", "\u2018", "\u2018\u2018", "\u2019", "\u2019\u2019", "\u201A", "\u201A\u201A", "\u201B", "\u201B\u201B").Replace(value)
+			fmt.Fprintf(w, "$env:%s = '%s'\n", key, value)
+		case shellCmd:
+			// Quoting the assignment protects & | < > ^, but batch files still expand %
+			fmt.Fprintf(w, "set \"%s=%s\"\r\n", key, strings.ReplaceAll(value, "%", "%%"))
+		default:
+			fmt.Fprintf(w, "export %s='%s'\n", key, shellEscape(value))
+		}
 	}
-
 	return nil
 }
 
+// isShellName reports whether s is a POSIX shell variable name
+func isShellName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, r := range s {
+		switch {
+		case r == '_', r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+		case r >= '0' && r <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
 // shellEscape escapes single quotes in a string for use within single quotes
 func shellEscape(s string) string {
 	// Replace any single quote with '\''
@@ -76,6 +203,31 @@ func shellEscape(s string) string {
 	return shellReplaceAll(s, "'", "'\\''")
 }
 
+// quoteShellArg quotes a single argument for POSIX shells, leaving plain words untouched
+func quoteShellArg(s string) string {
+	if s == "" {
+		return "''"
+	}
+	for _, r := range s {
+		if !isShellSafeRune(r) {
+			return "'" + shellEscape(s) + "'"
+		}
+	}
+	return s
+}
+
+// isShellSafeRune reports whether r never needs quoting in a POSIX shell word
+func isShellSafeRune(r rune) bool {
+	switch {
+	case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		return true
+	case strings.ContainsRune("-_./:=@%+,", r):
+		return true
+	default:
+		return false
+	}
+}
+
 // shellReplaceAll is a simple string replacement function
 func shellReplaceAll(s, old, replacement string) string {
 	// Handle edge case: empty old string should return original string
@@ -104,40 +256,3 @@ func shellIndexOf(s, substr string) int {
 	}
 	return -1
 }
-
-// handleMissingVariablesShell generates an error for missing environment variables during shell export
-func handleMissingVariablesShell(missingVars []providers.MissingVariable) error {
-	if len(missingVars) == 0 {
-		return nil
-	}
-
-	var errorMsg strings.Builder
-	errorMsg.WriteString(fmt.Sprintf("Cannot generate shell exports: Missing %d required environment variable(s) in GitHub Actions:\n\n", len(missingVars)))
-
-	// Group by provider for better organization
-	providerGroups := make(map[string][]providers.MissingVariable)
-	for _, mv := range missingVars {
-		providerGroups[mv.Provider] = append(providerGroups[mv.Provider], mv)
-	}
-
-	for provider, vars := range providerGroups {
-		errorMsg.WriteString(fmt.Sprintf("Provider '%s':\n", provider))
-		for _, mv := range vars {
-			errorMsg.WriteString(fmt.Sprintf("  • %s (maps to: %s)\n", mv.VariableName, mv.MappedTo))
-		}
-		errorMsg.WriteString("\n")
-	}
-
-	errorMsg.WriteString("To fix this, add the missing environment variables to your GitHub Actions workflow:\n\n")
-	errorMsg.WriteString("```yaml\n")
-	errorMsg.WriteString("- name: Set shell variables\n")
-	errorMsg.WriteString("  env:\n")
-	for _, mv := range missingVars {
-		errorMsg.WriteString(fmt.Sprintf("    %s: ${{ secrets.%s }}\n", mv.VariableName, mv.VariableName))
-	}
-	errorMsg.WriteString("  run: eval \"$(feller sh)\"\n")
-	errorMsg.WriteString("```\n\n")
-	errorMsg.WriteString("Or use --silent flag to export only available secrets.")
-
-	return errors.New(errorMsg.String())
-}
@@ -2,11 +2,17 @@ package cmd
 
 import (
 	"bytes"
+	"context"
 	"os"
+	"os/exec"
+	"path/filepath"
 	"strings"
 	"testing"
 
+	"github.com/containifyci/feller/pkg/config"
+	"github.com/containifyci/feller/pkg/fellertest"
 	"github.com/containifyci/feller/pkg/providers"
+	"github.com/spf13/cobra"
 )
 
 const shTestConfigContent = `providers:
@@ -80,6 +86,71 @@ func TestShellEscape(t *testing.T) {
 	}
 }
 
+func TestQuoteShellArg(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{name: "plain word", input: "hello", expected: "hello"},
+		{name: "path and flags", input: "--config=/etc/app.yml", expected: "--config=/etc/app.yml"},
+		{name: "empty string", input: "", expected: "''"},
+		{name: "spaces", input: "my file", expected: "'my file'"},
+		{name: "single quote", input: "don't", expected: "'don'\\''t'"},
+		{name: "command substitution", input: "$(whoami)", expected: "'$(whoami)'"},
+		{name: "command separator", input: "; rm -rf /", expected: "'; rm -rf /'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := quoteShellArg(tt.input); got != tt.expected {
+				t.Errorf("quoteShellArg(%q) = %q, want %q", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestShellCommandStringInjection(t *testing.T) {
+	t.Parallel()
+	inputs := []string{
+		"with spaces",
+		"it's quoted",
+		`"double" quotes`,
+		"$(echo injected)",
+		"`echo injected`",
+		"; echo injected",
+		"a && echo injected || true",
+		"$HOME",
+		"*",
+		"line1\nline2",
+		"",
+	}
+
+	for _, input := range inputs {
+		t.Run(input, func(t *testing.T) {
+			t.Parallel()
+			script := shellCommandString([]string{"printf", "%s", input})
+			out, err := exec.CommandContext(context.Background(), "/bin/sh", "-c", script).Output()
+			if err != nil {
+				t.Fatalf("shell execution of %q failed: %v", script, err)
+			}
+			if string(out) != input {
+				t.Errorf("argument %q reached the command as %q (script: %s)", input, string(out), script)
+			}
+		})
+	}
+}
+
+func TestShellCommandStringSingleScript(t *testing.T) {
+	t.Parallel()
+	script := "echo $HOME | head -c 10"
+	if got := shellCommandString([]string{script}); got != script {
+		t.Errorf("shellCommandString() = %q, want single argument passed verbatim", got)
+	}
+}
+
 func TestShellReplaceAll(t *testing.T) {
 	t.Parallel()
 	tests := []struct {
@@ -311,22 +382,22 @@ func TestHandleMissingVariablesShell(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
-			err := handleMissingVariablesShell(tt.missingVars)
+			err := missingVariablesError(tt.missingVars, missingShell, config.Messages{})
 
 			if tt.wantErr {
 				if err == nil {
-					t.Errorf("handleMissingVariablesShell() expected error but got none")
+					t.Errorf("missingVariablesError() expected error but got none")
 					return
 				}
 
 				errMsg := err.Error()
 				for _, contains := range tt.errContains {
 					if !strings.Contains(errMsg, contains) {
-						t.Errorf("handleMissingVariablesShell() error should contain %q, got: %v", contains, err)
+						t.Errorf("missingVariablesError() error should contain %q, got: %v", contains, err)
 					}
 				}
 			} else if err != nil {
-				t.Errorf("handleMissingVariablesShell() unexpected error = %v", err)
+				t.Errorf("missingVariablesError() unexpected error = %v", err)
 			}
 		})
 	}
@@ -522,20 +593,11 @@ func TestExportShell(t *testing.T) { //nolint:paralleltest // uses t.Setenv() in
 			// Set silent mode
 			silent = tt.silent
 
-			// Capture stdout
-			oldStdout := os.Stdout
-			r, w, _ := os.Pipe()
-			os.Stdout = w
-
-			// Run the function
-			err := exportShell(nil, []string{})
-
-			// Restore stdout and read output
-			w.Close()
-			os.Stdout = oldStdout
-
 			var buf bytes.Buffer
-			buf.ReadFrom(r)
+			cmd := &cobra.Command{}
+			cmd.SetOut(&buf)
+
+			err := exportShell(cmd, []string{})
 			output := buf.String()
 
 			if tt.wantErr {
@@ -558,3 +620,147 @@ func TestExportShell(t *testing.T) { //nolint:paralleltest // uses t.Setenv() in
 		})
 	}
 }
+
+func TestWriteShellExports(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name        string
+		secrets     providers.SecretMap
+		expected    string
+		errContains string
+	}{
+		{name: "sorted exports", secrets: providers.SecretMap{"B": "it's", "A": "1"}, expected: "export A='1'\nexport B='it'\\''s'\n"},
+		{name: "injection in key", secrets: providers.SecretMap{"X;rm -rf /": "1"}, errContains: "not a valid shell variable name"},
+		{name: "leading digit", secrets: providers.SecretMap{"1X": "1"}, errContains: "not a valid shell variable name"},
+		{name: "nul byte", secrets: providers.SecretMap{"A": "a\x00b"}, errContains: "value contains a NUL byte"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var buf bytes.Buffer
+			err := writeShellExports(&buf, tt.secrets)
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Errorf("writeShellExports() error = %v, expected to contain %q", err, tt.errContains)
+				}
+				if buf.Len() != 0 {
+					t.Errorf("writeShellExports() wrote %q before failing", buf.String())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("writeShellExports() unexpected error = %v", err)
+			}
+			if buf.String() != tt.expected {
+				t.Errorf("writeShellExports() = %q, want %q", buf.String(), tt.expected)
+			}
+		})
+	}
+}
+
+func TestWriteShellStatements(t *testing.T) {
+	t.Parallel()
+	secrets := providers.SecretMap{"B": `it's \ 50% "x"`, "A": "1"}
+	tests := []struct {
+		syntax   string
+		expected string
+	}{
+		{syntax: shellBash, expected: "export A='1'\nexport B='it'\\''s \\ 50% \"x\"'\n"},
+		{syntax: shellFish, expected: "set -gx A '1'\nset -gx B 'it\\'s \\\\ 50% \"x\"'\n"},
+		{syntax: shellPowerShell, expected: "$env:A = '1'\n$env:B = 'it''s \\ 50% \"x\"'\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.syntax, func(t *testing.T) {
+			t.Parallel()
+			var buf bytes.Buffer
+			if err := writeShellStatements(&buf, secrets, tt.syntax); err != nil {
+				t.Fatalf("writeShellStatements() unexpected error = %v", err)
+			}
+			if buf.String() != tt.expected {
+				t.Errorf("writeShellStatements() = %q, want %q", buf.String(), tt.expected)
+			}
+		})
+	}
+
+	t.Run("powershell smart quotes", func(t *testing.T) {
+		t.Parallel()
+		var buf bytes.Buffer
+		if err := writeShellStatements(&buf, providers.SecretMap{"A": "it\u2019s \u2018a\u2019 \u201Ab\u201B'; rm x"}, shellPowerShell); err != nil {
+			t.Fatalf("writeShellStatements() unexpected error = %v", err)
+		}
+		if expected := "$env:A = 'it\u2019\u2019s \u2018\u2018a\u2019\u2019 \u201A\u201Ab\u201B\u201B''; rm x'\n"; buf.String() != expected {
+			t.Errorf("writeShellStatements() = %q, want %q", buf.String(), expected)
+		}
+	})
+
+	t.Run(shellCmd, func(t *testing.T) {
+		t.Parallel()
+		var buf bytes.Buffer
+		if err := writeShellStatements(&buf, providers.SecretMap{"A": "a&b|c 50%"}, shellCmd); err != nil {
+			t.Fatalf("writeShellStatements() unexpected error = %v", err)
+		}
+		if expected := "set \"A=a&b|c 50%%\"\r\n"; buf.String() != expected {
+			t.Errorf("writeShellStatements() = %q, want %q", buf.String(), expected)
+		}
+		for _, value := range []string{`a"b`, "a\nb", "a\rb"} {
+			err := writeShellStatements(&bytes.Buffer{}, providers.SecretMap{"A": value}, shellCmd)
+			if err == nil || !strings.Contains(err.Error(), "double quote or line break") {
+				t.Errorf("writeShellStatements(%q) error = %v", value, err)
+			}
+		}
+	})
+}
+
+//nolint:paralleltest // modifies environment variables and global flag variables
+func TestResolveShellSyntax(t *testing.T) {
+	t.Cleanup(func() { shSyntax = "" })
+	tests := []struct {
+		flag, shell, comspec string
+		expected             string
+	}{
+		{shell: "/bin/bash", expected: shellBash},
+		{shell: "/usr/bin/zsh", comspec: `C:\Windows\system32\cmd.exe`, expected: shellBash},
+		{shell: "/opt/homebrew/bin/fish", expected: shellFish},
+		{shell: "/usr/bin/pwsh", expected: shellPowerShell},
+		{comspec: `C:\Windows\system32\cmd.exe`, expected: shellCmd},
+		{comspec: `C:\Program Files\PowerShell\7\PWSH.EXE`, expected: shellPowerShell},
+		{expected: shellBash},
+		{flag: shellFish, shell: "/bin/bash", expected: shellFish},
+	}
+	for _, tt := range tests {
+		t.Setenv("SHELL", tt.shell)
+		t.Setenv("COMSPEC", tt.comspec)
+		shSyntax = tt.flag
+		syntax, err := resolveShellSyntax()
+		if err != nil || syntax != tt.expected {
+			t.Errorf("resolveShellSyntax() with SHELL=%q COMSPEC=%q --shell=%q = %q, %v, want %q", tt.shell, tt.comspec, tt.flag, syntax, err, tt.expected)
+		}
+	}
+
+	shSyntax = "tcsh"
+	if _, err := resolveShellSyntax(); err == nil || err.Error() != "unsupported shell: tcsh (expected bash, fish, powershell, cmd)" {
+		t.Errorf("resolveShellSyntax() error = %v", err)
+	}
+}
+
+//nolint:paralleltest // modifies environment variables and global flag variables
+func TestExportShellOutputFile(t *testing.T) {
+	t.Setenv("GITHUB_ACTIONS", "true")
+	originalCfg, originalOut := cfgFile, exportOut
+	t.Cleanup(func() { cfgFile, exportOut = originalCfg, originalOut })
+	cfgFile = fellertest.WriteConfig(t, fellertest.NewConfig().Provider("gsm", fellertest.FakeGSM(t, map[string]string{"API_KEY": "a'b"})).Build())
+	exportOut = filepath.Join(t.TempDir(), "secrets.sh")
+
+	var stdout bytes.Buffer
+	cmd := &cobra.Command{}
+	cmd.SetOut(&stdout)
+	if err := exportShell(cmd, nil); err != nil {
+		t.Fatalf("exportShell() error = %v", err)
+	}
+	data, err := os.ReadFile(exportOut)
+	if err != nil || string(data) != "export API_KEY='a'\\''b'\n" || stdout.Len() != 0 {
+		t.Errorf("exportShell() wrote %q, %v to the file and %q to stdout", data, err, stdout.String())
+	}
+}
@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/containifyci/feller/pkg/logger"
+	"github.com/containifyci/feller/pkg/providers"
+	"github.com/spf13/cobra"
+)
+
+var showConflicts bool
+
+// showCmd represents the show command
+var showCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Show collected secrets with masked values and their origin",
+	Long: `Show every collected key with a masked value and the provider and path
+map that supplied it. Keys declared in the config but missing from the
+environment are listed as (missing).
+
+Use --conflicts to list only keys supplied by more than one provider,
+together with every origin and which one wins. Later providers override
+earlier ones, so a conflict usually means one mapping is shadowing another.
+
+Examples:
+  feller show
+  feller show --conflicts`,
+	Args: cobra.NoArgs,
+	RunE: showSecrets,
+}
+
+func init() {
+	rootCmd.AddCommand(showCmd)
+	showCmd.Flags().BoolVar(&showConflicts, "conflicts", false, "Only show keys supplied by multiple providers, with all origins")
+}
+
+func showSecrets(cmd *cobra.Command, args []string) error {
+	native, err := resolveNatively()
+	if err != nil {
+		return err
+	}
+	if !native {
+		if showConflicts {
+			return errors.New("--conflicts is not supported by teller fallback mode")
+		}
+		return fallbackToTeller(tellerCommandArgs(cmd, append([]string{"show"}, args...)...), "")
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	result, err := collectSecrets(cfg)
+	if err != nil {
+		return err
+	}
+	logger.Debug("Collected %d secrets, %d conflicting keys", len(result.Secrets), len(result.Conflicts()))
+
+	if showConflicts {
+		return writeConflicts(os.Stdout, result)
+	}
+	return writeShow(os.Stdout, result)
+}
+
+// writeShow prints every collected key with its masked value and origin
+func writeShow(out io.Writer, result *providers.CollectionResult) error {
+	keys := make([]string, 0, len(result.Secrets))
+	for key := range result.Secrets {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	missing := make([]providers.MissingVariable, len(result.MissingVars))
+	copy(missing, result.MissingVars)
+	sort.Slice(missing, func(i, j int) bool { return missing[i].MappedTo < missing[j].MappedTo })
+
+	w := newTable(out)
+	fmt.Fprintln(w, "KEY\tVALUE\tPROVIDER\tMAP")
+	for _, key := range keys {
+		source := result.Sources[key]
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", key, maskSecret(result.Secrets[key]), source.Provider, source.MapID)
+	}
+	for _, mv := range missing {
+		fmt.Fprintf(w, "%s\t(missing)\t%s\t\n", mv.MappedTo, mv.Provider)
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to write secrets: %w", err)
+	}
+	return nil
+}
+
+// writeConflicts prints every key supplied by more than one provider with all of its origins
+func writeConflicts(out io.Writer, result *providers.CollectionResult) error {
+	conflicts := result.Conflicts()
+	if len(conflicts) == 0 {
+		fmt.Fprintln(out, "No conflicting keys")
+		return nil
+	}
+
+	w := newTable(out)
+	fmt.Fprintln(w, "KEY\tPROVIDER\tKIND\tMAP\tSTATUS")
+	for _, key := range conflicts {
+		origins := result.Origins[key]
+		for i, origin := range origins {
+			status := "overridden"
+			if i == len(origins)-1 {
+				status = "used"
+			}
+			// Continuation rows leave the key out, except in plain output where every row
+			// stands on its own
+			label := key
+			if i > 0 && !isPlain() {
+				label = ""
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", label, origin.Provider, origin.Kind, origin.MapID, status)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to write conflicts: %w", err)
+	}
+	fmt.Fprintf(out, "\n%d key(s) supplied by multiple providers\n", len(conflicts))
+	return nil
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/containifyci/feller/pkg/providers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func conflictResult() *providers.CollectionResult {
+	ci := providers.SecretSource{Provider: "gha", Kind: "google_secretmanager", MapID: "ci"}
+	local := providers.SecretSource{Provider: "local", Kind: "dotenv", MapID: "vars"}
+	return &providers.CollectionResult{
+		Secrets: providers.SecretMap{"DATABASE_URL": "postgres://local", "API_KEY": "abcdefgh"},
+		Sources: map[string]providers.SecretSource{"DATABASE_URL": local, "API_KEY": ci},
+		Origins: map[string][]providers.SecretSource{
+			"DATABASE_URL": {ci, local},
+			"API_KEY":      {ci},
+		},
+		MissingVars: []providers.MissingVariable{{VariableName: "TOKEN", MappedTo: "GH_TOKEN", Provider: "gha"}},
+	}
+}
+
+func TestWriteShow(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	require.NoError(t, writeShow(&buf, conflictResult()))
+
+	expected := "KEY           VALUE             PROVIDER  MAP\n" +
+		"API_KEY       ab****gh          gha       ci\n" +
+		"DATABASE_URL  po************al  local     vars\n" +
+		"GH_TOKEN      (missing)         gha       \n"
+	assert.Equal(t, expected, buf.String())
+}
+
+func TestWriteConflicts(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		result   *providers.CollectionResult
+		name     string
+		expected string
+	}{
+		{
+			name:   "conflicting key",
+			result: conflictResult(),
+			expected: "KEY           PROVIDER  KIND                  MAP   STATUS\n" +
+				"DATABASE_URL  gha       google_secretmanager  ci    overridden\n" +
+				"              local     dotenv                vars  used\n" +
+				"\n1 key(s) supplied by multiple providers\n",
+		},
+		{
+			name:     "no conflicts",
+			result:   &providers.CollectionResult{Secrets: providers.SecretMap{"A": "1"}},
+			expected: "No conflicting keys\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var buf bytes.Buffer
+			require.NoError(t, writeConflicts(&buf, tt.result))
+			assert.Equal(t, tt.expected, buf.String())
+		})
+	}
+}
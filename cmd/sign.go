@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/containifyci/feller/pkg/logger"
+)
+
+// Signing methods. Both delegate to the tool of the same name, like github-secret uses gh.
+const (
+	signCosign   = "cosign"   // Sigstore keyless signing, or with a cosign key pair
+	signMinisign = "minisign" // Signing with a minisign key pair
+)
+
+// githubOIDCIssuer issues the identity tokens cosign signs with in GitHub Actions
+const githubOIDCIssuer = "https://token.actions.githubusercontent.com"
+
+// signatureFile returns the path of the signature written next to the file at path
+func signatureFile(method, path string) (string, error) {
+	switch method {
+	case signCosign:
+		return path + ".sigstore.json", nil
+	case signMinisign:
+		return path + ".minisig", nil
+	default:
+		return "", fmt.Errorf("unsupported signing method: %s (expected cosign or minisign)", method)
+	}
+}
+
+// signArgs returns the arguments of the signing tool for path. key is the private key;
+// without one cosign signs keylessly with the ambient OIDC identity.
+func signArgs(method, path, key string) ([]string, error) {
+	sig, err := signatureFile(method, path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch method {
+	case signCosign:
+		args := []string{"sign-blob", "--yes", "--bundle", sig}
+		if key != "" {
+			args = append(args, "--key", key)
+		}
+		return append(args, path), nil
+	default:
+		if key == "" {
+			return nil, errors.New("minisign signing requires --sign-key")
+		}
+		return []string{"-S", "-s", key, "-m", path, "-x", sig}, nil
+	}
+}
+
+// verifyOptions selects how the signature of a file is verified
+type verifyOptions struct {
+	method         string
+	key            string // Public key; cosign verifies keylessly without one
+	identity       string // Expected certificate identity of keyless signatures
+	identityRegexp string
+	issuer         string
+}
+
+// verifyArgs returns the arguments of the verification tool for path
+func verifyArgs(opts verifyOptions, path string) ([]string, error) {
+	sig, err := signatureFile(opts.method, path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch opts.method {
+	case signCosign:
+		args := []string{"verify-blob", "--bundle", sig}
+		switch {
+		case opts.key != "":
+			args = append(args, "--key", opts.key)
+		case opts.identity != "" && opts.identityRegexp != "":
+			return nil, errors.New("--certificate-identity and --certificate-identity-regexp are mutually exclusive")
+		case opts.identity != "":
+			args = append(args, "--certificate-identity", opts.identity, "--certificate-oidc-issuer", opts.issuer)
+		case opts.identityRegexp != "":
+			args = append(args, "--certificate-identity-regexp", opts.identityRegexp, "--certificate-oidc-issuer", opts.issuer)
+		default:
+			// Accepting any identity would accept a signature anyone can create
+			return nil, errors.New("keyless verification requires --certificate-identity or --certificate-identity-regexp")
+		}
+		return append(args, path), nil
+	default:
+		if opts.key == "" {
+			return nil, errors.New("minisign verification requires --key")
+		}
+		return []string{"-V", "-p", opts.key, "-m", path, "-x", sig}, nil
+	}
+}
+
+// detectSignMethod returns the method of the single signature found next to path
+func detectSignMethod(path string) (string, error) {
+	var found []string
+	for _, method := range []string{signCosign, signMinisign} {
+		sig, _ := signatureFile(method, path)
+		if _, err := os.Stat(sig); err == nil {
+			found = append(found, method)
+		}
+	}
+
+	switch len(found) {
+	case 1:
+		return found[0], nil
+	case 0:
+		return "", fmt.Errorf("no signature found for %s (expected %s.sigstore.json or %s.minisig)", path, path, path)
+	default:
+		return "", fmt.Errorf("multiple signatures found for %s, select one with --method (%s)", path, strings.Join(found, ", "))
+	}
+}
+
+// runSignTool runs the signing tool of method. Its output goes to stderr so it cannot mix
+// with exported data, and stdin is passed through for key password prompts.
+func runSignTool(method string, args []string) error {
+	toolPath, err := exec.LookPath(method)
+	if err != nil {
+		return fmt.Errorf("%s not found in PATH - please install it to sign and verify exports", method)
+	}
+
+	logger.Verbose("Executing: %s %s", toolPath, strings.Join(args, " "))
+	// #nosec G204 - The tool is fixed, arguments are paths and identities given by the user
+	cmd := exec.CommandContext(context.Background(), toolPath, args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s failed: %w", method, err)
+	}
+	return nil
+}
+
+// signFile signs the file at path and returns the path of the signature
+func signFile(method, path, key string) (string, error) {
+	args, err := signArgs(method, path, key)
+	if err != nil {
+		return "", err
+	}
+	if err := runSignTool(method, args); err != nil {
+		return "", fmt.Errorf("failed to sign %s: %w", path, err)
+	}
+
+	sig, _ := signatureFile(method, path)
+	logger.Verbose("Wrote %s signature to %s", method, sig)
+	return sig, nil
+}
@@ -0,0 +1,181 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/containifyci/feller/pkg/fellertest"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignArgs(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name        string
+		method      string
+		key         string
+		errContains string
+		expected    []string
+	}{
+		{name: "cosign keyless", method: signCosign, expected: []string{"sign-blob", "--yes", "--bundle", "s.json.sigstore.json", "s.json"}},
+		{name: "cosign key", method: signCosign, key: "cosign.key", expected: []string{"sign-blob", "--yes", "--bundle", "s.json.sigstore.json", "--key", "cosign.key", "s.json"}},
+		{name: "minisign", method: signMinisign, key: "mini.key", expected: []string{"-S", "-s", "mini.key", "-m", "s.json", "-x", "s.json.minisig"}},
+		{name: "minisign without key", method: signMinisign, errContains: "requires --sign-key"},
+		{name: "unsupported", method: "gpg", errContains: "unsupported signing method: gpg"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			args, err := signArgs(tt.method, "s.json", tt.key)
+			if tt.errContains != "" {
+				require.ErrorContains(t, err, tt.errContains)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, args)
+		})
+	}
+}
+
+func TestVerifyArgs(t *testing.T) {
+	t.Parallel()
+	identity := "https://github.com/o/r/.github/workflows/release.yml@refs/heads/main"
+	tests := []struct {
+		name        string
+		errContains string
+		opts        verifyOptions
+		expected    []string
+	}{
+		{
+			name:     "cosign identity",
+			opts:     verifyOptions{method: signCosign, identity: identity, issuer: githubOIDCIssuer},
+			expected: []string{"verify-blob", "--bundle", "s.json.sigstore.json", "--certificate-identity", identity, "--certificate-oidc-issuer", githubOIDCIssuer, "s.json"},
+		},
+		{
+			name:     "cosign identity regexp",
+			opts:     verifyOptions{method: signCosign, identityRegexp: "^https://github.com/o/", issuer: githubOIDCIssuer},
+			expected: []string{"verify-blob", "--bundle", "s.json.sigstore.json", "--certificate-identity-regexp", "^https://github.com/o/", "--certificate-oidc-issuer", githubOIDCIssuer, "s.json"},
+		},
+		{
+			name:     "cosign key",
+			opts:     verifyOptions{method: signCosign, key: "cosign.pub", identity: identity},
+			expected: []string{"verify-blob", "--bundle", "s.json.sigstore.json", "--key", "cosign.pub", "s.json"},
+		},
+		{name: "cosign without identity", opts: verifyOptions{method: signCosign, issuer: githubOIDCIssuer}, errContains: "requires --certificate-identity"},
+		{name: "cosign both identities", opts: verifyOptions{method: signCosign, identity: "a", identityRegexp: "b"}, errContains: "mutually exclusive"},
+		{name: "minisign", opts: verifyOptions{method: signMinisign, key: "mini.pub"}, expected: []string{"-V", "-p", "mini.pub", "-m", "s.json", "-x", "s.json.minisig"}},
+		{name: "minisign without key", opts: verifyOptions{method: signMinisign}, errContains: "requires --key"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			args, err := verifyArgs(tt.opts, "s.json")
+			if tt.errContains != "" {
+				require.ErrorContains(t, err, tt.errContains)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, args)
+		})
+	}
+}
+
+func TestDetectSignMethod(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "secrets.json")
+
+	_, err := detectSignMethod(path)
+	require.ErrorContains(t, err, "no signature found")
+
+	require.NoError(t, os.WriteFile(path+".minisig", nil, 0o600))
+	method, err := detectSignMethod(path)
+	require.NoError(t, err)
+	assert.Equal(t, signMinisign, method)
+
+	require.NoError(t, os.WriteFile(path+".sigstore.json", nil, 0o600))
+	_, err = detectSignMethod(path)
+	require.ErrorContains(t, err, "multiple signatures found")
+}
+
+// fakeSignTool installs a script named like the signing tool that records its arguments in
+// the signature file, which is the last argument or the one following -x
+func fakeSignTool(t *testing.T, method string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake signing tool is a shell script")
+	}
+	dir := t.TempDir()
+	script := `#!/bin/sh
+sig=""
+prev=""
+for arg in "$@"; do
+  case "$prev" in --bundle|-x) sig="$arg" ;; esac
+  prev="$arg"
+done
+case "$1" in
+  sign-blob|-S) echo "$@" > "$sig" ;;
+  verify-blob|-V) [ -s "$sig" ] || exit 1 ;;
+esac
+echo "tool output"
+`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, method), []byte(script), 0o700)) // #nosec G306 - must be executable
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+//nolint:paralleltest // modifies environment variables and global flag variables
+func TestExportSign(t *testing.T) {
+	fakeSignTool(t, signCosign)
+	t.Setenv("GITHUB_ACTIONS", "true")
+
+	cfg := fellertest.NewConfig().Provider("gsm", fellertest.FakeGSM(t, map[string]string{"API_KEY": "abc"})).Build()
+	originalCfg, originalOut, originalSign := cfgFile, exportOut, exportSign
+	t.Cleanup(func() { cfgFile, exportOut, exportSign = originalCfg, originalOut, originalSign })
+	cfgFile = fellertest.WriteConfig(t, cfg)
+	exportOut = filepath.Join(t.TempDir(), "secrets.json")
+	exportSign = signCosign
+
+	var stdout bytes.Buffer
+	cmd := &cobra.Command{}
+	cmd.SetOut(&stdout)
+	require.NoError(t, exportSecrets(cmd, []string{"json"}))
+	assert.Empty(t, stdout.String(), "tool output must not reach stdout")
+
+	sig, err := os.ReadFile(exportOut + ".sigstore.json")
+	require.NoError(t, err)
+	assert.Contains(t, string(sig), "sign-blob --yes --bundle "+exportOut+".sigstore.json "+exportOut)
+
+	verifyCmd.SetOut(&stdout)
+	t.Cleanup(func() { verifyCmd.SetOut(nil) })
+	originalOpts := verifyOpts
+	t.Cleanup(func() { verifyOpts = originalOpts })
+	verifyOpts = verifyOptions{identity: "https://github.com/o/r", issuer: githubOIDCIssuer}
+	require.NoError(t, verifyCmd.RunE(verifyCmd, []string{exportOut}))
+	assert.Equal(t, "Verified cosign signature of "+exportOut+"\n", stdout.String())
+
+	require.NoError(t, os.WriteFile(exportOut+".sigstore.json", nil, 0o600))
+	require.ErrorContains(t, verifyCmd.RunE(verifyCmd, []string{exportOut}), "is not valid")
+}
+
+//nolint:paralleltest // modifies environment variables and global flag variables
+func TestExportSignFlagErrors(t *testing.T) {
+	originalOut, originalSign := exportOut, exportSign
+	t.Cleanup(func() { exportOut, exportSign = originalOut, originalSign })
+
+	exportSign, exportOut = signCosign, ""
+	require.ErrorContains(t, exportSecrets(&cobra.Command{}, []string{"json"}), "--sign requires --out")
+
+	exportSign, exportOut = "gpg", "secrets.json"
+	require.ErrorContains(t, exportSecrets(&cobra.Command{}, []string{"json"}), "unsupported signing method")
+
+	t.Setenv("GITHUB_ACTIONS", "")
+	exportSign = signMinisign
+	require.ErrorContains(t, exportSecrets(&cobra.Command{}, []string{"json"}), "not supported by teller fallback mode")
+}
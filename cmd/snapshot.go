@@ -0,0 +1,276 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/containifyci/feller/pkg/bundle"
+	"github.com/containifyci/feller/pkg/config"
+	"github.com/containifyci/feller/pkg/logger"
+	"github.com/containifyci/feller/pkg/providers"
+	"github.com/spf13/cobra"
+)
+
+var (
+	snapshotOut        string
+	snapshotRecipients []string
+
+	restoreTo         string
+	restoreMap        string
+	restoreIdentity   string
+	restoreRecipients []string
+	restoreOnly       []string
+	restoreExclude    []string
+	restoreDryRun     bool
+)
+
+var missingSnapshot = providers.MissingContext{
+	Command: "snapshot",
+	Prefix:  "Cannot snapshot secrets: ",
+	Step:    "Snapshot secrets",
+	Run:     "feller snapshot",
+	Hint:    "Resolve every secret before taking a snapshot, or use --silent to capture the available secrets only.",
+}
+
+// snapshotCmd represents the snapshot command
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Capture every resolved secret in an encrypted file",
+	Long: `Resolve every secret and write them to an age-encrypted snapshot, with the
+provider and map that supplied each key. The snapshot is a bundle: 'feller
+restore' writes it back into a provider, and a bundle provider can read it
+directly.
+
+--recipients lists the age public keys (age1...) or SSH public keys allowed to
+decrypt the snapshot. The file is created with 0600 permissions and replaced
+atomically. Secrets are resolved by feller itself, so providers must be of
+kinds feller supports. The age tool must be installed.
+
+Examples:
+  feller snapshot --out snap.age --recipients age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p
+  feller snapshot --out snap.age --recipients "$(cat recovery.pub)" --providers gsm`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		return runSnapshot(cmd.OutOrStdout())
+	},
+}
+
+// restoreCmd represents the restore command
+var restoreCmd = &cobra.Command{
+	Use:   "restore FILE --to PROVIDER",
+	Short: "Write the secrets of a snapshot into a provider",
+	Long: `Decrypt a snapshot written by 'feller snapshot' and write its secrets into the
+provider named by --to, for disaster-recovery drills and for cloning the
+secrets of one environment into another.
+
+The provider must be of a kind feller can write: dotenv or bundle. Secrets are
+written to the provider's only map, or the map named by --map. Maps with keys
+receive the keys they map, under their source names, so the provider resolves
+them to the same output keys; other keys are skipped and listed. Maps without
+keys receive every key that passes their include and exclude globs.
+
+dotenv files keep their other lines and are rewritten atomically with 0600
+permissions; values with line breaks cannot be written. Bundles keep their
+other secrets when they can be decrypted with the provider's identity, and are
+re-encrypted for --recipients.
+
+The snapshot is decrypted with the identity file given by --identity or the
+age identity in the FELLER_AGE_KEY environment variable. --dry-run lists the
+keys that would be written without writing them.
+
+Examples:
+  feller restore snap.age --to local --identity key.txt
+  feller restore snap.age --to staging --map app --only DATABASE_URL --dry-run
+  feller restore snap.age --to backup --recipients age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runRestore(cmd.OutOrStdout(), args[0])
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(snapshotCmd)
+	snapshotCmd.Flags().StringVar(&snapshotOut, "out", "", "File to write the snapshot to")
+	snapshotCmd.Flags().StringSliceVar(&snapshotRecipients, "recipients", nil, "age recipients allowed to decrypt the snapshot (comma-separated)")
+	_ = snapshotCmd.MarkFlagRequired("out")
+	_ = snapshotCmd.MarkFlagRequired("recipients")
+
+	rootCmd.AddCommand(restoreCmd)
+	restoreCmd.Flags().StringVar(&restoreTo, "to", "", "Provider to write the secrets into")
+	restoreCmd.Flags().StringVar(&restoreMap, "map", "", "Path map id to write to, when the provider has several")
+	restoreCmd.Flags().StringVar(&restoreIdentity, "identity", "", "age identity file that decrypts the snapshot")
+	restoreCmd.Flags().StringSliceVar(&restoreRecipients, "recipients", nil, "age recipients of the rewritten bundle (required for bundle providers)")
+	restoreCmd.Flags().StringSliceVar(&restoreOnly, "only", nil, "Only restore these keys (comma-separated)")
+	restoreCmd.Flags().StringSliceVar(&restoreExclude, "exclude", nil, "Do not restore these keys (comma-separated)")
+	restoreCmd.Flags().BoolVar(&restoreDryRun, "dry-run", false, "List the keys that would be written without writing them")
+	_ = restoreCmd.MarkFlagRequired("to")
+	_ = restoreCmd.RegisterFlagCompletionFunc("to", completeProviderList)
+}
+
+func runSnapshot(out io.Writer) error {
+	if len(snapshotRecipients) == 0 {
+		return errors.New("snapshot requires --recipients")
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if err := checkNativeKinds(cfg); err != nil {
+		return err
+	}
+	result, err := collectSecrets(cfg)
+	if err != nil {
+		return err
+	}
+	if err := checkMissing(result, missingSnapshot, cfg.Messages); err != nil {
+		return err
+	}
+
+	data, err := bundle.Encrypt(providers.NewBundle(result.Secrets, result.Sources), snapshotRecipients)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt snapshot: %w", err)
+	}
+	if err := writeFileAtomic(snapshotOut, data, 0o600); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Captured %d secret(s) in %s\n", len(result.Secrets), snapshotOut)
+	return nil
+}
+
+func runRestore(out io.Writer, path string) error {
+	unlock, err := lockConfig("restore")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	cfg, err := config.LoadConfig(cfgFile)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	provider, ok := cfg.Providers[restoreTo]
+	if !ok {
+		return fmt.Errorf("unknown provider %q", restoreTo)
+	}
+	if provider.Kind != providers.KindBundle && provider.Kind != providers.KindDotenv {
+		return fmt.Errorf("provider %s is of kind %s, which feller cannot write (supported: %s, %s)", restoreTo, provider.Kind, providers.KindBundle, providers.KindDotenv)
+	}
+	if provider.Kind == providers.KindBundle && len(restoreRecipients) == 0 && !restoreDryRun {
+		return fmt.Errorf("provider %s is a bundle, restoring into it requires --recipients", restoreTo)
+	}
+	pathMap, err := selectMap(restoreTo, provider, restoreMap, "--map")
+	if err != nil {
+		return err
+	}
+
+	snapshot, err := providers.LoadBundle(path, restoreIdentity)
+	if err != nil {
+		return err //nolint:wrapcheck // names the snapshot
+	}
+	secrets := make(providers.SecretMap, len(snapshot.Secrets))
+	for key, entry := range snapshot.Secrets {
+		secrets[key] = entry.Value
+	}
+	restored, skipped := providers.RestoreKeys(pathMap, filterSecrets(secrets, restoreOnly, restoreExclude))
+	if len(skipped) > 0 {
+		logger.Info("Skipping %d key(s) map %s does not read: %s", len(skipped), pathMap.ID, strings.Join(skipped, ", "))
+	}
+	if len(restored) == 0 {
+		return fmt.Errorf("snapshot %s holds no key map %s of provider %s reads", path, pathMap.ID, restoreTo)
+	}
+
+	keys := make([]string, 0, len(restored))
+	for key := range restored {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	target := config.LocalPath(pathMap.Path)
+	if restoreDryRun {
+		fmt.Fprintf(out, "Would write %d secret(s) to %s (provider %s, map %s):\n", len(keys), target, restoreTo, pathMap.ID)
+		for _, key := range keys {
+			fmt.Fprintf(out, "  %s\n", key)
+		}
+		return nil
+	}
+
+	if provider.Kind == providers.KindBundle {
+		err = restoreBundle(provider, target, restored)
+	} else {
+		err = restoreDotenv(target, restored)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Restored %d secret(s) to %s (provider %s, map %s)\n", len(keys), target, restoreTo, pathMap.ID)
+	return nil
+}
+
+// selectMap returns the path map of provider name with the id given by the flag or field
+// named option, which may be empty when the provider has a single map
+func selectMap(name string, provider config.Provider, id, option string) (config.PathMap, error) {
+	if id == "" {
+		if len(provider.Maps) != 1 {
+			return config.PathMap{}, fmt.Errorf("provider %s has %d maps, choose one with %s", name, len(provider.Maps), option)
+		}
+		return provider.Maps[0], nil
+	}
+	for _, pathMap := range provider.Maps {
+		if pathMap.ID == id {
+			return pathMap, nil
+		}
+	}
+	return config.PathMap{}, fmt.Errorf("provider %s has no map %q", name, id)
+}
+
+// restoreDotenv merges secrets into the dotenv file at path, creating it when missing
+func restoreDotenv(path string, secrets providers.SecretMap) error {
+	// #nosec G304 - Dotenv paths come from the user's config
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	updated, err := providers.UpdateDotenv(data, secrets)
+	if err != nil {
+		return fmt.Errorf("failed to restore into %s: %w", path, err)
+	}
+	if err := writeFileAtomic(path, updated, 0o600); err != nil {
+		return err
+	}
+	recordDotenvFile(path)
+	return nil
+}
+
+// restoreBundle merges secrets into the bundle at path, keeping its other secrets when the
+// provider's identity decrypts it, and re-encrypts it for --recipients
+func restoreBundle(provider config.Provider, path string, secrets providers.SecretMap) error {
+	merged := make(providers.SecretMap, len(secrets))
+	sources := make(map[string]providers.SecretSource)
+	if _, err := os.Stat(path); err == nil {
+		identity, err := providers.BundleIdentity(provider)
+		if err != nil {
+			return fmt.Errorf("provider %s: %w", restoreTo, err)
+		}
+		existing, err := providers.LoadBundle(path, identity)
+		if err != nil {
+			return fmt.Errorf("cannot keep the other secrets of %s: %w", path, err)
+		}
+		for key, entry := range existing.Secrets {
+			merged[key] = entry.Value
+			sources[key] = providers.SecretSource{Provider: entry.Provider, Kind: entry.Kind, MapID: entry.MapID}
+		}
+	}
+	for key, value := range secrets {
+		merged[key] = value
+		delete(sources, key)
+	}
+
+	data, err := bundle.Encrypt(providers.NewBundle(merged, sources), restoreRecipients)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt bundle: %w", err)
+	}
+	return writeFileAtomic(path, data, 0o600)
+}
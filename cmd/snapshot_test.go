@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/containifyci/feller/pkg/config"
+	"github.com/containifyci/feller/pkg/fellertest"
+	"github.com/containifyci/feller/pkg/providers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//nolint:paralleltest // modifies environment variables and global flag variables
+func TestSnapshotRestore(t *testing.T) {
+	fellertest.FakeAge(t)
+	t.Setenv("GITHUB_ACTIONS", "true")
+	t.Cleanup(func() {
+		cfgFile, snapshotOut, snapshotRecipients = "", "", nil
+		restoreTo, restoreMap, restoreIdentity, restoreRecipients, restoreDryRun = "", "", "", nil, false
+	})
+
+	dir := t.TempDir()
+	source := fellertest.NewConfig().Provider("gsm", fellertest.FakeGSM(t, map[string]string{"API_KEY": "abc", "DB_URL": "postgres://db"})).Build()
+	cfgFile = fellertest.WriteConfig(t, source)
+	snapshotOut = filepath.Join(dir, "snap.age")
+	snapshotRecipients = []string{fellertest.FakeAgeRecipient}
+
+	var out bytes.Buffer
+	require.NoError(t, runSnapshot(&out))
+	assert.Equal(t, "Captured 2 secret(s) in "+snapshotOut+"\n", out.String())
+	info, err := os.Stat(snapshotOut)
+	require.NoError(t, err)
+	if os.PathSeparator == '/' {
+		assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+	}
+
+	// The target environment reads API_KEY from a differently named dotenv key
+	envPath := filepath.Join(dir, ".env")
+	require.NoError(t, os.WriteFile(envPath, []byte("# staging\napi_key=old\nOTHER=kept\n"), 0o600))
+	local := config.Provider{Kind: providers.KindDotenv, Maps: []config.PathMap{{ID: "app", Path: envPath, Keys: map[string]string{"api_key": "API_KEY", "OTHER": "OTHER"}}}}
+	backup := fellertest.FakeBundle(t, map[string]string{"KEEP": "me", "API_KEY": "stale"})
+	target := fellertest.NewConfig().Provider("local", local).Provider("backup", backup).Provider("gsm", fellertest.MissingGSM(t, "API_KEY")).Build()
+	cfgFile = fellertest.WriteConfig(t, target)
+	restoreIdentity = filepath.Join(dir, "key.txt")
+	require.NoError(t, os.WriteFile(restoreIdentity, []byte("AGE-SECRET-KEY-1\n"), 0o600))
+
+	restoreTo, restoreDryRun = "local", true
+	out.Reset()
+	require.NoError(t, runRestore(&out, snapshotOut))
+	assert.Equal(t, "Would write 1 secret(s) to "+envPath+" (provider local, map app):\n  api_key\n", out.String())
+
+	restoreDryRun = false
+	out.Reset()
+	require.NoError(t, runRestore(&out, snapshotOut))
+	assert.Equal(t, "Restored 1 secret(s) to "+envPath+" (provider local, map app)\n", out.String())
+	data, err := os.ReadFile(envPath)
+	require.NoError(t, err)
+	assert.Equal(t, "# staging\napi_key=\"abc\"\nOTHER=kept\n", string(data))
+
+	restoreTo = "backup"
+	err = runRestore(&out, snapshotOut)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "requires --recipients")
+
+	restoreRecipients = []string{fellertest.FakeAgeRecipient}
+	require.NoError(t, runRestore(&out, snapshotOut))
+	b, err := providers.LoadBundle(backup.Maps[0].Path, restoreIdentity)
+	require.NoError(t, err)
+	assert.Len(t, b.Secrets, 3)
+	assert.Equal(t, "abc", b.Secrets["API_KEY"].Value)
+	assert.Equal(t, "me", b.Secrets["KEEP"].Value)
+
+	restoreTo = "gsm"
+	err = runRestore(&out, snapshotOut)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "which feller cannot write")
+
+	restoreTo = "missing"
+	err = runRestore(&out, snapshotOut)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `unknown provider "missing"`)
+}
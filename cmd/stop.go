@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/containifyci/feller/pkg/logger"
+	"github.com/spf13/cobra"
+)
+
+var stopAll bool
+
+// stopCmd represents the stop command
+var stopCmd = &cobra.Command{
+	Use:   "stop [name...]",
+	Short: "Stop processes started with feller run --detach",
+	Long: `Stop background processes started with 'feller run --detach' and remove
+their pidfiles. Log files are kept for inspection.
+
+Examples:
+  feller stop api
+  feller stop --all`,
+	RunE: stopProcesses,
+}
+
+func init() {
+	rootCmd.AddCommand(stopCmd)
+	stopCmd.Flags().BoolVar(&stopAll, "all", false, "Stop all detached processes")
+}
+
+func stopProcesses(_ *cobra.Command, args []string) error {
+	names := args
+	if stopAll {
+		processes, err := listDetachedProcesses()
+		if err != nil {
+			return err
+		}
+		names = make([]string, 0, len(processes))
+		for _, process := range processes {
+			names = append(names, process.Name)
+		}
+	}
+
+	if len(names) == 0 && !stopAll {
+		return errors.New("specify at least one process name or use --all")
+	}
+
+	for _, name := range names {
+		if err := stopDetachedProcess(name); err != nil {
+			return err
+		}
+		logger.Verbose("Stopped detached process: %s", name)
+		fmt.Printf("Stopped %s\n", name)
+	}
+	return nil
+}
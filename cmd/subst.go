@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/containifyci/feller/pkg/logger"
+	"github.com/containifyci/feller/pkg/providers"
+	"github.com/spf13/cobra"
+)
+
+// Handling of placeholders whose key is not resolved
+const (
+	substMissingError = "error"
+	substMissingEmpty = "empty"
+	substMissingKeep  = "keep"
+)
+
+var substMissingModes = []string{substMissingError, substMissingEmpty, substMissingKeep}
+
+var substMissing string
+
+// substPattern matches {{ .KEY }} and ${KEY} placeholders
+var substPattern = regexp.MustCompile(`\{\{\s*\.([A-Za-z_][A-Za-z0-9_]*)\s*\}\}|\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+var missingSubst = providers.MissingContext{
+	Command: "subst",
+	Prefix:  "Cannot substitute secrets: ",
+	Step:    "Render with secrets",
+	Run:     "feller subst < app.conf.tmpl > app.conf",
+	Hint:    "Or use --silent flag to substitute only available secrets.",
+}
+
+// substCmd represents the subst command
+var substCmd = &cobra.Command{
+	Use:   "subst",
+	Short: "Replace secret placeholders in a stream read from stdin",
+	Long: `Read text from stdin and write it with every {{ .KEY }} and ${KEY}
+placeholder replaced by the value of the secret KEY.
+
+Other text, including $KEY without braces and templates that are not a
+single key such as {{ .Values.name }}, is copied unchanged. --missing
+decides what happens to placeholders whose key is not resolved:
+  error - fail without writing any output (default)
+  empty - replace them with an empty string
+  keep  - leave them in the output unchanged
+
+Use -o/--out to write the result atomically to a file with 0600 permissions
+instead of stdout.
+
+Examples:
+  feller subst < app.conf.tmpl > app.conf
+  feller subst --missing keep -o config.yaml < config.yaml.tmpl`,
+	Args: cobra.NoArgs,
+	RunE: substSecrets,
+}
+
+func init() {
+	rootCmd.AddCommand(substCmd)
+	addOutputFlag(substCmd)
+	substCmd.Flags().StringVar(&substMissing, "missing", substMissingError, "Handling of placeholders with unresolved keys (error, empty, keep)")
+	_ = substCmd.RegisterFlagCompletionFunc("missing", cobra.FixedCompletions(substMissingModes, cobra.ShellCompDirectiveNoFileComp))
+}
+
+func substSecrets(cmd *cobra.Command, _ []string) error {
+	if !slices.Contains(substMissingModes, substMissing) {
+		return fmt.Errorf("unsupported --missing mode: %s (expected %s)", substMissing, strings.Join(substMissingModes, ", "))
+	}
+
+	native, err := resolveNatively()
+	if err != nil {
+		return err
+	}
+	if !native {
+		return errors.New("feller subst is not supported by teller fallback mode")
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	result, err := collectSecrets(cfg)
+	if err != nil {
+		return err
+	}
+	if err := checkMissing(result, missingSubst, cfg.Messages); err != nil {
+		return err
+	}
+
+	input, err := io.ReadAll(cmd.InOrStdin())
+	if err != nil {
+		return fmt.Errorf("failed to read stdin: %w", err)
+	}
+	output, err := substitute(input, result.Secrets, substMissing)
+	if err != nil {
+		return err
+	}
+	return writeOutput(cmd.OutOrStdout(), exportOut, output)
+}
+
+// substitute replaces the placeholders in input with the values of secrets, handling
+// unresolved keys according to missing
+func substitute(input []byte, secrets providers.SecretMap, missing string) ([]byte, error) {
+	var unknown []string
+	output := substPattern.ReplaceAllFunc(input, func(placeholder []byte) []byte {
+		match := substPattern.FindSubmatch(placeholder)
+		key := string(match[1]) + string(match[2])
+		if value, ok := secrets[key]; ok {
+			return []byte(value)
+		}
+		switch missing {
+		case substMissingKeep:
+			return placeholder
+		case substMissingEmpty:
+			return nil
+		}
+		if !slices.Contains(unknown, key) {
+			unknown = append(unknown, key)
+		}
+		return placeholder
+	})
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("input references unknown secrets: %s (use --missing empty or keep to allow them)", strings.Join(unknown, ", "))
+	}
+	logger.Debug("Substituted placeholders in %d bytes of input", len(input))
+	return output, nil
+}
@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/containifyci/feller/pkg/fellertest"
+	"github.com/containifyci/feller/pkg/providers"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubstitute(t *testing.T) {
+	t.Parallel()
+	secrets := providers.SecretMap{"DB_URL": "postgres://db", "TOKEN": "t$k", "EMPTY": ""}
+	input := "url={{ .DB_URL }}\ntoken=${TOKEN}{{.EMPTY}}\nhome=$HOME ${HOME} {{ .Values.name }} {{ .MISSING }}\n"
+
+	tests := []struct {
+		name        string
+		missing     string
+		expected    string
+		errContains string
+	}{
+		{
+			name:        "error",
+			missing:     substMissingError,
+			errContains: "input references unknown secrets: HOME, MISSING",
+		},
+		{
+			name:     "empty",
+			missing:  substMissingEmpty,
+			expected: "url=postgres://db\ntoken=t$k\nhome=$HOME  {{ .Values.name }} \n",
+		},
+		{
+			name:     "keep",
+			missing:  substMissingKeep,
+			expected: "url=postgres://db\ntoken=t$k\nhome=$HOME ${HOME} {{ .Values.name }} {{ .MISSING }}\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := substitute([]byte(input), secrets, tt.missing)
+			if tt.errContains != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errContains)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, string(got))
+		})
+	}
+
+	t.Run("values are not substituted again", func(t *testing.T) {
+		t.Parallel()
+		got, err := substitute([]byte("${A}"), providers.SecretMap{"A": "${B}", "B": "b"}, substMissingError)
+		require.NoError(t, err)
+		assert.Equal(t, "${B}", string(got))
+	})
+}
+
+//nolint:paralleltest // sets environment variables and global flag variables
+func TestSubstSecrets(t *testing.T) {
+	t.Setenv("GITHUB_ACTIONS", "true")
+	originalCfg, originalOut, originalMissing := cfgFile, exportOut, substMissing
+	t.Cleanup(func() { cfgFile, exportOut, substMissing = originalCfg, originalOut, originalMissing })
+	cfgFile = fellertest.WriteConfig(t, fellertest.NewConfig().Provider("gsm", fellertest.FakeGSM(t, map[string]string{"API_KEY": "k"})).Build())
+	exportOut = ""
+
+	run := func(input string) (string, error) {
+		var stdout bytes.Buffer
+		cmd := &cobra.Command{}
+		cmd.SetIn(strings.NewReader(input))
+		cmd.SetOut(&stdout)
+		err := substSecrets(cmd, nil)
+		return stdout.String(), err
+	}
+
+	substMissing = substMissingError
+	out, err := run("key: {{ .API_KEY }}\n")
+	require.NoError(t, err)
+	assert.Equal(t, "key: k\n", out)
+
+	out, err = run("key: ${API_KEY} ${OTHER}\n")
+	require.ErrorContains(t, err, "unknown secrets: OTHER")
+	assert.Empty(t, out)
+
+	substMissing = "drop"
+	_, err = run("")
+	require.ErrorContains(t, err, "unsupported --missing mode: drop")
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/containifyci/feller/pkg/i18n"
+	"github.com/containifyci/feller/pkg/providers"
+)
+
+// stepSummaryEnv names the file GitHub Actions renders as the job summary
+const stepSummaryEnv = "GITHUB_STEP_SUMMARY"
+
+var printSummary bool
+
+// keyChecksum returns a checksum of the sorted key names, so two environments can be compared
+// without revealing any value
+func keyChecksum(secrets providers.SecretMap) string {
+	keys := make([]string, 0, len(secrets))
+	for key := range secrets {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	sum := sha256.Sum256([]byte(strings.Join(keys, "\n")))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// reportSummary prints the number of keys and their checksum to stderr, and appends them to
+// the job summary when running in GitHub Actions
+func reportSummary(stderr io.Writer, secrets providers.SecretMap) error {
+	checksum := keyChecksum(secrets)
+	fmt.Fprintln(stderr, i18n.Sprintf("feller: resolved %d key(s), key checksum %s", len(secrets), checksum))
+
+	path := os.Getenv(stepSummaryEnv)
+	if path == "" {
+		return nil
+	}
+	// #nosec G304 - Path is provided by the GitHub Actions runner
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open step summary: %w", err)
+	}
+	_, err = fmt.Fprintf(f, "### feller\n\n| Keys | Key checksum |\n| --- | --- |\n| %d | `%s` |\n\n", len(secrets), checksum)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write step summary: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/containifyci/feller/pkg/providers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyChecksum(t *testing.T) {
+	t.Parallel()
+	a := keyChecksum(providers.SecretMap{"API_KEY": "one", "DATABASE_URL": "two"})
+	b := keyChecksum(providers.SecretMap{"DATABASE_URL": "changed", "API_KEY": "values"})
+	c := keyChecksum(providers.SecretMap{"API_KEY": "one"})
+
+	assert.Equal(t, a, b, "the checksum covers key names only")
+	assert.NotEqual(t, a, c)
+	assert.Equal(t, "sha256:e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855", keyChecksum(nil))
+}
+
+//nolint:paralleltest // modifies environment variables
+func TestReportSummary(t *testing.T) {
+	stepSummary := filepath.Join(t.TempDir(), "summary.md")
+	require.NoError(t, os.WriteFile(stepSummary, []byte("previous\n"), 0o600))
+	t.Setenv(stepSummaryEnv, stepSummary)
+
+	secrets := providers.SecretMap{"API_KEY": "abc"}
+	checksum := keyChecksum(secrets)
+
+	var stderr bytes.Buffer
+	require.NoError(t, reportSummary(&stderr, secrets))
+	assert.Equal(t, "feller: resolved 1 key(s), key checksum "+checksum+"\n", stderr.String())
+
+	data, err := os.ReadFile(stepSummary)
+	require.NoError(t, err)
+	assert.Equal(t, "previous\n### feller\n\n| Keys | Key checksum |\n| --- | --- |\n| 1 | `"+checksum+"` |\n\n", string(data))
+}
+
+//nolint:paralleltest // modifies global flag variables and environment variables
+func TestSummaryFallback(t *testing.T) {
+	t.Setenv("GITHUB_ACTIONS", "")
+	original := printSummary
+	t.Cleanup(func() { printSummary = original })
+	printSummary = true
+
+	require.ErrorContains(t, exportSecrets(exportCmd, []string{"json"}), "--summary is not supported by teller fallback mode")
+}
@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/containifyci/feller/pkg/config"
+	"github.com/containifyci/feller/pkg/reconcile"
+	"github.com/containifyci/feller/pkg/state"
+	"github.com/spf13/cobra"
+)
+
+var (
+	syncDryRun   bool
+	syncRollback string
+	syncIdentity string
+)
+
+// syncCmd represents the sync command
+var syncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Write the secrets to every target of the config",
+	Long: `Fan the secrets of the config out to its targets: GitHub repositories,
+Vercel projects, Cloudflare Workers and Fly.io apps, each under the name the
+key has there. Keys listed in the sync section are written to their targets
+only, under the name given per target (the key itself when empty); the
+providers and keys of each target select the other keys:
+
+  targets:
+    ci:  {kind: github, repo: owner/repo}
+    web: {kind: vercel, project: web, environments: [production, preview]}
+  sync:
+    API_KEY:
+      ci: ""                      # GitHub secret API_KEY
+      web: NEXT_PUBLIC_API_KEY    # Vercel variable NEXT_PUBLIC_API_KEY
+
+Only secrets that are missing from a target or changed since feller wrote
+them are written, as by 'feller reconcile', which sync runs once across all
+targets. Vercel targets need a token in VERCEL_TOKEN, Cloudflare targets in
+CLOUDFLARE_API_TOKEN and Fly.io targets in FLY_API_TOKEN; GitHub targets use the
+GitHub CLI (gh), with the token of --token-stdin when given. --dry-run reports
+what would be written.
+
+Before writing, every sync records the state of the secrets it changes in
+.feller/sync next to the config and prints the id of the run. --rollback with
+that id restores the state before the run after a bad deployment of secrets:
+secrets the run created are deleted, once confirmed with --confirm or
+--force-destructive, and the others get their previous values back. Previous values are kept encrypted to the age recipients of the backups
+section of the config and decrypted with --identity, the identity of that
+section or FELLER_AGE_KEY; without recipients only created secrets can be
+rolled back. Vercel returns the values of variables that are not sensitive,
+so they are recorded as they were. GitHub, Cloudflare and Fly.io never return
+secret values, so their runs record digests instead, and a previous value is
+restored when an earlier recorded sync wrote it. The fingerprints of the restored secrets are
+forgotten, so the next sync writes the config values again. Only the newest
+retention runs of the backups section (10 by default) are kept.
+
+--notify-webhook sends a summary of the created, updated and deleted secrets
+of a sync or rollback to a webhook, as generic JSON or, with --notify-format
+slack, as a Slack message.
+
+Examples:
+  feller sync
+  feller sync --dry-run
+  feller sync --target web
+  feller sync --rollback 20261016T101500Z --dry-run
+  feller sync --rollback 20261016T101500Z --identity key.txt --confirm ci
+  feller sync --notify-webhook "$SLACK_WEBHOOK_URL" --notify-format slack`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		if err := loadGitHubToken(cmd.InOrStdin()); err != nil {
+			return err
+		}
+		return runSync(cmd.InOrStdin(), cmd.OutOrStdout())
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(syncCmd)
+	syncCmd.Flags().BoolVar(&syncDryRun, "dry-run", false, "Report the secrets that would be written without writing them")
+	syncCmd.Flags().StringSliceVar(&reconcileTargets, "target", nil, "Only sync these targets (comma-separated names)")
+	syncCmd.Flags().StringVar(&syncRollback, "rollback", "", "Restore the state of the targets before the sync run with this id")
+	syncCmd.Flags().StringVar(&syncIdentity, "identity", "", "age identity file that decrypts the values recorded by the run, with --rollback")
+	syncCmd.Flags().BoolVar(&planJSON, "json", false, "Print the plan of --rollback --dry-run as JSON")
+	addDestructiveFlags(syncCmd)
+	addNotifyFlags(syncCmd)
+	addTokenStdinFlag(syncCmd)
+	_ = syncCmd.RegisterFlagCompletionFunc("rollback", completeSyncRuns)
+}
+
+// completeSyncRuns completes the ids of the recorded sync runs, newest first
+func completeSyncRuns(_ *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+	configPath, err := config.ResolveConfigPath(cfgFile)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	ids, _ := state.SyncRuns(state.Dir(configPath))
+	slices.Reverse(ids)
+	return ids, cobra.ShellCompDirectiveNoFileComp
+}
+
+// runSync writes the drifted secrets of every selected target once, recording their state
+// before, and fails when a target failed. With --rollback it restores the state before a run.
+func runSync(in io.Reader, out io.Writer) error {
+	if err := validateNotifyFlags(); err != nil {
+		return err
+	}
+	if syncRollback != "" {
+		return runSyncRollback(in, out, syncRollback)
+	}
+	if planJSON {
+		return errors.New("--json requires --rollback and --dry-run")
+	}
+
+	reconcileCheck = syncDryRun
+	if !syncDryRun {
+		recorder, err := newSyncRecorder()
+		if err != nil {
+			return err
+		}
+		syncRecord = recorder
+		defer func() { syncRecord = nil }()
+	}
+	results, err := reconcileRun(out)
+	if syncRecord != nil && syncRecord.saved {
+		fmt.Fprintf(out, "Recorded sync run %s, roll it back with: feller sync --rollback %s\n", syncRecord.run.ID, syncRecord.run.ID)
+	}
+	if len(results) > 0 {
+		sendNotification(syncNotification(results))
+	}
+	if err != nil {
+		return err
+	}
+	var failed int
+	for _, result := range results {
+		if result.Err != nil {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to sync %d target(s)", failed)
+	}
+	return nil
+}
+
+// syncNotification summarizes the secrets a sync wrote. Targets stop at their first failed
+// write, so the drift after it is skipped; dry runs count what would be written.
+func syncNotification(results []reconcile.Result) NotificationPayload {
+	n := NotificationPayload{Operation: "sync", DryRun: syncDryRun}
+	for _, r := range results {
+		n.Targets = append(n.Targets, r.Target)
+		for i, drift := range r.Drift {
+			outcome := notifySkipped
+			switch {
+			case syncDryRun || i < r.Fixed:
+				outcome = notifyUpdated
+				if drift.Status == reconcile.StatusMissing {
+					outcome = notifyCreated
+				}
+			case r.Err != nil && i == r.Fixed:
+				outcome = notifyFailed
+			}
+			n.count(r.Target+" "+drift.Scope, drift.Key, outcome)
+		}
+	}
+	return n
+}
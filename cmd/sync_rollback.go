@@ -0,0 +1,370 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/containifyci/feller/pkg/bundle"
+	"github.com/containifyci/feller/pkg/config"
+	"github.com/containifyci/feller/pkg/logger"
+	"github.com/containifyci/feller/pkg/plan"
+	"github.com/containifyci/feller/pkg/providers"
+	"github.com/containifyci/feller/pkg/reconcile"
+	"github.com/containifyci/feller/pkg/state"
+)
+
+// syncValues are the values of the secrets a sync run wrote, stored encrypted in its record:
+// the values before the run where the target returns them, and the values written. Both are
+// keyed by syncValueKey.
+type syncValues struct {
+	Previous map[string]string `json:"previous,omitempty"`
+	Written  map[string]string `json:"written"`
+}
+
+// syncValueKey identifies the secret of a change in syncValues
+func syncValueKey(c state.SyncChange) string {
+	return c.Target + "/" + c.Scope + "/" + c.Key
+}
+
+// syncRecorder records the state of the targets before feller sync writes them. It is nil
+// outside feller sync.
+type syncRecorder struct {
+	dir     string
+	backups config.Backups
+	run     *state.SyncRun // Started by the first capture, which holds the config lock
+	values  syncValues
+	saved   bool
+}
+
+// syncRecord is the recorder of the running feller sync
+var syncRecord *syncRecorder
+
+// newSyncRecorder starts the record of a sync run of the config
+func newSyncRecorder() (*syncRecorder, error) {
+	cfg, err := config.LoadConfig(cfgFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	configPath, err := config.ResolveConfigPath(cfgFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find config: %w", err)
+	}
+	return &syncRecorder{
+		dir:     state.Dir(configPath),
+		backups: cfg.Backups,
+		values:  syncValues{Previous: make(map[string]string), Written: make(map[string]string)},
+	}, nil
+}
+
+// capture records the state of the drifted secrets of the target name before they are
+// written and saves the record, so a failed save stops the writes. Targets that return values
+// have them recorded; for the others the digest of the value feller uploaded last stands in.
+func (s *syncRecorder) capture(name string, target config.Target, scopes []targetScope, drift []reconcile.Drift, desired *providers.CollectionResult) error {
+	if s.run == nil {
+		s.run = state.NewSyncRun(s.dir, time.Now())
+	}
+	read := make(map[string]map[string]string)
+	for _, scope := range scopes {
+		if scope.Read == nil {
+			continue
+		}
+		values, err := scope.Read()
+		if err != nil {
+			return fmt.Errorf("failed to record the state before the sync: %w", err)
+		}
+		read[scope.Name] = values
+	}
+
+	for _, d := range drift {
+		label := planTarget(target) + " (" + d.Scope + ")"
+		value := desired.Secrets[d.Key]
+		c := state.SyncChange{Target: name, Scope: d.Scope, Key: d.Key, Existed: d.Status != reconcile.StatusMissing, Written: uploads.Digest(label, d.Key, value)}
+		if values, ok := read[d.Scope]; ok {
+			if previous, set := values[d.Key]; set {
+				c.Existed, c.Previous = true, uploads.Digest(label, d.Key, previous)
+				s.values.Previous[syncValueKey(c)] = previous
+			}
+		} else if c.Existed {
+			c.Previous = uploads.Recorded(label, d.Key)
+		}
+		s.values.Written[syncValueKey(c)] = value
+		s.run.Changes = append(s.run.Changes, c)
+	}
+	return s.save()
+}
+
+// save writes the record, with the values encrypted to the backup recipients. Without
+// recipients only names and digests are recorded, so only created secrets can be rolled back.
+func (s *syncRecorder) save() error {
+	if s.backups.Enabled() {
+		plaintext, err := json.Marshal(s.values)
+		if err != nil {
+			return fmt.Errorf("failed to encode sync run: %w", err)
+		}
+		ciphertext, err := bundle.Seal(plaintext, s.backups.Recipients)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt sync run: %w", err)
+		}
+		s.run.Values = string(ciphertext)
+	} else if !s.saved {
+		logger.Info("Not recording secret values of sync run %s: set backups.recipients in the config to roll back changed secrets", s.run.ID)
+	}
+	if err := state.SaveSyncRun(s.dir, s.run, s.backups.Keep()); err != nil {
+		return fmt.Errorf("failed to record sync run, the target was not written: %w", err)
+	}
+	s.saved = true
+	return nil
+}
+
+// runSyncRollback restores the secrets the sync run id changed to their state before it:
+// secrets it created are deleted, once the deletion is confirmed, and the previous values of
+// the others are written back
+func runSyncRollback(in io.Reader, out io.Writer, id string) error {
+	if len(reconcileTargets) > 0 {
+		return errors.New("--target cannot be used with --rollback, a run is rolled back as a whole")
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	configPath, err := config.ResolveConfigPath(cfgFile)
+	if err != nil {
+		return fmt.Errorf("failed to resolve config path: %w", err)
+	}
+	dir := state.Dir(configPath)
+
+	// Rolling back changes shared state, so only one process may do it per config
+	if !syncDryRun {
+		unlock, err := lockConfig("sync")
+		if err != nil {
+			return err
+		}
+		defer unlock()
+		if err := loadUploads(); err != nil {
+			return err
+		}
+		defer saveUploads()
+	}
+
+	run, err := state.LoadSyncRun(dir, id)
+	if err != nil {
+		return err //nolint:wrapcheck // names the run
+	}
+	identity := syncIdentity
+	if identity == "" && cfg.Backups.Identity != "" {
+		identity = config.LocalPath(cfg.Backups.Identity)
+	}
+	previous, err := syncPreviousValues(dir, run, identity)
+	if err != nil {
+		return err
+	}
+
+	p := plan.New("sync rollback")
+	var unknown []string
+	for _, c := range run.Changes {
+		target, ok := cfg.Targets[c.Target]
+		if !ok {
+			return fmt.Errorf("sync run %s wrote to target %s, which is no longer in the config", id, c.Target)
+		}
+		change := plan.Change{Action: plan.ActionDelete, Target: planTarget(target), Scope: c.Scope, Key: c.Key, Reason: "created by the run"}
+		if c.Existed {
+			if _, ok := previous[syncValueKey(c)]; !ok {
+				unknown = append(unknown, fmt.Sprintf("%s (%s) of %s", c.Key, c.Scope, c.Target))
+			}
+			change.Action, change.Reason = plan.ActionUpdate, "previous value"
+		}
+		p.Add(change)
+	}
+	if len(unknown) > 0 {
+		return fmt.Errorf("cannot roll back sync run %s, the previous values of %s are unknown: "+
+			"they are only recorded when backups.recipients is set, and GitHub secrets must have been written by an earlier recorded sync",
+			id, strings.Join(unknown, ", "))
+	}
+	if err := writePlan(out, p); err != nil {
+		return err
+	}
+	n := NotificationPayload{Operation: "sync rollback", DryRun: syncDryRun}
+	for _, c := range run.Changes {
+		if !slices.Contains(n.Targets, c.Target) {
+			n.Targets = append(n.Targets, c.Target)
+		}
+	}
+	slices.Sort(n.Targets)
+	if syncDryRun {
+		if !planJSON {
+			fmt.Fprintln(out, "Dry run, nothing was changed")
+		}
+		for _, c := range run.Changes {
+			outcome := notifyDeleted
+			if c.Existed {
+				outcome = notifyUpdated
+			}
+			n.count(c.Target+" "+c.Scope, c.Key, outcome)
+		}
+		sendNotification(n)
+		return nil
+	}
+
+	byTarget := make(map[string][]state.SyncChange)
+	var deleting []string
+	var deletions int
+	for _, c := range run.Changes {
+		byTarget[c.Target] = append(byTarget[c.Target], c)
+		if !c.Existed {
+			deletions++
+			if !slices.Contains(deleting, c.Target) {
+				deleting = append(deleting, c.Target)
+			}
+		}
+	}
+	slices.Sort(deleting)
+	if err := confirmDestructive(in, out, deleting, deletions); err != nil {
+		return err
+	}
+	for _, name := range slices.Sorted(maps.Keys(byTarget)) {
+		if err := rollbackTarget(name, cfg.Targets[name], byTarget[name], previous, reconcile.Path(configPath, name), &n); err != nil {
+			sendNotification(n)
+			return fmt.Errorf("failed to roll back target %s: %w", name, err)
+		}
+	}
+	sendNotification(n)
+	if err := state.RemoveSyncRun(dir, id); err != nil {
+		return fmt.Errorf("failed to remove rolled back sync run: %w", err)
+	}
+	fmt.Fprintf(out, "Rolled back %d secret(s) of sync run %s\n", len(run.Changes), id)
+	return nil
+}
+
+// rollbackTarget restores the secrets a sync run changed in target, recording them in the
+// audit log and counting them in n, and forgets their fingerprints at recordPath, so the next
+// sync or reconcile writes them again
+func rollbackTarget(name string, target config.Target, changes []state.SyncChange, previous map[string]string, recordPath string, n *NotificationPayload) error {
+	if err := checkTarget(name, target); err != nil {
+		return err
+	}
+	scopes, err := targetScopes(target)
+	if err != nil {
+		return err
+	}
+	byName := make(map[string]targetScope, len(scopes))
+	for _, scope := range scopes {
+		byName[scope.Name] = scope
+	}
+
+	// The audit log gets the secrets restored so far even when a later one fails
+	restored := make(map[string][]string)
+	deleted := make(map[string][]string)
+	defer func() {
+		for _, scope := range slices.Sorted(maps.Keys(restored)) {
+			recordAudit(state.AuditEntry{Command: "sync rollback", Provider: name, Map: scope, Target: planTarget(target), Keys: restored[scope]})
+		}
+		for _, scope := range slices.Sorted(maps.Keys(deleted)) {
+			recordAudit(state.AuditEntry{Command: "sync rollback delete", Provider: name, Map: scope, Target: planTarget(target), Keys: deleted[scope]})
+		}
+	}()
+	for _, c := range changes {
+		scope, ok := byName[c.Scope]
+		switch {
+		case !ok:
+			return fmt.Errorf("target no longer has the %s scope of %s", c.Scope, c.Key)
+		case c.Existed:
+			if err := scope.Set(c.Key, previous[syncValueKey(c)]); err != nil {
+				n.count(name+" "+c.Scope, c.Key, notifyFailed)
+				return err
+			}
+			restored[c.Scope] = append(restored[c.Scope], c.Key)
+			n.count(name+" "+c.Scope, c.Key, notifyUpdated)
+		case scope.Existing[c.Key]:
+			if err := scope.Delete(c.Key); err != nil {
+				n.count(name+" "+c.Scope, c.Key, notifyFailed)
+				return err
+			}
+			deleted[c.Scope] = append(deleted[c.Scope], c.Key)
+			n.count(name+" "+c.Scope, c.Key, notifyDeleted)
+		}
+	}
+
+	recorded, err := reconcile.LoadRecorded(recordPath)
+	if err != nil || recorded == nil {
+		return err //nolint:wrapcheck // names the file
+	}
+	for _, c := range changes {
+		delete(recorded.Secrets, c.Key)
+	}
+	return recorded.Write(recordPath) //nolint:wrapcheck // names the file
+}
+
+// syncPreviousValues returns the values the secrets of run had before it, by syncValueKey.
+// Values read from the target are recorded in the run itself. GitHub never returns values, so
+// its secrets get the value an earlier recorded run wrote, when its digest is the one the
+// secret had before the run.
+func syncPreviousValues(dir string, run *state.SyncRun, identity string) (map[string]string, error) {
+	values, err := openSyncValues(run, identity)
+	if err != nil {
+		return nil, err
+	}
+	previous := maps.Clone(values.Previous)
+	if previous == nil {
+		previous = make(map[string]string)
+	}
+
+	var wanted []state.SyncChange
+	for _, c := range run.Changes {
+		if _, ok := previous[syncValueKey(c)]; !ok && c.Existed && c.Previous != "" {
+			wanted = append(wanted, c)
+		}
+	}
+	if len(wanted) == 0 {
+		return previous, nil
+	}
+	ids, err := state.SyncRuns(dir)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // already describes the failure
+	}
+	for i := len(ids) - 1; i >= 0 && len(wanted) > 0; i-- {
+		if ids[i] >= run.ID {
+			continue
+		}
+		earlier, err := state.LoadSyncRun(dir, ids[i])
+		if err != nil {
+			return nil, err //nolint:wrapcheck // names the run
+		}
+		earlierValues, err := openSyncValues(earlier, identity)
+		if err != nil {
+			return nil, err
+		}
+		wanted = slices.DeleteFunc(wanted, func(c state.SyncChange) bool {
+			for _, e := range earlier.Changes {
+				value, ok := earlierValues.Written[syncValueKey(e)]
+				if ok && syncValueKey(e) == syncValueKey(c) && e.Written == c.Previous {
+					previous[syncValueKey(c)] = value
+					return true
+				}
+			}
+			return false
+		})
+	}
+	return previous, nil
+}
+
+// openSyncValues decrypts the values recorded in run, which are empty when none were
+func openSyncValues(run *state.SyncRun, identity string) (*syncValues, error) {
+	var values syncValues
+	if run.Values == "" {
+		return &values, nil
+	}
+	plaintext, err := bundle.Open([]byte(run.Values), identity)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt sync run %s: %w", run.ID, err)
+	}
+	if err := json.Unmarshal(plaintext, &values); err != nil {
+		return nil, fmt.Errorf("failed to parse sync run %s: %w", run.ID, err)
+	}
+	return &values, nil
+}
@@ -0,0 +1,275 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/containifyci/feller/pkg/config"
+	"github.com/containifyci/feller/pkg/fellertest"
+	"github.com/containifyci/feller/pkg/metrics"
+	"github.com/containifyci/feller/pkg/reconcile"
+	"github.com/containifyci/feller/pkg/state"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeVercel serves the env endpoints of the Vercel API for project web of team team_1,
+// recording the variables written and upserting them by key
+type fakeVercel struct {
+	mu      sync.Mutex
+	envs    []map[string]any
+	written []map[string]any
+	ids     int
+}
+
+func (f *fakeVercel) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if r.Header.Get("Authorization") != "Bearer vercel-token" || r.URL.Query().Get("teamId") != "team_1" {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"error":{"message":"forbidden"}}`))
+		return
+	}
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/v9/projects/web/env":
+		_ = json.NewEncoder(w).Encode(map[string]any{"envs": f.envs})
+	case r.Method == http.MethodPost && r.URL.Path == "/v10/projects/web/env" && r.URL.Query().Get("upsert") == "true":
+		var env map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&env)
+		f.written = append(f.written, env)
+		f.ids++
+		stored := maps.Clone(env)
+		stored["id"] = fmt.Sprintf("env_%d", f.ids)
+		f.envs = append(slices.DeleteFunc(f.envs, func(e map[string]any) bool { return e["key"] == env["key"] }), stored)
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, "/v9/projects/web/env/"):
+		id := strings.TrimPrefix(r.URL.Path, "/v9/projects/web/env/")
+		f.envs = slices.DeleteFunc(f.envs, func(e map[string]any) bool { return e["id"] == id })
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+//nolint:paralleltest // modifies environment variables and global flag variables
+func TestSync(t *testing.T) {
+	log := fakeGH(t)
+	t.Setenv("GITHUB_ACTIONS", "true")
+	t.Setenv(vercelTokenEnv, "vercel-token")
+	t.Setenv("GH_FAKE_SECRETS", `[]`)
+	vercel := &fakeVercel{envs: []map[string]any{{"key": "PARTIAL", "target": []string{"production"}}}}
+	server := httptest.NewServer(vercel)
+	t.Cleanup(server.Close)
+	originalAPI := vercelAPI
+	vercelAPI = server.URL
+	t.Cleanup(func() {
+		cfgFile, reconcileCheck, reconcileTargets, syncDryRun, vercelAPI = "", false, nil, false, originalAPI
+		reconcileMetrics = metrics.NewRegistry()
+	})
+
+	cfg := fellertest.NewConfig().
+		Provider("local", fellertest.FakeDotenv(t, map[string]string{"API_KEY": "abc", "DB_URL": "db", "PARTIAL": "p"})).
+		Build()
+	cfg.Targets = map[string]config.Target{
+		"ci":  {Kind: config.TargetKindGitHub, Repo: "owner/repo"},
+		"web": {Kind: config.TargetKindVercel, Project: "web", Team: "team_1", Environments: []string{"production", "preview"}},
+	}
+	cfg.Sync = map[string]config.SyncTargets{
+		"API_KEY": {"ci": "", "web": "NEXT_PUBLIC_API_KEY"},
+		"DB_URL":  {"ci": "DATABASE_URL"},
+	}
+	cfgFile = fellertest.WriteConfig(t, cfg)
+
+	syncDryRun = true
+	var out bytes.Buffer
+	require.NoError(t, runSync(nil, &out))
+	assert.Equal(t, "ci (owner/repo): 3 drifted secret(s) of 3\n"+
+		"  API_KEY (repository): missing\n  DATABASE_URL (repository): missing\n  PARTIAL (repository): missing\n"+
+		"web (web): 2 drifted secret(s) of 2\n"+
+		"  NEXT_PUBLIC_API_KEY (project): missing\n  PARTIAL (project): missing\n", out.String())
+	assert.NoFileExists(t, log)
+	assert.Empty(t, vercel.written)
+
+	syncDryRun = false
+	out.Reset()
+	require.NoError(t, runSync(nil, &out))
+	calls, err := os.ReadFile(log)
+	require.NoError(t, err)
+	assert.Equal(t, "secret set API_KEY --repo owner/repo\nsecret set DATABASE_URL --repo owner/repo\nsecret set PARTIAL --repo owner/repo\n", string(calls))
+	assert.Equal(t, []map[string]any{
+		{"key": "NEXT_PUBLIC_API_KEY", "value": "abc", "type": "encrypted", "target": []any{"production", "preview"}},
+		{"key": "PARTIAL", "value": "p", "type": "encrypted", "target": []any{"production", "preview"}},
+	}, vercel.written)
+
+	// Written secrets are recorded, so the next sync has nothing to do
+	out.Reset()
+	reconcileTargets = []string{"web"}
+	require.NoError(t, runSync(nil, &out))
+	assert.Equal(t, "web (web): in sync, 2 secret(s)\n", out.String())
+
+	t.Setenv(vercelTokenEnv, "wrong")
+	out.Reset()
+	err = runSync(nil, &out)
+	require.Error(t, err)
+	assert.Equal(t, "failed to sync 1 target(s)", err.Error())
+	assert.Contains(t, out.String(), "web (web): vercel returned status 403: forbidden")
+}
+
+//nolint:paralleltest // modifies global flag variables
+func TestTargetSecretsSync(t *testing.T) {
+	cfg := fellertest.NewConfig().Provider("local", fellertest.FakeDotenv(t, map[string]string{"A": "1", "B": "2"})).Build()
+	cfg.Targets = map[string]config.Target{"ci": {Kind: config.TargetKindGitHub, Repo: "o/r"}}
+	result := fellertest.Collect(t, cfg)
+
+	cfg.Sync = map[string]config.SyncTargets{"A": {"ci": "B"}}
+	_, err := targetSecrets(cfg, "ci", cfg.Targets["ci"], result)
+	require.Error(t, err)
+	assert.Equal(t, "target ci would receive B from both A and B, rename one in the sync section", err.Error())
+
+	cfg.Sync = map[string]config.SyncTargets{"A": {"edge": ""}}
+	t.Cleanup(func() { cfgFile = "" })
+	cfgFile = fellertest.WriteConfig(t, cfg)
+	_, err = reconcileRun(&bytes.Buffer{})
+	require.Error(t, err)
+	assert.Equal(t, `sync of A uses unknown target "edge"`, err.Error())
+}
+
+//nolint:paralleltest // modifies environment variables and global flag variables
+func TestSyncRollback(t *testing.T) {
+	log := fakeGH(t)
+	fellertest.FakeAge(t)
+	t.Setenv("GITHUB_ACTIONS", "true")
+	t.Setenv(vercelTokenEnv, "vercel-token")
+	t.Setenv("GH_FAKE_SECRETS", `[]`)
+	vercel := &fakeVercel{envs: []map[string]any{
+		{"id": "env_0", "key": "API_KEY", "type": "encrypted", "value": "old", "target": []string{"production"}},
+	}}
+	server := httptest.NewServer(vercel)
+	t.Cleanup(server.Close)
+	originalAPI, originalTerminal := vercelAPI, stdinIsTerminal
+	vercelAPI, stdinIsTerminal = server.URL, func() bool { return false }
+	t.Cleanup(func() {
+		cfgFile, reconcileCheck, syncDryRun, syncRollback, vercelAPI = "", false, false, "", originalAPI
+		confirmTargets, stdinIsTerminal, notifyWebhook = nil, originalTerminal, ""
+		reconcileMetrics = metrics.NewRegistry()
+	})
+
+	identity := filepath.Join(t.TempDir(), "key.txt")
+	require.NoError(t, os.WriteFile(identity, []byte("AGE-SECRET-KEY-FELLERTEST\n"), 0o600))
+	local := fellertest.FakeDotenv(t, map[string]string{"API_KEY": "v1"})
+	cfg := fellertest.NewConfig().Provider("local", local).Build()
+	cfg.Targets = map[string]config.Target{
+		"ci":  {Kind: config.TargetKindGitHub, Repo: "owner/repo"},
+		"web": {Kind: config.TargetKindVercel, Project: "web", Team: "team_1", Environments: []string{"production"}},
+	}
+	cfg.Backups = config.Backups{Recipients: []string{fellertest.FakeAgeRecipient}, Identity: identity}
+	cfgFile = fellertest.WriteConfig(t, cfg)
+	dir := state.Dir(cfgFile)
+
+	// The first run creates the GitHub secret and replaces the Vercel variable
+	var out bytes.Buffer
+	require.NoError(t, runSync(nil, &out))
+	ids, err := state.SyncRuns(dir)
+	require.NoError(t, err)
+	require.Len(t, ids, 1)
+	assert.Contains(t, out.String(), "Recorded sync run "+ids[0]+", roll it back with: feller sync --rollback "+ids[0]+"\n")
+	run, err := state.LoadSyncRun(dir, ids[0])
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(run.Values, "FAKE-AGE "+fellertest.FakeAgeRecipient+"\n"), "values are encrypted to the recipients")
+
+	// The second run changes both, GitHub only telling that the secret exists
+	require.NoError(t, os.WriteFile(config.LocalPath(local.Maps[0].Path), []byte("API_KEY=v2\n"), 0o600))
+	t.Setenv("GH_FAKE_SECRETS", `[{"name":"API_KEY"}]`)
+	require.NoError(t, runSync(nil, &bytes.Buffer{}))
+	ids, err = state.SyncRuns(dir)
+	require.NoError(t, err)
+	require.Len(t, ids, 2)
+	require.NoError(t, os.Remove(log))
+
+	syncRollback, syncDryRun = ids[1], true
+	out.Reset()
+	require.NoError(t, runSync(nil, &out))
+	assert.Equal(t, "github:owner/repo (repository)\n  ~ API_KEY  previous value\n"+
+		"vercel:web (project)\n  ~ API_KEY  previous value\n"+
+		"Plan: 0 to create, 2 to update, 0 to delete.\n"+
+		"Dry run, nothing was changed\n", out.String())
+	assert.NoFileExists(t, log)
+
+	// The GitHub value comes from the first run, which wrote it
+	syncDryRun = false
+	out.Reset()
+	require.NoError(t, runSync(nil, &out))
+	assert.Contains(t, out.String(), "Rolled back 2 secret(s) of sync run "+ids[1]+"\n")
+	calls, err := os.ReadFile(log)
+	require.NoError(t, err)
+	assert.Equal(t, "secret set API_KEY --repo owner/repo\n", string(calls))
+	uploaded, err := state.LoadUploads(dir)
+	require.NoError(t, err)
+	assert.True(t, uploaded.Unchanged("github:owner/repo (repository)", "API_KEY", "v1"))
+	assert.Equal(t, "v1", vercel.written[len(vercel.written)-1]["value"])
+	recorded, err := reconcile.LoadRecorded(reconcile.Path(cfgFile, "ci"))
+	require.NoError(t, err)
+	assert.NotContains(t, recorded.Secrets, "API_KEY", "the next sync writes the config value again")
+	_, err = state.LoadSyncRun(dir, ids[1])
+	require.EqualError(t, err, "no sync run "+ids[1]+" was recorded")
+
+	// Rolling back the first run deletes the secret it created, once confirmed, and restores
+	// the Vercel value
+	require.NoError(t, os.Remove(log))
+	syncRollback = ids[0]
+	require.EqualError(t, runSync(nil, &bytes.Buffer{}), "deleting 1 secret(s) from ci needs --confirm ci or --force-destructive")
+	assert.NoFileExists(t, log)
+	confirmTargets = []string{"ci"}
+	var notified NotificationPayload
+	webhook := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&notified))
+	}))
+	t.Cleanup(webhook.Close)
+	notifyWebhook = webhook.URL
+	require.NoError(t, runSync(nil, &bytes.Buffer{}))
+	assert.Equal(t, "sync rollback", notified.Operation)
+	assert.Equal(t, []string{"ci", "web"}, notified.Targets)
+	assert.Equal(t, []string{"API_KEY (ci repository)"}, notified.Deleted)
+	assert.Equal(t, 1, notified.Updated)
+	calls, err = os.ReadFile(log)
+	require.NoError(t, err)
+	assert.Equal(t, "secret delete API_KEY --repo owner/repo\n", string(calls))
+	assert.Equal(t, "old", vercel.written[len(vercel.written)-1]["value"])
+	entries, err := state.ReadAudit(dir)
+	require.NoError(t, err)
+	var audited []string
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Command, "sync rollback") {
+			audited = append(audited, entry.Command+" "+entry.Target+" "+entry.Map+" "+strings.Join(entry.Keys, ","))
+		}
+	}
+	assert.Equal(t, []string{
+		"sync rollback github:owner/repo repository API_KEY",
+		"sync rollback vercel:web project API_KEY",
+		"sync rollback delete github:owner/repo repository API_KEY",
+		"sync rollback vercel:web project API_KEY",
+	}, audited)
+
+	// Without recorded values a changed secret cannot be restored
+	require.NoError(t, state.SaveSyncRun(dir, &state.SyncRun{
+		ID: "20261016T120000Z", Created: time.Now(),
+		Changes: []state.SyncChange{{Target: "ci", Scope: "repository", Key: "API_KEY", Existed: true, Previous: "hmac-sha256:x"}},
+	}, 10))
+	syncRollback = "20261016T120000Z"
+	err = runSync(nil, &bytes.Buffer{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "cannot roll back sync run 20261016T120000Z, the previous values of API_KEY (repository) of ci are unknown")
+
+	syncRollback = "../uploads"
+	require.EqualError(t, runSync(nil, &bytes.Buffer{}), `invalid sync run "../uploads", run ids look like 20260102T150405Z`)
+}
@@ -0,0 +1,263 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/containifyci/feller/pkg/config"
+	"github.com/containifyci/feller/pkg/logger"
+	"github.com/containifyci/feller/pkg/providers"
+	"github.com/containifyci/feller/pkg/telemetry"
+	"github.com/spf13/cobra"
+)
+
+var (
+	telemetryEndpoint string
+	telemetryLocal    bool
+
+	// telemetryKinds are the provider kinds of the config loaded by the command
+	telemetryKinds []string
+)
+
+// telemetryCmd represents the telemetry command
+var telemetryCmd = &cobra.Command{
+	Use:   "telemetry",
+	Short: "Manage the opt-in anonymous usage telemetry",
+	Long: `Feller can record anonymous usage events to help its maintainers decide which
+providers and formats to work on. Telemetry is off unless you turn it on.
+
+Each event holds the command run (e.g. "export"), the kinds of the configured
+providers (e.g. "dotenv"), the hour it ran, the duration, whether the command
+succeeded, the operating system and architecture, and a random installation
+id. Events never hold key names, secret values, paths, provider names or
+command arguments.
+
+Events are kept in telemetry.jsonl in the user's config directory, or the
+directory named by FELLER_TELEMETRY_DIR. Once a batch is complete, a
+background feller process posts them over https to the feller endpoint, or the
+one given with --endpoint, and removes them; commands never wait for it. With
+--local they stay on this machine. DO_NOT_TRACK=1 or FELLER_TELEMETRY=0
+disables telemetry regardless of this setting.
+
+Examples:
+  feller telemetry status
+  feller telemetry on
+  feller telemetry on --endpoint https://telemetry.example.com/feller
+  feller telemetry on --local
+  feller telemetry off`,
+}
+
+var telemetryOnCmd = &cobra.Command{
+	Use:   "on",
+	Short: "Turn on the anonymous usage telemetry",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		return updateTelemetry(func(settings *telemetry.Settings) error {
+			if telemetryLocal && cmd.Flags().Changed("endpoint") {
+				return errors.New("--local and --endpoint cannot be used together")
+			}
+			if !telemetryLocal {
+				if err := telemetry.CheckEndpoint(telemetryEndpoint); err != nil {
+					return err //nolint:wrapcheck // names the endpoint
+				}
+			}
+			if err := settings.Enable(); err != nil {
+				return err //nolint:wrapcheck // describes the failure
+			}
+			settings.Endpoint = telemetryEndpoint
+			if telemetryLocal {
+				settings.Endpoint = ""
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), "Telemetry is on, thank you! See 'feller telemetry status' for what is recorded.")
+			return nil
+		})
+	},
+}
+
+var telemetryOffCmd = &cobra.Command{
+	Use:   "off",
+	Short: "Turn off the telemetry and delete the recorded events",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		return updateTelemetry(func(settings *telemetry.Settings) error {
+			settings.Enabled = false
+			fmt.Fprintln(cmd.OutOrStdout(), "Telemetry is off")
+			return nil
+		})
+	},
+}
+
+var telemetryStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show whether telemetry is on and what it recorded",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		dir, err := telemetry.DefaultDir()
+		if err != nil {
+			return err //nolint:wrapcheck // describes the missing directory
+		}
+		settings, err := telemetry.LoadSettings(dir)
+		if err != nil {
+			return err //nolint:wrapcheck // names the settings
+		}
+		pending, err := telemetry.Pending(dir)
+		if err != nil {
+			return err //nolint:wrapcheck // names the events
+		}
+		writeTelemetryStatus(cmd.OutOrStdout(), dir, settings, len(pending))
+		return nil
+	},
+}
+
+// telemetryUploadCmd uploads the recorded events; recordTelemetry runs it in the background
+var telemetryUploadCmd = &cobra.Command{
+	Use:    "__upload",
+	Hidden: true,
+	Args:   cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		dir, err := telemetry.DefaultDir()
+		if err != nil {
+			return err //nolint:wrapcheck // describes the missing directory
+		}
+		settings, err := telemetry.LoadSettings(dir)
+		if err != nil || !settings.Enabled || settings.Endpoint == "" || telemetry.DisabledByEnv() {
+			return err //nolint:wrapcheck // names the settings
+		}
+		return telemetry.Upload(cmd.Context(), dir, settings.Endpoint) //nolint:wrapcheck // describes the failure
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(telemetryCmd)
+	telemetryCmd.AddCommand(telemetryOnCmd, telemetryOffCmd, telemetryStatusCmd, telemetryUploadCmd)
+	telemetryOnCmd.Flags().StringVar(&telemetryEndpoint, "endpoint", telemetry.DefaultEndpoint, "HTTPS URL to post the events to")
+	telemetryOnCmd.Flags().BoolVar(&telemetryLocal, "local", false, "Keep the events on this machine instead of posting them")
+}
+
+// updateTelemetry applies update to the telemetry settings and saves them. Turning
+// telemetry off also deletes the recorded events.
+func updateTelemetry(update func(*telemetry.Settings) error) error {
+	dir, err := telemetry.DefaultDir()
+	if err != nil {
+		return err //nolint:wrapcheck // describes the missing directory
+	}
+	settings, err := telemetry.LoadSettings(dir)
+	if err != nil {
+		return err //nolint:wrapcheck // names the settings
+	}
+	if err := update(settings); err != nil {
+		return err
+	}
+	if !settings.Enabled {
+		if err := telemetry.Clear(dir); err != nil {
+			return err //nolint:wrapcheck // names the events
+		}
+	}
+	return settings.Write(dir) //nolint:wrapcheck // names the settings
+}
+
+// writeTelemetryStatus prints the telemetry settings and the number of recorded events
+func writeTelemetryStatus(out io.Writer, dir string, settings *telemetry.Settings, pending int) {
+	switch {
+	case telemetry.DisabledByEnv():
+		fmt.Fprintln(out, "Telemetry: off (disabled by DO_NOT_TRACK or FELLER_TELEMETRY)")
+	case settings.Enabled:
+		fmt.Fprintln(out, "Telemetry: on")
+	default:
+		fmt.Fprintln(out, "Telemetry: off, turn it on with 'feller telemetry on'")
+	}
+	if settings.ID != "" {
+		fmt.Fprintf(out, "Installation id: %s\n", settings.ID)
+	}
+	endpoint := settings.Endpoint
+	if endpoint == "" {
+		endpoint = "none, events stay local"
+	}
+	fmt.Fprintf(out, "Endpoint: %s\n", endpoint)
+	fmt.Fprintf(out, "Recorded events: %d (%s)\n", pending, filepath.Join(dir, telemetry.EventsFile))
+	fmt.Fprintln(out, "Recorded per command: command, provider kinds, hour, duration, success, OS and architecture")
+}
+
+// setTelemetryKinds remembers the provider kinds of cfg for the telemetry event. Kinds
+// feller does not know are recorded as "other", since they are free text, and plugin kinds as
+// "plugin", since their names are chosen by organizations.
+func setTelemetryKinds(cfg *config.TellerConfig) {
+	seen := make(map[string]bool)
+	for _, provider := range cfg.Providers {
+		kind := provider.Kind
+		if _, ok := providers.LookupKind(kind); !ok {
+			kind = "other"
+		} else if providers.IsPluginKind(kind) {
+			kind = "plugin"
+		}
+		seen[kind] = true
+	}
+	telemetryKinds = make([]string, 0, len(seen))
+	for kind := range seen {
+		telemetryKinds = append(telemetryKinds, kind)
+	}
+	sort.Strings(telemetryKinds)
+}
+
+// recordTelemetry records the command that ran when telemetry is on, uploading the recorded
+// events once a batch is complete. It never fails the command.
+func recordTelemetry(cmd *cobra.Command, start time.Time, success bool) {
+	if cmd == nil || cmd == rootCmd || cmd == telemetryCmd || cmd.Parent() == telemetryCmd ||
+		strings.HasPrefix(cmd.Name(), "__") || telemetry.DisabledByEnv() {
+		return
+	}
+	dir, err := telemetry.DefaultDir()
+	if err != nil {
+		logger.Debug("Not recording telemetry: %v", err)
+		return
+	}
+	settings, err := telemetry.LoadSettings(dir)
+	if err != nil || !settings.Enabled {
+		return
+	}
+
+	pending, err := telemetry.Record(dir, telemetry.Event{
+		Time:     start.UTC().Truncate(time.Hour),
+		ID:       settings.ID,
+		Command:  strings.TrimPrefix(cmd.CommandPath(), rootCmd.Name()+" "),
+		Kinds:    telemetryKinds,
+		Duration: time.Since(start).Milliseconds(),
+		Success:  success,
+		OS:       runtime.GOOS,
+		Arch:     runtime.GOARCH,
+	})
+	if err != nil {
+		logger.Debug("Failed to record telemetry: %v", err)
+		return
+	}
+	if settings.Endpoint != "" && pending >= telemetry.UploadBatch {
+		if err := startTelemetryUpload(); err != nil {
+			logger.Debug("Failed to upload telemetry: %v", err)
+		}
+	}
+}
+
+// startTelemetryUpload runs feller telemetry __upload in the background, so the command
+// does not wait for the endpoint; replaced in tests
+var startTelemetryUpload = func() error {
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate feller: %w", err)
+	}
+	// #nosec G204 - Runs feller itself
+	cmd := exec.CommandContext(context.Background(), executable, telemetryCmd.Name(), telemetryUploadCmd.Name())
+	setDetachAttributes(cmd)
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start the upload: %w", err)
+	}
+	return cmd.Process.Release() //nolint:wrapcheck // only frees resources of the started process
+}
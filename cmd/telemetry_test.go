@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/containifyci/feller/pkg/config"
+	"github.com/containifyci/feller/pkg/telemetry"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//nolint:paralleltest // Sets environment variables and global state
+func TestTelemetry(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(telemetry.DirEnv, dir)
+	t.Setenv(telemetry.DisableEnv, "")
+	t.Setenv("DO_NOT_TRACK", "")
+	uploads := 0
+	start := startTelemetryUpload
+	startTelemetryUpload = func() error { uploads++; return nil }
+	t.Cleanup(func() {
+		telemetryKinds, startTelemetryUpload = nil, start
+		telemetryEndpoint, telemetryLocal = telemetry.DefaultEndpoint, false
+		_ = telemetryOnCmd.Flags().Set("endpoint", telemetry.DefaultEndpoint)
+		telemetryOnCmd.Flags().Lookup("endpoint").Changed = false
+	})
+
+	var out bytes.Buffer
+	for _, c := range []*cobra.Command{telemetryOnCmd, telemetryOffCmd, telemetryStatusCmd} {
+		c.SetOut(&out)
+	}
+
+	// Off by default: nothing is recorded
+	recordTelemetry(showCmd, time.Now(), true)
+	require.NoError(t, telemetryStatusCmd.RunE(telemetryStatusCmd, nil))
+	assert.Contains(t, out.String(), "Telemetry: off, turn it on")
+	assert.Contains(t, out.String(), "Recorded events: 0")
+
+	// Events only travel over https
+	require.NoError(t, telemetryOnCmd.Flags().Set("endpoint", "http://telemetry.example.com"))
+	require.EqualError(t, telemetryOnCmd.RunE(telemetryOnCmd, nil), "telemetry endpoint http://telemetry.example.com must be an https URL")
+	telemetryLocal = true
+	require.EqualError(t, telemetryOnCmd.RunE(telemetryOnCmd, nil), "--local and --endpoint cannot be used together")
+	telemetryOnCmd.Flags().Lookup("endpoint").Changed = false
+
+	out.Reset()
+	require.NoError(t, telemetryOnCmd.RunE(telemetryOnCmd, nil))
+	assert.Contains(t, out.String(), "Telemetry is on")
+	settings, err := telemetry.LoadSettings(dir)
+	require.NoError(t, err)
+	assert.Empty(t, settings.Endpoint, "--local keeps the events local")
+
+	telemetryLocal, telemetryEndpoint = false, telemetry.DefaultEndpoint
+	require.NoError(t, telemetryOnCmd.RunE(telemetryOnCmd, nil))
+	settings, err = telemetry.LoadSettings(dir)
+	require.NoError(t, err)
+	assert.Equal(t, telemetry.DefaultEndpoint, settings.Endpoint)
+
+	setTelemetryKinds(&config.TellerConfig{Providers: map[string]config.Provider{
+		"secret-project-name": {Kind: "dotenv"},
+		"gsm":                 {Kind: "google_secretmanager"},
+		"custom":              {Kind: "internal_vault_of_acme"},
+		"acme":                {Kind: "plugin/acme-vault"},
+	}})
+	recordTelemetry(exportCmd, time.Now(), false)
+	recordTelemetry(telemetryStatusCmd, time.Now(), true)
+
+	events, err := telemetry.Pending(dir)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Zero(t, uploads, "uploads wait for a complete batch")
+	var event telemetry.Event
+	require.NoError(t, json.Unmarshal(events[0], &event))
+	assert.Equal(t, "export", event.Command)
+	assert.Equal(t, []string{"dotenv", "google_secretmanager", "other", "plugin"}, event.Kinds)
+	assert.False(t, event.Success)
+	assert.Len(t, event.ID, 32)
+	assert.NotContains(t, string(events[0]), "secret-project-name")
+	assert.NotContains(t, string(events[0]), "acme")
+
+	// A complete batch is uploaded in the background
+	for range telemetry.UploadBatch - 1 {
+		recordTelemetry(showCmd, time.Now(), true)
+	}
+	assert.Equal(t, 1, uploads)
+
+	// The environment overrides the setting
+	t.Setenv("DO_NOT_TRACK", "1")
+	recordTelemetry(showCmd, time.Now(), true)
+	out.Reset()
+	require.NoError(t, telemetryStatusCmd.RunE(telemetryStatusCmd, nil))
+	assert.Contains(t, out.String(), "Telemetry: off (disabled by DO_NOT_TRACK or FELLER_TELEMETRY)")
+	assert.Contains(t, out.String(), fmt.Sprintf("Recorded events: %d", telemetry.UploadBatch))
+
+	out.Reset()
+	require.NoError(t, telemetryOffCmd.RunE(telemetryOffCmd, nil))
+	assert.Equal(t, "Telemetry is off\n", out.String())
+	events, err = telemetry.Pending(dir)
+	require.NoError(t, err)
+	assert.Empty(t, events)
+}
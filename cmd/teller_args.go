@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/containifyci/feller/pkg/logger"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// tellerFlags maps the flags of feller commands to their teller equivalents in fallback
+// mode. Flags mapped to "" are applied by feller around teller; flags missing here have no
+// equivalent and are dropped with a warning. Flags that would change the result, such as
+// --sign, are rejected by the commands before falling back.
+var tellerFlags = map[string]map[string]string{
+	"run":    {"reset": "--reset", "shell": "--shell", "argfile": ""},
+	"export": {"out": "", "output": ""},
+	"env":    {"out": "", "output": ""},
+	"sh":     {"out": "", "output": "", "shell": ""},
+}
+
+// tellerSubcommands lists the subcommands of each teller major version feller falls back to
+var tellerSubcommands = map[int][]string{
+	1: {"run", "export", "env", "sh", "show", "yaml", "json", "scan", "redact", "copy", "put", "delete", "new"},
+	2: {"run", "export", "env", "sh", "show", "scan", "redact", "copy", "put", "delete", "new"},
+}
+
+var tellerVersionPattern = regexp.MustCompile(`(\d+)\.\d+\.\d+`)
+
+// tellerCommandArgs returns the teller arguments for command, followed by the teller
+// equivalents of the flags set on cmd
+func tellerCommandArgs(cmd *cobra.Command, command ...string) []string {
+	args := append([]string{}, command...)
+	translations := tellerFlags[cmd.Name()]
+	// LocalFlags is a fresh flag set that does not track which flags were set, so check Changed
+	cmd.LocalFlags().VisitAll(func(flag *pflag.Flag) {
+		teller, known := translations[flag.Name]
+		switch {
+		case !flag.Changed:
+		case !known:
+			logger.Info("--%s is not supported by teller and is ignored", flag.Name)
+		case teller == "":
+			logger.Debug("Applying --%s around teller", flag.Name)
+		case flag.Value.Type() == "bool" && flag.Value.String() != "true":
+			logger.Debug("Not passing --%s=false to teller", flag.Name)
+		default:
+			args = append(args, teller)
+			logger.Debug("Added %s flag to teller command", teller)
+		}
+	})
+	return args
+}
+
+// checkTellerSubcommand fails when the teller at tellerPath does not have subcommand. Teller
+// versions that cannot be determined or are newer than feller knows are not checked.
+func checkTellerSubcommand(tellerPath, subcommand string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// #nosec G204 - Teller path is resolved by findTellerBinary
+	output, err := exec.CommandContext(ctx, tellerPath, "--version").Output()
+	if err != nil {
+		logger.Debug("Failed to determine teller version: %v", err)
+		return nil
+	}
+	match := tellerVersionPattern.FindSubmatch(output)
+	if match == nil {
+		logger.Debug("Unrecognized teller version output: %q", output)
+		return nil
+	}
+	major, _ := strconv.Atoi(string(match[1]))
+	subcommands, known := tellerSubcommands[major]
+	if !known {
+		logger.Debug("Not checking subcommands of unknown teller version %s", match[0])
+		return nil
+	}
+
+	logger.Debug("Teller version: %s", match[0])
+	if !slices.Contains(subcommands, subcommand) {
+		return fmt.Errorf("teller %s does not support %q, which feller falls back to outside CI", match[0], subcommand)
+	}
+	return nil
+}
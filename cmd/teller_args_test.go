@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTellerCommandArgs(t *testing.T) {
+	t.Parallel()
+
+	newRun := func() *cobra.Command {
+		cmd := &cobra.Command{Use: "run"}
+		cmd.Flags().Bool("reset", false, "")
+		cmd.Flags().Bool("shell", false, "")
+		cmd.Flags().String("argfile", "", "")
+		cmd.Flags().StringSlice("pre-hook", nil, "")
+		return cmd
+	}
+
+	tests := []struct {
+		name  string
+		flags []string
+		want  []string
+	}{
+		{name: "no flags", want: []string{"run"}},
+		{name: "translated flags", flags: []string{"--reset", "--shell"}, want: []string{"run", "--reset", "--shell"}},
+		{name: "false bool", flags: []string{"--reset=false"}, want: []string{"run"}},
+		{name: "applied by feller", flags: []string{"--argfile", "args.txt"}, want: []string{"run"}},
+		{name: "unsupported flag", flags: []string{"--pre-hook", "make"}, want: []string{"run"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			cmd := newRun()
+			require.NoError(t, cmd.ParseFlags(tt.flags))
+			assert.Equal(t, tt.want, tellerCommandArgs(cmd, "run"))
+		})
+	}
+}
+
+func TestCheckTellerSubcommand(t *testing.T) {
+	t.Parallel()
+
+	writeTeller := func(t *testing.T, script string) string {
+		t.Helper()
+		path := filepath.Join(t.TempDir(), "teller")
+		require.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\n"+script+"\n"), 0o700))
+		return path
+	}
+
+	tests := []struct {
+		name        string
+		script      string
+		subcommand  string
+		errContains string
+	}{
+		{name: "v2 supports export", script: `echo "teller 2.0.7"`, subcommand: "export"},
+		{name: "v2 dropped yaml", script: `echo "teller 2.0.7"`, subcommand: "yaml", errContains: `teller 2.0.7 does not support "yaml"`},
+		{name: "v1 supports yaml", script: `echo "teller version 1.5.6"`, subcommand: "yaml"},
+		{name: "unknown major", script: `echo "teller 3.0.0"`, subcommand: "yaml"},
+		{name: "unrecognized output", script: `echo "teller"`, subcommand: "yaml"},
+		{name: "version fails", script: "exit 1", subcommand: "yaml"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			err := checkTellerSubcommand(writeTeller(t, tt.script), tt.subcommand)
+			if tt.errContains == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errContains)
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/containifyci/feller/pkg/providers"
+)
+
+var showTimings bool
+
+// reportTimings prints the requests and time of each provider to stderr when --timings is set
+func reportTimings(stderr io.Writer, timings []providers.Timing) {
+	if !showTimings {
+		return
+	}
+	for _, timing := range timings {
+		if timing.CacheHit {
+			fmt.Fprintf(stderr, "feller: provider %s (%s): served from the cache, resolved %s ago, refreshing\n",
+				timing.Provider, timing.Kind, timing.Cached.Round(time.Second))
+			continue
+		}
+		fmt.Fprintf(stderr, "feller: provider %s (%s): %d request(s), %d throttled for %s, %s total\n",
+			timing.Provider, timing.Kind, timing.Requests, timing.Throttled,
+			timing.Waited.Round(time.Millisecond), timing.Elapsed.Round(time.Millisecond))
+	}
+}
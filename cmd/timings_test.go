@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/containifyci/feller/pkg/providers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//nolint:paralleltest // Modifies the global timings flag
+func TestReportTimings(t *testing.T) {
+	t.Cleanup(func() { showTimings = false })
+	timings := []providers.Timing{{Provider: "gsm", Kind: "google_secretmanager", Requests: 3, Throttled: 1, Waited: 250 * time.Millisecond, Elapsed: 1234567 * time.Microsecond}}
+
+	var out bytes.Buffer
+	reportTimings(&out, timings)
+	assert.Empty(t, out.String())
+
+	showTimings = true
+	reportTimings(&out, timings)
+	assert.Equal(t, "feller: provider gsm (google_secretmanager): 3 request(s), 1 throttled for 250ms, 1.235s total\n", out.String())
+
+	out.Reset()
+	reportTimings(&out, []providers.Timing{{Provider: "vault", Kind: "hashicorp_vault", Cached: 90 * time.Second, CacheHit: true}})
+	assert.Equal(t, "feller: provider vault (hashicorp_vault): served from the cache, resolved 1m30s ago, refreshing\n", out.String())
+}
+
+//nolint:paralleltest // Modifies the global github-secret add flags
+func TestSetupGitHubRateLimit(t *testing.T) {
+	t.Cleanup(func() { githubRateLimit, githubBurst, githubLimiter = 0, 0, nil })
+
+	githubRateLimit, githubBurst = 0, 0
+	require.NoError(t, setupGitHubRateLimit())
+	assert.Nil(t, githubLimiter)
+	require.NoError(t, throttleGitHub())
+	assert.Equal(t, 1, githubTiming.Requests)
+
+	githubRateLimit, githubBurst = 2, 5
+	require.NoError(t, setupGitHubRateLimit())
+	assert.NotNil(t, githubLimiter)
+	assert.Equal(t, "github", githubTiming.Provider)
+	assert.Zero(t, githubTiming.Requests)
+
+	githubRateLimit, githubBurst = 0, 5
+	require.EqualError(t, setupGitHubRateLimit(), "--burst requires --rate-limit")
+	githubRateLimit, githubBurst = -1, 0
+	require.EqualError(t, setupGitHubRateLimit(), "--rate-limit must not be negative")
+}
@@ -0,0 +1,214 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+
+	"github.com/containifyci/feller/pkg/bundle"
+	"github.com/containifyci/feller/pkg/config"
+	"github.com/containifyci/feller/pkg/plan"
+	"github.com/containifyci/feller/pkg/providers"
+	"github.com/containifyci/feller/pkg/state"
+	"github.com/spf13/cobra"
+)
+
+var (
+	undoIdentity string
+	undoDryRun   bool
+	undoYes      bool
+)
+
+// undoCmd represents the undo command
+var undoCmd = &cobra.Command{
+	Use:   "undo",
+	Short: "Restore the secrets the last put, delete or copy changed",
+	Long: `Restore the secrets of the most recent backup: the values a provider held
+before 'feller put', 'feller delete' or 'feller copy' changed them. Keys the
+command created are deleted again.
+
+Backups are written when the config lists age recipients for them:
+
+  backups:
+    recipients: [age1...]
+    identity: ~/.config/feller/backup-key.txt
+    retention: 10
+
+They are stored encrypted in .feller/backups next to the config, one file per
+change, and only the newest retention backups (10 by default) are kept. Undo
+removes the backup it restored, so running it again walks further back. It
+does not take a backup itself.
+
+The backup is decrypted with the identity file given by --identity, the
+identity of the backups config or the age identity in the FELLER_AGE_KEY
+environment variable. The changes are printed as a plan and applied after a
+[y/N] confirmation, or right away with --yes. Deleting the keys the command
+created also needs --confirm with the provider or --force-destructive, like
+'feller delete'. --dry-run stops after the plan, and --json prints it as JSON.
+
+Examples:
+  feller undo --dry-run
+  feller undo --yes --identity key.txt`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		return runUndo(cmd.InOrStdin(), cmd.OutOrStdout())
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(undoCmd)
+	undoCmd.Flags().StringVar(&undoIdentity, "identity", "", "age identity file that decrypts the backup")
+	undoCmd.Flags().BoolVar(&undoDryRun, "dry-run", false, "Print the plan without changing anything")
+	undoCmd.Flags().BoolVarP(&undoYes, "yes", "y", false, "Restore without asking for confirmation")
+	undoCmd.Flags().BoolVar(&planJSON, "json", false, "Print the plan of --dry-run as JSON")
+	addDestructiveFlags(undoCmd)
+}
+
+func runUndo(in io.Reader, out io.Writer) error {
+	if planJSON && !undoDryRun {
+		return errors.New("--json requires --dry-run")
+	}
+
+	unlock, err := lockUnlessDryRun("undo", undoDryRun)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	path, b, err := latestBackup()
+	if err != nil {
+		return err
+	}
+	target, err := openWriteTarget(b.Provider, b.Map)
+	if err != nil {
+		return err
+	}
+	if target.writer.Target() != b.Target {
+		return fmt.Errorf("backup %s is of %s, but map %s of provider %s now writes %s", path, b.Target, b.Map, b.Provider, target.writer.Target())
+	}
+	names := append(slices.Sorted(maps.Keys(b.Values)), b.Absent...)
+	current, err := target.writer.Read(names)
+	if err != nil {
+		return err //nolint:wrapcheck // names the provider
+	}
+
+	p := plan.New("undo")
+	restored := make(providers.SecretMap, len(b.Values))
+	var deleted []string
+	slices.Sort(names)
+	for _, name := range names {
+		value, backedUp := b.Values[name]
+		now, set := current[name]
+		change := target.change(plan.ActionNoop, name, name)
+		switch {
+		case backedUp && !set:
+			change.Action = plan.ActionCreate
+			restored[name] = value
+		case backedUp && now != value:
+			change.Action = plan.ActionUpdate
+			restored[name] = value
+		case !backedUp && set:
+			change.Action = plan.ActionDelete
+			deleted = append(deleted, name)
+		default:
+			change.Reason = "unchanged"
+		}
+		p.Add(change)
+	}
+	if err := writePlan(out, p); err != nil {
+		return err
+	}
+	if undoDryRun {
+		if !planJSON {
+			fmt.Fprintln(out, "Dry run, nothing was changed")
+		}
+		return nil
+	}
+	if len(restored)+len(deleted) == 0 {
+		fmt.Fprintf(out, "%s already matches the backup of %s %s\n", target.writer.Target(), b.Command, b.Created.Format("2006-01-02 15:04:05 UTC"))
+		return removeBackup(path)
+	}
+	if !undoYes && !confirm(in, out, fmt.Sprintf("Restore %d secret(s) of %s from before %s?", len(restored)+len(deleted), target.writer.Target(), b.Command)) {
+		return errors.New("undo cancelled, nothing was changed")
+	}
+	// Keys the command created are deleted, which scripts confirm by naming the provider
+	if len(deleted) > 0 && (!undoYes || !stdinIsTerminal()) {
+		if err := confirmDestructive(in, out, []string{target.name}, len(deleted)); err != nil {
+			return err
+		}
+	}
+
+	if len(restored) > 0 {
+		if err := target.writer.Put(restored); err != nil {
+			return err //nolint:wrapcheck // names the provider or file
+		}
+	}
+	var removed []string
+	if len(deleted) > 0 {
+		removed, err = target.writer.Delete(deleted)
+	}
+	// Backends that fail part way have deleted some keys, which the audit log must hold too
+	changed := append(slices.Collect(maps.Keys(restored)), removed...)
+	slices.Sort(changed)
+	target.record("undo", changed)
+	if err != nil {
+		return err //nolint:wrapcheck // names the provider or file
+	}
+	fmt.Fprintf(out, "Restored %d secret(s) of %s from before %s %s (provider %s, map %s)\n",
+		len(changed), target.writer.Target(), b.Command, b.Created.Format("2006-01-02 15:04:05 UTC"), target.name, target.pathMap.ID)
+	return removeBackup(path)
+}
+
+// latestBackup returns the path and the content of the newest backup of the config
+func latestBackup() (string, *secretBackup, error) {
+	cfg, err := config.LoadConfig(cfgFile)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	configPath, err := config.ResolveConfigPath(cfgFile)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to find config: %w", err)
+	}
+	backups, err := state.Backups(state.Dir(configPath))
+	if err != nil {
+		return "", nil, err //nolint:wrapcheck // already describes the failure
+	}
+	if len(backups) == 0 {
+		return "", nil, errors.New("no backup to undo: backups are written by put, delete and copy when backups.recipients is set")
+	}
+	path := backups[len(backups)-1]
+
+	identity := undoIdentity
+	if identity == "" && cfg.Backups.Identity != "" {
+		identity = config.LocalPath(cfg.Backups.Identity)
+	}
+	// #nosec G304 - The path is inside the state directory
+	ciphertext, err := os.ReadFile(path)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read backup: %w", err)
+	}
+	plaintext, err := bundle.Open(ciphertext, identity)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to decrypt backup %s: %w", path, err)
+	}
+	var b secretBackup
+	if err := json.Unmarshal(plaintext, &b); err != nil {
+		return "", nil, fmt.Errorf("failed to parse backup %s: %w", path, err)
+	}
+	if b.Version != backupVersion {
+		return "", nil, fmt.Errorf("backup %s has version %d, this feller reads version %d", path, b.Version, backupVersion)
+	}
+	return path, &b, nil
+}
+
+// removeBackup removes a restored backup, so the next undo restores the one before it
+func removeBackup(path string) error {
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("failed to remove restored backup: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/containifyci/feller/pkg/config"
+	"github.com/containifyci/feller/pkg/fellertest"
+	"github.com/containifyci/feller/pkg/state"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//nolint:paralleltest // modifies environment variables and global flag variables
+func TestUndo(t *testing.T) {
+	fellertest.FakeAge(t)
+	originalTerminal := stdinIsTerminal
+	stdinIsTerminal = func() bool { return false }
+	t.Cleanup(func() {
+		cfgFile, putProvider, putYes, deleteProvider, forceDestructive, planJSON = "", "", false, "", false, false
+		undoIdentity, undoDryRun, undoYes, confirmTargets, stdinIsTerminal = "", false, false, nil, originalTerminal
+	})
+
+	identity := filepath.Join(t.TempDir(), "key.txt")
+	require.NoError(t, os.WriteFile(identity, []byte("AGE-SECRET-KEY-FELLERTEST\n"), 0o600))
+	local := fellertest.FakeDotenv(t, map[string]string{"API_KEY": "old", "KEEP": "v"})
+	path := config.LocalPath(local.Maps[0].Path)
+	cfg := fellertest.NewConfig().Provider("local", local).Build()
+	cfg.Backups = config.Backups{Recipients: []string{fellertest.FakeAgeRecipient}, Identity: identity, Retention: 2}
+	cfgFile = fellertest.WriteConfig(t, cfg)
+
+	err := runUndo(strings.NewReader(""), &bytes.Buffer{})
+	require.EqualError(t, err, "no backup to undo: backups are written by put, delete and copy when backups.recipients is set")
+
+	putProvider, putYes = "local", true
+	require.NoError(t, runPut(strings.NewReader(""), &bytes.Buffer{}, []string{"API_KEY=new", "TOKEN=t"}))
+	deleteProvider, forceDestructive = "local", true
+	require.NoError(t, runDelete(strings.NewReader(""), &bytes.Buffer{}, []string{"KEEP"}))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "API_KEY=\"new\"\nTOKEN=\"t\"\n", string(data))
+	backups, err := state.Backups(state.Dir(cfgFile))
+	require.NoError(t, err)
+	require.Len(t, backups, 2)
+	ciphertext, err := os.ReadFile(backups[0])
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(ciphertext), "FAKE-AGE "+fellertest.FakeAgeRecipient+"\n"), "backups are encrypted to the recipients")
+
+	undoDryRun = true
+	var out bytes.Buffer
+	require.NoError(t, runUndo(strings.NewReader(""), &out))
+	assert.Equal(t, "dotenv:"+path+" (local)\n"+
+		"  + KEEP\n"+
+		"Plan: 1 to create, 0 to update, 0 to delete.\n"+
+		"Dry run, nothing was changed\n", out.String())
+
+	// Declining leaves the backup for the next try
+	undoDryRun = false
+	out.Reset()
+	err = runUndo(strings.NewReader("n\n"), &out)
+	require.EqualError(t, err, "undo cancelled, nothing was changed")
+	assert.Contains(t, out.String(), "Restore 1 secret(s) of dotenv:"+path+" from before delete? [y/N]: ")
+
+	out.Reset()
+	require.NoError(t, runUndo(strings.NewReader("y\n"), &out))
+	assert.Contains(t, out.String(), "Restored 1 secret(s) of dotenv:"+path+" from before delete ")
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "API_KEY=\"new\"\nTOKEN=\"t\"\nKEEP=\"v\"\n", string(data))
+
+	// Each undo walks one change further back, deleting the keys put created once confirmed
+	undoYes, forceDestructive = true, false
+	err = runUndo(strings.NewReader(""), &bytes.Buffer{})
+	require.EqualError(t, err, "deleting 1 secret(s) from local needs --confirm local or --force-destructive")
+	confirmTargets = []string{"local"}
+	out.Reset()
+	require.NoError(t, runUndo(strings.NewReader(""), &out))
+	assert.Contains(t, out.String(), "  ~ API_KEY\n  - TOKEN\n")
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "API_KEY=\"old\"\nKEEP=\"v\"\n", string(data))
+
+	err = runUndo(strings.NewReader(""), &bytes.Buffer{})
+	require.ErrorContains(t, err, "no backup to undo")
+
+	entries, err := state.ReadAudit(state.Dir(cfgFile))
+	require.NoError(t, err)
+	require.Len(t, entries, 4)
+	assert.Equal(t, "undo", entries[3].Command)
+	assert.Equal(t, []string{"API_KEY", "TOKEN"}, entries[3].Keys)
+}
+
+//nolint:paralleltest // modifies global flag variables
+func TestBackupRetention(t *testing.T) {
+	fellertest.FakeAge(t)
+	t.Cleanup(func() {
+		cfgFile, putProvider, putYes = "", "", false
+	})
+
+	local := fellertest.FakeDotenv(t, map[string]string{"API_KEY": "0"})
+	cfg := fellertest.NewConfig().Provider("local", local).Build()
+	cfg.Backups = config.Backups{Recipients: []string{fellertest.FakeAgeRecipient}, Retention: 2}
+	cfgFile = fellertest.WriteConfig(t, cfg)
+
+	putProvider, putYes = "local", true
+	for _, value := range []string{"1", "2", "3"} {
+		require.NoError(t, runPut(strings.NewReader(""), &bytes.Buffer{}, []string{"API_KEY=" + value}))
+	}
+	backups, err := state.Backups(state.Dir(cfgFile))
+	require.NoError(t, err)
+	require.Len(t, backups, 2)
+	data, err := os.ReadFile(backups[0])
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"values":{"API_KEY":"1"}`)
+}
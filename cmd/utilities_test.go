@@ -5,6 +5,8 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/containifyci/feller/pkg/config"
+	"github.com/containifyci/feller/pkg/fellertest"
 	"github.com/containifyci/feller/pkg/providers"
 )
 
@@ -31,7 +33,7 @@ func ensureStandardPathsInPATH(currentPath string) string {
 	return currentPath
 }
 
-func TestIsGitHubActions(t *testing.T) {
+func TestIsCI(t *testing.T) {
 	// Save original environment
 	originalVal := os.Getenv("GITHUB_ACTIONS")
 	t.Cleanup(func() {
@@ -78,9 +80,113 @@ func TestIsGitHubActions(t *testing.T) {
 				t.Setenv("GITHUB_ACTIONS", tt.envValue)
 			}
 
-			result := isGitHubActions()
+			result := isCI()
 			if result != tt.expected {
-				t.Errorf("isGitHubActions() = %v, want %v", result, tt.expected)
+				t.Errorf("isCI() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+//nolint:paralleltest // modifies global flag variables and environment variables
+func TestExecutionModeOverride(t *testing.T) {
+	originalLocal, originalActions := forceLocal, forceActions
+	t.Cleanup(func() { forceLocal, forceActions = originalLocal, originalActions })
+
+	tests := []struct {
+		name         string
+		githubEnv    string
+		gitlabEnv    string
+		modeEnv      string
+		errContains  string
+		forceLocal   bool
+		forceActions bool
+		expected     bool
+	}{
+		{name: "detected", githubEnv: "true", expected: true},
+		{name: "auto mode", githubEnv: "true", modeEnv: "auto", expected: true},
+		{name: "local mode in actions", githubEnv: "true", modeEnv: "local", expected: false},
+		{name: "actions mode locally", modeEnv: "actions", expected: true},
+		{name: "other CI platform", gitlabEnv: "true", expected: true},
+		{name: "local mode on other CI platform", gitlabEnv: "true", modeEnv: "local", expected: false},
+		{name: "force-local over mode", githubEnv: "true", modeEnv: "actions", forceLocal: true, expected: false},
+		{name: "force-actions over mode", modeEnv: "local", forceActions: true, expected: true},
+		{name: "invalid mode", githubEnv: "true", modeEnv: "ci", errContains: `invalid FELLER_MODE "ci"`, expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("GITHUB_ACTIONS", tt.githubEnv)
+			t.Setenv("GITLAB_CI", tt.gitlabEnv)
+			t.Setenv(modeEnv, tt.modeEnv)
+			forceLocal, forceActions = tt.forceLocal, tt.forceActions
+
+			_, err := executionMode()
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Errorf("executionMode() error = %v, expected to contain %q", err, tt.errContains)
+				}
+			} else if err != nil {
+				t.Errorf("executionMode() unexpected error = %v", err)
+			}
+			if got := isCI(); got != tt.expected {
+				t.Errorf("isCI() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+//nolint:paralleltest // Modifies environment variables and global flags
+func TestResolveNatively(t *testing.T) {
+	disabled := false
+	aws := config.Provider{Kind: "aws_secretsmanager", Maps: []config.PathMap{{ID: "aws", Path: "prod/app", Keys: map[string]string{"token": "AWS_TOKEN"}}}}
+
+	tests := []struct {
+		name        string
+		githubEnv   string
+		noFallback  bool
+		fallback    *bool
+		exclude     []string
+		withAWS     bool
+		errContains string
+		expected    bool
+	}{
+		{name: "CI", githubEnv: "true", withAWS: true, expected: true},
+		{name: "fallback by default", expected: false},
+		{name: "no-fallback flag", noFallback: true, expected: true},
+		{name: "fallback disabled in config", fallback: &disabled, expected: true},
+		{name: "unsupported kind", noFallback: true, withAWS: true, errContains: `provider "aws" has kind "aws_secretsmanager"`, expected: true},
+		{name: "unsupported kind excluded", noFallback: true, withAWS: true, exclude: []string{"aws"}, expected: true},
+		{name: "unsupported kind with fallback", withAWS: true, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, name := range []string{"GITHUB_ACTIONS", "GITLAB_CI", "CIRCLECI", "BUILDKITE", "JENKINS_URL", modeEnv} {
+				t.Setenv(name, "")
+			}
+			t.Setenv("GITHUB_ACTIONS", tt.githubEnv)
+
+			builder := fellertest.NewConfig().Provider("env", fellertest.FakeDotenv(t, map[string]string{"A": "1"}))
+			if tt.withAWS {
+				builder = builder.Provider("aws", aws)
+			}
+			cfg := builder.Build()
+			cfg.Fallback = tt.fallback
+
+			cfgFile, noFallback, excludeProviders = fellertest.WriteConfig(t, cfg), tt.noFallback, tt.exclude
+			t.Cleanup(func() { cfgFile, noFallback, excludeProviders = "", false, nil })
+
+			native, err := resolveNatively()
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Errorf("resolveNatively() error = %v, expected to contain %q", err, tt.errContains)
+				}
+			} else if err != nil {
+				t.Errorf("resolveNatively() unexpected error = %v", err)
+			}
+			if native != tt.expected {
+				t.Errorf("resolveNatively() = %v, want %v", native, tt.expected)
 			}
 		})
 	}
@@ -290,22 +396,22 @@ func TestHandleMissingVariables(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
-			err := handleMissingVariables(tt.missingVars)
+			err := missingVariablesError(tt.missingVars, missingRun, config.Messages{})
 
 			if tt.wantErr {
 				if err == nil {
-					t.Errorf("handleMissingVariables() expected error but got none")
+					t.Errorf("missingVariablesError() expected error but got none")
 					return
 				}
 
 				errMsg := err.Error()
 				for _, contains := range tt.errContains {
 					if !strings.Contains(errMsg, contains) {
-						t.Errorf("handleMissingVariables() error should contain %q, got: %v", contains, err)
+						t.Errorf("missingVariablesError() error should contain %q, got: %v", contains, err)
 					}
 				}
 			} else if err != nil {
-				t.Errorf("handleMissingVariables() unexpected error = %v", err)
+				t.Errorf("missingVariablesError() unexpected error = %v", err)
 			}
 		})
 	}
@@ -404,7 +510,7 @@ func TestExecuteDirectCommand(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
-			err := executeDirectCommand(tt.args, tt.env)
+			err := executeDirectCommand(tt.args, tt.env, nil)
 
 			if tt.wantErr {
 				if err == nil {
@@ -481,7 +587,7 @@ func TestExecuteShellCommand(t *testing.T) {
 				t.Setenv("SHELL", tt.shell)
 			}
 
-			err := executeShellCommand(tt.args, tt.env)
+			err := executeShellCommand(tt.args, tt.env, nil)
 
 			if tt.wantErr {
 				if err == nil {
@@ -594,7 +700,7 @@ func TestFallbackToTeller(t *testing.T) {
 			cfgFile = tt.cfgFile
 			verbose = tt.verbose
 
-			err := fallbackToTeller(tt.args)
+			err := fallbackToTeller(tt.args, "")
 
 			if tt.wantErr {
 				if err == nil {
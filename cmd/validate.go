@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/containifyci/feller/pkg/config"
+	"github.com/containifyci/feller/pkg/logger"
+	"github.com/containifyci/feller/pkg/validate"
+	"github.com/spf13/cobra"
+)
+
+// validateCmd represents the validate command
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Validate the teller configuration",
+	Long: `Validate the teller configuration without resolving any secrets.
+
+Reports syntax errors, unknown fields, unsupported provider kinds, missing
+required map fields, invalid transforms and schema types, and dotenv files
+that do not exist or are readable by group or others. Each finding is printed
+as file:line:column. The command fails when at least one error is found;
+warnings alone do not fail. With --strict, readable dotenv files are errors.
+
+With --watch the config and the dotenv files it references are polled for
+changes. After the first full report only the difference is printed: new
+findings prefixed with "+" and resolved ones with "-", followed by a count.
+Watch mode runs until interrupted.
+
+Examples:
+  feller validate
+  feller validate --config ci/.teller.yml
+  feller validate --strict
+  feller validate --watch`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		if validateWatch {
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+			defer stop()
+			return watchConfig(ctx, os.Stdout, validateInterval)
+		}
+		return validateConfig(os.Stdout)
+	},
+}
+
+var (
+	validateWatch    bool
+	validateInterval time.Duration
+)
+
+func init() {
+	rootCmd.AddCommand(validateCmd)
+	validateCmd.Flags().BoolVarP(&validateWatch, "watch", "w", false, "Re-validate whenever the config or its dotenv files change")
+	validateCmd.Flags().DurationVar(&validateInterval, "interval", 500*time.Millisecond, "How often --watch checks files for changes")
+}
+
+// validateConfig validates the resolved config file and prints its diagnostics
+func validateConfig(out io.Writer) error {
+	path, err := config.ResolveConfigPath(cfgFile)
+	if err != nil {
+		return fmt.Errorf("failed to find config: %w", err)
+	}
+
+	diagnostics, err := validate.FileWithOptions(path, validateOptions())
+	if err != nil {
+		return fmt.Errorf("failed to validate config: %w", err)
+	}
+
+	for _, d := range diagnostics {
+		fmt.Fprintf(out, "%s:%s\n", path, d)
+	}
+
+	if errorCount, _ := countDiagnostics(diagnostics); errorCount > 0 {
+		return fmt.Errorf("%s has %d error(s)", path, errorCount)
+	}
+	fmt.Fprintf(out, "%s is valid (%d warning(s))\n", path, len(diagnostics))
+	return nil
+}
+
+// watchConfig validates the resolved config file on every change until ctx is done, printing
+// the full report first and only new and resolved diagnostics afterwards
+func watchConfig(ctx context.Context, out io.Writer, interval time.Duration) error {
+	if interval <= 0 {
+		return errors.New("--interval must be positive")
+	}
+	path, err := config.ResolveConfigPath(cfgFile)
+	if err != nil {
+		return fmt.Errorf("failed to find config: %w", err)
+	}
+
+	logger.Info("Watching %s and its dotenv files, press Ctrl+C to stop", path)
+	var previous []validate.Diagnostic
+	first := true
+	validate.Watch(ctx, path, interval, validateOptions(), func(result validate.Result) {
+		if result.Err != nil {
+			// Editors that save by replacing the file briefly leave it missing
+			fmt.Fprintf(out, "%s: %v\n", path, result.Err)
+			return
+		}
+
+		if first {
+			for _, d := range result.Diagnostics {
+				fmt.Fprintf(out, "%s:%s\n", path, d)
+			}
+			first = false
+		} else {
+			added, resolved := validate.Diff(previous, result.Diagnostics)
+			for _, d := range resolved {
+				fmt.Fprintf(out, "- %s:%s\n", path, d)
+			}
+			for _, d := range added {
+				fmt.Fprintf(out, "+ %s:%s\n", path, d)
+			}
+		}
+		previous = result.Diagnostics
+
+		errorCount, warningCount := countDiagnostics(result.Diagnostics)
+		fmt.Fprintf(out, "[%s] %s: %d error(s), %d warning(s)\n", time.Now().Format(time.TimeOnly), path, errorCount, warningCount)
+	})
+	return nil
+}
+
+// validateOptions returns the validation options selected by the global flags
+func validateOptions() validate.Options {
+	return validate.Options{StrictPermissions: strictPermissions}
+}
+
+// countDiagnostics returns the number of errors and warnings
+func countDiagnostics(diagnostics []validate.Diagnostic) (errorCount, warningCount int) {
+	for _, d := range diagnostics {
+		if d.Severity == validate.SeverityError {
+			errorCount++
+		} else {
+			warningCount++
+		}
+	}
+	return errorCount, warningCount
+}
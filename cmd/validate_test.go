@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//nolint:paralleltest // modifies the global cfgFile variable
+func TestValidateConfig(t *testing.T) {
+	originalCfgFile := cfgFile
+	t.Cleanup(func() {
+		cfgFile = originalCfgFile
+	})
+
+	tests := []struct {
+		name        string
+		content     string
+		expected    string
+		errContains string
+	}{
+		{
+			name:     "valid config",
+			content:  "providers:\n  gha:\n    kind: google_secretmanager\n    maps:\n      - id: ci\n        keys: {A: A}\n",
+			expected: "is valid (0 warning(s))",
+		},
+		{
+			name:        "invalid config",
+			content:     "providers:\n  gha:\n    maps: []\n",
+			expected:    `:2:3: error: provider "gha" is missing required field "kind"`,
+			errContains: "has 1 error(s)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfgFile = filepath.Join(t.TempDir(), ".teller.yml")
+			require.NoError(t, os.WriteFile(cfgFile, []byte(tt.content), 0o600))
+
+			var out bytes.Buffer
+			err := validateConfig(&out)
+			if tt.errContains != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errContains)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Contains(t, out.String(), tt.expected)
+		})
+	}
+}
+
+// syncBuffer is a bytes.Buffer that can be written by a watcher while the test reads it
+type syncBuffer struct {
+	buf bytes.Buffer
+	mu  sync.Mutex
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+//nolint:paralleltest // modifies the global cfgFile variable
+func TestWatchConfig(t *testing.T) {
+	originalCfgFile := cfgFile
+	t.Cleanup(func() {
+		cfgFile = originalCfgFile
+	})
+
+	cfgFile = filepath.Join(t.TempDir(), ".teller.yml")
+	valid := "providers:\n  gha:\n    kind: google_secretmanager\n    maps:\n      - id: ci\n        keys: {A: A}\n"
+	require.NoError(t, os.WriteFile(cfgFile, []byte(valid+"extra: 1\n"), 0o600))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	out := &syncBuffer{}
+	done := make(chan error)
+	go func() {
+		done <- watchConfig(ctx, out, 5*time.Millisecond)
+	}()
+
+	require.Eventually(t, func() bool {
+		return strings.Contains(out.String(), "0 error(s), 1 warning(s)")
+	}, 5*time.Second, 5*time.Millisecond)
+	assert.Contains(t, out.String(), cfgFile+`:7:1: warning: unknown field "extra"`)
+
+	require.NoError(t, os.WriteFile(cfgFile, []byte(valid), 0o600))
+	require.Eventually(t, func() bool {
+		return strings.Contains(out.String(), "0 error(s), 0 warning(s)")
+	}, 5*time.Second, 5*time.Millisecond)
+	assert.Contains(t, out.String(), "- "+cfgFile+`:7:1: warning: unknown field "extra"`)
+
+	cancel()
+	require.NoError(t, <-done)
+}
+
+func TestWatchConfigInvalidInterval(t *testing.T) {
+	t.Parallel()
+	err := watchConfig(context.Background(), &bytes.Buffer{}, 0)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "--interval must be positive")
+}
@@ -0,0 +1,208 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/containifyci/feller/pkg/config"
+	"github.com/containifyci/feller/pkg/logger"
+)
+
+// vercelTokenEnv holds the token feller writes the variables of vercel targets with
+const vercelTokenEnv = "VERCEL_TOKEN"
+
+// vercelTimeout bounds each request to the Vercel API
+const vercelTimeout = 30 * time.Second
+
+// vercelAPI is the base URL of the Vercel REST API, replaced in tests
+var vercelAPI = "https://api.vercel.com"
+
+// vercelEnv is an environment variable of a Vercel project as listed by the API
+type vercelEnv struct {
+	ID     string   `json:"id"`
+	Key    string   `json:"key"`
+	Type   string   `json:"type"`
+	Value  string   `json:"value"`
+	Target []string `json:"target"`
+}
+
+// vercelTarget is the plan target of a Vercel project
+func vercelTarget(project string) string {
+	return "vercel:" + project
+}
+
+// vercelEnvironments returns the environments a vercel target writes to
+func vercelEnvironments(target config.Target) []string {
+	if len(target.Environments) > 0 {
+		return target.Environments
+	}
+	return config.VercelEnvironments
+}
+
+// vercelEnvs returns the variables of the project of target, with the values of those the
+// API decrypts when decrypt is set
+func vercelEnvs(target config.Target, decrypt bool) ([]vercelEnv, error) {
+	path := "/v9/projects/" + url.PathEscape(target.Project) + "/env"
+	if decrypt {
+		path += "?decrypt=true"
+	}
+	var list struct {
+		Envs []vercelEnv `json:"envs"`
+	}
+	if err := vercelRequest(http.MethodGet, path, target, nil, &list); err != nil {
+		return nil, err
+	}
+	return list.Envs, nil
+}
+
+// inVercelEnvironments reports whether env is set in one of the environments target writes to
+func inVercelEnvironments(env vercelEnv, target config.Target) bool {
+	return slices.ContainsFunc(vercelEnvironments(target), func(environment string) bool {
+		return slices.Contains(env.Target, environment)
+	})
+}
+
+// listVercelEnv returns the keys of the project of target that are set in every environment
+// the target writes to
+func listVercelEnv(target config.Target) (map[string]bool, error) {
+	envs, err := vercelEnvs(target, false)
+	if err != nil {
+		return nil, err
+	}
+
+	environments := vercelEnvironments(target)
+	covered := make(map[string][]string)
+	for _, env := range envs {
+		covered[env.Key] = append(covered[env.Key], env.Target...)
+	}
+	existing := make(map[string]bool, len(covered))
+	for key, targets := range covered {
+		existing[key] = !slices.ContainsFunc(environments, func(environment string) bool {
+			return !slices.Contains(targets, environment)
+		})
+	}
+	logger.Debug("Vercel project %s has %d variable(s)", target.Project, len(existing))
+	return existing, nil
+}
+
+// readVercelEnv returns the values of the variables of the project of target set in the
+// environments it writes to. Sensitive variables are never returned by the API and are left
+// out.
+func readVercelEnv(target config.Target) (map[string]string, error) {
+	envs, err := vercelEnvs(target, true)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]string)
+	for _, env := range envs {
+		if _, ok := values[env.Key]; !ok && env.Type != "sensitive" && inVercelEnvironments(env, target) {
+			values[env.Key] = env.Value
+		}
+	}
+	return values, nil
+}
+
+// deleteVercelEnv removes the variable key from the environments of target
+func deleteVercelEnv(target config.Target, key string) error {
+	logger.Debug("Deleting Vercel variable %s of project %s", key, target.Project)
+	envs, err := vercelEnvs(target, false)
+	if err != nil {
+		return err
+	}
+	for _, env := range envs {
+		if env.Key != key || !inVercelEnvironments(env, target) {
+			continue
+		}
+		path := "/v9/projects/" + url.PathEscape(target.Project) + "/env/" + url.PathEscape(env.ID)
+		if err := vercelRequest(http.MethodDelete, path, target, nil, nil); err != nil {
+			return fmt.Errorf("failed to delete Vercel variable %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// setVercelEnv creates or updates the encrypted variable key in the environments of target
+func setVercelEnv(target config.Target, key, value string) error {
+	logger.Debug("Setting Vercel variable %s of project %s", key, target.Project)
+	body := map[string]any{
+		"key":    key,
+		"value":  value,
+		"type":   "encrypted",
+		"target": vercelEnvironments(target),
+	}
+	path := "/v10/projects/" + url.PathEscape(target.Project) + "/env?upsert=true"
+	if err := vercelRequest(http.MethodPost, path, target, body, nil); err != nil {
+		return fmt.Errorf("failed to set Vercel variable %s: %w", key, err)
+	}
+	return nil
+}
+
+// vercelRequest sends a request to the Vercel API on behalf of the team of target, decoding
+// the JSON response into out when it is not nil
+func vercelRequest(method, path string, target config.Target, body, out any) error {
+	token := os.Getenv(vercelTokenEnv)
+	if token == "" {
+		return fmt.Errorf("%s is not set, create a token at https://vercel.com/account/tokens", vercelTokenEnv)
+	}
+
+	endpoint, err := url.Parse(vercelAPI + path)
+	if err != nil {
+		return fmt.Errorf("invalid Vercel API URL: %w", err)
+	}
+	if target.Team != "" {
+		query := endpoint.Query()
+		query.Set("teamId", target.Team)
+		endpoint.RawQuery = query.Encode()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode Vercel request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), vercelTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, method, endpoint.String(), reader)
+	if err != nil {
+		return fmt.Errorf("failed to create Vercel request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("vercel request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var failure struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if json.NewDecoder(io.LimitReader(resp.Body, 1<<16)).Decode(&failure) == nil && failure.Error.Message != "" {
+			return fmt.Errorf("vercel returned status %d: %s", resp.StatusCode, failure.Error.Message)
+		}
+		return fmt.Errorf("vercel returned status %d", resp.StatusCode)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode Vercel response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var verifyOpts = verifyOptions{}
+
+// verifyCmd represents the verify command
+var verifyCmd = &cobra.Command{
+	Use:   "verify FILE",
+	Short: "Verify the signature of an exported file",
+	Long: `Verify the signature written by 'feller export --sign' next to FILE.
+
+cosign signatures are read from FILE.sigstore.json and minisign signatures
+from FILE.minisig; --method selects one when both exist. Keyless cosign
+signatures are only accepted from the expected identity, given with
+--certificate-identity (or --certificate-identity-regexp) and
+--certificate-oidc-issuer, which defaults to GitHub Actions. Signatures made
+with a key are verified with --key, the public key file.
+
+The cosign or minisign tool must be installed. Verification does not read
+the teller configuration and works the same outside GitHub Actions.
+
+Examples:
+  feller verify secrets.json \
+    --certificate-identity https://github.com/owner/repo/.github/workflows/release.yml@refs/heads/main
+  feller verify secrets.json --key minisign.pub`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		opts := verifyOpts
+		if opts.method == "" {
+			method, err := detectSignMethod(args[0])
+			if err != nil {
+				return err
+			}
+			opts.method = method
+		}
+
+		toolArgs, err := verifyArgs(opts, args[0])
+		if err != nil {
+			return err
+		}
+		if err := runSignTool(opts.method, toolArgs); err != nil {
+			return fmt.Errorf("signature of %s is not valid: %w", args[0], err)
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Verified %s signature of %s\n", opts.method, args[0])
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(verifyCmd)
+	verifyCmd.Flags().StringVar(&verifyOpts.method, "method", "", "Signature to verify (cosign, minisign); detected from the signature file by default")
+	verifyCmd.Flags().StringVar(&verifyOpts.key, "key", "", "Public key file (required for minisign)")
+	verifyCmd.Flags().StringVar(&verifyOpts.identity, "certificate-identity", "", "Expected identity of keyless cosign signatures")
+	verifyCmd.Flags().StringVar(&verifyOpts.identityRegexp, "certificate-identity-regexp", "", "Regular expression the identity of keyless cosign signatures must match")
+	verifyCmd.Flags().StringVar(&verifyOpts.issuer, "certificate-oidc-issuer", githubOIDCIssuer, "Expected OIDC issuer of keyless cosign signatures")
+}
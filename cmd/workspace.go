@@ -0,0 +1,287 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/containifyci/feller/pkg/config"
+	"github.com/containifyci/feller/pkg/logger"
+	"github.com/containifyci/feller/pkg/workspace"
+	"github.com/spf13/cobra"
+)
+
+// workspaceEnv selects a workspace like --workspace
+const workspaceEnv = "FELLER_WORKSPACE"
+
+var (
+	workspaceName  string
+	workspaceUnset bool
+)
+
+// workspaceCmd represents the workspace command
+var workspaceCmd = &cobra.Command{
+	Use:   "workspace",
+	Short: "Switch between named configs",
+	Long: `Register configs under short names and switch between them, instead of
+passing long --config paths when working on many projects or environments.
+
+Workspaces are kept in workspaces.yml in the user's config directory, or the
+file named by FELLER_WORKSPACES. The workspace a command uses is, in order:
+--workspace, the FELLER_WORKSPACE environment variable, then the one selected
+with 'feller workspace use'. --config always takes precedence over all of them.
+
+Commands run in a workspace run in the directory of its config, as if you had
+changed into the project, so relative paths in the config keep working.
+
+Examples:
+  feller workspace add api ~/src/api/.teller.yml
+  feller workspace list
+  feller workspace use api
+  feller workspace run billing -- ./deploy.sh
+  feller --workspace billing show`,
+}
+
+var workspaceListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the registered workspaces",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		registry, _, err := loadWorkspaces()
+		if err != nil {
+			return err
+		}
+		return writeWorkspaces(cmd.OutOrStdout(), registry)
+	},
+}
+
+var workspaceAddCmd = &cobra.Command{
+	Use:   "add NAME [CONFIG]",
+	Short: "Register a config as a workspace",
+	Long: `Register a config under a name. Without CONFIG the config given by --config,
+or found from the current directory, is registered.`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		configPath := cfgFile
+		if len(args) == 2 {
+			configPath = args[1]
+		}
+		resolved, err := config.ResolveConfigPath(configPath)
+		if err != nil {
+			return fmt.Errorf("failed to resolve config path: %w", err)
+		}
+		if _, err := os.Stat(resolved); err != nil {
+			return fmt.Errorf("cannot register %s: %w", resolved, err)
+		}
+		return updateWorkspaces(func(registry *workspace.Registry) error {
+			if err := registry.Add(args[0], resolved); err != nil {
+				return err //nolint:wrapcheck // names the workspace
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Added workspace %s: %s\n", args[0], registry.Workspaces[args[0]].Config)
+			return nil
+		})
+	},
+}
+
+var workspaceRemoveCmd = &cobra.Command{
+	Use:               "remove NAME",
+	Short:             "Unregister a workspace",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeWorkspaces,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return updateWorkspaces(func(registry *workspace.Registry) error {
+			if err := registry.Remove(args[0]); err != nil {
+				return err //nolint:wrapcheck // names the workspace
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Removed workspace %s\n", args[0])
+			return nil
+		})
+	},
+}
+
+var workspaceUseCmd = &cobra.Command{
+	Use:   "use NAME",
+	Short: "Select the workspace used by later commands",
+	Long: `Select the workspace later commands use when neither --config, --workspace
+nor FELLER_WORKSPACE is given. --unset clears the selection, so the config is
+found from the current directory again.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if workspaceUnset {
+			return cobra.NoArgs(cmd, args)
+		}
+		return cobra.ExactArgs(1)(cmd, args)
+	},
+	ValidArgsFunction: completeWorkspaces,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := ""
+		if !workspaceUnset {
+			name = args[0]
+		}
+		return updateWorkspaces(func(registry *workspace.Registry) error {
+			if err := registry.Use(name); err != nil {
+				return err //nolint:wrapcheck // names the workspace
+			}
+			if name == "" {
+				fmt.Fprintln(cmd.OutOrStdout(), "No workspace selected")
+			} else {
+				fmt.Fprintf(cmd.OutOrStdout(), "Using workspace %s: %s\n", name, registry.Workspaces[name].Config)
+			}
+			return nil
+		})
+	},
+}
+
+var workspaceRunCmd = &cobra.Command{
+	Use:   "run NAME -- command [args...]",
+	Short: "Run a command with the secrets of a workspace",
+	Long: `Run a command with the secrets of a workspace, in the directory of its config.
+This is a shorthand for 'feller --workspace NAME run -- command'; use that form
+for the flags of run.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) < 2 || cmd.ArgsLenAtDash() != 1 {
+			return errors.New("usage: feller workspace run NAME -- command [args...]")
+		}
+		return nil
+	},
+	ValidArgsFunction: completeWorkspaces,
+	RunE: func(_ *cobra.Command, args []string) error {
+		if cfgFile != "" {
+			return errors.New("workspace run cannot be used with --config")
+		}
+		if err := switchWorkspace(args[0]); err != nil {
+			return err
+		}
+		return runCommand(runCmd, args[1:])
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(workspaceCmd)
+	workspaceCmd.AddCommand(workspaceListCmd, workspaceAddCmd, workspaceRemoveCmd, workspaceUseCmd, workspaceRunCmd)
+	workspaceUseCmd.Flags().BoolVar(&workspaceUnset, "unset", false, "Clear the selected workspace")
+	// Stop flag parsing at the command so its flags are never consumed
+	workspaceRunCmd.Flags().SetInterspersed(false)
+}
+
+// loadWorkspaces reads the workspace registry and returns it with its path
+func loadWorkspaces() (*workspace.Registry, string, error) {
+	path, err := workspace.DefaultPath()
+	if err != nil {
+		return nil, "", err //nolint:wrapcheck // describes the missing directory
+	}
+	registry, err := workspace.Load(path)
+	if err != nil {
+		return nil, "", err //nolint:wrapcheck // names the registry
+	}
+	return registry, path, nil
+}
+
+// updateWorkspaces applies update to the workspace registry and saves it
+func updateWorkspaces(update func(*workspace.Registry) error) error {
+	registry, path, err := loadWorkspaces()
+	if err != nil {
+		return err
+	}
+	if err := update(registry); err != nil {
+		return err
+	}
+	return registry.Write(path) //nolint:wrapcheck // names the registry
+}
+
+// writeWorkspaces prints the workspaces, marking the current one
+func writeWorkspaces(out io.Writer, registry *workspace.Registry) error {
+	if len(registry.Workspaces) == 0 {
+		fmt.Fprintln(out, "No workspaces registered, add one with 'feller workspace add NAME [CONFIG]'")
+		return nil
+	}
+	w := newTable(out)
+	fmt.Fprintln(w, "\tNAME\tCONFIG")
+	for _, name := range registry.Names() {
+		marker := ""
+		if name == registry.Current {
+			marker = "*"
+		}
+		path := registry.Workspaces[name].Config
+		if _, err := os.Stat(path); err != nil {
+			path += " (missing)"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", marker, name, path)
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to write workspaces: %w", err)
+	}
+	return nil
+}
+
+// enterWorkspace switches to the workspace selected by --workspace, FELLER_WORKSPACE or
+// 'feller workspace use' unless --config is given. The workspace commands manage the
+// registry and are never run in a workspace.
+func enterWorkspace(cmd *cobra.Command) error {
+	if cmd == workspaceCmd || cmd.Parent() == workspaceCmd {
+		return nil
+	}
+	if cfgFile != "" {
+		if workspaceName != "" {
+			return errors.New("--workspace cannot be used with --config")
+		}
+		return nil
+	}
+	name, err := selectedWorkspace(workspaceName)
+	if err != nil || name == "" {
+		return err
+	}
+	return switchWorkspace(name)
+}
+
+// selectedWorkspace returns the workspace selected by flag, FELLER_WORKSPACE or the
+// registry, or "" when none is. Without an explicit selection an unavailable registry
+// selects none.
+func selectedWorkspace(flag string) (string, error) {
+	name := flag
+	if name == "" {
+		name = os.Getenv(workspaceEnv)
+	}
+	registry, _, err := loadWorkspaces()
+	if err != nil {
+		if name == "" {
+			logger.Debug("Not using a workspace: %v", err)
+			return "", nil
+		}
+		return "", err
+	}
+	if name == "" {
+		name = registry.Current
+	}
+	return name, nil
+}
+
+// switchWorkspace uses the config of the workspace name and changes into its directory
+func switchWorkspace(name string) error {
+	registry, _, err := loadWorkspaces()
+	if err != nil {
+		return err
+	}
+	ws, err := registry.Lookup(name)
+	if err != nil {
+		return err //nolint:wrapcheck // names the workspace
+	}
+	if err := os.Chdir(ws.Dir()); err != nil {
+		return fmt.Errorf("failed to enter workspace %s: %w", name, err)
+	}
+	cfgFile = ws.Config
+	logger.Verbose("Using workspace %s: %s", name, ws.Config)
+	return nil
+}
+
+// completeWorkspaces completes the registered workspace names
+func completeWorkspaces(_ *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	registry, _, err := loadWorkspaces()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return registry.Names(), cobra.ShellCompDirectiveNoFileComp
+}
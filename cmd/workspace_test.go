@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/containifyci/feller/pkg/fellertest"
+	"github.com/containifyci/feller/pkg/workspace"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//nolint:paralleltest // Changes the working directory, environment and global flags
+func TestWorkspaces(t *testing.T) {
+	t.Setenv(workspace.PathEnv, filepath.Join(t.TempDir(), workspace.FileName))
+	t.Setenv(workspaceEnv, "")
+	t.Chdir(t.TempDir())
+	t.Cleanup(func() { cfgFile, workspaceName, workspaceUnset = "", "", false })
+
+	api := fellertest.WriteConfig(t, fellertest.NewConfig().Provider("local", fellertest.FakeDotenv(t, map[string]string{"API_KEY": "abc"})).Build())
+	var out bytes.Buffer
+	for _, c := range []*cobra.Command{workspaceListCmd, workspaceAddCmd, workspaceRemoveCmd, workspaceUseCmd} {
+		c.SetOut(&out)
+	}
+
+	require.NoError(t, workspaceListCmd.RunE(workspaceListCmd, nil))
+	assert.Contains(t, out.String(), "No workspaces registered")
+
+	out.Reset()
+	require.NoError(t, workspaceAddCmd.RunE(workspaceAddCmd, []string{"api", api}))
+	assert.Equal(t, "Added workspace api: "+api+"\n", out.String())
+	err := workspaceAddCmd.RunE(workspaceAddCmd, []string{"gone", filepath.Join(t.TempDir(), "missing.yml")})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "cannot register")
+
+	// Without a selection the config is found from the current directory as before
+	require.NoError(t, enterWorkspace(showCmd))
+	assert.Empty(t, cfgFile)
+
+	out.Reset()
+	require.NoError(t, workspaceUseCmd.RunE(workspaceUseCmd, []string{"api"}))
+	require.NoError(t, workspaceListCmd.RunE(workspaceListCmd, nil))
+	assert.Regexp(t, `Using workspace api: .*\n\s+NAME\s+CONFIG\n\*\s+api\s+`+regexp.QuoteMeta(api)+`\n`, out.String())
+
+	require.NoError(t, enterWorkspace(showCmd))
+	assert.Equal(t, api, cfgFile)
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Dir(api), wd)
+
+	// --config wins, and cannot be combined with --workspace
+	cfgFile, workspaceName = "other.yml", "api"
+	err = enterWorkspace(showCmd)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "--workspace cannot be used with --config")
+
+	cfgFile, workspaceName = "", "nope"
+	err = enterWorkspace(showCmd)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `unknown workspace "nope" (registered: api)`)
+
+	// The workspace commands manage the registry outside any workspace
+	cfgFile, workspaceName = "", ""
+	require.NoError(t, enterWorkspace(workspaceAddCmd))
+	assert.Empty(t, cfgFile)
+
+	out.Reset()
+	workspaceUnset = true
+	require.NoError(t, workspaceUseCmd.RunE(workspaceUseCmd, nil))
+	require.NoError(t, workspaceRemoveCmd.RunE(workspaceRemoveCmd, []string{"api"}))
+	assert.Equal(t, "No workspace selected\nRemoved workspace api\n", out.String())
+}
@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/containifyci/feller/pkg/bundle"
+	"github.com/containifyci/feller/pkg/config"
+	"github.com/containifyci/feller/pkg/logger"
+	"github.com/containifyci/feller/pkg/plan"
+	"github.com/containifyci/feller/pkg/providers"
+	"github.com/containifyci/feller/pkg/state"
+)
+
+// backupVersion is the format version of secret backups
+const backupVersion = 1
+
+// secretBackup holds the values secrets of a target had before a command changed them
+type secretBackup struct {
+	Version  int               `json:"version"`
+	Created  time.Time         `json:"created"`
+	Command  string            `json:"command"`
+	Provider string            `json:"provider"`
+	Map      string            `json:"map"`
+	Target   string            `json:"target"`
+	Values   map[string]string `json:"values"`           // Source name -> value before the change
+	Absent   []string          `json:"absent,omitempty"` // Source names that were not set
+}
+
+// writeTarget is the path map of a provider that put, delete and undo change, with the
+// writer of its backend
+type writeTarget struct {
+	name     string
+	provider config.Provider
+	pathMap  config.PathMap
+	writer   providers.Writer
+	backups  config.Backups
+}
+
+// openWriteTarget returns the map of provider name with the id mapID, or its only map
+func openWriteTarget(name, mapID string) (*writeTarget, error) {
+	cfg, err := config.LoadConfig(cfgFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	provider, ok := cfg.Providers[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown provider %q", name)
+	}
+	pathMap, err := selectMap(name, provider, mapID, "--map")
+	if err != nil {
+		return nil, err
+	}
+	writer, err := providers.NewWriter(name, provider, pathMap)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // names the provider and map
+	}
+	return &writeTarget{name: name, provider: provider, pathMap: pathMap, writer: writer, backups: cfg.Backups}, nil
+}
+
+// sources returns the names the map reads keys from, by the key applications see
+func (t *writeTarget) sources(keys []string) (map[string]string, error) {
+	sources, unread := t.readable(keys)
+	if len(unread) > 0 {
+		return nil, fmt.Errorf("map %s of provider %s does not read %s", t.pathMap.ID, t.name, strings.Join(unread, ", "))
+	}
+	return sources, nil
+}
+
+// readable returns the names the map reads keys from, by the key applications see, and the
+// keys it does not read
+func (t *writeTarget) readable(keys []string) (map[string]string, []string) {
+	sources := make(map[string]string, len(keys))
+	var unread []string
+	for _, key := range keys {
+		restored, _ := providers.RestoreKeys(t.pathMap, providers.SecretMap{key: ""})
+		if len(restored) == 0 {
+			unread = append(unread, key)
+		}
+		for source := range restored {
+			sources[key] = source
+		}
+	}
+	return sources, unread
+}
+
+// change returns the planned change of key, noting the name it is stored under
+func (t *writeTarget) change(action, key, source string) plan.Change {
+	change := plan.Change{Action: action, Target: t.writer.Target(), Scope: t.name, Key: key}
+	if source != key {
+		change.Reason = "as " + source
+	}
+	return change
+}
+
+// record notes a change of keys made by command: the checksum of a dotenv file feller wrote
+// itself and an entry in the audit log
+func (t *writeTarget) record(command string, keys []string) {
+	if t.provider.Kind == providers.KindDotenv {
+		recordDotenvFile(config.LocalPath(t.pathMap.Path))
+	}
+	recordAudit(state.AuditEntry{Command: command, Provider: t.name, Map: t.pathMap.ID, Target: t.writer.Target(), Keys: keys})
+}
+
+// backup saves the values the source names had before command changes them, encrypted to
+// the backup recipients, so feller undo can restore them. Without recipients nothing is
+// saved, with a warning on every change; a backup that cannot be saved stops the change.
+func (t *writeTarget) backup(command string, names []string, before providers.SecretMap) error {
+	if !t.backups.Enabled() {
+		logger.Error("WARNING: no backup of %s before %s, so feller undo cannot restore it. Set backups.recipients in the config to enable backups.", t.writer.Target(), command)
+		return nil
+	}
+	b := secretBackup{
+		Version: backupVersion, Created: time.Now().UTC(), Command: command,
+		Provider: t.name, Map: t.pathMap.ID, Target: t.writer.Target(), Values: make(map[string]string, len(names)),
+	}
+	for _, name := range names {
+		if value, ok := before[name]; ok {
+			b.Values[name] = value
+		} else {
+			b.Absent = append(b.Absent, name)
+		}
+	}
+	plaintext, err := json.Marshal(b)
+	if err != nil {
+		return fmt.Errorf("failed to encode backup: %w", err)
+	}
+	ciphertext, err := bundle.Seal(plaintext, t.backups.Recipients)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt backup of %s: %w", t.writer.Target(), err)
+	}
+	configPath, err := config.ResolveConfigPath(cfgFile)
+	if err != nil {
+		return fmt.Errorf("failed to find config: %w", err)
+	}
+	path, err := state.SaveBackup(state.Dir(configPath), b.Created, ciphertext, t.backups.Keep())
+	if err != nil {
+		return fmt.Errorf("failed to back up %s, nothing was changed: %w", t.writer.Target(), err)
+	}
+	logger.Verbose("Backed up %d secret(s) of %s to %s", len(names), t.writer.Target(), path)
+	return nil
+}
+
+// sourceNames returns the source names of keys, sorted
+func sourceNames(sources map[string]string) []string {
+	names := make([]string, 0, len(sources))
+	for _, source := range sources {
+		names = append(names, source)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,209 @@
+// Package apply reads the operations files of 'feller apply': declarative lists of secret
+// changes that are reviewed like code and applied together.
+package apply
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/containifyci/feller/pkg/generate"
+	"gopkg.in/yaml.v3"
+)
+
+// Operation types
+const (
+	OpPut    = "put"
+	OpDelete = "delete"
+	OpSync   = "sync"
+)
+
+// Generators of the generate source of put operations
+var Generators = []string{"password", "hex", "base64", "uuid"}
+
+// defaultLength is the length of generated values when none is given: characters of
+// passwords, random bytes of hex and base64 values
+const defaultLength = 32
+
+// Manifest is the content of an operations file
+type Manifest struct {
+	Operations []Operation `yaml:"operations"`
+}
+
+// Operation is a single change. Exactly one of Put, Delete and Sync is set.
+type Operation struct {
+	Put    string `yaml:"put,omitempty"`    // Key to set
+	Delete string `yaml:"delete,omitempty"` // Key to remove
+	Sync   string `yaml:"sync,omitempty"`   // GitHub repository (owner/repo) to upload secrets to
+
+	Provider string `yaml:"provider,omitempty"` // Provider written by put and delete
+	Map      string `yaml:"map,omitempty"`      // Path map id, when the provider has several
+
+	// Value sources of put, exactly one of which is set. Values are never written in the
+	// manifest itself, so it can be committed and reviewed.
+	FromEnv  string `yaml:"from_env,omitempty"`
+	FromFile string `yaml:"from_file,omitempty"`
+	Generate string `yaml:"generate,omitempty"`
+	Length   int    `yaml:"length,omitempty"` // Length of generated values
+
+	Dependabot bool `yaml:"dependabot,omitempty"` // Also upload Dependabot secrets on sync
+}
+
+// Type returns the operation type: OpPut, OpDelete or OpSync
+func (o Operation) Type() string {
+	switch {
+	case o.Put != "":
+		return OpPut
+	case o.Delete != "":
+		return OpDelete
+	default:
+		return OpSync
+	}
+}
+
+// Key returns the key written by put and delete operations
+func (o Operation) Key() string {
+	if o.Put != "" {
+		return o.Put
+	}
+	return o.Delete
+}
+
+// Source describes where a put operation takes its value from, without the value
+func (o Operation) Source() string {
+	switch {
+	case o.FromEnv != "":
+		return "environment variable " + o.FromEnv
+	case o.FromFile != "":
+		return "file " + o.FromFile
+	default:
+		return "generated " + o.Generate
+	}
+}
+
+// Value resolves the value of a put operation from its source. Values read from files lose
+// one trailing line break, as written by editors and echo.
+func (o Operation) Value() (string, error) {
+	switch {
+	case o.FromEnv != "":
+		value, ok := os.LookupEnv(o.FromEnv)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", o.FromEnv)
+		}
+		return value, nil
+	case o.FromFile != "":
+		// #nosec G304 - The file is named by the reviewed operations file
+		data, err := os.ReadFile(o.FromFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s: %w", o.FromFile, err)
+		}
+		value := strings.TrimSuffix(string(data), "\n")
+		return strings.TrimSuffix(value, "\r"), nil
+	}
+
+	length := o.Length
+	if length == 0 {
+		length = defaultLength
+	}
+	switch o.Generate {
+	case "password":
+		return generate.Password(length, generate.Alphanumeric+generate.Symbols) //nolint:wrapcheck // describes the length
+	case "hex":
+		return generate.Hex(length) //nolint:wrapcheck // describes the length
+	case "base64":
+		return generate.Base64(length, false) //nolint:wrapcheck // describes the length
+	default:
+		return generate.UUID() //nolint:wrapcheck // describes the random source
+	}
+}
+
+// Load reads and validates the operations file at path
+func Load(path string) (*Manifest, error) {
+	// #nosec G304 - The operations file is named by the user
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read operations file: %w", err)
+	}
+	m, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("operations file %s: %w", path, err)
+	}
+	return m, nil
+}
+
+// Parse decodes and validates an operations file. Unknown fields are errors, since a
+// misspelled field would silently change what is applied.
+func Parse(data []byte) (*Manifest, error) {
+	m := &Manifest{}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(m); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse: %w", err)
+	}
+	if len(m.Operations) == 0 {
+		return nil, errors.New("no operations")
+	}
+	for i, op := range m.Operations {
+		if err := op.validate(); err != nil {
+			return nil, fmt.Errorf("operation %d: %w", i+1, err)
+		}
+	}
+	return m, nil
+}
+
+func (o Operation) validate() error {
+	set := 0
+	for _, field := range []string{o.Put, o.Delete, o.Sync} {
+		if field != "" {
+			set++
+		}
+	}
+	if set != 1 {
+		return errors.New("set exactly one of put, delete and sync")
+	}
+
+	sources := 0
+	for _, field := range []string{o.FromEnv, o.FromFile, o.Generate} {
+		if field != "" {
+			sources++
+		}
+	}
+	switch o.Type() {
+	case OpPut:
+		if sources != 1 {
+			return fmt.Errorf("put %s needs exactly one of from_env, from_file and generate", o.Put)
+		}
+		if o.Generate != "" && !slices.Contains(Generators, o.Generate) {
+			return fmt.Errorf("put %s: unknown generator %q (supported: %s)", o.Put, o.Generate, strings.Join(Generators, ", "))
+		}
+		if o.Length != 0 && (o.Generate == "" || o.Generate == "uuid") {
+			return fmt.Errorf("put %s: length only applies to the password, hex and base64 generators", o.Put)
+		}
+		if o.Length < 0 {
+			return fmt.Errorf("put %s: length must be positive", o.Put)
+		}
+	case OpDelete:
+		if sources != 0 || o.Length != 0 {
+			return fmt.Errorf("delete %s takes no value", o.Delete)
+		}
+	case OpSync:
+		if o.Provider != "" || o.Map != "" || sources != 0 || o.Length != 0 {
+			return fmt.Errorf("sync %s only takes dependabot", o.Sync)
+		}
+		if owner, name, ok := strings.Cut(o.Sync, "/"); !ok || owner == "" || name == "" || strings.Contains(name, "/") {
+			return fmt.Errorf("sync %q is not a GitHub repository (owner/repo)", o.Sync)
+		}
+		return nil
+	}
+	if o.Provider == "" {
+		return fmt.Errorf("%s %s needs a provider", o.Type(), o.Key())
+	}
+	if o.Dependabot {
+		return fmt.Errorf("%s %s: dependabot only applies to sync", o.Type(), o.Key())
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package apply
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name        string
+		data        string
+		expected    []string
+		errContains string
+	}{
+		{
+			name: "all operations",
+			data: `operations:
+  - put: API_KEY
+    provider: local
+    from_env: NEW_API_KEY
+  - delete: OLD_TOKEN
+    provider: local
+    map: app
+  - sync: owner/repo
+    dependabot: true
+`,
+			expected: []string{OpPut, OpDelete, OpSync},
+		},
+		{name: "empty", data: "", errContains: "no operations"},
+		{name: "unknown field", data: "operations:\n  - put: A\n    provider: p\n    value: secret\n", errContains: "field value not found"},
+		{name: "two types", data: "operations:\n  - put: A\n    delete: B\n    provider: p\n", errContains: "operation 1: set exactly one"},
+		{name: "no source", data: "operations:\n  - put: A\n    provider: p\n", errContains: "put A needs exactly one of"},
+		{name: "two sources", data: "operations:\n  - put: A\n    provider: p\n    from_env: A\n    generate: hex\n", errContains: "put A needs exactly one of"},
+		{name: "unknown generator", data: "operations:\n  - put: A\n    provider: p\n    generate: pin\n", errContains: `unknown generator "pin"`},
+		{name: "uuid length", data: "operations:\n  - put: A\n    provider: p\n    generate: uuid\n    length: 8\n", errContains: "length only applies"},
+		{name: "no provider", data: "operations:\n  - delete: A\n", errContains: "delete A needs a provider"},
+		{name: "delete value", data: "operations:\n  - delete: A\n    provider: p\n    from_env: A\n", errContains: "delete A takes no value"},
+		{name: "sync provider", data: "operations:\n  - sync: o/r\n    provider: p\n", errContains: "sync o/r only takes dependabot"},
+		{name: "sync repo", data: "operations:\n  - sync: repo\n", errContains: `sync "repo" is not a GitHub repository`},
+		{name: "put dependabot", data: "operations:\n  - put: A\n    provider: p\n    generate: hex\n    dependabot: true\n", errContains: "dependabot only applies to sync"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			m, err := Parse([]byte(tt.data))
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Errorf("Parse() error = %v, expected to contain %q", err, tt.errContains)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			var types []string
+			for _, op := range m.Operations {
+				types = append(types, op.Type())
+			}
+			if strings.Join(types, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("Parse() types = %v, expected %v", types, tt.expected)
+			}
+		})
+	}
+}
+
+//nolint:paralleltest // sets an environment variable
+func TestOperationValue(t *testing.T) {
+	t.Setenv("APPLY_TEST_VALUE", "from env")
+	file := filepath.Join(t.TempDir(), "value")
+	if err := os.WriteFile(file, []byte("from file\r\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		op          Operation
+		expected    string
+		length      int
+		errContains string
+	}{
+		{name: "env", op: Operation{FromEnv: "APPLY_TEST_VALUE"}, expected: "from env"},
+		{name: "unset env", op: Operation{FromEnv: "APPLY_TEST_UNSET"}, errContains: "APPLY_TEST_UNSET is not set"},
+		{name: "file", op: Operation{FromFile: file}, expected: "from file"},
+		{name: "missing file", op: Operation{FromFile: file + ".missing"}, errContains: "failed to read"},
+		{name: "password", op: Operation{Generate: "password", Length: 12}, length: 12},
+		{name: "hex default", op: Operation{Generate: "hex"}, length: 64},
+		{name: "uuid", op: Operation{Generate: "uuid"}, length: 36},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.op.Value()
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Errorf("Value() error = %v, expected to contain %q", err, tt.errContains)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Value() error = %v", err)
+			}
+			if tt.expected != "" && got != tt.expected {
+				t.Errorf("Value() = %q, expected %q", got, tt.expected)
+			}
+			if tt.length != 0 && len(got) != tt.length {
+				t.Errorf("Value() = %q, expected length %d", got, tt.length)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "ops.yml")
+	if err := os.WriteFile(path, []byte("operations:\n  - sync: owner\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "operations file "+path+": operation 1") {
+		t.Errorf("Load() error = %v, expected to name the file and operation", err)
+	}
+}
@@ -0,0 +1,156 @@
+// Package bundle implements feller's secret bundle: every collected secret with its origin,
+// encrypted with age so it can be handed from one CI stage or machine to another and read
+// back by the bundle provider. Encryption is delegated to the age tool, which must be
+// installed; plaintext is only ever passed through pipes, never written to disk.
+package bundle
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Version is the format version of bundles written by this package
+const Version = 1
+
+// KeyEnv holds an age identity (AGE-SECRET-KEY-...) used to decrypt bundles when no
+// identity file is configured, e.g. from a CI secret
+const KeyEnv = "FELLER_AGE_KEY"
+
+// Bundle is the decrypted content of a bundle file
+type Bundle struct {
+	Version int              `json:"version"`
+	Created time.Time        `json:"created"`
+	Secrets map[string]Entry `json:"secrets"`
+}
+
+// Entry is a secret value and where it was collected from
+type Entry struct {
+	Value    string `json:"value"`
+	Provider string `json:"provider,omitempty"`
+	Kind     string `json:"kind,omitempty"`
+	MapID    string `json:"map_id,omitempty"`
+}
+
+// Keys returns the sorted keys of the bundle
+func (b *Bundle) Keys() []string {
+	keys := make([]string, 0, len(b.Secrets))
+	for key := range b.Secrets {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Parse decodes a decrypted bundle
+func Parse(data []byte) (*Bundle, error) {
+	var b Bundle
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, fmt.Errorf("invalid bundle: %w", err)
+	}
+	if b.Version != Version {
+		return nil, fmt.Errorf("unsupported bundle version %d (supported: %d)", b.Version, Version)
+	}
+	if b.Secrets == nil {
+		b.Secrets = map[string]Entry{}
+	}
+	return &b, nil
+}
+
+// Encrypt encodes b and encrypts it to the age recipients, ASCII armored so it can be
+// stored anywhere text can
+func Encrypt(b *Bundle, recipients []string) ([]byte, error) {
+	plaintext, err := json.Marshal(b)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode bundle: %w", err)
+	}
+	return Seal(plaintext, recipients)
+}
+
+// Seal encrypts plaintext to the age recipients, ASCII armored
+func Seal(plaintext []byte, recipients []string) ([]byte, error) {
+	if len(recipients) == 0 {
+		return nil, errors.New("at least one recipient is required")
+	}
+	args := []string{"--encrypt", "--armor"}
+	for _, recipient := range recipients {
+		args = append(args, "--recipient", recipient)
+	}
+	return runAge(args, plaintext)
+}
+
+// Decrypt decrypts and decodes a bundle with the identity file at identityPath, or with
+// the identity in KeyEnv when identityPath is empty
+func Decrypt(ciphertext []byte, identityPath string) (*Bundle, error) {
+	plaintext, err := Open(ciphertext, identityPath)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(plaintext)
+}
+
+// Open decrypts ciphertext with the identity file at identityPath, or with the identity in
+// KeyEnv when identityPath is empty
+func Open(ciphertext []byte, identityPath string) ([]byte, error) {
+	if identityPath == "" {
+		key := strings.TrimSpace(os.Getenv(KeyEnv))
+		if key == "" {
+			return nil, fmt.Errorf("no age identity: set the identity option of the provider or %s", KeyEnv)
+		}
+		path, cleanup, err := writeIdentity(key)
+		if err != nil {
+			return nil, err
+		}
+		defer cleanup()
+		identityPath = path
+	}
+
+	return runAge([]string{"--decrypt", "--identity", identityPath}, ciphertext)
+}
+
+// writeIdentity stores key in a private temporary file, since age reads identities from files
+func writeIdentity(key string) (string, func(), error) {
+	f, err := os.CreateTemp("", "feller-age-*.txt")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create identity file: %w", err)
+	}
+	cleanup := func() { _ = os.Remove(f.Name()) }
+	_, err = f.WriteString(key + "\n")
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("failed to write identity file: %w", err)
+	}
+	return f.Name(), cleanup, nil
+}
+
+// runAge runs the age tool with input on stdin and returns its stdout
+func runAge(args []string, input []byte) ([]byte, error) {
+	agePath, err := exec.LookPath("age")
+	if err != nil {
+		return nil, errors.New("age not found in PATH - please install it to use secret bundles")
+	}
+
+	var stdout, stderr bytes.Buffer
+	// #nosec G204 - The tool is fixed, arguments are recipients and identity paths
+	cmd := exec.CommandContext(context.Background(), agePath, args...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("age failed: %s", msg)
+		}
+		return nil, fmt.Errorf("age failed: %w", err)
+	}
+	return stdout.Bytes(), nil
+}
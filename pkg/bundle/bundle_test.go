@@ -0,0 +1,97 @@
+package bundle_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/containifyci/feller/pkg/bundle"
+	"github.com/containifyci/feller/pkg/fellertest"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name         string
+		input        string
+		errContains  string
+		expectedKeys []string
+	}{
+		{name: "secrets", input: `{"version":1,"secrets":{"B":{"value":"2"},"A":{"value":"1","provider":"gsm"}}}`, expectedKeys: []string{"A", "B"}},
+		{name: "no secrets", input: `{"version":1}`, expectedKeys: []string{}},
+		{name: "newer version", input: `{"version":2,"secrets":{}}`, errContains: "unsupported bundle version 2"},
+		{name: "not json", input: "AGE-ENCRYPTED", errContains: "invalid bundle"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			b, err := bundle.Parse([]byte(tt.input))
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Errorf("Parse() error = %v, expected to contain %q", err, tt.errContains)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse() unexpected error = %v", err)
+			}
+			if keys := b.Keys(); !reflect.DeepEqual(keys, tt.expectedKeys) {
+				t.Errorf("Parse() keys = %v, want %v", keys, tt.expectedKeys)
+			}
+		})
+	}
+}
+
+//nolint:paralleltest // installs a fake age tool in PATH
+func TestEncryptDecrypt(t *testing.T) {
+	fellertest.FakeAge(t)
+	original := &bundle.Bundle{
+		Version: bundle.Version,
+		Created: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		Secrets: map[string]bundle.Entry{
+			"DATABASE_URL": {Value: "postgres://u:p@db/app", Provider: "gsm", Kind: "google_secretmanager", MapID: "ci"},
+			"MULTILINE":    {Value: "a\nb"},
+		},
+	}
+
+	ciphertext, err := bundle.Encrypt(original, []string{fellertest.FakeAgeRecipient, "age1second"})
+	if err != nil {
+		t.Fatalf("Encrypt() unexpected error = %v", err)
+	}
+	if !strings.HasPrefix(string(ciphertext), "FAKE-AGE "+fellertest.FakeAgeRecipient+" age1second\n") {
+		t.Errorf("Encrypt() did not pass every recipient: %q", ciphertext)
+	}
+
+	identity := filepath.Join(t.TempDir(), "key.txt")
+	if err := os.WriteFile(identity, []byte("AGE-SECRET-KEY-1\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write identity: %v", err)
+	}
+	decrypted, err := bundle.Decrypt(ciphertext, identity)
+	if err != nil {
+		t.Fatalf("Decrypt() unexpected error = %v", err)
+	}
+	if !reflect.DeepEqual(decrypted, original) {
+		t.Errorf("Decrypt() = %+v, want %+v", decrypted, original)
+	}
+
+	// The identity can come from the environment instead of a file
+	t.Setenv(bundle.KeyEnv, "AGE-SECRET-KEY-1")
+	if _, err := bundle.Decrypt(ciphertext, ""); err != nil {
+		t.Errorf("Decrypt() with %s unexpected error = %v", bundle.KeyEnv, err)
+	}
+
+	t.Setenv(bundle.KeyEnv, "")
+	if _, err := bundle.Decrypt(ciphertext, ""); err == nil || !strings.Contains(err.Error(), "no age identity") {
+		t.Errorf("Decrypt() without identity error = %v, want no age identity", err)
+	}
+	if _, err := bundle.Decrypt([]byte("garbage\n"), identity); err == nil || !strings.Contains(err.Error(), "age failed: invalid header") {
+		t.Errorf("Decrypt() of garbage error = %v, want age failure", err)
+	}
+	if _, err := bundle.Encrypt(original, nil); err == nil {
+		t.Errorf("Encrypt() without recipients expected error but got none")
+	}
+}
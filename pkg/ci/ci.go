@@ -0,0 +1,53 @@
+// Package ci detects the CI system feller runs on. On every supported system secrets are
+// mapped to environment variables by the pipeline, which feller reads without teller.
+package ci
+
+// Supported CI platform ids
+const (
+	GitHubActions = "github"
+	GitLab        = "gitlab"
+	CircleCI      = "circleci"
+	Buildkite     = "buildkite"
+	Jenkins       = "jenkins"
+)
+
+// Platform describes a CI system
+type Platform struct {
+	ID     string
+	Name   string                                // Display name used in messages
+	detect func(getenv func(string) string) bool // Reports whether the environment is this platform
+}
+
+// isTrue returns a detector for a marker variable the platform sets to "true"
+func isTrue(name string) func(getenv func(string) string) bool {
+	return func(getenv func(string) string) bool { return getenv(name) == "true" }
+}
+
+// platforms in detection order
+var platforms = []Platform{
+	{ID: GitHubActions, Name: "GitHub Actions", detect: isTrue("GITHUB_ACTIONS")},
+	{ID: GitLab, Name: "GitLab CI", detect: isTrue("GITLAB_CI")},
+	{ID: CircleCI, Name: "CircleCI", detect: isTrue("CIRCLECI")},
+	{ID: Buildkite, Name: "Buildkite", detect: isTrue("BUILDKITE")},
+	{ID: Jenkins, Name: "Jenkins", detect: func(getenv func(string) string) bool { return getenv("JENKINS_URL") != "" }},
+}
+
+// Detect returns the CI platform of the environment read through getenv
+func Detect(getenv func(string) string) (Platform, bool) {
+	for _, platform := range platforms {
+		if platform.detect(getenv) {
+			return platform, true
+		}
+	}
+	return Platform{}, false
+}
+
+// Lookup returns the platform with the given id
+func Lookup(id string) (Platform, bool) {
+	for _, platform := range platforms {
+		if platform.ID == id {
+			return platform, true
+		}
+	}
+	return Platform{}, false
+}
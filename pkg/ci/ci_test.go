@@ -0,0 +1,42 @@
+package ci
+
+import "testing"
+
+func TestDetect(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		env      map[string]string
+		name     string
+		expected string
+	}{
+		{name: "github actions", env: map[string]string{"GITHUB_ACTIONS": "true"}, expected: GitHubActions},
+		{name: "gitlab", env: map[string]string{"GITLAB_CI": "true", "CI": "true"}, expected: GitLab},
+		{name: "circleci", env: map[string]string{"CIRCLECI": "true"}, expected: CircleCI},
+		{name: "buildkite", env: map[string]string{"BUILDKITE": "true"}, expected: Buildkite},
+		{name: "jenkins", env: map[string]string{"JENKINS_URL": "https://jenkins.example.com/"}, expected: Jenkins},
+		{name: "github actions wins", env: map[string]string{"JENKINS_URL": "https://jenkins", "GITHUB_ACTIONS": "true"}, expected: GitHubActions},
+		{name: "generic CI is not supported", env: map[string]string{"CI": "true"}},
+		{name: "marker not true", env: map[string]string{"GITLAB_CI": "false"}},
+		{name: "local", env: map[string]string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			platform, ok := Detect(func(name string) string { return tt.env[name] })
+			if ok != (tt.expected != "") || platform.ID != tt.expected {
+				t.Errorf("Detect() = %q, %v, want %q", platform.ID, ok, tt.expected)
+			}
+		})
+	}
+}
+
+func TestLookup(t *testing.T) {
+	t.Parallel()
+	if platform, ok := Lookup(GitLab); !ok || platform.Name != "GitLab CI" {
+		t.Errorf("Lookup(%q) = %+v, %v", GitLab, platform, ok)
+	}
+	if _, ok := Lookup("travis"); ok {
+		t.Errorf("Lookup(travis) found an unsupported platform")
+	}
+}
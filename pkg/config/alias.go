@@ -0,0 +1,86 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// AliasArgs returns the feller arguments the alias name expands to. ok is false when the
+// config defines no such alias.
+func (c *TellerConfig) AliasArgs(name string) (args []string, ok bool, err error) {
+	line, ok := c.Aliases[name]
+	if !ok {
+		return nil, false, nil
+	}
+	args, err = SplitArgs(line)
+	if err != nil {
+		return nil, true, fmt.Errorf("invalid alias %s: %w", name, err)
+	}
+	if len(args) == 0 {
+		return nil, true, fmt.Errorf("invalid alias %s: no arguments", name)
+	}
+	return args, true, nil
+}
+
+// SplitArgs splits a command line into arguments the way a POSIX shell splits words:
+// whitespace separates arguments, single quotes preserve everything literally, and inside
+// double quotes and unquoted text a backslash escapes the next character. Variables and
+// globs are not expanded.
+func SplitArgs(line string) ([]string, error) {
+	var (
+		args    []string
+		current strings.Builder
+		inWord  bool
+		quote   rune
+		escaped bool
+	)
+
+	for _, r := range line {
+		switch {
+		case escaped:
+			// Inside double quotes a backslash only escapes characters special there
+			if quote == '"' && !strings.ContainsRune("\"\\$`", r) {
+				current.WriteRune('\\')
+			}
+			current.WriteRune(r)
+			escaped = false
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '\\':
+			escaped, inWord = true, true
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inWord = r, true
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+			if inWord {
+				args = append(args, current.String())
+				current.Reset()
+				inWord = false
+			}
+		default:
+			current.WriteRune(r)
+			inWord = true
+		}
+	}
+
+	switch {
+	case escaped:
+		return nil, errors.New("trailing backslash")
+	case quote != 0:
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if inWord {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
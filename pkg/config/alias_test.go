@@ -0,0 +1,72 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitArgs(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name        string
+		input       string
+		errContains string
+		expected    []string
+	}{
+		{name: "words", input: "run --env prod -- ./deploy.sh", expected: []string{"run", "--env", "prod", "--", "./deploy.sh"}},
+		{name: "extra whitespace", input: "  export\tjson \n", expected: []string{"export", "json"}},
+		{name: "single quotes", input: `run -- sh -c 'echo "$HOME" \n'`, expected: []string{"run", "--", "sh", "-c", `echo "$HOME" \n`}},
+		{name: "double quotes", input: `run -- echo "a \"b\" \$c \d"`, expected: []string{"run", "--", "echo", `a "b" $c \d`}},
+		{name: "escaped space", input: `export --out my\ file.json`, expected: []string{"export", "--out", "my file.json"}},
+		{name: "empty quoted argument", input: `run -- echo ''`, expected: []string{"run", "--", "echo", ""}},
+		{name: "adjacent quotes", input: `--providers=a,"b c"`, expected: []string{"--providers=a,b c"}},
+		{name: "empty", input: "", expected: nil},
+		{name: "unterminated single quote", input: "run 'x", errContains: "unterminated ' quote"},
+		{name: "unterminated double quote", input: `run "x`, errContains: `unterminated " quote`},
+		{name: "trailing backslash", input: `run \`, errContains: "trailing backslash"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			result, err := SplitArgs(tt.input)
+
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Errorf("SplitArgs() error = %v, expected to contain %q", err, tt.errContains)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SplitArgs() unexpected error = %v", err)
+			}
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("SplitArgs() = %#v, want %#v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestAliasArgs(t *testing.T) {
+	t.Parallel()
+	cfg := &TellerConfig{Aliases: map[string]string{
+		"deploy": "run -- ./deploy.sh",
+		"broken": "run 'x",
+		"blank":  "  ",
+	}}
+
+	args, ok, err := cfg.AliasArgs("deploy")
+	if err != nil || !ok || !reflect.DeepEqual(args, []string{"run", "--", "./deploy.sh"}) {
+		t.Errorf("AliasArgs(deploy) = %v, %v, %v", args, ok, err)
+	}
+	if _, ok, err := cfg.AliasArgs("missing"); ok || err != nil {
+		t.Errorf("AliasArgs(missing) = %v, %v, want not found", ok, err)
+	}
+	if _, ok, err := cfg.AliasArgs("broken"); !ok || err == nil || !strings.Contains(err.Error(), "invalid alias broken") {
+		t.Errorf("AliasArgs(broken) = %v, %v, want invalid alias error", ok, err)
+	}
+	if _, ok, err := cfg.AliasArgs("blank"); !ok || err == nil || !strings.Contains(err.Error(), "no arguments") {
+		t.Errorf("AliasArgs(blank) = %v, %v, want no arguments error", ok, err)
+	}
+}
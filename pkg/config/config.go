@@ -5,6 +5,9 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
 
 	"github.com/containifyci/feller/pkg/logger"
 	"gopkg.in/yaml.v3"
@@ -12,7 +15,159 @@ import (
 
 // TellerConfig represents the structure of a .teller.yml configuration file
 type TellerConfig struct {
-	Providers map[string]Provider `yaml:"providers"`
+	Version    int                    `yaml:"version,omitempty"` // Config schema version, 1 when absent
+	Providers  map[string]Provider    `yaml:"providers"`
+	Hooks      Hooks                  `yaml:"hooks,omitempty"`
+	Transforms map[string][]Transform `yaml:"transforms,omitempty"`
+	Schema     map[string]string      `yaml:"schema,omitempty"`  // Output key -> value type
+	Aliases    map[string]string      `yaml:"aliases,omitempty"` // Alias name -> feller arguments
+	Messages   Messages               `yaml:"messages,omitempty"`
+	Fallback   *bool                  `yaml:"fallback,omitempty"` // false resolves secrets natively outside CI too
+	Targets    map[string]Target      `yaml:"targets,omitempty"`  // Target name -> external system reconciled with the secrets
+	Backups    Backups                `yaml:"backups,omitempty"`
+	Collisions string                 `yaml:"collisions,omitempty"` // Strategy for keys of several providers, last-wins when empty
+	Sync       map[string]SyncTargets `yaml:"sync,omitempty"`       // Output key -> the targets it is written to
+
+	// OnCI is set by feller on CI, where Google Secret Manager maps read the environment the
+	// workflow passes through instead of the API
+	OnCI bool `yaml:"-"`
+
+	deprecated []DeprecatedField
+}
+
+// FallbackDisabled reports whether the config opts out of the teller fallback
+func (c *TellerConfig) FallbackDisabled() bool {
+	return c.Fallback != nil && !*c.Fallback
+}
+
+// Transform is a single post-processing step applied to a collected secret value.
+// Steps without arguments are written as plain strings (e.g. "trim"), steps with
+// arguments as single-key mappings (e.g. "json_extract: db.password").
+type Transform struct {
+	Type     string
+	From     string
+	To       string
+	Path     string
+	Template string
+}
+
+// UnmarshalYAML decodes the string or single-key mapping form of a transform
+func (t *Transform) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		t.Type = node.Value
+		return nil
+	case yaml.MappingNode:
+		if len(node.Content) != 2 {
+			return fmt.Errorf("line %d: transform must have exactly one type", node.Line)
+		}
+		t.Type = node.Content[0].Value
+		arg := node.Content[1]
+		switch t.Type {
+		case "replace":
+			var replace struct {
+				From string `yaml:"from"`
+				To   string `yaml:"to"`
+			}
+			if err := arg.Decode(&replace); err != nil {
+				return fmt.Errorf("line %d: invalid replace transform: %w", node.Line, err)
+			}
+			t.From, t.To = replace.From, replace.To
+		case "json_extract":
+			t.Path = arg.Value
+		case "template":
+			t.Template = arg.Value
+		default:
+			return fmt.Errorf("line %d: transform %q does not take arguments", node.Line, t.Type)
+		}
+		return nil
+	default:
+		return fmt.Errorf("line %d: transform must be a string or a mapping", node.Line)
+	}
+}
+
+// MarshalYAML encodes a transform in the form UnmarshalYAML accepts
+func (t Transform) MarshalYAML() (any, error) {
+	switch t.Type {
+	case "replace":
+		return map[string]map[string]string{t.Type: {"from": t.From, "to": t.To}}, nil
+	case "json_extract":
+		return map[string]string{t.Type: t.Path}, nil
+	case "template":
+		return map[string]string{t.Type: t.Template}, nil
+	default:
+		return t.Type, nil
+	}
+}
+
+// Hooks represents commands executed around the child process of feller run
+type Hooks struct {
+	PreRun  []string `yaml:"pre_run,omitempty"`
+	PostRun []string `yaml:"post_run,omitempty"`
+}
+
+// Target kinds
+const (
+	TargetKindGitHub     = "github"     // The secrets of a GitHub repository
+	TargetKindVercel     = "vercel"     // The environment variables of a Vercel project
+	TargetKindCloudflare = "cloudflare" // The secrets of a Cloudflare Worker
+	TargetKindFly        = "fly"        // The secrets of a Fly.io app
+)
+
+// TargetKinds lists the target kinds 'feller reconcile' and 'feller sync' can write
+var TargetKinds = []string{TargetKindGitHub, TargetKindVercel, TargetKindCloudflare, TargetKindFly}
+
+// VercelEnvironments are the environments of a Vercel project, all of which a vercel target
+// writes to unless it names some
+var VercelEnvironments = []string{"production", "preview", "development"}
+
+// Target is an external system 'feller reconcile' keeps in line with the secrets of the config
+type Target struct {
+	Kind         string   `yaml:"kind"`
+	Repo         string   `yaml:"repo,omitempty"`         // GitHub repository (owner/repo)
+	Dependabot   bool     `yaml:"dependabot,omitempty"`   // Also reconcile the Dependabot secrets
+	Project      string   `yaml:"project,omitempty"`      // Vercel project id or name
+	Team         string   `yaml:"team,omitempty"`         // Vercel team id owning the project
+	Environments []string `yaml:"environments,omitempty"` // Vercel environments; VercelEnvironments when empty
+	Account      string   `yaml:"account,omitempty"`      // Cloudflare account id owning the Worker
+	Script       string   `yaml:"script,omitempty"`       // Cloudflare Worker name
+	App          string   `yaml:"app,omitempty"`          // Fly.io app name
+	Providers    []string `yaml:"providers,omitempty"`    // Only secrets supplied by these providers; all when empty
+	Keys         []string `yaml:"keys,omitempty"`         // Only these output keys; all when empty
+}
+
+// SyncTargets maps the name of each target a key is written to onto the name the key has
+// there, the key itself when empty. Keys listed in the sync section are only written to
+// their targets, regardless of the providers and keys of the targets.
+type SyncTargets map[string]string
+
+// DefaultBackupRetention is the number of backups kept when the config sets none
+const DefaultBackupRetention = 10
+
+// Backups configures the encrypted backups feller writes before it changes secrets
+type Backups struct {
+	Recipients []string `yaml:"recipients,omitempty"` // age recipients of the backups; no backups when empty
+	Identity   string   `yaml:"identity,omitempty"`   // age identity file that decrypts them for feller undo
+	Retention  int      `yaml:"retention,omitempty"`  // Backups kept, DefaultBackupRetention when 0
+}
+
+// Enabled reports whether backups are configured
+func (b Backups) Enabled() bool {
+	return len(b.Recipients) > 0
+}
+
+// Keep returns the number of backups to keep
+func (b Backups) Keep() int {
+	if b.Retention > 0 {
+		return b.Retention
+	}
+	return DefaultBackupRetention
+}
+
+// Messages customizes the guidance feller prints when secrets cannot be resolved
+type Messages struct {
+	MissingVariables     string `yaml:"missing_variables,omitempty"`      // Template of the missing environment variable error
+	MissingVariablesFile string `yaml:"missing_variables_file,omitempty"` // File holding that template
 }
 
 // Provider represents a single provider configuration
@@ -24,24 +179,32 @@ type Provider struct {
 
 // PathMap represents a path mapping within a provider
 type PathMap struct {
-	Keys map[string]string `yaml:"keys,omitempty"`
-	ID   string            `yaml:"id"`
-	Path string            `yaml:"path"`
+	Keys    map[string]string   `yaml:"keys,omitempty"`
+	ID      string              `yaml:"id"`
+	Path    string              `yaml:"path"`
+	Include []string            `yaml:"include,omitempty"`  // Globs of keys discovery resolves, all when empty
+	Exclude []string            `yaml:"exclude,omitempty"`  // Globs of keys discovery skips
+	Version string              `yaml:"version,omitempty"`  // Secret version to read, LatestVersion when empty
+	Stage   string              `yaml:"stage,omitempty"`    // AWS staging label or SSM parameter label to read
+	Tags    []string            `yaml:"tags,omitempty"`     // Secret sets the map belongs to, selected with --tags
+	KeyTags map[string][]string `yaml:"key_tags,omitempty"` // Source key -> tags replacing those of the map
+}
+
+// LatestVersion selects the current version of a secret
+const LatestVersion = "latest"
+
+// Pinned reports whether the map requests a version other than the latest
+func (m PathMap) Pinned() bool {
+	return (m.Version != "" && m.Version != LatestVersion) || m.Stage != ""
 }
 
 // LoadConfig loads and parses a Teller configuration file
 func LoadConfig(configPath string) (*TellerConfig, error) {
 	logger.Debug("Loading configuration...")
 
-	if configPath == "" {
-		logger.Debug("No config path provided, searching upwards from current directory")
-		// Find config file upwards from current directory
-		var err error
-		configPath, err = findConfigFile()
-		if err != nil {
-			logger.Debug("Config file search failed: %v", err)
-			return nil, err
-		}
+	configPath, err := ResolveConfigPath(configPath)
+	if err != nil {
+		return nil, err
 	}
 
 	logger.Debug("Using config file: %s", configPath)
@@ -55,10 +218,19 @@ func LoadConfig(configPath string) (*TellerConfig, error) {
 	logger.Debug("Config file size: %d bytes", len(data))
 
 	var config TellerConfig
-	if err := yaml.Unmarshal(data, &config); err != nil {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		logger.Debug("Failed to parse YAML: %v", err)
+		return nil, fmt.Errorf("failed to parse config file %s: %w", configPath, err)
+	}
+	if err := doc.Decode(&config); err != nil && len(doc.Content) > 0 {
 		logger.Debug("Failed to parse YAML: %v", err)
 		return nil, fmt.Errorf("failed to parse config file %s: %w", configPath, err)
 	}
+	config.deprecated = FindDeprecated(&doc)
+	if err := config.checkVersion(); err != nil {
+		return nil, fmt.Errorf("config file %s: %w", configPath, err)
+	}
 
 	logger.Debug("Parsed %d providers from config", len(config.Providers))
 	for name, provider := range config.Providers {
@@ -68,6 +240,35 @@ func LoadConfig(configPath string) (*TellerConfig, error) {
 	return &config, nil
 }
 
+// ResolveConfigPath returns configPath, the .teller.yml in it when it is a directory, or the
+// nearest .teller.yml when it is empty
+func ResolveConfigPath(configPath string) (string, error) {
+	if configPath != "" {
+		configPath = LocalPath(configPath)
+		if info, err := os.Stat(configPath); err == nil && info.IsDir() {
+			return filepath.Join(configPath, ".teller.yml"), nil
+		}
+		return configPath, nil
+	}
+
+	logger.Debug("No config path provided, searching upwards from current directory")
+	path, err := findConfigFile()
+	if err != nil {
+		logger.Debug("Config file search failed: %v", err)
+		return "", err
+	}
+	return path, nil
+}
+
+// LocalPath converts a path written with either separator, e.g. in a config authored on
+// Windows, to the separator of the current platform
+func LocalPath(path string) string {
+	if filepath.Separator != '\\' {
+		path = strings.ReplaceAll(path, `\`, "/")
+	}
+	return filepath.FromSlash(path)
+}
+
 // findConfigFile searches for .teller.yml upward from the current directory
 func findConfigFile() (string, error) {
 	dir, err := os.Getwd()
@@ -109,3 +310,135 @@ func (c *TellerConfig) GetProvidersByKind(kind string) map[string]Provider {
 	}
 	return providers
 }
+
+// SelectProviders restricts the configuration to the included providers (all when empty)
+// minus the excluded ones. Unknown provider names are reported as errors.
+func (c *TellerConfig) SelectProviders(include, exclude []string) error {
+	if len(include) == 0 && len(exclude) == 0 {
+		return nil
+	}
+
+	for _, name := range append(append([]string{}, include...), exclude...) {
+		if _, exists := c.Providers[name]; !exists {
+			return fmt.Errorf("unknown provider %q (available: %s)", name, strings.Join(c.providerNames(), ", "))
+		}
+	}
+
+	selected := make(map[string]Provider)
+	if len(include) == 0 {
+		for name, provider := range c.Providers {
+			selected[name] = provider
+		}
+	} else {
+		for _, name := range include {
+			selected[name] = c.Providers[name]
+		}
+	}
+	for _, name := range exclude {
+		delete(selected, name)
+	}
+
+	if len(selected) == 0 {
+		return errors.New("provider selection excludes every configured provider")
+	}
+
+	logger.Debug("Selected %d of %d providers", len(selected), len(c.Providers))
+	c.Providers = selected
+	return nil
+}
+
+// TagsOf returns the tags of the source key of the map: its key_tags entry, or the tags of
+// the map when it has none
+func (m PathMap) TagsOf(key string) []string {
+	if tags, ok := m.KeyTags[key]; ok {
+		return tags
+	}
+	return m.Tags
+}
+
+// SelectTags restricts the config to the secrets tagged with one of tags: maps with keys keep
+// the keys whose tags match, maps without keys are kept whole when their own tags match, and
+// providers left without maps are removed. Untagged secrets are never selected.
+func (c *TellerConfig) SelectTags(tags []string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+
+	known := c.Tags()
+	for _, tag := range tags {
+		if !slices.Contains(known, tag) {
+			return fmt.Errorf("unknown tag %q (available: %s)", tag, strings.Join(known, ", "))
+		}
+	}
+	matches := func(keyTags []string) bool {
+		return slices.ContainsFunc(keyTags, func(tag string) bool { return slices.Contains(tags, tag) })
+	}
+
+	selected := make(map[string]Provider)
+	for name, provider := range c.Providers {
+		var maps []PathMap
+		for _, m := range provider.Maps {
+			if len(m.Keys) == 0 {
+				if matches(m.Tags) {
+					maps = append(maps, m)
+				}
+				continue
+			}
+			keys := make(map[string]string)
+			for from, to := range m.Keys {
+				if matches(m.TagsOf(from)) {
+					keys[from] = to
+				}
+			}
+			if len(keys) > 0 {
+				m.Keys = keys
+				maps = append(maps, m)
+			}
+		}
+		if len(maps) > 0 {
+			provider.Maps = maps
+			selected[name] = provider
+		}
+	}
+
+	if len(selected) == 0 {
+		return errors.New("tag selection excludes every configured secret")
+	}
+
+	logger.Debug("Selected %d of %d providers by tags %v", len(selected), len(c.Providers), tags)
+	c.Providers = selected
+	return nil
+}
+
+// Tags returns the sorted tags used by the maps and keys of the config
+func (c *TellerConfig) Tags() []string {
+	seen := make(map[string]bool)
+	for _, provider := range c.Providers {
+		for _, m := range provider.Maps {
+			for _, tag := range m.Tags {
+				seen[tag] = true
+			}
+			for _, keyTags := range m.KeyTags {
+				for _, tag := range keyTags {
+					seen[tag] = true
+				}
+			}
+		}
+	}
+	tags := make([]string, 0, len(seen))
+	for tag := range seen {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// providerNames returns the sorted names of all configured providers
+func (c *TellerConfig) providerNames() []string {
+	names := make([]string, 0, len(c.Providers))
+	for name := range c.Providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -3,8 +3,12 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"reflect"
+	"sort"
 	"strings"
 	"testing"
+
+	"gopkg.in/yaml.v3"
 )
 
 func TestLoadConfig(t *testing.T) {
@@ -351,3 +355,260 @@ func createTellerYmlInCurrentDir(t *testing.T) string {
 func cleanupTempFile(path string) {
 	os.Remove(path)
 }
+
+func TestTransformUnmarshalYAML(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name        string
+		yamlData    string
+		errContains string
+		expected    []Transform
+		wantErr     bool
+	}{
+		{
+			name: "all forms",
+			yamlData: `
+- trim
+- replace: {from: "http://", to: "https://"}
+- json_extract: db.password
+- template: "prefix-{{ .Value }}"
+`,
+			expected: []Transform{
+				{Type: "trim"},
+				{Type: "replace", From: "http://", To: "https://"},
+				{Type: "json_extract", Path: "db.password"},
+				{Type: "template", Template: "prefix-{{ .Value }}"},
+			},
+		},
+		{
+			name:        "argument for argumentless transform",
+			yamlData:    "- upper: yes",
+			wantErr:     true,
+			errContains: `transform "upper" does not take arguments`,
+		},
+		{
+			name:        "multiple types in one step",
+			yamlData:    "- {trim: x, upper: y}",
+			wantErr:     true,
+			errContains: "exactly one type",
+		},
+		{
+			name:        "sequence step",
+			yamlData:    "- [trim]",
+			wantErr:     true,
+			errContains: "must be a string or a mapping",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var transforms []Transform
+			err := yaml.Unmarshal([]byte(tt.yamlData), &transforms)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Unmarshal() expected error but got none")
+				} else if !strings.Contains(err.Error(), tt.errContains) {
+					t.Errorf("Unmarshal() error = %v, expected to contain %q", err, tt.errContains)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Unmarshal() unexpected error = %v", err)
+			}
+			if !reflect.DeepEqual(transforms, tt.expected) {
+				t.Errorf("Unmarshal() = %+v, want %+v", transforms, tt.expected)
+			}
+
+			// Marshaling must produce the form that unmarshals back to the same steps
+			data, err := yaml.Marshal(transforms)
+			if err != nil {
+				t.Fatalf("Marshal() unexpected error = %v", err)
+			}
+			var roundTrip []Transform
+			if err := yaml.Unmarshal(data, &roundTrip); err != nil {
+				t.Fatalf("Unmarshal() of marshaled transforms unexpected error = %v\n%s", err, data)
+			}
+			if !reflect.DeepEqual(roundTrip, tt.expected) {
+				t.Errorf("round trip = %+v, want %+v", roundTrip, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSelectProviders(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name        string
+		errContains string
+		include     []string
+		exclude     []string
+		expected    []string
+		wantErr     bool
+	}{
+		{name: "no selection keeps all", expected: []string{"gha", "local", "prod"}},
+		{name: "include subset", include: []string{"gha", "prod"}, expected: []string{"gha", "prod"}},
+		{name: "exclude", exclude: []string{"prod"}, expected: []string{"gha", "local"}},
+		{name: "include and exclude", include: []string{"gha", "prod"}, exclude: []string{"prod"}, expected: []string{"gha"}},
+		{
+			name:        "unknown provider",
+			include:     []string{"staging"},
+			wantErr:     true,
+			errContains: `unknown provider "staging" (available: gha, local, prod)`,
+		},
+		{
+			name:        "everything excluded",
+			exclude:     []string{"gha", "local", "prod"},
+			wantErr:     true,
+			errContains: "excludes every configured provider",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			cfg := &TellerConfig{Providers: map[string]Provider{
+				"gha":   {Kind: "google_secretmanager"},
+				"local": {Kind: "dotenv"},
+				"prod":  {Kind: "google_secretmanager"},
+			}}
+
+			err := cfg.SelectProviders(tt.include, tt.exclude)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("SelectProviders() expected error but got none")
+				} else if !strings.Contains(err.Error(), tt.errContains) {
+					t.Errorf("SelectProviders() error = %v, expected to contain %q", err, tt.errContains)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("SelectProviders() unexpected error = %v", err)
+			}
+			if names := cfg.providerNames(); !reflect.DeepEqual(names, tt.expected) {
+				t.Errorf("SelectProviders() providers = %v, want %v", names, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSelectTags(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name        string
+		errContains string
+		tags        []string
+		expected    map[string][]string // Provider -> selected source keys, or map ids of maps without keys
+	}{
+		{
+			name:     "no selection keeps all",
+			expected: map[string][]string{"gha": {"API_KEY", "E2E_TOKEN", "UNTAGGED"}, "local": {"app", "tools"}},
+		},
+		{
+			name:     "map tags and key tags",
+			tags:     []string{"backend"},
+			expected: map[string][]string{"gha": {"API_KEY"}, "local": {"app"}},
+		},
+		{
+			name:     "key tags replace map tags",
+			tags:     []string{"e2e"},
+			expected: map[string][]string{"gha": {"E2E_TOKEN"}},
+		},
+		{
+			name:     "several tags",
+			tags:     []string{"e2e", "tools"},
+			expected: map[string][]string{"gha": {"E2E_TOKEN"}, "local": {"tools"}},
+		},
+		{
+			name:        "unknown tag",
+			tags:        []string{"frontend"},
+			errContains: `unknown tag "frontend" (available: backend, e2e, tools)`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			cfg := &TellerConfig{Providers: map[string]Provider{
+				"gha": {Kind: "google_secretmanager", Maps: []PathMap{
+					{ID: "ci", Tags: []string{"backend"}, Keys: map[string]string{"API_KEY": "API_KEY", "E2E_TOKEN": "TOKEN"}, KeyTags: map[string][]string{"E2E_TOKEN": {"e2e"}}},
+					{ID: "misc", Keys: map[string]string{"UNTAGGED": "UNTAGGED"}},
+				}},
+				"local": {Kind: "dotenv", Maps: []PathMap{
+					{ID: "app", Path: ".env", Tags: []string{"backend"}},
+					{ID: "tools", Path: ".env.tools", Tags: []string{"tools"}},
+				}},
+			}}
+
+			err := cfg.SelectTags(tt.tags)
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Errorf("SelectTags() error = %v, expected to contain %q", err, tt.errContains)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SelectTags() unexpected error = %v", err)
+			}
+
+			selected := make(map[string][]string)
+			for name, provider := range cfg.Providers {
+				for _, m := range provider.Maps {
+					if len(m.Keys) == 0 {
+						selected[name] = append(selected[name], m.ID)
+					}
+					for key := range m.Keys {
+						selected[name] = append(selected[name], key)
+					}
+				}
+				sort.Strings(selected[name])
+			}
+			if !reflect.DeepEqual(selected, tt.expected) {
+				t.Errorf("SelectTags() selected = %v, want %v", selected, tt.expected)
+			}
+		})
+	}
+}
+
+func TestLocalPath(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{name: "forward slashes", input: "secrets/dev.env", expected: filepath.Join("secrets", "dev.env")},
+		{name: "backslashes", input: `secrets\dev.env`, expected: filepath.Join("secrets", "dev.env")},
+		{name: "mixed separators", input: `..\config/ci\.env`, expected: filepath.Join("..", "config", "ci", ".env")},
+		{name: "plain file", input: ".env", expected: ".env"},
+		{name: "empty", input: "", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if result := LocalPath(tt.input); result != tt.expected {
+				t.Errorf("LocalPath(%q) = %q, want %q", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestResolveConfigPathDirectory(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	file := filepath.Join(dir, "ci.yml")
+	if err := os.WriteFile(file, []byte("providers: {}\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	for input, expected := range map[string]string{dir: filepath.Join(dir, ".teller.yml"), file: file} {
+		if got, err := ResolveConfigPath(input); err != nil || got != expected {
+			t.Errorf("ResolveConfigPath(%q) = %q, %v, want %q", input, got, err, expected)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CurrentVersion is the newest config version feller understands. Configs without a
+// version field are version 1.
+const CurrentVersion = 2
+
+// Deprecation describes a config field that is obsolete. Configs of an older version than
+// Removed get a warning suggesting the replacement; from version Removed on the field is
+// an error.
+type Deprecation struct {
+	Path        []string // Field path from the root; "*" matches any provider name
+	Removed     int      // Config version that no longer accepts the field
+	Replacement string
+}
+
+// Deprecations lists the obsolete config fields. Most come from teller 1 configs, which
+// teller 2 and feller ignore.
+var Deprecations = []Deprecation{
+	{Path: []string{"project"}, Removed: 2, Replacement: "remove it, configs have no project name"},
+	{Path: []string{"opts"}, Removed: 2, Replacement: "move the values into the options of each provider"},
+	{Path: []string{"carry_env"}, Removed: 2, Replacement: "remove it, 'feller run' keeps the environment unless --reset is given"},
+	{Path: []string{"confirm"}, Removed: 2, Replacement: "remove it, feller never asks for confirmation before resolving secrets"},
+	{Path: []string{"providers", "*", "env_sync"}, Removed: 2, Replacement: "use a map without keys, which resolves every key at its path"},
+	{Path: []string{"providers", "*", "env"}, Removed: 2, Replacement: "use a map whose keys rename source keys to output keys"},
+}
+
+// DeprecatedField is an obsolete field found in a config
+type DeprecatedField struct {
+	Deprecation
+	Field string     // Dotted path of the field, e.g. providers.heroku.env_sync
+	Node  *yaml.Node // Key node of the field
+}
+
+// String describes the field and its replacement
+func (f DeprecatedField) String() string {
+	return fmt.Sprintf("%s is deprecated and removed in config version %d: %s", f.Field, f.Removed, f.Replacement)
+}
+
+// FindDeprecated returns the deprecated fields of the config document root, in the order
+// of Deprecations and then of the document
+func FindDeprecated(root *yaml.Node) []DeprecatedField {
+	if root == nil {
+		return nil
+	}
+	if root.Kind == yaml.DocumentNode {
+		if len(root.Content) == 0 {
+			return nil
+		}
+		root = root.Content[0]
+	}
+	var found []DeprecatedField
+	for _, d := range Deprecations {
+		found = findDeprecated(root, d, d.Path, nil, found)
+	}
+	return found
+}
+
+// findDeprecated appends the fields of node matching the remaining path to found
+func findDeprecated(node *yaml.Node, d Deprecation, path, field []string, found []DeprecatedField) []DeprecatedField {
+	if node.Kind != yaml.MappingNode {
+		return found
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := node.Content[i]
+		if path[0] != "*" && key.Value != path[0] {
+			continue
+		}
+		current := append(append([]string{}, field...), key.Value)
+		if len(path) == 1 {
+			found = append(found, DeprecatedField{Deprecation: d, Field: strings.Join(current, "."), Node: key})
+			continue
+		}
+		found = findDeprecated(node.Content[i+1], d, path[1:], current, found)
+	}
+	return found
+}
+
+// checkVersion fails for configs newer than CurrentVersion and for deprecated fields
+// removed in the config's version
+func (c *TellerConfig) checkVersion() error {
+	if c.Version < 0 || c.Version > CurrentVersion {
+		return fmt.Errorf("config version %d is not supported by this feller (supported: 1 to %d), upgrade feller", c.Version, CurrentVersion)
+	}
+	for _, f := range c.deprecated {
+		if c.SchemaVersion() >= f.Removed {
+			return fmt.Errorf("line %d: %s was removed in config version %d: %s", f.Node.Line, f.Field, f.Removed, f.Replacement)
+		}
+	}
+	return nil
+}
+
+// SchemaVersion returns the config version, 1 when the config does not declare one
+func (c *TellerConfig) SchemaVersion() int {
+	if c.Version == 0 {
+		return 1
+	}
+	return c.Version
+}
+
+// Deprecated returns the deprecated fields of the config as loaded by LoadConfig
+func (c *TellerConfig) Deprecated() []DeprecatedField {
+	return c.deprecated
+}
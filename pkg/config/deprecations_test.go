@@ -0,0 +1,90 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestFindDeprecated(t *testing.T) {
+	t.Parallel()
+	data := `project: demo
+carry_env: true
+providers:
+  heroku:
+    kind: dotenv
+    env_sync:
+      path: app
+  vault:
+    kind: hashicorp_vault
+    env:
+      TOKEN:
+        path: secret/token
+`
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(data), &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	found := FindDeprecated(&doc)
+	expected := []string{"project:1", "carry_env:2", "providers.heroku.env_sync:6", "providers.vault.env:10"}
+	if len(found) != len(expected) {
+		t.Fatalf("FindDeprecated() returned %d fields, want %d: %v", len(found), len(expected), found)
+	}
+	for i, want := range expected {
+		if got := fmt.Sprintf("%s:%d", found[i].Field, found[i].Node.Line); got != want {
+			t.Errorf("FindDeprecated()[%d] = %s, want %s", i, got, want)
+		}
+	}
+	if FindDeprecated(&yaml.Node{}) != nil {
+		t.Errorf("FindDeprecated() of an empty document should be nil")
+	}
+}
+
+func TestLoadConfigVersion(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name        string
+		data        string
+		version     int
+		deprecated  int
+		errContains string
+	}{
+		{name: "unversioned", data: "providers: {}\n", version: 1},
+		{name: "current", data: "version: 2\nproviders: {}\n", version: 2},
+		{name: "deprecated field", data: "project: demo\nproviders: {}\n", version: 1, deprecated: 1},
+		{name: "removed field", data: "version: 2\nproject: demo\n", errContains: "line 2: project was removed in config version 2"},
+		{name: "newer version", data: "version: 3\n", errContains: "config version 3 is not supported"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			path := filepath.Join(t.TempDir(), ".teller.yml")
+			if err := os.WriteFile(path, []byte(tt.data), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg, err := LoadConfig(path)
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("LoadConfig() error = %v, want it to contain %q", err, tt.errContains)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadConfig() unexpected error = %v", err)
+			}
+			if got := cfg.SchemaVersion(); got != tt.version {
+				t.Errorf("SchemaVersion() = %d, want %d", got, tt.version)
+			}
+			if got := len(cfg.Deprecated()); got != tt.deprecated {
+				t.Errorf("Deprecated() returned %d fields, want %d", got, tt.deprecated)
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Document is a config file held as a YAML node tree. Commands that modify a config edit a
+// Document instead of re-marshaling TellerConfig, so comments, key order, and fields feller
+// does not know about survive the round trip.
+type Document struct {
+	node yaml.Node
+}
+
+// ParseDocument parses config file content into an editable document
+func ParseDocument(data []byte) (*Document, error) {
+	doc := &Document{}
+	if err := yaml.Unmarshal(data, &doc.node); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+	return doc, nil
+}
+
+// LoadDocument reads a config file into an editable document
+func LoadDocument(path string) (*Document, error) {
+	// #nosec G304 - Config path is provided by the user
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+	doc, err := ParseDocument(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return doc, nil
+}
+
+// Bytes encodes the document with two space indentation
+func (d *Document) Bytes() ([]byte, error) {
+	if len(d.node.Content) == 0 {
+		return []byte{}, nil
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(formatIndent)
+	if err := encoder.Encode(&d.node); err != nil {
+		return nil, fmt.Errorf("failed to encode config: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encode config: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// WriteFile writes the document to path, keeping the permissions of an existing file
+func (d *Document) WriteFile(path string) error {
+	data, err := d.Bytes()
+	if err != nil {
+		return err
+	}
+
+	mode := os.FileMode(0o600)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	if err := os.WriteFile(path, data, mode); err != nil {
+		return fmt.Errorf("failed to write config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// root returns the top-level mapping, creating it for an empty document when create is set
+func (d *Document) root(create bool) *yaml.Node {
+	if len(d.node.Content) == 0 {
+		if !create {
+			return nil
+		}
+		d.node.Kind = yaml.DocumentNode
+		d.node.Content = []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}
+	}
+	return d.node.Content[0]
+}
+
+// Lookup returns the value node at the given mapping key path, or nil when it does not exist
+func (d *Document) Lookup(path ...string) *yaml.Node {
+	node := d.root(false)
+	for _, key := range path {
+		if node == nil {
+			return nil
+		}
+		_, node = mappingValue(node, key)
+	}
+	return node
+}
+
+// Set stores value at the given mapping key path, creating intermediate mappings as needed.
+// Replacing an existing value keeps the comments attached to it; new keys are appended.
+func (d *Document) Set(value any, path ...string) error {
+	if len(path) == 0 {
+		return errors.New("empty config path")
+	}
+
+	var encoded yaml.Node
+	if err := encoded.Encode(value); err != nil {
+		return fmt.Errorf("failed to encode value for %s: %w", strings.Join(path, "."), err)
+	}
+
+	node := d.root(true)
+	for i, key := range path {
+		if node.Kind != yaml.MappingNode {
+			return fmt.Errorf("cannot set %s: %s is not a mapping", strings.Join(path, "."), strings.Join(path[:i], "."))
+		}
+
+		_, child := mappingValue(node, key)
+		last := i == len(path)-1
+		switch {
+		case child == nil && last:
+			appendPair(node, key, &encoded)
+		case child == nil:
+			child = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			appendPair(node, key, child)
+		case last:
+			encoded.HeadComment = child.HeadComment
+			encoded.LineComment = child.LineComment
+			encoded.FootComment = child.FootComment
+			*child = encoded
+		}
+		node = child
+	}
+	return nil
+}
+
+// Delete removes the key at the given mapping path together with its comments and reports
+// whether it existed
+func (d *Document) Delete(path ...string) bool {
+	if len(path) == 0 {
+		return false
+	}
+
+	parent := d.root(false)
+	if len(path) > 1 {
+		parent = d.Lookup(path[:len(path)-1]...)
+	}
+	if parent == nil {
+		return false
+	}
+
+	index, _ := mappingValue(parent, path[len(path)-1])
+	if index < 0 {
+		return false
+	}
+	if foot := parent.Content[index].FootComment; index == len(parent.Content)-2 && index >= 2 {
+		// Keep notes trailing the block when its last key goes away
+		parent.Content[index-2].FootComment = joinComments(parent.Content[index-2].FootComment, foot)
+	}
+	parent.Content = append(parent.Content[:index], parent.Content[index+2:]...)
+	return true
+}
+
+// appendPair adds key to the end of a mapping node. A foot comment on the previous last key
+// trails the whole block, e.g. notes at the end of the file, so it moves to the new key.
+func appendPair(node *yaml.Node, key string, value *yaml.Node) {
+	keyNode := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}
+	if n := len(node.Content); n >= 2 {
+		keyNode.FootComment = node.Content[n-2].FootComment
+		node.Content[n-2].FootComment = ""
+	}
+	node.Content = append(node.Content, keyNode, value)
+}
+
+// mappingValue returns the key index and value node of key in a mapping node, or -1 and nil
+func mappingValue(node *yaml.Node, key string) (int, *yaml.Node) {
+	if node.Kind != yaml.MappingNode {
+		return -1, nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return i, node.Content[i+1]
+		}
+	}
+	return -1, nil
+}
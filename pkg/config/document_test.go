@@ -0,0 +1,230 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const annotatedConfig = `# Secrets for CI
+providers:
+  # production secrets, rotated monthly
+  gsm:
+    kind: google_secretmanager # needs workload identity
+    maps:
+      - id: prod
+        keys:
+          DB_PASSWORD: projects/p/secrets/db # owned by the data team
+  local:
+    kind: dotenv
+hooks:
+  pre_run:
+    - ./fetch.sh
+# trailing notes
+`
+
+func TestDocumentRoundTrip(t *testing.T) {
+	t.Parallel()
+	doc, err := ParseDocument([]byte(annotatedConfig))
+	if err != nil {
+		t.Fatalf("ParseDocument() unexpected error = %v", err)
+	}
+	result, err := doc.Bytes()
+	if err != nil {
+		t.Fatalf("Bytes() unexpected error = %v", err)
+	}
+	if string(result) != annotatedConfig {
+		t.Errorf("Bytes() =\n%s\nwant\n%s", result, annotatedConfig)
+	}
+}
+
+func TestDocumentEdits(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		edit        func(doc *Document) error
+		name        string
+		contains    []string
+		notContains []string
+		errContains string
+		wantErr     bool
+	}{
+		{
+			name: "replace scalar keeps line comment",
+			edit: func(doc *Document) error {
+				return doc.Set("dotenv", "providers", "gsm", "kind")
+			},
+			contains:    []string{"kind: dotenv # needs workload identity", "# production secrets, rotated monthly", "# trailing notes"},
+			notContains: []string{"google_secretmanager"},
+		},
+		{
+			name: "new keys are appended and parents created",
+			edit: func(doc *Document) error {
+				return doc.Set("int", "schema", "PORT")
+			},
+			contains: []string{"    - ./fetch.sh\nschema:\n  PORT: int\n# trailing notes\n", "# Secrets for CI\nproviders:"},
+		},
+		{
+			name: "set structured value",
+			edit: func(doc *Document) error {
+				return doc.Set([]string{"rm -f kubeconfig"}, "hooks", "post_run")
+			},
+			contains: []string{"  post_run:\n    - rm -f kubeconfig\n"},
+		},
+		{
+			name: "delete removes key and its comments",
+			edit: func(doc *Document) error {
+				if !doc.Delete("providers", "gsm") {
+					t.Errorf("Delete() = false, want true")
+				}
+				if doc.Delete("providers", "missing") {
+					t.Errorf("Delete() of missing key = true, want false")
+				}
+				return nil
+			},
+			contains:    []string{"providers:\n  local:\n    kind: dotenv\n", "# trailing notes"},
+			notContains: []string{"gsm", "rotated monthly"},
+		},
+		{
+			name: "delete last key keeps trailing comment",
+			edit: func(doc *Document) error {
+				doc.Delete("hooks")
+				return nil
+			},
+			contains:    []string{"    kind: dotenv\n# trailing notes\n"},
+			notContains: []string{"hooks"},
+		},
+		{
+			name: "set through a list fails",
+			edit: func(doc *Document) error {
+				return doc.Set("x", "providers", "gsm", "maps", "id")
+			},
+			wantErr:     true,
+			errContains: "providers.gsm.maps is not a mapping",
+		},
+		{
+			name: "empty path",
+			edit: func(doc *Document) error {
+				return doc.Set("x")
+			},
+			wantErr:     true,
+			errContains: "empty config path",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			doc, err := ParseDocument([]byte(annotatedConfig))
+			if err != nil {
+				t.Fatalf("ParseDocument() unexpected error = %v", err)
+			}
+
+			err = tt.edit(doc)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("edit expected error but got none")
+				} else if !strings.Contains(err.Error(), tt.errContains) {
+					t.Errorf("edit error = %v, expected to contain %q", err, tt.errContains)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("edit unexpected error = %v", err)
+			}
+
+			result, err := doc.Bytes()
+			if err != nil {
+				t.Fatalf("Bytes() unexpected error = %v", err)
+			}
+			for _, want := range tt.contains {
+				if !strings.Contains(string(result), want) {
+					t.Errorf("Bytes() =\n%s\nexpected to contain %q", result, want)
+				}
+			}
+			for _, unwanted := range tt.notContains {
+				if strings.Contains(string(result), unwanted) {
+					t.Errorf("Bytes() =\n%s\nexpected not to contain %q", result, unwanted)
+				}
+			}
+		})
+	}
+}
+
+func TestDocumentLookup(t *testing.T) {
+	t.Parallel()
+	doc, err := ParseDocument([]byte(annotatedConfig))
+	if err != nil {
+		t.Fatalf("ParseDocument() unexpected error = %v", err)
+	}
+
+	if node := doc.Lookup("providers", "gsm", "kind"); node == nil || node.Value != "google_secretmanager" {
+		t.Errorf("Lookup(providers.gsm.kind) = %v, want google_secretmanager", node)
+	}
+	if node := doc.Lookup("providers", "vault"); node != nil {
+		t.Errorf("Lookup(providers.vault) = %v, want nil", node)
+	}
+	if node := doc.Lookup("hooks", "pre_run", "x"); node != nil {
+		t.Errorf("Lookup() through a list = %v, want nil", node)
+	}
+}
+
+func TestDocumentEmpty(t *testing.T) {
+	t.Parallel()
+	doc, err := ParseDocument(nil)
+	if err != nil {
+		t.Fatalf("ParseDocument() unexpected error = %v", err)
+	}
+	if node := doc.Lookup("providers"); node != nil {
+		t.Errorf("Lookup() on empty document = %v, want nil", node)
+	}
+	if err := doc.Set("dotenv", "providers", "local", "kind"); err != nil {
+		t.Fatalf("Set() unexpected error = %v", err)
+	}
+	result, err := doc.Bytes()
+	if err != nil {
+		t.Fatalf("Bytes() unexpected error = %v", err)
+	}
+	expected := "providers:\n  local:\n    kind: dotenv\n"
+	if string(result) != expected {
+		t.Errorf("Bytes() = %q, want %q", result, expected)
+	}
+}
+
+func TestDocumentFiles(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), ".teller.yml")
+	if err := os.WriteFile(path, []byte(annotatedConfig), 0o640); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	doc, err := LoadDocument(path)
+	if err != nil {
+		t.Fatalf("LoadDocument() unexpected error = %v", err)
+	}
+	if err := doc.Set("int", "schema", "PORT"); err != nil {
+		t.Fatalf("Set() unexpected error = %v", err)
+	}
+	if err := doc.WriteFile(path); err != nil {
+		t.Fatalf("WriteFile() unexpected error = %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Failed to stat config: %v", err)
+	}
+	if info.Mode().Perm() != 0o640 {
+		t.Errorf("WriteFile() mode = %v, want 0640", info.Mode().Perm())
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read config: %v", err)
+	}
+	if !strings.Contains(string(data), "# owned by the data team") || !strings.HasSuffix(string(data), "  PORT: int\n# trailing notes\n") {
+		t.Errorf("WriteFile() content =\n%s", data)
+	}
+
+	if _, err := LoadDocument(filepath.Join(t.TempDir(), "missing.yml")); err == nil {
+		t.Errorf("LoadDocument() of missing file expected error but got none")
+	}
+}
@@ -0,0 +1,155 @@
+package config
+
+import (
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Canonical field order of each config section, used when formatting and validating configs
+var (
+	RootFields     = []string{"version", "providers", "hooks", "transforms", "schema", "aliases", "messages", "fallback", "targets", "backups", "collisions", "sync"}
+	ProviderFields = []string{"kind", "maps", "options"}
+	PathMapFields  = []string{"id", "path", "version", "stage", "keys", "include", "exclude", "tags", "key_tags"}
+	HookFields     = []string{"pre_run", "post_run"}
+	MessageFields  = []string{"missing_variables", "missing_variables_file"}
+	TargetFields   = []string{"kind", "repo", "dependabot", "project", "team", "environments", "account", "script", "app", "providers", "keys"}
+	BackupFields   = []string{"recipients", "identity", "retention"}
+)
+
+// formatIndent is the indentation width of formatted configs
+const formatIndent = 2
+
+// Format returns the canonical form of a config file, see Document.Format
+func Format(data []byte) ([]byte, error) {
+	doc, err := ParseDocument(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(doc.node.Content) == 0 {
+		return data, nil
+	}
+	doc.Format()
+	return doc.Bytes()
+}
+
+// Format rewrites the document into canonical form: known fields in canonical order,
+// names and keys sorted alphabetically, block style with two space indentation, and
+// scalars quoted only where YAML requires it. Comments and list order are preserved.
+func (d *Document) Format() {
+	if len(d.node.Content) == 0 {
+		return
+	}
+
+	normalizeStyle(&d.node)
+	if root := d.node.Content[0]; root.Kind == yaml.MappingNode && len(root.Content) > 0 {
+		// A comment at the top of the file is attached to the first key, but it usually
+		// describes the whole file, so keep it at the top when keys are reordered
+		if header := root.Content[0].HeadComment; header != "" {
+			root.Content[0].HeadComment = ""
+			d.node.HeadComment = joinComments(d.node.HeadComment, header)
+		}
+		formatRoot(root)
+	}
+}
+
+func joinComments(a, b string) string {
+	if a == "" {
+		return b
+	}
+	return a + "\n" + b
+}
+
+// normalizeStyle resets every node to the default style so the encoder uses block
+// collections and only quotes scalars that would otherwise change meaning
+func normalizeStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		normalizeStyle(child)
+	}
+}
+
+func formatRoot(root *yaml.Node) {
+	sortMapping(root, RootFields)
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		value := root.Content[i+1]
+		switch root.Content[i].Value {
+		case "providers":
+			sortMapping(value, nil)
+			for j := 1; j < len(value.Content); j += 2 {
+				formatProvider(value.Content[j])
+			}
+		case "hooks":
+			sortMapping(value, HookFields)
+		case "messages":
+			sortMapping(value, MessageFields)
+		case "backups":
+			sortMapping(value, BackupFields)
+		case "transforms", "schema", "aliases":
+			sortMapping(value, nil)
+		case "sync":
+			sortMapping(value, nil)
+			for j := 1; j < len(value.Content); j += 2 {
+				sortMapping(value.Content[j], nil)
+			}
+		case "targets":
+			sortMapping(value, nil)
+			for j := 1; j < len(value.Content); j += 2 {
+				sortMapping(value.Content[j], TargetFields)
+			}
+		}
+	}
+}
+
+func formatProvider(provider *yaml.Node) {
+	sortMapping(provider, ProviderFields)
+	for i := 0; i+1 < len(provider.Content); i += 2 {
+		if provider.Content[i].Value != "maps" || provider.Content[i+1].Kind != yaml.SequenceNode {
+			continue
+		}
+		for _, pathMap := range provider.Content[i+1].Content {
+			sortMapping(pathMap, PathMapFields)
+			for j := 0; j+1 < len(pathMap.Content); j += 2 {
+				if pathMap.Content[j].Value == "keys" {
+					sortMapping(pathMap.Content[j+1], nil)
+				}
+			}
+		}
+	}
+}
+
+// sortMapping orders the pairs of a mapping node: keys listed in order first, in that
+// order, followed by the remaining keys alphabetically
+func sortMapping(node *yaml.Node, order []string) {
+	if node.Kind != yaml.MappingNode {
+		return
+	}
+
+	rank := make(map[string]int, len(order))
+	for i, key := range order {
+		rank[key] = i
+	}
+
+	pairs := make([][2]*yaml.Node, 0, len(node.Content)/2)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		pairs = append(pairs, [2]*yaml.Node{node.Content[i], node.Content[i+1]})
+	}
+
+	sort.SliceStable(pairs, func(i, j int) bool {
+		ki, kj := pairs[i][0].Value, pairs[j][0].Value
+		ri, iKnown := rank[ki]
+		rj, jKnown := rank[kj]
+		switch {
+		case iKnown && jKnown:
+			return ri < rj
+		case iKnown != jKnown:
+			return iKnown
+		default:
+			return ki < kj
+		}
+	})
+
+	for i, pair := range pairs {
+		node.Content[2*i], node.Content[2*i+1] = pair[0], pair[1]
+	}
+}
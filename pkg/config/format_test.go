@@ -0,0 +1,180 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestFormat(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name        string
+		input       string
+		expected    string
+		errContains string
+		wantErr     bool
+	}{
+		{
+			name: "canonical order indentation and quoting",
+			input: `# Secrets for CI
+schema:
+    PORT: 'int'   # the listen port
+providers:
+    local:
+        maps:
+        -   keys: {Z_KEY: "true", A_KEY: 'A'}
+            id: "dev"
+            path: .env
+        kind: dotenv
+    gha:
+        kind: google_secretmanager
+hooks:
+  post_run: ["rm -f kubeconfig"]
+  pre_run: [./fetch.sh]
+custom: 1
+`,
+			expected: `# Secrets for CI
+
+providers:
+  gha:
+    kind: google_secretmanager
+  local:
+    kind: dotenv
+    maps:
+      - id: dev
+        path: .env
+        keys:
+          A_KEY: A
+          Z_KEY: "true"
+hooks:
+  pre_run:
+    - ./fetch.sh
+  post_run:
+    - rm -f kubeconfig
+schema:
+  PORT: int # the listen port
+custom: 1
+`,
+		},
+		{
+			name: "transform steps keep their order",
+			input: `transforms:
+  B: [upper, trim]
+  A:
+    - replace: {from: "http://", to: "https://"}
+    - template: "{{ .Value }}"
+`,
+			expected: `transforms:
+  A:
+    - replace:
+        from: http://
+        to: https://
+    - template: '{{ .Value }}'
+  B:
+    - upper
+    - trim
+`,
+		},
+		{
+			name: "target fields",
+			input: `targets:
+  prod:
+    keys: [A]
+    repo: owner/prod
+    kind: github
+  ci:
+    dependabot: true
+    kind: github
+    repo: owner/ci
+`,
+			expected: `targets:
+  ci:
+    kind: github
+    repo: owner/ci
+    dependabot: true
+  prod:
+    kind: github
+    repo: owner/prod
+    keys:
+      - A
+`,
+		},
+		{
+			name:     "empty document",
+			input:    "",
+			expected: "",
+		},
+		{
+			name:        "invalid yaml",
+			input:       "providers: [",
+			wantErr:     true,
+			errContains: "failed to parse config",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			result, err := Format([]byte(tt.input))
+
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Format() expected error but got none")
+				} else if !strings.Contains(err.Error(), tt.errContains) {
+					t.Errorf("Format() error = %v, expected to contain %q", err, tt.errContains)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Format() unexpected error = %v", err)
+			}
+			if string(result) != tt.expected {
+				t.Errorf("Format() =\n%s\nwant\n%s", result, tt.expected)
+			}
+
+			again, err := Format(result)
+			if err != nil {
+				t.Fatalf("Format() of formatted output unexpected error = %v", err)
+			}
+			if string(again) != string(result) {
+				t.Errorf("Format() is not idempotent:\n%s\nthen\n%s", result, again)
+			}
+		})
+	}
+}
+
+func TestFormatPreservesConfig(t *testing.T) {
+	t.Parallel()
+	input := validConfigYAML() + `transforms:
+  DATABASE_URL: [trim, {json_extract: "db.url"}]
+schema: {PORT: int, DEBUG: bool}
+`
+	formatted, err := Format([]byte(input))
+	if err != nil {
+		t.Fatalf("Format() unexpected error = %v", err)
+	}
+
+	var before, after TellerConfig
+	if err := yaml.Unmarshal([]byte(input), &before); err != nil {
+		t.Fatalf("Failed to parse input: %v", err)
+	}
+	if err := yaml.Unmarshal(formatted, &after); err != nil {
+		t.Fatalf("Failed to parse formatted output: %v", err)
+	}
+	// Options are yaml.Node values carrying positions, so compare the semantic fields
+	for name, provider := range before.Providers {
+		provider.Options = yaml.Node{}
+		before.Providers[name] = provider
+	}
+	for name, provider := range after.Providers {
+		provider.Options = yaml.Node{}
+		after.Providers[name] = provider
+	}
+	if !reflect.DeepEqual(before, after) {
+		t.Errorf("Format() changed the config:\nbefore %+v\nafter  %+v", before, after)
+	}
+}
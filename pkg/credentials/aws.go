@@ -0,0 +1,123 @@
+package credentials
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Names of the profiles written for the role chain
+const (
+	awsSourceProfile = "feller-source"
+	awsRoleProfile   = "feller-role-%d"
+)
+
+// prepareAWS writes AWS config and credentials files with one profile per role of the
+// chain, each assuming its role with the previous profile, and selects the last one. The
+// existing files are copied so profiles such as SSO logins keep working as the source.
+func prepareAWS(opts *Options, dir string, env []string) ([]string, error) {
+	configData, err := readAWSFile(env, "AWS_CONFIG_FILE", "config")
+	if err != nil {
+		return nil, err
+	}
+	credentialsData, err := readAWSFile(env, "AWS_SHARED_CREDENTIALS_FILE", "credentials")
+	if err != nil {
+		return nil, err
+	}
+
+	// Environment credentials take precedence over profiles in every SDK, so they are moved
+	// into a profile the chain starts from and removed from the environment
+	source := lookupEnv(env, "AWS_PROFILE")
+	if source == "" {
+		source = "default"
+	}
+	if keyID, secret := lookupEnv(env, "AWS_ACCESS_KEY_ID"), lookupEnv(env, "AWS_SECRET_ACCESS_KEY"); keyID != "" || secret != "" {
+		if keyID == "" || secret == "" {
+			return nil, errors.New("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY must be set together")
+		}
+		source = awsSourceProfile
+		credentialsData = appendSection(credentialsData, awsSourceProfile, map[string]string{
+			"aws_access_key_id":     keyID,
+			"aws_secret_access_key": secret,
+			"aws_session_token":     lookupEnv(env, "AWS_SESSION_TOKEN"),
+		})
+		for _, key := range []string{"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "AWS_SESSION_TOKEN"} {
+			env = setEnv(env, key, "")
+		}
+	}
+
+	configData, profile := appendRoleChain(configData, source, opts)
+
+	configPath := filepath.Join(dir, "aws-config")
+	credentialsPath := filepath.Join(dir, "aws-credentials")
+	if err := os.WriteFile(configPath, configData, 0o600); err != nil {
+		return nil, fmt.Errorf("failed to write AWS config: %w", err)
+	}
+	if err := os.WriteFile(credentialsPath, credentialsData, 0o600); err != nil {
+		return nil, fmt.Errorf("failed to write AWS credentials: %w", err)
+	}
+
+	env = setEnv(env, "AWS_CONFIG_FILE", configPath)
+	env = setEnv(env, "AWS_SHARED_CREDENTIALS_FILE", credentialsPath)
+	env = setEnv(env, "AWS_DEFAULT_PROFILE", "")
+	return setEnv(env, "AWS_PROFILE", profile), nil
+}
+
+// appendRoleChain appends a profile per role to an AWS config file and returns the name
+// of the profile of the last role
+func appendRoleChain(data []byte, source string, opts *Options) ([]byte, string) {
+	profile := source
+	for i, role := range opts.AssumeRoles {
+		next := fmt.Sprintf(awsRoleProfile, i+1)
+		data = appendSection(data, "profile "+next, map[string]string{
+			"role_arn":          role,
+			"source_profile":    profile,
+			"role_session_name": opts.RoleSessionName,
+			"duration_seconds":  strconv.Itoa(opts.RoleDurationSeconds),
+		})
+		profile = next
+	}
+	return data, profile
+}
+
+// appendSection appends an INI section with the non-empty values in a fixed order
+func appendSection(data []byte, name string, values map[string]string) []byte {
+	var b strings.Builder
+	b.Write(data)
+	if len(data) > 0 && !strings.HasSuffix(string(data), "\n") {
+		b.WriteByte('\n')
+	}
+	fmt.Fprintf(&b, "\n[%s]\n", name)
+	for _, key := range []string{"role_arn", "source_profile", "role_session_name", "duration_seconds",
+		"aws_access_key_id", "aws_secret_access_key", "aws_session_token"} {
+		if value := values[key]; value != "" {
+			fmt.Fprintf(&b, "%s = %s\n", key, value)
+		}
+	}
+	return []byte(b.String())
+}
+
+// readAWSFile reads the AWS file named by envKey, or ~/.aws/name by default. A missing file
+// is empty.
+func readAWSFile(env []string, envKey, name string) ([]byte, error) {
+	path := lookupEnv(env, envKey)
+	if path == "" {
+		home := lookupEnv(env, "HOME")
+		if home == "" {
+			home = lookupEnv(env, "USERPROFILE")
+		}
+		path = filepath.Join(home, ".aws", name)
+	}
+	// #nosec G304 - Path of the user's AWS configuration
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read AWS %s: %w", name, err)
+	}
+	return data, nil
+}
@@ -0,0 +1,283 @@
+// Package credentials prepares short-lived cloud credentials for the teller process:
+// impersonation of a GCP service account, chained AWS roles and Vault logins with a SPIFFE
+// identity, declared in provider options. Feller does not call the cloud APIs; it writes
+// credential configuration the Google and AWS SDKs in teller understand, so the runner's
+// broad default credentials are only used to mint narrowly scoped ones. Vault has no such
+// configuration, so feller logs in itself and passes the token on.
+package credentials
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/containifyci/feller/pkg/config"
+)
+
+// Options are the credential options of a provider. Other provider options are ignored.
+type Options struct {
+	ImpersonateServiceAccount string   `yaml:"impersonate_service_account"`
+	ImpersonationDelegates    []string `yaml:"impersonation_delegates"`
+	AssumeRoles               []string `yaml:"assume_roles"` // Assumed in order, each with the previous one's credentials
+	RoleSessionName           string   `yaml:"role_session_name"`
+	RoleDurationSeconds       int      `yaml:"role_duration_seconds"`
+	SpiffeAudience            string   `yaml:"spiffe_audience"` // Audience of the JWT-SVID presented to Vault
+	SpiffeSocket              string   `yaml:"spiffe_socket"`   // Workload API socket of the SPIRE agent
+	VaultAuthRole             string   `yaml:"vault_auth_role"`
+	VaultAuthMount            string   `yaml:"vault_auth_mount"`
+	CACert                    string   `yaml:"ca_cert"` // PEM bundle of a private CA
+	ClientCert                string   `yaml:"client_cert"`
+	ClientKey                 string   `yaml:"client_key"`
+	InsecureSkipVerify        bool     `yaml:"insecure_skip_verify"`
+	Proxy                     string   `yaml:"proxy"`
+	NoProxy                   []string `yaml:"no_proxy"` // Hosts reached without the proxy
+	Endpoint                  string   `yaml:"endpoint"` // API endpoint, e.g. of an emulator
+	Address                   string   `yaml:"address"`  // Endpoint of Vault
+}
+
+// Defaults of assumed AWS roles. 900 seconds is the shortest session AWS allows.
+const (
+	defaultSessionName        = "feller"
+	defaultDurationSeconds    = 900
+	maxDurationSeconds        = 43200
+	maxChainedDurationSeconds = 3600
+)
+
+// Plan is the credential setup of one teller run. Teller resolves every provider in one
+// process, so all providers must agree on the credentials they ask for.
+type Plan struct {
+	GCP   *Options            // Service account impersonation, nil when not requested
+	AWS   *Options            // Role chain, nil when not requested
+	Vault *Options            // Vault login with a SPIFFE identity, nil when not requested
+	TLS   map[string]*Options // CA and client certificate by provider kind, whose SDK reads them
+	Proxy string              // Proxy of every provider, since teller reads one from the environment
+
+	NoProxy   []string          // Hosts added to NO_PROXY, sorted
+	Endpoints map[string]string // Variable -> endpoint of the SDK reading it
+}
+
+// endpointEnv names the variable that points the SDK teller uses for a provider kind at
+// another endpoint
+var endpointEnv = map[string]string{
+	"aws_secretsmanager": "AWS_ENDPOINT_URL_SECRETS_MANAGER",
+	"aws_ssm":            "AWS_ENDPOINT_URL_SSM",
+	"hashicorp_vault":    "VAULT_ADDR",
+}
+
+// Empty reports whether the plan requests no credentials
+func (p *Plan) Empty() bool {
+	return p.GCP == nil && p.AWS == nil && p.Vault == nil && len(p.TLS) == 0 && p.Proxy == "" && len(p.NoProxy) == 0 && len(p.Endpoints) == 0
+}
+
+// PlanFor collects the credential options of every provider of cfg
+func PlanFor(cfg *config.TellerConfig) (*Plan, error) {
+	names := make([]string, 0, len(cfg.Providers))
+	for name := range cfg.Providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	plan := &Plan{}
+	var gcpFrom, awsFrom, vaultFrom, proxyFrom string
+	endpointFrom := make(map[string]string)
+	tlsFrom := make(map[string]string)
+	for _, name := range names {
+		provider := cfg.Providers[name]
+		if provider.Options.IsZero() {
+			continue
+		}
+		var opts Options
+		if err := provider.Options.Decode(&opts); err != nil {
+			return nil, fmt.Errorf("invalid options of provider %s: %w", name, err)
+		}
+
+		if opts.ImpersonateServiceAccount != "" {
+			if plan.GCP != nil && (plan.GCP.ImpersonateServiceAccount != opts.ImpersonateServiceAccount ||
+				!slices.Equal(plan.GCP.ImpersonationDelegates, opts.ImpersonationDelegates)) {
+				return nil, fmt.Errorf("providers %s and %s impersonate different service accounts, but teller uses one set of credentials per run", gcpFrom, name)
+			}
+			plan.GCP, gcpFrom = &Options{ImpersonateServiceAccount: opts.ImpersonateServiceAccount, ImpersonationDelegates: opts.ImpersonationDelegates}, name
+		}
+
+		if len(opts.AssumeRoles) > 0 {
+			role := Options{AssumeRoles: opts.AssumeRoles, RoleSessionName: opts.RoleSessionName, RoleDurationSeconds: opts.RoleDurationSeconds}
+			if role.RoleSessionName == "" {
+				role.RoleSessionName = defaultSessionName
+			}
+			if role.RoleDurationSeconds == 0 {
+				role.RoleDurationSeconds = defaultDurationSeconds
+			}
+			if err := checkDuration(role); err != nil {
+				return nil, fmt.Errorf("invalid options of provider %s: %w", name, err)
+			}
+			if plan.AWS != nil && (!slices.Equal(plan.AWS.AssumeRoles, role.AssumeRoles) ||
+				plan.AWS.RoleSessionName != role.RoleSessionName || plan.AWS.RoleDurationSeconds != role.RoleDurationSeconds) {
+				return nil, fmt.Errorf("providers %s and %s assume different roles, but teller uses one set of credentials per run", awsFrom, name)
+			}
+			plan.AWS, awsFrom = &role, name
+		}
+
+		if opts.SpiffeAudience != "" || opts.VaultAuthRole != "" {
+			login := Options{SpiffeAudience: opts.SpiffeAudience, SpiffeSocket: opts.SpiffeSocket, VaultAuthRole: opts.VaultAuthRole, VaultAuthMount: opts.VaultAuthMount}
+			if login.SpiffeAudience == "" || login.VaultAuthRole == "" {
+				return nil, fmt.Errorf("invalid options of provider %s: spiffe_audience and vault_auth_role must be set together", name)
+			}
+			if login.VaultAuthMount == "" {
+				login.VaultAuthMount = defaultVaultAuthMount
+			}
+			if plan.Vault != nil && !reflect.DeepEqual(*plan.Vault, login) {
+				return nil, fmt.Errorf("providers %s and %s log in to Vault differently, but teller uses one set of credentials per run", vaultFrom, name)
+			}
+			plan.Vault, vaultFrom = &login, name
+		}
+
+		if err := checkTLS(opts); err != nil {
+			return nil, fmt.Errorf("invalid options of provider %s: %w", name, err)
+		}
+		// Plugins are never run by teller
+		if (opts.CACert != "" || opts.ClientCert != "" || opts.InsecureSkipVerify) && !strings.HasPrefix(provider.Kind, "plugin/") {
+			if (opts.ClientCert != "" || opts.InsecureSkipVerify) && provider.Kind != "hashicorp_vault" {
+				return nil, fmt.Errorf("provider %s sets client_cert or insecure_skip_verify, but teller only supports them for hashicorp_vault providers; resolve it with feller instead", name)
+			}
+			transport := Options{CACert: opts.CACert, ClientCert: opts.ClientCert, ClientKey: opts.ClientKey, InsecureSkipVerify: opts.InsecureSkipVerify}
+			if current := plan.TLS[provider.Kind]; current != nil && !reflect.DeepEqual(*current, transport) {
+				return nil, fmt.Errorf("providers %s and %s have different TLS options, but teller uses one %s client per run", tlsFrom[provider.Kind], name, provider.Kind)
+			}
+			if plan.TLS == nil {
+				plan.TLS = make(map[string]*Options)
+			}
+			plan.TLS[provider.Kind], tlsFrom[provider.Kind] = &transport, name
+		}
+		if opts.Proxy != "" {
+			if plan.Proxy != "" && plan.Proxy != opts.Proxy {
+				return nil, fmt.Errorf("providers %s and %s use different proxies, but teller uses one proxy per run", proxyFrom, name)
+			}
+			plan.Proxy, proxyFrom = opts.Proxy, name
+		}
+
+		endpoint := opts.Endpoint
+		if provider.Kind == "hashicorp_vault" {
+			endpoint = opts.Address
+		}
+		if endpoint != "" && !strings.HasPrefix(provider.Kind, "plugin/") {
+			variable, ok := endpointEnv[provider.Kind]
+			if !ok {
+				return nil, fmt.Errorf("provider %s has an endpoint, but teller cannot reach %s providers through one; resolve it with feller instead", name, provider.Kind)
+			}
+			if plan.Endpoints[variable] != "" && plan.Endpoints[variable] != endpoint {
+				return nil, fmt.Errorf("providers %s and %s use different endpoints, but teller uses one %s per run", endpointFrom[variable], name, variable)
+			}
+			if plan.Endpoints == nil {
+				plan.Endpoints = make(map[string]string)
+			}
+			plan.Endpoints[variable], endpointFrom[variable] = endpoint, name
+		}
+
+		// Hosts without the proxy do not conflict, so they are merged
+		plan.NoProxy = appendHosts(plan.NoProxy, opts.NoProxy...)
+	}
+	return plan, nil
+}
+
+// checkDuration checks the session duration against the limits of AWS, which allows at
+// most one hour for roles assumed with the credentials of another role
+func checkDuration(role Options) error {
+	maxSeconds := maxDurationSeconds
+	if len(role.AssumeRoles) > 1 {
+		maxSeconds = maxChainedDurationSeconds
+	}
+	if role.RoleDurationSeconds < defaultDurationSeconds || role.RoleDurationSeconds > maxSeconds {
+		return fmt.Errorf("role_duration_seconds must be between %d and %d", defaultDurationSeconds, maxSeconds)
+	}
+	return nil
+}
+
+// Prepare writes the credential configuration of plan to a private temporary directory and
+// returns environ adjusted so a child process uses it. cleanup removes the directory, which
+// holds copies of the source credentials, and must be called once the child has exited.
+func Prepare(plan *Plan, environ []string) (env []string, cleanup func(), err error) {
+	if plan.Empty() {
+		return environ, func() {}, nil
+	}
+
+	dir, err := os.MkdirTemp("", "feller-credentials-*")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create credentials directory: %w", err)
+	}
+	cleanup = func() { _ = os.RemoveAll(dir) }
+
+	env = slices.Clone(environ)
+	// Before the Vault login, which reads VAULT_ADDR
+	for _, variable := range slices.Sorted(maps.Keys(plan.Endpoints)) {
+		env = setEnv(env, variable, plan.Endpoints[variable])
+	}
+	if len(plan.TLS) > 0 || plan.Proxy != "" {
+		if env, err = prepareTLS(plan, dir, env); err != nil {
+			cleanup()
+			return nil, nil, err
+		}
+	}
+	if len(plan.NoProxy) > 0 {
+		env = prepareNoProxy(plan.NoProxy, env)
+	}
+	if plan.GCP != nil {
+		if env, err = prepareGCP(plan.GCP, dir, env); err != nil {
+			cleanup()
+			return nil, nil, err
+		}
+	}
+	if plan.AWS != nil {
+		if env, err = prepareAWS(plan.AWS, dir, env); err != nil {
+			cleanup()
+			return nil, nil, err
+		}
+	}
+	if plan.Vault != nil {
+		if env, err = prepareVault(plan.Vault, vaultTransport(plan), plan.NoProxy, env); err != nil {
+			cleanup()
+			return nil, nil, err
+		}
+	}
+	return env, cleanup, nil
+}
+
+// vaultTransport returns the TLS and proxy options of feller's own Vault login, nil when the
+// plan has none
+func vaultTransport(plan *Plan) *Options {
+	opts := plan.TLS["hashicorp_vault"]
+	if opts == nil && plan.Proxy == "" {
+		return nil
+	}
+	transport := Options{Proxy: plan.Proxy}
+	if opts != nil {
+		transport.CACert, transport.ClientCert, transport.ClientKey = opts.CACert, opts.ClientCert, opts.ClientKey
+		transport.InsecureSkipVerify = opts.InsecureSkipVerify
+	}
+	return &transport
+}
+
+// lookupEnv returns the value of key in environ
+func lookupEnv(environ []string, key string) string {
+	for i := len(environ) - 1; i >= 0; i-- {
+		if k, v, ok := strings.Cut(environ[i], "="); ok && k == key {
+			return v
+		}
+	}
+	return ""
+}
+
+// setEnv returns environ with key set to value, or removed when value is empty
+func setEnv(environ []string, key, value string) []string {
+	environ = slices.DeleteFunc(environ, func(kv string) bool {
+		k, _, _ := strings.Cut(kv, "=")
+		return k == key
+	})
+	if value != "" {
+		environ = append(environ, key+"="+value)
+	}
+	return environ
+}
@@ -0,0 +1,321 @@
+package credentials
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/containifyci/feller/pkg/config"
+	"gopkg.in/yaml.v3"
+)
+
+func parseConfig(t *testing.T, content string) *config.TellerConfig {
+	t.Helper()
+	var cfg config.TellerConfig
+	if err := yaml.Unmarshal([]byte(content), &cfg); err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+	return &cfg
+}
+
+func TestPlanFor(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name          string
+		config        string
+		errContains   string
+		expectedGCP   *Options
+		expectedAWS   *Options
+		expectedVault *Options
+	}{
+		{name: "no options", config: "providers:\n  a: {kind: dotenv}\n"},
+		{name: "other options only", config: "providers:\n  a:\n    kind: hashicorp_vault\n    options: {namespace: team}\n"},
+		{
+			name:        "impersonation",
+			config:      "providers:\n  gsm:\n    kind: google_secretmanager\n    options:\n      impersonate_service_account: reader@p.iam.gserviceaccount.com\n      impersonation_delegates: [hop@p.iam.gserviceaccount.com]\n",
+			expectedGCP: &Options{ImpersonateServiceAccount: "reader@p.iam.gserviceaccount.com", ImpersonationDelegates: []string{"hop@p.iam.gserviceaccount.com"}},
+		},
+		{
+			name:        "role chain with defaults",
+			config:      "providers:\n  aws:\n    kind: aws_secretsmanager\n    options: {assume_roles: ['arn:aws:iam::1:role/a', 'arn:aws:iam::2:role/b']}\n",
+			expectedAWS: &Options{AssumeRoles: []string{"arn:aws:iam::1:role/a", "arn:aws:iam::2:role/b"}, RoleSessionName: "feller", RoleDurationSeconds: 900},
+		},
+		{
+			name: "same credentials in two providers",
+			config: "providers:\n  a:\n    kind: google_secretmanager\n    options: {impersonate_service_account: r@p.iam.gserviceaccount.com}\n" +
+				"  b:\n    kind: google_secretmanager\n    options: {impersonate_service_account: r@p.iam.gserviceaccount.com}\n",
+			expectedGCP: &Options{ImpersonateServiceAccount: "r@p.iam.gserviceaccount.com"},
+		},
+		{
+			name: "different service accounts",
+			config: "providers:\n  a:\n    kind: google_secretmanager\n    options: {impersonate_service_account: r@p.iam.gserviceaccount.com}\n" +
+				"  b:\n    kind: google_secretmanager\n    options: {impersonate_service_account: w@p.iam.gserviceaccount.com}\n",
+			errContains: "providers a and b impersonate different service accounts",
+		},
+		{
+			name:        "chained session too long",
+			config:      "providers:\n  aws:\n    kind: aws_ssm\n    options: {assume_roles: [a, b], role_duration_seconds: 7200}\n",
+			errContains: "role_duration_seconds must be between 900 and 3600",
+		},
+		{
+			name:        "session too short",
+			config:      "providers:\n  aws:\n    kind: aws_ssm\n    options: {assume_roles: [a], role_duration_seconds: 60}\n",
+			errContains: "role_duration_seconds must be between 900 and 43200",
+		},
+		{
+			name:          "vault login with default mount",
+			config:        "providers:\n  vault:\n    kind: hashicorp_vault\n    options: {spiffe_audience: vault, vault_auth_role: app}\n",
+			expectedVault: &Options{SpiffeAudience: "vault", VaultAuthRole: "app", VaultAuthMount: "jwt"},
+		},
+		{
+			name:        "vault role without audience",
+			config:      "providers:\n  vault:\n    kind: hashicorp_vault\n    options: {vault_auth_role: app}\n",
+			errContains: "spiffe_audience and vault_auth_role must be set together",
+		},
+		{
+			name: "different vault roles",
+			config: "providers:\n  a:\n    kind: hashicorp_vault\n    options: {spiffe_audience: vault, vault_auth_role: app}\n" +
+				"  b:\n    kind: hashicorp_vault\n    options: {spiffe_audience: vault, vault_auth_role: admin}\n",
+			errContains: "providers a and b log in to Vault differently",
+		},
+		{name: "invalid options", config: "providers:\n  a:\n    kind: aws_ssm\n    options: {assume_roles: nope}\n", errContains: "invalid options of provider a"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			plan, err := PlanFor(parseConfig(t, tt.config))
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Errorf("PlanFor() error = %v, expected to contain %q", err, tt.errContains)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("PlanFor() unexpected error = %v", err)
+			}
+			if !reflect.DeepEqual(plan.GCP, tt.expectedGCP) || !reflect.DeepEqual(plan.AWS, tt.expectedAWS) {
+				t.Errorf("PlanFor() = GCP %+v, AWS %+v, want GCP %+v, AWS %+v", plan.GCP, plan.AWS, tt.expectedGCP, tt.expectedAWS)
+			}
+			if !reflect.DeepEqual(plan.Vault, tt.expectedVault) {
+				t.Errorf("PlanFor() = Vault %+v, want %+v", plan.Vault, tt.expectedVault)
+			}
+			if plan.Empty() != (tt.expectedGCP == nil && tt.expectedAWS == nil && tt.expectedVault == nil) {
+				t.Errorf("Empty() = %v", plan.Empty())
+			}
+		})
+	}
+}
+
+func TestPlanForEndpoints(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name        string
+		config      string
+		expected    map[string]string
+		errContains string
+	}{
+		{
+			name: "localstack",
+			config: "providers:\n  sm:\n    kind: aws_secretsmanager\n    options: {endpoint: 'http://localhost:4566'}\n" +
+				"  ssm:\n    kind: aws_ssm\n    options: {endpoint: 'http://localhost:4566'}\n",
+			expected: map[string]string{"AWS_ENDPOINT_URL_SECRETS_MANAGER": "http://localhost:4566", "AWS_ENDPOINT_URL_SSM": "http://localhost:4566"},
+		},
+		{
+			name:     "vault address",
+			config:   "providers:\n  vault:\n    kind: hashicorp_vault\n    options: {address: 'https://vault.staging:8200'}\n",
+			expected: map[string]string{"VAULT_ADDR": "https://vault.staging:8200"},
+		},
+		{
+			name: "different endpoints",
+			config: "providers:\n  a:\n    kind: aws_ssm\n    options: {endpoint: 'http://localhost:4566'}\n" +
+				"  b:\n    kind: aws_ssm\n    options: {endpoint: 'https://ssm.eu-west-1.amazonaws.com'}\n",
+			errContains: "providers a and b use different endpoints, but teller uses one AWS_ENDPOINT_URL_SSM per run",
+		},
+		{
+			name:        "kind without variable",
+			config:      "providers:\n  gsm:\n    kind: google_secretmanager\n    options: {endpoint: 'https://secretmanager.private.example'}\n",
+			errContains: "teller cannot reach google_secretmanager providers through one",
+		},
+		{name: "plugin", config: "providers:\n  p:\n    kind: plugin/acme\n    options: {endpoint: 'http://localhost:9000'}\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			plan, err := PlanFor(parseConfig(t, tt.config))
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Errorf("PlanFor() error = %v, expected to contain %q", err, tt.errContains)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("PlanFor() unexpected error = %v", err)
+			}
+			if len(plan.Endpoints) != len(tt.expected) || (len(tt.expected) > 0 && !reflect.DeepEqual(plan.Endpoints, tt.expected)) {
+				t.Errorf("PlanFor() endpoints = %v, want %v", plan.Endpoints, tt.expected)
+			}
+		})
+	}
+}
+
+func TestPrepareEndpoints(t *testing.T) {
+	t.Parallel()
+	plan := &Plan{Endpoints: map[string]string{"VAULT_ADDR": "http://127.0.0.1:8200"}}
+	env, cleanup, err := Prepare(plan, []string{"VAULT_ADDR=https://vault.prod", "OTHER=x"})
+	if err != nil {
+		t.Fatalf("Prepare() unexpected error = %v", err)
+	}
+	defer cleanup()
+	if lookupEnv(env, "VAULT_ADDR") != "http://127.0.0.1:8200" || lookupEnv(env, "OTHER") != "x" {
+		t.Errorf("Prepare() env = %v", env)
+	}
+}
+
+func TestPrepareEmpty(t *testing.T) {
+	t.Parallel()
+	environ := []string{"A=1"}
+	env, cleanup, err := Prepare(&Plan{}, environ)
+	if err != nil || !reflect.DeepEqual(env, environ) {
+		t.Errorf("Prepare() = %v, %v, want environment unchanged", env, err)
+	}
+	cleanup()
+}
+
+func TestPrepareGCP(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	source := filepath.Join(dir, "wif.json")
+	if err := os.WriteFile(source, []byte(`{"type": "external_account", "audience": "//iam.googleapis.com/x"}`), 0o600); err != nil {
+		t.Fatalf("Failed to write credentials: %v", err)
+	}
+
+	plan := &Plan{GCP: &Options{ImpersonateServiceAccount: "reader@p.iam.gserviceaccount.com"}}
+	env, cleanup, err := Prepare(plan, []string{"GOOGLE_APPLICATION_CREDENTIALS=" + source, "OTHER=x"})
+	if err != nil {
+		t.Fatalf("Prepare() unexpected error = %v", err)
+	}
+
+	path := lookupEnv(env, gcpCredentialsEnv)
+	if path == source || lookupEnv(env, "OTHER") != "x" {
+		t.Fatalf("Prepare() env = %v", env)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read prepared credentials: %v", err)
+	}
+	var creds map[string]any
+	if err := json.Unmarshal(data, &creds); err != nil {
+		t.Fatalf("Prepared credentials are not JSON: %v", err)
+	}
+	expected := map[string]any{
+		"type":                              "impersonated_service_account",
+		"service_account_impersonation_url": "https://iamcredentials.googleapis.com/v1/projects/-/serviceAccounts/reader@p.iam.gserviceaccount.com:generateAccessToken",
+		"delegates":                         []any{},
+		"source_credentials":                map[string]any{"type": "external_account", "audience": "//iam.googleapis.com/x"},
+	}
+	if !reflect.DeepEqual(creds, expected) {
+		t.Errorf("Prepare() credentials = %v, want %v", creds, expected)
+	}
+
+	cleanup()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("cleanup() left credentials behind: %v", err)
+	}
+}
+
+func TestPrepareGCPErrors(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	impersonated := filepath.Join(dir, "impersonated.json")
+	if err := os.WriteFile(impersonated, []byte(`{"type": "impersonated_service_account"}`), 0o600); err != nil {
+		t.Fatalf("Failed to write credentials: %v", err)
+	}
+	plan := &Plan{GCP: &Options{ImpersonateServiceAccount: "r@p.iam.gserviceaccount.com"}}
+
+	tests := []struct {
+		name        string
+		errContains string
+		environ     []string
+	}{
+		{name: "no source credentials", environ: []string{"HOME=" + dir, "CLOUDSDK_CONFIG=" + dir}, errContains: "no credentials to impersonate"},
+		{name: "already impersonated", environ: []string{"GOOGLE_APPLICATION_CREDENTIALS=" + impersonated}, errContains: "already impersonate"},
+		{name: "missing file", environ: []string{"GOOGLE_APPLICATION_CREDENTIALS=" + filepath.Join(dir, "nope.json")}, errContains: "failed to read GCP credentials"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if _, _, err := Prepare(plan, tt.environ); err == nil || !strings.Contains(err.Error(), tt.errContains) {
+				t.Errorf("Prepare() error = %v, expected to contain %q", err, tt.errContains)
+			}
+		})
+	}
+}
+
+func TestPrepareAWS(t *testing.T) {
+	t.Parallel()
+	home := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(home, ".aws"), 0o700); err != nil {
+		t.Fatalf("Failed to create .aws: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(home, ".aws", "config"), []byte("[profile sso]\nsso_start_url = https://x"), 0o600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	plan := &Plan{AWS: &Options{AssumeRoles: []string{"arn:a", "arn:b"}, RoleSessionName: "ci", RoleDurationSeconds: 900}}
+
+	t.Run("environment credentials", func(t *testing.T) {
+		t.Parallel()
+		env, cleanup, err := Prepare(plan, []string{"HOME=" + home, "AWS_ACCESS_KEY_ID=AKIA", "AWS_SECRET_ACCESS_KEY=s3cr3t", "AWS_SESSION_TOKEN=tok", "AWS_REGION=eu-west-1"})
+		if err != nil {
+			t.Fatalf("Prepare() unexpected error = %v", err)
+		}
+		defer cleanup()
+
+		for _, key := range []string{"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "AWS_SESSION_TOKEN"} {
+			if lookupEnv(env, key) != "" {
+				t.Errorf("Prepare() kept %s, which would take precedence over the role", key)
+			}
+		}
+		if lookupEnv(env, "AWS_PROFILE") != "feller-role-2" || lookupEnv(env, "AWS_REGION") != "eu-west-1" {
+			t.Errorf("Prepare() env = %v", env)
+		}
+
+		configData, _ := os.ReadFile(lookupEnv(env, "AWS_CONFIG_FILE"))
+		expectedConfig := "[profile sso]\nsso_start_url = https://x\n" +
+			"\n[profile feller-role-1]\nrole_arn = arn:a\nsource_profile = feller-source\nrole_session_name = ci\nduration_seconds = 900\n" +
+			"\n[profile feller-role-2]\nrole_arn = arn:b\nsource_profile = feller-role-1\nrole_session_name = ci\nduration_seconds = 900\n"
+		if string(configData) != expectedConfig {
+			t.Errorf("Prepare() config =\n%s\nwant\n%s", configData, expectedConfig)
+		}
+		credentialsData, _ := os.ReadFile(lookupEnv(env, "AWS_SHARED_CREDENTIALS_FILE"))
+		expectedCredentials := "\n[feller-source]\naws_access_key_id = AKIA\naws_secret_access_key = s3cr3t\naws_session_token = tok\n"
+		if string(credentialsData) != expectedCredentials {
+			t.Errorf("Prepare() credentials =\n%s\nwant\n%s", credentialsData, expectedCredentials)
+		}
+	})
+
+	t.Run("source profile", func(t *testing.T) {
+		t.Parallel()
+		env, cleanup, err := Prepare(plan, []string{"HOME=" + home, "AWS_PROFILE=sso"})
+		if err != nil {
+			t.Fatalf("Prepare() unexpected error = %v", err)
+		}
+		defer cleanup()
+		configData, _ := os.ReadFile(lookupEnv(env, "AWS_CONFIG_FILE"))
+		if !strings.Contains(string(configData), "role_arn = arn:a\nsource_profile = sso\n") {
+			t.Errorf("Prepare() config does not start the chain from the sso profile:\n%s", configData)
+		}
+	})
+
+	t.Run("partial environment credentials", func(t *testing.T) {
+		t.Parallel()
+		if _, _, err := Prepare(plan, []string{"HOME=" + home, "AWS_ACCESS_KEY_ID=AKIA"}); err == nil || !strings.Contains(err.Error(), "must be set together") {
+			t.Errorf("Prepare() error = %v, want incomplete credentials error", err)
+		}
+	})
+}
@@ -0,0 +1,96 @@
+package credentials
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// gcpCredentialsEnv points the Google SDKs at a credentials file
+const gcpCredentialsEnv = "GOOGLE_APPLICATION_CREDENTIALS"
+
+// impersonationURL is the IAM Credentials endpoint minting access tokens for an account
+const impersonationURL = "https://iamcredentials.googleapis.com/v1/projects/-/serviceAccounts/%s:generateAccessToken"
+
+// prepareGCP writes an impersonated_service_account credentials file whose source is the
+// current application default credentials, so the SDK exchanges them for a token of the
+// target account on first use
+func prepareGCP(opts *Options, dir string, env []string) ([]string, error) {
+	sourcePath := gcpSourcePath(env)
+	if sourcePath == "" {
+		return nil, fmt.Errorf("no credentials to impersonate %s with: set %s or run 'gcloud auth application-default login'",
+			opts.ImpersonateServiceAccount, gcpCredentialsEnv)
+	}
+	// #nosec G304 - Path of the user's application default credentials
+	source, err := os.ReadFile(sourcePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read GCP credentials: %w", err)
+	}
+
+	data, err := impersonatedCredentials(source, opts)
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, "gcp-impersonated.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return nil, fmt.Errorf("failed to write GCP credentials: %w", err)
+	}
+	return setEnv(env, gcpCredentialsEnv, path), nil
+}
+
+// impersonatedCredentials returns a credentials file impersonating the account of opts
+// with the source credentials file
+func impersonatedCredentials(source []byte, opts *Options) ([]byte, error) {
+	var sourceCreds map[string]any
+	if err := json.Unmarshal(source, &sourceCreds); err != nil {
+		return nil, fmt.Errorf("invalid GCP credentials file: %w", err)
+	}
+	switch sourceCreds["type"] {
+	case "authorized_user", "service_account", "external_account", "external_account_authorized_user":
+	case "impersonated_service_account":
+		return nil, errors.New("GCP credentials already impersonate a service account; use impersonation_delegates to chain accounts")
+	default:
+		return nil, fmt.Errorf("unsupported GCP credentials type %v", sourceCreds["type"])
+	}
+
+	delegates := opts.ImpersonationDelegates
+	if delegates == nil {
+		delegates = []string{}
+	}
+	data, err := json.MarshalIndent(map[string]any{
+		"type":                              "impersonated_service_account",
+		"service_account_impersonation_url": fmt.Sprintf(impersonationURL, opts.ImpersonateServiceAccount),
+		"delegates":                         delegates,
+		"source_credentials":                sourceCreds,
+	}, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode GCP credentials: %w", err)
+	}
+	return data, nil
+}
+
+// gcpSourcePath returns the application default credentials file the SDKs would use, or
+// an empty string when there is none
+func gcpSourcePath(env []string) string {
+	if path := lookupEnv(env, gcpCredentialsEnv); path != "" {
+		return path
+	}
+
+	var configDir string
+	switch {
+	case lookupEnv(env, "CLOUDSDK_CONFIG") != "":
+		configDir = lookupEnv(env, "CLOUDSDK_CONFIG")
+	case runtime.GOOS == "windows":
+		configDir = filepath.Join(lookupEnv(env, "APPDATA"), "gcloud")
+	default:
+		configDir = filepath.Join(lookupEnv(env, "HOME"), ".config", "gcloud")
+	}
+	path := filepath.Join(configDir, "application_default_credentials.json")
+	if _, err := os.Stat(path); err != nil {
+		return ""
+	}
+	return path
+}
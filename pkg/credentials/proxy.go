@@ -0,0 +1,129 @@
+package credentials
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/containifyci/feller/pkg/config"
+)
+
+// providerHosts lists the API hosts of provider kinds with fixed endpoints. A leading dot
+// matches every subdomain, as in NO_PROXY.
+var providerHosts = map[string][]string{
+	"google_secretmanager": {"secretmanager.googleapis.com", "oauth2.googleapis.com", "iamcredentials.googleapis.com", "sts.googleapis.com"},
+	"aws_secretsmanager":   {".amazonaws.com"},
+	"aws_ssm":              {".amazonaws.com"},
+}
+
+// endpointOptions are the provider options naming the endpoint of kinds without a fixed one
+type endpointOptions struct {
+	Address  string   `yaml:"address"`
+	Endpoint string   `yaml:"endpoint"`
+	NoProxy  []string `yaml:"no_proxy"`
+}
+
+// BypassProxy adds the hosts of the named providers to the NO_PROXY of the plan, so teller
+// reaches them directly while other providers keep using the proxy. Hosts come from the
+// kind, the endpoint option, the address option or VAULT_ADDR of Vault, and the no_proxy
+// option.
+func (p *Plan) BypassProxy(cfg *config.TellerConfig, names []string, environ []string) error {
+	for _, name := range names {
+		provider, ok := cfg.Providers[name]
+		if !ok {
+			return fmt.Errorf("unknown provider %q in --no-proxy-providers", name)
+		}
+		var opts endpointOptions
+		if !provider.Options.IsZero() {
+			if err := provider.Options.Decode(&opts); err != nil {
+				return fmt.Errorf("invalid options of provider %s: %w", name, err)
+			}
+		}
+
+		hosts := slices.Clone(providerHosts[provider.Kind])
+		address := opts.Address
+		if address == "" && provider.Kind == "hashicorp_vault" {
+			address = lookupEnv(environ, "VAULT_ADDR")
+		}
+		if opts.Endpoint != "" {
+			address = opts.Endpoint
+		}
+		if address != "" {
+			endpoint, err := url.Parse(address)
+			if err != nil || endpoint.Hostname() == "" {
+				return fmt.Errorf("invalid address %q of provider %s", address, name)
+			}
+			hosts = append(hosts, endpoint.Hostname())
+		}
+		hosts = append(hosts, opts.NoProxy...)
+		if len(hosts) == 0 {
+			return fmt.Errorf("cannot tell which hosts provider %s connects to; list them in its no_proxy option", name)
+		}
+		p.NoProxy = appendHosts(p.NoProxy, hosts...)
+	}
+	return nil
+}
+
+// appendHosts adds hosts to a sorted list without duplicates
+func appendHosts(list []string, hosts ...string) []string {
+	for _, host := range hosts {
+		if host != "" && !slices.Contains(list, host) {
+			list = append(list, host)
+		}
+	}
+	sort.Strings(list)
+	return list
+}
+
+// prepareNoProxy appends hosts to the NO_PROXY of environ
+func prepareNoProxy(hosts []string, env []string) []string {
+	value := strings.Join(hosts, ",")
+	for _, key := range []string{"NO_PROXY", "no_proxy"} {
+		if existing := lookupEnv(env, key); existing != "" {
+			env = setEnv(env, key, existing+","+value)
+		} else {
+			env = setEnv(env, key, value)
+		}
+	}
+	return env
+}
+
+// proxyFunc returns the proxy of the requests feller makes itself: none for hosts in
+// noProxy, the proxy option when set, and the environment's otherwise
+func proxyFunc(proxy string, noProxy []string) (func(*http.Request) (*url.URL, error), error) {
+	var fixed *url.URL
+	if proxy != "" {
+		var err error
+		if fixed, err = url.Parse(proxy); err != nil {
+			return nil, fmt.Errorf("invalid proxy: %w", err)
+		}
+	}
+	return func(req *http.Request) (*url.URL, error) {
+		if bypassesProxy(req.URL.Hostname(), noProxy) {
+			return nil, nil
+		}
+		if fixed != nil {
+			return fixed, nil
+		}
+		return http.ProxyFromEnvironment(req)
+	}, nil
+}
+
+// bypassesProxy reports whether host matches an entry of noProxy: "*", the host itself, or
+// a domain it belongs to
+func bypassesProxy(host string, noProxy []string) bool {
+	for _, entry := range noProxy {
+		if h, _, err := net.SplitHostPort(entry); err == nil {
+			entry = h
+		}
+		domain := strings.TrimPrefix(entry, ".")
+		if entry == "*" || host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,124 @@
+package credentials
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestPlanForNoProxy(t *testing.T) {
+	t.Parallel()
+	plan, err := PlanFor(parseConfig(t, "providers:\n  a:\n    kind: hashicorp_vault\n    options: {no_proxy: [vault.internal, .svc]}\n  b:\n    kind: aws_ssm\n    options: {no_proxy: [vault.internal]}\n"))
+	if err != nil || !slices.Equal(plan.NoProxy, []string{".svc", "vault.internal"}) || plan.Empty() {
+		t.Errorf("PlanFor() = %+v, %v", plan, err)
+	}
+}
+
+func TestBypassProxy(t *testing.T) {
+	t.Parallel()
+	cfg := parseConfig(t, `providers:
+  gsm:
+    kind: google_secretmanager
+  vault:
+    kind: hashicorp_vault
+  remote:
+    kind: hashicorp_vault
+    options: {address: "https://vault.internal:8200"}
+  localstack:
+    kind: aws_ssm
+    options: {endpoint: "http://localstack:4566"}
+  env:
+    kind: dotenv
+  custom:
+    kind: dotenv
+    options: {no_proxy: [files.internal]}
+`)
+	environ := []string{"VAULT_ADDR=https://vault.corp.example"}
+
+	tests := []struct {
+		name        string
+		providers   []string
+		expected    []string
+		errContains string
+	}{
+		{name: "fixed hosts", providers: []string{"gsm"}, expected: []string{"iamcredentials.googleapis.com", "oauth2.googleapis.com", "secretmanager.googleapis.com", "sts.googleapis.com"}},
+		{name: "vault addr", providers: []string{"vault"}, expected: []string{"vault.corp.example"}},
+		{name: "address option", providers: []string{"remote", "vault"}, expected: []string{"vault.corp.example", "vault.internal"}},
+		{name: "endpoint option", providers: []string{"localstack"}, expected: []string{".amazonaws.com", "localstack"}},
+		{name: "no_proxy option", providers: []string{"custom"}, expected: []string{"files.internal"}},
+		{name: "unknown hosts", providers: []string{"env"}, errContains: "cannot tell which hosts provider env connects to"},
+		{name: "unknown provider", providers: []string{"missing"}, errContains: `unknown provider "missing"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var plan Plan
+			err := plan.BypassProxy(cfg, tt.providers, environ)
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Errorf("BypassProxy() error = %v, expected to contain %q", err, tt.errContains)
+				}
+				return
+			}
+			if err != nil || !slices.Equal(plan.NoProxy, tt.expected) {
+				t.Errorf("BypassProxy() = %v, %v, expected %v", plan.NoProxy, err, tt.expected)
+			}
+		})
+	}
+}
+
+func TestPrepareNoProxy(t *testing.T) {
+	t.Parallel()
+	env := prepareNoProxy([]string{".svc", "vault.internal"}, []string{"NO_PROXY=localhost"})
+	if got := lookupEnv(env, "NO_PROXY"); got != "localhost,.svc,vault.internal" {
+		t.Errorf("NO_PROXY = %q", got)
+	}
+	if got := lookupEnv(env, "no_proxy"); got != ".svc,vault.internal" {
+		t.Errorf("no_proxy = %q", got)
+	}
+}
+
+func TestBypassesProxy(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		host     string
+		noProxy  []string
+		expected bool
+	}{
+		{host: "vault.internal", noProxy: []string{"vault.internal"}, expected: true},
+		{host: "vault.internal", noProxy: []string{"vault.internal:8200"}, expected: true},
+		{host: "secretsmanager.eu-west-1.amazonaws.com", noProxy: []string{".amazonaws.com"}, expected: true},
+		{host: "a.svc", noProxy: []string{"svc"}, expected: true},
+		{host: "anything", noProxy: []string{"*"}, expected: true},
+		{host: "notvault.internal", noProxy: []string{"vault.internal"}, expected: false},
+		{host: "vault.internal", noProxy: nil, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			t.Parallel()
+			if got := bypassesProxy(tt.host, tt.noProxy); got != tt.expected {
+				t.Errorf("bypassesProxy(%q, %v) = %v, expected %v", tt.host, tt.noProxy, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestProxyFunc(t *testing.T) {
+	t.Parallel()
+	proxy, err := proxyFunc("http://proxy:3128", []string{"vault.internal"})
+	if err != nil {
+		t.Fatalf("proxyFunc() error = %v", err)
+	}
+
+	direct, _ := http.NewRequest(http.MethodGet, "https://vault.internal/v1/sys/health", nil)
+	if got, err := proxy(direct); err != nil || got != nil {
+		t.Errorf("proxy(vault.internal) = %v, %v, expected no proxy", got, err)
+	}
+	proxied, _ := http.NewRequest(http.MethodGet, "https://vault.example.com", nil)
+	if got, err := proxy(proxied); err != nil || got == nil || got.Host != "proxy:3128" {
+		t.Errorf("proxy(vault.example.com) = %v, %v, expected proxy:3128", got, err)
+	}
+}
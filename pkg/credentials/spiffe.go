@@ -0,0 +1,253 @@
+package credentials
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// SPIFFE Workload API socket of the SPIRE agent, and its default location
+const (
+	spiffeSocketEnv     = "SPIFFE_ENDPOINT_SOCKET"
+	defaultSpiffeSocket = "/tmp/spire-agent/public/api.sock"
+)
+
+// defaultVaultAuthMount is the path the Vault JWT auth method is enabled at by default
+const defaultVaultAuthMount = "jwt"
+
+// spiffeTimeout bounds fetching the SVID and logging in to Vault
+const spiffeTimeout = 30 * time.Second
+
+// prepareVault fetches a JWT-SVID from the Workload API of the SPIRE agent, exchanges it for a Vault token with
+// the JWT auth method and hands the token to teller in VAULT_TOKEN. The SVID is short-lived
+// and bound to the audience, so it is never written to disk. The login honors the TLS and
+// proxy options.
+func prepareVault(opts, transport *Options, noProxy, env []string) ([]string, error) {
+	addr := lookupEnv(env, "VAULT_ADDR")
+	if addr == "" {
+		return nil, errors.New("VAULT_ADDR must be set to log in to Vault with a SPIFFE identity")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), spiffeTimeout)
+	defer cancel()
+
+	svid, err := fetchJWTSVID(ctx, opts, env)
+	if err != nil {
+		return nil, err
+	}
+	client, err := httpClient(transport, noProxy)
+	if err != nil {
+		return nil, err
+	}
+	token, err := vaultLogin(ctx, client, addr, lookupEnv(env, "VAULT_NAMESPACE"), opts, svid)
+	if err != nil {
+		return nil, err
+	}
+	return setEnv(env, "VAULT_TOKEN", token), nil
+}
+
+// Workload API method and the metadata header SPIRE agents require of every call
+const (
+	workloadAPIFetchJWTSVID = "/SpiffeWorkloadAPI/FetchJWTSVID"
+	workloadAPIHeader       = "workload.spiffe.io"
+)
+
+// fetchJWTSVID returns a JWT-SVID for the audience of opts from the Workload API at the socket
+// of opts, SPIFFE_ENDPOINT_SOCKET of env or the default socket
+func fetchJWTSVID(ctx context.Context, opts *Options, env []string) (string, error) {
+	socket := opts.SpiffeSocket
+	if socket == "" {
+		socket = lookupEnv(env, spiffeSocketEnv)
+	}
+	return FetchJWTSVID(ctx, opts.SpiffeAudience, socket)
+}
+
+// FetchJWTSVID returns a JWT-SVID for audience from the SPIFFE Workload API, which the SPIRE
+// agent serves with gRPC on a Unix socket. An empty socket is read from
+// SPIFFE_ENDPOINT_SOCKET, falling back to the default socket of the agent.
+func FetchJWTSVID(ctx context.Context, audience, socket string) (string, error) {
+	if socket == "" {
+		socket = os.Getenv(spiffeSocketEnv)
+	}
+	if socket == "" {
+		socket = defaultSpiffeSocket
+	}
+	path := strings.TrimPrefix(strings.TrimPrefix(socket, "unix://"), "unix:")
+
+	// gRPC is HTTP/2 without TLS on the socket
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "unix", path)
+		},
+		Protocols: new(http.Protocols),
+	}
+	transport.Protocols.SetUnencryptedHTTP2(true)
+	defer transport.CloseIdleConnections()
+
+	request := protoAppendString(nil, 1, audience)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "http://localhost"+workloadAPIFetchJWTSVID, bytes.NewReader(grpcFrame(request)))
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch a JWT-SVID from %s: %w", path, err)
+	}
+	req.Header.Set("Content-Type", "application/grpc")
+	req.Header.Set("TE", "trailers")
+	req.Header.Set(workloadAPIHeader, "true")
+
+	resp, err := (&http.Client{Transport: transport}).Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch a JWT-SVID from %s: %w", path, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch a JWT-SVID from %s: %w", path, err)
+	}
+	// Failures without a message come in the headers, others in the trailers after the body
+	status, message := resp.Header.Get("Grpc-Status"), resp.Header.Get("Grpc-Message")
+	if status == "" {
+		status, message = resp.Trailer.Get("Grpc-Status"), resp.Trailer.Get("Grpc-Message")
+	}
+	if resp.StatusCode != http.StatusOK || status != "0" {
+		if unescaped, err := url.PathUnescape(message); err == nil {
+			message = unescaped
+		}
+		return "", fmt.Errorf("failed to fetch a JWT-SVID from %s: %s, gRPC status %s: %s", path, resp.Status, status, message)
+	}
+	return parseJWTSVIDResponse(data)
+}
+
+// grpcFrame returns message in the length-prefixed framing of gRPC, uncompressed
+func grpcFrame(message []byte) []byte {
+	frame := make([]byte, 5, 5+len(message))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(message))) // #nosec G115 - Messages are tiny
+	return append(frame, message...)
+}
+
+// parseJWTSVIDResponse returns the token of the first SVID of the framed JWTSVIDResponse in
+// data, whose svids are field 1, each with the token in field 2
+func parseJWTSVIDResponse(data []byte) (string, error) {
+	if len(data) < 5 || data[0] != 0 || uint64(len(data)-5) < uint64(binary.BigEndian.Uint32(data[1:5])) {
+		return "", errors.New("the Workload API returned a malformed or compressed response")
+	}
+	var token string
+	err := protoFields(data[5:5+binary.BigEndian.Uint32(data[1:5])], func(field int, svid []byte) error {
+		if field != 1 || token != "" {
+			return nil
+		}
+		return protoFields(svid, func(field int, value []byte) error {
+			if field == 2 {
+				token = string(value)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return "", fmt.Errorf("the Workload API returned a malformed response: %w", err)
+	}
+	if token == "" {
+		return "", errors.New("the Workload API returned no JWT-SVID")
+	}
+	return token, nil
+}
+
+// protoAppendString appends value as the string field of a protobuf message to b
+func protoAppendString(b []byte, field int, value string) []byte {
+	b = binary.AppendUvarint(b, uint64(field)<<3|2) // #nosec G115 - Field numbers are positive
+	b = binary.AppendUvarint(b, uint64(len(value)))
+	return append(b, value...)
+}
+
+// protoFields calls fn with the number and content of every length-delimited field of the
+// protobuf message msg, skipping fields of the other wire types
+func protoFields(msg []byte, fn func(field int, value []byte) error) error {
+	for len(msg) > 0 {
+		key, n := binary.Uvarint(msg)
+		if n <= 0 {
+			return errors.New("invalid field key")
+		}
+		msg = msg[n:]
+		switch key & 7 {
+		case 0:
+			if _, n = binary.Uvarint(msg); n <= 0 {
+				return errors.New("invalid varint")
+			}
+		case 1:
+			n = 8
+		case 5:
+			n = 4
+		case 2:
+			length, m := binary.Uvarint(msg)
+			if m <= 0 || uint64(len(msg)-m) < length {
+				return errors.New("truncated field")
+			}
+			if err := fn(int(key>>3), msg[m:m+int(length)]); err != nil { // #nosec G115 - Bounded by the message
+				return err
+			}
+			n = m + int(length) // #nosec G115 - Bounded by the message
+		default:
+			return fmt.Errorf("unsupported wire type %d", key&7)
+		}
+		if n > len(msg) {
+			return errors.New("truncated field")
+		}
+		msg = msg[n:]
+	}
+	return nil
+}
+
+// vaultLogin exchanges svid for a Vault token at the JWT auth method of opts
+func vaultLogin(ctx context.Context, client *http.Client, addr, namespace string, opts *Options, svid string) (string, error) {
+	body, err := json.Marshal(map[string]string{"role": opts.VaultAuthRole, "jwt": svid})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode Vault login: %w", err)
+	}
+	url := fmt.Sprintf("%s/v1/auth/%s/login", strings.TrimSuffix(addr, "/"), strings.Trim(opts.VaultAuthMount, "/"))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("failed to log in to Vault: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if namespace != "" {
+		req.Header.Set("X-Vault-Namespace", namespace)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to log in to Vault: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", fmt.Errorf("failed to log in to Vault: %w", err)
+	}
+
+	var result struct {
+		Errors []string `json:"errors"`
+		Auth   struct {
+			ClientToken string `json:"client_token"`
+		} `json:"auth"`
+	}
+	_ = json.Unmarshal(data, &result)
+	if resp.StatusCode != http.StatusOK {
+		status := resp.Status
+		if len(result.Errors) > 0 {
+			status += ": " + strings.Join(result.Errors, "; ")
+		}
+		return "", fmt.Errorf("failed to log in to Vault as role %s: %s", opts.VaultAuthRole, status)
+	}
+	if result.Auth.ClientToken == "" {
+		return "", errors.New("vault login returned no token")
+	}
+	return result.Auth.ClientToken, nil
+}
@@ -0,0 +1,150 @@
+package credentials
+
+import (
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// jwtSVIDResponse returns a framed JWTSVIDResponse with the tokens as SVIDs
+func jwtSVIDResponse(tokens ...string) []byte {
+	var message []byte
+	for _, token := range tokens {
+		svid := protoAppendString(nil, 1, "spiffe://example.org/app")
+		svid = protoAppendString(svid, 2, token)
+		message = protoAppendString(message, 1, string(svid))
+	}
+	return grpcFrame(message)
+}
+
+func TestParseJWTSVIDResponse(t *testing.T) {
+	t.Parallel()
+	if token, err := parseJWTSVIDResponse(jwtSVIDResponse("eyJhbGciOiJFUzI1NiJ9.e30.sig", "second")); err != nil || token != "eyJhbGciOiJFUzI1NiJ9.e30.sig" {
+		t.Errorf("parseJWTSVIDResponse() = %q, %v", token, err)
+	}
+	if _, err := parseJWTSVIDResponse(jwtSVIDResponse()); err == nil || err.Error() != "the Workload API returned no JWT-SVID" {
+		t.Errorf("parseJWTSVIDResponse() error = %v, expected no JWT-SVID", err)
+	}
+	truncated := jwtSVIDResponse("token")
+	if _, err := parseJWTSVIDResponse(truncated[:len(truncated)-2]); err == nil {
+		t.Error("parseJWTSVIDResponse() expected error for a truncated response")
+	}
+}
+
+// fakeWorkloadAPI serves the FetchJWTSVID method of the Workload API on a Unix socket,
+// issuing "svid-token" for the audience vault, and returns the socket
+func fakeWorkloadAPI(t *testing.T) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the Workload API is served on a Unix socket")
+	}
+	dir, err := os.MkdirTemp("", "spire")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+	socket := filepath.Join(dir, "agent.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := &http.Server{Protocols: new(http.Protocols), ReadHeaderTimeout: time.Second}
+	server.Protocols.SetUnencryptedHTTP2(true)
+	server.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/grpc")
+		if r.URL.Path != workloadAPIFetchJWTSVID || r.Header.Get(workloadAPIHeader) != "true" {
+			w.Header().Set("Grpc-Status", "3")
+			w.Header().Set("Grpc-Message", "security header missing")
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		var audience string
+		_ = protoFields(body[5:], func(field int, value []byte) error {
+			if field == 1 {
+				audience = string(value)
+			}
+			return nil
+		})
+		w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+		if audience != "vault" {
+			w.Header().Set("Grpc-Status", "7")
+			w.Header().Set("Grpc-Message", "no identity issued for "+audience)
+			return
+		}
+		_, _ = w.Write(jwtSVIDResponse("svid-token"))
+		w.Header().Set("Grpc-Status", "0")
+	})
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(func() { _ = server.Close() })
+	return socket
+}
+
+func TestPrepareVault(t *testing.T) {
+	t.Parallel()
+	socket := fakeWorkloadAPI(t)
+
+	var login map[string]string
+	var namespace string
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/auth/spiffe/login" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"errors": ["no handler for route"]}`))
+			return
+		}
+		namespace = r.Header.Get("X-Vault-Namespace")
+		_ = json.NewDecoder(r.Body).Decode(&login)
+		if login["role"] != "app" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"errors": ["role \"` + login["role"] + `\" could not be found"]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"auth": {"client_token": "hvs.token"}}`))
+	}))
+	t.Cleanup(vault.Close)
+
+	plan := &Plan{Vault: &Options{SpiffeAudience: "vault", VaultAuthRole: "app", VaultAuthMount: "spiffe"}}
+	env, cleanup, err := Prepare(plan, []string{"VAULT_ADDR=" + vault.URL + "/", "VAULT_NAMESPACE=team", "SPIFFE_ENDPOINT_SOCKET=unix://" + socket, "VAULT_TOKEN=old"})
+	if err != nil {
+		t.Fatalf("Prepare() unexpected error = %v", err)
+	}
+	defer cleanup()
+
+	if lookupEnv(env, "VAULT_TOKEN") != "hvs.token" || strings.Count(strings.Join(env, "\n"), "VAULT_TOKEN=") != 1 {
+		t.Errorf("Prepare() env = %v, want the Vault token", env)
+	}
+	if login["jwt"] != "svid-token" || namespace != "team" {
+		t.Errorf("Vault login = %v in namespace %q", login, namespace)
+	}
+
+	t.Run("audience rejected", func(t *testing.T) {
+		plan := &Plan{Vault: &Options{SpiffeAudience: "db", SpiffeSocket: socket, VaultAuthRole: "app", VaultAuthMount: "spiffe"}}
+		_, _, err := Prepare(plan, []string{"VAULT_ADDR=" + vault.URL})
+		if err == nil || !strings.Contains(err.Error(), "gRPC status 7: no identity issued for db") {
+			t.Errorf("Prepare() error = %v", err)
+		}
+	})
+
+	t.Run("login rejected", func(t *testing.T) {
+		plan := &Plan{Vault: &Options{SpiffeAudience: "vault", SpiffeSocket: socket, VaultAuthRole: "admin", VaultAuthMount: "spiffe"}}
+		_, _, err := Prepare(plan, []string{"VAULT_ADDR=" + vault.URL})
+		if err == nil || !strings.Contains(err.Error(), `failed to log in to Vault as role admin: 400 Bad Request: role "admin" could not be found`) {
+			t.Errorf("Prepare() error = %v", err)
+		}
+	})
+
+	t.Run("no vault address", func(t *testing.T) {
+		_, _, err := Prepare(plan, nil)
+		if err == nil || !strings.Contains(err.Error(), "VAULT_ADDR must be set") {
+			t.Errorf("Prepare() error = %v", err)
+		}
+	})
+}
@@ -0,0 +1,222 @@
+package credentials
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/containifyci/feller/pkg/config"
+	"github.com/containifyci/feller/pkg/logger"
+)
+
+// caEnv names the variable the SDK teller uses for a provider kind reads its trusted CAs
+// from. Kinds not listed use Go's HTTP client, which reads SSL_CERT_FILE.
+var caEnv = map[string]string{
+	"aws_secretsmanager":   "AWS_CA_BUNDLE",
+	"aws_ssm":              "AWS_CA_BUNDLE",
+	"google_secretmanager": "GRPC_DEFAULT_SSL_ROOTS_FILE_PATH",
+	"hashicorp_vault":      "VAULT_CACERT",
+}
+
+// defaultCAEnv is the variable of the kinds missing from caEnv
+const defaultCAEnv = "SSL_CERT_FILE"
+
+// proxyEnv lists the variables teller reads its proxy from, which applies to every provider
+var proxyEnv = []string{"HTTPS_PROXY", "https_proxy", "HTTP_PROXY", "http_proxy"}
+
+// systemRootFiles are the CA bundles of common Linux distributions and macOS, as searched by
+// crypto/x509
+var systemRootFiles = []string{
+	"/etc/ssl/certs/ca-certificates.crt",
+	"/etc/pki/tls/certs/ca-bundle.crt",
+	"/etc/ssl/ca-bundle.pem",
+	"/etc/pki/tls/cacert.pem",
+	"/etc/pki/ca-trust/extracted/pem/tls-ca-bundle.pem",
+	"/etc/ssl/cert.pem",
+}
+
+// checkTLS validates the TLS options of a provider
+func checkTLS(opts Options) error {
+	if (opts.ClientCert == "") != (opts.ClientKey == "") {
+		return errors.New("client_cert and client_key must be set together")
+	}
+	if opts.Proxy != "" {
+		proxy, err := url.Parse(opts.Proxy)
+		if err != nil || proxy.Host == "" {
+			return fmt.Errorf("invalid proxy %q (expected e.g. http://proxy.example.com:3128)", opts.Proxy)
+		}
+		switch proxy.Scheme {
+		case "http", "https", "socks5":
+		default:
+			return fmt.Errorf("unsupported proxy scheme %q (supported: http, https, socks5)", proxy.Scheme)
+		}
+	}
+	return nil
+}
+
+// prepareTLS points the SDK of every provider kind in plan at its CA and, for Vault, client
+// certificate, and teller at the proxy. Paths are made absolute and checked, so a typo fails
+// before teller runs. The variables replace the trusted CAs of their SDK, so each gets a
+// bundle in dir of the system CAs and the ca_cert of every kind reading it.
+func prepareTLS(plan *Plan, dir string, env []string) ([]string, error) {
+	environ := slices.Clone(env)
+	bundles := make(map[string][]string)
+	for _, kind := range slices.Sorted(maps.Keys(plan.TLS)) {
+		opts := plan.TLS[kind]
+		paths := make(map[string]string)
+		for _, file := range []struct{ option, path string }{
+			{"ca_cert", opts.CACert}, {"client_cert", opts.ClientCert}, {"client_key", opts.ClientKey},
+		} {
+			option, path := file.option, file.path
+			if path == "" {
+				continue
+			}
+			abs, err := filepath.Abs(path)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s: %w", option, err)
+			}
+			if _, err := os.Stat(abs); err != nil {
+				return nil, fmt.Errorf("invalid %s: %w", option, err)
+			}
+			paths[option] = abs
+		}
+		if ca := paths["ca_cert"]; ca != "" {
+			variable := caVariable(kind)
+			if !slices.Contains(bundles[variable], ca) {
+				bundles[variable] = append(bundles[variable], ca)
+			}
+		}
+		// Only the Vault SDK reads a client certificate and skips verification from the environment
+		if kind == "hashicorp_vault" {
+			env = setEnv(env, "VAULT_CLIENT_CERT", paths["client_cert"])
+			env = setEnv(env, "VAULT_CLIENT_KEY", paths["client_key"])
+			if opts.InsecureSkipVerify {
+				env = setEnv(env, "VAULT_SKIP_VERIFY", "true")
+			}
+		}
+	}
+
+	for _, variable := range slices.Sorted(maps.Keys(bundles)) {
+		bundle, err := writeCABundle(dir, variable, bundles[variable], environ)
+		if err != nil {
+			return nil, err
+		}
+		env = setEnv(env, variable, bundle)
+	}
+	if plan.Proxy != "" {
+		for _, key := range proxyEnv {
+			env = setEnv(env, key, plan.Proxy)
+		}
+	}
+	return env, nil
+}
+
+// caVariable returns the variable the SDK of a provider kind reads its trusted CAs from
+func caVariable(kind string) string {
+	if variable, ok := caEnv[kind]; ok {
+		return variable
+	}
+	return defaultCAEnv
+}
+
+// writeCABundle writes the CAs the variable trusts in environ, from the file it or SSL_CERT_FILE
+// names or else the system bundle, followed by cas to dir and returns its path
+func writeCABundle(dir, variable string, cas []string, environ []string) (string, error) {
+	var bundle []byte
+	candidates := append([]string{lookupEnv(environ, variable), lookupEnv(environ, defaultCAEnv)}, systemRootFiles...)
+	for _, path := range candidates {
+		if path == "" {
+			continue
+		}
+		// #nosec G304 - Paths of CA bundles from the environment and the system
+		if data, err := os.ReadFile(path); err == nil {
+			bundle = append(append(bundle, data...), '\n')
+			break
+		}
+	}
+	if bundle == nil {
+		logger.Info("Found no system CA bundle, teller trusts only the ca_cert read through %s", variable)
+	}
+	for _, ca := range cas {
+		// #nosec G304 - CA path comes from the provider options
+		data, err := os.ReadFile(ca)
+		if err != nil {
+			return "", fmt.Errorf("failed to read ca_cert: %w", err)
+		}
+		bundle = append(append(bundle, data...), '\n')
+	}
+	path := filepath.Join(dir, strings.ToLower(variable)+".pem")
+	if err := os.WriteFile(path, bundle, 0o600); err != nil {
+		return "", fmt.Errorf("failed to write CA bundle: %w", err)
+	}
+	return path, nil
+}
+
+// httpClient returns a client for the requests feller makes itself, honoring the TLS and
+// proxy options of the plan
+func httpClient(opts *Options, noProxy []string) (*http.Client, error) {
+	if opts == nil {
+		if len(noProxy) == 0 {
+			return http.DefaultClient, nil
+		}
+		opts = &Options{}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// #nosec G402 - Verification is only skipped when insecure_skip_verify is set, with a warning
+	transport.TLSClientConfig = &tls.Config{MinVersion: tls.VersionTLS12, InsecureSkipVerify: opts.InsecureSkipVerify}
+	if opts.CACert != "" {
+		// #nosec G304 - CA path comes from the provider options
+		pem, err := os.ReadFile(opts.CACert)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ca_cert: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("ca_cert %s contains no PEM certificates", opts.CACert)
+		}
+		transport.TLSClientConfig.RootCAs = pool
+	}
+	if opts.ClientCert != "" {
+		cert, err := tls.LoadX509KeyPair(opts.ClientCert, opts.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		transport.TLSClientConfig.Certificates = []tls.Certificate{cert}
+	}
+	proxy, err := proxyFunc(opts.Proxy, noProxy)
+	if err != nil {
+		return nil, err
+	}
+	transport.Proxy = proxy
+	return &http.Client{Transport: transport}, nil
+}
+
+// ClientFor returns a client for the requests feller sends to a provider itself, honoring
+// its ca_cert, client_cert, client_key, insecure_skip_verify, proxy and no_proxy options
+func ClientFor(provider config.Provider) (*http.Client, error) {
+	var opts Options
+	if !provider.Options.IsZero() {
+		if err := provider.Options.Decode(&opts); err != nil {
+			return nil, fmt.Errorf("invalid options: %w", err)
+		}
+	}
+	if err := checkTLS(opts); err != nil {
+		return nil, err
+	}
+	if opts.CACert == "" && opts.ClientCert == "" && !opts.InsecureSkipVerify && opts.Proxy == "" && len(opts.NoProxy) == 0 {
+		return http.DefaultClient, nil
+	}
+	return httpClient(&opts, opts.NoProxy)
+}
@@ -0,0 +1,172 @@
+package credentials
+
+import (
+	"encoding/pem"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckTLS(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name        string
+		opts        Options
+		errContains string
+	}{
+		{name: "ca only", opts: Options{CACert: "ca.pem"}},
+		{name: "client pair", opts: Options{ClientCert: "c.pem", ClientKey: "k.pem"}},
+		{name: "socks proxy", opts: Options{Proxy: "socks5://proxy:1080"}},
+		{name: "cert without key", opts: Options{ClientCert: "c.pem"}, errContains: "client_cert and client_key must be set together"},
+		{name: "proxy without scheme", opts: Options{Proxy: "proxy:3128"}, errContains: `invalid proxy "proxy:3128"`},
+		{name: "unsupported scheme", opts: Options{Proxy: "ftp://proxy:21"}, errContains: `unsupported proxy scheme "ftp"`},
+		{name: "proxy without host", opts: Options{Proxy: "http://"}, errContains: `invalid proxy "http://"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			err := checkTLS(tt.opts)
+			if tt.errContains == "" {
+				if err != nil {
+					t.Errorf("checkTLS() unexpected error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+				t.Errorf("checkTLS() error = %v, expected to contain %q", err, tt.errContains)
+			}
+		})
+	}
+}
+
+func TestPlanForTLS(t *testing.T) {
+	t.Parallel()
+	plan, err := PlanFor(parseConfig(t, "providers:\n  vault:\n    kind: hashicorp_vault\n    options: {ca_cert: ca.pem, proxy: 'http://proxy:3128'}\n"))
+	if err != nil || plan.TLS["hashicorp_vault"] == nil || plan.TLS["hashicorp_vault"].CACert != "ca.pem" || plan.Proxy != "http://proxy:3128" {
+		t.Errorf("PlanFor() = %+v, %v", plan, err)
+	}
+
+	// Providers of different kinds are reached by different SDKs
+	plan, err = PlanFor(parseConfig(t, "providers:\n  a:\n    kind: hashicorp_vault\n    options: {ca_cert: a.pem}\n  b:\n    kind: aws_ssm\n    options: {ca_cert: b.pem}\n"))
+	if err != nil || plan.TLS["hashicorp_vault"].CACert != "a.pem" || plan.TLS["aws_ssm"].CACert != "b.pem" {
+		t.Errorf("PlanFor() = %+v, %v", plan, err)
+	}
+
+	tests := []struct {
+		config  string
+		wantErr string
+	}{
+		{
+			config:  "providers:\n  a:\n    kind: aws_ssm\n    options: {ca_cert: a.pem}\n  b:\n    kind: aws_ssm\n    options: {ca_cert: b.pem}\n",
+			wantErr: "providers a and b have different TLS options, but teller uses one aws_ssm client per run",
+		},
+		{
+			config:  "providers:\n  a:\n    kind: aws_ssm\n    options: {proxy: 'http://a:1'}\n  b:\n    kind: hashicorp_vault\n    options: {proxy: 'http://b:1'}\n",
+			wantErr: "providers a and b use different proxies, but teller uses one proxy per run",
+		},
+		{
+			config:  "providers:\n  a:\n    kind: aws_ssm\n    options: {client_cert: c.pem, client_key: k.pem}\n",
+			wantErr: "provider a sets client_cert or insecure_skip_verify, but teller only supports them for hashicorp_vault providers",
+		},
+	}
+	for _, tt := range tests {
+		if _, err := PlanFor(parseConfig(t, tt.config)); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("PlanFor() error = %v, expected %q", err, tt.wantErr)
+		}
+	}
+}
+
+func TestPrepareTLS(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	system := filepath.Join(dir, "system.pem")
+	vaultCA := filepath.Join(dir, "vault-ca.pem")
+	awsCA := filepath.Join(dir, "aws-ca.pem")
+	for path, content := range map[string]string{system: "SYSTEM", vaultCA: "VAULT", awsCA: "AWS"} {
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("Failed to write CA: %v", err)
+		}
+	}
+
+	plan := &Plan{
+		TLS: map[string]*Options{
+			"hashicorp_vault": {CACert: vaultCA, InsecureSkipVerify: true},
+			"aws_ssm":         {CACert: awsCA},
+			"doppler":         {CACert: awsCA},
+		},
+		Proxy: "http://proxy:3128",
+	}
+	env, cleanup, err := Prepare(plan, []string{"HTTPS_PROXY=http://old:1", "SSL_CERT_FILE=" + system})
+	if err != nil {
+		t.Fatalf("Prepare() unexpected error = %v", err)
+	}
+	defer cleanup()
+	for key, want := range map[string]string{
+		"VAULT_SKIP_VERIFY": "true", "HTTPS_PROXY": "http://proxy:3128", "http_proxy": "http://proxy:3128",
+		"GRPC_DEFAULT_SSL_ROOTS_FILE_PATH": "", "VAULT_CLIENT_CERT": "",
+	} {
+		if got := lookupEnv(env, key); got != want {
+			t.Errorf("Prepare() %s = %q, want %q", key, got, want)
+		}
+	}
+	// Every bundle keeps the CAs trusted so far
+	for key, want := range map[string]string{"VAULT_CACERT": "SYSTEM\nVAULT\n", "AWS_CA_BUNDLE": "SYSTEM\nAWS\n", "SSL_CERT_FILE": "SYSTEM\nAWS\n"} {
+		data, err := os.ReadFile(lookupEnv(env, key))
+		if err != nil || string(data) != want {
+			t.Errorf("Prepare() %s holds %q, %v, want %q", key, data, err, want)
+		}
+	}
+
+	missing := &Plan{TLS: map[string]*Options{"hashicorp_vault": {ClientCert: filepath.Join(t.TempDir(), "client.pem"), ClientKey: vaultCA}}}
+	if _, _, err := Prepare(missing, nil); err == nil || !strings.Contains(err.Error(), "invalid client_cert") {
+		t.Errorf("Prepare() error = %v, want missing client_cert", err)
+	}
+}
+
+func TestHTTPClientCA(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	// The rejected handshake is expected
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	ca := filepath.Join(t.TempDir(), "ca.pem")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(ca, certPEM, 0o600); err != nil {
+		t.Fatalf("Failed to write CA: %v", err)
+	}
+
+	get := func(opts *Options) error {
+		client, err := httpClient(opts, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			return err
+		}
+		return resp.Body.Close()
+	}
+
+	if err := get(&Options{}); err == nil {
+		t.Error("httpClient() trusted a certificate of an unknown CA")
+	}
+	if err := get(&Options{CACert: ca}); err != nil {
+		t.Errorf("httpClient() with ca_cert error = %v", err)
+	}
+	if err := get(&Options{InsecureSkipVerify: true}); err != nil {
+		t.Errorf("httpClient() with insecure_skip_verify error = %v", err)
+	}
+	if _, err := httpClient(&Options{CACert: filepath.Join(t.TempDir(), "none.pem")}, nil); err == nil {
+		t.Error("httpClient() expected error for a missing ca_cert")
+	}
+}
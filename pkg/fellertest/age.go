@@ -0,0 +1,89 @@
+package fellertest
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/containifyci/feller/pkg/bundle"
+	"github.com/containifyci/feller/pkg/config"
+	"github.com/containifyci/feller/pkg/providers"
+	"gopkg.in/yaml.v3"
+)
+
+// FakeAgeRecipient is a recipient accepted by the fake age tool of FakeAge
+const FakeAgeRecipient = "age1fellertest"
+
+// fakeAge stands in for the age tool. "Encryption" prefixes the input with a header naming
+// the recipients, and decryption requires a non-empty identity file and that header. It
+// only uses shell builtins so it works whatever PATH holds.
+const fakeAge = `#!/bin/sh
+mode=""; identity=""; recipients=""; prev=""
+for arg in "$@"; do
+  case "$prev" in
+    --identity) identity="$arg" ;;
+    --recipient) recipients="$recipients $arg" ;;
+  esac
+  case "$arg" in
+    --encrypt) mode=encrypt ;;
+    --decrypt) mode=decrypt ;;
+  esac
+  prev="$arg"
+done
+if [ "$mode" = encrypt ]; then
+  echo "FAKE-AGE$recipients"
+else
+  [ -s "$identity" ] || { echo "no identity matched any of the recipients" >&2; exit 1; }
+  read -r header
+  case "$header" in FAKE-AGE*) ;; *) echo "invalid header" >&2; exit 1 ;; esac
+fi
+while IFS= read -r line || [ -n "$line" ]; do printf '%s\n' "$line"; done
+`
+
+// FakeAge puts a fake age tool first in PATH for the duration of the test, so bundles can
+// be written and read without real keys. It modifies the environment, so it cannot be used
+// in parallel tests, and is skipped on Windows.
+func FakeAge(tb testing.TB) {
+	tb.Helper()
+	if runtime.GOOS == "windows" {
+		tb.Skip("fellertest: the fake age tool is a shell script")
+	}
+	dir := tb.TempDir()
+	// #nosec G306 - The fake tool must be executable
+	if err := os.WriteFile(filepath.Join(dir, "age"), []byte(fakeAge), 0o700); err != nil {
+		tb.Fatalf("fellertest: failed to write fake age tool: %v", err)
+	}
+	tb.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+// FakeBundle returns a bundle provider in discovery mode backed by a temporary bundle of
+// the given secrets, encrypted with the fake age tool of FakeAge, which it installs
+func FakeBundle(tb testing.TB, secrets map[string]string) config.Provider {
+	tb.Helper()
+	FakeAge(tb)
+
+	data, err := bundle.Encrypt(providers.NewBundle(secrets, nil), []string{FakeAgeRecipient})
+	if err != nil {
+		tb.Fatalf("fellertest: failed to encrypt bundle: %v", err)
+	}
+	dir := tb.TempDir()
+	path := filepath.Join(dir, "secrets.age")
+	identity := filepath.Join(dir, "key.txt")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		tb.Fatalf("fellertest: failed to write bundle: %v", err)
+	}
+	if err := os.WriteFile(identity, []byte("AGE-SECRET-KEY-FELLERTEST\n"), 0o600); err != nil {
+		tb.Fatalf("fellertest: failed to write identity: %v", err)
+	}
+
+	var options yaml.Node
+	if err := options.Encode(map[string]string{"identity": identity}); err != nil {
+		tb.Fatalf("fellertest: failed to encode options: %v", err)
+	}
+	return config.Provider{
+		Kind:    providers.KindBundle,
+		Maps:    []config.PathMap{{ID: FakeMapID, Path: path}},
+		Options: options,
+	}
+}
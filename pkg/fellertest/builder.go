@@ -0,0 +1,73 @@
+package fellertest
+
+import (
+	"github.com/containifyci/feller/pkg/config"
+)
+
+// Builder assembles a config in code. Methods return the builder so calls can be chained.
+type Builder struct {
+	cfg config.TellerConfig
+}
+
+// NewConfig starts an empty config
+func NewConfig() *Builder {
+	return &Builder{}
+}
+
+// Provider adds or replaces the provider called name
+func (b *Builder) Provider(name string, provider config.Provider) *Builder {
+	if b.cfg.Providers == nil {
+		b.cfg.Providers = make(map[string]config.Provider)
+	}
+	b.cfg.Providers[name] = provider
+	return b
+}
+
+// Transform appends steps to the transforms of an output key
+func (b *Builder) Transform(key string, steps ...config.Transform) *Builder {
+	if b.cfg.Transforms == nil {
+		b.cfg.Transforms = make(map[string][]config.Transform)
+	}
+	b.cfg.Transforms[key] = append(b.cfg.Transforms[key], steps...)
+	return b
+}
+
+// Schema declares the value type of an output key
+func (b *Builder) Schema(key, valueType string) *Builder {
+	if b.cfg.Schema == nil {
+		b.cfg.Schema = make(map[string]string)
+	}
+	b.cfg.Schema[key] = valueType
+	return b
+}
+
+// Hooks sets the commands run before and after the child process of feller run
+func (b *Builder) Hooks(preRun, postRun []string) *Builder {
+	b.cfg.Hooks = config.Hooks{PreRun: preRun, PostRun: postRun}
+	return b
+}
+
+// Build returns the assembled config. Later changes to the builder do not affect it.
+func (b *Builder) Build() *config.TellerConfig {
+	cfg := b.cfg
+	cfg.Providers = cloneMap(b.cfg.Providers)
+	cfg.Schema = cloneMap(b.cfg.Schema)
+	if b.cfg.Transforms != nil {
+		cfg.Transforms = make(map[string][]config.Transform, len(b.cfg.Transforms))
+		for key, steps := range b.cfg.Transforms {
+			cfg.Transforms[key] = append([]config.Transform(nil), steps...)
+		}
+	}
+	return &cfg
+}
+
+func cloneMap[V any](m map[string]V) map[string]V {
+	if m == nil {
+		return nil
+	}
+	clone := make(map[string]V, len(m))
+	for k, v := range m {
+		clone[k] = v
+	}
+	return clone
+}
@@ -0,0 +1,49 @@
+// Package fellertest provides helpers for testing code built on feller's library API:
+// in-memory configs, fake providers that need no credentials, and golden-file assertions.
+package fellertest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/containifyci/feller/pkg/config"
+	"github.com/containifyci/feller/pkg/providers"
+	"gopkg.in/yaml.v3"
+)
+
+// Config parses an in-memory .teller.yml, failing the test when it is invalid
+func Config(tb testing.TB, content string) *config.TellerConfig {
+	tb.Helper()
+	var cfg config.TellerConfig
+	if err := yaml.Unmarshal([]byte(content), &cfg); err != nil {
+		tb.Fatalf("fellertest: invalid config: %v", err)
+	}
+	return &cfg
+}
+
+// WriteConfig writes cfg as .teller.yml into a temporary directory and returns its path,
+// for code that takes a config file path such as config.LoadConfig or the --config flag
+func WriteConfig(tb testing.TB, cfg *config.TellerConfig) string {
+	tb.Helper()
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		tb.Fatalf("fellertest: failed to encode config: %v", err)
+	}
+	path := filepath.Join(tb.TempDir(), ".teller.yml")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		tb.Fatalf("fellertest: failed to write config: %v", err)
+	}
+	return path
+}
+
+// Collect collects the secrets of cfg without printing missing variable warnings, failing
+// the test when collection fails
+func Collect(tb testing.TB, cfg *config.TellerConfig) *providers.CollectionResult {
+	tb.Helper()
+	result, err := providers.CollectSecretsWithResult(cfg, true)
+	if err != nil {
+		tb.Fatalf("fellertest: failed to collect secrets: %v", err)
+	}
+	return result
+}
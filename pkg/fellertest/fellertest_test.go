@@ -0,0 +1,102 @@
+package fellertest
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/containifyci/feller/pkg/config"
+	"github.com/containifyci/feller/pkg/providers"
+)
+
+func TestConfig(t *testing.T) {
+	t.Parallel()
+	cfg := Config(t, `providers:
+  local:
+    kind: dotenv
+    maps:
+      - id: dev
+        path: .env
+schema:
+  PORT: int
+`)
+	if cfg.Providers["local"].Kind != providers.KindDotenv || cfg.Providers["local"].Maps[0].Path != ".env" {
+		t.Errorf("Config() providers = %+v", cfg.Providers)
+	}
+	if cfg.Schema["PORT"] != "int" {
+		t.Errorf("Config() schema = %v", cfg.Schema)
+	}
+}
+
+func TestWriteConfig(t *testing.T) {
+	t.Parallel()
+	cfg := NewConfig().
+		Provider("local", config.Provider{Kind: providers.KindDotenv, Maps: []config.PathMap{{ID: "dev", Path: ".env"}}}).
+		Transform("TOKEN", config.Transform{Type: "trim"}, config.Transform{Type: "json_extract", Path: "a.b"}).
+		Schema("PORT", "int").
+		Hooks([]string{"./pre.sh"}, nil).
+		Build()
+
+	loaded, err := config.LoadConfig(WriteConfig(t, cfg))
+	if err != nil {
+		t.Fatalf("LoadConfig() unexpected error = %v", err)
+	}
+	if !reflect.DeepEqual(loaded.Providers["local"].Maps, cfg.Providers["local"].Maps) {
+		t.Errorf("LoadConfig() maps = %+v, want %+v", loaded.Providers["local"].Maps, cfg.Providers["local"].Maps)
+	}
+	if !reflect.DeepEqual(loaded.Schema, cfg.Schema) || !reflect.DeepEqual(loaded.Hooks, cfg.Hooks) {
+		t.Errorf("LoadConfig() = %+v, want %+v", loaded, cfg)
+	}
+}
+
+func TestBuilderBuildIsACopy(t *testing.T) {
+	t.Parallel()
+	builder := NewConfig().Schema("A", "int").Transform("A", config.Transform{Type: "trim"})
+	first := builder.Build()
+	builder.Schema("B", "bool").Transform("A", config.Transform{Type: "upper"})
+
+	if len(first.Schema) != 1 || len(first.Transforms["A"]) != 1 {
+		t.Errorf("Build() result changed after further building: %+v", first)
+	}
+	if second := builder.Build(); len(second.Schema) != 2 || len(second.Transforms["A"]) != 2 {
+		t.Errorf("Build() = %+v, want two schema entries and two steps", second)
+	}
+	if empty := NewConfig().Build(); empty.Providers != nil || empty.Schema != nil || empty.Transforms != nil {
+		t.Errorf("Build() of empty builder = %+v, want nil maps", empty)
+	}
+}
+
+//nolint:paralleltest // FakeGSM sets environment variables
+func TestFakeProviders(t *testing.T) {
+	cfg := NewConfig().
+		Provider("gsm", FakeGSM(t, map[string]string{"API_TOKEN": "  gsm-token  "})).
+		Provider("local", FakeDotenv(t, map[string]string{"PORT": "8080", "QUOTED": `say "hi" \n`})).
+		Provider("gone", MissingGSM(t, "NOT_SET")).
+		Transform("API_TOKEN", config.Transform{Type: "trim"}).
+		Schema("PORT", "int").
+		Build()
+
+	result := Collect(t, cfg)
+	expected := providers.SecretMap{"API_TOKEN": "gsm-token", "PORT": "8080", "QUOTED": `say "hi" \n`}
+	if !reflect.DeepEqual(result.Secrets, expected) {
+		t.Errorf("Collect() secrets = %v, want %v", result.Secrets, expected)
+	}
+	if source := result.Sources["PORT"]; source.Provider != "local" || source.MapID != FakeMapID {
+		t.Errorf("Collect() source of PORT = %+v", source)
+	}
+	if len(result.MissingVars) != 1 || result.MissingVars[0].VariableName != "NOT_SET" || result.MissingVars[0].Provider != "gone" {
+		t.Errorf("Collect() missing = %+v, want NOT_SET from gone", result.MissingVars)
+	}
+}
+
+//nolint:paralleltest // installs a fake age tool in PATH
+func TestFakeBundle(t *testing.T) {
+	cfg := NewConfig().Provider("handoff", FakeBundle(t, map[string]string{"API_KEY": "abc", "EMPTY": ""})).Build()
+	result := Collect(t, cfg)
+
+	if !reflect.DeepEqual(result.Secrets, providers.SecretMap{"API_KEY": "abc", "EMPTY": ""}) {
+		t.Errorf("Collect() = %v", result.Secrets)
+	}
+	if source := result.Sources["API_KEY"]; source.Kind != providers.KindBundle || source.MapID != FakeMapID {
+		t.Errorf("Collect() source = %+v, want bundle provider", source)
+	}
+}
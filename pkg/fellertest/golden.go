@@ -0,0 +1,62 @@
+package fellertest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// UpdateGoldenEnv is the environment variable that makes AssertGolden rewrite golden files
+// instead of comparing against them, e.g. UPDATE_GOLDEN=1 go test ./...
+const UpdateGoldenEnv = "UPDATE_GOLDEN"
+
+// AssertGolden compares got with the golden file testdata/<name>.golden relative to the
+// package under test. When UPDATE_GOLDEN is set the file is (re)written instead.
+func AssertGolden(tb testing.TB, name string, got []byte) {
+	tb.Helper()
+	path := filepath.Join("testdata", filepath.FromSlash(name)+".golden")
+
+	if os.Getenv(UpdateGoldenEnv) != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+			tb.Errorf("fellertest: failed to create golden directory: %v", err)
+			return
+		}
+		if err := os.WriteFile(path, got, 0o600); err != nil {
+			tb.Errorf("fellertest: failed to update golden file: %v", err)
+		}
+		return
+	}
+
+	// #nosec G304 - Golden file names come from the test
+	want, err := os.ReadFile(path)
+	if err != nil {
+		tb.Errorf("fellertest: failed to read golden file, run with %s=1 to create it: %v", UpdateGoldenEnv, err)
+		return
+	}
+	if string(got) != string(want) {
+		tb.Errorf("fellertest: output does not match %s (run with %s=1 to update)\n%s\ngot:\n%s\nwant:\n%s",
+			path, UpdateGoldenEnv, firstDifference(string(got), string(want)), got, want)
+	}
+}
+
+// firstDifference describes the first line at which got and want differ
+func firstDifference(got, want string) string {
+	gotLines, wantLines := strings.Split(got, "\n"), strings.Split(want, "\n")
+	for i := 0; i < max(len(gotLines), len(wantLines)); i++ {
+		g, w := lineAt(gotLines, i), lineAt(wantLines, i)
+		if g != w {
+			return fmt.Sprintf("first difference at line %d:\n  got:  %s\n  want: %s", i+1, g, w)
+		}
+	}
+	return "outputs differ"
+}
+
+func lineAt(lines []string, i int) string {
+	if i >= len(lines) {
+		return "(end of output)"
+	}
+	return strconv.Quote(lines[i])
+}
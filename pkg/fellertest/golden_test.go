@@ -0,0 +1,80 @@
+package fellertest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// recorder captures failures reported through testing.TB
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+//nolint:paralleltest // changes the working directory and UPDATE_GOLDEN
+func TestAssertGolden(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	r := &recorder{TB: t}
+	AssertGolden(r, "export/env", []byte("A=1\n"))
+	if len(r.errors) != 1 || !strings.Contains(r.errors[0], "run with UPDATE_GOLDEN=1 to create it") {
+		t.Errorf("AssertGolden() without golden file errors = %v", r.errors)
+	}
+
+	t.Setenv(UpdateGoldenEnv, "1")
+	r = &recorder{TB: t}
+	AssertGolden(r, "export/env", []byte("A=1\nB=2\n"))
+	if len(r.errors) != 0 {
+		t.Errorf("AssertGolden() in update mode errors = %v", r.errors)
+	}
+	data, err := os.ReadFile(filepath.Join("testdata", "export", "env.golden"))
+	if err != nil || string(data) != "A=1\nB=2\n" {
+		t.Errorf("golden file = %q, %v", data, err)
+	}
+
+	t.Setenv(UpdateGoldenEnv, "")
+	r = &recorder{TB: t}
+	AssertGolden(r, "export/env", []byte("A=1\nB=2\n"))
+	if len(r.errors) != 0 {
+		t.Errorf("AssertGolden() of matching output errors = %v", r.errors)
+	}
+
+	r = &recorder{TB: t}
+	AssertGolden(r, "export/env", []byte("A=1\nB=3\n"))
+	if len(r.errors) != 1 || !strings.Contains(r.errors[0], `first difference at line 2:`) {
+		t.Errorf("AssertGolden() of different output errors = %v", r.errors)
+	}
+}
+
+func TestFirstDifference(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		got      string
+		want     string
+		expected string
+	}{
+		{name: "changed line", got: "a\nb\n", want: "a\nc\n", expected: "first difference at line 2:\n  got:  \"b\"\n  want: \"c\""},
+		{name: "extra line", got: "a\nb", want: "a", expected: "first difference at line 2:\n  got:  \"b\"\n  want: (end of output)"},
+		{name: "missing line", got: "a", want: "a\nb", expected: "first difference at line 2:\n  got:  (end of output)\n  want: \"b\""},
+		{name: "trailing whitespace", got: "a ", want: "a", expected: "first difference at line 1:\n  got:  \"a \"\n  want: \"a\""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if result := firstDifference(tt.got, tt.want); result != tt.expected {
+				t.Errorf("firstDifference() = %q, want %q", result, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package fellertest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/containifyci/feller/pkg/config"
+	"github.com/containifyci/feller/pkg/providers"
+)
+
+// FakeMapID is the path map id of providers created by FakeGSM and FakeDotenv
+const FakeMapID = "fake"
+
+// FakeGSM returns a Google Secret Manager provider that resolves secrets to the given
+// values. Like feller in GitHub Actions it reads them from environment variables, which are
+// set for the duration of the test, so it cannot be used in parallel tests.
+func FakeGSM(tb testing.TB, secrets map[string]string) config.Provider {
+	tb.Helper()
+	keys := make(map[string]string, len(secrets))
+	for key, value := range secrets {
+		tb.Setenv(key, value)
+		keys[key] = key
+	}
+	return config.Provider{
+		Kind: providers.KindGoogleSecretManager,
+		Maps: []config.PathMap{{ID: FakeMapID, Keys: keys}},
+	}
+}
+
+// MissingGSM returns a Google Secret Manager provider whose keys all resolve to missing
+// variables, for testing how code reports them
+func MissingGSM(tb testing.TB, keys ...string) config.Provider {
+	tb.Helper()
+	mapping := make(map[string]string, len(keys))
+	for _, key := range keys {
+		tb.Setenv(key, "")
+		mapping[key] = key
+	}
+	return config.Provider{
+		Kind: providers.KindGoogleSecretManager,
+		Maps: []config.PathMap{{ID: FakeMapID, Keys: mapping}},
+	}
+}
+
+// FakeDotenv returns a dotenv provider in discovery mode backed by a temporary .env file
+// holding the given secrets. Values may contain anything but line breaks.
+func FakeDotenv(tb testing.TB, secrets map[string]string) config.Provider {
+	tb.Helper()
+	keys := make([]string, 0, len(secrets))
+	for key := range secrets {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var content []byte
+	for _, key := range keys {
+		value := secrets[key]
+		if strings.ContainsAny(value, "\r\n") {
+			tb.Fatalf("fellertest: dotenv value of %s contains a line break", key)
+		}
+		// The dotenv parser strips one pair of surrounding quotes without unescaping, which
+		// keeps leading and trailing whitespace and inner quotes intact
+		content = fmt.Appendf(content, "%s=\"%s\"\n", key, value)
+	}
+
+	path := filepath.Join(tb.TempDir(), ".env")
+	if err := os.WriteFile(path, content, 0o600); err != nil {
+		tb.Fatalf("fellertest: failed to write dotenv file: %v", err)
+	}
+	return config.Provider{
+		Kind: providers.KindDotenv,
+		Maps: []config.PathMap{{ID: FakeMapID, Path: path}},
+	}
+}
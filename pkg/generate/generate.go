@@ -0,0 +1,135 @@
+// Package generate creates cryptographically secure secret values: passwords, random bytes
+// in hex or base64, UUIDs and SSH key pairs. Every value comes from crypto/rand.
+package generate
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// Character sets of generated passwords
+const (
+	Alphanumeric = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+	Symbols      = "!#$%&()*+,-./:;<=>?@[]^_{|}~"
+)
+
+// Password returns length characters drawn uniformly from charset
+func Password(length int, charset string) (string, error) {
+	if length < 1 {
+		return "", fmt.Errorf("length must be at least 1, got %d", length)
+	}
+	if charset == "" {
+		return "", errors.New("character set must not be empty")
+	}
+	limit := big.NewInt(int64(len(charset)))
+	password := make([]byte, length)
+	for i := range password {
+		n, err := rand.Int(rand.Reader, limit)
+		if err != nil {
+			return "", fmt.Errorf("failed to read random data: %w", err)
+		}
+		password[i] = charset[n.Int64()]
+	}
+	return string(password), nil
+}
+
+// Bytes returns n random bytes
+func Bytes(n int) ([]byte, error) {
+	if n < 1 {
+		return nil, fmt.Errorf("length must be at least 1, got %d", n)
+	}
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("failed to read random data: %w", err)
+	}
+	return b, nil
+}
+
+// Hex returns n random bytes hex encoded
+func Hex(n int) (string, error) {
+	b, err := Bytes(n)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Base64 returns n random bytes base64 encoded, with the URL-safe alphabet and no padding
+// when urlSafe is set
+func Base64(n int, urlSafe bool) (string, error) {
+	b, err := Bytes(n)
+	if err != nil {
+		return "", err
+	}
+	if urlSafe {
+		return base64.RawURLEncoding.EncodeToString(b), nil
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+// UUID returns a random (version 4) UUID
+func UUID() (string, error) {
+	b, err := Bytes(16)
+	if err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40 // Version 4
+	b[8] = b[8]&0x3f | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
+
+// SSHKeyPair returns a new Ed25519 key pair: the unencrypted private key in the OpenSSH
+// format and the public key as an authorized_keys line
+func SSHKeyPair(comment string) (private, public []byte, err error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+	check, err := Bytes(4)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pubBlob := sshString(nil, []byte("ssh-ed25519"))
+	pubBlob = sshString(pubBlob, pub)
+
+	// The private section repeats a random check value, holds the key and comment, and is
+	// padded to the cipher block size, 8 for the "none" cipher
+	section := append(append([]byte{}, check...), check...)
+	section = sshString(section, []byte("ssh-ed25519"))
+	section = sshString(section, pub)
+	section = sshString(section, priv)
+	section = sshString(section, []byte(comment))
+	for i := byte(1); len(section)%8 != 0; i++ {
+		section = append(section, i)
+	}
+
+	key := append([]byte("openssh-key-v1"), 0)
+	key = sshString(key, []byte("none")) // Cipher
+	key = sshString(key, []byte("none")) // KDF
+	key = sshString(key, nil)            // KDF options
+	key = binary.BigEndian.AppendUint32(key, 1)
+	key = sshString(key, pubBlob)
+	key = sshString(key, section)
+
+	private = pem.EncodeToMemory(&pem.Block{Type: "OPENSSH PRIVATE KEY", Bytes: key})
+	public = []byte("ssh-ed25519 " + base64.StdEncoding.EncodeToString(pubBlob))
+	if comment != "" {
+		public = append(public, ' ')
+		public = append(public, comment...)
+	}
+	return private, append(public, '\n'), nil
+}
+
+// sshString appends s to b as an SSH wire format string: its length, then its bytes
+func sshString(b, s []byte) []byte {
+	b = binary.BigEndian.AppendUint32(b, uint32(len(s))) // #nosec G115 - Keys and comments are far below 4 GiB
+	return append(b, s...)
+}
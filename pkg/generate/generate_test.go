@@ -0,0 +1,127 @@
+package generate
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/pem"
+	"strings"
+	"testing"
+)
+
+func TestPassword(t *testing.T) {
+	t.Parallel()
+	password, err := Password(2000, "ab")
+	if err != nil {
+		t.Fatalf("Password() error = %v", err)
+	}
+	if len(password) != 2000 || strings.Trim(password, "ab") != "" {
+		t.Fatalf("Password() = %q, want 2000 characters of a and b", password)
+	}
+	// Both characters must occur; with a uniform source missing one is vanishingly unlikely
+	if !strings.Contains(password, "a") || !strings.Contains(password, "b") {
+		t.Errorf("Password() did not use the whole character set")
+	}
+
+	if _, err := Password(0, Alphanumeric); err == nil {
+		t.Errorf("Password(0) should fail")
+	}
+	if _, err := Password(8, ""); err == nil {
+		t.Errorf("Password() with an empty character set should fail")
+	}
+}
+
+func TestEncodings(t *testing.T) {
+	t.Parallel()
+	hex, err := Hex(16)
+	if err != nil || len(hex) != 32 {
+		t.Errorf("Hex(16) = %q, %v, want 32 hex digits", hex, err)
+	}
+	std, err := Base64(32, false)
+	if err != nil || len(std) != 44 || !strings.HasSuffix(std, "=") {
+		t.Errorf("Base64(32) = %q, %v, want 44 padded characters", std, err)
+	}
+	url, err := Base64(32, true)
+	if err != nil || len(url) != 43 || strings.ContainsAny(url, "+/=") {
+		t.Errorf("Base64(32, url) = %q, %v, want 43 URL-safe characters", url, err)
+	}
+	if _, err := Bytes(-1); err == nil {
+		t.Errorf("Bytes(-1) should fail")
+	}
+
+	first, _ := UUID()
+	second, _ := UUID()
+	if first == second || len(first) != 36 || first[14] != '4' || !strings.ContainsRune("89ab", rune(first[19])) {
+		t.Errorf("UUID() = %s, %s, want distinct version 4 UUIDs", first, second)
+	}
+}
+
+func TestSSHKeyPair(t *testing.T) {
+	t.Parallel()
+	private, public, err := SSHKeyPair("deploy@ci")
+	if err != nil {
+		t.Fatalf("SSHKeyPair() error = %v", err)
+	}
+
+	fields := strings.Fields(string(public))
+	if len(fields) != 3 || fields[0] != "ssh-ed25519" || fields[2] != "deploy@ci" {
+		t.Fatalf("public key = %q", public)
+	}
+	pubBlob, err := base64.StdEncoding.DecodeString(fields[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	block, _ := pem.Decode(private)
+	if block == nil || block.Type != "OPENSSH PRIVATE KEY" {
+		t.Fatalf("private key is not an OpenSSH PEM block: %s", private)
+	}
+	r := bytes.NewReader(block.Bytes)
+	magic := make([]byte, len("openssh-key-v1")+1)
+	_, _ = r.Read(magic)
+	if string(magic) != "openssh-key-v1\x00" {
+		t.Fatalf("magic = %q", magic)
+	}
+	for _, want := range []string{"none", "none", ""} {
+		if got := readString(t, r); string(got) != want {
+			t.Fatalf("header field = %q, want %q", got, want)
+		}
+	}
+	var count uint32
+	_ = binary.Read(r, binary.BigEndian, &count)
+	if count != 1 || !bytes.Equal(readString(t, r), pubBlob) {
+		t.Fatalf("private key does not embed the public key")
+	}
+
+	section := bytes.NewReader(readString(t, r))
+	var check1, check2 uint32
+	_ = binary.Read(section, binary.BigEndian, &check1)
+	_ = binary.Read(section, binary.BigEndian, &check2)
+	if check1 != check2 || section.Size()%8 != 0 {
+		t.Fatalf("private section checks %d and %d, size %d", check1, check2, section.Size())
+	}
+	readString(t, section)
+	pub := ed25519.PublicKey(readString(t, section))
+	priv := ed25519.PrivateKey(readString(t, section))
+	if comment := readString(t, section); string(comment) != "deploy@ci" {
+		t.Errorf("comment = %q", comment)
+	}
+	if !ed25519.Verify(pub, []byte("message"), ed25519.Sign(priv, []byte("message"))) {
+		t.Errorf("private and public key do not match")
+	}
+}
+
+// readString reads an SSH wire format string
+func readString(t *testing.T, r *bytes.Reader) []byte {
+	t.Helper()
+	var n uint32
+	if err := binary.Read(r, binary.BigEndian, &n); err != nil {
+		t.Fatalf("failed to read string length: %v", err)
+	}
+	s := make([]byte, n)
+	if _, err := r.Read(s); err != nil && n > 0 {
+		t.Fatalf("failed to read string: %v", err)
+	}
+	return s
+}
@@ -0,0 +1,51 @@
+package i18n
+
+// german translates feller's messages into German
+var german = map[string]string{
+	// Missing environment variables
+	"Missing %d required environment variable(s) in %s:": "%d erforderliche Umgebungsvariable(n) fehlen in %s:",
+	"maps to: %s": "wird zu: %s",
+	"To fix this, add them as masked variables in the CI/CD settings of the project or group:":   "Legen Sie sie zur Behebung als maskierte Variablen in den CI/CD-Einstellungen des Projekts oder der Gruppe an:",
+	"To fix this, add them to the project's environment variables or to a context the job uses:": "Fügen Sie sie zur Behebung den Umgebungsvariablen des Projekts oder einem Kontext des Jobs hinzu:",
+	"To fix this, export them from an agent environment hook or a secrets plugin of the step:":   "Exportieren Sie sie zur Behebung aus einem Environment-Hook des Agents oder einem Secrets-Plugin des Steps:",
+	"To fix this, bind them from Jenkins credentials in your pipeline:":                          "Binden Sie sie zur Behebung in Ihrer Pipeline aus Jenkins-Credentials ein:",
+	"To fix this, add the missing environment variables to your GitHub Actions workflow:":        "Fügen Sie zur Behebung die fehlenden Umgebungsvariablen Ihrem GitHub-Actions-Workflow hinzu:",
+
+	// Commands reporting missing environment variables
+	"Cannot start the container: ":           "Der Container kann nicht gestartet werden: ",
+	"Cannot inspect the process: ":           "Der Prozess kann nicht untersucht werden: ",
+	"Cannot write secrets for the pod: ":     "Die Secrets für den Pod können nicht geschrieben werden: ",
+	"Cannot lock secrets: ":                  "Die Secrets können nicht festgeschrieben werden: ",
+	"Cannot export: ":                        "Export nicht möglich: ",
+	"Cannot generate shell exports: ":        "Die Shell-Exports können nicht erzeugt werden: ",
+	"Cannot snapshot secrets: ":              "Der Snapshot der Secrets ist nicht möglich: ",
+	"Cannot reconcile targets: ":             "Die Ziele können nicht abgeglichen werden: ",
+	"Cannot copy secrets: ":                  "Die Secrets können nicht kopiert werden: ",
+	"Cannot expose secrets to later steps: ": "Die Secrets können nicht für spätere Steps bereitgestellt werden: ",
+	"Cannot substitute secrets: ":            "Die Secrets können nicht eingesetzt werden: ",
+	"Run with secrets":                       "Mit Secrets ausführen",
+	"Inspect process environment":            "Prozessumgebung untersuchen",
+	"Write secrets":                          "Secrets schreiben",
+	"Lock secrets":                           "Secrets festschreiben",
+	"Export with secrets":                    "Mit Secrets exportieren",
+	"Set shell variables":                    "Shell-Variablen setzen",
+	"Reconcile targets":                      "Ziele abgleichen",
+	"Snapshot secrets":                       "Snapshot der Secrets erstellen",
+	"Copy secrets":                           "Secrets kopieren",
+	"Expose secrets":                         "Secrets bereitstellen",
+	"Render with secrets":                    "Mit Secrets rendern",
+	"In a container, set them in its environment, e.g. from a Kubernetes Secret with envFrom, or use --silent to start with the available secrets only.":       "Setzen Sie sie in einem Container in dessen Umgebung, z. B. aus einem Kubernetes-Secret mit envFrom, oder starten Sie mit --silent nur mit den verfügbaren Secrets.",
+	"Resolve every secret to compare all of them, or use --silent to compare the available secrets only.":                                                      "Lösen Sie alle Secrets auf, um alle zu vergleichen, oder vergleichen Sie mit --silent nur die verfügbaren Secrets.",
+	"In a pod, set them in the init container's environment, e.g. from a Kubernetes Secret with envFrom, or use --silent to write the available secrets only.": "Setzen Sie sie in einem Pod in der Umgebung des Init-Containers, z. B. aus einem Kubernetes-Secret mit envFrom, oder schreiben Sie mit --silent nur die verfügbaren Secrets.",
+	"Resolve every secret before locking, or use --silent to lock the available secrets only.":                                                                 "Lösen Sie vor dem Festschreiben alle Secrets auf, oder schreiben Sie mit --silent nur die verfügbaren Secrets fest.",
+	"Or use --silent flag to suppress this error and continue with available secrets only.":                                                                    "Oder unterdrücken Sie diesen Fehler mit --silent und fahren Sie nur mit den verfügbaren Secrets fort.",
+	"Or use --silent flag to export only available secrets.":                                                                                                   "Oder exportieren Sie mit --silent nur die verfügbaren Secrets.",
+	"Resolve every secret before reconciling, or use --silent to reconcile the available secrets only.":                                                        "Lösen Sie vor dem Abgleich alle Secrets auf, oder gleichen Sie mit --silent nur die verfügbaren Secrets ab.",
+	"Resolve every secret before taking a snapshot, or use --silent to capture the available secrets only.":                                                    "Lösen Sie vor dem Snapshot alle Secrets auf, oder erfassen Sie mit --silent nur die verfügbaren Secrets.",
+	"Resolve every secret of the source provider before copying, or use --silent or --keys to copy the available secrets only.":                                "Lösen Sie vor dem Kopieren alle Secrets des Quell-Providers auf, oder kopieren Sie mit --silent oder --keys nur die verfügbaren Secrets.",
+	"Or use --silent flag to expose only available secrets.":                                                                                                   "Oder stellen Sie mit --silent nur die verfügbaren Secrets bereit.",
+	"Or use --silent flag to substitute only available secrets.":                                                                                               "Oder setzen Sie mit --silent nur die verfügbaren Secrets ein.",
+
+	// Summaries
+	"feller: resolved %d key(s), key checksum %s": "feller: %d Schlüssel aufgelöst, Schlüssel-Prüfsumme %s",
+}
@@ -0,0 +1,52 @@
+package i18n
+
+// english lists feller's messages in the default language. Every catalog translates exactly
+// these messages, which TestCatalogs checks.
+var english = []string{
+	// Missing environment variables
+	"Missing %d required environment variable(s) in %s:",
+	"maps to: %s",
+	"To fix this, add them as masked variables in the CI/CD settings of the project or group:",
+	"To fix this, add them to the project's environment variables or to a context the job uses:",
+	"To fix this, export them from an agent environment hook or a secrets plugin of the step:",
+	"To fix this, bind them from Jenkins credentials in your pipeline:",
+	"To fix this, add the missing environment variables to your GitHub Actions workflow:",
+
+	// Commands reporting missing environment variables
+	"Cannot start the container: ",
+	"Cannot inspect the process: ",
+	"Cannot write secrets for the pod: ",
+	"Cannot lock secrets: ",
+	"Cannot export: ",
+	"Cannot generate shell exports: ",
+	"Cannot snapshot secrets: ",
+	"Cannot reconcile targets: ",
+	"Cannot copy secrets: ",
+	"Cannot expose secrets to later steps: ",
+	"Cannot substitute secrets: ",
+	"Run with secrets",
+	"Inspect process environment",
+	"Write secrets",
+	"Lock secrets",
+	"Export with secrets",
+	"Set shell variables",
+	"Reconcile targets",
+	"Snapshot secrets",
+	"Copy secrets",
+	"Expose secrets",
+	"Render with secrets",
+	"In a container, set them in its environment, e.g. from a Kubernetes Secret with envFrom, or use --silent to start with the available secrets only.",
+	"Resolve every secret to compare all of them, or use --silent to compare the available secrets only.",
+	"In a pod, set them in the init container's environment, e.g. from a Kubernetes Secret with envFrom, or use --silent to write the available secrets only.",
+	"Resolve every secret before locking, or use --silent to lock the available secrets only.",
+	"Or use --silent flag to suppress this error and continue with available secrets only.",
+	"Or use --silent flag to export only available secrets.",
+	"Resolve every secret before reconciling, or use --silent to reconcile the available secrets only.",
+	"Resolve every secret before taking a snapshot, or use --silent to capture the available secrets only.",
+	"Resolve every secret of the source provider before copying, or use --silent or --keys to copy the available secrets only.",
+	"Or use --silent flag to expose only available secrets.",
+	"Or use --silent flag to substitute only available secrets.",
+
+	// Summaries
+	"feller: resolved %d key(s), key checksum %s",
+}
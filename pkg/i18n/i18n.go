@@ -0,0 +1,112 @@
+// Package i18n translates user-facing messages. Messages are written in English in the
+// code and looked up by their English text in the catalog of the selected locale, so a
+// missing translation falls back to English.
+package i18n
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// LangEnv selects the locale of feller's messages, taking precedence over LC_ALL,
+// LC_MESSAGES and LANG
+const LangEnv = "FELLER_LANG"
+
+// Supported locales
+const (
+	English = "en"
+	German  = "de"
+)
+
+// catalogs maps locales to translations of English messages. English needs none.
+var catalogs = map[string]map[string]string{
+	German: german,
+}
+
+var current atomic.Value
+
+// Locales returns the supported locales sorted
+func Locales() []string {
+	locales := []string{English}
+	for locale := range catalogs {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// Normalize returns the language of a locale name such as de_DE.UTF-8, or English for
+// the C and POSIX locales
+func Normalize(name string) string {
+	name = strings.ToLower(name)
+	if i := strings.IndexAny(name, ".@"); i >= 0 {
+		name = name[:i]
+	}
+	if i := strings.IndexAny(name, "_-"); i >= 0 {
+		name = name[:i]
+	}
+	if name == "c" || name == "posix" {
+		return English
+	}
+	return name
+}
+
+// Detect returns the locale selected by FELLER_LANG or else by the first of LC_ALL,
+// LC_MESSAGES and LANG that is set. Unsupported system locales select English; an
+// unsupported FELLER_LANG is an error.
+func Detect() (string, error) {
+	if name := os.Getenv(LangEnv); name != "" {
+		locale := Normalize(name)
+		if !supported(locale) {
+			return English, fmt.Errorf("unsupported %s %q (supported: %s)", LangEnv, name, strings.Join(Locales(), ", "))
+		}
+		return locale, nil
+	}
+	for _, env := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if name := os.Getenv(env); name != "" {
+			if locale := Normalize(name); supported(locale) {
+				return locale, nil
+			}
+			return English, nil
+		}
+	}
+	return English, nil
+}
+
+// SetLocale selects the locale of translated messages
+func SetLocale(locale string) error {
+	if !supported(locale) {
+		return fmt.Errorf("unsupported locale %q (supported: %s)", locale, strings.Join(Locales(), ", "))
+	}
+	current.Store(locale)
+	return nil
+}
+
+// Locale returns the selected locale, English unless SetLocale selected another
+func Locale() string {
+	if locale, ok := current.Load().(string); ok {
+		return locale
+	}
+	return English
+}
+
+// T translates the English message msg into the selected locale
+func T(msg string) string {
+	if translated, ok := catalogs[Locale()][msg]; ok {
+		return translated
+	}
+	return msg
+}
+
+// Sprintf formats the translation of the English format
+func Sprintf(format string, args ...any) string {
+	return fmt.Sprintf(T(format), args...)
+}
+
+func supported(locale string) bool {
+	_, ok := catalogs[locale]
+	return ok || locale == English
+}
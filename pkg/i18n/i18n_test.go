@@ -0,0 +1,116 @@
+package i18n
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	t.Parallel()
+	tests := map[string]string{
+		"de_DE.UTF-8":    German,
+		"de-AT":          German,
+		"DE":             German,
+		"en_US.UTF-8":    English,
+		"C":              English,
+		"POSIX":          English,
+		"C.UTF-8":        English,
+		"fr_FR@euro":     "fr",
+		"sr_RS@latin":    "sr",
+		"pt_BR.ISO-8859": "pt",
+	}
+	for name, expected := range tests {
+		if got := Normalize(name); got != expected {
+			t.Errorf("Normalize(%q) = %q, want %q", name, got, expected)
+		}
+	}
+}
+
+//nolint:paralleltest // Sets environment variables
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      map[string]string
+		expected string
+		wantErr  bool
+	}{
+		{name: "nothing set", expected: English},
+		{name: "LANG", env: map[string]string{"LANG": "de_DE.UTF-8"}, expected: German},
+		{name: "LC_ALL wins", env: map[string]string{"LC_ALL": "C", "LANG": "de_DE.UTF-8"}, expected: English},
+		{name: "LC_MESSAGES", env: map[string]string{"LC_MESSAGES": "de_CH", "LANG": "en_US"}, expected: German},
+		{name: "unsupported system locale", env: map[string]string{"LANG": "fr_FR.UTF-8"}, expected: English},
+		{name: "FELLER_LANG wins", env: map[string]string{LangEnv: "de", "LC_ALL": "en_US"}, expected: German},
+		{name: "unsupported FELLER_LANG", env: map[string]string{LangEnv: "fr"}, expected: English, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, env := range []string{LangEnv, "LC_ALL", "LC_MESSAGES", "LANG"} {
+				t.Setenv(env, tt.env[env])
+			}
+			got, err := Detect()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Detect() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.expected {
+				t.Errorf("Detect() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+//nolint:paralleltest // Changes the selected locale
+func TestTranslate(t *testing.T) {
+	t.Cleanup(func() { _ = SetLocale(English) })
+
+	msg := "Missing %d required environment variable(s) in %s:"
+	if got := Sprintf(msg, 2, "GitHub Actions"); got != "Missing 2 required environment variable(s) in GitHub Actions:" {
+		t.Errorf("Sprintf() in English = %q", got)
+	}
+	if err := SetLocale("fr"); err == nil || !strings.Contains(err.Error(), "supported: de, en") {
+		t.Errorf("SetLocale(fr) error = %v", err)
+	}
+
+	if err := SetLocale(German); err != nil {
+		t.Fatal(err)
+	}
+	if got := Sprintf(msg, 2, "GitHub Actions"); got != "2 erforderliche Umgebungsvariable(n) fehlen in GitHub Actions:" {
+		t.Errorf("Sprintf() in German = %q", got)
+	}
+	if got := T("a message without translation"); got != "a message without translation" {
+		t.Errorf("T() without translation = %q, want the English message", got)
+	}
+}
+
+var verb = regexp.MustCompile(`%[-+# 0-9.]*[a-zA-Z%]`)
+
+func TestCatalogs(t *testing.T) {
+	t.Parallel()
+	messages := make(map[string]bool, len(english))
+	for _, msg := range english {
+		if messages[msg] {
+			t.Errorf("duplicate message %q", msg)
+		}
+		messages[msg] = true
+	}
+	for locale, catalog := range catalogs {
+		for _, msg := range english {
+			if _, ok := catalog[msg]; !ok {
+				t.Errorf("%s: no translation of %q", locale, msg)
+			}
+		}
+		for msg, translated := range catalog {
+			if !messages[msg] {
+				t.Errorf("%s: translation of %q, which is not a message", locale, msg)
+			}
+			if translated == "" {
+				t.Errorf("%s: empty translation of %q", locale, msg)
+			}
+			want, got := verb.FindAllString(msg, -1), verb.FindAllString(translated, -1)
+			if strings.Join(want, " ") != strings.Join(got, " ") {
+				t.Errorf("%s: translation of %q has verbs %v, want %v", locale, msg, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,148 @@
+//go:build integration
+
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/containifyci/feller/pkg/credentials"
+	"github.com/containifyci/feller/pkg/fellertest"
+	"github.com/containifyci/feller/pkg/providers"
+)
+
+//nolint:paralleltest // sets environment variables
+func TestGSMEmulator(t *testing.T) {
+	testGSMWriter(t, Endpoint(t, GSMEndpointEnv))
+}
+
+//nolint:paralleltest // sets environment variables
+func TestVaultDevServer(t *testing.T) {
+	address := Endpoint(t, VaultAddrEnv)
+	if os.Getenv(VaultTokenEnv) == "" {
+		t.Setenv(VaultTokenEnv, "root")
+	}
+	// The address option has to win over the environment
+	t.Setenv("VAULT_ADDR", "http://127.0.0.1:1")
+	t.Setenv("VAULT_NAMESPACE", "")
+
+	path := "secret/data/" + UniqueName(t, "feller-it-")
+	cfg := fellertest.Config(t, `providers:
+  vault:
+    kind: hashicorp_vault
+    options:
+      address: "`+address+`"
+      auth: {method: token, token_env: `+VaultTokenEnv+`}
+    maps:
+      - id: app
+        path: `+path+`
+`)
+	provider := cfg.Providers["vault"]
+	writer, err := providers.NewWriter("vault", provider, provider.Maps[0])
+	if err != nil {
+		t.Fatalf("NewWriter() error = %v", err)
+	}
+	if err := writer.Put(providers.SecretMap{"API_KEY": "abc", "PORT": "8080"}); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	result := fellertest.Collect(t, cfg)
+	if result.Secrets["API_KEY"] != "abc" || result.Secrets["PORT"] != "8080" || result.Sources["API_KEY"].Provider != "vault" {
+		t.Errorf("CollectSecretsWithResult() = %v from %v", result.Secrets, result.Sources)
+	}
+
+	if removed, err := writer.Delete([]string{"PORT"}); err != nil || len(removed) != 1 {
+		t.Fatalf("Delete() = %v, %v, expected PORT removed", removed, err)
+	}
+	if values, err := writer.Read([]string{"API_KEY", "PORT"}); err != nil || len(values) != 1 {
+		t.Errorf("Read() after Delete() = %v, %v, expected only API_KEY", values, err)
+	}
+}
+
+// TestLocalStack resolves an AWS Secrets Manager secret with teller, which feller points at
+// LocalStack through the endpoint option. Feller has no AWS provider of its own.
+//
+//nolint:paralleltest // sets environment variables
+func TestLocalStack(t *testing.T) {
+	endpoint := Endpoint(t, LocalStackEnv)
+	tellerPath, err := exec.LookPath("teller")
+	if err != nil {
+		t.Skip("teller is not in PATH")
+	}
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+	t.Setenv("AWS_REGION", "us-east-1")
+
+	name := UniqueName(t, "feller-it/")
+	createAWSSecret(t, endpoint, name, `{"DB_PASSWORD":"s3cr3t"}`)
+
+	cfg := fellertest.Config(t, `providers:
+  aws:
+    kind: aws_secretsmanager
+    options: {endpoint: "`+endpoint+`"}
+    maps:
+      - id: db
+        path: `+name+`
+`)
+	plan, err := credentials.PlanFor(cfg)
+	if err != nil {
+		t.Fatalf("PlanFor() error = %v", err)
+	}
+	env, cleanup, err := credentials.Prepare(plan, os.Environ())
+	if err != nil {
+		t.Fatalf("Prepare() error = %v", err)
+	}
+	defer cleanup()
+
+	configPath := fellertest.WriteConfig(t, cfg)
+	cmd := exec.Command(tellerPath, "--config", configPath, "env")
+	cmd.Env = env
+	cmd.Dir = filepath.Dir(configPath)
+	output, err := cmd.CombinedOutput()
+	if err != nil || !strings.Contains(string(output), "s3cr3t") {
+		t.Errorf("teller env = %s, %v, expected the secret of LocalStack", output, err)
+	}
+}
+
+// createAWSSecret creates a secret in LocalStack, deleting it when the test ends
+func createAWSSecret(t *testing.T, endpoint, name, value string) {
+	t.Helper()
+	if err := awsCall(endpoint, "secretsmanager.CreateSecret", map[string]any{"Name": name, "SecretString": value}); err != nil {
+		t.Fatalf("Failed to create secret %s: %v", name, err)
+	}
+	t.Cleanup(func() {
+		_ = awsCall(endpoint, "secretsmanager.DeleteSecret", map[string]any{"SecretId": name, "ForceDeleteWithoutRecovery": true})
+	})
+}
+
+// awsCall sends a request to the JSON API of LocalStack, which does not check signatures
+func awsCall(endpoint, target string, body any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	service, _, _ := strings.Cut(target, ".")
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", target)
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential=test/20240101/us-east-1/"+service+"/aws4_request, SignedHeaders=host, Signature=0")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", target, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package integration
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// FakeGSM is an in-memory emulator of the Secret Manager REST API subset feller uses:
+// creating and deleting secrets, adding versions and accessing the latest one. It accepts
+// any bearer token. Google publishes no Secret Manager emulator, so the tests fall back to
+// it when GSMEndpointEnv is not set.
+type FakeGSM struct {
+	mu      sync.Mutex
+	secrets map[string][]byte // projects/P/secrets/NAME -> latest version, nil without versions
+}
+
+// NewFakeGSM returns an emulator without secrets
+func NewFakeGSM() *FakeGSM {
+	return &FakeGSM{secrets: make(map[string][]byte)}
+}
+
+func (f *FakeGSM) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+		gsmError(w, http.StatusUnauthorized, "Request is missing required authentication credential.")
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	path, method, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/v1/"), ":")
+	switch {
+	case r.Method == http.MethodPost && strings.HasSuffix(path, "/secrets") && method == "":
+		name := path + "/" + r.URL.Query().Get("secretId")
+		if _, exists := f.secrets[name]; exists {
+			gsmError(w, http.StatusConflict, "Secret already exists")
+			return
+		}
+		f.secrets[name] = nil
+		writeJSON(w, map[string]string{"name": name})
+	case r.Method == http.MethodPost && method == "addVersion":
+		if _, exists := f.secrets[path]; !exists {
+			gsmError(w, http.StatusNotFound, "Secret not found")
+			return
+		}
+		var version struct {
+			Payload struct {
+				Data []byte `json:"data"`
+			} `json:"payload"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&version); err != nil {
+			gsmError(w, http.StatusBadRequest, "Invalid payload")
+			return
+		}
+		f.secrets[path] = version.Payload.Data
+		writeJSON(w, map[string]string{"name": path + "/versions/latest"})
+	case r.Method == http.MethodGet && method == "access":
+		value, exists := f.secrets[strings.TrimSuffix(path, "/versions/latest")]
+		if !exists || value == nil {
+			gsmError(w, http.StatusNotFound, "Secret version not found")
+			return
+		}
+		writeJSON(w, map[string]any{"payload": map[string][]byte{"data": value}})
+	case r.Method == http.MethodDelete && method == "":
+		if _, exists := f.secrets[path]; !exists {
+			gsmError(w, http.StatusNotFound, "Secret not found")
+			return
+		}
+		delete(f.secrets, path)
+		writeJSON(w, map[string]string{})
+	default:
+		gsmError(w, http.StatusNotImplemented, "FakeGSM does not implement "+r.Method+" "+r.URL.Path)
+	}
+}
+
+// gsmError writes an error in the format of Google APIs
+func gsmError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]any{"error": map[string]any{"code": status, "message": message}})
+}
+
+func writeJSON(w http.ResponseWriter, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(body)
+}
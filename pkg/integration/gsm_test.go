@@ -0,0 +1,54 @@
+package integration
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/containifyci/feller/pkg/fellertest"
+	"github.com/containifyci/feller/pkg/providers"
+)
+
+//nolint:paralleltest // sets environment variables
+func TestFakeGSM(t *testing.T) {
+	server := httptest.NewServer(NewFakeGSM())
+	t.Cleanup(server.Close)
+	testGSMWriter(t, server.URL)
+}
+
+// testGSMWriter writes, reads and deletes secrets through the Secret Manager API at endpoint
+func testGSMWriter(t *testing.T, endpoint string) {
+	t.Helper()
+	t.Setenv("GOOGLE_OAUTH_ACCESS_TOKEN", "emulator")
+	cfg := fellertest.Config(t, `providers:
+  gsm:
+    kind: google_secretmanager
+    options: {endpoint: "`+endpoint+`"}
+    maps:
+      - id: app
+        path: projects/feller-it
+`)
+	provider := cfg.Providers["gsm"]
+	writer, err := providers.NewWriter("gsm", provider, provider.Maps[0])
+	if err != nil {
+		t.Fatalf("NewWriter() error = %v", err)
+	}
+
+	key := UniqueName(t, "FELLER_IT_")
+	if err := writer.Put(providers.SecretMap{key: "first"}); err != nil {
+		t.Fatalf("Put() creating the secret error = %v", err)
+	}
+	if err := writer.Put(providers.SecretMap{key: "second\nline"}); err != nil {
+		t.Fatalf("Put() adding a version error = %v", err)
+	}
+	values, err := writer.Read([]string{key, key + "_MISSING"})
+	if err != nil || len(values) != 1 || values[key] != "second\nline" {
+		t.Errorf("Read() = %v, %v, expected the latest version of %s only", values, err, key)
+	}
+
+	if removed, err := writer.Delete([]string{key, key + "_MISSING"}); err != nil || len(removed) != 1 {
+		t.Fatalf("Delete() = %v, %v, expected only %s removed", removed, err, key)
+	}
+	if values, err := writer.Read([]string{key}); err != nil || len(values) != 0 {
+		t.Errorf("Read() after Delete() = %v, %v", values, err)
+	}
+}
@@ -0,0 +1,45 @@
+// Package integration tests feller against local emulators of the remote secret stores: a
+// Vault dev server, LocalStack for AWS and a Secret Manager emulator. The tests only build
+// with the integration tag and skip stores whose emulator is not configured, so
+//
+//	make test-integration
+//
+// starts the emulators of compose.yml, points the variables below at them and runs the tests.
+package integration
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"testing"
+)
+
+// Variables naming the endpoints of the emulators
+const (
+	VaultAddrEnv   = "FELLER_IT_VAULT_ADDR"          // Vault dev server, e.g. http://127.0.0.1:8200
+	VaultTokenEnv  = "FELLER_IT_VAULT_TOKEN"         // Root token of the dev server, root when empty
+	LocalStackEnv  = "FELLER_IT_LOCALSTACK_ENDPOINT" // LocalStack, e.g. http://127.0.0.1:4566
+	GSMEndpointEnv = "FELLER_IT_GSM_ENDPOINT"        // Secret Manager REST emulator, FakeGSM when empty
+)
+
+// Endpoint returns the endpoint of the emulator named by variable, skipping the test when it
+// is not set
+func Endpoint(tb testing.TB, variable string) string {
+	tb.Helper()
+	endpoint := os.Getenv(variable)
+	if endpoint == "" {
+		tb.Skipf("%s is not set; run make test-integration to start the emulators", variable)
+	}
+	return endpoint
+}
+
+// UniqueName returns prefix followed by a random suffix, so runs against a shared emulator
+// do not see each other's secrets
+func UniqueName(tb testing.TB, prefix string) string {
+	tb.Helper()
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		tb.Fatalf("integration: failed to generate a name: %v", err)
+	}
+	return prefix + hex.EncodeToString(suffix)
+}
@@ -0,0 +1,207 @@
+// Package lockfile records which secrets a config resolved to, so later runs can fail when a
+// secret changed. Values are never stored: each is kept as a salted PBKDF2 fingerprint,
+// which is slow to brute force even for short secrets.
+package lockfile
+
+import (
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/containifyci/feller/pkg/providers"
+)
+
+// FileName is the name of the lockfile, kept next to the config
+const FileName = "feller.lock"
+
+// Version is the format version of lockfiles written by this package
+const Version = 1
+
+// Fingerprint parameters. The salt is per lockfile and the key is mixed in, so equal values
+// of different keys have different fingerprints.
+const (
+	fingerprintPrefix     = "pbkdf2-sha256:"
+	fingerprintIterations = 20000
+	fingerprintLength     = 16
+	saltLength            = 16
+)
+
+// File is the content of a lockfile
+type File struct {
+	Version int              `json:"version"`
+	Locked  time.Time        `json:"locked"`
+	Salt    string           `json:"salt"`
+	Secrets map[string]Entry `json:"secrets"`
+}
+
+// Entry pins one output key to the provider that supplied it and a fingerprint of its value
+type Entry struct {
+	Provider    string `json:"provider"`
+	Kind        string `json:"kind"`
+	Map         string `json:"map,omitempty"`
+	Fingerprint string `json:"fingerprint"`
+}
+
+// PathFor returns the lockfile of the config at configPath
+func PathFor(configPath string) string {
+	return filepath.Join(filepath.Dir(configPath), FileName)
+}
+
+// New records the collected secrets with a fresh salt
+func New(result *providers.CollectionResult) (*File, error) {
+	salt := make([]byte, saltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+	f := &File{
+		Version: Version,
+		Locked:  time.Now().UTC().Truncate(time.Second),
+		Salt:    base64.StdEncoding.EncodeToString(salt),
+		Secrets: make(map[string]Entry, len(result.Secrets)),
+	}
+	for key, value := range result.Secrets {
+		entry, err := f.entry(key, value, result.Sources[key])
+		if err != nil {
+			return nil, err
+		}
+		f.Secrets[key] = entry
+	}
+	return f, nil
+}
+
+// entry returns the entry of a collected secret
+func (f *File) entry(key, value string, source providers.SecretSource) (Entry, error) {
+	salt, err := base64.StdEncoding.DecodeString(f.Salt)
+	if err != nil {
+		return Entry{}, fmt.Errorf("invalid lockfile salt: %w", err)
+	}
+	sum, err := pbkdf2.Key(sha256.New, value, append(salt, key...), fingerprintIterations, fingerprintLength)
+	if err != nil {
+		return Entry{}, fmt.Errorf("failed to fingerprint %s: %w", key, err)
+	}
+	return Entry{
+		Provider:    source.Provider,
+		Kind:        source.Kind,
+		Map:         source.MapID,
+		Fingerprint: fingerprintPrefix + base64.RawStdEncoding.EncodeToString(sum),
+	}, nil
+}
+
+// Change statuses
+const (
+	StatusChanged = "changed" // The value differs
+	StatusMoved   = "moved"   // Another provider or map supplies the key
+	StatusAdded   = "added"   // The key is not in the lockfile
+	StatusRemoved = "removed" // The key is no longer resolved
+)
+
+// Change is a key whose collected secret differs from the lockfile
+type Change struct {
+	Key     string `json:"key"`
+	Status  string `json:"status"`
+	Current *Entry `json:"current,omitempty"` // Nil when removed
+	Locked  *Entry `json:"locked,omitempty"`  // Nil when added
+}
+
+// Changes compares the collected secrets with the lockfile and returns the differing keys
+// sorted by key
+func (f *File) Changes(result *providers.CollectionResult) ([]Change, error) {
+	var changes []Change
+	for key, value := range result.Secrets {
+		current, err := f.entry(key, value, result.Sources[key])
+		if err != nil {
+			return nil, err
+		}
+		locked, ok := f.Secrets[key]
+		switch {
+		case !ok:
+			changes = append(changes, Change{Key: key, Status: StatusAdded, Current: &current})
+		case current.Provider != locked.Provider || current.Map != locked.Map:
+			changes = append(changes, Change{Key: key, Status: StatusMoved, Current: &current, Locked: &locked})
+		case current.Fingerprint != locked.Fingerprint:
+			changes = append(changes, Change{Key: key, Status: StatusChanged, Current: &current, Locked: &locked})
+		}
+	}
+	for key, entry := range f.Secrets {
+		if _, ok := result.Secrets[key]; !ok {
+			changes = append(changes, Change{Key: key, Status: StatusRemoved, Locked: &entry})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key })
+	return changes, nil
+}
+
+// Diff describes how the collected secrets differ from the lockfile, one line per key
+// sorted by key. It is empty when they match.
+func (f *File) Diff(result *providers.CollectionResult) ([]string, error) {
+	changes, err := f.Changes(result)
+	if err != nil {
+		return nil, err
+	}
+	diff := make([]string, len(changes))
+	for i, change := range changes {
+		diff[i] = change.String()
+	}
+	return diff, nil
+}
+
+// String describes the change, e.g. "API_KEY: value changed"
+func (c Change) String() string {
+	switch c.Status {
+	case StatusAdded:
+		return c.Key + ": not in the lockfile"
+	case StatusRemoved:
+		return c.Key + ": no longer resolved"
+	case StatusMoved:
+		return fmt.Sprintf("%s: supplied by %s instead of %s", c.Key, c.Current.Source(), c.Locked.Source())
+	default:
+		return c.Key + ": value changed"
+	}
+}
+
+// Source returns the provider and map that supplied the entry
+func (e Entry) Source() providers.SecretSource {
+	return providers.SecretSource{Provider: e.Provider, Kind: e.Kind, MapID: e.Map}
+}
+
+// Load reads the lockfile at path
+func Load(path string) (*File, error) {
+	// #nosec G304 - Lockfile path is derived from the config path
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read lockfile: %w", err)
+	}
+	var f File
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse lockfile %s: %w", path, err)
+	}
+	if f.Version != Version {
+		return nil, fmt.Errorf("unsupported lockfile version %d (supported: %d)", f.Version, Version)
+	}
+	if f.Salt == "" {
+		return nil, errors.New("lockfile has no salt")
+	}
+	return &f, nil
+}
+
+// Write saves the lockfile at path
+func (f *File) Write(path string) error {
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode lockfile: %w", err)
+	}
+	// The lockfile holds no values and is meant to be committed
+	// #nosec G306 - Fingerprints are salted and slow to reverse
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write lockfile: %w", err)
+	}
+	return nil
+}
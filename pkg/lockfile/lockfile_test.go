@@ -0,0 +1,89 @@
+package lockfile
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/containifyci/feller/pkg/providers"
+)
+
+func collected(secrets providers.SecretMap, provider string) *providers.CollectionResult {
+	sources := make(map[string]providers.SecretSource, len(secrets))
+	for key := range secrets {
+		sources[key] = providers.SecretSource{Provider: provider, Kind: providers.KindDotenv, MapID: "env"}
+	}
+	return &providers.CollectionResult{Secrets: secrets, Sources: sources}
+}
+
+func TestRoundTrip(t *testing.T) {
+	t.Parallel()
+	f, err := New(collected(providers.SecretMap{"API_KEY": "abc", "TOKEN": "abc"}, "local"))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if f.Secrets["API_KEY"].Fingerprint == f.Secrets["TOKEN"].Fingerprint {
+		t.Error("equal values of different keys have equal fingerprints")
+	}
+	if !strings.HasPrefix(f.Secrets["API_KEY"].Fingerprint, fingerprintPrefix) || strings.Contains(f.Secrets["API_KEY"].Fingerprint, "abc") {
+		t.Errorf("fingerprint = %q", f.Secrets["API_KEY"].Fingerprint)
+	}
+
+	path := filepath.Join(t.TempDir(), FileName)
+	if err := f.Write(path); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if !reflect.DeepEqual(loaded, f) {
+		t.Errorf("Load() = %+v, expected %+v", loaded, f)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	t.Parallel()
+	f, err := New(collected(providers.SecretMap{"API_KEY": "abc", "DB_URL": "postgres://db", "OLD": "x"}, "local"))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	diff, err := f.Diff(collected(providers.SecretMap{"API_KEY": "abc", "DB_URL": "postgres://db", "OLD": "x"}, "local"))
+	if err != nil || len(diff) != 0 {
+		t.Errorf("Diff() of unchanged secrets = %v, %v", diff, err)
+	}
+
+	current := collected(providers.SecretMap{"API_KEY": "rotated", "DB_URL": "postgres://db", "NEW": "y"}, "local")
+	current.Sources["DB_URL"] = providers.SecretSource{Provider: "gsm", Kind: providers.KindGoogleSecretManager, MapID: "ci"}
+	diff, err = f.Diff(current)
+	if err != nil {
+		t.Fatalf("Diff() error = %v", err)
+	}
+	expected := []string{
+		"API_KEY: value changed",
+		"DB_URL: supplied by provider 'gsm' (map 'ci') instead of provider 'local' (map 'env')",
+		"NEW: not in the lockfile",
+		"OLD: no longer resolved",
+	}
+	if !reflect.DeepEqual(diff, expected) {
+		t.Errorf("Diff() = %q, expected %q", diff, expected)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	t.Parallel()
+	if _, err := Load(filepath.Join(t.TempDir(), FileName)); err == nil {
+		t.Error("Load() of a missing file succeeded")
+	}
+
+	path := filepath.Join(t.TempDir(), FileName)
+	f := &File{Version: Version + 1, Salt: "c2FsdA==", Secrets: map[string]Entry{}}
+	if err := f.Write(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "unsupported lockfile version") {
+		t.Errorf("Load() error = %v", err)
+	}
+}
@@ -0,0 +1,117 @@
+package lsp
+
+import (
+	"strings"
+)
+
+// cursorContext describes where the cursor is in a block style YAML document. It is derived
+// from indentation rather than a parse tree so it keeps working while the document is invalid.
+type cursorContext struct {
+	Path     []string // Keys of the enclosing mappings, outermost first
+	Key      string   // Key on the cursor line, empty for plain list items
+	Value    string   // Value on the cursor line
+	InValue  bool     // Cursor is after the key's colon (or on a plain list item)
+	ListItem bool     // Cursor line is a list item
+}
+
+// contextAt computes the cursor context for a 0-based line and character
+func contextAt(text string, line, character int) cursorContext {
+	lines := strings.Split(text, "\n")
+	if line < 0 || line >= len(lines) {
+		return cursorContext{}
+	}
+
+	current := strings.TrimRight(lines[line], "\r")
+	ctx := cursorContext{}
+
+	indent := indentOf(current)
+	content := strings.TrimSpace(current)
+	if content == "" {
+		// On a blank line the cursor column decides which section a new key belongs to
+		indent = character
+	}
+
+	offset := indent
+	if item, ok := strings.CutPrefix(content, "-"); ok && (item == "" || item[0] == ' ') {
+		ctx.ListItem = true
+		offset += len(content) - len(strings.TrimSpace(item))
+		content = strings.TrimSpace(item)
+	}
+
+	if key, value, found := strings.Cut(content, ":"); found && !strings.HasPrefix(content, "{") {
+		ctx.Key = strings.TrimSpace(key)
+		ctx.Value = trimValue(value)
+		ctx.InValue = character > offset+len(key)
+	} else {
+		ctx.Value = trimValue(content)
+		ctx.InValue = ctx.ListItem
+	}
+
+	ctx.Path = parentKeys(lines[:line], indent)
+	return ctx
+}
+
+// parentKeys walks upwards from the cursor line collecting the keys of enclosing mappings.
+// List items are transparent: keys inside an item belong to the list's parent key.
+func parentKeys(lines []string, indent int) []string {
+	var path []string
+	for i := len(lines) - 1; i >= 0 && indent > 0; i-- {
+		line := strings.TrimRight(lines[i], "\r")
+		content := strings.TrimSpace(line)
+		if content == "" || strings.HasPrefix(content, "#") {
+			continue
+		}
+
+		lineIndent := indentOf(line)
+		if lineIndent >= indent {
+			continue
+		}
+
+		if item, ok := strings.CutPrefix(content, "- "); ok {
+			itemIndent := lineIndent + 2
+			if indent > itemIndent {
+				// Nested below a key of the item, e.g. "- replace:" followed by "from:"
+				if key, _, found := strings.Cut(strings.TrimSpace(item), ":"); found {
+					path = append(path, strings.TrimSpace(key))
+				}
+			}
+			indent = lineIndent
+			continue
+		}
+
+		if key, _, found := strings.Cut(content, ":"); found {
+			path = append(path, strings.TrimSpace(key))
+		}
+		indent = lineIndent
+	}
+
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
+
+func indentOf(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+// trimValue strips whitespace, quotes and trailing comments from a scalar value
+func trimValue(value string) string {
+	if before, _, found := strings.Cut(value, " #"); found {
+		value = before
+	}
+	return strings.Trim(strings.TrimSpace(value), `"'`)
+}
+
+// matchesPath reports whether path matches pattern, where "*" matches any single key
+func matchesPath(path []string, pattern ...string) bool {
+	if len(path) != len(pattern) {
+		return false
+	}
+	for i := range pattern {
+		if pattern[i] != "*" && pattern[i] != path[i] {
+			return false
+		}
+	}
+	return true
+}
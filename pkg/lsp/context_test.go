@@ -0,0 +1,128 @@
+package lsp
+
+import (
+	"reflect"
+	"testing"
+)
+
+const sampleConfig = `providers:
+  gha:
+    kind: google_secretmanager
+    maps:
+      - id: ci
+        keys:
+          TOKEN: TOKEN
+
+transforms:
+  TOKEN:
+    - trim
+    - replace:
+        from: a
+schema:
+  TOKEN: int
+`
+
+func TestContextAt(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name      string
+		expected  cursorContext
+		line      int
+		character int
+	}{
+		{
+			name:      "top level key",
+			line:      0,
+			character: 2,
+			expected:  cursorContext{Path: nil, Key: "providers", InValue: false},
+		},
+		{
+			name:      "kind value",
+			line:      2,
+			character: 12,
+			expected:  cursorContext{Path: []string{"providers", "gha"}, Key: "kind", Value: "google_secretmanager", InValue: true},
+		},
+		{
+			name:      "list item key",
+			line:      4,
+			character: 9,
+			expected:  cursorContext{Path: []string{"providers", "gha", "maps"}, Key: "id", Value: "ci", ListItem: true},
+		},
+		{
+			name:      "key inside list item",
+			line:      5,
+			character: 9,
+			expected:  cursorContext{Path: []string{"providers", "gha", "maps"}, Key: "keys"},
+		},
+		{
+			name:      "nested mapping below list item",
+			line:      6,
+			character: 11,
+			expected:  cursorContext{Path: []string{"providers", "gha", "maps", "keys"}, Key: "TOKEN", Value: "TOKEN"},
+		},
+		{
+			name:      "blank line uses cursor column",
+			line:      7,
+			character: 4,
+			expected:  cursorContext{Path: []string{"providers", "gha"}},
+		},
+		{
+			name:      "plain list item",
+			line:      10,
+			character: 7,
+			expected:  cursorContext{Path: []string{"transforms", "TOKEN"}, Value: "trim", InValue: true, ListItem: true},
+		},
+		{
+			name:      "key below list item key",
+			line:      12,
+			character: 9,
+			expected:  cursorContext{Path: []string{"transforms", "TOKEN", "replace"}, Key: "from", Value: "a"},
+		},
+		{
+			name:      "schema value",
+			line:      14,
+			character: 10,
+			expected:  cursorContext{Path: []string{"schema"}, Key: "TOKEN", Value: "int", InValue: true},
+		},
+		{
+			name:      "line out of range",
+			line:      100,
+			character: 0,
+			expected:  cursorContext{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			ctx := contextAt(sampleConfig, tt.line, tt.character)
+			if !reflect.DeepEqual(ctx, tt.expected) {
+				t.Errorf("contextAt(%d, %d) = %+v, want %+v", tt.line, tt.character, ctx, tt.expected)
+			}
+		})
+	}
+}
+
+func TestMatchesPath(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		path     []string
+		pattern  []string
+		expected bool
+	}{
+		{name: "exact", path: []string{"schema"}, pattern: []string{"schema"}, expected: true},
+		{name: "wildcard", path: []string{"providers", "gha"}, pattern: []string{"providers", "*"}, expected: true},
+		{name: "length mismatch", path: []string{"providers"}, pattern: []string{"providers", "*"}, expected: false},
+		{name: "different key", path: []string{"hooks"}, pattern: []string{"schema"}, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := matchesPath(tt.path, tt.pattern...); got != tt.expected {
+				t.Errorf("matchesPath(%v, %v) = %v, want %v", tt.path, tt.pattern, got, tt.expected)
+			}
+		})
+	}
+}
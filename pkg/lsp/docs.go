@@ -0,0 +1,121 @@
+package lsp
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/containifyci/feller/pkg/providers"
+)
+
+// Field documentation per config section, also used as completion candidates
+var (
+	rootDocs = map[string]string{
+		"version":    "Config schema version, `1` when absent. Version `2` turns deprecated fields into errors; feller refuses versions newer than it supports.",
+		"providers":  "Named secret providers. Each provider has a `kind` and a list of `maps` describing which keys it supplies.",
+		"hooks":      "Commands run around `feller run`: `pre_run` before the command, `post_run` after it (even on failure).",
+		"transforms": "Per-key post-processing steps applied in order after all providers are collected.",
+		"schema":     "Per-key value types (string, int, bool, url, email, json) validated and normalized after transforms.",
+		"aliases":    "Named feller invocations, e.g. `deploy: run -- ./deploy.sh`, run with `feller deploy`.",
+		"messages":   "Customized guidance printed when secrets cannot be resolved, e.g. `missing_variables`.",
+		"fallback":   "Set to `false` to resolve secrets with feller's own providers outside CI too instead of running teller, like `--no-fallback`.",
+		"targets":    "Named external systems `feller sync` and `feller reconcile` write the secrets to, e.g. the secrets of a GitHub repository, a Cloudflare Worker or a Fly.io app, or the environment variables of a Vercel project.",
+		"backups":    "Encrypted backups of the secrets `feller put` and `feller delete` change, restored with `feller undo`.",
+		"collisions": "How keys supplied by several providers are resolved: `last-wins` (default), `first-wins`, `error`, or `prefix-provider` to keep each value as `PROVIDER_KEY`. `--collision-strategy` overrides it.",
+		"sync":       "Per-key targets: each output key maps target names to the name of the key there, e.g. `API_KEY: {ci: API_KEY, web: NEXT_PUBLIC_API_KEY}`. Listed keys are only written to their targets.",
+	}
+
+	providerDocs = map[string]string{
+		"kind":    "Provider kind, e.g. `google_secretmanager` or `dotenv`. Run `feller providers kinds` for the full list; `plugin/NAME` runs the provider plugin `feller-provider-NAME` from PATH.",
+		"maps":    "List of path maps. Each map has an `id`, a `path`, and optional `keys` mapping source names to output names.",
+		"options": "Provider specific options, passed through to teller. Bundle providers take `identity`, the age identity file; github providers take `prefix`, the prefix of the variables maps without keys discover, and `strip_prefix`; hashicorp_vault providers take `address`, `namespace`, `kv_version` and an `auth` block with `method` (token, approle, kubernetes, jwt), `mount`, `role`, `role_id`, `secret_id_env`, `token_env`, `jwt_file` and `jwt_env`; `impersonate_service_account`, `assume_roles` and `spiffe_audience` with `vault_auth_role` give teller short-lived credentials; `ca_cert`, `client_cert`, `client_key`, `insecure_skip_verify`, `proxy` and `no_proxy` configure TLS and the proxy; `endpoint` points google_secretmanager, aws_secretsmanager and aws_ssm providers at a private endpoint or an emulator; `rate_limit` with `requests_per_second` and `burst` spaces out API requests; `cache` with `stale` serves the last secrets of hashicorp_vault and plugin providers while refreshing them in long-running processes.",
+	}
+
+	mapDocs = map[string]string{
+		"id":       "Identifier of the map, shown in conflict reports and CSV exports.",
+		"path":     "Secret path (GSM resource path or dotenv file path).",
+		"keys":     "Mapping of source key to output key. Omit for dotenv discovery mode to export every key in the file.",
+		"include":  "Globs of the keys discovery resolves, e.g. `APP_*`; all keys when omitted. Google Secret Manager maps discover environment variables and need it.",
+		"exclude":  "Globs of the keys discovery skips, applied after `include`.",
+		"version":  "Secret version to read: `latest` (default), a version number, or a Google Secret Manager alias or AWS version id.",
+		"stage":    "AWS Secrets Manager staging label (e.g. `AWSPREVIOUS`) or SSM parameter label to read.",
+		"tags":     "Secret sets the map belongs to, e.g. `[backend, e2e]`; `--tags backend` resolves only the maps and keys tagged `backend`.",
+		"key_tags": "Mapping of source key to tags, replacing the `tags` of the map for that key.",
+	}
+
+	hookDocs = map[string]string{
+		"pre_run":  "Commands run through the shell before the child process; a failure aborts the run.",
+		"post_run": "Commands run through the shell after the child process, even when it fails.",
+	}
+
+	messageDocs = map[string]string{
+		"missing_variables":      "Go template of the missing environment variable error, with `.Providers`, `.Variables`, `.EnvNames` and `secretRef`.",
+		"missing_variables_file": "File holding the `missing_variables` template, e.g. shared across repositories. `FELLER_ERROR_TEMPLATE` is used when neither is set.",
+	}
+
+	backupDocs = map[string]string{
+		"recipients": "age recipients (age1... or SSH public keys) the backups are encrypted to; no backups are written without them.",
+		"identity":   "age identity file `feller undo` decrypts backups with; `FELLER_AGE_KEY` when omitted.",
+		"retention":  "Number of backups kept, oldest removed first; 10 when omitted.",
+	}
+
+	targetDocs = map[string]string{
+		"kind":         "Target kind. `github` holds the secrets of a GitHub repository, `vercel` the environment variables of a Vercel project, `cloudflare` the secrets of a Cloudflare Worker and `fly` the secrets of a Fly.io app.",
+		"repo":         "GitHub repository of the target, `owner/repo`.",
+		"dependabot":   "Also reconcile the Dependabot secrets of the repository.",
+		"project":      "Vercel project id or name of the target; `VERCEL_TOKEN` authenticates.",
+		"team":         "Vercel team id owning the project, for projects outside the personal account.",
+		"environments": "Vercel environments the variables are written to (production, preview, development); all when omitted.",
+		"account":      "Cloudflare account id owning the Worker; `CLOUDFLARE_API_TOKEN` authenticates.",
+		"script":       "Name of the Cloudflare Worker whose secrets are written.",
+		"app":          "Fly.io app whose secrets are written; `FLY_API_TOKEN` authenticates.",
+		"providers":    "Only reconcile secrets supplied by these providers; all when omitted.",
+		"keys":         "Only reconcile these output keys; all when omitted.",
+	}
+
+	transformDocs = map[string]string{
+		"trim":          "Remove leading and trailing whitespace.",
+		"upper":         "Convert the value to upper case.",
+		"lower":         "Convert the value to lower case.",
+		"replace":       "Replace every occurrence: `replace: {from: \"a\", to: \"b\"}`.",
+		"base64_decode": "Decode a base64 value (standard or URL encoding, padded or not).",
+		"gzip_decode":   "Decompress a gzip value.",
+		"json_extract":  "Extract a field from a JSON value by dot path: `json_extract: db.password` (array indexes allowed).",
+		"template":      "Render a Go template with `.Key`, `.Value` and `.Secrets`: `template: \"postgres://{{ .Value }}\"`.",
+	}
+
+	schemaDocs = map[string]string{
+		providers.TypeString: "Any value (no validation).",
+		providers.TypeInt:    "Base 10 integer, normalized without leading zeros or whitespace.",
+		providers.TypeBool:   "Boolean (true/false, yes/no, on/off, 1/0), normalized to `true` or `false`.",
+		providers.TypeURL:    "Absolute URL with a scheme.",
+		providers.TypeEmail:  "Email address, normalized to the bare address.",
+		providers.TypeJSON:   "Valid JSON, normalized to its compact form.",
+	}
+)
+
+// kindDoc renders the hover documentation of a provider kind
+func kindDoc(info providers.KindInfo) string {
+	var capabilities []string
+	if info.Capabilities.Read {
+		capabilities = append(capabilities, "read")
+	}
+	if info.Capabilities.Write {
+		capabilities = append(capabilities, "write")
+	}
+	if info.Capabilities.Discovery {
+		capabilities = append(capabilities, "discovery")
+	}
+	return fmt.Sprintf("**%s**\n\n%s\n\nCapabilities: %s  \nRequired map fields: %s",
+		info.Kind, info.Description, strings.Join(capabilities, ", "), strings.Join(info.RequiredMapFields, ", "))
+}
+
+// sortedKeys returns the keys of a documentation map in alphabetical order
+func sortedKeys(docs map[string]string) []string {
+	keys := make([]string, 0, len(docs))
+	for key := range docs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,285 @@
+// Package lsp implements a small Language Server Protocol server for .teller.yml files,
+// providing diagnostics, hover documentation, and completions over stdio.
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/containifyci/feller/pkg/logger"
+	"github.com/containifyci/feller/pkg/providers"
+	"github.com/containifyci/feller/pkg/validate"
+)
+
+// Server is an LSP server communicating over a reader and writer pair.
+// Positions are treated as byte offsets, which matches UTF-16 for ASCII configs.
+type Server struct {
+	in       *bufio.Reader
+	out      io.Writer
+	docs     map[string]string
+	mu       sync.Mutex
+	shutdown bool
+}
+
+// NewServer creates a server reading requests from in and writing responses to out
+func NewServer(in io.Reader, out io.Writer) *Server {
+	return &Server{
+		in:   bufio.NewReader(in),
+		out:  out,
+		docs: make(map[string]string),
+	}
+}
+
+// Run serves requests until the client sends exit or closes the input
+func (s *Server) Run() error {
+	for {
+		body, err := s.readMessage()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+
+		var msg message
+		if err := json.Unmarshal(body, &msg); err != nil {
+			s.replyError(nil, codeParseError, "invalid JSON-RPC message")
+			continue
+		}
+
+		if msg.Method == "exit" {
+			if !s.shutdown {
+				return errors.New("exit received before shutdown")
+			}
+			return nil
+		}
+		s.handle(&msg)
+	}
+}
+
+// readMessage reads one Content-Length framed message
+func (s *Server) readMessage() ([]byte, error) {
+	headers, err := textproto.NewReader(s.in).ReadMIMEHeader()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read message header: %w", err)
+	}
+
+	length, err := strconv.Atoi(headers.Get("Content-Length"))
+	if err != nil || length < 0 {
+		return nil, fmt.Errorf("invalid Content-Length header %q", headers.Get("Content-Length"))
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(s.in, body); err != nil {
+		return nil, fmt.Errorf("failed to read message body: %w", err)
+	}
+	return body, nil
+}
+
+func (s *Server) write(v any) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		logger.Debug("Failed to encode LSP message: %v", err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n%s", len(body), body); err != nil {
+		logger.Debug("Failed to write LSP message: %v", err)
+	}
+}
+
+func (s *Server) reply(id *json.RawMessage, result any) {
+	s.write(response{JSONRPC: "2.0", ID: id, Result: result})
+}
+
+func (s *Server) replyError(id *json.RawMessage, code int, msg string) {
+	s.write(response{JSONRPC: "2.0", ID: id, Error: &responseError{Code: code, Message: msg}})
+}
+
+func (s *Server) handle(msg *message) {
+	logger.Debug("LSP request: %s", msg.Method)
+
+	switch msg.Method {
+	case "initialize":
+		s.reply(msg.ID, map[string]any{
+			"capabilities": map[string]any{
+				"textDocumentSync":   textDocumentSyncFull,
+				"hoverProvider":      true,
+				"completionProvider": map[string]any{"triggerCharacters": []string{":", " ", "-"}},
+			},
+			"serverInfo": map[string]string{"name": "feller"},
+		})
+	case "shutdown":
+		s.shutdown = true
+		s.reply(msg.ID, nil)
+	case "textDocument/didOpen":
+		var params didOpenParams
+		if json.Unmarshal(msg.Params, &params) == nil {
+			s.update(params.TextDocument.URI, params.TextDocument.Text)
+		}
+	case "textDocument/didChange":
+		var params didChangeParams
+		if json.Unmarshal(msg.Params, &params) == nil && len(params.ContentChanges) > 0 {
+			// Full sync: the last change holds the complete document
+			s.update(params.TextDocument.URI, params.ContentChanges[len(params.ContentChanges)-1].Text)
+		}
+	case "textDocument/didClose":
+		var params didCloseParams
+		if json.Unmarshal(msg.Params, &params) == nil {
+			delete(s.docs, params.TextDocument.URI)
+			s.publish(params.TextDocument.URI, []diagnostic{})
+		}
+	case "textDocument/hover", "textDocument/completion":
+		var params textDocumentPositionParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			s.replyError(msg.ID, codeInvalidParams, err.Error())
+			return
+		}
+		ctx := contextAt(s.docs[params.TextDocument.URI], params.Position.Line, params.Position.Character)
+		if msg.Method == "textDocument/hover" {
+			s.reply(msg.ID, hoverFor(ctx))
+		} else {
+			s.reply(msg.ID, completionsFor(ctx))
+		}
+	default:
+		// Notifications without an id (initialized, $/cancelRequest, ...) need no response
+		if msg.ID != nil {
+			s.replyError(msg.ID, codeMethodNotFound, "method not supported: "+msg.Method)
+		}
+	}
+}
+
+// update stores the document text and publishes fresh diagnostics
+func (s *Server) update(uri, text string) {
+	s.docs[uri] = text
+	s.publish(uri, diagnosticsFor(text))
+}
+
+func (s *Server) publish(uri string, diagnostics []diagnostic) {
+	s.write(notification{
+		JSONRPC: "2.0",
+		Method:  "textDocument/publishDiagnostics",
+		Params:  publishDiagnosticsParams{URI: uri, Diagnostics: diagnostics},
+	})
+}
+
+// diagnosticsFor converts validation results into LSP diagnostics spanning the rest of the line
+func diagnosticsFor(text string) []diagnostic {
+	lines := strings.Split(text, "\n")
+	results := validate.Config([]byte(text))
+	diagnostics := make([]diagnostic, 0, len(results))
+	for _, d := range results {
+		line := max(d.Line-1, 0)
+		start := max(d.Column-1, 0)
+		end := start
+		if line < len(lines) {
+			end = max(len(strings.TrimRight(lines[line], "\r")), start)
+		}
+
+		severity := severityWarning
+		if d.Severity == validate.SeverityError {
+			severity = severityError
+		}
+		diagnostics = append(diagnostics, diagnostic{
+			Range:    textRange{Start: position{Line: line, Character: start}, End: position{Line: line, Character: end}},
+			Severity: severity,
+			Source:   "feller",
+			Message:  d.Message,
+		})
+	}
+	return diagnostics
+}
+
+// hoverFor returns documentation for the key or value under the cursor, or nil
+func hoverFor(ctx cursorContext) *hover {
+	doc := ""
+	switch {
+	case ctx.InValue && ctx.Key == "kind" && matchesPath(ctx.Path, "providers", "*"):
+		if info, ok := providers.LookupKind(ctx.Value); ok {
+			doc = kindDoc(info)
+		}
+	case matchesPath(ctx.Path, "transforms", "*"):
+		name := ctx.Key
+		if name == "" {
+			name = ctx.Value
+		}
+		doc = transformDocs[name]
+	case ctx.InValue && matchesPath(ctx.Path, "schema"):
+		doc = schemaDocs[ctx.Value]
+	case !ctx.InValue:
+		doc = fieldDocs(ctx.Path)[ctx.Key]
+	}
+
+	if doc == "" {
+		return nil
+	}
+	return &hover{Contents: markupContent{Kind: "markdown", Value: doc}}
+}
+
+// completionsFor returns completion candidates for the cursor position
+func completionsFor(ctx cursorContext) []completionItem {
+	switch {
+	case ctx.InValue && ctx.Key == "kind" && matchesPath(ctx.Path, "providers", "*"):
+		var items []completionItem
+		for _, info := range providers.Kinds() {
+			items = append(items, completionItem{
+				Label:         info.Kind,
+				Kind:          completionKindValue,
+				Detail:        info.Description,
+				Documentation: &markupContent{Kind: "markdown", Value: kindDoc(info)},
+			})
+		}
+		return items
+	case ctx.InValue && matchesPath(ctx.Path, "schema"):
+		return valueItems(schemaDocs)
+	case ctx.ListItem && matchesPath(ctx.Path, "transforms", "*"):
+		return valueItems(transformDocs)
+	case !ctx.InValue:
+		docs := fieldDocs(ctx.Path)
+		items := make([]completionItem, 0, len(docs))
+		for _, key := range sortedKeys(docs) {
+			items = append(items, completionItem{Label: key, Kind: completionKindProperty, Detail: docs[key]})
+		}
+		return items
+	}
+	return []completionItem{}
+}
+
+func valueItems(docs map[string]string) []completionItem {
+	items := make([]completionItem, 0, len(docs))
+	for _, key := range sortedKeys(docs) {
+		items = append(items, completionItem{Label: key, Kind: completionKindValue, Detail: docs[key]})
+	}
+	return items
+}
+
+// fieldDocs returns the documented fields of the section at path
+func fieldDocs(path []string) map[string]string {
+	switch {
+	case len(path) == 0:
+		return rootDocs
+	case matchesPath(path, "providers", "*"):
+		return providerDocs
+	case matchesPath(path, "providers", "*", "maps"):
+		return mapDocs
+	case matchesPath(path, "hooks"):
+		return hookDocs
+	case matchesPath(path, "messages"):
+		return messageDocs
+	case matchesPath(path, "targets", "*"):
+		return targetDocs
+	case matchesPath(path, "backups"):
+		return backupDocs
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,210 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/containifyci/feller/pkg/providers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func frame(t *testing.T, msg map[string]any) string {
+	t.Helper()
+	msg["jsonrpc"] = "2.0"
+	body, err := json.Marshal(msg)
+	require.NoError(t, err)
+	return fmt.Sprintf("Content-Length: %d\r\n\r\n%s", len(body), body)
+}
+
+// readFrames decodes every framed message written by the server
+func readFrames(t *testing.T, out []byte) []map[string]any {
+	t.Helper()
+	reader := bufio.NewReader(bytes.NewReader(out))
+	var messages []map[string]any
+	for {
+		headers, err := textproto.NewReader(reader).ReadMIMEHeader()
+		if err == io.EOF {
+			return messages
+		}
+		require.NoError(t, err)
+		length, err := strconv.Atoi(headers.Get("Content-Length"))
+		require.NoError(t, err)
+		body := make([]byte, length)
+		_, err = io.ReadFull(reader, body)
+		require.NoError(t, err)
+
+		var msg map[string]any
+		require.NoError(t, json.Unmarshal(body, &msg))
+		messages = append(messages, msg)
+	}
+}
+
+func runSession(t *testing.T, requests ...map[string]any) ([]map[string]any, error) {
+	t.Helper()
+	var in strings.Builder
+	for _, req := range requests {
+		in.WriteString(frame(t, req))
+	}
+	var out bytes.Buffer
+	err := NewServer(strings.NewReader(in.String()), &out).Run()
+	return readFrames(t, out.Bytes()), err
+}
+
+func responseByID(messages []map[string]any, id float64) map[string]any {
+	for _, msg := range messages {
+		if msg["id"] == id {
+			return msg
+		}
+	}
+	return nil
+}
+
+func TestServerSession(t *testing.T) {
+	t.Parallel()
+	uri := "file:///repo/.teller.yml"
+	text := "providers:\n  gha:\n    kind: google_secretmanager\n    maps:\n      - id: ci\nschema:\n  PORT: port\n"
+
+	messages, err := runSession(t,
+		map[string]any{"id": 1, "method": "initialize", "params": map[string]any{}},
+		map[string]any{"method": "initialized", "params": map[string]any{}},
+		map[string]any{"method": "textDocument/didOpen", "params": map[string]any{
+			"textDocument": map[string]any{"uri": uri, "languageId": "yaml", "version": 1, "text": text},
+		}},
+		map[string]any{"id": 2, "method": "textDocument/hover", "params": map[string]any{
+			"textDocument": map[string]any{"uri": uri}, "position": map[string]any{"line": 2, "character": 12},
+		}},
+		map[string]any{"id": 3, "method": "textDocument/completion", "params": map[string]any{
+			"textDocument": map[string]any{"uri": uri}, "position": map[string]any{"line": 6, "character": 8},
+		}},
+		map[string]any{"id": 4, "method": "workspace/symbol", "params": map[string]any{}},
+		map[string]any{"id": 5, "method": "shutdown"},
+		map[string]any{"method": "exit"},
+	)
+	require.NoError(t, err)
+
+	initResult, ok := responseByID(messages, 1)["result"].(map[string]any)
+	require.True(t, ok, "initialize result missing")
+	capabilities := initResult["capabilities"].(map[string]any)
+	assert.Equal(t, true, capabilities["hoverProvider"])
+	assert.InDelta(t, textDocumentSyncFull, capabilities["textDocumentSync"], 0)
+
+	var published map[string]any
+	for _, msg := range messages {
+		if msg["method"] == "textDocument/publishDiagnostics" {
+			published = msg["params"].(map[string]any)
+		}
+	}
+	require.NotNil(t, published, "no diagnostics published")
+	diagnostics := published["diagnostics"].([]any)
+	require.Len(t, diagnostics, 2)
+	first := diagnostics[0].(map[string]any)
+	assert.Contains(t, first["message"], `missing field "keys"`)
+	assert.InDelta(t, severityError, first["severity"], 0)
+	assert.Equal(t, map[string]any{
+		"start": map[string]any{"line": float64(4), "character": float64(8)},
+		"end":   map[string]any{"line": float64(4), "character": float64(14)},
+	}, first["range"])
+
+	hoverResult := responseByID(messages, 2)["result"].(map[string]any)
+	assert.Contains(t, hoverResult["contents"].(map[string]any)["value"], "**google_secretmanager**")
+
+	completions := responseByID(messages, 3)["result"].([]any)
+	var labels []string
+	for _, item := range completions {
+		labels = append(labels, item.(map[string]any)["label"].(string))
+	}
+	assert.Equal(t, []string{"bool", "email", "int", "json", "string", "url"}, labels)
+
+	unsupported := responseByID(messages, 4)["error"].(map[string]any)
+	assert.InDelta(t, codeMethodNotFound, unsupported["code"], 0)
+
+	assert.Contains(t, responseByID(messages, 5), "result")
+}
+
+func TestServerExitWithoutShutdown(t *testing.T) {
+	t.Parallel()
+	_, err := runSession(t, map[string]any{"method": "exit"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "exit received before shutdown")
+}
+
+func TestServerEOF(t *testing.T) {
+	t.Parallel()
+	_, err := runSession(t)
+	require.NoError(t, err)
+}
+
+func TestHoverFor(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		contains string
+		ctx      cursorContext
+	}{
+		{name: "root key", ctx: cursorContext{Key: "transforms"}, contains: "post-processing"},
+		{name: "provider field", ctx: cursorContext{Path: []string{"providers", "x"}, Key: "maps"}, contains: "path maps"},
+		{name: "map field", ctx: cursorContext{Path: []string{"providers", "x", "maps"}, Key: "keys"}, contains: "discovery mode"},
+		{name: "hook field", ctx: cursorContext{Path: []string{"hooks"}, Key: "post_run"}, contains: "even when it fails"},
+		{name: "transform step", ctx: cursorContext{Path: []string{"transforms", "A"}, Value: "json_extract", InValue: true, ListItem: true}, contains: "dot path"},
+		{name: "transform key", ctx: cursorContext{Path: []string{"transforms", "A"}, Key: "replace", ListItem: true}, contains: "Replace every occurrence"},
+		{name: "schema type", ctx: cursorContext{Path: []string{"schema"}, Key: "PORT", Value: "bool", InValue: true}, contains: "Boolean"},
+		{name: "unknown key", ctx: cursorContext{Key: "nope"}, contains: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			result := hoverFor(tt.ctx)
+			if tt.contains == "" {
+				assert.Nil(t, result)
+				return
+			}
+			require.NotNil(t, result)
+			assert.Contains(t, result.Contents.Value, tt.contains)
+		})
+	}
+}
+
+func TestCompletionsFor(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		expected []string
+		ctx      cursorContext
+	}{
+		{name: "root keys", ctx: cursorContext{}, expected: []string{"aliases", "backups", "collisions", "fallback", "hooks", "messages", "providers", "schema", "sync", "targets", "transforms", "version"}},
+		{name: "provider fields", ctx: cursorContext{Path: []string{"providers", "x"}}, expected: []string{"kind", "maps", "options"}},
+		{name: "kinds", ctx: cursorContext{Path: []string{"providers", "x"}, Key: "kind", InValue: true}, expected: []string{"bundle", "dotenv", "github", "google_secretmanager", "hashicorp_vault"}},
+		{
+			name:     "transform steps",
+			ctx:      cursorContext{Path: []string{"transforms", "A"}, InValue: true, ListItem: true},
+			expected: []string{"base64_decode", "gzip_decode", "json_extract", "lower", "replace", "template", "trim", "upper"},
+		},
+		{name: "no candidates", ctx: cursorContext{Path: []string{"providers", "x"}, Key: "maps", InValue: true}, expected: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var labels []string
+			for _, item := range completionsFor(tt.ctx) {
+				labels = append(labels, item.Label)
+			}
+			assert.Equal(t, tt.expected, labels)
+		})
+	}
+}
+
+func TestDocsCoverSupportedTypes(t *testing.T) {
+	t.Parallel()
+	assert.ElementsMatch(t, providers.TransformTypes, sortedKeys(transformDocs))
+	assert.ElementsMatch(t, providers.SchemaTypes(), sortedKeys(schemaDocs))
+}
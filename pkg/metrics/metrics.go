@@ -0,0 +1,132 @@
+// Package metrics keeps counters and gauges of long-running feller commands and renders
+// them in the Prometheus text exposition format, without a client library.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Metric types
+const (
+	TypeCounter = "counter"
+	TypeGauge   = "gauge"
+)
+
+// ContentType is the content type of the text exposition format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Registry holds metric families. It is safe for concurrent use; the zero value is not,
+// use NewRegistry.
+type Registry struct {
+	mu       sync.Mutex
+	families map[string]*family
+}
+
+// family is a metric with its samples by rendered label set
+type family struct {
+	name, help, typ string
+	samples         map[string]float64
+}
+
+// NewRegistry returns an empty registry
+func NewRegistry() *Registry {
+	return &Registry{families: make(map[string]*family)}
+}
+
+// Add increases the counter name with the given label name and value pairs by delta
+func (r *Registry) Add(name, help string, delta float64, labels ...string) {
+	r.update(name, help, TypeCounter, labels, func(v float64) float64 { return v + delta })
+}
+
+// Set sets the gauge name with the given label name and value pairs to value
+func (r *Registry) Set(name, help string, value float64, labels ...string) {
+	r.update(name, help, TypeGauge, labels, func(float64) float64 { return value })
+}
+
+func (r *Registry) update(name, help, typ string, labels []string, update func(float64) float64) {
+	if len(labels)%2 != 0 {
+		panic(fmt.Sprintf("metrics: odd number of label names and values for %s", name))
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	f, ok := r.families[name]
+	if !ok {
+		f = &family{name: name, help: help, typ: typ, samples: make(map[string]float64)}
+		r.families[name] = f
+	}
+	if f.typ != typ {
+		panic(fmt.Sprintf("metrics: %s is a %s, not a %s", name, f.typ, typ))
+	}
+	key := renderLabels(labels)
+	f.samples[key] = update(f.samples[key])
+}
+
+// Value returns the sample of name with the given label name and value pairs, and whether
+// it exists
+func (r *Registry) Value(name string, labels ...string) (float64, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	f, ok := r.families[name]
+	if !ok {
+		return 0, false
+	}
+	value, ok := f.samples[renderLabels(labels)]
+	return value, ok
+}
+
+// WriteTo writes every family in the text exposition format, sorted by name and labels
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	names := make([]string, 0, len(r.families))
+	for name := range r.families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	for _, name := range names {
+		f := r.families[name]
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", f.name, f.help, f.name, f.typ)
+		keys := make([]string, 0, len(f.samples))
+		for key := range f.samples {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fmt.Fprintf(&b, "%s%s %s\n", f.name, key, strconv.FormatFloat(f.samples[key], 'f', -1, 64))
+		}
+	}
+	r.mu.Unlock()
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err //nolint:wrapcheck // the caller names the destination
+}
+
+// Handler serves the metrics, e.g. on /metrics
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", ContentType)
+		_, _ = r.WriteTo(w)
+	})
+}
+
+// renderLabels renders label name and value pairs as {a="1",b="2"}, sorted by name
+func renderLabels(labels []string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	pairs := make([]string, 0, len(labels)/2)
+	for i := 0; i+1 < len(labels); i += 2 {
+		pairs = append(pairs, labels[i]+`="`+escaper.Replace(labels[i+1])+`"`)
+	}
+	sort.Strings(pairs)
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// escaper escapes label values as the text format requires
+var escaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
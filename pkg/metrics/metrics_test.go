@@ -0,0 +1,64 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRegistry(t *testing.T) {
+	t.Parallel()
+	r := NewRegistry()
+	r.Add("feller_requests_total", "Requests.", 2, "provider", "gsm", "kind", "google_secretmanager")
+	r.Add("feller_requests_total", "Requests.", 3, "kind", "google_secretmanager", "provider", "gsm")
+	r.Add("feller_requests_total", "Requests.", 1, "provider", `we"ird\`, "kind", "dotenv")
+	r.Set("feller_secrets", "Secrets.", 7)
+	r.Set("feller_secrets", "Secrets.", 5)
+
+	var b strings.Builder
+	if _, err := r.WriteTo(&b); err != nil {
+		t.Fatalf("WriteTo() error = %v", err)
+	}
+	expected := `# HELP feller_requests_total Requests.
+# TYPE feller_requests_total counter
+feller_requests_total{kind="dotenv",provider="we\"ird\\"} 1
+feller_requests_total{kind="google_secretmanager",provider="gsm"} 5
+# HELP feller_secrets Secrets.
+# TYPE feller_secrets gauge
+feller_secrets 5
+`
+	if b.String() != expected {
+		t.Errorf("WriteTo() = %q, expected %q", b.String(), expected)
+	}
+
+	if value, ok := r.Value("feller_requests_total", "provider", "gsm", "kind", "google_secretmanager"); !ok || value != 5 {
+		t.Errorf("Value() = %v, %v, expected 5, true", value, ok)
+	}
+	if _, ok := r.Value("feller_missing"); ok {
+		t.Error("Value() of a missing metric exists")
+	}
+}
+
+func TestRegistryTypeMismatch(t *testing.T) {
+	t.Parallel()
+	r := NewRegistry()
+	r.Set("feller_secrets", "Secrets.", 1)
+	defer func() {
+		if recover() == nil {
+			t.Error("Add() on a gauge did not panic")
+		}
+	}()
+	r.Add("feller_secrets", "Secrets.", 1)
+}
+
+func TestHandler(t *testing.T) {
+	t.Parallel()
+	r := NewRegistry()
+	r.Set("feller_up", "Up.", 1)
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Header().Get("Content-Type") != ContentType || !strings.Contains(rec.Body.String(), "feller_up 1\n") {
+		t.Errorf("Handler() = %q (%s)", rec.Body.String(), rec.Header().Get("Content-Type"))
+	}
+}
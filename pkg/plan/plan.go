@@ -0,0 +1,123 @@
+// Package plan describes the changes a command is about to make to secrets in one
+// structure shared by every target, rendered as JSON for tools or as a diff for people.
+package plan
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Version is the format version of JSON plans
+const Version = 1
+
+// Actions of a change
+const (
+	ActionCreate = "create" // The target does not hold the key yet
+	ActionUpdate = "update" // The target holds the key, its value is replaced
+	ActionDelete = "delete" // The key is removed from the target
+	ActionNoop   = "no-op"  // The key is left as it is
+)
+
+// AllKeys is the key of changes to every key a target receives, when they are not known
+// before the change is made
+const AllKeys = "*"
+
+// Plan is the list of changes of one command, in the order they are made
+type Plan struct {
+	Version int      `json:"version"`
+	Command string   `json:"command"`
+	Changes []Change `json:"changes"`
+}
+
+// Change is a single key of a target
+type Change struct {
+	Action string `json:"action"`
+	Target string `json:"target"`           // System and location, e.g. "github:owner/repo" or "dotenv:.env"
+	Scope  string `json:"scope,omitempty"`  // Part of the target, e.g. "repository", "Dependabot" or a map id
+	Key    string `json:"key"`              // Key as applications see it, or AllKeys
+	Reason string `json:"reason,omitempty"` // Why the change is made, e.g. "missing" or the value source
+}
+
+// New returns an empty plan of command
+func New(command string) *Plan {
+	return &Plan{Version: Version, Command: command, Changes: []Change{}}
+}
+
+// Add appends a change
+func (p *Plan) Add(change Change) {
+	p.Changes = append(p.Changes, change)
+}
+
+// Counts returns the number of changes per action
+func (p *Plan) Counts() map[string]int {
+	counts := make(map[string]int)
+	for _, change := range p.Changes {
+		counts[change.Action]++
+	}
+	return counts
+}
+
+// Empty reports whether the plan changes nothing
+func (p *Plan) Empty() bool {
+	for _, change := range p.Changes {
+		if change.Action != ActionNoop {
+			return false
+		}
+	}
+	return true
+}
+
+// WriteJSON writes the plan as indented JSON
+func (p *Plan) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(p); err != nil {
+		return fmt.Errorf("failed to encode plan: %w", err)
+	}
+	return nil
+}
+
+// symbols prefix the changes of each action in rendered plans, as in terraform plans
+var symbols = map[string]string{ActionCreate: "+", ActionUpdate: "~", ActionDelete: "-", ActionNoop: " "}
+
+// Render writes the plan as a diff: the changes grouped by target and scope in the order
+// they are first changed, one line per key prefixed with + (create), ~ (update), - (delete)
+// or a space (no-op), followed by a summary line
+func (p *Plan) Render(w io.Writer) {
+	var groups []string
+	changes := make(map[string][]Change)
+	for _, change := range p.Changes {
+		group := change.Target
+		if change.Scope != "" {
+			group += " (" + change.Scope + ")"
+		}
+		if _, ok := changes[group]; !ok {
+			groups = append(groups, group)
+		}
+		changes[group] = append(changes[group], change)
+	}
+
+	for _, group := range groups {
+		fmt.Fprintf(w, "%s\n", group)
+		width := 0
+		for _, change := range changes[group] {
+			width = max(width, len(change.Key))
+		}
+		for _, change := range changes[group] {
+			line := fmt.Sprintf("  %s %s", symbols[change.Action], change.Key)
+			if change.Reason != "" {
+				line += strings.Repeat(" ", width-len(change.Key)) + "  " + change.Reason
+			}
+			fmt.Fprintln(w, line)
+		}
+	}
+
+	counts := p.Counts()
+	if p.Empty() {
+		fmt.Fprintln(w, "No changes.")
+		return
+	}
+	fmt.Fprintf(w, "Plan: %d to create, %d to update, %d to delete.\n", counts[ActionCreate], counts[ActionUpdate], counts[ActionDelete])
+}
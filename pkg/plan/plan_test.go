@@ -0,0 +1,93 @@
+package plan
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		changes  []Change
+		expected string
+	}{
+		{
+			name:     "empty",
+			expected: "No changes.\n",
+		},
+		{
+			name: "only no-ops",
+			changes: []Change{
+				{Action: ActionNoop, Target: "github:owner/repo", Scope: "repository", Key: "A", Reason: "exists, skipped"},
+			},
+			expected: "github:owner/repo (repository)\n    A  exists, skipped\nNo changes.\n",
+		},
+		{
+			name: "grouped by target and scope",
+			changes: []Change{
+				{Action: ActionCreate, Target: "github:owner/repo", Scope: "repository", Key: "API_KEY"},
+				{Action: ActionUpdate, Target: "github:owner/repo", Scope: "repository", Key: "DB", Reason: "exists"},
+				{Action: ActionDelete, Target: "dotenv:.env", Key: "OLD"},
+				{Action: ActionCreate, Target: "github:owner/repo", Scope: "Dependabot", Key: "API_KEY", Reason: "missing"},
+				{Action: ActionUpdate, Target: "github:owner/repo", Scope: "repository", Key: AllKeys},
+			},
+			expected: "github:owner/repo (repository)\n" +
+				"  + API_KEY\n" +
+				"  ~ DB       exists\n" +
+				"  ~ *\n" +
+				"dotenv:.env\n" +
+				"  - OLD\n" +
+				"github:owner/repo (Dependabot)\n" +
+				"  + API_KEY  missing\n" +
+				"Plan: 2 to create, 2 to update, 1 to delete.\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			p := New("test")
+			for _, change := range tt.changes {
+				p.Add(change)
+			}
+			var out bytes.Buffer
+			p.Render(&out)
+			if out.String() != tt.expected {
+				t.Errorf("Render() = %q, expected %q", out.String(), tt.expected)
+			}
+		})
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	t.Parallel()
+	p := New("apply")
+	p.Add(Change{Action: ActionCreate, Target: "dotenv:.env", Key: "A"})
+	var out bytes.Buffer
+	if err := p.WriteJSON(&out); err != nil {
+		t.Fatalf("WriteJSON() error = %v", err)
+	}
+
+	var got map[string]any
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatalf("WriteJSON() wrote invalid JSON: %v", err)
+	}
+	changes, ok := got["changes"].([]any)
+	if got["version"] != float64(Version) || got["command"] != "apply" || !ok || len(changes) != 1 {
+		t.Fatalf("WriteJSON() = %s", out.String())
+	}
+	if change := changes[0].(map[string]any); change["action"] != "create" || change["key"] != "A" || change["scope"] != nil {
+		t.Errorf("WriteJSON() change = %v, expected create of A without scope", change)
+	}
+
+	// Empty plans list no changes rather than null, so tools can iterate them
+	out.Reset()
+	if err := New("apply").WriteJSON(&out); err != nil {
+		t.Fatalf("WriteJSON() error = %v", err)
+	}
+	if !bytes.Contains(out.Bytes(), []byte(`"changes": []`)) {
+		t.Errorf("WriteJSON() of an empty plan = %s", out.String())
+	}
+}
@@ -0,0 +1,128 @@
+package providers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/containifyci/feller/pkg/config"
+)
+
+// Teller kinds feller does not read natively, reported so CI identities of the fallback
+// mode can be right-sized too
+const (
+	kindAWSSecretsManager = "aws_secretsmanager"
+	kindAWSParameterStore = "aws_ssm"
+)
+
+// discoveryKey stands for every key of a map without key mappings
+const discoveryKey = "*"
+
+// AccessRequirement is the access needed to read one output key from its provider
+type AccessRequirement struct {
+	Key        string `json:"key"` // Output key, or * for maps that read every key
+	Provider   string `json:"provider"`
+	Kind       string `json:"kind"`
+	Resource   string `json:"resource"`   // What access must be granted on
+	Permission string `json:"permission"` // Narrowest permission allowing the read
+	Role       string `json:"role"`       // Predefined role or policy granting the permission
+}
+
+// AccessReport returns the access every configured key needs, sorted by provider and key.
+// It only inspects the config; nothing is read from the providers.
+func AccessReport(cfg *config.TellerConfig) []AccessRequirement {
+	var report []AccessRequirement
+	for name, provider := range cfg.Providers {
+		for _, pathMap := range provider.Maps {
+			if len(pathMap.Keys) == 0 {
+				report = append(report, pinResource(accessRequirement(name, provider.Kind, pathMap.Path, discoveryKey, discoveryKey), pathMap))
+				continue
+			}
+			for fromKey, toKey := range pathMap.Keys {
+				report = append(report, pinResource(accessRequirement(name, provider.Kind, pathMap.Path, fromKey, toKey), pathMap))
+			}
+		}
+	}
+
+	sort.Slice(report, func(i, j int) bool {
+		if report[i].Provider != report[j].Provider {
+			return report[i].Provider < report[j].Provider
+		}
+		if report[i].Key != report[j].Key {
+			return report[i].Key < report[j].Key
+		}
+		return report[i].Resource < report[j].Resource
+	})
+	return report
+}
+
+// pinResource narrows the resource of req to the version or stage the map pins
+func pinResource(req AccessRequirement, pathMap config.PathMap) AccessRequirement {
+	if !pathMap.Pinned() {
+		return req
+	}
+	versionOnly := pathMap.Stage == ""
+	switch {
+	case req.Kind == KindGoogleSecretManager && versionOnly:
+		req.Resource += "/versions/" + pathMap.Version
+	case req.Kind == KindHashiCorpVault && versionOnly:
+		req.Resource += "?version=" + pathMap.Version
+	case req.Kind == kindAWSParameterStore && (versionOnly || pathMap.Version == ""):
+		req.Resource += ":" + pathMap.Version + pathMap.Stage
+	default:
+		req.Resource += " (" + describeVersion(pathMap) + ")"
+	}
+	return req
+}
+
+// accessRequirement describes the access reading fromKey of a map at path needs
+func accessRequirement(provider, kind, path, fromKey, toKey string) AccessRequirement {
+	req := AccessRequirement{Key: toKey, Provider: provider, Kind: kind}
+	switch kind {
+	case KindGoogleSecretManager:
+		project := strings.TrimSuffix(path, "/")
+		if project == "" {
+			project = "projects/-" // The project of the credentials
+		}
+		req.Resource = fmt.Sprintf("%s/secrets/%s", project, fromKey)
+		req.Permission = "secretmanager.versions.access"
+		req.Role = "roles/secretmanager.secretAccessor"
+	case KindGitHub:
+		req.Resource = "environment variable " + fromKey
+		req.Permission = "secret passed to the job"
+		req.Role = "env: " + fromKey + ": ${{ secrets." + fromKey + " }}"
+		if fromKey == discoveryKey {
+			req.Resource = "environment variables of the job"
+			req.Role = "env entries of the job"
+		}
+	case KindDotenv:
+		req.Resource = path
+		req.Permission = "file read"
+		req.Role = "none"
+	case KindBundle:
+		req.Resource = path
+		req.Permission = "file read"
+		req.Role = "age identity of a bundle recipient"
+	case KindHashiCorpVault:
+		req.Resource = path
+		req.Permission = "read"
+		req.Role = fmt.Sprintf(`path %q { capabilities = ["read"] }`, path)
+	case kindAWSSecretsManager:
+		req.Resource = path
+		req.Permission = "secretsmanager:GetSecretValue"
+		req.Role = "IAM policy allowing secretsmanager:GetSecretValue on the secret ARN"
+	case kindAWSParameterStore:
+		req.Resource = path
+		req.Permission = "ssm:GetParameter"
+		req.Role = "IAM policy allowing ssm:GetParameter on the parameter ARN"
+	default:
+		req.Resource = path
+		req.Permission = "unknown"
+		req.Role = "unknown"
+		if plugin, ok := PluginName(kind); ok {
+			req.Permission = "defined by plugin " + plugin
+			req.Role = "credentials of " + PluginBinaryPrefix + plugin
+		}
+	}
+	return req
+}
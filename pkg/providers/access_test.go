@@ -0,0 +1,44 @@
+package providers
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/containifyci/feller/pkg/config"
+)
+
+func TestAccessReport(t *testing.T) {
+	t.Parallel()
+	cfg := &config.TellerConfig{Providers: map[string]config.Provider{
+		"gsm": {Kind: KindGoogleSecretManager, Maps: []config.PathMap{
+			{ID: "app", Path: "projects/my-project/", Keys: map[string]string{"db-password": "DB_PASSWORD", "api-key": "API_KEY"}},
+			{ID: "shared", Keys: map[string]string{"token": "TOKEN"}},
+		}},
+		"local":  {Kind: KindDotenv, Maps: []config.PathMap{{ID: "env", Path: ".env"}}},
+		"vault":  {Kind: "hashicorp_vault", Maps: []config.PathMap{{ID: "v", Path: "secret/data/app", Keys: map[string]string{"KEY": "VAULT_KEY"}}}},
+		"other":  {Kind: "custom", Maps: []config.PathMap{{ID: "c", Path: "x", Keys: map[string]string{"A": "A"}}}},
+		"pinned": {Kind: KindGoogleSecretManager, Maps: []config.PathMap{{ID: "p", Path: "projects/old", Version: "3", Keys: map[string]string{"cert": "CERT"}}}},
+		"ssm":    {Kind: "aws_ssm", Maps: []config.PathMap{{ID: "s", Path: "/app/db", Stage: "prod", Keys: map[string]string{"DB": "DB"}}}},
+	}}
+
+	expected := []AccessRequirement{
+		{Key: "API_KEY", Provider: "gsm", Kind: KindGoogleSecretManager, Resource: "projects/my-project/secrets/api-key",
+			Permission: "secretmanager.versions.access", Role: "roles/secretmanager.secretAccessor"},
+		{Key: "DB_PASSWORD", Provider: "gsm", Kind: KindGoogleSecretManager, Resource: "projects/my-project/secrets/db-password",
+			Permission: "secretmanager.versions.access", Role: "roles/secretmanager.secretAccessor"},
+		{Key: "TOKEN", Provider: "gsm", Kind: KindGoogleSecretManager, Resource: "projects/-/secrets/token",
+			Permission: "secretmanager.versions.access", Role: "roles/secretmanager.secretAccessor"},
+		{Key: "*", Provider: "local", Kind: KindDotenv, Resource: ".env", Permission: "file read", Role: "none"},
+		{Key: "A", Provider: "other", Kind: "custom", Resource: "x", Permission: "unknown", Role: "unknown"},
+		{Key: "CERT", Provider: "pinned", Kind: KindGoogleSecretManager, Resource: "projects/old/secrets/cert/versions/3",
+			Permission: "secretmanager.versions.access", Role: "roles/secretmanager.secretAccessor"},
+		{Key: "DB", Provider: "ssm", Kind: "aws_ssm", Resource: "/app/db:prod",
+			Permission: "ssm:GetParameter", Role: "IAM policy allowing ssm:GetParameter on the parameter ARN"},
+		{Key: "VAULT_KEY", Provider: "vault", Kind: "hashicorp_vault", Resource: "secret/data/app",
+			Permission: "read", Role: `path "secret/data/app" { capabilities = ["read"] }`},
+	}
+
+	if got := AccessReport(cfg); !reflect.DeepEqual(got, expected) {
+		t.Errorf("AccessReport() =\n%+v\nwant\n%+v", got, expected)
+	}
+}
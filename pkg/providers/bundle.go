@@ -0,0 +1,99 @@
+package providers
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/containifyci/feller/pkg/bundle"
+	"github.com/containifyci/feller/pkg/config"
+	"github.com/containifyci/feller/pkg/logger"
+)
+
+// bundleOptions are the options of a bundle provider
+type bundleOptions struct {
+	Identity string `yaml:"identity"` // age identity file; bundle.KeyEnv is used when empty
+}
+
+// collectBundleSecrets collects secrets from age-encrypted bundles written by
+// 'feller export bundle' and also returns the path map id of each key
+func collectBundleSecrets(provider config.Provider) (SecretMap, map[string]string, error) {
+	logger.Debug("Collecting bundle secrets from %d path maps", len(provider.Maps))
+	identity, err := BundleIdentity(provider)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	secrets := make(SecretMap)
+	mapIDs := make(map[string]string)
+	for i, pathMap := range provider.Maps {
+		logger.Debug("Processing bundle path map %d (id: %s, path: %s)", i+1, pathMap.ID, pathMap.Path)
+
+		b, err := LoadBundle(pathMap.Path, identity)
+		if err != nil {
+			return nil, nil, err
+		}
+		logger.Debug("Loaded %d secrets from bundle '%s' created %s", len(b.Secrets), pathMap.Path, b.Created)
+
+		if len(pathMap.Keys) == 0 {
+			for key, entry := range b.Secrets {
+				if !discovers(pathMap, key) {
+					continue
+				}
+				secrets[key] = entry.Value
+				mapIDs[key] = pathMap.ID
+			}
+			continue
+		}
+		for fromKey, toKey := range pathMap.Keys {
+			if entry, ok := b.Secrets[fromKey]; ok {
+				secrets[toKey] = entry.Value
+				mapIDs[toKey] = pathMap.ID
+			} else {
+				logger.Debug("Key '%s' not found in bundle '%s'", fromKey, pathMap.Path)
+			}
+		}
+	}
+	return secrets, mapIDs, nil
+}
+
+// BundleIdentity returns the identity option of a bundle provider, empty when bundle.KeyEnv
+// holds the identity
+func BundleIdentity(provider config.Provider) (string, error) {
+	var opts bundleOptions
+	if !provider.Options.IsZero() {
+		if err := provider.Options.Decode(&opts); err != nil {
+			return "", fmt.Errorf("invalid bundle options: %w", err)
+		}
+	}
+	return opts.Identity, nil
+}
+
+// LoadBundle reads and decrypts the bundle file at path with the identity file, or the
+// identity in bundle.KeyEnv when identity is empty
+func LoadBundle(path, identity string) (*bundle.Bundle, error) {
+	path = config.LocalPath(path)
+	// #nosec G304 - Bundle paths come from the user's config
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bundle %s: %w", path, err)
+	}
+	if identity != "" {
+		identity = config.LocalPath(identity)
+	}
+	b, err := bundle.Decrypt(data, identity)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt bundle %s: %w", path, err)
+	}
+	return b, nil
+}
+
+// NewBundle returns a bundle of the given secrets with the origin of each
+func NewBundle(secrets SecretMap, sources map[string]SecretSource) *bundle.Bundle {
+	b := &bundle.Bundle{Version: bundle.Version, Created: time.Now().UTC(), Secrets: make(map[string]bundle.Entry, len(secrets))}
+	for key, value := range secrets {
+		source := sources[key]
+		b.Secrets[key] = bundle.Entry{Value: value, Provider: source.Provider, Kind: source.Kind, MapID: source.MapID}
+	}
+	return b
+}
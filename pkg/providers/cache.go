@@ -0,0 +1,188 @@
+package providers
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/containifyci/feller/pkg/config"
+	"github.com/containifyci/feller/pkg/logger"
+	"gopkg.in/yaml.v3"
+)
+
+// Cache serves the secrets a provider resolved last while it is refreshed in the background
+type Cache struct {
+	Stale string `yaml:"stale"` // How old served secrets may be, e.g. 10m
+}
+
+// cacheOptions are the provider options configuring the cache
+type cacheOptions struct {
+	Cache *Cache `yaml:"cache"`
+}
+
+// CacheWindowFor returns the staleness window of the cache option of a provider, 0 when it
+// has none
+func CacheWindowFor(provider config.Provider) (time.Duration, error) {
+	if provider.Options.IsZero() {
+		return 0, nil
+	}
+	var opts cacheOptions
+	if err := provider.Options.Decode(&opts); err != nil {
+		return 0, fmt.Errorf("invalid cache: %w", err)
+	}
+	if opts.Cache == nil {
+		return 0, nil
+	}
+	window, err := time.ParseDuration(opts.Cache.Stale)
+	if err != nil || window <= 0 {
+		return 0, errors.New("cache stale must be a positive duration, e.g. 10m")
+	}
+	return window, nil
+}
+
+// ProviderHealth is the state of a cached provider: when it was last resolved, and when and
+// why a refresh failed last
+type ProviderHealth struct {
+	Provider string
+	Resolved time.Time
+	Failed   time.Time
+	Err      error
+}
+
+// cachedSecrets are the secrets of a provider with the path map id of each key and the
+// mapped keys it lacked
+type cachedSecrets struct {
+	secrets     SecretMap
+	mapIDs      map[string]string
+	missingVars []MissingVariable
+}
+
+// cacheEntry is the last successful resolution of a provider with the fingerprint of the
+// configuration it was resolved with, and the health of its refreshes
+type cacheEntry struct {
+	fingerprint string
+	value       cachedSecrets
+	health      ProviderHealth
+}
+
+// secretCache holds the secrets of cached providers by name for the lifetime of the
+// process, so long-running commands such as reconcile --interval keep working through
+// short outages of a provider
+type secretCache struct {
+	mu        sync.Mutex
+	entries   map[string]*cacheEntry
+	refreshes flightGroup[cachedSecrets]
+	pending   sync.WaitGroup
+}
+
+var providerCache secretCache
+
+// cacheNow returns the current time, replaced in tests
+var cacheNow = time.Now
+
+// collectCached collects the secrets of a provider with collect, or, when the provider has
+// a cache option, serves those resolved within its staleness window and refreshes them in
+// the background. The age of served secrets is recorded in the meter.
+func collectCached(name string, provider config.Provider, m *meter,
+	collect func(string, config.Provider, *meter) (SecretMap, map[string]string, []MissingVariable, error),
+) (SecretMap, map[string]string, []MissingVariable, error) {
+	window, err := CacheWindowFor(provider)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("provider %s: %w", name, err)
+	}
+	if window == 0 {
+		return collect(name, provider, m)
+	}
+	data, err := yaml.Marshal(provider)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("provider %s: failed to encode config: %w", name, err)
+	}
+
+	fetch := func(m *meter) (cachedSecrets, error) {
+		secrets, mapIDs, missingVars, err := collect(name, provider, m)
+		return cachedSecrets{secrets: secrets, mapIDs: mapIDs, missingVars: missingVars}, err
+	}
+	refresh := func() (cachedSecrets, error) {
+		// The meter of the caller is reported before the refresh ends
+		background, err := newMeter(name, provider)
+		if err != nil {
+			return cachedSecrets{}, err
+		}
+		return fetch(background)
+	}
+	value, age, hit, err := providerCache.resolve(name, string(data), window, func() (cachedSecrets, error) { return fetch(m) }, refresh)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if m != nil {
+		m.timing.Cached, m.timing.CacheHit = age, hit
+	}
+	return value.secrets, value.mapIDs, value.missingVars, nil
+}
+
+// resolve returns the secrets of provider name when they were resolved with the same
+// configuration, its fingerprint, within window, starting refresh in the background, and their age.
+// Otherwise it resolves them with fetch and waits for it. It reports whether the secrets
+// were served from the cache.
+func (c *secretCache) resolve(name, fingerprint string, window time.Duration, fetch, refresh func() (cachedSecrets, error)) (cachedSecrets, time.Duration, bool, error) {
+	c.mu.Lock()
+	entry := c.entries[name]
+	var value cachedSecrets
+	var age time.Duration
+	fresh := entry != nil && entry.fingerprint == fingerprint
+	if fresh {
+		value, age = entry.value, cacheNow().Sub(entry.health.Resolved)
+		fresh = age <= window
+	}
+	c.mu.Unlock()
+
+	// Refreshes of one configuration are shared, a changed one is resolved separately
+	key := name + "\x00" + fingerprint
+	if fresh {
+		logger.Debug("Serving the secrets of provider '%s' resolved %s ago, refreshing", name, age.Round(time.Second))
+		c.pending.Add(1)
+		go func() {
+			defer c.pending.Done()
+			if _, _, err := c.refreshes.Do(key, func() (cachedSecrets, error) { return c.store(name, fingerprint, refresh) }); err != nil {
+				logger.Error("provider %s: refresh failed, serving the secrets resolved %s ago: %v", name, age.Round(time.Second), err)
+			}
+		}()
+		return value, age, true, nil
+	}
+	value, _, err := c.refreshes.Do(key, func() (cachedSecrets, error) { return c.store(name, fingerprint, fetch) })
+	return value, 0, false, err
+}
+
+// store resolves the secrets of provider name with fetch, recording a failure in the
+// health of the cached secrets
+func (c *secretCache) store(name, fingerprint string, fetch func() (cachedSecrets, error)) (cachedSecrets, error) {
+	value, err := fetch()
+	now := cacheNow()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[string]*cacheEntry)
+	}
+	if err != nil {
+		if entry := c.entries[name]; entry != nil {
+			entry.health.Failed, entry.health.Err = now, err
+		}
+		return value, err
+	}
+	c.entries[name] = &cacheEntry{fingerprint: fingerprint, value: value, health: ProviderHealth{Provider: name, Resolved: now}}
+	return value, nil
+}
+
+// CacheHealth returns the health of every cached provider, sorted by provider name
+func CacheHealth() []ProviderHealth {
+	providerCache.mu.Lock()
+	defer providerCache.mu.Unlock()
+	health := make([]ProviderHealth, 0, len(providerCache.entries))
+	for _, entry := range providerCache.entries {
+		health = append(health, entry.health)
+	}
+	sort.Slice(health, func(i, j int) bool { return health[i].Provider < health[j].Provider })
+	return health
+}
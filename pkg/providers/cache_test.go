@@ -0,0 +1,133 @@
+package providers
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/containifyci/feller/pkg/config"
+)
+
+func TestCacheWindowFor(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		options  string
+		expected time.Duration
+		wantErr  bool
+	}{
+		{name: "no cache option", options: "{kv_version: 1}"},
+		{name: "window", options: "{cache: {stale: 10m}}", expected: 10 * time.Minute},
+		{name: "negative window", options: "{cache: {stale: -1m}}", wantErr: true},
+		{name: "no window", options: "{cache: {}}", wantErr: true},
+		{name: "invalid window", options: "{cache: {stale: soon}}", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			window, err := CacheWindowFor(vaultProvider(t, tt.options))
+			if (err != nil) != tt.wantErr || window != tt.expected {
+				t.Errorf("CacheWindowFor() = %v, %v, expected %v (error: %v)", window, err, tt.expected, tt.wantErr)
+			}
+		})
+	}
+	if window, err := CacheWindowFor(config.Provider{Kind: KindHashiCorpVault}); window != 0 || err != nil {
+		t.Errorf("CacheWindowFor() without options = %v, %v", window, err)
+	}
+}
+
+//nolint:paralleltest // replaces the clock of the cache
+func TestSecretCacheResolve(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	t.Cleanup(func() { cacheNow = time.Now })
+	cacheNow = func() time.Time { return now }
+
+	var c secretCache
+	var fetched int
+	var failure error
+	fetch := func() (cachedSecrets, error) {
+		fetched++
+		if failure != nil {
+			return cachedSecrets{}, failure
+		}
+		return cachedSecrets{secrets: SecretMap{"A": string(rune('0' + fetched))}}, nil
+	}
+	resolve := func(fingerprint string) (string, time.Duration, error) {
+		t.Helper()
+		value, age, hit, err := c.resolve("vault", fingerprint, 5*time.Minute, fetch, fetch)
+		c.pending.Wait()
+		if hit != (age > 0) {
+			t.Errorf("resolve() hit = %v with age %v", hit, age)
+		}
+		return value.secrets["A"], age, err
+	}
+
+	if value, age, err := resolve("v1"); value != "1" || age != 0 || err != nil {
+		t.Errorf("resolve() = %q, %v, %v, expected the first value", value, age, err)
+	}
+
+	// Within the window the cached value is served while a refresh fails in the background
+	now = now.Add(time.Minute)
+	failure = errors.New("connection refused")
+	if value, age, err := resolve("v1"); value != "1" || age != time.Minute || err != nil {
+		t.Errorf("resolve() during an outage = %q, %v, %v, expected the cached value", value, age, err)
+	}
+	if health := c.entries["vault"].health; !health.Failed.Equal(now) || !errors.Is(health.Err, failure) {
+		t.Errorf("health after a failed refresh = %+v", health)
+	}
+
+	// Beyond the window the error is returned
+	now = now.Add(5 * time.Minute)
+	if _, _, err := resolve("v1"); !errors.Is(err, failure) {
+		t.Errorf("resolve() beyond the window error = %v", err)
+	}
+
+	failure = nil
+	if value, age, err := resolve("v1"); value != "4" || age != 0 || err != nil {
+		t.Errorf("resolve() after the outage = %q, %v, %v", value, age, err)
+	}
+	if health := c.entries["vault"].health; !health.Resolved.Equal(now) || health.Err != nil {
+		t.Errorf("health after a successful refresh = %+v", health)
+	}
+
+	// A changed configuration is not served from the cache
+	if value, age, err := resolve("v2"); value != "5" || age != 0 || err != nil {
+		t.Errorf("resolve() of a changed config = %q, %v, %v", value, age, err)
+	}
+}
+
+//nolint:paralleltest // sets environment variables
+func TestCollectVaultSecretsCached(t *testing.T) {
+	server := fakeVault(t, map[string]string{
+		"secret/data/app": `{"data":{"data":{"API_KEY":"abc"},"metadata":{"version":1}}}`,
+	})
+	t.Setenv("VAULT_ADDR", server.URL)
+	t.Setenv("VAULT_TOKEN", "t0k3n")
+	t.Setenv("VAULT_NAMESPACE", "")
+
+	cfg := &config.TellerConfig{Providers: map[string]config.Provider{
+		"cached-vault": vaultProvider(t, "{cache: {stale: 1h}}", config.PathMap{ID: "app", Path: "secret/data/app"}),
+	}}
+	result, err := CollectSecretsWithResult(cfg, false)
+	if err != nil || result.Secrets["API_KEY"] != "abc" {
+		t.Fatalf("CollectSecretsWithResult() = %v, %v", result, err)
+	}
+
+	// Vault rejects the token, the secrets resolved before are served
+	t.Setenv("VAULT_TOKEN", "revoked")
+	result, err = CollectSecretsWithResult(cfg, false)
+	providerCache.pending.Wait()
+	if err != nil || result.Secrets["API_KEY"] != "abc" {
+		t.Fatalf("CollectSecretsWithResult() during an outage = %v, %v", result, err)
+	}
+	if len(result.Timings) != 1 || result.Timings[0].Requests != 0 {
+		t.Errorf("Timings = %+v, expected no requests", result.Timings)
+	}
+	reported := false
+	for _, health := range CacheHealth() {
+		reported = reported || health.Provider == "cached-vault" && health.Err != nil
+	}
+	if !reported {
+		t.Errorf("CacheHealth() = %+v, expected the failed refresh of cached-vault", CacheHealth())
+	}
+}
@@ -0,0 +1,123 @@
+package providers
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/containifyci/feller/pkg/logger"
+)
+
+// Strategies for keys supplied by more than one provider
+const (
+	CollisionLastWins       = "last-wins"       // The provider collected last supplies the key (default)
+	CollisionFirstWins      = "first-wins"      // The provider collected first supplies the key
+	CollisionError          = "error"           // Collection fails
+	CollisionPrefixProvider = "prefix-provider" // Every value is kept as <PROVIDER>_<KEY>
+)
+
+// CollisionStrategies lists the supported collision strategies
+var CollisionStrategies = []string{CollisionError, CollisionFirstWins, CollisionLastWins, CollisionPrefixProvider}
+
+// CheckCollisionStrategy validates a collision strategy; empty selects last-wins
+func CheckCollisionStrategy(strategy string) error {
+	if strategy == "" {
+		return nil
+	}
+	for _, known := range CollisionStrategies {
+		if strategy == known {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown collision strategy %q (expected %s)", strategy, strings.Join(CollisionStrategies, ", "))
+}
+
+// contribution is a value a provider supplied for a key, before collisions are resolved
+type contribution struct {
+	key    string
+	value  string
+	source SecretSource
+}
+
+// merge resolves the contributions of the collected providers into the secrets, sources and
+// origins of the result. Providers are collected by kind and then name, which defines which
+// one is first and last.
+func (r *CollectionResult) merge(strategy string) error {
+	if err := CheckCollisionStrategy(strategy); err != nil {
+		return err
+	}
+	byKey := make(map[string][]contribution)
+	var keys []string
+	for _, c := range r.contributions {
+		if _, seen := byKey[c.key]; !seen {
+			keys = append(keys, c.key)
+		}
+		byKey[c.key] = append(byKey[c.key], c)
+	}
+	sort.Strings(keys)
+
+	var collisions []string
+	renamed := make(map[string][]contribution)
+	for _, key := range keys {
+		values := byKey[key]
+		for _, c := range values {
+			r.Origins[key] = append(r.Origins[key], c.source)
+		}
+		if len(values) == 1 {
+			r.set(key, values[0])
+			continue
+		}
+		switch strategy {
+		case CollisionFirstWins:
+			r.set(key, values[0])
+		case CollisionError:
+			collisions = append(collisions, fmt.Sprintf("%s from %s", key, describeOrigins(r.Origins[key])))
+		case CollisionPrefixProvider:
+			delete(r.Origins, key)
+			for _, c := range values {
+				prefixed := providerPrefix(c.source.Provider) + "_" + key
+				renamed[prefixed] = append(renamed[prefixed], c)
+			}
+		default:
+			r.set(key, values[len(values)-1])
+		}
+	}
+	if len(collisions) > 0 {
+		return fmt.Errorf("keys supplied by more than one provider (collision strategy error): %s", strings.Join(collisions, "; "))
+	}
+
+	for _, prefixed := range slices.Sorted(maps.Keys(renamed)) {
+		values := renamed[prefixed]
+		if _, taken := byKey[prefixed]; taken || len(values) > 1 {
+			return fmt.Errorf("cannot rename %s of provider %s to %s: the key already exists", values[0].key, values[0].source.Provider, prefixed)
+		}
+		logger.Debug("Key '%s' of %s renamed to '%s' (collision strategy prefix-provider)", values[0].key, values[0].source, prefixed)
+		r.Origins[prefixed] = []SecretSource{values[0].source}
+		r.set(prefixed, values[0])
+	}
+	r.contributions = nil
+	return nil
+}
+
+// set makes c the value of key
+func (r *CollectionResult) set(key string, c contribution) {
+	r.Secrets[key] = c.value
+	r.Sources[key] = c.source
+}
+
+// providerPrefix returns the provider name as the prefix of an environment variable:
+// upper case with every other character than letters and digits replaced by an underscore
+func providerPrefix(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+}
@@ -0,0 +1,84 @@
+package providers
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/containifyci/feller/pkg/config"
+)
+
+//nolint:paralleltest // sets environment variables
+func TestCollisionStrategies(t *testing.T) {
+	t.Setenv("CI_TOKEN", "from_ci")
+	dir := t.TempDir()
+	for name, content := range map[string]string{"a.env": "TOKEN=from_a\nONLY_A=a\n", "b.env": "TOKEN=from_b\n"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatalf("Failed to write env file: %v", err)
+		}
+	}
+	providers := map[string]config.Provider{
+		"ci":      {Kind: KindGoogleSecretManager, Maps: []config.PathMap{{ID: "ci", Keys: map[string]string{"CI_TOKEN": "TOKEN"}}}},
+		"local-a": {Kind: KindDotenv, Maps: []config.PathMap{{ID: "a", Path: filepath.Join(dir, "a.env")}}},
+		"local-b": {Kind: KindDotenv, Maps: []config.PathMap{{ID: "b", Path: filepath.Join(dir, "b.env")}}},
+	}
+
+	tests := []struct {
+		strategy string
+		expected SecretMap
+		wantErr  string
+	}{
+		{strategy: "", expected: SecretMap{"TOKEN": "from_b", "ONLY_A": "a"}},
+		{strategy: CollisionLastWins, expected: SecretMap{"TOKEN": "from_b", "ONLY_A": "a"}},
+		{strategy: CollisionFirstWins, expected: SecretMap{"TOKEN": "from_ci", "ONLY_A": "a"}},
+		{
+			strategy: CollisionPrefixProvider,
+			expected: SecretMap{"CI_TOKEN": "from_ci", "LOCAL_A_TOKEN": "from_a", "LOCAL_B_TOKEN": "from_b", "ONLY_A": "a"},
+		},
+		{
+			strategy: CollisionError,
+			wantErr:  "keys supplied by more than one provider (collision strategy error): TOKEN from provider 'ci' (map 'ci'), provider 'local-a' (map 'a'), provider 'local-b' (map 'b')",
+		},
+		{strategy: "random", wantErr: `unknown collision strategy "random" (expected error, first-wins, last-wins, prefix-provider)`},
+	}
+	for _, tt := range tests {
+		cfg := &config.TellerConfig{Providers: providers, Collisions: tt.strategy}
+		result, err := CollectSecretsWithResult(cfg, false)
+		if tt.wantErr != "" {
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("CollectSecretsWithResult(%s) error = %v, expected %q", tt.strategy, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("CollectSecretsWithResult(%s) error = %v", tt.strategy, err)
+		}
+		if !reflect.DeepEqual(result.Secrets, tt.expected) {
+			t.Errorf("CollectSecretsWithResult(%s) = %v, want %v", tt.strategy, result.Secrets, tt.expected)
+		}
+		for key := range tt.expected {
+			if result.Sources[key].Provider == "" {
+				t.Errorf("CollectSecretsWithResult(%s) has no source of %s", tt.strategy, key)
+			}
+		}
+	}
+}
+
+func TestPrefixProviderTakenKey(t *testing.T) {
+	t.Parallel()
+	result := &CollectionResult{Secrets: SecretMap{}, Sources: map[string]SecretSource{}, Origins: map[string][]SecretSource{}}
+	result.add("a", config.Provider{}, map[string]string{"KEY": "1"}, nil)
+	result.add("b", config.Provider{}, map[string]string{"KEY": "2", "A_KEY": "3"}, nil)
+	var order []string
+	for _, c := range result.contributions {
+		order = append(order, c.source.Provider+"/"+c.key)
+	}
+	if expected := []string{"a/KEY", "b/A_KEY", "b/KEY"}; !reflect.DeepEqual(order, expected) {
+		t.Errorf("add() recorded %v, want %v", order, expected)
+	}
+	err := result.merge(CollisionPrefixProvider)
+	if err == nil || err.Error() != "cannot rename KEY of provider a to A_KEY: the key already exists" {
+		t.Errorf("merge() error = %v", err)
+	}
+}
@@ -0,0 +1,79 @@
+package providers
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/containifyci/feller/pkg/config"
+)
+
+// CheckDiscoveryFilter validates the include and exclude globs of a path map
+func CheckDiscoveryFilter(pathMap config.PathMap) error {
+	for _, pattern := range append(append([]string{}, pathMap.Include...), pathMap.Exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid glob %q in map %s: %w", pattern, pathMap.ID, err)
+		}
+	}
+	return nil
+}
+
+// checkDiscoveryFilters validates the globs of every map, so collecting can rely on them
+func checkDiscoveryFilters(cfg *config.TellerConfig) error {
+	for name, provider := range cfg.Providers {
+		for _, pathMap := range provider.Maps {
+			if err := CheckDiscoveryFilter(pathMap); err != nil {
+				return fmt.Errorf("provider %s: %w", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// discovers reports whether discovery of a map resolves key: it must match an include glob,
+// when there are any, and no exclude glob. Globs must have been checked.
+func discovers(pathMap config.PathMap, key string) bool {
+	included := len(pathMap.Include) == 0
+	for _, pattern := range pathMap.Include {
+		if ok, _ := path.Match(pattern, key); ok {
+			included = true
+			break
+		}
+	}
+	if !included {
+		return false
+	}
+	for _, pattern := range pathMap.Exclude {
+		if ok, _ := path.Match(pattern, key); ok {
+			return false
+		}
+	}
+	return true
+}
+
+// ListsGSMProject reports whether a Google Secret Manager map discovers its keys by listing
+// the secrets of a project: it has no keys and the path projects/PROJECT
+func ListsGSMProject(pathMap config.PathMap) bool {
+	return len(pathMap.Keys) == 0 && gsmProjectPattern.MatchString(strings.TrimSuffix(pathMap.Path, "/"))
+}
+
+// discoverGSMSecrets lists the secrets of the project of a map, page by page, and reads the
+// latest version of those passing its globs. Secrets without an enabled version are skipped.
+func discoverGSMSecrets(name string, provider config.Provider, pathMap config.PathMap, m *meter) (SecretMap, error) {
+	endpoint, err := endpointFor(provider)
+	if err != nil {
+		return nil, fmt.Errorf("provider %s: %w", name, err)
+	}
+	client := &gsmWriter{name: name, provider: provider, project: strings.TrimSuffix(pathMap.Path, "/"), endpoint: endpoint, meter: m}
+	names, err := client.list()
+	if err != nil {
+		return nil, err
+	}
+	discovered := make([]string, 0, len(names))
+	for _, key := range names {
+		if discovers(pathMap, key) {
+			discovered = append(discovered, key)
+		}
+	}
+	return client.Read(discovered)
+}
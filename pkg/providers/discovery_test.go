@@ -0,0 +1,191 @@
+package providers
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/containifyci/feller/pkg/config"
+)
+
+func TestDiscovers(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		pathMap  config.PathMap
+		key      string
+		expected bool
+	}{
+		{name: "no globs", key: "ANY", expected: true},
+		{name: "included", pathMap: config.PathMap{Include: []string{"APP_*"}}, key: "APP_TOKEN", expected: true},
+		{name: "not included", pathMap: config.PathMap{Include: []string{"APP_*"}}, key: "PATH", expected: false},
+		{name: "second include", pathMap: config.PathMap{Include: []string{"APP_*", "DB_?RL"}}, key: "DB_URL", expected: true},
+		{name: "excluded", pathMap: config.PathMap{Exclude: []string{"*_DEBUG"}}, key: "APP_DEBUG", expected: false},
+		{name: "exclude wins", pathMap: config.PathMap{Include: []string{"APP_*"}, Exclude: []string{"APP_[AB]*"}}, key: "APP_BETA", expected: false},
+		{name: "character class", pathMap: config.PathMap{Include: []string{"APP_[AB]*"}}, key: "APP_ALPHA", expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := discovers(tt.pathMap, tt.key); got != tt.expected {
+				t.Errorf("discovers(%+v, %q) = %v, expected %v", tt.pathMap, tt.key, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCheckDiscoveryFilter(t *testing.T) {
+	t.Parallel()
+	if err := CheckDiscoveryFilter(config.PathMap{ID: "ok", Include: []string{"A*"}, Exclude: []string{"A[BC]"}}); err != nil {
+		t.Errorf("CheckDiscoveryFilter() unexpected error = %v", err)
+	}
+	err := CheckDiscoveryFilter(config.PathMap{ID: "bad", Exclude: []string{"A["}})
+	if err == nil || !strings.Contains(err.Error(), `invalid glob "A[" in map bad`) {
+		t.Errorf("CheckDiscoveryFilter() error = %v", err)
+	}
+}
+
+func TestCollectDotenvDiscoveryFilter(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(path, []byte("APP_TOKEN=a\nAPP_DEBUG=1\nOTHER=b\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	provider := config.Provider{Kind: KindDotenv, Maps: []config.PathMap{{ID: "app", Path: path, Include: []string{"APP_*"}, Exclude: []string{"*_DEBUG"}}}}
+
+	secrets, _, err := collectDotenvSecrets(provider)
+	if err != nil {
+		t.Fatalf("collectDotenvSecrets() error = %v", err)
+	}
+	if expected := (SecretMap{"APP_TOKEN": "a"}); !reflect.DeepEqual(secrets, expected) {
+		t.Errorf("collectDotenvSecrets() = %v, expected %v", secrets, expected)
+	}
+}
+
+//nolint:paralleltest // Modifies environment variables
+func TestCollectGSMDiscovery(t *testing.T) {
+	t.Setenv("FELLER_DISCOVERY_TOKEN", "abc")
+	t.Setenv("FELLER_DISCOVERY_EMPTY", "")
+	t.Setenv("FELLER_DISCOVERY_SKIP", "x")
+
+	provider := config.Provider{Kind: KindGoogleSecretManager, Maps: []config.PathMap{
+		{ID: "discovered", Include: []string{"FELLER_DISCOVERY_*"}, Exclude: []string{"*_SKIP"}},
+		{ID: "unfiltered"},
+	}}
+	secrets, mapIDs, missing, err := collectGSMSecretsWithMissing(provider, "gsm", false, nil)
+	if err != nil {
+		t.Fatalf("collectGSMSecretsWithMissing() error = %v", err)
+	}
+	if expected := (SecretMap{"FELLER_DISCOVERY_TOKEN": "abc"}); !reflect.DeepEqual(secrets, expected) {
+		t.Errorf("collectGSMSecretsWithMissing() = %v, expected %v", secrets, expected)
+	}
+	if mapIDs["FELLER_DISCOVERY_TOKEN"] != "discovered" || len(missing) != 0 {
+		t.Errorf("collectGSMSecretsWithMissing() map ids = %v, missing = %v", mapIDs, missing)
+	}
+
+	cfg := &config.TellerConfig{Providers: map[string]config.Provider{"gsm": {Kind: KindGoogleSecretManager, Maps: []config.PathMap{{ID: "bad", Include: []string{"["}}}}}}
+	if _, err := CollectSecretsWithResult(cfg, false); err == nil || !strings.Contains(err.Error(), "provider gsm: invalid glob") {
+		t.Errorf("CollectSecretsWithResult() error = %v, expected an invalid glob", err)
+	}
+}
+
+//nolint:paralleltest // Replaces the Secret Manager endpoint and sets environment variables
+func TestCollectGSMProjectDiscovery(t *testing.T) {
+	requests := fakeGSM(t, map[string]string{"APP_A": "a", "APP_B": "b", "APP_DEBUG": "1", "OTHER": "o", "APP_C": "c"})
+	t.Setenv(gsmTokenEnv, "t0k3n")
+
+	provider := config.Provider{Kind: KindGoogleSecretManager, Maps: []config.PathMap{
+		{ID: "listed", Path: "projects/demo", Include: []string{"APP_*"}, Exclude: []string{"*_DEBUG"}},
+	}}
+	if !ListsGSMProject(provider.Maps[0]) {
+		t.Fatal("ListsGSMProject() = false for a map of a project without keys")
+	}
+	m := &meter{}
+	secrets, mapIDs, missing, err := collectGSMSecretsWithMissing(provider, "gsm", false, m)
+	if err != nil {
+		t.Fatalf("collectGSMSecretsWithMissing() error = %v", err)
+	}
+	if expected := (SecretMap{"APP_A": "a", "APP_B": "b", "APP_C": "c"}); !reflect.DeepEqual(secrets, expected) {
+		t.Errorf("collectGSMSecretsWithMissing() = %v, expected %v", secrets, expected)
+	}
+	if mapIDs["APP_C"] != "listed" || len(missing) != 0 {
+		t.Errorf("collectGSMSecretsWithMissing() map ids = %v, missing = %v", mapIDs, missing)
+	}
+
+	// The fake serves two secrets per page, so five secrets take three list requests
+	var lists []string
+	for _, request := range *requests {
+		if strings.HasPrefix(request, "GET /v1/projects/demo/secrets?") {
+			lists = append(lists, request)
+		}
+	}
+	expected := []string{
+		"GET /v1/projects/demo/secrets?pageSize=250",
+		"GET /v1/projects/demo/secrets?pageSize=250&pageToken=2",
+		"GET /v1/projects/demo/secrets?pageSize=250&pageToken=4",
+	}
+	if !reflect.DeepEqual(lists, expected) {
+		t.Errorf("list requests = %v, expected %v", lists, expected)
+	}
+	if m.timing.Requests != len(*requests) {
+		t.Errorf("metered %d requests, expected %d", m.timing.Requests, len(*requests))
+	}
+
+	t.Setenv(gsmTokenEnv, "expired")
+	if _, _, _, err := collectGSMSecretsWithMissing(provider, "gsm", false, nil); err == nil || !strings.Contains(err.Error(), "provider gsm: failed to list the secrets of projects/demo: 401 Unauthorized") {
+		t.Errorf("collectGSMSecretsWithMissing() with an expired token error = %v", err)
+	}
+}
+
+//nolint:paralleltest // Replaces the Secret Manager endpoint and timeout and sets environment variables
+func TestCollectGSMProjectDiscoveryDeadlines(t *testing.T) {
+	secrets := make(map[string]string)
+	for i := range gsmPageSize + 50 {
+		secrets[fmt.Sprintf("APP_%03d", i)] = strconv.Itoa(i)
+	}
+	fakeGSM(t, secrets)
+	t.Setenv(gsmTokenEnv, "t0k3n")
+	timeout := gsmTimeout
+	gsmTimeout = 100 * time.Millisecond
+	t.Cleanup(func() { gsmTimeout = timeout })
+
+	// Spaced out by a millisecond, the requests take far longer than one timeout together
+	m := &meter{limiter: NewLimiter(RateLimit{RequestsPerSecond: 1000, Burst: 1})}
+	provider := config.Provider{Kind: KindGoogleSecretManager, Maps: []config.PathMap{{ID: "listed", Path: "projects/demo"}}}
+	discovered, _, _, err := collectGSMSecretsWithMissing(provider, "gsm", false, m)
+	if err != nil {
+		t.Fatalf("collectGSMSecretsWithMissing() error = %v", err)
+	}
+	if len(discovered) != len(secrets) || discovered["APP_299"] != "299" {
+		t.Errorf("collectGSMSecretsWithMissing() discovered %d secrets, expected %d", len(discovered), len(secrets))
+	}
+}
+
+//nolint:paralleltest // Replaces the Secret Manager endpoint and sets environment variables
+func TestCollectGSMProjectDiscoveryOnCI(t *testing.T) {
+	requests := fakeGSM(t, map[string]string{"APP_A": "a"})
+	t.Setenv(gsmTokenEnv, "t0k3n")
+	t.Setenv("APP_B", "b")
+
+	// CI passes secrets through the environment, so project maps discover variables like others
+	provider := config.Provider{Kind: KindGoogleSecretManager, Maps: []config.PathMap{
+		{ID: "listed", Path: "projects/demo", Include: []string{"APP_*"}},
+		{ID: "unfiltered", Path: "projects/demo"},
+	}}
+	secrets, _, _, err := collectGSMSecretsWithMissing(provider, "gsm", true, nil)
+	if err != nil {
+		t.Fatalf("collectGSMSecretsWithMissing() error = %v", err)
+	}
+	if expected := (SecretMap{"APP_B": "b"}); !reflect.DeepEqual(secrets, expected) {
+		t.Errorf("collectGSMSecretsWithMissing() = %v, expected %v", secrets, expected)
+	}
+	if len(*requests) != 0 {
+		t.Errorf("collectGSMSecretsWithMissing() on CI sent %v, expected no requests", *requests)
+	}
+}
@@ -0,0 +1,105 @@
+package providers
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+var (
+	bomUTF8    = []byte{0xEF, 0xBB, 0xBF}
+	bomUTF16LE = []byte{0xFF, 0xFE}
+	bomUTF16BE = []byte{0xFE, 0xFF}
+)
+
+// decodeText returns the content of a text file as UTF-8 with LF line endings. Files saved
+// by Windows editors often carry a byte order mark, CRLF line endings, or are UTF-16 encoded.
+// Text that is not valid UTF-8 either is read as Latin-1, the legacy encoding of most such
+// files, since every byte sequence is valid Latin-1.
+func decodeText(data []byte) (string, error) {
+	var text string
+	switch {
+	case bytes.HasPrefix(data, bomUTF8):
+		text = string(data[len(bomUTF8):])
+	case bytes.HasPrefix(data, bomUTF16LE):
+		decoded, err := decodeUTF16(data[len(bomUTF16LE):], binary.LittleEndian)
+		if err != nil {
+			return "", err
+		}
+		text = decoded
+	case bytes.HasPrefix(data, bomUTF16BE):
+		decoded, err := decodeUTF16(data[len(bomUTF16BE):], binary.BigEndian)
+		if err != nil {
+			return "", err
+		}
+		text = decoded
+	default:
+		if order := detectUTF16(data); order != nil {
+			decoded, err := decodeUTF16(data, order)
+			if err != nil {
+				return "", err
+			}
+			text = decoded
+		} else {
+			text = string(data)
+		}
+	}
+
+	if !utf8.ValidString(text) {
+		text = decodeLatin1([]byte(text))
+	}
+	return strings.ReplaceAll(text, "\r\n", "\n"), nil
+}
+
+// decodeLatin1 decodes ISO 8859-1, whose bytes are the first 256 Unicode code points
+func decodeLatin1(data []byte) string {
+	runes := make([]rune, len(data))
+	for i, b := range data {
+		runes[i] = rune(b)
+	}
+	return string(runes)
+}
+
+func decodeUTF16(data []byte, order binary.ByteOrder) (string, error) {
+	if len(data)%2 != 0 {
+		return "", errors.New("UTF-16 file has an odd number of bytes")
+	}
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		units[i] = order.Uint16(data[2*i:])
+	}
+	return string(utf16.Decode(units)), nil
+}
+
+// detectUTF16 recognizes UTF-16 without a byte order mark by the zero byte that every ASCII
+// character leaves in the high half of its code unit. Env files are mostly ASCII, so the
+// first code units are a reliable sample.
+func detectUTF16(data []byte) binary.ByteOrder {
+	const sample = 16
+	if len(data) < 2 || len(data)%2 != 0 {
+		return nil
+	}
+
+	evenZeros, oddZeros, units := 0, 0, 0
+	for i := 0; i+1 < len(data) && units < sample; i += 2 {
+		units++
+		if data[i] == 0 {
+			evenZeros++
+		}
+		if data[i+1] == 0 {
+			oddZeros++
+		}
+	}
+
+	switch {
+	case oddZeros == units && evenZeros == 0:
+		return binary.LittleEndian
+	case evenZeros == units && oddZeros == 0:
+		return binary.BigEndian
+	default:
+		return nil
+	}
+}
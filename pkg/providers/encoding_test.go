@@ -0,0 +1,63 @@
+package providers
+
+import (
+	"encoding/binary"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+// encodeUTF16 encodes text as UTF-16 in the given byte order, without a byte order mark
+func encodeUTF16(text string, order binary.AppendByteOrder) string {
+	var data []byte
+	for _, unit := range utf16.Encode([]rune(text)) {
+		data = order.AppendUint16(data, unit)
+	}
+	return string(data)
+}
+
+func TestDecodeText(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name        string
+		input       string
+		expected    string
+		errContains string
+		wantErr     bool
+	}{
+		{name: "plain utf-8", input: "KEY=value\n", expected: "KEY=value\n"},
+		{name: "crlf line endings", input: "A=1\r\nB=2\r\n", expected: "A=1\nB=2\n"},
+		{name: "utf-8 bom", input: "\xEF\xBB\xBFKEY=välue\r\n", expected: "KEY=välue\n"},
+		{name: "utf-16 le bom", input: "\xFF\xFE" + encodeUTF16("KEY=välue\r\n", binary.LittleEndian), expected: "KEY=välue\n"},
+		{name: "utf-16 be bom", input: "\xFE\xFF" + encodeUTF16("KEY=välue\r\n", binary.BigEndian), expected: "KEY=välue\n"},
+		{name: "utf-16 le without bom", input: encodeUTF16("KEY=value\n", binary.LittleEndian), expected: "KEY=value\n"},
+		{name: "utf-16 be without bom", input: encodeUTF16("KEY=value\n", binary.BigEndian), expected: "KEY=value\n"},
+		{name: "empty", input: "", expected: ""},
+		{name: "odd length utf-16", input: "\xFF\xFEK\x00E", wantErr: true, errContains: "odd number of bytes"},
+		{name: "latin-1", input: "KEY=v\xE4lue\r\n", expected: "KEY=välue\n"},
+		{name: "latin-1 after utf-8 bom", input: "\xEF\xBB\xBFKEY=\xFF", expected: "KEY=ÿ"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			result, err := decodeText([]byte(tt.input))
+
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("decodeText() expected error but got none")
+				} else if !strings.Contains(err.Error(), tt.errContains) {
+					t.Errorf("decodeText() error = %v, expected to contain %q", err, tt.errContains)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("decodeText() unexpected error = %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("decodeText() = %q, want %q", result, tt.expected)
+			}
+		})
+	}
+}
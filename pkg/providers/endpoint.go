@@ -0,0 +1,46 @@
+package providers
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/containifyci/feller/pkg/config"
+)
+
+// endpointOptions are the provider options overriding the API endpoint of a provider, e.g.
+// a private endpoint or an emulator
+type endpointOptions struct {
+	Endpoint string `yaml:"endpoint"`
+}
+
+// CheckEndpoint checks the endpoint option of a provider
+func CheckEndpoint(provider config.Provider) error {
+	_, err := endpointFor(provider)
+	return err
+}
+
+// endpointFor returns the endpoint option of a provider, the base URL of its API, or "" when
+// it has none
+func endpointFor(provider config.Provider) (string, error) {
+	if provider.Options.IsZero() {
+		return "", nil
+	}
+	var opts endpointOptions
+	if err := provider.Options.Decode(&opts); err != nil {
+		return "", fmt.Errorf("invalid endpoint: %w", err)
+	}
+	if opts.Endpoint == "" {
+		return "", nil
+	}
+	switch provider.Kind {
+	case KindHashiCorpVault:
+		return "", fmt.Errorf("%s providers take their endpoint in the address option", provider.Kind)
+	case KindDotenv, KindBundle, KindGitHub:
+		return "", fmt.Errorf("%s providers have no endpoint", provider.Kind)
+	}
+	endpoint, err := url.Parse(opts.Endpoint)
+	if err != nil || (endpoint.Scheme != "https" && endpoint.Scheme != "http") || endpoint.Host == "" {
+		return "", fmt.Errorf("endpoint %q must be an http or https URL, e.g. http://localhost:8085", opts.Endpoint)
+	}
+	return opts.Endpoint, nil
+}
@@ -0,0 +1,35 @@
+package providers
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckEndpoint(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		kind    string
+		options string
+		wantErr string
+	}{
+		{kind: KindGoogleSecretManager, options: "{}"},
+		{kind: KindGoogleSecretManager, options: "{endpoint: 'https://secretmanager.private.example'}"},
+		{kind: "aws_ssm", options: "{endpoint: 'http://localhost:4566'}"},
+		{kind: KindHashiCorpVault, options: "{address: 'http://127.0.0.1:8200'}"},
+		{kind: KindHashiCorpVault, options: "{endpoint: 'http://127.0.0.1:8200'}", wantErr: "hashicorp_vault providers take their endpoint in the address option"},
+		{kind: KindDotenv, options: "{endpoint: 'http://localhost'}", wantErr: "dotenv providers have no endpoint"},
+		{kind: KindGoogleSecretManager, options: "{endpoint: 'localhost:8085'}", wantErr: `endpoint "localhost:8085" must be an http or https URL, e.g. http://localhost:8085`},
+		{kind: KindGoogleSecretManager, options: "{endpoint: [a]}", wantErr: "invalid endpoint"},
+	}
+	for _, tt := range tests {
+		provider := githubProvider(t, tt.options)
+		provider.Kind = tt.kind
+		err := CheckEndpoint(provider)
+		if tt.wantErr == "" && err != nil {
+			t.Errorf("CheckEndpoint(%s %s) error = %v", tt.kind, tt.options, err)
+		}
+		if tt.wantErr != "" && (err == nil || !strings.HasPrefix(err.Error(), tt.wantErr)) {
+			t.Errorf("CheckEndpoint(%s %s) error = %v, expected %q", tt.kind, tt.options, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package providers
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func FuzzParseEnvFile(f *testing.F) {
+	for _, seed := range []string{
+		"KEY=value\n",
+		"A=1\r\nB=\"two\"\r\n# comment\r\n",
+		"\xEF\xBB\xBFKEY='single'\n",
+		"\xFF\xFEK\x00=\x001\x00",
+		"=value\nexport KEY=1\nKEY\n",
+		"KEY=\x00\n",
+		"KEY=\xFF\n",
+	} {
+		f.Add([]byte(seed))
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		env, err := parseEnvFile(data)
+		if err != nil {
+			return
+		}
+		for key, value := range env {
+			if key == "" || strings.ContainsFunc(key, isInvalidKeyRune) || strings.Contains(key, "=") {
+				t.Errorf("parseEnvFile() produced invalid key %q", key)
+			}
+			if strings.ContainsAny(value, "\x00\n") || !utf8.ValidString(value) {
+				t.Errorf("parseEnvFile() produced invalid value %q for %s", value, key)
+			}
+		}
+	})
+}
+
+func FuzzParseEnvFileRoundTrip(f *testing.F) {
+	f.Add("KEY", "value")
+	f.Add("DATABASE_URL", `postgres://u:"p"@h/db`)
+	f.Add("PADDED", "  spaced  ")
+	f.Add("Q", `'`)
+
+	f.Fuzz(func(t *testing.T, key, value string) {
+		if key == "" || strings.ContainsAny(key, "=#") || strings.ContainsFunc(key, isInvalidKeyRune) ||
+			strings.ContainsAny(value, "\x00\r\n") || !utf8.ValidString(key) || !utf8.ValidString(value) {
+			t.Skip("not representable in a dotenv file")
+		}
+
+		// Values written between double quotes must come back exactly
+		env, err := parseEnvFile([]byte(key + `="` + value + "\"\n"))
+		if err != nil {
+			t.Fatalf("parseEnvFile() unexpected error = %v", err)
+		}
+		if got, ok := env[key]; !ok || got != value {
+			t.Errorf("parseEnvFile() %s = %q, want %q", key, got, value)
+		}
+	})
+}
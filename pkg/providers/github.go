@@ -0,0 +1,94 @@
+package providers
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/containifyci/feller/pkg/config"
+	"github.com/containifyci/feller/pkg/logger"
+)
+
+// githubPrefixPattern matches the prefixes of environment variable names github providers accept
+var githubPrefixPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// githubOptions are the options of a github provider
+type githubOptions struct {
+	Prefix      string `yaml:"prefix"`       // Variables discovered by maps without keys, e.g. SECRET_
+	StripPrefix bool   `yaml:"strip_prefix"` // Remove the prefix from the names of discovered variables
+}
+
+// CheckGitHubOptions validates the options of a github provider
+func CheckGitHubOptions(provider config.Provider) error {
+	_, err := decodeGitHubOptions(provider)
+	return err
+}
+
+// decodeGitHubOptions decodes and checks the options of a github provider
+func decodeGitHubOptions(provider config.Provider) (githubOptions, error) {
+	var opts githubOptions
+	if !provider.Options.IsZero() {
+		if err := provider.Options.Decode(&opts); err != nil {
+			return opts, fmt.Errorf("invalid github options: %w", err)
+		}
+	}
+	if opts.Prefix != "" && !githubPrefixPattern.MatchString(opts.Prefix) {
+		return opts, fmt.Errorf("prefix %q must start an environment variable name: letters, digits and underscores", opts.Prefix)
+	}
+	if opts.StripPrefix && opts.Prefix == "" {
+		return opts, errors.New("strip_prefix needs a prefix")
+	}
+	return opts, nil
+}
+
+// collectGitHubSecrets reads the secrets a GitHub Actions workflow passes in as environment
+// variables. Maps with keys read the named variables and report the unset ones as missing;
+// maps without keys discover the variables with the prefix, optionally stripping it.
+func collectGitHubSecrets(name string, provider config.Provider) (SecretMap, map[string]string, []MissingVariable, error) {
+	opts, err := decodeGitHubOptions(provider)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("provider %s: %w", name, err)
+	}
+	secrets := make(SecretMap)
+	mapIDs := make(map[string]string)
+	var missingVars []MissingVariable
+
+	for _, pathMap := range provider.Maps {
+		if len(pathMap.Keys) > 0 {
+			for fromKey, toKey := range pathMap.Keys {
+				if value := os.Getenv(fromKey); value != "" {
+					secrets[toKey] = value
+					mapIDs[toKey] = pathMap.ID
+					logger.Debug("Found env var '%s' with value '%s', mapped to key '%s'", fromKey, maskSecret(value), toKey)
+					continue
+				}
+				missingVars = append(missingVars, MissingVariable{VariableName: fromKey, MappedTo: toKey, Provider: name})
+			}
+			continue
+		}
+
+		// Discovery over the whole environment would pick up PATH and the like
+		if opts.Prefix == "" && len(pathMap.Include) == 0 {
+			return nil, nil, nil, fmt.Errorf("provider %s: map %s has no keys, set the prefix option or include globs to choose the variables it discovers", name, pathMap.ID)
+		}
+		for _, entry := range os.Environ() {
+			variable, value, _ := strings.Cut(entry, "=")
+			if value == "" || !strings.HasPrefix(variable, opts.Prefix) || variable == opts.Prefix {
+				continue
+			}
+			key := variable
+			if opts.StripPrefix {
+				key = strings.TrimPrefix(variable, opts.Prefix)
+			}
+			if !discovers(pathMap, key) {
+				continue
+			}
+			secrets[key] = value
+			mapIDs[key] = pathMap.ID
+			logger.Debug("Discovered env var '%s' with value '%s' as key '%s'", variable, maskSecret(value), key)
+		}
+	}
+	return secrets, mapIDs, missingVars, nil
+}
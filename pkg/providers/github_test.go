@@ -0,0 +1,159 @@
+package providers
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/containifyci/feller/pkg/config"
+	"gopkg.in/yaml.v3"
+)
+
+// githubProvider returns a github provider with the options in YAML
+func githubProvider(t *testing.T, options string, maps ...config.PathMap) config.Provider {
+	t.Helper()
+	var node yaml.Node
+	if err := yaml.Unmarshal([]byte(options), &node); err != nil {
+		t.Fatal(err)
+	}
+	return config.Provider{Kind: KindGitHub, Maps: maps, Options: *node.Content[0]}
+}
+
+//nolint:paralleltest // sets environment variables
+func TestCollectGitHubSecrets(t *testing.T) {
+	t.Setenv("SECRET_API_KEY", "abc")
+	t.Setenv("SECRET_DB_PASSWORD", "pw")
+	t.Setenv("SECRET_EMPTY", "")
+	t.Setenv("SECRET_", "prefix only")
+	t.Setenv("DEPLOY_TOKEN", "tok")
+
+	tests := []struct {
+		name     string
+		options  string
+		maps     []config.PathMap
+		expected SecretMap
+		missing  []string
+		wantErr  string
+	}{
+		{
+			name:     "keys",
+			options:  "{}",
+			maps:     []config.PathMap{{ID: "ci", Keys: map[string]string{"DEPLOY_TOKEN": "TOKEN", "NOT_SET": "NOT_SET", "SECRET_EMPTY": "EMPTY"}}},
+			expected: SecretMap{"TOKEN": "tok"},
+			missing:  []string{"NOT_SET", "SECRET_EMPTY"},
+		},
+		{
+			name:     "prefix",
+			options:  "{prefix: SECRET_}",
+			maps:     []config.PathMap{{ID: "ci"}},
+			expected: SecretMap{"SECRET_API_KEY": "abc", "SECRET_DB_PASSWORD": "pw"},
+		},
+		{
+			name:     "stripped prefix",
+			options:  "{prefix: SECRET_, strip_prefix: true}",
+			maps:     []config.PathMap{{ID: "ci"}},
+			expected: SecretMap{"API_KEY": "abc", "DB_PASSWORD": "pw"},
+		},
+		{
+			name:     "include and exclude after stripping",
+			options:  "{prefix: SECRET_, strip_prefix: true}",
+			maps:     []config.PathMap{{ID: "ci", Include: []string{"*_KEY", "DB_*"}, Exclude: []string{"DB_*"}}},
+			expected: SecretMap{"API_KEY": "abc"},
+		},
+		{
+			name:     "include without prefix",
+			options:  "{}",
+			maps:     []config.PathMap{{ID: "ci", Include: []string{"DEPLOY_*"}}},
+			expected: SecretMap{"DEPLOY_TOKEN": "tok"},
+		},
+		{
+			name:    "discovery without prefix",
+			options: "{}",
+			maps:    []config.PathMap{{ID: "ci"}},
+			wantErr: "provider gh: map ci has no keys, set the prefix option or include globs to choose the variables it discovers",
+		},
+		{
+			name:    "invalid options",
+			options: "{strip_prefix: true}",
+			maps:    []config.PathMap{{ID: "ci"}},
+			wantErr: "provider gh: strip_prefix needs a prefix",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			secrets, mapIDs, missing, err := collectGitHubSecrets("gh", githubProvider(t, tt.options, tt.maps...))
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("collectGitHubSecrets() error = %v, expected %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("collectGitHubSecrets() error = %v", err)
+			}
+			if len(secrets) != len(tt.expected) {
+				t.Errorf("collectGitHubSecrets() = %v, expected %v", secrets, tt.expected)
+			}
+			for key, value := range tt.expected {
+				if secrets[key] != value || mapIDs[key] != "ci" {
+					t.Errorf("collectGitHubSecrets()[%s] = %q from %q, expected %q from ci", key, secrets[key], mapIDs[key], value)
+				}
+			}
+			names := make([]string, 0, len(missing))
+			for _, v := range missing {
+				names = append(names, v.VariableName)
+				if v.Provider != "gh" {
+					t.Errorf("missing variable %s has provider %q, expected gh", v.VariableName, v.Provider)
+				}
+			}
+			sort.Strings(names)
+			if len(names) != len(tt.missing) {
+				t.Fatalf("collectGitHubSecrets() missing = %v, expected %v", names, tt.missing)
+			}
+			for i := range names {
+				if names[i] != tt.missing[i] {
+					t.Errorf("collectGitHubSecrets() missing = %v, expected %v", names, tt.missing)
+				}
+			}
+		})
+	}
+}
+
+//nolint:paralleltest // sets environment variables
+func TestCollectSecretsWithGitHub(t *testing.T) {
+	t.Setenv("SECRET_API_KEY", "abc")
+	provider := githubProvider(t, "{prefix: SECRET_, strip_prefix: true}", config.PathMap{ID: "ci"})
+	cfg := &config.TellerConfig{Providers: map[string]config.Provider{"actions": provider}}
+
+	result, err := CollectSecretsWithResult(cfg, false)
+	if err != nil {
+		t.Fatalf("CollectSecretsWithResult() error = %v", err)
+	}
+	if result.Secrets["API_KEY"] != "abc" || result.Sources["API_KEY"].Kind != KindGitHub {
+		t.Errorf("CollectSecretsWithResult() = %v from %v", result.Secrets, result.Sources)
+	}
+}
+
+func TestCheckGitHubOptions(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		options string
+		wantErr string
+	}{
+		{options: "{}"},
+		{options: "{prefix: SECRET_}"},
+		{options: "{prefix: _S, strip_prefix: true}"},
+		{options: "{prefix: 1SECRET}", wantErr: `prefix "1SECRET" must start an environment variable name: letters, digits and underscores`},
+		{options: "{prefix: SECRET-}", wantErr: `prefix "SECRET-" must start an environment variable name: letters, digits and underscores`},
+		{options: "{strip_prefix: true}", wantErr: "strip_prefix needs a prefix"},
+	}
+	for _, tt := range tests {
+		err := CheckGitHubOptions(githubProvider(t, tt.options))
+		if tt.wantErr == "" && err != nil {
+			t.Errorf("CheckGitHubOptions(%s) error = %v", tt.options, err)
+		}
+		if tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr) {
+			t.Errorf("CheckGitHubOptions(%s) error = %v, expected %q", tt.options, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package providers
+
+import "sort"
+
+// Supported provider kinds
+const (
+	KindGoogleSecretManager = "google_secretmanager"
+	KindDotenv              = "dotenv"
+	KindBundle              = "bundle"
+	KindHashiCorpVault      = "hashicorp_vault"
+	KindGitHub              = "github"
+)
+
+// Capabilities describes what feller can do with a provider kind
+type Capabilities struct {
+	Read      bool `json:"read"`
+	Write     bool `json:"write"`
+	Discovery bool `json:"discovery"` // Maps without keys resolve every available key
+}
+
+// KindInfo describes a supported provider kind
+type KindInfo struct {
+	Kind              string       `json:"kind"`
+	Description       string       `json:"description"`
+	AuthMethods       []string     `json:"auth_methods"`
+	RequiredOptions   []string     `json:"required_options"`
+	RequiredMapFields []string     `json:"required_map_fields"`
+	Capabilities      Capabilities `json:"capabilities"`
+}
+
+var kinds = map[string]KindInfo{
+	KindGoogleSecretManager: {
+		Kind:              KindGoogleSecretManager,
+		Description:       "Reads secrets from environment variables populated by the GitHub Actions workflow, or lists the secrets of a project",
+		Capabilities:      Capabilities{Read: true, Write: true, Discovery: true},
+		AuthMethods:       []string{"environment"},
+		RequiredOptions:   []string{},
+		RequiredMapFields: []string{"id", "keys"},
+	},
+	KindDotenv: {
+		Kind:              KindDotenv,
+		Description:       "Reads secrets from a local .env file",
+		Capabilities:      Capabilities{Read: true, Write: true, Discovery: true},
+		AuthMethods:       []string{"none"},
+		RequiredOptions:   []string{},
+		RequiredMapFields: []string{"id", "path"},
+	},
+	KindBundle: {
+		Kind:              KindBundle,
+		Description:       "Reads secrets from an age-encrypted bundle written by feller export bundle",
+		Capabilities:      Capabilities{Read: true, Write: true, Discovery: true},
+		AuthMethods:       []string{"age-identity"},
+		RequiredOptions:   []string{},
+		RequiredMapFields: []string{"id", "path"},
+	},
+	KindGitHub: {
+		Kind:              KindGitHub,
+		Description:       "Passes through the secrets a GitHub Actions workflow sets as environment variables",
+		Capabilities:      Capabilities{Read: true, Discovery: true},
+		AuthMethods:       []string{"environment"},
+		RequiredOptions:   []string{},
+		RequiredMapFields: []string{"id"},
+	},
+	KindHashiCorpVault: {
+		Kind:              KindHashiCorpVault,
+		Description:       "Reads KV version 1 or 2 secrets from HashiCorp Vault",
+		Capabilities:      Capabilities{Read: true, Write: true, Discovery: true},
+		AuthMethods:       vaultAuthMethods,
+		RequiredOptions:   []string{},
+		RequiredMapFields: []string{"id", "path"},
+	},
+}
+
+// Kinds returns every supported provider kind sorted by name
+func Kinds() []KindInfo {
+	infos := make([]KindInfo, 0, len(kinds))
+	for _, info := range kinds {
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Kind < infos[j].Kind })
+	return infos
+}
+
+// LookupKind returns the description of a provider kind, including plugin/NAME kinds
+// whether or not their plugin is installed
+func LookupKind(kind string) (KindInfo, bool) {
+	if name, ok := PluginName(kind); ok {
+		return pluginKindInfo(kind, name), true
+	}
+	info, ok := kinds[kind]
+	return info, ok
+}
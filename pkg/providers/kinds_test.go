@@ -0,0 +1,52 @@
+package providers
+
+import (
+	"testing"
+)
+
+func TestKinds(t *testing.T) {
+	t.Parallel()
+	infos := Kinds()
+
+	if len(infos) != 5 {
+		t.Fatalf("Kinds() returned %d kinds, want 5", len(infos))
+	}
+	for i := 1; i < len(infos); i++ {
+		if infos[i-1].Kind >= infos[i].Kind {
+			t.Errorf("Kinds() not sorted: %s before %s", infos[i-1].Kind, infos[i].Kind)
+		}
+	}
+	for _, info := range infos {
+		if info.AuthMethods == nil || info.RequiredOptions == nil || info.RequiredMapFields == nil {
+			t.Errorf("Kinds() %s has nil lists, which encode as null in JSON", info.Kind)
+		}
+	}
+}
+
+func TestLookupKind(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name          string
+		kind          string
+		wantFound     bool
+		wantDiscovery bool
+	}{
+		{name: "dotenv supports discovery", kind: KindDotenv, wantFound: true, wantDiscovery: true},
+		{name: "gsm supports discovery", kind: KindGoogleSecretManager, wantFound: true, wantDiscovery: true},
+		{name: "vault supports discovery", kind: KindHashiCorpVault, wantFound: true, wantDiscovery: true},
+		{name: "unknown kind", kind: "aws_secretsmanager", wantFound: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			info, found := LookupKind(tt.kind)
+			if found != tt.wantFound {
+				t.Fatalf("LookupKind(%q) found = %v, want %v", tt.kind, found, tt.wantFound)
+			}
+			if found && info.Capabilities.Discovery != tt.wantDiscovery {
+				t.Errorf("LookupKind(%q) discovery = %v, want %v", tt.kind, info.Capabilities.Discovery, tt.wantDiscovery)
+			}
+		})
+	}
+}
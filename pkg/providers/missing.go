@@ -0,0 +1,142 @@
+package providers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/containifyci/feller/pkg/ci"
+	"github.com/containifyci/feller/pkg/i18n"
+)
+
+// DefaultMissingTemplate renders the missing environment variable error of feller commands,
+// with instructions for the CI platform in the selected locale
+const DefaultMissingTemplate = `{{.Prefix}}{{t "Missing %d required environment variable(s) in %s:" .Count .Platform.Name}}
+
+{{range .Providers}}Provider '{{.Name}}':
+{{range .Variables}}  {{if $.Plain}}-{{else}}•{{end}} {{.VariableName}} ({{t "maps to: %s" .MappedTo}})
+{{end}}
+{{end}}{{if eq .Platform.ID "gitlab"}}{{t "To fix this, add them as masked variables in the CI/CD settings of the project or group:"}}
+
+{{range .EnvNames}}  - {{.}}
+{{end}}{{else if eq .Platform.ID "circleci"}}{{t "To fix this, add them to the project's environment variables or to a context the job uses:"}}
+
+{{range .EnvNames}}  - {{.}}
+{{end}}{{else if eq .Platform.ID "buildkite"}}{{t "To fix this, export them from an agent environment hook or a secrets plugin of the step:"}}
+
+{{range .EnvNames}}  - {{.}}
+{{end}}{{else if eq .Platform.ID "jenkins"}}{{t "To fix this, bind them from Jenkins credentials in your pipeline:"}}
+
+{{if not .Plain}}` + "```groovy" + `
+{{end}}environment {
+{{range .EnvNames}}    {{.}} = credentials('{{.}}')
+{{end}}}
+{{if not .Plain}}` + "```" + `
+{{end}}{{else}}{{t "To fix this, add the missing environment variables to your GitHub Actions workflow:"}}
+
+{{if not .Plain}}` + "```yaml" + `
+{{end}}- name: {{.Step}}
+  env:
+{{range .EnvNames}}    {{.}}: {{secretRef .}}
+{{end}}  run: {{.Run}}
+{{if not .Plain}}` + "```" + `
+{{end}}{{end}}
+{{.Hint}}`
+
+// MissingContext describes the command that reports missing variables
+type MissingContext struct {
+	Command string // feller command, e.g. export
+	Prefix  string // Text before the default message, e.g. "Cannot export: "
+	Step    string // Workflow step name of the suggested fix
+	Run     string // Command of the suggested workflow step
+	Hint    string // Closing advice of the default message
+	Plain   bool   // Plain output: no bullets or Markdown code fences
+
+	Platform ci.Platform // CI platform the command runs on, GitHub Actions when unset
+}
+
+// MissingProviderGroup holds the missing variables of one provider
+type MissingProviderGroup struct {
+	Name      string
+	Variables []MissingVariable
+}
+
+// MissingMessage is the data missing variable templates are executed with
+type MissingMessage struct {
+	MissingContext
+	Count     int
+	Providers []MissingProviderGroup // Sorted by name, variables sorted by name
+	Variables []MissingVariable      // Every missing variable sorted by name and provider
+	EnvNames  []string               // Distinct environment variable names, sorted
+}
+
+var missingFuncs = template.FuncMap{
+	// secretRef returns the workflow expression reading a repository secret
+	"secretRef": func(name string) string { return "${{ secrets." + name + " }}" },
+	// t formats the translation of an English message
+	"t": i18n.Sprintf,
+}
+
+// ParseMissingTemplate parses a missing variable template
+func ParseMissingTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("missing").Funcs(missingFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid missing variables template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// NewMissingMessage groups and sorts missing variables. Sorting compares bytes, so the
+// message is the same regardless of map iteration order and locale.
+func NewMissingMessage(missingVars []MissingVariable, ctx MissingContext) MissingMessage {
+	vars := make([]MissingVariable, len(missingVars))
+	copy(vars, missingVars)
+	sort.Slice(vars, func(i, j int) bool {
+		if vars[i].VariableName != vars[j].VariableName {
+			return vars[i].VariableName < vars[j].VariableName
+		}
+		if vars[i].Provider != vars[j].Provider {
+			return vars[i].Provider < vars[j].Provider
+		}
+		return vars[i].MappedTo < vars[j].MappedTo
+	})
+
+	if ctx.Platform.ID == "" {
+		ctx.Platform, _ = ci.Lookup(ci.GitHubActions)
+	}
+	ctx.Prefix, ctx.Step, ctx.Hint = i18n.T(ctx.Prefix), i18n.T(ctx.Step), i18n.T(ctx.Hint)
+	msg := MissingMessage{MissingContext: ctx, Count: len(vars), Variables: vars}
+	groups := make(map[string][]MissingVariable)
+	seen := make(map[string]bool)
+	for _, mv := range vars {
+		groups[mv.Provider] = append(groups[mv.Provider], mv)
+		if !seen[mv.VariableName] {
+			seen[mv.VariableName] = true
+			msg.EnvNames = append(msg.EnvNames, mv.VariableName)
+		}
+	}
+	for name, groupVars := range groups {
+		msg.Providers = append(msg.Providers, MissingProviderGroup{Name: name, Variables: groupVars})
+	}
+	sort.Slice(msg.Providers, func(i, j int) bool { return msg.Providers[i].Name < msg.Providers[j].Name })
+	return msg
+}
+
+// FormatMissing renders the missing variable message with text, or with the default
+// template when text is empty
+func FormatMissing(missingVars []MissingVariable, ctx MissingContext, text string) (string, error) {
+	if text == "" {
+		text = DefaultMissingTemplate
+	}
+	tmpl, err := ParseMissingTemplate(text)
+	if err != nil {
+		return "", err
+	}
+
+	var buf strings.Builder
+	if err := tmpl.Execute(&buf, NewMissingMessage(missingVars, ctx)); err != nil {
+		return "", fmt.Errorf("failed to render missing variables template: %w", err)
+	}
+	return strings.TrimRight(buf.String(), "\n"), nil
+}
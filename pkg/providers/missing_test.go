@@ -0,0 +1,76 @@
+package providers
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFormatMissing(t *testing.T) {
+	t.Parallel()
+	missing := []MissingVariable{
+		{VariableName: "B_TOKEN", MappedTo: "TOKEN", Provider: "b"},
+		{VariableName: "A_URL", MappedTo: "URL", Provider: "a"},
+		{VariableName: "A_KEY", MappedTo: "KEY", Provider: "a"},
+	}
+	ctx := MissingContext{Command: "run", Step: "Run", Run: "feller run -- make", Hint: "hint"}
+
+	tests := []struct {
+		name        string
+		template    string
+		expected    string
+		errContains string
+	}{
+		{
+			name: "custom template",
+			template: "{{.Count}} secrets missing for feller {{.Command}}, see https://wiki.example.com/secrets\n" +
+				"{{range .Providers}}{{.Name}}:{{range .Variables}} {{.VariableName}}{{end}}\n{{end}}" +
+				"{{range .EnvNames}}{{.}}: {{secretRef .}}\n{{end}}\n\n",
+			expected: "3 secrets missing for feller run, see https://wiki.example.com/secrets\n" +
+				"a: A_KEY A_URL\nb: B_TOKEN\n" +
+				"A_KEY: ${{ secrets.A_KEY }}\nA_URL: ${{ secrets.A_URL }}\nB_TOKEN: ${{ secrets.B_TOKEN }}",
+		},
+		{name: "variables sorted across providers", template: "{{range .Variables}}{{.VariableName}} {{end}}", expected: "A_KEY A_URL B_TOKEN "},
+		{name: "syntax error", template: "{{.Count", errContains: "invalid missing variables template"},
+		{name: "unknown field", template: "{{.Channel}}", errContains: "failed to render missing variables template"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := FormatMissing(missing, ctx, tt.template)
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Errorf("FormatMissing() error = %v, expected to contain %q", err, tt.errContains)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("FormatMissing() unexpected error = %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("FormatMissing() =\n%s\nwant\n%s", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestFormatMissingDeterministic(t *testing.T) {
+	t.Parallel()
+	missing := []MissingVariable{
+		{VariableName: "C", MappedTo: "C", Provider: "z"},
+		{VariableName: "B", MappedTo: "B", Provider: "y"},
+		{VariableName: "A", MappedTo: "A", Provider: "x"},
+	}
+	first, err := FormatMissing(missing, MissingContext{}, "")
+	if err != nil {
+		t.Fatalf("FormatMissing() unexpected error = %v", err)
+	}
+	for range 20 {
+		if got, _ := FormatMissing(missing, MissingContext{}, ""); got != first {
+			t.Fatalf("FormatMissing() is not deterministic:\n%s\nvs\n%s", got, first)
+		}
+	}
+	if !strings.Contains(first, "Provider 'x':\n  • A (maps to: A)\n\nProvider 'y'") {
+		t.Errorf("FormatMissing() does not sort providers:\n%s", first)
+	}
+}
@@ -0,0 +1,20 @@
+package providers
+
+import (
+	"os"
+	"runtime"
+)
+
+// ExposedMode returns the permissions of the file at path when users other than its owner
+// can read it, which secret files should not allow. Windows has no such permission bits,
+// and files that cannot be inspected are not reported.
+func ExposedMode(path string) (os.FileMode, bool) {
+	if runtime.GOOS == "windows" {
+		return 0, false
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() || info.Mode().Perm()&0o044 == 0 {
+		return 0, false
+	}
+	return info.Mode().Perm(), true
+}
@@ -0,0 +1,424 @@
+package providers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/containifyci/feller/pkg/config"
+	"github.com/containifyci/feller/pkg/logger"
+)
+
+// Provider plugins are executables named feller-provider-NAME in PATH, used by providers of
+// kind plugin/NAME. Feller starts the plugin once per provider and exchanges one JSON object
+// per line over its stdin and stdout: a handshake agreeing on the protocol version, then a
+// resolve request with the options and maps of the provider. The plugin writes diagnostics
+// to stderr and exits once stdin is closed.
+const (
+	PluginKindPrefix   = "plugin/"
+	PluginBinaryPrefix = "feller-provider-"
+
+	// PluginProtocolVersion is the version of the plugin protocol feller speaks
+	PluginProtocolVersion = 1
+)
+
+// pluginTimeout bounds the run of a plugin, from the start to the resolve response
+const pluginTimeout = 60 * time.Second
+
+// pluginNamePattern matches the names of plugins, so kinds cannot point outside PATH
+var pluginNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// Error codes of plugin responses, mapped to guidance by pluginError
+const (
+	PluginErrorNotFound         = "not_found"
+	PluginErrorUnauthenticated  = "unauthenticated"
+	PluginErrorPermissionDenied = "permission_denied"
+	PluginErrorUnavailable      = "unavailable"
+	PluginErrorInvalidConfig    = "invalid_config"
+)
+
+// pluginErrorHints explains the error codes of plugin responses
+var pluginErrorHints = map[string]string{
+	PluginErrorNotFound:         "a secret of the provider does not exist",
+	PluginErrorUnauthenticated:  "the plugin has no valid credentials; log in to its backend or set its credential variables",
+	PluginErrorPermissionDenied: "the credentials of the plugin may not read a secret of the provider",
+	PluginErrorUnavailable:      "the backend of the plugin cannot be reached; try again later",
+	PluginErrorInvalidConfig:    "the options or maps of the provider are invalid for the plugin",
+}
+
+// PluginHello is the handshake feller sends, with the protocol version it speaks
+type PluginHello struct {
+	Type     string `json:"type"` // Always "handshake"
+	Protocol int    `json:"protocol"`
+}
+
+// PluginHandshake is the answer of the plugin to PluginHello
+type PluginHandshake struct {
+	Type         string       `json:"type"` // Always "handshake"
+	Protocol     int          `json:"protocol"`
+	Name         string       `json:"name,omitempty"`
+	Description  string       `json:"description,omitempty"`
+	Capabilities Capabilities `json:"capabilities"`
+	Versions     bool         `json:"versions,omitempty"` // The plugin reads the version and stage of maps
+}
+
+// PluginResolveRequest asks the plugin for the secrets of the maps of a provider
+type PluginResolveRequest struct {
+	Type     string         `json:"type"` // Always "resolve"
+	Provider string         `json:"provider"`
+	Options  map[string]any `json:"options,omitempty"`
+	Maps     []PluginMap    `json:"maps"`
+}
+
+// PluginMap is a path map as sent to plugins. Maps without keys ask for every key the plugin
+// can discover; feller applies include and exclude itself.
+type PluginMap struct {
+	ID      string   `json:"id"`
+	Path    string   `json:"path,omitempty"`
+	Keys    []string `json:"keys,omitempty"` // Source keys to read
+	Version string   `json:"version,omitempty"`
+	Stage   string   `json:"stage,omitempty"`
+}
+
+// PluginResolveResponse holds the values of the maps of a resolve request, in request order,
+// or the error that prevented resolving them
+type PluginResolveResponse struct {
+	Type  string            `json:"type"` // Always "resolve"
+	Maps  []PluginMapValues `json:"maps"`
+	Error *PluginError      `json:"error,omitempty"`
+}
+
+// PluginMapValues are the values of one map by source key; keys left out are not found
+type PluginMapValues struct {
+	ID     string            `json:"id"`
+	Values map[string]string `json:"values"`
+}
+
+// PluginError is an error reported by a plugin, with one of the PluginError* codes
+type PluginError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// PluginName returns the plugin name of a plugin/NAME kind
+func PluginName(kind string) (string, bool) {
+	name, ok := strings.CutPrefix(kind, PluginKindPrefix)
+	if !ok || !pluginNamePattern.MatchString(name) {
+		return "", false
+	}
+	return name, true
+}
+
+// IsPluginKind reports whether kind refers to a provider plugin
+func IsPluginKind(kind string) bool {
+	_, ok := PluginName(kind)
+	return ok
+}
+
+// pluginKindInfo describes a plugin kind. What the plugin supports is only known after the
+// handshake, so discovery is allowed and checked when the plugin runs.
+func pluginKindInfo(kind, name string) KindInfo {
+	return KindInfo{
+		Kind:              kind,
+		Description:       "Resolved by the provider plugin " + PluginBinaryPrefix + name,
+		Capabilities:      Capabilities{Read: true, Discovery: true},
+		AuthMethods:       []string{"plugin"},
+		RequiredOptions:   []string{},
+		RequiredMapFields: []string{"id"},
+	}
+}
+
+// FindPlugin returns the path of the executable of the plugin name in PATH
+func FindPlugin(name string) (string, error) {
+	binary := PluginBinaryPrefix + name
+	path, err := exec.LookPath(binary)
+	if err != nil {
+		return "", fmt.Errorf("provider plugin %s not found: install %s in PATH", name, binary)
+	}
+	return path, nil
+}
+
+// PluginInfo describes a plugin found in PATH
+type PluginInfo struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
+// DiscoverPlugins lists the plugins in PATH sorted by name. Like exec.LookPath, the first
+// executable of a name in PATH wins.
+func DiscoverPlugins() []PluginInfo {
+	seen := make(map[string]bool)
+	var plugins []PluginInfo
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		if dir == "" {
+			continue
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			name, ok := strings.CutPrefix(entry.Name(), PluginBinaryPrefix)
+			if runtime.GOOS == "windows" {
+				name = strings.TrimSuffix(name, filepath.Ext(name))
+			}
+			if !ok || seen[name] || !pluginNamePattern.MatchString(name) || !isExecutable(entry) {
+				continue
+			}
+			seen[name] = true
+			plugins = append(plugins, PluginInfo{Name: name, Path: filepath.Join(dir, entry.Name())})
+		}
+	}
+	sort.Slice(plugins, func(i, j int) bool { return plugins[i].Name < plugins[j].Name })
+	return plugins
+}
+
+// isExecutable reports whether the directory entry is a file that can be run
+func isExecutable(entry os.DirEntry) bool {
+	info, err := entry.Info()
+	if err != nil || info.IsDir() {
+		return false
+	}
+	if runtime.GOOS == "windows" {
+		return strings.EqualFold(filepath.Ext(entry.Name()), ".exe")
+	}
+	return info.Mode()&0o111 != 0
+}
+
+// pluginSession is a running plugin
+type pluginSession struct {
+	name   string
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout *bufio.Reader
+	stderr *bytes.Buffer
+}
+
+// startPlugin starts the plugin name and performs the handshake
+func startPlugin(ctx context.Context, name string) (*pluginSession, PluginHandshake, error) {
+	path, err := FindPlugin(name)
+	if err != nil {
+		return nil, PluginHandshake{}, err
+	}
+	logger.Debug("Starting provider plugin %s (%s)", name, path)
+
+	// #nosec G204 - The plugin is an executable in PATH with a checked name
+	cmd := exec.CommandContext(ctx, path)
+	s := &pluginSession{name: name, cmd: cmd, stderr: &bytes.Buffer{}}
+	cmd.Stderr = s.stderr
+	if s.stdin, err = cmd.StdinPipe(); err != nil {
+		return nil, PluginHandshake{}, fmt.Errorf("failed to start plugin %s: %w", name, err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, PluginHandshake{}, fmt.Errorf("failed to start plugin %s: %w", name, err)
+	}
+	s.stdout = bufio.NewReader(stdout)
+	if err := cmd.Start(); err != nil {
+		return nil, PluginHandshake{}, fmt.Errorf("failed to start plugin %s: %w", name, err)
+	}
+
+	var handshake PluginHandshake
+	if err := s.exchange(PluginHello{Type: "handshake", Protocol: PluginProtocolVersion}, &handshake); err != nil {
+		s.close()
+		return nil, handshake, err
+	}
+	if handshake.Type != "handshake" {
+		s.close()
+		return nil, handshake, fmt.Errorf("plugin %s answered the handshake with a %q message", name, handshake.Type)
+	}
+	if handshake.Protocol != PluginProtocolVersion {
+		s.close()
+		return nil, handshake, fmt.Errorf("plugin %s speaks protocol version %d, feller speaks %d; update feller or the plugin",
+			name, handshake.Protocol, PluginProtocolVersion)
+	}
+	logger.Debug("Plugin %s speaks protocol version %d: %+v", name, handshake.Protocol, handshake.Capabilities)
+	return s, handshake, nil
+}
+
+// exchange writes request as one line and decodes the next line of the plugin into response
+func (s *pluginSession) exchange(request, response any) error {
+	data, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("failed to encode request to plugin %s: %w", s.name, err)
+	}
+	if _, err := s.stdin.Write(append(data, '\n')); err != nil {
+		return s.failure(fmt.Errorf("failed to write to plugin %s: %w", s.name, err))
+	}
+	line, err := s.stdout.ReadBytes('\n')
+	if err != nil && len(line) == 0 {
+		return s.failure(fmt.Errorf("plugin %s exited without answering: %w", s.name, err))
+	}
+	if err := json.Unmarshal(line, response); err != nil {
+		return fmt.Errorf("plugin %s wrote invalid JSON: %w", s.name, err)
+	}
+	return nil
+}
+
+// failure adds the last line the plugin wrote to stderr to err, which usually explains it
+func (s *pluginSession) failure(err error) error {
+	_ = s.cmd.Wait()
+	if last := lastLine(s.stderr.String()); last != "" {
+		return fmt.Errorf("%w: %s", err, last)
+	}
+	return err
+}
+
+// close closes stdin, which tells the plugin to exit, and waits for it
+func (s *pluginSession) close() {
+	_ = s.stdin.Close()
+	if err := s.cmd.Wait(); err != nil {
+		logger.Debug("Plugin %s exited with %v", s.name, err)
+	}
+	if s.stderr.Len() > 0 {
+		logger.Debug("Plugin %s wrote to stderr:\n%s", s.name, strings.TrimRight(s.stderr.String(), "\n"))
+	}
+}
+
+// lastLine returns the last non-empty line of text
+func lastLine(text string) string {
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
+
+// pluginError turns the error of a plugin response into an error with guidance
+func pluginError(name string, e *PluginError) error {
+	message := e.Message
+	if message == "" {
+		message = e.Code
+	}
+	if hint, ok := pluginErrorHints[e.Code]; ok {
+		return fmt.Errorf("plugin %s: %s (%s: %s)", name, message, e.Code, hint)
+	}
+	return fmt.Errorf("plugin %s: %s", name, message)
+}
+
+// collectPluginSecrets resolves the maps of a plugin provider with its plugin, returning the
+// path map id of each key as well. Keys the plugin leaves out are reported as missing, like
+// keys missing from a Vault secret.
+func collectPluginSecrets(name string, provider config.Provider, m *meter) (SecretMap, map[string]string, []MissingVariable, error) {
+	plugin, _ := PluginName(provider.Kind)
+	request := PluginResolveRequest{Type: "resolve", Provider: name}
+	if !provider.Options.IsZero() {
+		if err := provider.Options.Decode(&request.Options); err != nil {
+			return nil, nil, nil, fmt.Errorf("provider %s: invalid options: %w", name, err)
+		}
+		// Handled by feller
+		delete(request.Options, "rate_limit")
+		delete(request.Options, "cache")
+	}
+	for _, pathMap := range provider.Maps {
+		pm := PluginMap{ID: pathMap.ID, Path: pathMap.Path, Version: pathMap.Version, Stage: pathMap.Stage}
+		for fromKey := range pathMap.Keys {
+			pm.Keys = append(pm.Keys, fromKey)
+		}
+		sort.Strings(pm.Keys)
+		request.Maps = append(request.Maps, pm)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), pluginTimeout)
+	defer cancel()
+	session, handshake, err := startPlugin(ctx, plugin)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("provider %s: %w", name, err)
+	}
+	defer session.close()
+
+	if err := checkPluginMaps(plugin, handshake, provider.Maps); err != nil {
+		return nil, nil, nil, fmt.Errorf("provider %s: %w", name, err)
+	}
+
+	m.wait()
+	var response PluginResolveResponse
+	if err := session.exchange(request, &response); err != nil {
+		return nil, nil, nil, fmt.Errorf("provider %s: %w", name, err)
+	}
+	if response.Error != nil {
+		return nil, nil, nil, fmt.Errorf("provider %s: %w", name, pluginError(plugin, response.Error))
+	}
+	if len(response.Maps) != len(provider.Maps) {
+		return nil, nil, nil, fmt.Errorf("provider %s: plugin %s returned %d maps for %d requested", name, plugin, len(response.Maps), len(provider.Maps))
+	}
+
+	secrets := make(SecretMap)
+	mapIDs := make(map[string]string)
+	var missingVars []MissingVariable
+	for i, pathMap := range provider.Maps {
+		values := response.Maps[i].Values
+		logger.Debug("Plugin %s returned %d keys for map '%s'", plugin, len(values), pathMap.ID)
+		if len(pathMap.Keys) == 0 {
+			for key, value := range values {
+				if !discovers(pathMap, key) {
+					continue
+				}
+				secrets[key] = value
+				mapIDs[key] = pathMap.ID
+			}
+			continue
+		}
+		for fromKey, toKey := range pathMap.Keys {
+			if value, ok := values[fromKey]; ok {
+				secrets[toKey] = value
+				mapIDs[toKey] = pathMap.ID
+			} else {
+				logger.Debug("Key '%s' not found by plugin %s in map '%s'", fromKey, plugin, pathMap.ID)
+				missingVars = append(missingVars, MissingVariable{VariableName: fromKey, MappedTo: toKey, Provider: name})
+			}
+		}
+	}
+	return secrets, mapIDs, missingVars, nil
+}
+
+// checkPluginMaps checks the maps against the capabilities of the plugin from the handshake
+func checkPluginMaps(plugin string, handshake PluginHandshake, maps []config.PathMap) error {
+	if !handshake.Capabilities.Read {
+		return fmt.Errorf("plugin %s cannot read secrets", plugin)
+	}
+	for _, pathMap := range maps {
+		if len(pathMap.Keys) == 0 && !handshake.Capabilities.Discovery {
+			return fmt.Errorf("map %s has no keys, but plugin %s cannot discover them", pathMap.ID, plugin)
+		}
+		if pathMap.Pinned() && !handshake.Versions {
+			return fmt.Errorf("map %s pins %s, but plugin %s reads no versions", pathMap.ID, describeVersion(pathMap), plugin)
+		}
+	}
+	return nil
+}
+
+// pluginProviders returns the providers of plugin kinds
+func pluginProviders(cfg *config.TellerConfig) map[string]config.Provider {
+	result := make(map[string]config.Provider)
+	for name, provider := range cfg.Providers {
+		if IsPluginKind(provider.Kind) {
+			result[name] = provider
+		}
+	}
+	return result
+}
+
+// ProbePlugin starts the plugin name, performs the handshake and stops it again, for
+// listing what plugins support
+func ProbePlugin(name string) (PluginHandshake, error) {
+	if !pluginNamePattern.MatchString(name) {
+		return PluginHandshake{}, fmt.Errorf("invalid plugin name %q: lowercase letters, digits, - and _", name)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), pluginTimeout)
+	defer cancel()
+	session, handshake, err := startPlugin(ctx, name)
+	if err != nil {
+		return handshake, err
+	}
+	session.close()
+	return handshake, nil
+}
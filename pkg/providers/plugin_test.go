@@ -0,0 +1,236 @@
+package providers
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/containifyci/feller/pkg/config"
+	"gopkg.in/yaml.v3"
+)
+
+// fakePluginScript answers the handshake with $FAKE_PLUGIN_HANDSHAKE and the resolve request
+// with $FAKE_PLUGIN_RESPONSE, logging both requests to $FAKE_PLUGIN_LOG. With
+// $FAKE_PLUGIN_FAIL set, it writes that to stderr and exits after reading the handshake.
+const fakePluginScript = `#!/bin/sh
+read -r handshake
+if [ -n "$FAKE_PLUGIN_FAIL" ]; then
+  echo "$FAKE_PLUGIN_FAIL" >&2
+  exit 3
+fi
+echo "$handshake" > "$FAKE_PLUGIN_LOG"
+echo "$FAKE_PLUGIN_HANDSHAKE"
+read -r request
+echo "$request" >> "$FAKE_PLUGIN_LOG"
+echo "$FAKE_PLUGIN_RESPONSE"
+`
+
+// fakePlugin installs feller-provider-fake as the only plugin in PATH and returns the path
+// of the log of its requests
+func fakePlugin(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	// #nosec G306 - The fake plugin must be executable
+	if err := os.WriteFile(filepath.Join(dir, PluginBinaryPrefix+"fake"), []byte(fakePluginScript), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	log := filepath.Join(t.TempDir(), "requests.log")
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+"/bin:/usr/bin")
+	t.Setenv("FAKE_PLUGIN_LOG", log)
+	t.Setenv("FAKE_PLUGIN_FAIL", "")
+	t.Setenv("FAKE_PLUGIN_HANDSHAKE", `{"type":"handshake","protocol":1,"capabilities":{"read":true,"discovery":true}}`)
+	return log
+}
+
+//nolint:paralleltest // sets environment variables
+func TestCollectPluginSecrets(t *testing.T) {
+	log := fakePlugin(t)
+	keyed := config.PathMap{ID: "app", Path: "team/app", Keys: map[string]string{"api_key": "API_KEY", "db": "DATABASE_URL", "gone": "GONE"}}
+	discovered := config.PathMap{ID: "shared", Path: "team/shared", Exclude: []string{"*_DEBUG"}}
+
+	tests := []struct {
+		name      string
+		handshake string
+		response  string
+		fail      string
+		maps      []config.PathMap
+		expected  SecretMap
+		missing   []MissingVariable
+		wantErr   string
+	}{
+		{
+			name:     "keys and discovery",
+			response: `{"type":"resolve","maps":[{"id":"app","values":{"api_key":"k","db":"d"}},{"id":"shared","values":{"TOKEN":"t","LOG_DEBUG":"1"}}]}`,
+			maps:     []config.PathMap{keyed, discovered},
+			expected: SecretMap{"API_KEY": "k", "DATABASE_URL": "d", "TOKEN": "t"},
+			missing:  []MissingVariable{{VariableName: "gone", MappedTo: "GONE", Provider: "acme"}},
+		},
+		{
+			name:     "mapped error",
+			response: `{"type":"resolve","error":{"code":"unauthenticated","message":"token expired"}}`,
+			maps:     []config.PathMap{keyed},
+			wantErr:  "provider acme: plugin fake: token expired (unauthenticated: the plugin has no valid credentials; log in to its backend or set its credential variables)",
+		},
+		{
+			name:     "unknown error code",
+			response: `{"type":"resolve","error":{"code":"quota","message":"too many requests"}}`,
+			maps:     []config.PathMap{keyed},
+			wantErr:  "provider acme: plugin fake: too many requests",
+		},
+		{
+			name:     "wrong number of maps",
+			response: `{"type":"resolve","maps":[]}`,
+			maps:     []config.PathMap{keyed},
+			wantErr:  "provider acme: plugin fake returned 0 maps for 1 requested",
+		},
+		{
+			name:      "protocol mismatch",
+			handshake: `{"type":"handshake","protocol":2,"capabilities":{"read":true}}`,
+			maps:      []config.PathMap{keyed},
+			wantErr:   "provider acme: plugin fake speaks protocol version 2, feller speaks 1; update feller or the plugin",
+		},
+		{
+			name:      "discovery not supported",
+			handshake: `{"type":"handshake","protocol":1,"capabilities":{"read":true}}`,
+			maps:      []config.PathMap{discovered},
+			wantErr:   "provider acme: map shared has no keys, but plugin fake cannot discover them",
+		},
+		{
+			name:     "versions not supported",
+			maps:     []config.PathMap{{ID: "app", Keys: map[string]string{"a": "A"}, Version: "3"}},
+			wantErr:  "provider acme: map app pins version 3, but plugin fake reads no versions",
+			response: `{"type":"resolve","maps":[{"id":"app","values":{}}]}`,
+		},
+		{
+			name:    "plugin fails",
+			fail:    "backend unreachable",
+			maps:    []config.PathMap{keyed},
+			wantErr: "provider acme: plugin fake exited without answering: EOF: backend unreachable",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.handshake != "" {
+				t.Setenv("FAKE_PLUGIN_HANDSHAKE", tt.handshake)
+			}
+			t.Setenv("FAKE_PLUGIN_RESPONSE", tt.response)
+			t.Setenv("FAKE_PLUGIN_FAIL", tt.fail)
+
+			m, _ := newMeter("acme", config.Provider{})
+			secrets, mapIDs, missing, err := collectPluginSecrets("acme", config.Provider{Kind: "plugin/fake", Maps: tt.maps}, m)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("collectPluginSecrets() error = %v, expected %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("collectPluginSecrets() error = %v", err)
+			}
+			if len(secrets) != len(tt.expected) {
+				t.Errorf("collectPluginSecrets() = %v, expected %v", secrets, tt.expected)
+			}
+			for key, value := range tt.expected {
+				if secrets[key] != value {
+					t.Errorf("collectPluginSecrets()[%s] = %q, expected %q", key, secrets[key], value)
+				}
+			}
+			if mapIDs["TOKEN"] != "shared" || mapIDs["API_KEY"] != "app" {
+				t.Errorf("collectPluginSecrets() map ids = %v", mapIDs)
+			}
+			if !reflect.DeepEqual(missing, tt.missing) {
+				t.Errorf("collectPluginSecrets() missing = %v, expected %v", missing, tt.missing)
+			}
+		})
+	}
+
+	// The requests feller sends, without the options feller handles itself
+	t.Setenv("FAKE_PLUGIN_RESPONSE", `{"type":"resolve","maps":[{"id":"app","values":{}}]}`)
+	provider := config.Provider{Kind: "plugin/fake", Maps: []config.PathMap{keyed}}
+	var options yaml.Node
+	if err := yaml.Unmarshal([]byte("{region: eu, rate_limit: {requests_per_second: 5}}"), &options); err != nil {
+		t.Fatal(err)
+	}
+	provider.Options = *options.Content[0]
+	if _, _, _, err := collectPluginSecrets("acme", provider, nil); err != nil {
+		t.Fatalf("collectPluginSecrets() error = %v", err)
+	}
+	data, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"type":"handshake","protocol":1}
+{"type":"resolve","provider":"acme","options":{"region":"eu"},"maps":[{"id":"app","path":"team/app","keys":["api_key","db","gone"]}]}
+`
+	if string(data) != expected {
+		t.Errorf("plugin requests =\n%s\nexpected\n%s", data, expected)
+	}
+}
+
+//nolint:paralleltest // sets environment variables
+func TestCollectSecretsWithPlugin(t *testing.T) {
+	fakePlugin(t)
+	t.Setenv("FAKE_PLUGIN_RESPONSE", `{"type":"resolve","maps":[{"id":"app","values":{"api_key":"k"}}]}`)
+	cfg := &config.TellerConfig{Providers: map[string]config.Provider{
+		"acme":    {Kind: "plugin/fake", Maps: []config.PathMap{{ID: "app", Keys: map[string]string{"api_key": "API_KEY"}}}},
+		"missing": {Kind: "plugin/absent", Maps: []config.PathMap{{ID: "x"}}},
+	}}
+
+	if _, err := CollectSecretsWithResult(cfg, false); err == nil || !strings.Contains(err.Error(), "provider plugin absent not found: install feller-provider-absent in PATH") {
+		t.Errorf("CollectSecretsWithResult() with a missing plugin error = %v", err)
+	}
+
+	delete(cfg.Providers, "missing")
+	result, err := CollectSecretsWithResult(cfg, false)
+	if err != nil {
+		t.Fatalf("CollectSecretsWithResult() error = %v", err)
+	}
+	if result.Secrets["API_KEY"] != "k" || result.Sources["API_KEY"].Kind != "plugin/fake" {
+		t.Errorf("CollectSecretsWithResult() = %v from %v", result.Secrets, result.Sources)
+	}
+}
+
+//nolint:paralleltest // sets environment variables
+func TestDiscoverPlugins(t *testing.T) {
+	fakePlugin(t)
+	plugins := DiscoverPlugins()
+	if len(plugins) != 1 || plugins[0].Name != "fake" {
+		t.Fatalf("DiscoverPlugins() = %v, expected the fake plugin", plugins)
+	}
+
+	handshake, err := ProbePlugin("fake")
+	if err != nil || !handshake.Capabilities.Discovery {
+		t.Errorf("ProbePlugin() = %+v, %v", handshake, err)
+	}
+	if _, err := ProbePlugin("../fake"); err == nil {
+		t.Error("ProbePlugin() accepted a name outside PATH")
+	}
+}
+
+func TestPluginName(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		kind string
+		name string
+		ok   bool
+	}{
+		{kind: "plugin/onepassword", name: "onepassword", ok: true},
+		{kind: "plugin/aws-sm_2", name: "aws-sm_2", ok: true},
+		{kind: "plugin/", ok: false},
+		{kind: "plugin/../bin/sh", ok: false},
+		{kind: "plugin/Upper", ok: false},
+		{kind: "dotenv", ok: false},
+	}
+	for _, tt := range tests {
+		name, ok := PluginName(tt.kind)
+		if name != tt.name || ok != tt.ok {
+			t.Errorf("PluginName(%q) = %q, %v, expected %q, %v", tt.kind, name, ok, tt.name, tt.ok)
+		}
+		if _, known := LookupKind(tt.kind); tt.ok && !known {
+			t.Errorf("LookupKind(%q) does not know the plugin kind", tt.kind)
+		}
+	}
+}
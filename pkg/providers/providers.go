@@ -1,10 +1,16 @@
 package providers
 
 import (
-	"bufio"
 	"fmt"
+	"maps"
 	"os"
+	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
+	"sync/atomic"
+	"time"
+	"unicode"
 
 	"github.com/containifyci/feller/pkg/config"
 	"github.com/containifyci/feller/pkg/logger"
@@ -20,11 +26,23 @@ type MissingVariable struct {
 	Provider     string // The provider name that expected this variable
 }
 
+// SecretSource identifies where a collected secret came from
+type SecretSource struct {
+	Provider string // The provider name that supplied the value
+	Kind     string // The provider kind
+	MapID    string // The id of the path map that supplied the value
+}
+
 // CollectionResult contains the collected secrets and any missing variables
 type CollectionResult struct {
 	Secrets        SecretMap
+	Sources        map[string]SecretSource   // Output key -> provider that supplied the final value
+	Origins        map[string][]SecretSource // Output key -> every provider that supplied it, in merge order
 	MissingVars    []MissingVariable
 	HasMissingVars bool
+	Timings        []Timing // Requests and time of each provider, sorted by provider name
+
+	contributions []contribution // Collected values in collection order, merged once all providers are done
 }
 
 // CollectSecrets collects all secrets from all providers in the configuration
@@ -41,52 +59,180 @@ func CollectSecretsWithResult(cfg *config.TellerConfig, silent bool) (*Collectio
 	logger.Debug("Collecting secrets from all providers (silent: %v)", silent)
 	result := &CollectionResult{
 		Secrets:     make(SecretMap),
+		Sources:     make(map[string]SecretSource),
+		Origins:     make(map[string][]SecretSource),
 		MissingVars: []MissingVariable{},
 	}
 
+	if err := checkDiscoveryFilters(cfg); err != nil {
+		return nil, err
+	}
+	// Environment variables and files hold one value, so a pin would silently read another version
+	if pinned := unreadablePins(cfg); len(pinned) > 0 {
+		return nil, fmt.Errorf("cannot resolve %s: feller reads environment variables and files, which have no versions", strings.Join(pinned, ", "))
+	}
+
 	// Process Google Secret Manager providers (read from environment)
-	gsmProviders := cfg.GetProvidersByKind("google_secretmanager")
+	gsmProviders := cfg.GetProvidersByKind(KindGoogleSecretManager)
 	logger.Debug("Found %d Google Secret Manager providers", len(gsmProviders))
 
-	for name, provider := range gsmProviders {
+	for _, name := range slices.Sorted(maps.Keys(gsmProviders)) {
+		provider := gsmProviders[name]
 		logger.Debug("Processing GSM provider '%s'", name)
-		providerSecrets, missingVars := collectGSMSecretsWithMissing(provider, name)
+		m, err := newMeter(name, provider)
+		if err != nil {
+			return nil, err
+		}
+		start := time.Now()
+		providerSecrets, mapIDs, missingVars, err := collectGSMSecretsWithMissing(provider, name, cfg.OnCI, m)
+		result.record(m, start)
+		if err != nil {
+			return nil, err
+		}
 		logger.Debug("GSM provider '%s' returned %d secrets, %d missing", name, len(providerSecrets), len(missingVars))
 
 		// Track missing variables
 		result.MissingVars = append(result.MissingVars, missingVars...)
 
-		// Merge secrets, later providers override earlier ones
-		for k, v := range providerSecrets {
-			if _, exists := result.Secrets[k]; exists {
-				logger.Debug("GSM provider '%s' overriding key '%s' (previous value from other provider)", name, k)
-			}
-			result.Secrets[k] = v
-			logger.Debug("Added secret key '%s' (value: %s) from GSM provider '%s'", k, maskSecret(v), name)
+		// Merge secrets, resolving keys of several providers with the collision strategy
+		result.add(name, provider, providerSecrets, mapIDs)
+	}
+
+	// Process github providers (environment variables set by the workflow)
+	githubProviders := cfg.GetProvidersByKind(KindGitHub)
+	logger.Debug("Found %d github providers", len(githubProviders))
+
+	for _, name := range slices.Sorted(maps.Keys(githubProviders)) {
+		provider := githubProviders[name]
+		logger.Debug("Processing github provider '%s'", name)
+		m, err := newMeter(name, provider)
+		if err != nil {
+			return nil, err
+		}
+		start := time.Now()
+		providerSecrets, mapIDs, missingVars, err := collectGitHubSecrets(name, provider)
+		result.record(m, start)
+		if err != nil {
+			return nil, err
+		}
+		logger.Debug("github provider '%s' returned %d secrets, %d missing", name, len(providerSecrets), len(missingVars))
+		result.MissingVars = append(result.MissingVars, missingVars...)
+
+		result.add(name, provider, providerSecrets, mapIDs)
+	}
+
+	// Process bundle providers (decrypted with age)
+	bundleProviders := cfg.GetProvidersByKind(KindBundle)
+	logger.Debug("Found %d bundle providers", len(bundleProviders))
+
+	for _, name := range slices.Sorted(maps.Keys(bundleProviders)) {
+		provider := bundleProviders[name]
+		logger.Debug("Processing bundle provider '%s'", name)
+		m, err := newMeter(name, provider)
+		if err != nil {
+			return nil, err
+		}
+		start := time.Now()
+		providerSecrets, mapIDs, err := collectBundleSecrets(provider)
+		result.record(m, start)
+		if err != nil {
+			logger.Debug("Failed to collect bundle secrets from provider '%s': %v", name, err)
+			return nil, fmt.Errorf("failed to collect bundle secrets: %w", err)
+		}
+		logger.Debug("Bundle provider '%s' returned %d secrets", name, len(providerSecrets))
+
+		result.add(name, provider, providerSecrets, mapIDs)
+	}
+
+	// Process HashiCorp Vault providers (read from the KV secrets engine)
+	vaultProviders := cfg.GetProvidersByKind(KindHashiCorpVault)
+	logger.Debug("Found %d Vault providers", len(vaultProviders))
+
+	for _, name := range slices.Sorted(maps.Keys(vaultProviders)) {
+		provider := vaultProviders[name]
+		logger.Debug("Processing Vault provider '%s'", name)
+		m, err := newMeter(name, provider)
+		if err != nil {
+			return nil, err
+		}
+		start := time.Now()
+		providerSecrets, mapIDs, missingVars, err := collectCached(name, provider, m, collectVaultSecrets)
+		result.record(m, start)
+		if err != nil {
+			logger.Debug("Failed to collect Vault secrets from provider '%s': %v", name, err)
+			return nil, fmt.Errorf("failed to collect vault secrets: %w", err)
 		}
+		logger.Debug("Vault provider '%s' returned %d secrets, %d missing", name, len(providerSecrets), len(missingVars))
+		result.MissingVars = append(result.MissingVars, missingVars...)
+
+		result.add(name, provider, providerSecrets, mapIDs)
+	}
+
+	// Process plugin providers (resolved by feller-provider-* executables)
+	plugins := pluginProviders(cfg)
+	logger.Debug("Found %d plugin providers", len(plugins))
+
+	for _, name := range slices.Sorted(maps.Keys(plugins)) {
+		provider := plugins[name]
+		logger.Debug("Processing plugin provider '%s' (%s)", name, provider.Kind)
+		m, err := newMeter(name, provider)
+		if err != nil {
+			return nil, err
+		}
+		start := time.Now()
+		providerSecrets, mapIDs, missingVars, err := collectCached(name, provider, m, collectPluginSecrets)
+		result.record(m, start)
+		if err != nil {
+			logger.Debug("Failed to collect plugin secrets from provider '%s': %v", name, err)
+			return nil, fmt.Errorf("failed to collect plugin secrets: %w", err)
+		}
+		logger.Debug("Plugin provider '%s' returned %d secrets, %d missing", name, len(providerSecrets), len(missingVars))
+		result.MissingVars = append(result.MissingVars, missingVars...)
+
+		result.add(name, provider, providerSecrets, mapIDs)
 	}
 
 	// Process dotenv providers (read from files)
-	dotenvProviders := cfg.GetProvidersByKind("dotenv")
+	dotenvProviders := cfg.GetProvidersByKind(KindDotenv)
 	logger.Debug("Found %d dotenv providers", len(dotenvProviders))
 
-	for name, provider := range dotenvProviders {
+	for _, name := range slices.Sorted(maps.Keys(dotenvProviders)) {
+		provider := dotenvProviders[name]
 		logger.Debug("Processing dotenv provider '%s'", name)
-		providerSecrets, err := collectDotenvSecrets(provider)
+		m, err := newMeter(name, provider)
+		if err != nil {
+			return nil, err
+		}
+		start := time.Now()
+		providerSecrets, mapIDs, err := collectDotenvSecrets(provider)
+		result.record(m, start)
 		if err != nil {
 			logger.Debug("Failed to collect dotenv secrets from provider '%s': %v", name, err)
 			return nil, fmt.Errorf("failed to collect dotenv secrets: %w", err)
 		}
 		logger.Debug("Dotenv provider '%s' returned %d secrets", name, len(providerSecrets))
 
-		// Merge secrets, later providers override earlier ones
-		for k, v := range providerSecrets {
-			if _, exists := result.Secrets[k]; exists {
-				logger.Debug("Dotenv provider '%s' overriding key '%s' (previous value from other provider)", name, k)
-			}
-			result.Secrets[k] = v
-			logger.Debug("Added secret key '%s' (value: %s) from dotenv provider '%s'", k, maskSecret(v), name)
-		}
+		// Merge secrets, resolving keys of several providers with the collision strategy
+		result.add(name, provider, providerSecrets, mapIDs)
+	}
+
+	if err := result.merge(cfg.Collisions); err != nil {
+		return nil, err
+	}
+
+	if err := ApplyTransforms(result.Secrets, cfg.Transforms); err != nil {
+		logger.Debug("Failed to apply transforms: %v", err)
+		return nil, err
+	}
+
+	if err := ApplySchema(result.Secrets, cfg.Schema, result.Sources); err != nil {
+		logger.Debug("Schema validation failed: %v", err)
+		return nil, err
+	}
+
+	for _, key := range result.Conflicts() {
+		logger.Debug("Key '%s' supplied by %d providers: %s; using %s", key, len(result.Origins[key]),
+			describeOrigins(result.Origins[key]), result.Sources[key])
 	}
 
 	result.HasMissingVars = len(result.MissingVars) > 0
@@ -95,6 +241,55 @@ func CollectSecretsWithResult(cfg *config.TellerConfig, silent bool) (*Collectio
 	return result, nil
 }
 
+// add records the secrets collected from provider name, supplied by the maps of mapIDs.
+// Providers collected later come after earlier ones; the keys of one provider are sorted, so
+// the order does not depend on map iteration.
+func (r *CollectionResult) add(name string, provider config.Provider, secrets map[string]string, mapIDs map[string]string) {
+	for _, key := range slices.Sorted(maps.Keys(secrets)) {
+		source := SecretSource{Provider: name, Kind: provider.Kind, MapID: mapIDs[key]}
+		r.contributions = append(r.contributions, contribution{key: key, value: secrets[key], source: source})
+		logger.Debug("Added secret key '%s' (value: %s) from %s", key, maskSecret(secrets[key]), source)
+	}
+}
+
+// record adds the timing of a provider collected since start
+func (r *CollectionResult) record(m *meter, start time.Time) {
+	m.timing.Elapsed = time.Since(start)
+	if m.timing.Throttled > 0 {
+		logger.Debug("Provider '%s' was throttled %d time(s) for %s", m.timing.Provider, m.timing.Throttled, m.timing.Waited)
+	}
+	i := sort.Search(len(r.Timings), func(i int) bool { return r.Timings[i].Provider >= m.timing.Provider })
+	r.Timings = slices.Insert(r.Timings, i, m.timing)
+}
+
+// Conflicts returns the sorted keys that were supplied by more than one provider
+func (r *CollectionResult) Conflicts() []string {
+	var keys []string
+	for key, origins := range r.Origins {
+		if len(origins) > 1 {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// String formats the source as "provider 'name' (map 'id')"
+func (s SecretSource) String() string {
+	if s.MapID == "" {
+		return fmt.Sprintf("provider '%s'", s.Provider)
+	}
+	return fmt.Sprintf("provider '%s' (map '%s')", s.Provider, s.MapID)
+}
+
+func describeOrigins(origins []SecretSource) string {
+	parts := make([]string, len(origins))
+	for i, origin := range origins {
+		parts[i] = origin.String()
+	}
+	return strings.Join(parts, ", ")
+}
+
 // maskSecret masks a secret value for debug logging
 func maskSecret(value string) string {
 	if len(value) <= 4 {
@@ -103,17 +298,46 @@ func maskSecret(value string) string {
 	return value[:2] + strings.Repeat("*", len(value)-4) + value[len(value)-2:]
 }
 
-// collectGSMSecretsWithMissing collects secrets and tracks missing environment variables
-func collectGSMSecretsWithMissing(provider config.Provider, providerName string) (SecretMap, []MissingVariable) {
+// collectGSMSecretsWithMissing collects secrets and tracks missing environment variables.
+// It also returns the id of the path map each output key was read from. Maps without keys
+// list the secrets of their project when their path is projects/PROJECT, except on CI, which
+// only passes through the environment, and otherwise discover the environment variables
+// matching their include globs.
+func collectGSMSecretsWithMissing(provider config.Provider, providerName string, onCI bool, m *meter) (SecretMap, map[string]string, []MissingVariable, error) {
 	logger.Debug("Collecting GSM secrets from %d path maps", len(provider.Maps))
 	secrets := make(SecretMap)
+	mapIDs := make(map[string]string)
 	var missingVars []MissingVariable
 
 	for i, pathMap := range provider.Maps {
 		logger.Debug("Processing GSM path map %d (id: %s, path: %s)", i+1, pathMap.ID, pathMap.Path)
 
+		if ListsGSMProject(pathMap) && !onCI {
+			discovered, err := discoverGSMSecrets(providerName, provider, pathMap, m)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			logger.Debug("Discovered %d secrets in %s", len(discovered), pathMap.Path)
+			for key, value := range discovered {
+				secrets[key] = value
+				mapIDs[key] = pathMap.ID
+			}
+			continue
+		}
 		if len(pathMap.Keys) == 0 {
-			logger.Debug("Discovery mode not supported for GSM provider, skipping map %d", i+1)
+			// Discovery over the whole environment would pick up PATH and the like, so it needs globs
+			if len(pathMap.Include) == 0 {
+				logger.Debug("Discovery mode without include globs or a projects/PROJECT path not supported for GSM provider, skipping map %d", i+1)
+				continue
+			}
+			for _, entry := range os.Environ() {
+				key, value, _ := strings.Cut(entry, "=")
+				if value != "" && discovers(pathMap, key) {
+					secrets[key] = value
+					mapIDs[key] = pathMap.ID
+					logger.Debug("Discovered env var '%s' with value '%s'", key, maskSecret(value))
+				}
+			}
 			continue
 		}
 
@@ -124,6 +348,7 @@ func collectGSMSecretsWithMissing(provider config.Provider, providerName string)
 			logger.Debug("Looking for environment variable '%s' to map to '%s'", fromKey, toKey)
 			if value := os.Getenv(fromKey); value != "" {
 				secrets[toKey] = value
+				mapIDs[toKey] = pathMap.ID
 				logger.Debug("Found env var '%s' with value '%s', mapped to key '%s'", fromKey, maskSecret(value), toKey)
 			} else {
 				logger.Debug("Environment variable '%s' not found or empty", fromKey)
@@ -137,14 +362,15 @@ func collectGSMSecretsWithMissing(provider config.Provider, providerName string)
 	}
 
 	logger.Debug("GSM provider collected %d secrets total, %d missing", len(secrets), len(missingVars))
-	return secrets, missingVars
+	return secrets, mapIDs, missingVars, nil
 }
 
 // collectDotenvSecrets collects secrets from dotenv provider
-// This reads from .env files on the filesystem
-func collectDotenvSecrets(provider config.Provider) (SecretMap, error) {
+// This reads from .env files on the filesystem and also returns the path map id of each key
+func collectDotenvSecrets(provider config.Provider) (SecretMap, map[string]string, error) {
 	logger.Debug("Collecting dotenv secrets from %d path maps", len(provider.Maps))
 	secrets := make(SecretMap)
+	mapIDs := make(map[string]string)
 
 	for i, pathMap := range provider.Maps {
 		logger.Debug("Processing dotenv path map %d (id: %s, path: %s)", i+1, pathMap.ID, pathMap.Path)
@@ -152,16 +378,20 @@ func collectDotenvSecrets(provider config.Provider) (SecretMap, error) {
 		envFile, err := loadEnvFile(pathMap.Path)
 		if err != nil {
 			logger.Debug("Failed to load env file '%s': %v", pathMap.Path, err)
-			return nil, fmt.Errorf("failed to load env file %s: %w", pathMap.Path, err)
+			return nil, nil, fmt.Errorf("failed to load env file %s: %w", pathMap.Path, err)
 		}
 
 		logger.Debug("Loaded %d variables from env file '%s'", len(envFile), pathMap.Path)
 
 		if len(pathMap.Keys) == 0 {
 			logger.Debug("Discovery mode: using all %d keys from the file", len(envFile))
-			// Discovery mode: use all keys from the file
+			// Discovery mode: use all keys from the file that pass the globs
 			for k, v := range envFile {
+				if !discovers(pathMap, k) {
+					continue
+				}
 				secrets[k] = v
+				mapIDs[k] = pathMap.ID
 				logger.Debug("Added key '%s' (value: %s) from env file", k, maskSecret(v))
 			}
 		} else {
@@ -170,6 +400,7 @@ func collectDotenvSecrets(provider config.Provider) (SecretMap, error) {
 			for fromKey, toKey := range pathMap.Keys {
 				if value, exists := envFile[fromKey]; exists {
 					secrets[toKey] = value
+					mapIDs[toKey] = pathMap.ID
 					logger.Debug("Mapped key '%s' to '%s' (value: %s) from env file", fromKey, toKey, maskSecret(value))
 				} else {
 					logger.Debug("Key '%s' not found in env file '%s'", fromKey, pathMap.Path)
@@ -179,62 +410,182 @@ func collectDotenvSecrets(provider config.Provider) (SecretMap, error) {
 	}
 
 	logger.Debug("Dotenv provider collected %d secrets total", len(secrets))
-	return secrets, nil
+	return secrets, mapIDs, nil
 }
 
-// loadEnvFile loads a .env file and returns key-value pairs
+// envFileFlights deduplicates reads of the same env file by concurrent collections
+var envFileFlights flightGroup[map[string]string]
+
+// sharedReads counts the env file loads that shared one read with a concurrent load
+var sharedReads atomic.Int64
+
+// SharedReads returns the number of env file loads that shared one read with a concurrent
+// load of the same file since the process started, the only reuse of provider results
+// within feller
+func SharedReads() int64 {
+	return sharedReads.Load()
+}
+
+// loadEnvFile loads a .env file and returns key-value pairs. Concurrent loads of the same
+// file share one read, so the returned map must not be modified.
 func loadEnvFile(filePath string) (map[string]string, error) {
 	logger.Debug("Loading env file: %s", filePath)
 
-	file, err := os.Open(filePath)
+	filePath = config.LocalPath(filePath)
+	key := filePath
+	if abs, err := filepath.Abs(filePath); err == nil {
+		key = abs
+	}
+	env, shared, err := envFileFlights.Do(key, func() (map[string]string, error) {
+		return readEnvFile(filePath)
+	})
+	if shared {
+		sharedReads.Add(1)
+		logger.Debug("Shared concurrent read of env file '%s'", filePath)
+	}
+	return env, err
+}
+
+// readEnvFile reads and parses a .env file
+func readEnvFile(filePath string) (map[string]string, error) {
+	data, err := os.ReadFile(filePath)
 	if err != nil {
 		logger.Debug("Failed to open env file '%s': %v", filePath, err)
 		return nil, fmt.Errorf("failed to open env file %s: %w", filePath, err)
 	}
-	defer file.Close()
 
-	env := make(map[string]string)
-	scanner := bufio.NewScanner(file)
-	lineNum := 0
+	env, err := parseEnvFile(data)
+	if err != nil {
+		logger.Debug("Failed to parse env file '%s': %v", filePath, err)
+		return nil, fmt.Errorf("failed to parse env file %s: %w", filePath, err)
+	}
 
-	for scanner.Scan() {
-		lineNum++
-		line := strings.TrimSpace(scanner.Text())
+	logger.Debug("Successfully loaded %d variables from env file '%s'", len(env), filePath)
+	return env, nil
+}
+
+// parseEnvFile parses the KEY=VALUE lines of a .env file. Lines may be arbitrarily long;
+// lines without a usable key are skipped, and values containing NUL bytes are rejected
+// because they cannot be passed to a process environment.
+func parseEnvFile(data []byte) (map[string]string, error) {
+	text, err := decodeText(data)
+	if err != nil {
+		return nil, err
+	}
+
+	env := make(map[string]string)
+	for i, raw := range strings.Split(text, "\n") {
+		lineNum := i + 1
+		line := strings.TrimSpace(raw)
 
 		// Skip empty lines and comments
 		if line == "" || strings.HasPrefix(line, "#") {
-			logger.Debug("Skipping line %d (empty or comment): %s", lineNum, line)
 			continue
 		}
 
 		// Parse KEY=VALUE format
-		parts := strings.SplitN(line, "=", 2)
-		if len(parts) == 2 {
-			key := strings.TrimSpace(parts[0])
-			value := strings.TrimSpace(parts[1])
-
-			// Remove quotes if present
-			if len(value) >= 2 {
-				if (value[0] == '"' && value[len(value)-1] == '"') ||
-					(value[0] == '\'' && value[len(value)-1] == '\'') {
-					originalValue := value
-					value = value[1 : len(value)-1]
-					logger.Debug("Removed quotes from value: %s -> %s", originalValue, maskSecret(value))
-				}
+		key, value, found := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !found || key == "" || strings.ContainsFunc(key, isInvalidKeyRune) {
+			logger.Debug("Skipping malformed line %d", lineNum)
+			continue
+		}
+		value = strings.TrimSpace(value)
+
+		// Remove quotes if present
+		if len(value) >= 2 {
+			if (value[0] == '"' && value[len(value)-1] == '"') ||
+				(value[0] == '\'' && value[len(value)-1] == '\'') {
+				value = value[1 : len(value)-1]
+				logger.Debug("Removed quotes from value of '%s'", key)
 			}
+		}
 
-			env[key] = value
-			logger.Debug("Parsed line %d: %s=%s", lineNum, key, maskSecret(value))
-		} else {
-			logger.Debug("Skipping malformed line %d: %s", lineNum, line)
+		if strings.ContainsRune(value, 0) {
+			return nil, fmt.Errorf("line %d: value of %s contains a NUL byte", lineNum, key)
 		}
+
+		env[key] = value
+		logger.Debug("Parsed line %d: %s=%s", lineNum, key, maskSecret(value))
 	}
+	return env, nil
+}
 
-	if err := scanner.Err(); err != nil {
-		logger.Debug("Error reading env file '%s': %v", filePath, err)
-		return nil, fmt.Errorf("error reading env file %s: %w", filePath, err)
+// isInvalidKeyRune reports runes that cannot appear in an environment variable name
+func isInvalidKeyRune(r rune) bool {
+	return r == 0 || unicode.IsSpace(r)
+}
+
+// OutputKeys returns the sorted output keys a config can produce without resolving any
+// secret: the targets of every key mapping and, for dotenv maps in discovery mode, the
+// names in the file. Unreadable dotenv files are skipped.
+func OutputKeys(cfg *config.TellerConfig) []string {
+	seen := make(map[string]bool)
+	for _, provider := range cfg.Providers {
+		for _, pathMap := range provider.Maps {
+			for _, toKey := range pathMap.Keys {
+				seen[toKey] = true
+			}
+			if len(pathMap.Keys) > 0 || provider.Kind != KindDotenv {
+				continue
+			}
+			env, err := loadEnvFile(pathMap.Path)
+			if err != nil {
+				logger.Debug("Skipping keys of env file '%s': %v", pathMap.Path, err)
+				continue
+			}
+			for key := range env {
+				seen[key] = true
+			}
+		}
 	}
 
-	logger.Debug("Successfully loaded %d variables from env file '%s'", len(env), filePath)
-	return env, nil
+	keys := make([]string, 0, len(seen))
+	for key := range seen {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// SourceModified returns when the source of a collected secret last changed, for kinds that
+// expose it: the modification time of dotenv and bundle files. Environment variables have none.
+func SourceModified(cfg *config.TellerConfig, source SecretSource) (time.Time, bool) {
+	provider, ok := cfg.Providers[source.Provider]
+	if !ok || (provider.Kind != KindDotenv && provider.Kind != KindBundle) {
+		return time.Time{}, false
+	}
+	for _, pathMap := range provider.Maps {
+		if pathMap.ID != source.MapID {
+			continue
+		}
+		info, err := os.Stat(config.LocalPath(pathMap.Path))
+		if err != nil {
+			return time.Time{}, false
+		}
+		return info.ModTime(), true
+	}
+	return time.Time{}, false
+}
+
+// DotenvFiles returns the files read by the dotenv providers of cfg, sorted and without
+// duplicates
+func DotenvFiles(cfg *config.TellerConfig) []string {
+	seen := make(map[string]bool)
+	for _, provider := range cfg.Providers {
+		if provider.Kind != KindDotenv {
+			continue
+		}
+		for _, pathMap := range provider.Maps {
+			if pathMap.Path != "" {
+				seen[config.LocalPath(pathMap.Path)] = true
+			}
+		}
+	}
+	paths := make([]string, 0, len(seen))
+	for path := range seen {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
 }
@@ -1,7 +1,9 @@
 package providers
 
 import (
+	"encoding/binary"
 	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
@@ -393,6 +395,41 @@ func TestCollectSecretsWithResult(t *testing.T) {
 	}
 }
 
+func TestCollectSecretsSources(t *testing.T) {
+	t.Setenv("SOURCE_VAR", "from_env")
+
+	envFile := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(envFile, []byte("FILE_KEY=from_file\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write env file: %v", err)
+	}
+
+	cfg := &config.TellerConfig{
+		Providers: map[string]config.Provider{
+			"gha": {
+				Kind: "google_secretmanager",
+				Maps: []config.PathMap{{ID: "ci-map", Keys: map[string]string{"SOURCE_VAR": "FROM_ENV"}}},
+			},
+			"local": {
+				Kind: "dotenv",
+				Maps: []config.PathMap{{ID: "local-map", Path: envFile}},
+			},
+		},
+	}
+
+	result, err := CollectSecretsWithResult(cfg, false)
+	if err != nil {
+		t.Fatalf("CollectSecretsWithResult() unexpected error = %v", err)
+	}
+
+	expected := map[string]SecretSource{
+		"FROM_ENV": {Provider: "gha", Kind: "google_secretmanager", MapID: "ci-map"},
+		"FILE_KEY": {Provider: "local", Kind: "dotenv", MapID: "local-map"},
+	}
+	if !reflect.DeepEqual(result.Sources, expected) {
+		t.Errorf("CollectSecretsWithResult() Sources = %+v, want %+v", result.Sources, expected)
+	}
+}
+
 func TestLoadEnvFile(t *testing.T) {
 	t.Parallel()
 	tests := []struct {
@@ -446,6 +483,40 @@ ANOTHER_KEY=another_value`,
 			},
 			wantErr: false,
 		},
+		{
+			name:        "windows line endings and bom",
+			fileContent: "\xEF\xBB\xBFKEY1=value1\r\nKEY2=\"quoted value\"\r\n# comment\r\n",
+			expectedVars: map[string]string{
+				"KEY1": "value1",
+				"KEY2": "quoted value",
+			},
+		},
+		{
+			name:         "utf-16 file",
+			fileContent:  "\xFF\xFE" + encodeUTF16("KEY1=value1\r\nKEY2='single quoted'\r\n", binary.LittleEndian),
+			expectedVars: map[string]string{"KEY1": "value1", "KEY2": "single quoted"},
+		},
+		{
+			name:         "megabyte long line",
+			fileContent:  "BIG=" + strings.Repeat("x", 2<<20) + "\nNEXT=1\n",
+			expectedVars: map[string]string{"BIG": strings.Repeat("x", 2<<20), "NEXT": "1"},
+		},
+		{
+			name:         "lines without a usable key",
+			fileContent:  "=orphan\nexport KEY=1\nMY KEY=2\nGOOD=3\n",
+			expectedVars: map[string]string{"GOOD": "3"},
+		},
+		{
+			name:        "nul byte in value",
+			fileContent: "OK=1\nBAD=a\x00b\n",
+			wantErr:     true,
+			errContains: "line 2: value of BAD contains a NUL byte",
+		},
+		{
+			name:         "latin-1 file",
+			fileContent:  "KEY=gr\xFC\xDFe\n",
+			expectedVars: map[string]string{"KEY": "grüße"},
+		},
 		{
 			name:        "nonexistent file",
 			fileContent: "", // Not used for this test
@@ -585,7 +656,10 @@ func TestCollectGSMSecretsWithMissing(t *testing.T) {
 	for _, tt := range tests { //nolint:paralleltest // main function uses t.Setenv()
 		t.Run(tt.name, func(t *testing.T) {
 			// Note: Cannot use t.Parallel() here as main function uses t.Setenv()
-			secrets, missingVars := collectGSMSecretsWithMissing(tt.provider, tt.providerName)
+			secrets, _, missingVars, err := collectGSMSecretsWithMissing(tt.provider, tt.providerName, false, nil)
+			if err != nil {
+				t.Fatalf("collectGSMSecretsWithMissing() error = %v", err)
+			}
 
 			if !reflect.DeepEqual(secrets, tt.expectedSecrets) {
 				t.Errorf("collectGSMSecretsWithMissing() secrets = %v, want %v", secrets, tt.expectedSecrets)
@@ -701,7 +775,7 @@ VAR3=value3`,
 				tt.provider.Maps[0].Path = tmpFile.Name()
 			}
 
-			secrets, err := collectDotenvSecrets(tt.provider)
+			secrets, _, err := collectDotenvSecrets(tt.provider)
 
 			if tt.wantErr {
 				if err == nil {
@@ -723,3 +797,148 @@ VAR3=value3`,
 		})
 	}
 }
+
+func TestCollectSecretsSchema(t *testing.T) {
+	envFile := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(envFile, []byte("PORT=\" 8080\"\nDEBUG=yes\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write env file: %v", err)
+	}
+
+	cfg := &config.TellerConfig{
+		Providers: map[string]config.Provider{
+			"local": {Kind: "dotenv", Maps: []config.PathMap{{ID: "local-map", Path: envFile}}},
+		},
+		Schema: map[string]string{"PORT": "int", "DEBUG": "bool"},
+	}
+
+	result, err := CollectSecretsWithResult(cfg, false)
+	if err != nil {
+		t.Fatalf("CollectSecretsWithResult() unexpected error = %v", err)
+	}
+	expected := SecretMap{"PORT": "8080", "DEBUG": "true"}
+	if !reflect.DeepEqual(result.Secrets, expected) {
+		t.Errorf("CollectSecretsWithResult() = %v, want %v", result.Secrets, expected)
+	}
+
+	cfg.Schema["DEBUG"] = "int"
+	_, err = CollectSecretsWithResult(cfg, false)
+	if err == nil || !strings.Contains(err.Error(), "DEBUG (provider local, map local-map): expected int") {
+		t.Errorf("CollectSecretsWithResult() error = %v, want schema violation for DEBUG", err)
+	}
+}
+
+func TestCollectSecretsOrigins(t *testing.T) {
+	t.Setenv("SHARED_VAR", "from_env")
+
+	envFile := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(envFile, []byte("SHARED=from_file\nONLY_FILE=x\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write env file: %v", err)
+	}
+
+	cfg := &config.TellerConfig{
+		Providers: map[string]config.Provider{
+			"gha": {
+				Kind: "google_secretmanager",
+				Maps: []config.PathMap{{ID: "ci-map", Keys: map[string]string{"SHARED_VAR": "SHARED"}}},
+			},
+			"local": {
+				Kind: "dotenv",
+				Maps: []config.PathMap{{ID: "local-map", Path: envFile}},
+			},
+		},
+	}
+
+	result, err := CollectSecretsWithResult(cfg, false)
+	if err != nil {
+		t.Fatalf("CollectSecretsWithResult() unexpected error = %v", err)
+	}
+
+	if conflicts := result.Conflicts(); !reflect.DeepEqual(conflicts, []string{"SHARED"}) {
+		t.Errorf("Conflicts() = %v, want [SHARED]", conflicts)
+	}
+
+	// GSM providers are merged before dotenv providers, so the dotenv value wins
+	expected := []SecretSource{
+		{Provider: "gha", Kind: "google_secretmanager", MapID: "ci-map"},
+		{Provider: "local", Kind: "dotenv", MapID: "local-map"},
+	}
+	if !reflect.DeepEqual(result.Origins["SHARED"], expected) {
+		t.Errorf("Origins[SHARED] = %+v, want %+v", result.Origins["SHARED"], expected)
+	}
+	if result.Secrets["SHARED"] != "from_file" {
+		t.Errorf("Secrets[SHARED] = %q, want %q", result.Secrets["SHARED"], "from_file")
+	}
+}
+
+func TestSecretSourceString(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		expected string
+		source   SecretSource
+	}{
+		{name: "with map id", source: SecretSource{Provider: "gha", MapID: "ci"}, expected: "provider 'gha' (map 'ci')"},
+		{name: "without map id", source: SecretSource{Provider: "gha"}, expected: "provider 'gha'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := tt.source.String(); got != tt.expected {
+				t.Errorf("String() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestOutputKeys(t *testing.T) {
+	t.Parallel()
+	envPath := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(envPath, []byte("LOCAL_A=1\nLOCAL_B=2\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write env file: %v", err)
+	}
+
+	cfg := &config.TellerConfig{
+		Providers: map[string]config.Provider{
+			"gsm": {Kind: KindGoogleSecretManager, Maps: []config.PathMap{
+				{ID: "ci", Keys: map[string]string{"GSM_TOKEN": "API_TOKEN", "GSM_DB": "DATABASE_URL"}},
+				{ID: "no-keys"},
+			}},
+			"local": {Kind: KindDotenv, Maps: []config.PathMap{
+				{ID: "discovered", Path: envPath},
+				{ID: "mapped", Path: envPath, Keys: map[string]string{"LOCAL_A": "API_TOKEN"}},
+				{ID: "missing", Path: filepath.Join(t.TempDir(), "missing.env")},
+			}},
+		},
+	}
+
+	expected := []string{"API_TOKEN", "DATABASE_URL", "LOCAL_A", "LOCAL_B"}
+	if result := OutputKeys(cfg); !reflect.DeepEqual(result, expected) {
+		t.Errorf("OutputKeys() = %v, want %v", result, expected)
+	}
+	if result := OutputKeys(&config.TellerConfig{}); len(result) != 0 {
+		t.Errorf("OutputKeys() of empty config = %v, want none", result)
+	}
+}
+
+func TestSourceModified(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(path, []byte("A=1\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.TellerConfig{Providers: map[string]config.Provider{
+		"local": {Kind: KindDotenv, Maps: []config.PathMap{{ID: "env", Path: path}}},
+		"gsm":   {Kind: KindGoogleSecretManager, Maps: []config.PathMap{{ID: "ci"}}},
+	}}
+
+	if modified, ok := SourceModified(cfg, SecretSource{Provider: "local", MapID: "env"}); !ok || modified.IsZero() {
+		t.Errorf("SourceModified(dotenv) = %v, %v", modified, ok)
+	}
+	if _, ok := SourceModified(cfg, SecretSource{Provider: "gsm", MapID: "ci"}); ok {
+		t.Error("SourceModified(gsm) reported a time for environment variables")
+	}
+	if _, ok := SourceModified(cfg, SecretSource{Provider: "local", MapID: "missing"}); ok {
+		t.Error("SourceModified() reported a time for an unknown map")
+	}
+}
@@ -0,0 +1,160 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/containifyci/feller/pkg/config"
+)
+
+// RateLimit bounds the requests a provider sends to its API
+type RateLimit struct {
+	RequestsPerSecond float64 `yaml:"requests_per_second"`
+	Burst             int     `yaml:"burst"` // Requests sent without waiting; defaults to one second's worth
+}
+
+// rateLimitOptions are the provider options configuring client-side rate limiting
+type rateLimitOptions struct {
+	RateLimit *RateLimit `yaml:"rate_limit"`
+}
+
+// RateLimitFor returns the rate_limit option of a provider, or nil when it has none
+func RateLimitFor(provider config.Provider) (*RateLimit, error) {
+	if provider.Options.IsZero() {
+		return nil, nil
+	}
+	var opts rateLimitOptions
+	if err := provider.Options.Decode(&opts); err != nil {
+		return nil, fmt.Errorf("invalid rate_limit: %w", err)
+	}
+	if opts.RateLimit == nil {
+		return nil, nil
+	}
+	if err := opts.RateLimit.Check(); err != nil {
+		return nil, err
+	}
+	return opts.RateLimit, nil
+}
+
+// Check validates the rate and burst
+func (r RateLimit) Check() error {
+	if r.RequestsPerSecond <= 0 || math.IsInf(r.RequestsPerSecond, 0) || math.IsNaN(r.RequestsPerSecond) {
+		return errors.New("rate_limit requests_per_second must be a positive number")
+	}
+	if r.Burst < 0 {
+		return errors.New("rate_limit burst must not be negative")
+	}
+	return nil
+}
+
+// Limiter is a token bucket spacing out requests to a provider API. A nil Limiter does not
+// limit, but still counts requests.
+type Limiter struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+
+	now   func() time.Time
+	sleep func(context.Context, time.Duration) error
+}
+
+// NewLimiter returns a limiter for limit, starting with a full burst
+func NewLimiter(limit RateLimit) *Limiter {
+	burst := float64(limit.Burst)
+	if burst == 0 {
+		burst = math.Max(1, math.Ceil(limit.RequestsPerSecond))
+	}
+	return &Limiter{rate: limit.RequestsPerSecond, burst: burst, tokens: burst, now: time.Now, sleep: sleepContext}
+}
+
+// Wait blocks until a request may be sent and returns how long it waited
+func (l *Limiter) Wait(ctx context.Context) (time.Duration, error) {
+	if l == nil {
+		return 0, nil
+	}
+	l.mu.Lock()
+	now := l.now()
+	if !l.last.IsZero() {
+		l.tokens = math.Min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	}
+	l.last = now
+
+	// Take the token now, so concurrent callers queue up behind this one
+	l.tokens--
+	var delay time.Duration
+	if l.tokens < 0 {
+		delay = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	l.mu.Unlock()
+
+	if delay == 0 {
+		return 0, nil
+	}
+	if err := l.sleep(ctx, delay); err != nil {
+		return 0, err
+	}
+	return delay, nil
+}
+
+// sleepContext waits for d unless ctx is done first
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("rate limit wait interrupted: %w", ctx.Err())
+	}
+}
+
+// Timing records the requests one provider made while collecting, for --timings
+type Timing struct {
+	Provider  string
+	Kind      string
+	Requests  int
+	Throttled int           // Requests delayed by the rate limit
+	Waited    time.Duration // Total delay imposed by the rate limit
+	Elapsed   time.Duration // Total time spent collecting, including the delay
+	Cached    time.Duration // Age of the secrets served from the cache, 0 when resolved
+	CacheHit  bool          // Served from the cache instead of resolved
+}
+
+// meter counts the requests of one provider through its limiter. A nil meter does nothing.
+type meter struct {
+	limiter *Limiter
+	timing  Timing
+}
+
+// newMeter returns the meter of a provider, limited by its rate_limit option
+func newMeter(name string, provider config.Provider) (*meter, error) {
+	limit, err := RateLimitFor(provider)
+	if err != nil {
+		return nil, fmt.Errorf("provider %s: %w", name, err)
+	}
+	m := &meter{timing: Timing{Provider: name, Kind: provider.Kind}}
+	if limit != nil {
+		m.limiter = NewLimiter(*limit)
+	}
+	return m, nil
+}
+
+// wait waits for the limiter before a request and records it
+func (m *meter) wait() {
+	if m == nil {
+		return
+	}
+	// The background context is never cancelled, so waiting cannot fail
+	waited, _ := m.limiter.Wait(context.Background())
+	m.timing.Requests++
+	if waited > 0 {
+		m.timing.Throttled++
+		m.timing.Waited += waited
+	}
+}
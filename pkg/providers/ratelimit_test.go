@@ -0,0 +1,185 @@
+package providers
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/containifyci/feller/pkg/config"
+	"gopkg.in/yaml.v3"
+)
+
+// fakeClock drives a limiter without sleeping
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) limiter(limit RateLimit) *Limiter {
+	l := NewLimiter(limit)
+	l.now = func() time.Time { return c.now }
+	l.sleep = func(_ context.Context, d time.Duration) error {
+		c.now = c.now.Add(d)
+		return nil
+	}
+	return l
+}
+
+func TestLimiterWait(t *testing.T) {
+	t.Parallel()
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	l := clock.limiter(RateLimit{RequestsPerSecond: 2, Burst: 2})
+
+	var waits []time.Duration
+	for range 4 {
+		waited, err := l.Wait(context.Background())
+		if err != nil {
+			t.Fatalf("Wait() error = %v", err)
+		}
+		waits = append(waits, waited)
+	}
+	// The burst passes at once, then requests are spaced at the rate
+	expected := []time.Duration{0, 0, 500 * time.Millisecond, 500 * time.Millisecond}
+	for i := range expected {
+		if waits[i] != expected[i] {
+			t.Errorf("Wait() #%d = %s, expected %s", i+1, waits[i], expected[i])
+		}
+	}
+
+	// Idle time refills the bucket, up to the burst
+	clock.now = clock.now.Add(10 * time.Second)
+	for i := range 2 {
+		if waited, _ := l.Wait(context.Background()); waited != 0 {
+			t.Errorf("Wait() after idle #%d = %s, expected no delay", i+1, waited)
+		}
+	}
+	if waited, _ := l.Wait(context.Background()); waited != 500*time.Millisecond {
+		t.Errorf("Wait() beyond the burst = %s, expected 500ms", waited)
+	}
+}
+
+func TestLimiterWaitCancelled(t *testing.T) {
+	t.Parallel()
+	l := NewLimiter(RateLimit{RequestsPerSecond: 0.001, Burst: 1})
+	if _, err := l.Wait(context.Background()); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := l.Wait(ctx); err == nil || !strings.Contains(err.Error(), "rate limit wait interrupted") {
+		t.Errorf("Wait() error = %v, expected an interruption", err)
+	}
+
+	var unlimited *Limiter
+	if waited, err := unlimited.Wait(ctx); waited != 0 || err != nil {
+		t.Errorf("nil Wait() = %s, %v", waited, err)
+	}
+}
+
+func TestRateLimitFor(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name        string
+		options     string
+		expected    *RateLimit
+		errContains string
+	}{
+		{name: "no options"},
+		{name: "other options", options: "identity: key.txt"},
+		{name: "rate and burst", options: "rate_limit: {requests_per_second: 5, burst: 10}", expected: &RateLimit{RequestsPerSecond: 5, Burst: 10}},
+		{name: "fractional rate", options: "rate_limit: {requests_per_second: 0.5}", expected: &RateLimit{RequestsPerSecond: 0.5}},
+		{name: "zero rate", options: "rate_limit: {burst: 3}", errContains: "requests_per_second must be a positive number"},
+		{name: "negative burst", options: "rate_limit: {requests_per_second: 1, burst: -1}", errContains: "burst must not be negative"},
+		{name: "not a number", options: "rate_limit: {requests_per_second: fast}", errContains: "invalid rate_limit"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var provider config.Provider
+			if tt.options != "" {
+				provider = withOptions(t, provider, tt.options)
+			}
+			limit, err := RateLimitFor(provider)
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Errorf("RateLimitFor() error = %v, expected to contain %q", err, tt.errContains)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RateLimitFor() unexpected error = %v", err)
+			}
+			if (limit == nil) != (tt.expected == nil) || (limit != nil && *limit != *tt.expected) {
+				t.Errorf("RateLimitFor() = %+v, expected %+v", limit, tt.expected)
+			}
+		})
+	}
+}
+
+//nolint:paralleltest // sets environment variables
+func TestCollectTimings(t *testing.T) {
+	server := fakeVault(t, map[string]string{"secret/data/app": `{"data":{"data":{"API_KEY":"abc"}}}`})
+	t.Setenv("VAULT_TOKEN", "t0k3n")
+	path := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(path, []byte("DB_URL=db\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	content := `providers:
+  vault:
+    kind: hashicorp_vault
+    options:
+      address: ` + server.URL + `
+      rate_limit: {requests_per_second: 1000, burst: 1}
+    maps:
+      - id: a
+        path: secret/data/app
+      - id: b
+        path: secret/data/app
+  local:
+    kind: dotenv
+    options:
+      rate_limit: {requests_per_second: 1000, burst: 1}
+    maps:
+      - id: c
+        path: ` + path + `
+`
+	var cfg config.TellerConfig
+	if err := yaml.Unmarshal([]byte(content), &cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := CollectSecretsWithResult(&cfg, false)
+	if err != nil {
+		t.Fatalf("CollectSecretsWithResult() error = %v", err)
+	}
+	timings := make(map[string]Timing)
+	for _, timing := range result.Timings {
+		timings[timing.Provider] = timing
+	}
+	if timing := timings["vault"]; timing.Kind != KindHashiCorpVault || timing.Requests != 2 || timing.Throttled != 1 || timing.Waited <= 0 {
+		t.Errorf("Timing = %+v, expected 2 requests with 1 throttled", timing)
+	}
+	// Reading local files sends no request, so it is neither counted nor limited
+	if timing := timings["local"]; timing.Kind != KindDotenv || timing.Requests != 0 || timing.Throttled != 0 {
+		t.Errorf("Timing = %+v, expected no requests", timing)
+	}
+
+	cfg.Providers["local"] = withOptions(t, cfg.Providers["local"], "rate_limit: {requests_per_second: -1}")
+	if _, err := CollectSecretsWithResult(&cfg, false); err == nil || !strings.Contains(err.Error(), "provider local: rate_limit requests_per_second") {
+		t.Errorf("CollectSecretsWithResult() error = %v, expected an invalid rate_limit", err)
+	}
+}
+
+// withOptions returns provider with its options replaced by the YAML mapping options
+func withOptions(t *testing.T, provider config.Provider, options string) config.Provider {
+	t.Helper()
+	var node yaml.Node
+	if err := yaml.Unmarshal([]byte(options), &node); err != nil {
+		t.Fatal(err)
+	}
+	provider.Options = *node.Content[0]
+	return provider
+}
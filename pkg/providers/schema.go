@@ -0,0 +1,155 @@
+package providers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/containifyci/feller/pkg/logger"
+)
+
+// Supported schema types
+const (
+	TypeString = "string"
+	TypeInt    = "int"
+	TypeBool   = "bool"
+	TypeURL    = "url"
+	TypeEmail  = "email"
+	TypeJSON   = "json"
+)
+
+// CoerceFunc validates a value and returns its normalized form
+type CoerceFunc func(value string) (string, error)
+
+var coercers = map[string]CoerceFunc{
+	TypeString: func(v string) (string, error) { return v, nil },
+	TypeInt:    coerceInt,
+	TypeBool:   coerceBool,
+	TypeURL:    coerceURL,
+	TypeEmail:  coerceEmail,
+	TypeJSON:   coerceJSON,
+}
+
+// SchemaTypes returns the supported schema type names sorted alphabetically
+func SchemaTypes() []string {
+	types := make([]string, 0, len(coercers))
+	for typ := range coercers {
+		types = append(types, typ)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// SchemaError lists every collected secret whose value does not match its declared type
+type SchemaError struct {
+	Violations []string
+}
+
+func (e *SchemaError) Error() string {
+	return fmt.Sprintf("%d secret(s) failed schema validation:\n  - %s", len(e.Violations), strings.Join(e.Violations, "\n  - "))
+}
+
+// ApplySchema validates and normalizes secrets in place according to their declared types.
+// All violations are reported together, naming the provider that supplied each value.
+func ApplySchema(secrets SecretMap, schema map[string]string, sources map[string]SecretSource) error {
+	if len(schema) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(schema))
+	for key := range schema {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var violations []string
+	for _, key := range keys {
+		typ := schema[key]
+		coerce, ok := coercers[typ]
+		if !ok {
+			violations = append(violations, fmt.Sprintf("%s: unknown schema type %q", key, typ))
+			continue
+		}
+
+		value, exists := secrets[key]
+		if !exists {
+			logger.Debug("Skipping schema check for unresolved key '%s'", key)
+			continue
+		}
+
+		normalized, err := coerce(value)
+		if err != nil {
+			violations = append(violations, fmt.Sprintf("%s%s: expected %s: %v", key, describeSource(sources[key]), typ, err))
+			continue
+		}
+		if normalized != value {
+			logger.Debug("Normalized key '%s' as %s", key, typ)
+		}
+		secrets[key] = normalized
+	}
+
+	if len(violations) > 0 {
+		return &SchemaError{Violations: violations}
+	}
+	return nil
+}
+
+// describeSource formats the provider that supplied a value for error messages
+func describeSource(source SecretSource) string {
+	if source.Provider == "" {
+		return ""
+	}
+	if source.MapID == "" {
+		return fmt.Sprintf(" (provider %s)", source.Provider)
+	}
+	return fmt.Sprintf(" (provider %s, map %s)", source.Provider, source.MapID)
+}
+
+func coerceInt(value string) (string, error) {
+	n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil {
+		return "", errors.New("value is not an integer")
+	}
+	return strconv.FormatInt(n, 10), nil
+}
+
+func coerceBool(value string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "1", "t", "true", "yes", "y", "on":
+		return "true", nil
+	case "0", "f", "false", "no", "n", "off":
+		return "false", nil
+	default:
+		return "", errors.New("value is not a boolean")
+	}
+}
+
+func coerceURL(value string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(value))
+	if err != nil || u.Scheme == "" || (u.Host == "" && u.Opaque == "") {
+		return "", errors.New("value is not an absolute URL")
+	}
+	return u.String(), nil
+}
+
+func coerceEmail(value string) (string, error) {
+	addr, err := mail.ParseAddress(strings.TrimSpace(value))
+	if err != nil {
+		return "", errors.New("value is not an email address")
+	}
+	return addr.Address, nil
+}
+
+func coerceJSON(value string) (string, error) {
+	var out bytes.Buffer
+	if err := json.Compact(&out, []byte(value)); err != nil {
+		return "", errors.New("value is not valid JSON")
+	}
+	return out.String(), nil
+}
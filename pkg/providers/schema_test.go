@@ -0,0 +1,145 @@
+package providers
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCoercers(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		typ      string
+		value    string
+		expected string
+		wantErr  bool
+	}{
+		{name: "string unchanged", typ: TypeString, value: " any value ", expected: " any value "},
+		{name: "int", typ: TypeInt, value: " 0042 ", expected: "42"},
+		{name: "negative int", typ: TypeInt, value: "-7", expected: "-7"},
+		{name: "invalid int", typ: TypeInt, value: "4.2", wantErr: true},
+		{name: "bool yes", typ: TypeBool, value: "Yes", expected: "true"},
+		{name: "bool zero", typ: TypeBool, value: "0", expected: "false"},
+		{name: "invalid bool", typ: TypeBool, value: "maybe", wantErr: true},
+		{name: "url", typ: TypeURL, value: " https://example.com/path ", expected: "https://example.com/path"},
+		{name: "url without scheme", typ: TypeURL, value: "example.com", wantErr: true},
+		{name: "email", typ: TypeEmail, value: "Ops <ops@example.com>", expected: "ops@example.com"},
+		{name: "invalid email", typ: TypeEmail, value: "ops.example.com", wantErr: true},
+		{name: "json compacted", typ: TypeJSON, value: "{\n  \"a\": [1, 2]\n}", expected: `{"a":[1,2]}`},
+		{name: "invalid json", typ: TypeJSON, value: "{a:1}", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			result, err := coercers[tt.typ](tt.value)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("coerce %s(%q) expected error but got none", tt.typ, tt.value)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("coerce %s(%q) unexpected error = %v", tt.typ, tt.value, err)
+			}
+			if result != tt.expected {
+				t.Errorf("coerce %s(%q) = %q, want %q", tt.typ, tt.value, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestApplySchema(t *testing.T) {
+	t.Parallel()
+	sources := map[string]SecretSource{
+		"PORT":  {Provider: "gha", Kind: "google_secretmanager", MapID: "ci"},
+		"DEBUG": {Provider: "local", Kind: "dotenv"},
+	}
+
+	tests := []struct {
+		secrets     SecretMap
+		schema      map[string]string
+		expected    SecretMap
+		name        string
+		errContains []string
+		wantErr     bool
+	}{
+		{
+			name:     "no schema",
+			secrets:  SecretMap{"PORT": " 80 "},
+			expected: SecretMap{"PORT": " 80 "},
+		},
+		{
+			name:     "values normalized",
+			secrets:  SecretMap{"PORT": " 8080", "DEBUG": "on", "OTHER": "x"},
+			schema:   map[string]string{"PORT": TypeInt, "DEBUG": TypeBool},
+			expected: SecretMap{"PORT": "8080", "DEBUG": "true", "OTHER": "x"},
+		},
+		{
+			name:     "unresolved key skipped",
+			secrets:  SecretMap{},
+			schema:   map[string]string{"PORT": TypeInt},
+			expected: SecretMap{},
+		},
+		{
+			name:    "all violations reported with provider",
+			secrets: SecretMap{"PORT": "eighty", "DEBUG": "maybe"},
+			schema:  map[string]string{"PORT": TypeInt, "DEBUG": TypeBool},
+			wantErr: true,
+			errContains: []string{
+				"2 secret(s) failed schema validation",
+				"DEBUG (provider local): expected bool",
+				"PORT (provider gha, map ci): expected int",
+			},
+		},
+		{
+			name:        "unknown type",
+			secrets:     SecretMap{"PORT": "80"},
+			schema:      map[string]string{"PORT": "port"},
+			wantErr:     true,
+			errContains: []string{`PORT: unknown schema type "port"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			err := ApplySchema(tt.secrets, tt.schema, sources)
+
+			if tt.wantErr {
+				var schemaErr *SchemaError
+				if !errors.As(err, &schemaErr) {
+					t.Fatalf("ApplySchema() error = %v, want *SchemaError", err)
+				}
+				for _, want := range tt.errContains {
+					if !strings.Contains(err.Error(), want) {
+						t.Errorf("ApplySchema() error = %v, expected to contain %q", err, want)
+					}
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("ApplySchema() unexpected error = %v", err)
+			}
+			if !reflect.DeepEqual(tt.secrets, tt.expected) {
+				t.Errorf("ApplySchema() = %v, want %v", tt.secrets, tt.expected)
+			}
+		})
+	}
+}
+
+func TestApplySchemaDoesNotLeakValues(t *testing.T) {
+	t.Parallel()
+	err := ApplySchema(SecretMap{"TOKEN": "super-secret-value"}, map[string]string{"TOKEN": TypeJSON}, nil)
+	if err == nil {
+		t.Fatal("ApplySchema() expected error but got none")
+	}
+	if strings.Contains(err.Error(), "super-secret-value") {
+		t.Errorf("ApplySchema() error leaks secret value: %v", err)
+	}
+}
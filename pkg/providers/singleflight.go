@@ -0,0 +1,49 @@
+package providers
+
+import "sync"
+
+// flight is a resolution in progress, shared by every caller asking for the same key
+type flight[T any] struct {
+	done   chan struct{}
+	value  T
+	err    error
+	shared bool
+}
+
+// flightGroup deduplicates concurrent resolutions of the same key: while one is in
+// progress, further callers wait for it and receive its result instead of hitting the
+// backend again. Results are not cached beyond the call, so sequential calls still see
+// changes. Values are shared between callers and must not be modified.
+type flightGroup[T any] struct {
+	mu      sync.Mutex
+	flights map[string]*flight[T]
+}
+
+// Do runs fn for key unless a call for key is already in flight, in which case it waits
+// for that call. shared reports whether the result was handed to more than one caller.
+func (g *flightGroup[T]) Do(key string, fn func() (T, error)) (value T, shared bool, err error) {
+	g.mu.Lock()
+	if f, ok := g.flights[key]; ok {
+		f.shared = true
+		g.mu.Unlock()
+		<-f.done
+		return f.value, true, f.err
+	}
+	if g.flights == nil {
+		g.flights = make(map[string]*flight[T])
+	}
+	f := &flight[T]{done: make(chan struct{})}
+	g.flights[key] = f
+	g.mu.Unlock()
+
+	// Remove the flight even if fn panics, so later callers are not blocked forever
+	defer func() {
+		g.mu.Lock()
+		delete(g.flights, key)
+		shared = f.shared
+		g.mu.Unlock()
+		close(f.done)
+	}()
+	f.value, f.err = fn()
+	return f.value, false, f.err
+}
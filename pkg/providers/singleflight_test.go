@@ -0,0 +1,130 @@
+package providers
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/containifyci/feller/pkg/config"
+)
+
+func TestFlightGroupDeduplicates(t *testing.T) {
+	t.Parallel()
+	var g flightGroup[string]
+	var calls atomic.Int32
+	release := make(chan struct{})
+
+	const callers = 10
+	var wg sync.WaitGroup
+	var sharedCount atomic.Int32
+	results := make([]string, callers)
+	for i := range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			value, shared, err := g.Do("map", func() (string, error) {
+				calls.Add(1)
+				<-release
+				return "resolved", nil
+			})
+			if err != nil {
+				t.Errorf("Do() unexpected error = %v", err)
+			}
+			if shared {
+				sharedCount.Add(1)
+			}
+			results[i] = value
+		}()
+	}
+
+	// Wait until every caller is either running fn or waiting for it
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		g.mu.Lock()
+		f := g.flights["map"]
+		joined := f != nil && f.shared
+		g.mu.Unlock()
+		if joined || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+
+	if n := calls.Load(); n >= callers {
+		t.Errorf("Do() ran fn %d times for %d concurrent callers, want deduplication", n, callers)
+	}
+	if sharedCount.Load() == 0 {
+		t.Errorf("Do() reported no shared results")
+	}
+	for i, result := range results {
+		if result != "resolved" {
+			t.Errorf("caller %d got %q, want %q", i, result, "resolved")
+		}
+	}
+}
+
+func TestFlightGroupSequentialAndErrors(t *testing.T) {
+	t.Parallel()
+	var g flightGroup[int]
+	calls := 0
+	fn := func() (int, error) {
+		calls++
+		return calls, nil
+	}
+
+	// Results are not cached once a call has finished
+	first, shared, _ := g.Do("k", fn)
+	second, _, _ := g.Do("k", fn)
+	if first != 1 || second != 2 || shared {
+		t.Errorf("sequential Do() = %d, %d (shared %v), want 1, 2 (not shared)", first, second, shared)
+	}
+
+	errBackend := errors.New("backend down")
+	if _, _, err := g.Do("k", func() (int, error) { return 0, errBackend }); !errors.Is(err, errBackend) {
+		t.Errorf("Do() error = %v, want %v", err, errBackend)
+	}
+
+	// A panicking call must not leave the key blocked
+	func() {
+		defer func() { _ = recover() }()
+		_, _, _ = g.Do("k", func() (int, error) { panic("boom") })
+	}()
+	if value, _, err := g.Do("k", func() (int, error) { return 7, nil }); value != 7 || err != nil {
+		t.Errorf("Do() after panic = %d, %v, want 7", value, err)
+	}
+}
+
+func TestConcurrentCollection(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(path, []byte("A=1\nB=2\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write env file: %v", err)
+	}
+	cfg := &config.TellerConfig{Providers: map[string]config.Provider{
+		"one": {Kind: KindDotenv, Maps: []config.PathMap{{ID: "all", Path: path}}},
+		"two": {Kind: KindDotenv, Maps: []config.PathMap{{ID: "some", Path: path, Keys: map[string]string{"A": "C"}}}},
+	}}
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := CollectSecretsWithResult(cfg, false)
+			if err != nil {
+				t.Errorf("CollectSecretsWithResult() unexpected error = %v", err)
+				return
+			}
+			if result.Secrets["A"] != "1" || result.Secrets["B"] != "2" || result.Secrets["C"] != "1" {
+				t.Errorf("CollectSecretsWithResult() = %v", result.Secrets)
+			}
+		}()
+	}
+	wg.Wait()
+}
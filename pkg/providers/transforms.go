@@ -0,0 +1,198 @@
+package providers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/containifyci/feller/pkg/config"
+	"github.com/containifyci/feller/pkg/logger"
+)
+
+// maxDecompressedSize limits gzip_decode output to guard against decompression bombs
+const maxDecompressedSize = 10 << 20
+
+// TransformTypes lists the supported transform step types
+var TransformTypes = []string{"base64_decode", "gzip_decode", "json_extract", "lower", "replace", "template", "trim", "upper"}
+
+// TransformFunc transforms a single secret value
+type TransformFunc func(value string) (string, error)
+
+// TemplateData is the data available to template transforms
+type TemplateData struct {
+	Key     string
+	Value   string
+	Secrets SecretMap
+}
+
+// BuildTransform returns the function implementing a configured transform step.
+// The secrets map is exposed to template transforms as .Secrets.
+func BuildTransform(t config.Transform, key string, secrets SecretMap) (TransformFunc, error) {
+	switch t.Type {
+	case "trim":
+		return func(v string) (string, error) { return strings.TrimSpace(v), nil }, nil
+	case "upper":
+		return func(v string) (string, error) { return strings.ToUpper(v), nil }, nil
+	case "lower":
+		return func(v string) (string, error) { return strings.ToLower(v), nil }, nil
+	case "replace":
+		if t.From == "" {
+			return nil, errors.New("replace transform requires 'from'")
+		}
+		return func(v string) (string, error) { return strings.ReplaceAll(v, t.From, t.To), nil }, nil
+	case "base64_decode":
+		return base64Decode, nil
+	case "gzip_decode":
+		return gzipDecode, nil
+	case "json_extract":
+		if t.Path == "" {
+			return nil, errors.New("json_extract transform requires a path")
+		}
+		return func(v string) (string, error) { return jsonExtract(v, t.Path) }, nil
+	case "template":
+		tmpl, err := template.New(key).Option("missingkey=error").Parse(t.Template)
+		if err != nil {
+			return nil, fmt.Errorf("invalid template: %w", err)
+		}
+		return func(v string) (string, error) {
+			var out bytes.Buffer
+			if err := tmpl.Execute(&out, TemplateData{Key: key, Value: v, Secrets: secrets}); err != nil {
+				return "", fmt.Errorf("template execution failed: %w", err)
+			}
+			return out.String(), nil
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown transform type: %q", t.Type)
+	}
+}
+
+// Chain composes transform functions, applying them in order
+func Chain(funcs ...TransformFunc) TransformFunc {
+	return func(value string) (string, error) {
+		for _, fn := range funcs {
+			var err error
+			if value, err = fn(value); err != nil {
+				return "", err
+			}
+		}
+		return value, nil
+	}
+}
+
+// ApplyTransforms runs the configured transform pipelines against the collected secrets.
+// Templates see the values as they were before any transform ran.
+func ApplyTransforms(secrets SecretMap, transforms map[string][]config.Transform) error {
+	if len(transforms) == 0 {
+		return nil
+	}
+
+	snapshot := make(SecretMap, len(secrets))
+	for k, v := range secrets {
+		snapshot[k] = v
+	}
+
+	keys := make([]string, 0, len(transforms))
+	for key := range transforms {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value, exists := secrets[key]
+		if !exists {
+			logger.Debug("Skipping transforms for unresolved key '%s'", key)
+			continue
+		}
+
+		steps := transforms[key]
+		funcs := make([]TransformFunc, 0, len(steps))
+		for i, step := range steps {
+			fn, err := BuildTransform(step, key, snapshot)
+			if err != nil {
+				return fmt.Errorf("transform %d (%s) of key %s: %w", i+1, step.Type, key, err)
+			}
+			funcs = append(funcs, fn)
+		}
+
+		transformed, err := Chain(funcs...)(value)
+		if err != nil {
+			return fmt.Errorf("failed to transform key %s: %w", key, err)
+		}
+		secrets[key] = transformed
+		logger.Debug("Applied %d transform(s) to key '%s' (value: %s)", len(steps), key, maskSecret(transformed))
+	}
+
+	return nil
+}
+
+func base64Decode(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if decoded, err := enc.DecodeString(value); err == nil {
+			return string(decoded), nil
+		}
+	}
+	return "", errors.New("value is not valid base64")
+}
+
+func gzipDecode(value string) (string, error) {
+	reader, err := gzip.NewReader(strings.NewReader(value))
+	if err != nil {
+		return "", fmt.Errorf("value is not valid gzip data: %w", err)
+	}
+	defer reader.Close()
+
+	decoded, err := io.ReadAll(io.LimitReader(reader, maxDecompressedSize+1))
+	if err != nil {
+		return "", fmt.Errorf("failed to decompress gzip data: %w", err)
+	}
+	if len(decoded) > maxDecompressedSize {
+		return "", fmt.Errorf("decompressed value exceeds %d bytes", maxDecompressedSize)
+	}
+	return string(decoded), nil
+}
+
+// jsonExtract returns the element at a dot-separated path (object keys or array indexes).
+// String results are returned as-is, other values as compact JSON.
+func jsonExtract(value, path string) (string, error) {
+	var current any
+	if err := json.Unmarshal([]byte(value), &current); err != nil {
+		return "", fmt.Errorf("value is not valid JSON: %w", err)
+	}
+
+	for _, segment := range strings.Split(strings.TrimPrefix(path, "."), ".") {
+		switch node := current.(type) {
+		case map[string]any:
+			next, exists := node[segment]
+			if !exists {
+				return "", fmt.Errorf("json path %q: key %q not found", path, segment)
+			}
+			current = next
+		case []any:
+			index, err := strconv.Atoi(segment)
+			if err != nil || index < 0 || index >= len(node) {
+				return "", fmt.Errorf("json path %q: invalid array index %q", path, segment)
+			}
+			current = node[index]
+		default:
+			return "", fmt.Errorf("json path %q: cannot descend into %q", path, segment)
+		}
+	}
+
+	if str, ok := current.(string); ok {
+		return str, nil
+	}
+	encoded, err := json.Marshal(current)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode json value: %w", err)
+	}
+	return string(encoded), nil
+}
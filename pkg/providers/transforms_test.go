@@ -0,0 +1,261 @@
+package providers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/containifyci/feller/pkg/config"
+)
+
+func gzipString(t *testing.T, s string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write([]byte(s)); err != nil {
+		t.Fatalf("Failed to gzip value: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Failed to close gzip writer: %v", err)
+	}
+	return buf.String()
+}
+
+func TestBuildTransform(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name        string
+		value       string
+		expected    string
+		errContains string
+		transform   config.Transform
+		wantErr     bool
+	}{
+		{name: "trim", transform: config.Transform{Type: "trim"}, value: "  value\n", expected: "value"},
+		{name: "upper", transform: config.Transform{Type: "upper"}, value: "value", expected: "VALUE"},
+		{name: "lower", transform: config.Transform{Type: "lower"}, value: "VaLuE", expected: "value"},
+		{
+			name:      "replace",
+			transform: config.Transform{Type: "replace", From: "http://", To: "https://"},
+			value:     "http://example.com",
+			expected:  "https://example.com",
+		},
+		{
+			name:      "base64 decode",
+			transform: config.Transform{Type: "base64_decode"},
+			value:     base64.StdEncoding.EncodeToString([]byte("decoded")),
+			expected:  "decoded",
+		},
+		{
+			name:      "base64 decode unpadded url encoding",
+			transform: config.Transform{Type: "base64_decode"},
+			value:     base64.RawURLEncoding.EncodeToString([]byte("a?b>")),
+			expected:  "a?b>",
+		},
+		{
+			name:        "base64 decode invalid",
+			transform:   config.Transform{Type: "base64_decode"},
+			value:       "not base64!",
+			wantErr:     true,
+			errContains: "not valid base64",
+		},
+		{
+			name:        "gzip decode invalid",
+			transform:   config.Transform{Type: "gzip_decode"},
+			value:       "plain",
+			wantErr:     true,
+			errContains: "not valid gzip data",
+		},
+		{
+			name:      "json extract nested string",
+			transform: config.Transform{Type: "json_extract", Path: "db.password"},
+			value:     `{"db":{"password":"s3cret"}}`,
+			expected:  "s3cret",
+		},
+		{
+			name:      "json extract array index",
+			transform: config.Transform{Type: "json_extract", Path: "hosts.1"},
+			value:     `{"hosts":["a","b"]}`,
+			expected:  "b",
+		},
+		{
+			name:      "json extract non-string",
+			transform: config.Transform{Type: "json_extract", Path: "db"},
+			value:     `{"db":{"port":5432}}`,
+			expected:  `{"port":5432}`,
+		},
+		{
+			name:        "json extract missing key",
+			transform:   config.Transform{Type: "json_extract", Path: "db.user"},
+			value:       `{"db":{}}`,
+			wantErr:     true,
+			errContains: `key "user" not found`,
+		},
+		{
+			name:        "json extract invalid json",
+			transform:   config.Transform{Type: "json_extract", Path: "db"},
+			value:       "not json",
+			wantErr:     true,
+			errContains: "not valid JSON",
+		},
+		{
+			name:      "template",
+			transform: config.Transform{Type: "template", Template: "{{ .Key }}={{ .Value }}@{{ .Secrets.HOST }}"},
+			value:     "user",
+			expected:  "KEY=user@db.local",
+		},
+		{
+			name:        "template references unknown secret",
+			transform:   config.Transform{Type: "template", Template: "{{ .Secrets.MISSING }}"},
+			value:       "user",
+			wantErr:     true,
+			errContains: "template execution failed",
+		},
+		{
+			name:        "unknown type",
+			transform:   config.Transform{Type: "rot13"},
+			wantErr:     true,
+			errContains: `unknown transform type: "rot13"`,
+		},
+		{
+			name:        "replace without from",
+			transform:   config.Transform{Type: "replace", To: "x"},
+			wantErr:     true,
+			errContains: "requires 'from'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			fn, err := BuildTransform(tt.transform, "KEY", SecretMap{"HOST": "db.local"})
+			var result string
+			if err == nil {
+				result, err = fn(tt.value)
+			}
+
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("transform %s expected error but got none", tt.transform.Type)
+				} else if !strings.Contains(err.Error(), tt.errContains) {
+					t.Errorf("transform %s error = %v, expected to contain %q", tt.transform.Type, err, tt.errContains)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("transform %s unexpected error = %v", tt.transform.Type, err)
+			}
+			if result != tt.expected {
+				t.Errorf("transform %s = %q, want %q", tt.transform.Type, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGzipDecode(t *testing.T) {
+	t.Parallel()
+	result, err := gzipDecode(gzipString(t, "compressed value"))
+	if err != nil {
+		t.Fatalf("gzipDecode() unexpected error = %v", err)
+	}
+	if result != "compressed value" {
+		t.Errorf("gzipDecode() = %q, want %q", result, "compressed value")
+	}
+}
+
+func TestApplyTransforms(t *testing.T) {
+	t.Parallel()
+	encoded := base64.StdEncoding.EncodeToString([]byte(gzipString(t, `{"db":{"password":" pw "}}`)))
+
+	tests := []struct {
+		secrets     SecretMap
+		transforms  map[string][]config.Transform
+		expected    SecretMap
+		name        string
+		errContains string
+		wantErr     bool
+	}{
+		{
+			name:       "no transforms",
+			secrets:    SecretMap{"KEY": " value "},
+			transforms: nil,
+			expected:   SecretMap{"KEY": " value "},
+		},
+		{
+			name:    "steps applied in order",
+			secrets: SecretMap{"PASSWORD": encoded},
+			transforms: map[string][]config.Transform{
+				"PASSWORD": {
+					{Type: "base64_decode"},
+					{Type: "gzip_decode"},
+					{Type: "json_extract", Path: "db.password"},
+					{Type: "trim"},
+					{Type: "upper"},
+				},
+			},
+			expected: SecretMap{"PASSWORD": "PW"},
+		},
+		{
+			name:    "templates see untransformed values",
+			secrets: SecretMap{"USER": " admin ", "URL": "ignored"},
+			transforms: map[string][]config.Transform{
+				"USER": {{Type: "trim"}},
+				"URL":  {{Type: "template", Template: "postgres://{{ .Secrets.USER }}@host"}},
+			},
+			expected: SecretMap{"USER": "admin", "URL": "postgres:// admin @host"},
+		},
+		{
+			name:    "unresolved key is skipped",
+			secrets: SecretMap{"KEY": "value"},
+			transforms: map[string][]config.Transform{
+				"MISSING": {{Type: "upper"}},
+			},
+			expected: SecretMap{"KEY": "value"},
+		},
+		{
+			name:    "failing step reports key",
+			secrets: SecretMap{"KEY": "not json"},
+			transforms: map[string][]config.Transform{
+				"KEY": {{Type: "json_extract", Path: "a"}},
+			},
+			wantErr:     true,
+			errContains: "failed to transform key KEY",
+		},
+		{
+			name:    "invalid step reports position",
+			secrets: SecretMap{"KEY": "value"},
+			transforms: map[string][]config.Transform{
+				"KEY": {{Type: "trim"}, {Type: "reverse"}},
+			},
+			wantErr:     true,
+			errContains: "transform 2 (reverse) of key KEY",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			err := ApplyTransforms(tt.secrets, tt.transforms)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ApplyTransforms() expected error but got none")
+				} else if !strings.Contains(err.Error(), tt.errContains) {
+					t.Errorf("ApplyTransforms() error = %v, expected to contain %q", err, tt.errContains)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("ApplyTransforms() unexpected error = %v", err)
+			}
+			if !reflect.DeepEqual(tt.secrets, tt.expected) {
+				t.Errorf("ApplyTransforms() = %v, want %v", tt.secrets, tt.expected)
+			}
+		})
+	}
+}
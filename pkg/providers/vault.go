@@ -0,0 +1,527 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/containifyci/feller/pkg/config"
+	"github.com/containifyci/feller/pkg/credentials"
+	"github.com/containifyci/feller/pkg/logger"
+)
+
+// Auth methods of hashicorp_vault providers
+const (
+	VaultAuthToken      = "token"
+	VaultAuthAppRole    = "approle"
+	VaultAuthKubernetes = "kubernetes"
+	VaultAuthJWT        = "jwt"
+)
+
+// vaultAuthSPIFFE is the auth method of providers with the spiffe_audience option, a jwt login
+// with a JWT-SVID of the workload
+const vaultAuthSPIFFE = "spiffe"
+
+// vaultAuthMethods lists the supported auth methods in the order they are documented
+var vaultAuthMethods = []string{VaultAuthToken, VaultAuthAppRole, VaultAuthKubernetes, VaultAuthJWT}
+
+// Defaults of hashicorp_vault providers, matching the Vault CLI and Kubernetes
+const (
+	defaultVaultKVVersion      = 2
+	defaultVaultTokenEnv       = "VAULT_TOKEN"
+	defaultVaultRoleIDEnv      = "VAULT_ROLE_ID"
+	defaultVaultSecretIDEnv    = "VAULT_SECRET_ID"
+	defaultKubernetesTokenFile = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+)
+
+// vaultTimeout bounds the login and each read or list of a hashicorp_vault provider
+const vaultTimeout = 30 * time.Second
+
+// vaultOptions are the options of a hashicorp_vault provider. TLS and proxy options are
+// read by credentials.ClientFor.
+type vaultOptions struct {
+	Address   string    `yaml:"address"`    // VAULT_ADDR when empty
+	Namespace string    `yaml:"namespace"`  // VAULT_NAMESPACE when empty
+	KVVersion int       `yaml:"kv_version"` // Version of the KV secrets engine, 1 or 2
+	Auth      vaultAuth `yaml:"auth"`
+
+	// The SPIFFE login shared with teller runs, see credentials.Options
+	SpiffeAudience string `yaml:"spiffe_audience"`
+	SpiffeSocket   string `yaml:"spiffe_socket"`
+	VaultAuthRole  string `yaml:"vault_auth_role"`
+	VaultAuthMount string `yaml:"vault_auth_mount"`
+}
+
+// vaultAuth configures how a hashicorp_vault provider logs in. Secrets are only read from
+// environment variables and files, never from the config.
+type vaultAuth struct {
+	Method      string `yaml:"method"`        // token, approle, kubernetes or jwt
+	Mount       string `yaml:"mount"`         // Path the auth method is enabled at, the method name by default
+	Role        string `yaml:"role"`          // Role of kubernetes and jwt logins
+	RoleID      string `yaml:"role_id"`       // AppRole role id, VAULT_ROLE_ID when empty
+	SecretIDEnv string `yaml:"secret_id_env"` // Variable holding the AppRole secret id
+	TokenEnv    string `yaml:"token_env"`     // Variable holding the token of the token method
+	JWTFile     string `yaml:"jwt_file"`      // File holding the JWT of kubernetes and jwt logins
+	JWTEnv      string `yaml:"jwt_env"`       // Variable holding the JWT of jwt logins
+
+	audience string // Audience of the JWT-SVID of SPIFFE logins
+	socket   string // Workload API socket of SPIFFE logins
+}
+
+// CheckVaultOptions validates the options of a hashicorp_vault provider without reading the
+// environment
+func CheckVaultOptions(provider config.Provider) error {
+	_, err := decodeVaultOptions(provider)
+	return err
+}
+
+// decodeVaultOptions decodes and checks the options of a hashicorp_vault provider, filling
+// in the defaults that do not depend on the environment
+func decodeVaultOptions(provider config.Provider) (vaultOptions, error) {
+	var opts vaultOptions
+	if !provider.Options.IsZero() {
+		if err := provider.Options.Decode(&opts); err != nil {
+			return opts, fmt.Errorf("invalid vault options: %w", err)
+		}
+	}
+	if opts.KVVersion == 0 {
+		opts.KVVersion = defaultVaultKVVersion
+	}
+	if opts.KVVersion != 1 && opts.KVVersion != 2 {
+		return opts, fmt.Errorf("kv_version must be 1 or 2, got %d", opts.KVVersion)
+	}
+
+	auth := &opts.Auth
+	if opts.SpiffeAudience != "" || opts.VaultAuthRole != "" {
+		if opts.SpiffeAudience == "" || opts.VaultAuthRole == "" {
+			return opts, errors.New("spiffe_audience and vault_auth_role must be set together")
+		}
+		if auth.Method != "" {
+			return opts, fmt.Errorf("spiffe_audience logs in with a SPIFFE identity and cannot be combined with auth method %s", auth.Method)
+		}
+		auth.Method, auth.Role, auth.Mount = vaultAuthSPIFFE, opts.VaultAuthRole, opts.VaultAuthMount
+		auth.audience, auth.socket = opts.SpiffeAudience, opts.SpiffeSocket
+		if auth.Mount == "" {
+			auth.Mount = VaultAuthJWT
+		}
+		return opts, nil
+	}
+	if auth.Method == "" {
+		auth.Method = VaultAuthToken
+	}
+	switch auth.Method {
+	case VaultAuthToken:
+		if auth.TokenEnv == "" {
+			auth.TokenEnv = defaultVaultTokenEnv
+		}
+	case VaultAuthAppRole:
+		if auth.SecretIDEnv == "" {
+			auth.SecretIDEnv = defaultVaultSecretIDEnv
+		}
+	case VaultAuthKubernetes:
+		if auth.Role == "" {
+			return opts, errors.New("auth method kubernetes needs a role")
+		}
+		if auth.JWTFile == "" {
+			auth.JWTFile = defaultKubernetesTokenFile
+		}
+	case VaultAuthJWT:
+		if auth.Role == "" {
+			return opts, errors.New("auth method jwt needs a role")
+		}
+		if (auth.JWTFile == "") == (auth.JWTEnv == "") {
+			return opts, errors.New("auth method jwt needs exactly one of jwt_file and jwt_env")
+		}
+	default:
+		return opts, fmt.Errorf("unknown auth method %q (supported: %s)", auth.Method, strings.Join(vaultAuthMethods, ", "))
+	}
+	if auth.Mount == "" {
+		auth.Mount = auth.Method
+	}
+	return opts, nil
+}
+
+// vaultClient reads secrets from one Vault server
+type vaultClient struct {
+	http      *http.Client
+	address   string
+	namespace string
+	token     string
+}
+
+// collectVaultSecrets logs in to Vault and reads the KV secret of every path map, returning
+// the path map id of each key as well. Maps without keys read every key of their secret, or
+// of the secrets of their folder when the path ends in /; keys of the other maps their secret
+// lacks are reported as missing.
+func collectVaultSecrets(name string, provider config.Provider, m *meter) (SecretMap, map[string]string, []MissingVariable, error) {
+	logger.Debug("Collecting Vault secrets from %d path maps", len(provider.Maps))
+	ctx, cancel := context.WithTimeout(context.Background(), vaultTimeout)
+	client, opts, err := connectVault(ctx, name, provider, m)
+	cancel()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	secrets := make(SecretMap)
+	mapIDs := make(map[string]string)
+	var missingVars []MissingVariable
+	for i, pathMap := range provider.Maps {
+		logger.Debug("Processing Vault path map %d (id: %s, path: %s)", i+1, pathMap.ID, pathMap.Path)
+		var values map[string]string
+		if listsVaultFolder(pathMap) {
+			values, err = client.readFolder(pathMap, opts.KVVersion, m)
+		} else {
+			values, err = client.readWithin(pathMap, opts.KVVersion, m)
+		}
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("provider %s: %w", name, err)
+		}
+		logger.Debug("Read %d keys from Vault path '%s'", len(values), pathMap.Path)
+
+		if len(pathMap.Keys) == 0 {
+			for key, value := range values {
+				if !discovers(pathMap, key) {
+					continue
+				}
+				secrets[key] = value
+				mapIDs[key] = pathMap.ID
+			}
+			continue
+		}
+		for fromKey, toKey := range pathMap.Keys {
+			if value, ok := values[fromKey]; ok {
+				secrets[toKey] = value
+				mapIDs[toKey] = pathMap.ID
+			} else {
+				logger.Debug("Key '%s' not found in Vault path '%s'", fromKey, pathMap.Path)
+				missingVars = append(missingVars, MissingVariable{VariableName: fromKey, MappedTo: toKey, Provider: name})
+			}
+		}
+	}
+	return secrets, mapIDs, missingVars, nil
+}
+
+// listsVaultFolder reports whether a Vault map discovers its keys by listing the secrets of a
+// folder: it has no keys and a path ending in /
+func listsVaultFolder(pathMap config.PathMap) bool {
+	return len(pathMap.Keys) == 0 && strings.HasSuffix(pathMap.Path, "/")
+}
+
+// readWithin reads the KV secret of a path map with a deadline of its own, see read
+func (c *vaultClient) readWithin(pathMap config.PathMap, kvVersion int, m *meter) (map[string]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), vaultTimeout)
+	defer cancel()
+	m.wait()
+	return c.read(ctx, pathMap, kvVersion)
+}
+
+// readFolder returns the keys passing the globs of a map of every secret directly in its
+// folder, listed through the metadata path with KV version 2. Subfolders are skipped. Vault
+// lists a folder in one response; the list and each read have a deadline of their own, so
+// large folders are not cut off. A key in several secrets fails, as either value could be
+// meant.
+func (c *vaultClient) readFolder(pathMap config.PathMap, kvVersion int, m *meter) (map[string]string, error) {
+	if pathMap.Pinned() {
+		return nil, fmt.Errorf("map %s pins version %s, but its path %s is a folder of secrets", pathMap.ID, pathMap.Version, pathMap.Path)
+	}
+	folder := strings.Trim(pathMap.Path, "/") + "/"
+	list := folder
+	if kvVersion == 2 {
+		if !strings.Contains(folder, "/data/") {
+			return nil, fmt.Errorf("%s is not a KV version 2 folder, its path must include data/ (e.g. secret/data/apps/)", pathMap.Path)
+		}
+		list = strings.Replace(folder, "/data/", "/metadata/", 1)
+	}
+
+	var listing struct {
+		Data struct {
+			Keys []string `json:"keys"`
+		} `json:"data"`
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), vaultTimeout)
+	m.wait()
+	err := c.do(ctx, http.MethodGet, list+"?list=true", nil, &listing)
+	cancel()
+	var vaultErr *vaultError
+	switch {
+	case errors.As(err, &vaultErr) && vaultErr.StatusCode == http.StatusNotFound:
+		// Vault answers lists of empty folders with 404
+		return map[string]string{}, nil
+	case err != nil:
+		return nil, fmt.Errorf("failed to list %s: %w", pathMap.Path, err)
+	}
+
+	values := make(map[string]string)
+	owners := make(map[string]string) // Key -> the secret of the folder it was read from
+	for _, secret := range listing.Data.Keys {
+		if strings.HasSuffix(secret, "/") {
+			continue
+		}
+		secretValues, err := c.readWithin(config.PathMap{ID: pathMap.ID, Path: folder + secret}, kvVersion, m)
+		if err != nil {
+			return nil, err
+		}
+		for key, value := range secretValues {
+			if !discovers(pathMap, key) {
+				continue
+			}
+			if owner, ok := owners[key]; ok {
+				return nil, fmt.Errorf("key %s is in both %s and %s of %s", key, owner, secret, pathMap.Path)
+			}
+			values[key], owners[key] = value, secret
+		}
+	}
+	return values, nil
+}
+
+// connectVault returns a client of the Vault server of provider name, logged in with the
+// auth method of its options
+func connectVault(ctx context.Context, name string, provider config.Provider, m *meter) (*vaultClient, vaultOptions, error) {
+	opts, err := decodeVaultOptions(provider)
+	if err != nil {
+		return nil, opts, fmt.Errorf("provider %s: %w", name, err)
+	}
+	if opts.Address == "" {
+		opts.Address = os.Getenv("VAULT_ADDR")
+	}
+	if opts.Address == "" {
+		return nil, opts, fmt.Errorf("provider %s: set its address option or VAULT_ADDR", name)
+	}
+	if opts.Namespace == "" {
+		opts.Namespace = os.Getenv("VAULT_NAMESPACE")
+	}
+	httpClient, err := credentials.ClientFor(provider)
+	if err != nil {
+		return nil, opts, fmt.Errorf("provider %s: %w", name, err)
+	}
+
+	client := &vaultClient{http: sameHostRedirects(httpClient), address: strings.TrimSuffix(opts.Address, "/"), namespace: opts.Namespace}
+	if err := client.login(ctx, opts.Auth, m); err != nil {
+		return nil, opts, fmt.Errorf("provider %s: %w", name, err)
+	}
+	return client, opts, nil
+}
+
+// sameHostRedirects returns a copy of client that refuses redirects to another host. Go only
+// drops the Authorization and Cookie headers on those, so the X-Vault-Token of a request would
+// be sent to wherever the server redirects it.
+func sameHostRedirects(client *http.Client) *http.Client {
+	guarded := *client
+	guarded.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if req.URL.Host != via[0].URL.Host {
+			return fmt.Errorf("refusing to follow the redirect from %s to another host, %s", via[0].URL.Host, req.URL.Host)
+		}
+		if client.CheckRedirect != nil {
+			return client.CheckRedirect(req, via)
+		}
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		return nil
+	}
+	return &guarded
+}
+
+// login obtains the token of the client with the auth method of auth
+func (c *vaultClient) login(ctx context.Context, auth vaultAuth, m *meter) error {
+	var body map[string]string
+	switch auth.Method {
+	case VaultAuthToken:
+		c.token = os.Getenv(auth.TokenEnv)
+		if c.token == "" {
+			return fmt.Errorf("auth method token reads the token from %s, which is not set", auth.TokenEnv)
+		}
+		return nil
+	case VaultAuthAppRole:
+		roleID := auth.RoleID
+		if roleID == "" {
+			roleID = os.Getenv(defaultVaultRoleIDEnv)
+		}
+		secretID := os.Getenv(auth.SecretIDEnv)
+		if roleID == "" || secretID == "" {
+			return fmt.Errorf("auth method approle needs role_id (or %s) and %s", defaultVaultRoleIDEnv, auth.SecretIDEnv)
+		}
+		body = map[string]string{"role_id": roleID, "secret_id": secretID}
+	case VaultAuthKubernetes, VaultAuthJWT:
+		jwt, err := vaultJWT(auth)
+		if err != nil {
+			return err
+		}
+		body = map[string]string{"role": auth.Role, "jwt": jwt}
+	case vaultAuthSPIFFE:
+		svid, err := credentials.FetchJWTSVID(ctx, auth.audience, auth.socket)
+		if err != nil {
+			return err //nolint:wrapcheck // names the socket
+		}
+		body = map[string]string{"role": auth.Role, "jwt": svid}
+	}
+
+	m.wait()
+	var result struct {
+		Auth struct {
+			ClientToken string `json:"client_token"`
+		} `json:"auth"`
+	}
+	path := "auth/" + strings.Trim(auth.Mount, "/") + "/login"
+	if err := c.do(ctx, http.MethodPost, path, body, &result); err != nil {
+		return fmt.Errorf("failed to log in to Vault with auth method %s: %w", auth.Method, err)
+	}
+	if result.Auth.ClientToken == "" {
+		return fmt.Errorf("vault login with auth method %s returned no token", auth.Method)
+	}
+	c.token = result.Auth.ClientToken
+	logger.Debug("Logged in to Vault with auth method %s at %s", auth.Method, auth.Mount)
+	return nil
+}
+
+// vaultJWT returns the JWT of a kubernetes or jwt login
+func vaultJWT(auth vaultAuth) (string, error) {
+	if auth.JWTEnv != "" {
+		jwt := strings.TrimSpace(os.Getenv(auth.JWTEnv))
+		if jwt == "" {
+			return "", fmt.Errorf("auth method %s reads the JWT from %s, which is not set", auth.Method, auth.JWTEnv)
+		}
+		return jwt, nil
+	}
+	// #nosec G304 - The JWT path comes from the provider options
+	data, err := os.ReadFile(auth.JWTFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the JWT of auth method %s: %w", auth.Method, err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// read returns the values of the KV secret of a path map. With KV version 2 the path is the
+// API path including data/, e.g. secret/data/app, and a version of the map is read instead
+// of the latest one.
+func (c *vaultClient) read(ctx context.Context, pathMap config.PathMap, kvVersion int) (map[string]string, error) {
+	raw, _, err := c.readRaw(ctx, pathMap, kvVersion)
+	if err != nil {
+		return nil, err
+	}
+	return vaultStrings(raw), nil
+}
+
+// vaultStrings returns the values of a KV secret as strings
+func vaultStrings(raw map[string]json.RawMessage) map[string]string {
+	values := make(map[string]string, len(raw))
+	for key, value := range raw {
+		// Strings are used as they are, other JSON values as their JSON text
+		var s string
+		if err := json.Unmarshal(value, &s); err != nil {
+			s = string(value)
+		}
+		values[key] = s
+	}
+	return values
+}
+
+// readRaw returns the JSON values of the KV secret of a path map and, with KV version 2, the
+// version it read, see read
+func (c *vaultClient) readRaw(ctx context.Context, pathMap config.PathMap, kvVersion int) (map[string]json.RawMessage, int, error) {
+	path := strings.Trim(pathMap.Path, "/")
+	if pathMap.Pinned() {
+		if kvVersion == 1 {
+			return nil, 0, fmt.Errorf("map %s pins version %s, but KV version 1 has no versions", pathMap.ID, pathMap.Version)
+		}
+		path += "?version=" + url.QueryEscape(pathMap.Version)
+	}
+
+	var result struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := c.do(ctx, http.MethodGet, path, nil, &result); err != nil {
+		return nil, 0, fmt.Errorf("failed to read %s: %w", pathMap.Path, err)
+	}
+	data, version := result.Data, 0
+	if kvVersion == 2 {
+		var wrapped struct {
+			Data     json.RawMessage `json:"data"`
+			Metadata struct {
+				Version int `json:"version"`
+			} `json:"metadata"`
+		}
+		if err := json.Unmarshal(data, &wrapped); err != nil || len(wrapped.Data) == 0 || string(wrapped.Data) == "null" {
+			return nil, 0, fmt.Errorf("%s is not a KV version 2 secret, its path must include data/ (e.g. secret/data/app)", pathMap.Path)
+		}
+		data, version = wrapped.Data, wrapped.Metadata.Version
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, 0, fmt.Errorf("failed to decode %s: %w", pathMap.Path, err)
+	}
+	return raw, version, nil
+}
+
+// vaultError is a response of the Vault API with a status other than 2xx
+type vaultError struct {
+	StatusCode int
+	message    string // Status and the errors of the response
+}
+
+func (e *vaultError) Error() string {
+	return e.message
+}
+
+// do sends a request to the Vault API and decodes the JSON response into result, which may
+// be nil for responses without content
+func (c *vaultClient) do(ctx context.Context, method, path string, body any, result any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.address+"/v1/"+path, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("X-Vault-Token", c.token)
+	}
+	if c.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", c.namespace)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var failure struct {
+			Errors []string `json:"errors"`
+		}
+		_ = json.Unmarshal(data, &failure)
+		status := resp.Status
+		if len(failure.Errors) > 0 {
+			status += ": " + strings.Join(failure.Errors, "; ")
+		}
+		return &vaultError{StatusCode: resp.StatusCode, message: status}
+	}
+	if result == nil || len(data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, result); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,404 @@
+package providers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/containifyci/feller/pkg/config"
+	"gopkg.in/yaml.v3"
+)
+
+// fakeVault serves KV secrets by API path, with "@version" appended for pinned reads, to
+// requests with the token "t0k3n", and issues that token to logins with the JWT "jwt" or the
+// AppRole secret id "secret". Writes replace the secret at their path; KV version 2 writes
+// with a check-and-set option must name the version of metadata.version, and store the next one.
+// Lists are answered by listVault.
+func fakeVault(t *testing.T, secrets map[string]string) *httptest.Server {
+	t.Helper()
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimPrefix(r.URL.Path, "/v1/")
+		if strings.HasPrefix(path, "auth/") {
+			var body map[string]string
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			if body["jwt"] != "jwt" && body["secret_id"] != "secret" {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"errors":["permission denied"]}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"auth":{"client_token":"t0k3n"}}`))
+			return
+		}
+		if r.Header.Get("X-Vault-Token") != "t0k3n" {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"errors":["permission denied"]}`))
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Method == http.MethodPost {
+			body, _ := io.ReadAll(r.Body)
+			var write struct {
+				Data    json.RawMessage `json:"data"`
+				Options *struct {
+					CAS int `json:"cas"`
+				} `json:"options"`
+			}
+			if err := json.Unmarshal(body, &write); err == nil && write.Options != nil {
+				var stored struct {
+					Data struct {
+						Metadata struct {
+							Version int `json:"version"`
+						} `json:"metadata"`
+					} `json:"data"`
+				}
+				_ = json.Unmarshal([]byte(secrets[path]), &stored)
+				if write.Options.CAS != stored.Data.Metadata.Version {
+					w.WriteHeader(http.StatusBadRequest)
+					_, _ = w.Write([]byte(`{"errors":["check-and-set parameter did not match the current version"]}`))
+					return
+				}
+				body = []byte(fmt.Sprintf(`{"data":%s,"metadata":{"version":%d}}`, write.Data, write.Options.CAS+1))
+			}
+			secrets[path] = `{"data":` + string(body) + `}`
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		if r.URL.Query().Get("list") == "true" {
+			listVault(w, secrets, path)
+			return
+		}
+		if version := r.URL.Query().Get("version"); version != "" {
+			path += "@" + version
+		}
+		body, ok := secrets[path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"errors":[]}`))
+			return
+		}
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// listVault answers a list of the folder path with the secrets and subfolders directly in it,
+// taking the metadata/ of KV version 2 folders for data/
+func listVault(w http.ResponseWriter, secrets map[string]string, path string) {
+	prefix := strings.Replace(strings.TrimSuffix(path, "/")+"/", "/metadata/", "/data/", 1)
+	var names []string
+	for stored := range secrets {
+		name, found := strings.CutPrefix(stored, prefix)
+		if !found || strings.Contains(name, "@") {
+			continue
+		}
+		if folder, _, nested := strings.Cut(name, "/"); nested {
+			name = folder + "/"
+		}
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"errors":[]}`))
+		return
+	}
+	sort.Strings(names)
+	data, _ := json.Marshal(map[string]any{"data": map[string][]string{"keys": names}})
+	_, _ = w.Write(data)
+}
+
+// vaultProvider returns a hashicorp_vault provider with the options in YAML
+func vaultProvider(t *testing.T, options string, maps ...config.PathMap) config.Provider {
+	t.Helper()
+	var node yaml.Node
+	if err := yaml.Unmarshal([]byte(options), &node); err != nil {
+		t.Fatal(err)
+	}
+	return config.Provider{Kind: KindHashiCorpVault, Maps: maps, Options: *node.Content[0]}
+}
+
+//nolint:paralleltest // sets environment variables
+func TestCollectVaultSecrets(t *testing.T) {
+	server := fakeVault(t, map[string]string{
+		"secret/data/app":            `{"data":{"data":{"API_KEY":"abc","PORT":8080},"metadata":{"version":4}}}`,
+		"secret/data/app@2":          `{"data":{"data":{"API_KEY":"old"}}}`,
+		"kv/app":                     `{"data":{"API_KEY":"v1"}}`,
+		"secret/data/svc/api":        `{"data":{"data":{"API_KEY":"abc","API_DEBUG":"1"}}}`,
+		"secret/data/svc/db":         `{"data":{"data":{"DB_PASSWORD":"pw"}}}`,
+		"secret/data/svc/nested/old": `{"data":{"data":{"OLD":"x"}}}`,
+		"secret/data/clash/a":        `{"data":{"data":{"TOKEN":"a"}}}`,
+		"secret/data/clash/b":        `{"data":{"data":{"TOKEN":"b"}}}`,
+	})
+	jwtFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(jwtFile, []byte("jwt\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("VAULT_ADDR", server.URL)
+	t.Setenv("VAULT_TOKEN", "t0k3n")
+	t.Setenv("VAULT_NAMESPACE", "")
+	t.Setenv("APP_SECRET_ID", "secret")
+	t.Setenv("CI_JWT", "jwt")
+
+	app := config.PathMap{ID: "app", Path: "secret/data/app"}
+	tests := []struct {
+		name     string
+		options  string
+		maps     []config.PathMap
+		expected SecretMap
+		missing  []MissingVariable
+		requests int
+		wantErr  string
+	}{
+		{
+			name:     "token discovery",
+			options:  "{}",
+			maps:     []config.PathMap{app},
+			expected: SecretMap{"API_KEY": "abc", "PORT": "8080"},
+			requests: 1,
+		},
+		{
+			name:     "key mapping and version",
+			options:  "{}",
+			maps:     []config.PathMap{{ID: "app", Path: "/secret/data/app/", Version: "2", Keys: map[string]string{"API_KEY": "OLD_KEY", "MISSING": "MISSING"}}},
+			expected: SecretMap{"OLD_KEY": "old"},
+			missing:  []MissingVariable{{VariableName: "MISSING", MappedTo: "MISSING", Provider: "vault"}},
+			requests: 1,
+		},
+		{
+			name:     "kv version 1",
+			options:  "{kv_version: 1}",
+			maps:     []config.PathMap{{ID: "kv", Path: "kv/app"}},
+			expected: SecretMap{"API_KEY": "v1"},
+			requests: 1,
+		},
+		{
+			name:     "folder",
+			options:  "{}",
+			maps:     []config.PathMap{{ID: "svc", Path: "secret/data/svc/", Exclude: []string{"*_DEBUG"}}},
+			expected: SecretMap{"API_KEY": "abc", "DB_PASSWORD": "pw"},
+			requests: 3,
+		},
+		{
+			name:     "folder of kv version 1",
+			options:  "{kv_version: 1}",
+			maps:     []config.PathMap{{ID: "kv", Path: "kv/"}},
+			expected: SecretMap{"API_KEY": "v1"},
+			requests: 2,
+		},
+		{
+			name:     "empty folder",
+			options:  "{}",
+			maps:     []config.PathMap{{ID: "none", Path: "secret/data/none/"}},
+			expected: SecretMap{},
+			requests: 1,
+		},
+		{
+			name:     "approle",
+			options:  "{auth: {method: approle, role_id: app, secret_id_env: APP_SECRET_ID}}",
+			maps:     []config.PathMap{app},
+			expected: SecretMap{"API_KEY": "abc", "PORT": "8080"},
+			requests: 2,
+		},
+		{
+			name:     "kubernetes",
+			options:  "{auth: {method: kubernetes, role: app, jwt_file: " + jwtFile + "}}",
+			maps:     []config.PathMap{app},
+			expected: SecretMap{"API_KEY": "abc", "PORT": "8080"},
+			requests: 2,
+		},
+		{
+			name:     "jwt",
+			options:  "{auth: {method: jwt, mount: github, role: app, jwt_env: CI_JWT}}",
+			maps:     []config.PathMap{app},
+			expected: SecretMap{"API_KEY": "abc", "PORT": "8080"},
+			requests: 2,
+		},
+		{
+			name:    "failed login",
+			options: "{auth: {method: approle, role_id: app, secret_id_env: CI_JWT}}",
+			maps:    []config.PathMap{app},
+			wantErr: "provider vault: failed to log in to Vault with auth method approle: 400 Bad Request: permission denied",
+		},
+		{
+			name:    "missing token",
+			options: "{auth: {token_env: NO_SUCH_TOKEN}}",
+			maps:    []config.PathMap{app},
+			wantErr: "provider vault: auth method token reads the token from NO_SUCH_TOKEN, which is not set",
+		},
+		{
+			name:    "kv version 2 path without data",
+			options: "{}",
+			maps:    []config.PathMap{{ID: "kv", Path: "kv/app"}},
+			wantErr: "provider vault: kv/app is not a KV version 2 secret, its path must include data/ (e.g. secret/data/app)",
+		},
+		{
+			name:    "not found",
+			options: "{}",
+			maps:    []config.PathMap{{ID: "x", Path: "secret/data/none"}},
+			wantErr: "provider vault: failed to read secret/data/none: 404 Not Found",
+		},
+		{
+			name:    "spiffe without agent",
+			options: "{spiffe_audience: vault, vault_auth_role: app, spiffe_socket: " + filepath.Join(jwtFile, "none.sock") + "}",
+			maps:    []config.PathMap{app},
+			wantErr: "provider vault: failed to fetch a JWT-SVID from " + filepath.Join(jwtFile, "none.sock"),
+		},
+		{
+			name:    "version of kv version 1",
+			options: "{kv_version: 1}",
+			maps:    []config.PathMap{{ID: "kv", Path: "kv/app", Version: "2"}},
+			wantErr: "provider vault: map kv pins version 2, but KV version 1 has no versions",
+		},
+		{
+			name:    "key in several secrets of a folder",
+			options: "{}",
+			maps:    []config.PathMap{{ID: "clash", Path: "secret/data/clash/"}},
+			wantErr: "provider vault: key TOKEN is in both a and b of secret/data/clash/",
+		},
+		{
+			name:    "folder without data/",
+			options: "{}",
+			maps:    []config.PathMap{{ID: "svc", Path: "secret/svc/"}},
+			wantErr: "provider vault: secret/svc/ is not a KV version 2 folder",
+		},
+		{
+			name:    "version of a folder",
+			options: "{}",
+			maps:    []config.PathMap{{ID: "svc", Path: "secret/data/svc/", Version: "2"}},
+			wantErr: "provider vault: map svc pins version 2, but its path secret/data/svc/ is a folder of secrets",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, _ := newMeter("vault", config.Provider{})
+			secrets, mapIDs, missing, err := collectVaultSecrets("vault", vaultProvider(t, tt.options, tt.maps...), m)
+			if tt.wantErr != "" {
+				if err == nil || !strings.HasPrefix(err.Error(), tt.wantErr) {
+					t.Fatalf("collectVaultSecrets() error = %v, expected %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("collectVaultSecrets() error = %v", err)
+			}
+			if len(secrets) != len(tt.expected) {
+				t.Errorf("collectVaultSecrets() = %v, expected %v", secrets, tt.expected)
+			}
+			for key, value := range tt.expected {
+				if secrets[key] != value || mapIDs[key] != tt.maps[0].ID {
+					t.Errorf("collectVaultSecrets()[%s] = %q from %q, expected %q from %q", key, secrets[key], mapIDs[key], value, tt.maps[0].ID)
+				}
+			}
+			if !reflect.DeepEqual(missing, tt.missing) {
+				t.Errorf("collectVaultSecrets() missing = %v, expected %v", missing, tt.missing)
+			}
+			if m.timing.Requests != tt.requests {
+				t.Errorf("collectVaultSecrets() sent %d requests, expected %d", m.timing.Requests, tt.requests)
+			}
+		})
+	}
+}
+
+//nolint:paralleltest // sets environment variables
+func TestCollectSecretsWithVault(t *testing.T) {
+	server := fakeVault(t, map[string]string{"secret/data/app@3": `{"data":{"data":{"API_KEY":"pinned"}}}`})
+	t.Setenv("VAULT_TOKEN", "t0k3n")
+	provider := vaultProvider(t, "{address: "+server.URL+"}", config.PathMap{ID: "app", Path: "secret/data/app", Version: "3"})
+	cfg := &config.TellerConfig{Providers: map[string]config.Provider{"vault": provider}}
+
+	// Version numbers of Vault maps are read natively instead of being rejected as pins
+	result, err := CollectSecretsWithResult(cfg, false)
+	if err != nil {
+		t.Fatalf("CollectSecretsWithResult() error = %v", err)
+	}
+	if result.Secrets["API_KEY"] != "pinned" || result.Sources["API_KEY"].Kind != KindHashiCorpVault {
+		t.Errorf("CollectSecretsWithResult() = %v from %v", result.Secrets, result.Sources)
+	}
+
+	t.Setenv("VAULT_ADDR", "")
+	cfg.Providers["vault"] = vaultProvider(t, "{}", config.PathMap{ID: "app", Path: "secret/data/app"})
+	if _, err := CollectSecretsWithResult(cfg, false); err == nil || !strings.Contains(err.Error(), "set its address option or VAULT_ADDR") {
+		t.Errorf("CollectSecretsWithResult() without address error = %v", err)
+	}
+}
+
+//nolint:paralleltest // sets environment variables
+func TestVaultRefusesRedirectsToOtherHosts(t *testing.T) {
+	var leaked string
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		leaked = r.Header.Get("X-Vault-Token")
+		_, _ = w.Write([]byte(`{"data":{"data":{"API_KEY":"other"}}}`))
+	}))
+	t.Cleanup(other.Close)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/secret/data/moved" {
+			http.Redirect(w, r, "/v1/secret/data/app", http.StatusTemporaryRedirect)
+			return
+		}
+		if r.URL.Path == "/v1/secret/data/app" {
+			_, _ = w.Write([]byte(`{"data":{"data":{"API_KEY":"abc"}}}`))
+			return
+		}
+		http.Redirect(w, r, other.URL+r.URL.Path, http.StatusTemporaryRedirect)
+	}))
+	t.Cleanup(server.Close)
+	t.Setenv("VAULT_ADDR", server.URL)
+	t.Setenv("VAULT_TOKEN", "t0k3n")
+	t.Setenv("VAULT_NAMESPACE", "")
+
+	secrets, _, _, err := collectVaultSecrets("vault", vaultProvider(t, "{}", config.PathMap{ID: "app", Path: "secret/data/moved"}), nil)
+	if err != nil || secrets["API_KEY"] != "abc" {
+		t.Fatalf("collectVaultSecrets() = %v, %v, expected redirects on the same host to be followed", secrets, err)
+	}
+	_, _, _, err = collectVaultSecrets("vault", vaultProvider(t, "{}", config.PathMap{ID: "app", Path: "secret/data/elsewhere"}), nil)
+	if err == nil || !strings.Contains(err.Error(), "refusing to follow the redirect") {
+		t.Errorf("collectVaultSecrets() error = %v, expected the redirect to be refused", err)
+	}
+	if leaked != "" {
+		t.Errorf("the token was sent to the other host: %q", leaked)
+	}
+}
+
+func TestCheckVaultOptions(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		options string
+		wantErr string
+	}{
+		{options: "{}"},
+		{options: "{kv_version: 1, auth: {method: approle}}"},
+		{options: "{auth: {method: kubernetes, role: app}}"},
+		{options: "{kv_version: 3}", wantErr: "kv_version must be 1 or 2, got 3"},
+		{options: "{auth: {method: ldap}}", wantErr: `unknown auth method "ldap" (supported: token, approle, kubernetes, jwt)`},
+		{options: "{auth: {method: jwt, role: app}}", wantErr: "auth method jwt needs exactly one of jwt_file and jwt_env"},
+		{options: "{auth: {method: jwt, role: app, jwt_env: A, jwt_file: b}}", wantErr: "auth method jwt needs exactly one of jwt_file and jwt_env"},
+		{options: "{auth: {method: kubernetes}}", wantErr: "auth method kubernetes needs a role"},
+		{options: "{spiffe_audience: vault, vault_auth_role: app}"},
+		{options: "{spiffe_audience: vault}", wantErr: "spiffe_audience and vault_auth_role must be set together"},
+		{options: "{spiffe_audience: vault, vault_auth_role: app, auth: {method: approle}}", wantErr: "spiffe_audience logs in with a SPIFFE identity and cannot be combined with auth method approle"},
+	}
+	for _, tt := range tests {
+		err := CheckVaultOptions(vaultProvider(t, tt.options))
+		if tt.wantErr == "" && err != nil {
+			t.Errorf("CheckVaultOptions(%s) error = %v", tt.options, err)
+		}
+		if tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr) {
+			t.Errorf("CheckVaultOptions(%s) error = %v, expected %q", tt.options, err, tt.wantErr)
+		}
+	}
+}
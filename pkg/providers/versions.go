@@ -0,0 +1,99 @@
+package providers
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/containifyci/feller/pkg/config"
+)
+
+// gsmAliasPattern matches the version aliases of Google Secret Manager
+var gsmAliasPattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_-]{0,62}$`)
+
+// CheckVersion validates the version and stage of a map for its kind
+func CheckVersion(kind string, pathMap config.PathMap) error {
+	version := pathMap.Version
+	if version == config.LatestVersion {
+		version = ""
+	}
+	switch kind {
+	case KindGoogleSecretManager:
+		if pathMap.Stage != "" {
+			return errors.New("stage is not supported by kind google_secretmanager; use version with a number or alias")
+		}
+		if version != "" && !isVersionNumber(version) && !gsmAliasPattern.MatchString(version) {
+			return fmt.Errorf("version %q must be latest, a version number or an alias", pathMap.Version)
+		}
+	case KindHashiCorpVault:
+		if pathMap.Stage != "" {
+			return errors.New("stage is not supported by kind hashicorp_vault; use version")
+		}
+		if version != "" && !isVersionNumber(version) {
+			return fmt.Errorf("version %q must be latest or a KV version number", pathMap.Version)
+		}
+	case kindAWSParameterStore:
+		if version != "" && pathMap.Stage != "" {
+			return errors.New("version and stage cannot be combined for kind aws_ssm")
+		}
+		if version != "" && !isVersionNumber(version) {
+			return fmt.Errorf("version %q must be latest or a parameter version number", pathMap.Version)
+		}
+	case KindDotenv, KindBundle, KindGitHub:
+		if pathMap.Pinned() {
+			return fmt.Errorf("kind %s has no versions", kind)
+		}
+	}
+	// Secrets Manager takes any version id and staging label
+	return nil
+}
+
+// isVersionNumber reports whether version is a positive integer
+func isVersionNumber(version string) bool {
+	n, err := strconv.Atoi(version)
+	return err == nil && n > 0
+}
+
+// PinnedMaps describes the maps that request a version other than the latest, sorted
+func PinnedMaps(cfg *config.TellerConfig) []string {
+	var pinned []string
+	for name, provider := range cfg.Providers {
+		for _, pathMap := range provider.Maps {
+			if pathMap.Pinned() {
+				pinned = append(pinned, fmt.Sprintf("map %s of provider %s (%s)", pathMap.ID, name, describeVersion(pathMap)))
+			}
+		}
+	}
+	sort.Strings(pinned)
+	return pinned
+}
+
+// unreadablePins is PinnedMaps without the maps whose versions feller reads itself: version
+// numbers of hashicorp_vault maps, and the maps of plugins, which check them after the handshake
+func unreadablePins(cfg *config.TellerConfig) []string {
+	var pinned []string
+	for name, provider := range cfg.Providers {
+		for _, pathMap := range provider.Maps {
+			if pathMap.Pinned() && !IsPluginKind(provider.Kind) && (provider.Kind != KindHashiCorpVault || pathMap.Stage != "") {
+				pinned = append(pinned, fmt.Sprintf("map %s of provider %s (%s)", pathMap.ID, name, describeVersion(pathMap)))
+			}
+		}
+	}
+	sort.Strings(pinned)
+	return pinned
+}
+
+// describeVersion formats the version and stage of a map
+func describeVersion(pathMap config.PathMap) string {
+	var parts []string
+	if pathMap.Version != "" {
+		parts = append(parts, "version "+pathMap.Version)
+	}
+	if pathMap.Stage != "" {
+		parts = append(parts, "stage "+pathMap.Stage)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,68 @@
+package providers
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/containifyci/feller/pkg/config"
+)
+
+func TestCheckVersion(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name        string
+		kind        string
+		pathMap     config.PathMap
+		errContains string
+	}{
+		{name: "latest everywhere", kind: KindDotenv, pathMap: config.PathMap{Version: "latest"}},
+		{name: "gsm number", kind: KindGoogleSecretManager, pathMap: config.PathMap{Version: "3"}},
+		{name: "gsm alias", kind: KindGoogleSecretManager, pathMap: config.PathMap{Version: "prod-stable"}},
+		{name: "gsm invalid", kind: KindGoogleSecretManager, pathMap: config.PathMap{Version: "-1"}, errContains: `version "-1" must be latest, a version number or an alias`},
+		{name: "gsm stage", kind: KindGoogleSecretManager, pathMap: config.PathMap{Stage: "AWSCURRENT"}, errContains: "stage is not supported by kind google_secretmanager"},
+		{name: "vault number", kind: "hashicorp_vault", pathMap: config.PathMap{Version: "12"}},
+		{name: "vault alias", kind: "hashicorp_vault", pathMap: config.PathMap{Version: "prod"}, errContains: "must be latest or a KV version number"},
+		{name: "secrets manager stage", kind: "aws_secretsmanager", pathMap: config.PathMap{Stage: "AWSPREVIOUS"}},
+		{name: "secrets manager version id", kind: "aws_secretsmanager", pathMap: config.PathMap{Version: "a1b2c3d4-5678"}},
+		{name: "ssm label", kind: "aws_ssm", pathMap: config.PathMap{Stage: "prod"}},
+		{name: "ssm both", kind: "aws_ssm", pathMap: config.PathMap{Version: "2", Stage: "prod"}, errContains: "cannot be combined for kind aws_ssm"},
+		{name: "dotenv pinned", kind: KindDotenv, pathMap: config.PathMap{Version: "2"}, errContains: "kind dotenv has no versions"},
+		{name: "bundle stage", kind: KindBundle, pathMap: config.PathMap{Stage: "old"}, errContains: "kind bundle has no versions"},
+		{name: "unknown kind", kind: "custom", pathMap: config.PathMap{Version: "anything"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			err := CheckVersion(tt.kind, tt.pathMap)
+			if tt.errContains == "" {
+				if err != nil {
+					t.Errorf("CheckVersion() unexpected error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+				t.Errorf("CheckVersion() error = %v, expected to contain %q", err, tt.errContains)
+			}
+		})
+	}
+}
+
+func TestPinnedMaps(t *testing.T) {
+	t.Parallel()
+	cfg := &config.TellerConfig{Providers: map[string]config.Provider{
+		"gsm":   {Kind: KindGoogleSecretManager, Maps: []config.PathMap{{ID: "app", Version: "latest"}, {ID: "old", Version: "3"}}},
+		"aws":   {Kind: "aws_secretsmanager", Maps: []config.PathMap{{ID: "db", Version: "v1", Stage: "AWSPREVIOUS"}}},
+		"local": {Kind: KindDotenv, Maps: []config.PathMap{{ID: "env"}}},
+	}}
+	expected := []string{"map db of provider aws (version v1, stage AWSPREVIOUS)", "map old of provider gsm (version 3)"}
+	if got := PinnedMaps(cfg); !reflect.DeepEqual(got, expected) {
+		t.Errorf("PinnedMaps() = %v, expected %v", got, expected)
+	}
+
+	_, err := CollectSecretsWithResult(cfg, true)
+	if err == nil || !strings.Contains(err.Error(), "cannot resolve map db of provider aws") {
+		t.Errorf("CollectSecretsWithResult() error = %v, expected pinned maps to be refused", err)
+	}
+}
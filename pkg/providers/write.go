@@ -0,0 +1,115 @@
+package providers
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/containifyci/feller/pkg/config"
+)
+
+// UpdateDotenv sets values in the dotenv file content data, replacing the lines of existing
+// keys in place and appending new keys sorted. Comments and other lines are kept. Values
+// are written in double quotes, which the dotenv provider strips without unescaping, so
+// every value without line breaks or NUL bytes reads back unchanged.
+func UpdateDotenv(data []byte, values map[string]string) ([]byte, error) {
+	for key, value := range values {
+		if key == "" || strings.ContainsAny(key, "=#") || strings.ContainsFunc(key, isInvalidKeyRune) {
+			return nil, fmt.Errorf("key %q cannot be written to a dotenv file", key)
+		}
+		if strings.ContainsAny(value, "\r\n\x00") {
+			return nil, fmt.Errorf("value of %s contains a line break or NUL byte and cannot be written to a dotenv file", key)
+		}
+	}
+
+	text, err := decodeText(data)
+	if err != nil {
+		return nil, err
+	}
+	var lines []string
+	if text != "" {
+		lines = strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	}
+	written := make(map[string]bool, len(values))
+	for i, line := range lines {
+		key, _, found := strings.Cut(strings.TrimSpace(line), "=")
+		key = strings.TrimSpace(key)
+		if value, ok := values[key]; found && ok && !strings.HasPrefix(key, "#") {
+			lines[i] = fmt.Sprintf("%s=\"%s\"", key, value)
+			written[key] = true
+		}
+	}
+
+	added := make([]string, 0, len(values))
+	for key := range values {
+		if !written[key] {
+			added = append(added, key)
+		}
+	}
+	sort.Strings(added)
+	for _, key := range added {
+		lines = append(lines, fmt.Sprintf("%s=\"%s\"", key, values[key]))
+	}
+	return []byte(strings.Join(lines, "\n") + "\n"), nil
+}
+
+// RemoveDotenvKeys removes the lines of keys from the dotenv file content data, keeping
+// comments and other lines, and returns the keys that were found sorted
+func RemoveDotenvKeys(data []byte, keys []string) ([]byte, []string, error) {
+	text, err := decodeText(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	if text == "" {
+		return data, nil, nil
+	}
+	remove := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		remove[key] = true
+	}
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	kept := lines[:0]
+	var removed []string
+	for _, line := range lines {
+		key, _, found := strings.Cut(strings.TrimSpace(line), "=")
+		key = strings.TrimSpace(key)
+		if found && remove[key] && !strings.HasPrefix(key, "#") {
+			removed = append(removed, key)
+			continue
+		}
+		kept = append(kept, line)
+	}
+	sort.Strings(removed)
+	removed = slices.Compact(removed)
+	if len(kept) == 0 {
+		return []byte{}, removed, nil
+	}
+	return []byte(strings.Join(kept, "\n") + "\n"), removed, nil
+}
+
+// RestoreKeys maps output keys back to the names the path map reads them from, so
+// restoring secrets into the map resolves them to the same keys. Keys the map does not
+// produce are returned sorted in skipped. Maps without keys read every name, so secrets
+// are returned unchanged when they pass the map's include and exclude globs.
+func RestoreKeys(pathMap config.PathMap, secrets SecretMap) (SecretMap, []string) {
+	sources := make(map[string]string, len(pathMap.Keys))
+	for source, output := range pathMap.Keys {
+		sources[output] = source
+	}
+
+	restored := make(SecretMap, len(secrets))
+	var skipped []string
+	for key, value := range secrets {
+		switch source, ok := sources[key]; {
+		case ok:
+			restored[source] = value
+		case len(pathMap.Keys) == 0 && discovers(pathMap, key):
+			restored[key] = value
+		default:
+			skipped = append(skipped, key)
+		}
+	}
+	sort.Strings(skipped)
+	return restored, skipped
+}
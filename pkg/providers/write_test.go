@@ -0,0 +1,118 @@
+package providers
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/containifyci/feller/pkg/config"
+)
+
+func TestUpdateDotenv(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name        string
+		data        string
+		values      map[string]string
+		expected    string
+		errContains string
+	}{
+		{name: "empty file", values: map[string]string{"B": "2", "A": "1"}, expected: "A=\"1\"\nB=\"2\"\n"},
+		{
+			name:     "keeps other lines",
+			data:     "# comment\nA=old\nexport_me=x\n\nC='keep'",
+			values:   map[string]string{"A": "new", "D": "4"},
+			expected: "# comment\nA=\"new\"\nexport_me=x\n\nC='keep'\nD=\"4\"\n",
+		},
+		{name: "ignores commented keys", data: "#A=old\n", values: map[string]string{"A": "1"}, expected: "#A=old\nA=\"1\"\n"},
+		{name: "line break", values: map[string]string{"A": "a\nb"}, errContains: "value of A contains a line break"},
+		{name: "invalid key", values: map[string]string{"A B": "1"}, errContains: `key "A B" cannot be written`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := UpdateDotenv([]byte(tt.data), tt.values)
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Errorf("UpdateDotenv() error = %v, expected to contain %q", err, tt.errContains)
+				}
+				return
+			}
+			if err != nil || string(got) != tt.expected {
+				t.Errorf("UpdateDotenv() = %q, %v, expected %q", got, err, tt.expected)
+			}
+		})
+	}
+}
+
+func TestUpdateDotenvRoundTrip(t *testing.T) {
+	t.Parallel()
+	values := map[string]string{
+		"QUOTES":   `say "hi" and 'bye'`,
+		"QUOTED":   `"already quoted"`,
+		"SPACES":   "  padded  ",
+		"EMPTY":    "",
+		"SYMBOLS":  `a=b#c\n$HOME`,
+		"UNICODE":  "pässwörd",
+		"TRAILING": `ends with "`,
+	}
+	data, err := UpdateDotenv(nil, values)
+	if err != nil {
+		t.Fatalf("UpdateDotenv() error = %v", err)
+	}
+	parsed, err := parseEnvFile(data)
+	if err != nil || !maps.Equal(parsed, values) {
+		t.Errorf("parseEnvFile(UpdateDotenv()) = %q, %v, expected %q", parsed, err, values)
+	}
+}
+
+func TestRestoreKeys(t *testing.T) {
+	t.Parallel()
+	secrets := SecretMap{"API_KEY": "a", "DB_URL": "b", "OTHER": "c"}
+
+	keyed := config.PathMap{ID: "app", Keys: map[string]string{"api-key": "API_KEY", "db": "DB_URL"}}
+	restored, skipped := RestoreKeys(keyed, secrets)
+	if !maps.Equal(restored, SecretMap{"api-key": "a", "db": "b"}) || !slices.Equal(skipped, []string{"OTHER"}) {
+		t.Errorf("RestoreKeys(keyed) = %v, %v", restored, skipped)
+	}
+
+	discovery := config.PathMap{ID: "all", Exclude: []string{"DB_*"}}
+	restored, skipped = RestoreKeys(discovery, secrets)
+	if !maps.Equal(restored, SecretMap{"API_KEY": "a", "OTHER": "c"}) || !slices.Equal(skipped, []string{"DB_URL"}) {
+		t.Errorf("RestoreKeys(discovery) = %v, %v", restored, skipped)
+	}
+}
+
+func TestRemoveDotenvKeys(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name            string
+		data            string
+		keys            []string
+		expected        string
+		expectedRemoved []string
+	}{
+		{name: "empty file", keys: []string{"A"}, expected: ""},
+		{
+			name:            "keeps other lines",
+			data:            "# comment\nA=1\nB = 2\n\nC='3'\nA=again",
+			keys:            []string{"A", "C"},
+			expected:        "# comment\nB = 2\n\n",
+			expectedRemoved: []string{"A", "C"},
+		},
+		{name: "ignores commented keys", data: "#A=1\nB=2\n", keys: []string{"A"}, expected: "#A=1\nB=2\n"},
+		{name: "last key", data: "A=1\n", keys: []string{"A"}, expected: "", expectedRemoved: []string{"A"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, removed, err := RemoveDotenvKeys([]byte(tt.data), tt.keys)
+			if err != nil || string(got) != tt.expected || !slices.Equal(removed, tt.expectedRemoved) {
+				t.Errorf("RemoveDotenvKeys() = %q, %v, %v, expected %q, %v", got, removed, err, tt.expected, tt.expectedRemoved)
+			}
+		})
+	}
+}
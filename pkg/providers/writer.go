@@ -0,0 +1,526 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/containifyci/feller/pkg/config"
+	"github.com/containifyci/feller/pkg/credentials"
+	"github.com/containifyci/feller/pkg/logger"
+)
+
+// Writer creates, updates and deletes secrets in the backend of one path map. Keys are the
+// names the map reads, see RestoreKeys.
+type Writer interface {
+	// Target describes the backend, e.g. "dotenv:.env" or "vault:secret/data/app"
+	Target() string
+	// Read returns the values of those of keys the backend holds
+	Read(keys []string) (SecretMap, error)
+	// Put creates or updates keys with their values
+	Put(values SecretMap) error
+	// Delete removes keys, ignoring those the backend does not hold, and returns the keys it
+	// removed, also when it fails part way
+	Delete(keys []string) ([]string, error)
+}
+
+// WritableKinds lists the kinds NewWriter supports
+var WritableKinds = []string{KindDotenv, KindGoogleSecretManager, KindHashiCorpVault}
+
+// NewWriter returns the writer of a path map of provider name. Writers connect to their
+// backend on first use.
+func NewWriter(name string, provider config.Provider, pathMap config.PathMap) (Writer, error) {
+	if pathMap.Pinned() {
+		return nil, fmt.Errorf("map %s of provider %s pins %s; writes always create the latest version", pathMap.ID, name, describeVersion(pathMap))
+	}
+	switch provider.Kind {
+	case KindDotenv:
+		return &dotenvWriter{path: config.LocalPath(pathMap.Path)}, nil
+	case KindHashiCorpVault:
+		if _, err := decodeVaultOptions(provider); err != nil {
+			return nil, fmt.Errorf("provider %s: %w", name, err)
+		}
+		return &vaultWriter{name: name, provider: provider, pathMap: pathMap}, nil
+	case KindGoogleSecretManager:
+		project := strings.TrimSuffix(pathMap.Path, "/")
+		if !gsmProjectPattern.MatchString(project) {
+			return nil, fmt.Errorf("map %s of provider %s needs the path projects/PROJECT to write Google Secret Manager secrets", pathMap.ID, name)
+		}
+		endpoint, err := endpointFor(provider)
+		if err != nil {
+			return nil, fmt.Errorf("provider %s: %w", name, err)
+		}
+		return &gsmWriter{name: name, provider: provider, project: project, endpoint: endpoint}, nil
+	default:
+		return nil, fmt.Errorf("provider %s is of kind %s, which feller cannot write (supported: %s)", name, provider.Kind, strings.Join(WritableKinds, ", "))
+	}
+}
+
+// dotenvWriter writes a dotenv file, keeping comments and the order of existing keys
+type dotenvWriter struct {
+	path string
+}
+
+func (w *dotenvWriter) Target() string {
+	return "dotenv:" + w.path
+}
+
+func (w *dotenvWriter) Read(keys []string) (SecretMap, error) {
+	data, err := w.read()
+	if err != nil {
+		return nil, err
+	}
+	env, err := parseEnvFile(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", w.path, err)
+	}
+	return pick(env, keys), nil
+}
+
+func (w *dotenvWriter) Put(values SecretMap) error {
+	data, err := w.read()
+	if err != nil {
+		return err
+	}
+	updated, err := UpdateDotenv(data, values)
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", w.path, err)
+	}
+	return replaceFile(w.path, updated)
+}
+
+func (w *dotenvWriter) Delete(keys []string) ([]string, error) {
+	data, err := w.read()
+	if err != nil {
+		return nil, err
+	}
+	updated, removed, err := RemoveDotenvKeys(data, keys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", w.path, err)
+	}
+	if len(removed) == 0 {
+		return nil, nil
+	}
+	if err := replaceFile(w.path, updated); err != nil {
+		return nil, err
+	}
+	return removed, nil
+}
+
+// read returns the content of the file, which is empty when it does not exist yet
+func (w *dotenvWriter) read() ([]byte, error) {
+	// #nosec G304 - Dotenv paths come from the user's config
+	data, err := os.ReadFile(w.path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read %s: %w", w.path, err)
+	}
+	return data, nil
+}
+
+// replaceFile replaces path with data readable only by its owner, so a reader never sees a
+// partially written file
+func replaceFile(path string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), ".feller-*")
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	defer os.Remove(f.Name())
+	// Restrict the file before writing the secret into it
+	err = f.Chmod(0o600)
+	if err == nil {
+		_, err = f.Write(data)
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// vaultWriter writes the KV secret of a path map. Vault replaces a secret as a whole, so the
+// current keys are read and written back with the new values. With KV version 2 the write
+// only succeeds if the secret is still at the version that was read, so concurrent changes
+// are not overwritten.
+type vaultWriter struct {
+	name      string
+	provider  config.Provider
+	pathMap   config.PathMap
+	client    *vaultClient
+	kvVersion int
+	version   int // The KV version 2 version current read, 0 when the secret did not exist
+}
+
+func (w *vaultWriter) Target() string {
+	return "vault:" + strings.Trim(w.pathMap.Path, "/")
+}
+
+// Read returns the values of keys as strings, other JSON values as their JSON text
+func (w *vaultWriter) Read(keys []string) (SecretMap, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), vaultTimeout)
+	defer cancel()
+	current, err := w.current(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return pick(vaultStrings(current), keys), nil
+}
+
+func (w *vaultWriter) Put(values SecretMap) error {
+	ctx, cancel := context.WithTimeout(context.Background(), vaultTimeout)
+	defer cancel()
+	data, err := w.current(ctx)
+	if err != nil {
+		return err
+	}
+	for key, value := range values {
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Errorf("failed to encode %s: %w", key, err)
+		}
+		data[key] = encoded
+	}
+	return w.write(ctx, data)
+}
+
+func (w *vaultWriter) Delete(keys []string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), vaultTimeout)
+	defer cancel()
+	data, err := w.current(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var removed []string
+	for _, key := range keys {
+		if _, ok := data[key]; ok {
+			delete(data, key)
+			removed = append(removed, key)
+		}
+	}
+	if len(removed) == 0 {
+		return nil, nil
+	}
+	// One write removes every key, so it deletes all of them or none
+	if err := w.write(ctx, data); err != nil {
+		return nil, err
+	}
+	return removed, nil
+}
+
+// write replaces the secret with data, as a new version of the one current read on KV
+// version 2
+func (w *vaultWriter) write(ctx context.Context, data map[string]json.RawMessage) error {
+	var body any = data
+	if w.kvVersion == 2 {
+		wrapped := map[string]any{"data": data}
+		// Check-and-set against the version that was read. A secret that was not found may
+		// still have deleted versions, which a check for version 0 would reject.
+		if w.version > 0 {
+			wrapped["options"] = map[string]int{"cas": w.version}
+		}
+		body = wrapped
+	}
+	path := strings.Trim(w.pathMap.Path, "/")
+	err := w.client.do(ctx, http.MethodPost, path, body, nil)
+	var status *vaultError
+	switch {
+	case errors.As(err, &status) && status.StatusCode == http.StatusBadRequest && strings.Contains(status.message, "check-and-set"):
+		return fmt.Errorf("provider %s: %s changed while feller wrote it, try again: %w", w.name, w.pathMap.Path, err)
+	case err != nil:
+		return fmt.Errorf("provider %s: failed to write %s: %w", w.name, w.pathMap.Path, err)
+	}
+	return nil
+}
+
+// current logs in once and returns the keys of the secret, none when it does not exist yet
+func (w *vaultWriter) current(ctx context.Context) (map[string]json.RawMessage, error) {
+	if w.client == nil {
+		client, opts, err := connectVault(ctx, w.name, w.provider, nil)
+		if err != nil {
+			return nil, err
+		}
+		w.client, w.kvVersion = client, opts.KVVersion
+	}
+	current, version, err := w.client.readRaw(ctx, w.pathMap, w.kvVersion)
+	var status *vaultError
+	switch {
+	case errors.As(err, &status) && status.StatusCode == http.StatusNotFound:
+		logger.Debug("Vault secret %s does not exist yet", w.pathMap.Path)
+		w.version = 0
+		return make(map[string]json.RawMessage), nil
+	case err != nil:
+		return nil, fmt.Errorf("provider %s: %w", w.name, err)
+	}
+	w.version = version
+	return current, nil
+}
+
+// gsmProjectPattern matches the paths of Google Secret Manager maps writers can use
+var gsmProjectPattern = regexp.MustCompile(`^projects/[a-z0-9][a-z0-9.:-]*$`)
+
+// gsmEndpoint is the Secret Manager API of providers without an endpoint option, replaced
+// in tests
+var gsmEndpoint = "https://secretmanager.googleapis.com"
+
+// gsmTokenEnv holds an OAuth access token for the Secret Manager API, like for terraform
+const gsmTokenEnv = "GOOGLE_OAUTH_ACCESS_TOKEN"
+
+// gsmTimeout bounds the requests of one Google Secret Manager write, and each request of
+// reads and lists, so discovering a large project is not cut off. Replaced in tests.
+var gsmTimeout = 30 * time.Second
+
+// gsmPageSize is the number of secrets a list request of the Secret Manager API returns
+const gsmPageSize = 250
+
+// gsmWriter adds versions to the secrets of a Google Secret Manager project, creating
+// secrets that do not exist with automatic replication
+type gsmWriter struct {
+	name     string
+	provider config.Provider
+	project  string
+	endpoint string // The endpoint option, gsmEndpoint when empty
+	meter    *meter // Spaces out the requests of discovery; writes are not limited
+	client   *http.Client
+	token    string
+}
+
+func (w *gsmWriter) Target() string {
+	return "gsm:" + w.project
+}
+
+// Read returns the latest versions of the secrets; secrets without an enabled version are
+// not set
+func (w *gsmWriter) Read(keys []string) (SecretMap, error) {
+	if err := w.connectWithin(); err != nil {
+		return nil, err
+	}
+	values := make(SecretMap)
+	for _, key := range keys {
+		var version struct {
+			Payload struct {
+				Data string `json:"data"`
+			} `json:"payload"`
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), gsmTimeout)
+		status, err := w.do(ctx, http.MethodGet, w.project+"/secrets/"+url.PathEscape(key)+"/versions/latest:access", nil, &version)
+		cancel()
+		switch {
+		case status == http.StatusNotFound || status == http.StatusBadRequest:
+			continue
+		case err != nil:
+			return nil, fmt.Errorf("provider %s: failed to read secret %s: %w", w.name, key, err)
+		}
+		data, err := base64.StdEncoding.DecodeString(version.Payload.Data)
+		if err != nil {
+			return nil, fmt.Errorf("provider %s: failed to decode secret %s: %w", w.name, key, err)
+		}
+		values[key] = string(data)
+	}
+	return values, nil
+}
+
+// Put adds a version to each secret, creating those that do not exist
+func (w *gsmWriter) Put(values SecretMap) error {
+	ctx, cancel := context.WithTimeout(context.Background(), gsmTimeout)
+	defer cancel()
+	if err := w.connect(ctx); err != nil {
+		return err
+	}
+	for _, key := range sortedKeys(values) {
+		secret := w.project + "/secrets/" + url.PathEscape(key)
+		body := map[string]any{"payload": map[string]string{"data": base64.StdEncoding.EncodeToString([]byte(values[key]))}}
+		status, err := w.do(ctx, http.MethodPost, secret+":addVersion", body, nil)
+		if status == http.StatusNotFound {
+			create := map[string]any{"replication": map[string]any{"automatic": map[string]any{}}}
+			if _, err := w.do(ctx, http.MethodPost, w.project+"/secrets?secretId="+url.QueryEscape(key), create, nil); err != nil {
+				return fmt.Errorf("provider %s: failed to create secret %s: %w", w.name, key, err)
+			}
+			_, err = w.do(ctx, http.MethodPost, secret+":addVersion", body, nil)
+		}
+		if err != nil {
+			return fmt.Errorf("provider %s: failed to add a version to secret %s: %w", w.name, key, err)
+		}
+	}
+	return nil
+}
+
+// Delete deletes the secrets with every version, which cannot be undone. Each secret takes
+// its own request, so a failure leaves the secrets before it deleted.
+func (w *gsmWriter) Delete(keys []string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), gsmTimeout)
+	defer cancel()
+	if err := w.connect(ctx); err != nil {
+		return nil, err
+	}
+	var removed []string
+	for _, key := range keys {
+		status, err := w.do(ctx, http.MethodDelete, w.project+"/secrets/"+url.PathEscape(key), nil, nil)
+		switch {
+		case status == http.StatusNotFound:
+			continue
+		case err != nil:
+			return removed, fmt.Errorf("provider %s: failed to delete secret %s: %w", w.name, key, err)
+		}
+		removed = append(removed, key)
+	}
+	return removed, nil
+}
+
+// list returns the names of the secrets of the project, following the pages of the API
+func (w *gsmWriter) list() ([]string, error) {
+	if err := w.connectWithin(); err != nil {
+		return nil, err
+	}
+	var names []string
+	query := url.Values{"pageSize": {strconv.Itoa(gsmPageSize)}}
+	for {
+		var page struct {
+			Secrets []struct {
+				Name string `json:"name"`
+			} `json:"secrets"`
+			NextPageToken string `json:"nextPageToken"`
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), gsmTimeout)
+		_, err := w.do(ctx, http.MethodGet, w.project+"/secrets?"+query.Encode(), nil, &page)
+		cancel()
+		if err != nil {
+			return nil, fmt.Errorf("provider %s: failed to list the secrets of %s: %w", w.name, w.project, err)
+		}
+		for _, secret := range page.Secrets {
+			names = append(names, path.Base(secret.Name))
+		}
+		if page.NextPageToken == "" {
+			return names, nil
+		}
+		query.Set("pageToken", page.NextPageToken)
+	}
+}
+
+// connect prepares the HTTP client and the access token, once
+func (w *gsmWriter) connect(ctx context.Context) error {
+	if w.client != nil {
+		return nil
+	}
+	client, err := credentials.ClientFor(w.provider)
+	if err != nil {
+		return fmt.Errorf("provider %s: %w", w.name, err)
+	}
+	token, err := gsmAccessToken(ctx)
+	if err != nil {
+		return fmt.Errorf("provider %s: %w", w.name, err)
+	}
+	w.client, w.token = client, token
+	return nil
+}
+
+// connectWithin connects with a deadline of its own, for reads and lists that give each
+// request one
+func (w *gsmWriter) connectWithin() error {
+	ctx, cancel := context.WithTimeout(context.Background(), gsmTimeout)
+	defer cancel()
+	return w.connect(ctx)
+}
+
+// gsmAccessToken returns the access token of GOOGLE_OAUTH_ACCESS_TOKEN, or else of the
+// account gcloud is logged in with
+func gsmAccessToken(ctx context.Context) (string, error) {
+	if token := os.Getenv(gsmTokenEnv); token != "" {
+		return token, nil
+	}
+	output, err := exec.CommandContext(ctx, "gcloud", "auth", "print-access-token").Output()
+	token := strings.TrimSpace(string(output))
+	if err != nil || token == "" {
+		return "", fmt.Errorf("no access token for Google Secret Manager: set %s or log in with 'gcloud auth login'", gsmTokenEnv)
+	}
+	return token, nil
+}
+
+// do sends a request to the Secret Manager API and decodes the response into result, unless
+// it is nil. The status is returned with errors too.
+func (w *gsmWriter) do(ctx context.Context, method, path string, body, result any) (int, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return 0, fmt.Errorf("failed to encode request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	endpoint := w.endpoint
+	if endpoint == "" {
+		endpoint = gsmEndpoint
+	}
+	w.meter.wait()
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(endpoint, "/")+"/v1/"+path, reader)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+w.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode <= 299 {
+		if result != nil {
+			if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+				return resp.StatusCode, fmt.Errorf("failed to decode response: %w", err)
+			}
+		}
+		return resp.StatusCode, nil
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	var failure struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	_ = json.Unmarshal(data, &failure)
+	if failure.Error.Message != "" {
+		return resp.StatusCode, fmt.Errorf("%s: %s", resp.Status, failure.Error.Message)
+	}
+	return resp.StatusCode, errors.New(resp.Status)
+}
+
+// pick returns the values of those of keys that values holds
+func pick(values map[string]string, keys []string) SecretMap {
+	picked := make(SecretMap, len(keys))
+	for _, key := range keys {
+		if value, ok := values[key]; ok {
+			picked[key] = value
+		}
+	}
+	return picked
+}
+
+// sortedKeys returns the keys of values sorted
+func sortedKeys(values SecretMap) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,346 @@
+package providers
+
+import (
+	"encoding/json"
+	"io"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/containifyci/feller/pkg/config"
+)
+
+func TestDotenvWriter(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(path, []byte("# app\nA=1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	writer, err := NewWriter("local", config.Provider{Kind: KindDotenv}, config.PathMap{ID: "app", Path: path})
+	if err != nil {
+		t.Fatalf("NewWriter() error = %v", err)
+	}
+	if writer.Target() != "dotenv:"+path {
+		t.Errorf("Target() = %q", writer.Target())
+	}
+
+	values, err := writer.Read([]string{"A", "B"})
+	if err != nil || len(values) != 1 || values["A"] != "1" {
+		t.Errorf("Read() = %v, %v, expected only A", values, err)
+	}
+	if err := writer.Put(SecretMap{"A": "2", "B": "3"}); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "# app\nA=\"2\"\nB=\"3\"\n"; string(data) != expected {
+		t.Errorf("Put() wrote %q, expected %q", data, expected)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("Put() left mode %v, %v, expected 0600", info.Mode().Perm(), err)
+	}
+
+	// A missing file holds nothing and is created
+	missing, _ := NewWriter("local", config.Provider{Kind: KindDotenv}, config.PathMap{ID: "new", Path: filepath.Join(t.TempDir(), "new.env")})
+	if values, err := missing.Read([]string{"A"}); err != nil || len(values) != 0 {
+		t.Errorf("Read() of a missing file = %v, %v", values, err)
+	}
+	if err := missing.Put(SecretMap{"A": "1"}); err != nil {
+		t.Errorf("Put() into a missing file error = %v", err)
+	}
+
+	if removed, err := writer.Delete([]string{"A", "C"}); err != nil || strings.Join(removed, ",") != "A" {
+		t.Fatalf("Delete() = %v, %v, expected only A removed", removed, err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "# app\nB=\"3\"\n" {
+		t.Errorf("Delete() left %q", data)
+	}
+}
+
+//nolint:paralleltest // sets environment variables
+func TestVaultWriter(t *testing.T) {
+	secrets := map[string]string{
+		"secret/data/app": `{"data":{"data":{"API_KEY":"abc","PORT":8080},"metadata":{"version":4}}}`,
+	}
+	server := fakeVault(t, secrets)
+	t.Setenv("VAULT_ADDR", server.URL)
+	t.Setenv("VAULT_TOKEN", "t0k3n")
+	t.Setenv("VAULT_NAMESPACE", "")
+
+	tests := []struct {
+		name     string
+		options  string
+		path     string
+		existing int
+		expected string
+		deleted  string
+	}{
+		{
+			name:     "kv version 2 keeps other keys",
+			options:  "{}",
+			path:     "secret/data/app",
+			existing: 1,
+			expected: `{"data":{"data":{"API_KEY":"new","PORT":8080,"TOKEN":"t"},"metadata":{"version":5}}}`,
+			deleted:  `{"data":{"data":{"API_KEY":"new","PORT":8080},"metadata":{"version":6}}}`,
+		},
+		{
+			name:     "kv version 2 creates the secret",
+			options:  "{}",
+			path:     "secret/data/new",
+			expected: `{"data":{"data":{"API_KEY":"new","TOKEN":"t"}}}`,
+			deleted:  `{"data":{"data":{"API_KEY":"new"}}}`,
+		},
+		{
+			name:     "kv version 1",
+			options:  "{kv_version: 1}",
+			path:     "kv/app",
+			expected: `{"data":{"API_KEY":"new","TOKEN":"t"}}`,
+			deleted:  `{"data":{"API_KEY":"new"}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := vaultProvider(t, tt.options)
+			writer, err := NewWriter("vault", provider, config.PathMap{ID: "app", Path: tt.path})
+			if err != nil {
+				t.Fatalf("NewWriter() error = %v", err)
+			}
+			values, err := writer.Read([]string{"API_KEY", "TOKEN"})
+			if err != nil || len(values) != tt.existing {
+				t.Errorf("Read() = %v, %v, expected %d key(s)", values, err, tt.existing)
+			}
+			if err := writer.Put(SecretMap{"API_KEY": "new", "TOKEN": "t"}); err != nil {
+				t.Fatalf("Put() error = %v", err)
+			}
+			if secrets[tt.path] != tt.expected {
+				t.Errorf("Put() wrote %s, expected %s", secrets[tt.path], tt.expected)
+			}
+
+			if removed, err := writer.Delete([]string{"TOKEN", "MISSING"}); err != nil || strings.Join(removed, ",") != "TOKEN" {
+				t.Fatalf("Delete() = %v, %v, expected only TOKEN removed", removed, err)
+			}
+			if secrets[tt.path] != tt.deleted {
+				t.Errorf("Delete() wrote %s, expected %s", secrets[tt.path], tt.deleted)
+			}
+		})
+	}
+
+	// A change between the read and the write of Put is not overwritten
+	changed := `{"data":{"data":{"API_KEY":"other"},"metadata":{"version":9}}}`
+	racing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			secrets["secret/data/app"] = changed
+		}
+		server.Config.Handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(racing.Close)
+	t.Setenv("VAULT_ADDR", racing.URL)
+	writer, _ := NewWriter("vault", vaultProvider(t, "{}"), config.PathMap{ID: "app", Path: "secret/data/app"})
+	if err := writer.Put(SecretMap{"A": "1"}); err == nil || !strings.Contains(err.Error(), "provider vault: secret/data/app changed while feller wrote it, try again") {
+		t.Errorf("Put() of a changed secret error = %v", err)
+	}
+	if secrets["secret/data/app"] != changed {
+		t.Errorf("Put() overwrote a changed secret with %s", secrets["secret/data/app"])
+	}
+	t.Setenv("VAULT_ADDR", server.URL)
+
+	t.Setenv("VAULT_TOKEN", "wrong")
+	writer, _ = NewWriter("vault", vaultProvider(t, "{}"), config.PathMap{ID: "app", Path: "secret/data/app"})
+	if err := writer.Put(SecretMap{"A": "1"}); err == nil || !strings.Contains(err.Error(), "provider vault: failed to read secret/data/app: 403 Forbidden") {
+		t.Errorf("Put() with a rejected token error = %v", err)
+	}
+}
+
+// fakeGSM serves the Secret Manager API for the secrets of the project "projects/demo", by
+// their latest values, logging the requests, to requests with the token "t0k3n". Lists
+// return two secrets per page, and the secret PROTECTED cannot be deleted.
+func fakeGSM(t *testing.T, secrets map[string]string) *[]string {
+	t.Helper()
+	var mu sync.Mutex
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Header.Get("Authorization") != "Bearer t0k3n" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":{"message":"Request had invalid authentication credentials."}}`))
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, strings.TrimSpace(r.Method+" "+r.URL.RequestURI()+" "+string(body)))
+		name := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/v1/projects/demo/secrets"), "/")
+		name, method, _ := strings.Cut(name, ":")
+		name = strings.TrimSuffix(name, "/versions/latest")
+		value, exists := secrets[name]
+		switch {
+		case r.Method == http.MethodPost && name == "":
+			secrets[r.URL.Query().Get("secretId")] = ""
+		case r.Method == http.MethodGet && name == "":
+			names := slices.Sorted(maps.Keys(secrets))
+			start, _ := strconv.Atoi(r.URL.Query().Get("pageToken"))
+			var page struct {
+				Secrets       []map[string]string `json:"secrets"`
+				NextPageToken string              `json:"nextPageToken,omitempty"`
+			}
+			for _, secret := range names[start:min(start+2, len(names))] {
+				page.Secrets = append(page.Secrets, map[string]string{"name": "projects/123/secrets/" + secret})
+			}
+			if start+2 < len(names) {
+				page.NextPageToken = strconv.Itoa(start + 2)
+			}
+			data, _ := json.Marshal(page)
+			_, _ = w.Write(data)
+		case !exists:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error":{"message":"Secret not found"}}`))
+		case r.Method == http.MethodDelete && name == "PROTECTED":
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"error":{"message":"Permission denied"}}`))
+		case r.Method == http.MethodDelete:
+			delete(secrets, name)
+		case method == "addVersion":
+			var version struct {
+				Payload struct {
+					Data []byte `json:"data"`
+				} `json:"payload"`
+			}
+			_ = json.Unmarshal(body, &version)
+			secrets[name] = string(version.Payload.Data)
+		case method == "access":
+			data, _ := json.Marshal(map[string]any{"payload": map[string][]byte{"data": []byte(value)}})
+			_, _ = w.Write(data)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	endpoint := gsmEndpoint
+	gsmEndpoint = server.URL
+	t.Cleanup(func() { gsmEndpoint = endpoint })
+	return &requests
+}
+
+//nolint:paralleltest // replaces the Secret Manager endpoint and sets environment variables
+func TestGSMWriter(t *testing.T) {
+	secrets := map[string]string{"API_KEY": "old"}
+	requests := fakeGSM(t, secrets)
+	t.Setenv(gsmTokenEnv, "t0k3n")
+
+	writer, err := NewWriter("gsm", config.Provider{Kind: KindGoogleSecretManager}, config.PathMap{ID: "app", Path: "projects/demo"})
+	if err != nil {
+		t.Fatalf("NewWriter() error = %v", err)
+	}
+	if writer.Target() != "gsm:projects/demo" {
+		t.Errorf("Target() = %q", writer.Target())
+	}
+	values, err := writer.Read([]string{"API_KEY", "TOKEN"})
+	if err != nil || len(values) != 1 || values["API_KEY"] != "old" {
+		t.Errorf("Read() = %v, %v, expected only API_KEY", values, err)
+	}
+
+	*requests = nil
+	if err := writer.Put(SecretMap{"API_KEY": "new", "TOKEN": "t"}); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	expected := []string{
+		`POST /v1/projects/demo/secrets/API_KEY:addVersion {"payload":{"data":"bmV3"}}`,
+		`POST /v1/projects/demo/secrets/TOKEN:addVersion {"payload":{"data":"dA=="}}`,
+		`POST /v1/projects/demo/secrets?secretId=TOKEN {"replication":{"automatic":{}}}`,
+		`POST /v1/projects/demo/secrets/TOKEN:addVersion {"payload":{"data":"dA=="}}`,
+	}
+	if strings.Join(*requests, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Put() requests =\n%s\nexpected\n%s", strings.Join(*requests, "\n"), strings.Join(expected, "\n"))
+	}
+	if values, _ := writer.Read([]string{"API_KEY", "TOKEN"}); values["API_KEY"] != "new" || values["TOKEN"] != "t" {
+		t.Errorf("Read() after Put() = %v", values)
+	}
+
+	*requests = nil
+	if removed, err := writer.Delete([]string{"TOKEN", "MISSING"}); err != nil || strings.Join(removed, ",") != "TOKEN" {
+		t.Fatalf("Delete() = %v, %v, expected only TOKEN removed", removed, err)
+	}
+	if got := strings.Join(*requests, "\n"); got != "DELETE /v1/projects/demo/secrets/TOKEN\nDELETE /v1/projects/demo/secrets/MISSING" {
+		t.Errorf("Delete() requests =\n%s", got)
+	}
+	if _, kept := secrets["TOKEN"]; kept {
+		t.Error("Delete() kept TOKEN")
+	}
+
+	// A failure part way reports the secrets deleted before it
+	secrets["OLD"], secrets["PROTECTED"] = "o", "p"
+	removed, err := writer.Delete([]string{"OLD", "PROTECTED", "API_KEY"})
+	if err == nil || !strings.Contains(err.Error(), "failed to delete secret PROTECTED: 403 Forbidden") || strings.Join(removed, ",") != "OLD" {
+		t.Errorf("Delete() failing part way = %v, %v, expected OLD removed", removed, err)
+	}
+
+	t.Setenv(gsmTokenEnv, "expired")
+	writer, _ = NewWriter("gsm", config.Provider{Kind: KindGoogleSecretManager}, config.PathMap{ID: "app", Path: "projects/demo/"})
+	if _, err := writer.Read([]string{"API_KEY"}); err == nil || err.Error() != "provider gsm: failed to read secret API_KEY: 401 Unauthorized: Request had invalid authentication credentials." {
+		t.Errorf("Read() with an expired token error = %v", err)
+	}
+}
+
+func TestGSMWriterEndpoint(t *testing.T) {
+	secrets := map[string]string{"API_KEY": "emulated"}
+	fakeGSM(t, secrets)
+	emulator := gsmEndpoint
+	gsmEndpoint = "http://127.0.0.1:1"
+	t.Setenv(gsmTokenEnv, "t0k3n")
+
+	provider := githubProvider(t, "{endpoint: '"+emulator+"/'}")
+	provider.Kind = KindGoogleSecretManager
+	writer, err := NewWriter("gsm", provider, config.PathMap{ID: "app", Path: "projects/demo"})
+	if err != nil {
+		t.Fatalf("NewWriter() error = %v", err)
+	}
+	values, err := writer.Read([]string{"API_KEY"})
+	if err != nil || values["API_KEY"] != "emulated" {
+		t.Errorf("Read() = %v, %v, expected the secret of the endpoint", values, err)
+	}
+}
+
+func TestNewWriter(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		provider config.Provider
+		pathMap  config.PathMap
+		wantErr  string
+	}{
+		{
+			name:     "pinned version",
+			provider: config.Provider{Kind: KindDotenv},
+			pathMap:  config.PathMap{ID: "app", Path: ".env", Version: "3"},
+			wantErr:  "map app of provider p pins version 3; writes always create the latest version",
+		},
+		{
+			name:     "secret manager path",
+			provider: config.Provider{Kind: KindGoogleSecretManager},
+			pathMap:  config.PathMap{ID: "app", Keys: map[string]string{"A": "A"}},
+			wantErr:  "map app of provider p needs the path projects/PROJECT to write Google Secret Manager secrets",
+		},
+		{
+			name:     "read-only kind",
+			provider: config.Provider{Kind: KindGitHub},
+			pathMap:  config.PathMap{ID: "app"},
+			wantErr:  "provider p is of kind github, which feller cannot write (supported: dotenv, google_secretmanager, hashicorp_vault)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if _, err := NewWriter("p", tt.provider, tt.pathMap); err == nil || err.Error() != tt.wantErr {
+				t.Errorf("NewWriter() error = %v, expected %q", err, tt.wantErr)
+			}
+		})
+	}
+}
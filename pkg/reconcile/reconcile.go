@@ -0,0 +1,155 @@
+// Package reconcile compares external targets with the secrets of a config. Targets such as
+// GitHub repositories never return secret values, so the values written last are recorded
+// as salted fingerprints and compared with the current secrets instead.
+package reconcile
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/containifyci/feller/pkg/lockfile"
+	"github.com/containifyci/feller/pkg/metrics"
+	"github.com/containifyci/feller/pkg/plan"
+	"github.com/containifyci/feller/pkg/providers"
+	"github.com/containifyci/feller/pkg/state"
+)
+
+// Drift statuses
+const (
+	StatusMissing    = "missing"    // The target does not have the secret
+	StatusChanged    = "changed"    // The secret changed since it was written to the target
+	StatusUnverified = "unverified" // The target has the secret, but feller did not write it
+)
+
+// Drift is a secret of a target that does not match the config
+type Drift struct {
+	Key    string `json:"key"`
+	Scope  string `json:"scope"` // Where the target keeps the secret, e.g. "repository" or "Dependabot"
+	Status string `json:"status"`
+}
+
+// String describes the drift, e.g. "API_KEY (repository): missing"
+func (d Drift) String() string {
+	return fmt.Sprintf("%s (%s): %s", d.Key, d.Scope, d.Status)
+}
+
+// Change returns the change that fixes the drift in target, e.g. "github:owner/repo"
+func (d Drift) Change(target string) plan.Change {
+	action := plan.ActionUpdate
+	if d.Status == StatusMissing {
+		action = plan.ActionCreate
+	}
+	return plan.Change{Action: action, Target: target, Scope: d.Scope, Key: d.Key, Reason: d.Status}
+}
+
+// Compare returns the drift of the secrets in desired kept in scope of a target, which
+// holds the keys in existing, sorted by key. recorded holds the fingerprints of the
+// secrets written last and is nil before the first write. Secrets of the target that are
+// not desired are left alone and not reported.
+func Compare(desired *providers.CollectionResult, recorded *lockfile.File, scope string, existing map[string]bool) ([]Drift, error) {
+	changed := make(map[string]bool)
+	if recorded != nil {
+		changes, err := recorded.Changes(desired)
+		if err != nil {
+			return nil, err //nolint:wrapcheck // describes the failed fingerprint
+		}
+		for _, change := range changes {
+			// The provider does not matter to the target, only the value
+			if change.Current != nil && change.Locked != nil && change.Current.Fingerprint != change.Locked.Fingerprint {
+				changed[change.Key] = true
+			}
+		}
+	}
+
+	var drift []Drift
+	for key := range desired.Secrets {
+		var status string
+		switch {
+		case !existing[key]:
+			status = StatusMissing
+		case recorded == nil || !recordedKey(recorded, key):
+			status = StatusUnverified
+		case changed[key]:
+			status = StatusChanged
+		default:
+			continue
+		}
+		drift = append(drift, Drift{Key: key, Scope: scope, Status: status})
+	}
+	sort.Slice(drift, func(i, j int) bool { return drift[i].Key < drift[j].Key })
+	return drift, nil
+}
+
+func recordedKey(recorded *lockfile.File, key string) bool {
+	_, ok := recorded.Secrets[key]
+	return ok
+}
+
+// Path returns the file recording the secrets written to target, in the state directory of
+// the config at configPath
+func Path(configPath, target string) string {
+	return filepath.Join(state.Dir(configPath), state.ReconcileDir, target+".json")
+}
+
+// LoadRecorded reads the fingerprints at path, returning nil when none were recorded
+func LoadRecorded(path string) (*lockfile.File, error) {
+	recorded, err := lockfile.Load(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	return recorded, err //nolint:wrapcheck // names the file
+}
+
+// Record saves the fingerprints of the secrets written to a target at path
+func Record(path string, written *providers.CollectionResult) error {
+	recorded, err := lockfile.New(written)
+	if err != nil {
+		return err //nolint:wrapcheck // describes the failed fingerprint
+	}
+	if err := state.Ensure(filepath.Dir(filepath.Dir(path)), state.ReconcileDir); err != nil {
+		return err //nolint:wrapcheck // names the directory
+	}
+	return recorded.Write(path) //nolint:wrapcheck // names the file
+}
+
+// Result is the outcome of reconciling one target
+type Result struct {
+	Target  string
+	Secrets int     // Secrets the target should hold
+	Drift   []Drift // Drift found before fixing it
+	Fixed   int     // Secrets written to fix the drift
+	Err     error
+	Time    time.Time
+}
+
+// Observe sets the gauges of results in reg, labeled by target
+func Observe(reg *metrics.Registry, results []Result) {
+	for _, r := range results {
+		success := 1.0
+		if r.Err != nil {
+			success = 0
+		}
+		reg.Set("feller_reconcile_secrets", "Secrets the target should hold.", float64(r.Secrets), "target", r.Target)
+		reg.Set("feller_reconcile_drifted_secrets", "Secrets of the target that did not match the config.", float64(len(r.Drift)), "target", r.Target)
+		reg.Set("feller_reconcile_fixed_secrets", "Secrets written to the target to fix drift in the last run.", float64(r.Fixed), "target", r.Target)
+		reg.Set("feller_reconcile_success", "Whether the target was reconciled without errors.", success, "target", r.Target)
+		reg.Set("feller_reconcile_last_run_timestamp_seconds", "When the target was last reconciled.", float64(r.Time.Unix()), "target", r.Target)
+	}
+}
+
+// ObserveProviders sets the gauges of the health of cached providers in reg, labeled by
+// provider
+func ObserveProviders(reg *metrics.Registry, health []providers.ProviderHealth) {
+	for _, h := range health {
+		failing := 0.0
+		if h.Failed.After(h.Resolved) {
+			failing = 1
+		}
+		reg.Set("feller_provider_last_resolved_timestamp_seconds", "When the cached provider was last resolved.", float64(h.Resolved.Unix()), "provider", h.Provider)
+		reg.Set("feller_provider_refresh_failing", "Whether the last refresh of the cached provider failed, so stale secrets are served.", failing, "provider", h.Provider)
+	}
+}
@@ -0,0 +1,141 @@
+package reconcile
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/containifyci/feller/pkg/metrics"
+	"github.com/containifyci/feller/pkg/plan"
+	"github.com/containifyci/feller/pkg/providers"
+)
+
+func result(secrets map[string]string, provider string) *providers.CollectionResult {
+	r := &providers.CollectionResult{Secrets: secrets, Sources: make(map[string]providers.SecretSource)}
+	for key := range secrets {
+		r.Sources[key] = providers.SecretSource{Provider: provider, Kind: "dotenv"}
+	}
+	return r
+}
+
+func TestCompare(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), ".feller", "reconcile", "ci.json")
+	if err := Record(path, result(map[string]string{"SAME": "1", "MOVED": "2", "CHANGED": "3", "GONE": "4"}, "a")); err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+	recorded, err := LoadRecorded(path)
+	if err != nil {
+		t.Fatalf("LoadRecorded() error = %v", err)
+	}
+
+	desired := result(map[string]string{"SAME": "1", "MOVED": "2", "CHANGED": "new", "NEW": "5", "ABSENT": "6"}, "a")
+	desired.Sources["MOVED"] = providers.SecretSource{Provider: "b", Kind: "dotenv"}
+	existing := map[string]bool{"SAME": true, "MOVED": true, "CHANGED": true, "NEW": true, "UNMANAGED": true}
+
+	drift, err := Compare(desired, recorded, "repository", existing)
+	if err != nil {
+		t.Fatalf("Compare() error = %v", err)
+	}
+	var got []string
+	for _, d := range drift {
+		got = append(got, d.String())
+	}
+	expected := []string{"ABSENT (repository): missing", "CHANGED (repository): changed", "NEW (repository): unverified"}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Compare() = %v, expected %v", got, expected)
+	}
+
+	// Before the first write nothing can be verified
+	drift, err = Compare(desired, nil, "Dependabot", map[string]bool{"SAME": true})
+	if err != nil || len(drift) != 5 || drift[4] != (Drift{Key: "SAME", Scope: "Dependabot", Status: StatusUnverified}) {
+		t.Errorf("Compare() without record = %v, %v", drift, err)
+	}
+}
+
+func TestLoadRecordedMissing(t *testing.T) {
+	t.Parallel()
+	recorded, err := LoadRecorded(filepath.Join(t.TempDir(), "missing.json"))
+	if recorded != nil || err != nil {
+		t.Errorf("LoadRecorded() = %v, %v, expected nil, nil", recorded, err)
+	}
+}
+
+func TestPath(t *testing.T) {
+	t.Parallel()
+	got := Path(filepath.Join("project", ".teller.yml"), "ci")
+	if expected := filepath.Join("project", ".feller", "reconcile", "ci.json"); got != expected {
+		t.Errorf("Path() = %q, expected %q", got, expected)
+	}
+}
+
+func TestDriftChange(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		status string
+		action string
+	}{
+		{StatusMissing, plan.ActionCreate},
+		{StatusChanged, plan.ActionUpdate},
+		{StatusUnverified, plan.ActionUpdate},
+	}
+	for _, tt := range tests {
+		got := Drift{Key: "API_KEY", Scope: "repository", Status: tt.status}.Change("github:owner/repo")
+		expected := plan.Change{Action: tt.action, Target: "github:owner/repo", Scope: "repository", Key: "API_KEY", Reason: tt.status}
+		if got != expected {
+			t.Errorf("Change() of %s = %+v, expected %+v", tt.status, got, expected)
+		}
+	}
+}
+
+func TestObserve(t *testing.T) {
+	t.Parallel()
+	at := time.Unix(1700000000, 0)
+	reg := metrics.NewRegistry()
+	Observe(reg, []Result{
+		{Target: "ci", Secrets: 3, Drift: []Drift{{Key: "A"}}, Fixed: 1, Time: at},
+		{Target: "prod", Err: errors.New("failed"), Time: at},
+	})
+	var b strings.Builder
+	if _, err := reg.WriteTo(&b); err != nil {
+		t.Fatalf("WriteTo() error = %v", err)
+	}
+	for _, line := range []string{
+		"# TYPE feller_reconcile_drifted_secrets gauge",
+		`feller_reconcile_secrets{target="ci"} 3`,
+		`feller_reconcile_drifted_secrets{target="ci"} 1`,
+		`feller_reconcile_fixed_secrets{target="ci"} 1`,
+		`feller_reconcile_success{target="ci"} 1`,
+		`feller_reconcile_success{target="prod"} 0`,
+		`feller_reconcile_last_run_timestamp_seconds{target="prod"} 1700000000`,
+	} {
+		if !strings.Contains(b.String(), line+"\n") {
+			t.Errorf("Observe() = %q, expected to contain %q", b.String(), line)
+		}
+	}
+}
+
+func TestObserveProviders(t *testing.T) {
+	t.Parallel()
+	at := time.Unix(1700000000, 0)
+	reg := metrics.NewRegistry()
+	ObserveProviders(reg, []providers.ProviderHealth{
+		{Provider: "vault", Resolved: at},
+		{Provider: "plugin", Resolved: at, Failed: at.Add(time.Minute), Err: errors.New("unreachable")},
+	})
+	var b strings.Builder
+	if _, err := reg.WriteTo(&b); err != nil {
+		t.Fatalf("WriteTo() error = %v", err)
+	}
+	for _, line := range []string{
+		`feller_provider_last_resolved_timestamp_seconds{provider="vault"} 1700000000`,
+		`feller_provider_refresh_failing{provider="vault"} 0`,
+		`feller_provider_refresh_failing{provider="plugin"} 1`,
+	} {
+		if !strings.Contains(b.String(), line+"\n") {
+			t.Errorf("ObserveProviders() = %q, expected to contain %q", b.String(), line)
+		}
+	}
+}
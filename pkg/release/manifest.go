@@ -0,0 +1,303 @@
+// Package release generates packaging descriptors for feller releases: Homebrew formulas,
+// Scoop manifests and nfpm configs for deb and rpm packages. They describe the binaries
+// goreleaser publishes, so each channel can be regenerated from the release alone.
+package release
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"runtime/debug"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Project metadata shared by every descriptor
+const (
+	Name        = "feller"
+	Description = "Lightweight secret management optimized for GitHub Actions, compatible with teller configs"
+	Homepage    = "https://github.com/containifyci/feller"
+	License     = "MIT"
+	Maintainer  = "containifyci"
+)
+
+// downloadURL is the base URL of release assets; assets are at <base>/<tag>/<asset>
+const downloadURL = Homepage + "/releases/download"
+
+// Platform is an operating system and architecture feller is released for
+type Platform struct {
+	OS   string
+	Arch string
+}
+
+// Platforms lists the release builds, matching the goreleaser matrix
+var Platforms = []Platform{
+	{OS: "darwin", Arch: "amd64"},
+	{OS: "darwin", Arch: "arm64"},
+	{OS: "linux", Arch: "amd64"},
+	{OS: "linux", Arch: "arm64"},
+	{OS: "windows", Arch: "amd64"},
+}
+
+var versionPattern = regexp.MustCompile(`^v?\d+\.\d+\.\d+(-[0-9A-Za-z.-]+)?$`)
+
+// Info describes the release the descriptors are generated for
+type Info struct {
+	Version   string            // Version without a leading v, e.g. 1.2.3
+	Checksums map[string]string // Asset name -> sha256, as in checksums.txt
+}
+
+// NewInfo validates version and returns the release info for it
+func NewInfo(version string, checksums map[string]string) (Info, error) {
+	if !versionPattern.MatchString(version) {
+		return Info{}, fmt.Errorf("invalid release version %q (expected e.g. v1.2.3)", version)
+	}
+	return Info{Version: strings.TrimPrefix(version, "v"), Checksums: checksums}, nil
+}
+
+// BuildVersion returns the module version the running binary was built from. Go stamps it
+// from the release tag, so it is empty for builds of untagged commits.
+func BuildVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok || info.Main.Version == "(devel)" {
+		return ""
+	}
+	return info.Main.Version
+}
+
+// AssetName returns the name of the release binary for platform
+func AssetName(platform Platform) string {
+	name := fmt.Sprintf("%s-%s-%s", Name, platform.OS, platform.Arch)
+	if platform.OS == "windows" {
+		name += ".exe"
+	}
+	return name
+}
+
+// ParseChecksums reads a goreleaser checksums.txt ("<sha256>  <asset>" per line)
+func ParseChecksums(r io.Reader) (map[string]string, error) {
+	checksums := make(map[string]string)
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) != 2 || len(fields[0]) != 64 {
+			return nil, fmt.Errorf("line %d: expected \"<sha256>  <asset>\"", line)
+		}
+		checksums[strings.TrimPrefix(fields[1], "*")] = fields[0]
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read checksums: %w", err)
+	}
+	return checksums, nil
+}
+
+// url returns the download URL of asset
+func (i Info) url(asset string) string {
+	return fmt.Sprintf("%s/v%s/%s", downloadURL, i.Version, asset)
+}
+
+// checksum returns the sha256 of the asset of platform
+func (i Info) checksum(platform Platform) (string, error) {
+	asset := AssetName(platform)
+	sum, ok := i.Checksums[asset]
+	if !ok {
+		return "", fmt.Errorf("checksum of %s not found (pass the release's checksums.txt)", asset)
+	}
+	return sum, nil
+}
+
+// brewAsset is a platform download of the formula
+type brewAsset struct {
+	URL    string
+	SHA256 string
+}
+
+var brewTemplate = template.Must(template.New("brew").Parse(`class Feller < Formula
+  desc "{{.Description}}"
+  homepage "{{.Homepage}}"
+  version "{{.Version}}"
+  license "{{.License}}"
+{{range $os := .OSes}}
+  on_{{$os.Name}} do
+{{- range $os.Arches}}
+    on_{{.Name}} do
+      url "{{.Asset.URL}}"
+      sha256 "{{.Asset.SHA256}}"
+    end
+{{- end}}
+  end
+{{end}}
+  def install
+    bin.install Dir["feller-*"].first => "feller"
+  end
+
+  test do
+    system bin/"feller", "--help"
+  end
+end
+`))
+
+// brewOS names a Homebrew on_<os> block and its on_<arch> blocks
+type brewOS struct {
+	Name   string
+	Arches []brewArch
+}
+
+type brewArch struct {
+	Name  string
+	Asset brewAsset
+}
+
+// WriteBrew writes a Homebrew formula installing the macOS and Linux binaries
+func WriteBrew(w io.Writer, info Info) error {
+	oses := []brewOS{{Name: "macos"}, {Name: "linux"}}
+	index := map[string]int{"darwin": 0, "linux": 1}
+	for _, platform := range Platforms {
+		i, ok := index[platform.OS]
+		if !ok {
+			continue
+		}
+		sum, err := info.checksum(platform)
+		if err != nil {
+			return err
+		}
+		arch := map[string]string{"amd64": "intel", "arm64": "arm"}[platform.Arch]
+		oses[i].Arches = append(oses[i].Arches, brewArch{
+			Name:  arch,
+			Asset: brewAsset{URL: info.url(AssetName(platform)), SHA256: sum},
+		})
+	}
+
+	data := struct {
+		Description, Homepage, Version, License string
+		OSes                                    []brewOS
+	}{Description, Homepage, info.Version, License, oses}
+	if err := brewTemplate.Execute(w, data); err != nil {
+		return fmt.Errorf("failed to write brew formula: %w", err)
+	}
+	return nil
+}
+
+// scoopManifest is the JSON manifest of a Scoop bucket
+type scoopManifest struct {
+	Version      string                       `json:"version"`
+	Description  string                       `json:"description"`
+	Homepage     string                       `json:"homepage"`
+	License      string                       `json:"license"`
+	Architecture map[string]scoopArchitecture `json:"architecture"`
+	Bin          string                       `json:"bin"`
+}
+
+type scoopArchitecture struct {
+	URL  string `json:"url"`
+	Hash string `json:"hash"`
+}
+
+// WriteScoop writes a Scoop manifest installing the Windows binary
+func WriteScoop(w io.Writer, info Info) error {
+	manifest := scoopManifest{
+		Version:      info.Version,
+		Description:  Description,
+		Homepage:     Homepage,
+		License:      License,
+		Architecture: make(map[string]scoopArchitecture),
+		Bin:          Name + ".exe",
+	}
+	for _, platform := range Platforms {
+		if platform.OS != "windows" {
+			continue
+		}
+		sum, err := info.checksum(platform)
+		if err != nil {
+			return err
+		}
+		arch := map[string]string{"amd64": "64bit", "arm64": "arm64"}[platform.Arch]
+		// The fragment renames the downloaded binary to the name in bin
+		manifest.Architecture[arch] = scoopArchitecture{URL: info.url(AssetName(platform)) + "#/" + manifest.Bin, Hash: sum}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(manifest); err != nil {
+		return fmt.Errorf("failed to write scoop manifest: %w", err)
+	}
+	return nil
+}
+
+// nfpmConfig is the nfpm config building deb and rpm packages of one architecture
+type nfpmConfig struct {
+	Name        string        `yaml:"name"`
+	Arch        string        `yaml:"arch"`
+	Platform    string        `yaml:"platform"`
+	Version     string        `yaml:"version"`
+	Maintainer  string        `yaml:"maintainer"`
+	Description string        `yaml:"description"`
+	Homepage    string        `yaml:"homepage"`
+	License     string        `yaml:"license"`
+	Contents    []nfpmContent `yaml:"contents"`
+}
+
+type nfpmContent struct {
+	Src      string       `yaml:"src"`
+	Dst      string       `yaml:"dst"`
+	FileInfo nfpmFileInfo `yaml:"file_info"`
+}
+
+type nfpmFileInfo struct {
+	Mode fileMode `yaml:"mode"`
+}
+
+// fileMode is a permission written in octal, as in the nfpm documentation
+type fileMode uint32
+
+// MarshalYAML writes the mode as an octal integer such as 0755
+func (m fileMode) MarshalYAML() (any, error) {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: fmt.Sprintf("%04o", uint32(m))}, nil
+}
+
+// WriteNFPM writes the nfpm config packaging the Linux binary of arch, which is expected
+// next to the config under its release asset name
+func WriteNFPM(w io.Writer, info Info, arch string) error {
+	platform := Platform{OS: "linux", Arch: arch}
+	found := false
+	for _, p := range Platforms {
+		found = found || p == platform
+	}
+	if !found {
+		return fmt.Errorf("feller is not released for linux/%s", arch)
+	}
+
+	cfg := nfpmConfig{
+		Name:        Name,
+		Arch:        arch,
+		Platform:    platform.OS,
+		Version:     info.Version,
+		Maintainer:  Maintainer,
+		Description: Description,
+		Homepage:    Homepage,
+		License:     License,
+		Contents: []nfpmContent{{
+			Src:      "./" + AssetName(platform),
+			Dst:      "/usr/bin/" + Name,
+			FileInfo: nfpmFileInfo{Mode: 0o755},
+		}},
+	}
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(cfg); err != nil {
+		return fmt.Errorf("failed to write nfpm config: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return fmt.Errorf("failed to write nfpm config: %w", err)
+	}
+	return nil
+}
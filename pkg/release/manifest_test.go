@@ -0,0 +1,133 @@
+package release
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// testChecksums returns a checksum per release asset, derived from its name
+func testChecksums() map[string]string {
+	checksums := make(map[string]string)
+	for i, platform := range Platforms {
+		checksums[AssetName(platform)] = strings.Repeat(fmt.Sprint(i), 64)
+	}
+	return checksums
+}
+
+func TestNewInfo(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		version     string
+		expected    string
+		errContains string
+	}{
+		{version: "v1.2.3", expected: "1.2.3"},
+		{version: "1.2.3", expected: "1.2.3"},
+		{version: "v1.2.3-rc.1", expected: "1.2.3-rc.1"},
+		{version: "v1.2.4-0.20250101000000-abcdef123456", expected: "1.2.4-0.20250101000000-abcdef123456"},
+		{version: "latest", errContains: `invalid release version "latest"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			t.Parallel()
+			info, err := NewInfo(tt.version, nil)
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Errorf("NewInfo() error = %v, expected to contain %q", err, tt.errContains)
+				}
+				return
+			}
+			if err != nil || info.Version != tt.expected {
+				t.Errorf("NewInfo() = %q, %v, want %q", info.Version, err, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseChecksums(t *testing.T) {
+	t.Parallel()
+	sum := strings.Repeat("a", 64)
+
+	checksums, err := ParseChecksums(strings.NewReader(sum + "  feller-linux-amd64\n\n" + sum + " *feller-windows-amd64.exe\n"))
+	if err != nil {
+		t.Fatalf("ParseChecksums() unexpected error = %v", err)
+	}
+	if len(checksums) != 2 || checksums["feller-linux-amd64"] != sum || checksums["feller-windows-amd64.exe"] != sum {
+		t.Errorf("ParseChecksums() = %v", checksums)
+	}
+
+	if _, err := ParseChecksums(strings.NewReader(sum + "  a\nabc  feller-linux-amd64\n")); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("ParseChecksums() error = %v, want line 2 rejected", err)
+	}
+}
+
+func TestWriteBrew(t *testing.T) {
+	t.Parallel()
+	info := Info{Version: "1.2.3", Checksums: testChecksums()}
+
+	var buf bytes.Buffer
+	if err := WriteBrew(&buf, info); err != nil {
+		t.Fatalf("WriteBrew() unexpected error = %v", err)
+	}
+	formula := buf.String()
+	for _, want := range []string{
+		`version "1.2.3"`,
+		`license "MIT"`,
+		"  on_macos do\n    on_intel do\n      url \"https://github.com/containifyci/feller/releases/download/v1.2.3/feller-darwin-amd64\"\n      sha256 \"" + strings.Repeat("0", 64) + "\"",
+		"    on_arm do\n      url \"https://github.com/containifyci/feller/releases/download/v1.2.3/feller-linux-arm64\"\n      sha256 \"" + strings.Repeat("3", 64) + "\"",
+		`bin.install Dir["feller-*"].first => "feller"`,
+	} {
+		if !strings.Contains(formula, want) {
+			t.Errorf("WriteBrew() formula does not contain %q:\n%s", want, formula)
+		}
+	}
+	if strings.Contains(formula, "windows") {
+		t.Errorf("WriteBrew() formula refers to the Windows binary:\n%s", formula)
+	}
+
+	delete(info.Checksums, "feller-linux-arm64")
+	if err := WriteBrew(&bytes.Buffer{}, info); err == nil || !strings.Contains(err.Error(), "checksum of feller-linux-arm64 not found") {
+		t.Errorf("WriteBrew() error = %v, want missing checksum", err)
+	}
+}
+
+func TestWriteScoop(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	if err := WriteScoop(&buf, Info{Version: "1.2.3", Checksums: testChecksums()}); err != nil {
+		t.Fatalf("WriteScoop() unexpected error = %v", err)
+	}
+
+	var manifest scoopManifest
+	if err := json.Unmarshal(buf.Bytes(), &manifest); err != nil {
+		t.Fatalf("WriteScoop() wrote invalid JSON: %v", err)
+	}
+	want := scoopArchitecture{
+		URL:  "https://github.com/containifyci/feller/releases/download/v1.2.3/feller-windows-amd64.exe#/feller.exe",
+		Hash: strings.Repeat("4", 64),
+	}
+	if manifest.Version != "1.2.3" || manifest.Bin != "feller.exe" || len(manifest.Architecture) != 1 || manifest.Architecture["64bit"] != want {
+		t.Errorf("WriteScoop() = %+v", manifest)
+	}
+}
+
+func TestWriteNFPM(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	if err := WriteNFPM(&buf, Info{Version: "1.2.3"}, "arm64"); err != nil {
+		t.Fatalf("WriteNFPM() unexpected error = %v", err)
+	}
+	for _, want := range []string{"arch: arm64\n", "version: 1.2.3\n", "  - src: ./feller-linux-arm64\n    dst: /usr/bin/feller\n", "      mode: 0755\n"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("WriteNFPM() config does not contain %q:\n%s", want, buf.String())
+		}
+	}
+
+	if err := WriteNFPM(&bytes.Buffer{}, Info{Version: "1.2.3"}, "386"); err == nil || !strings.Contains(err.Error(), "not released for linux/386") {
+		t.Errorf("WriteNFPM() error = %v, want unsupported architecture", err)
+	}
+}
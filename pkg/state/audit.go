@@ -0,0 +1,76 @@
+package state
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// AuditFile is the log in the audit subdirectory of the changes feller made to secrets, one
+// JSON object per line
+const AuditFile = "audit.log"
+
+// AuditEntry records a change feller made to the secrets of a target. Values are never
+// recorded, only the keys.
+type AuditEntry struct {
+	Time     time.Time `json:"time"`
+	User     string    `json:"user,omitempty"`
+	Command  string    `json:"command"`
+	Provider string    `json:"provider"`
+	Map      string    `json:"map"`
+	Target   string    `json:"target"`
+	Keys     []string  `json:"keys"`
+}
+
+// AppendAudit appends entry to the audit log of the state directory dir
+func AppendAudit(dir string, entry AuditEntry) error {
+	if err := Ensure(dir, AuditDir); err != nil {
+		return err
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit entry: %w", err)
+	}
+	path := filepath.Join(dir, AuditDir, AuditFile)
+	// #nosec G304 - The path is inside the state directory
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	// A single write keeps the lines of concurrent feller processes apart
+	_, err = f.Write(append(data, '\n'))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	return nil
+}
+
+// ReadAudit returns the entries of the audit log of the state directory dir, oldest first. A
+// missing log has none.
+func ReadAudit(dir string) ([]AuditEntry, error) {
+	// #nosec G304 - The path is inside the state directory
+	data, err := os.ReadFile(filepath.Join(dir, AuditDir, AuditFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	var entries []AuditEntry
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	for decoder.More() {
+		var entry AuditEntry
+		if err := decoder.Decode(&entry); err != nil {
+			return nil, fmt.Errorf("failed to parse audit log: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
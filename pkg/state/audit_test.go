@@ -0,0 +1,33 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAudit(t *testing.T) {
+	t.Parallel()
+	dir := filepath.Join(t.TempDir(), DirName)
+	if entries, err := ReadAudit(dir); err != nil || len(entries) != 0 {
+		t.Fatalf("ReadAudit(missing) = %v, %v, expected none", entries, err)
+	}
+
+	when := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	for _, command := range []string{"put", "delete"} {
+		entry := AuditEntry{Time: when, User: "dev", Command: command, Provider: "local", Map: "app", Target: "dotenv:.env", Keys: []string{"API_KEY"}}
+		if err := AppendAudit(dir, entry); err != nil {
+			t.Fatalf("AppendAudit() error = %v", err)
+		}
+	}
+
+	entries, err := ReadAudit(dir)
+	if err != nil || len(entries) != 2 || entries[0].Command != "put" || entries[1].Command != "delete" || !entries[1].Time.Equal(when) {
+		t.Fatalf("ReadAudit() = %+v, %v", entries, err)
+	}
+	info, err := os.Stat(filepath.Join(dir, AuditDir, AuditFile))
+	if err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("audit log mode = %v, %v, expected 0600", info.Mode().Perm(), err)
+	}
+}
@@ -0,0 +1,71 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// backupSuffix is the extension of backup files, which are age encrypted
+const backupSuffix = ".age"
+
+// backupTimeFormat names backups by their creation time, so names sort by age
+const backupTimeFormat = "20060102T150405.000000000Z"
+
+// SaveBackup writes the encrypted backup data created at created to the state directory dir,
+// readable only by the current user, and removes the oldest backups beyond keep. It returns
+// the path of the backup.
+func SaveBackup(dir string, created time.Time, data []byte, keep int) (string, error) {
+	if err := Ensure(dir, BackupDir); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, BackupDir, created.UTC().Format(backupTimeFormat)+backupSuffix)
+	// #nosec G304 - The path is inside the state directory
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return "", fmt.Errorf("failed to write backup: %w", err)
+	}
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(path)
+		return "", fmt.Errorf("failed to write backup: %w", err)
+	}
+
+	backups, err := Backups(dir)
+	if err != nil {
+		return path, err
+	}
+	for len(backups) > keep {
+		if err := os.Remove(backups[0]); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return path, fmt.Errorf("failed to remove old backup: %w", err)
+		}
+		backups = backups[1:]
+	}
+	return path, nil
+}
+
+// Backups returns the paths of the backups in the state directory dir, oldest first
+func Backups(dir string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(dir, BackupDir))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list backups: %w", err)
+	}
+	var paths []string
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && strings.HasSuffix(entry.Name(), backupSuffix) {
+			paths = append(paths, filepath.Join(dir, BackupDir, entry.Name()))
+		}
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
@@ -0,0 +1,41 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBackups(t *testing.T) {
+	t.Parallel()
+	dir := filepath.Join(t.TempDir(), DirName)
+	if backups, err := Backups(dir); err != nil || len(backups) != 0 {
+		t.Fatalf("Backups(missing) = %v, %v, expected none", backups, err)
+	}
+
+	start := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	var paths []string
+	for i := range 4 {
+		path, err := SaveBackup(dir, start.Add(time.Duration(i)*time.Second), []byte{byte('a' + i)}, 3)
+		if err != nil {
+			t.Fatalf("SaveBackup() error = %v", err)
+		}
+		paths = append(paths, path)
+	}
+	if filepath.Base(paths[0]) != "20261016T120000.000000000Z.age" {
+		t.Errorf("SaveBackup() path = %s", paths[0])
+	}
+
+	backups, err := Backups(dir)
+	if err != nil || len(backups) != 3 || backups[0] != paths[1] || backups[2] != paths[3] {
+		t.Fatalf("Backups() = %v, %v, expected the newest 3 of %v", backups, err, paths)
+	}
+	data, err := os.ReadFile(backups[2])
+	if err != nil || string(data) != "d" {
+		t.Errorf("newest backup = %q, %v", data, err)
+	}
+	if info, err := os.Stat(backups[2]); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("backup mode = %v, %v, expected 0600", info.Mode().Perm(), err)
+	}
+}
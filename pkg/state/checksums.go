@@ -0,0 +1,73 @@
+package state
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ChecksumsFile is the file in the state directory recording the checksums of the files
+// secrets were read from
+const ChecksumsFile = "checksums.json"
+
+// Checksums records the content of secret files as last seen by feller, so changes made
+// behind its back can be reported
+type Checksums struct {
+	Files map[string]FileChecksum `json:"files"` // By absolute path
+}
+
+// FileChecksum is the checksum of a file and when it was recorded
+type FileChecksum struct {
+	SHA256   string    `json:"sha256"`
+	Recorded time.Time `json:"recorded"`
+}
+
+// LoadChecksums reads the checksums of the state directory dir. A missing file records none.
+func LoadChecksums(dir string) (*Checksums, error) {
+	c := &Checksums{Files: make(map[string]FileChecksum)}
+	// #nosec G304 - The path is inside the state directory
+	data, err := os.ReadFile(filepath.Join(dir, ChecksumsFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checksums: %w", err)
+	}
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("failed to parse checksums: %w", err)
+	}
+	if c.Files == nil {
+		c.Files = make(map[string]FileChecksum)
+	}
+	return c, nil
+}
+
+// Write saves the checksums in the state directory dir, replacing the file atomically so
+// concurrent feller processes never read a partial one
+func (c *Checksums) Write(dir string) error {
+	return writeJSON(dir, ChecksumsFile, "checksums", c)
+}
+
+// Record stores the checksum of the file at path and returns the previously recorded one.
+// changed is true when a different checksum was recorded before.
+func (c *Checksums) Record(path, sum string) (previous FileChecksum, changed bool) {
+	previous, ok := c.Files[path]
+	c.Files[path] = FileChecksum{SHA256: sum, Recorded: time.Now().UTC().Truncate(time.Second)}
+	return previous, ok && previous.SHA256 != sum
+}
+
+// FileSHA256 returns the hex SHA-256 digest of the file at path
+func FileSHA256(path string) (string, error) {
+	// #nosec G304 - Paths come from the user's config
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
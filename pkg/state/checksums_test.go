@@ -0,0 +1,45 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestChecksums(t *testing.T) {
+	t.Parallel()
+	dir := filepath.Join(t.TempDir(), DirName)
+	c, err := LoadChecksums(dir)
+	if err != nil || len(c.Files) != 0 {
+		t.Fatalf("LoadChecksums(missing) = %+v, %v, expected none", c, err)
+	}
+
+	path := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(path, []byte("A=1\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	sum, err := FileSHA256(path)
+	if err != nil || sum != "91d6a3d55e9fea7911c537afae6607c77fa8bc0f3a76c375e104ac5a8cfa84db" {
+		t.Fatalf("FileSHA256() = %q, %v", sum, err)
+	}
+	if _, changed := c.Record(path, sum); changed {
+		t.Error("Record() of a new file reported a change")
+	}
+	if err := c.Write(dir); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	loaded, err := LoadChecksums(dir)
+	if err != nil {
+		t.Fatalf("LoadChecksums() error = %v", err)
+	}
+	if _, changed := loaded.Record(path, sum); changed {
+		t.Error("Record() of the same checksum reported a change")
+	}
+	if previous, changed := loaded.Record(path, "other"); !changed || previous.SHA256 != sum {
+		t.Errorf("Record() = %+v, %v, expected the previous checksum and a change", previous, changed)
+	}
+	if _, err := os.Stat(filepath.Join(dir, ".gitignore")); err != nil {
+		t.Errorf("Write() did not create the state directory: %v", err)
+	}
+}
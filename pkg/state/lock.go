@@ -0,0 +1,110 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ErrLocked is returned by Acquire when another process holds the lock
+var ErrLocked = errors.New("config is locked by another feller process")
+
+// Lock is a held lockfile. Locks are advisory: they only keep feller processes that acquire
+// the same lock from running mutating operations on one config at the same time.
+type Lock struct {
+	path string
+}
+
+// LockInfo is the content of a lockfile, describing its holder
+type LockInfo struct {
+	PID       int       `json:"pid"`
+	Operation string    `json:"operation"`
+	Started   time.Time `json:"started"`
+}
+
+// LockPath returns the lockfile of the config at configPath. Each config in a directory
+// has its own lock.
+func LockPath(configPath string) string {
+	return filepath.Join(Dir(configPath), filepath.Base(configPath)+".lock")
+}
+
+// Acquire creates the lockfile at path for operation. It fails with ErrLocked while the
+// lockfile belongs to a running process, and takes over lockfiles left behind by processes
+// that no longer exist.
+func Acquire(path, operation string) (*Lock, error) {
+	if err := Ensure(filepath.Dir(path)); err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(LockInfo{PID: os.Getpid(), Operation: operation, Started: time.Now().UTC()})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode lock: %w", err)
+	}
+
+	// A stale lock is removed once before trying again; losing that race to another
+	// process is reported like any other held lock
+	for attempt := 0; ; attempt++ {
+		// #nosec G304 - Lock path is derived from the config path
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+		if err == nil {
+			_, writeErr := f.Write(data)
+			if closeErr := f.Close(); writeErr == nil {
+				writeErr = closeErr
+			}
+			if writeErr != nil {
+				_ = os.Remove(path)
+				return nil, fmt.Errorf("failed to write lock %s: %w", path, writeErr)
+			}
+			return &Lock{path: path}, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("failed to create lock %s: %w", path, err)
+		}
+
+		holder, readErr := ReadLock(path)
+		switch {
+		case errors.Is(readErr, os.ErrNotExist) && attempt == 0:
+			continue // Released in the meantime
+		case readErr == nil && !ProcessAlive(holder.PID) && attempt == 0:
+			if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return nil, fmt.Errorf("failed to remove stale lock %s: %w", path, err)
+			}
+			continue
+		case readErr == nil:
+			return nil, fmt.Errorf("%w: pid %d has been running %s since %s (remove %s if that process no longer exists)",
+				ErrLocked, holder.PID, holder.Operation, holder.Started.Local().Format(time.DateTime), path)
+		default:
+			return nil, fmt.Errorf("%w: %s exists but cannot be read: %w", ErrLocked, path, readErr)
+		}
+	}
+}
+
+// ReadLock returns the holder recorded in the lockfile at path
+func ReadLock(path string) (*LockInfo, error) {
+	// #nosec G304 - Lock path is derived from the config path
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // callers check for os.ErrNotExist
+	}
+	var info LockInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, fmt.Errorf("invalid lockfile: %w", err)
+	}
+	return &info, nil
+}
+
+// Release removes the lockfile. Releasing a lock twice is not an error.
+func (l *Lock) Release() error {
+	if l == nil || l.path == "" {
+		return nil
+	}
+	err := os.Remove(l.path)
+	l.path = ""
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to release lock: %w", err)
+	}
+	return nil
+}
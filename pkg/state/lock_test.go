@@ -0,0 +1,103 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLockPath(t *testing.T) {
+	t.Parallel()
+	expected := filepath.Join("app", DirName, ".teller.yml.lock")
+	if result := LockPath(filepath.Join("app", ".teller.yml")); result != expected {
+		t.Errorf("LockPath() = %q, want %q", result, expected)
+	}
+}
+
+func TestAcquire(t *testing.T) {
+	t.Parallel()
+	path := LockPath(filepath.Join(t.TempDir(), ".teller.yml"))
+
+	lock, err := Acquire(path, "sync")
+	if err != nil {
+		t.Fatalf("Acquire() unexpected error = %v", err)
+	}
+	info, err := ReadLock(path)
+	if err != nil {
+		t.Fatalf("ReadLock() unexpected error = %v", err)
+	}
+	if info.PID != os.Getpid() || info.Operation != "sync" || time.Since(info.Started) > time.Minute {
+		t.Errorf("ReadLock() = %+v, want current process running sync", info)
+	}
+
+	// The holder is alive, so a second acquisition fails and names it
+	_, err = Acquire(path, "rotate")
+	if !errors.Is(err, ErrLocked) {
+		t.Fatalf("Acquire() while held error = %v, want ErrLocked", err)
+	}
+	if !strings.Contains(err.Error(), "running sync since") {
+		t.Errorf("Acquire() error = %v, expected to describe the holder", err)
+	}
+
+	if err := lock.Release(); err != nil {
+		t.Fatalf("Release() unexpected error = %v", err)
+	}
+	if err := lock.Release(); err != nil {
+		t.Errorf("second Release() unexpected error = %v", err)
+	}
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Release() left lockfile behind: %v", err)
+	}
+
+	lock, err = Acquire(path, "rotate")
+	if err != nil {
+		t.Fatalf("Acquire() after release unexpected error = %v", err)
+	}
+	_ = lock.Release()
+}
+
+func TestAcquireStaleLock(t *testing.T) {
+	t.Parallel()
+	// The PID of an exited process is a holder that no longer exists
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("Failed to run helper process: %v", err)
+	}
+	path := LockPath(filepath.Join(t.TempDir(), ".teller.yml"))
+	if err := Ensure(filepath.Dir(path)); err != nil {
+		t.Fatalf("Ensure() unexpected error = %v", err)
+	}
+	data, _ := json.Marshal(LockInfo{PID: cmd.Process.Pid, Operation: "sync", Started: time.Now()})
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("Failed to write lockfile: %v", err)
+	}
+
+	lock, err := Acquire(path, "rotate")
+	if err != nil {
+		t.Fatalf("Acquire() over stale lock unexpected error = %v", err)
+	}
+	defer lock.Release()
+	if info, err := ReadLock(path); err != nil || info.Operation != "rotate" {
+		t.Errorf("ReadLock() = %+v, %v, want rotate", info, err)
+	}
+}
+
+func TestAcquireUnreadableLock(t *testing.T) {
+	t.Parallel()
+	path := LockPath(filepath.Join(t.TempDir(), ".teller.yml"))
+	if err := Ensure(filepath.Dir(path)); err != nil {
+		t.Fatalf("Ensure() unexpected error = %v", err)
+	}
+	if err := os.WriteFile(path, []byte("garbage"), 0o600); err != nil {
+		t.Fatalf("Failed to write lockfile: %v", err)
+	}
+
+	if _, err := Acquire(path, "sync"); !errors.Is(err, ErrLocked) || !strings.Contains(err.Error(), "cannot be read") {
+		t.Errorf("Acquire() error = %v, want ErrLocked for unreadable lockfile", err)
+	}
+}
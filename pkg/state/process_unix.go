@@ -0,0 +1,10 @@
+//go:build !windows
+
+package state
+
+import "syscall"
+
+// ProcessAlive reports whether a process with the given PID exists
+func ProcessAlive(pid int) bool {
+	return syscall.Kill(pid, 0) == nil
+}
@@ -0,0 +1,88 @@
+// Package state manages the project-local .feller directory next to a teller config, which
+// holds data feller keeps between invocations: caches, audit logs, backups, resume state of
+// interrupted operations, fingerprints of the secrets written to reconcile targets, digests
+// of the values uploaded to GitHub, records of sync runs and the lockfile of mutating
+// operations.
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// DirName is the name of the state directory
+const DirName = ".feller"
+
+// Subdirectories of the state directory
+const (
+	CacheDir     = "cache"
+	AuditDir     = "audit"
+	ResumeDir    = "resume"
+	ReconcileDir = "reconcile" // Fingerprints of the secrets last written to each target
+	BackupDir    = "backups"   // Encrypted values of secrets before feller changed them
+	SyncDir      = "sync"      // State of the targets before each feller sync
+)
+
+// gitignore keeps the whole state directory out of version control, so projects do not
+// need to list it in their own .gitignore
+const gitignore = "# Created by feller; local state must not be committed\n*\n"
+
+// Dir returns the state directory of the config at configPath
+func Dir(configPath string) string {
+	return filepath.Join(filepath.Dir(configPath), DirName)
+}
+
+// Ensure creates the state directory dir and the given subdirectories if they do not exist,
+// readable only by the current user
+func Ensure(dir string, subdirs ...string) error {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+
+	ignorePath := filepath.Join(dir, ".gitignore")
+	if _, err := os.Stat(ignorePath); errors.Is(err, os.ErrNotExist) {
+		if err := os.WriteFile(ignorePath, []byte(gitignore), 0o600); err != nil {
+			return fmt.Errorf("failed to write %s: %w", ignorePath, err)
+		}
+	}
+
+	for _, sub := range subdirs {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o700); err != nil {
+			return fmt.Errorf("failed to create state directory: %w", err)
+		}
+	}
+	return nil
+}
+
+// writeJSON saves v as the file name of the state directory dir, which may be in one of its
+// subdirectories, replacing the file atomically so concurrent feller processes never read a
+// partial one. what names the content in errors.
+func writeJSON(dir, name, what string, v any) error {
+	path := filepath.Join(dir, name)
+	if err := Ensure(dir); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", what, err)
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", what, err)
+	}
+	defer os.Remove(f.Name())
+	_, err = f.Write(append(data, '\n'))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", what, err)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDir(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name       string
+		configPath string
+		expected   string
+	}{
+		{name: "relative", configPath: ".teller.yml", expected: ".feller"},
+		{name: "nested", configPath: filepath.Join("app", "teller.yml"), expected: filepath.Join("app", ".feller")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if result := Dir(tt.configPath); result != tt.expected {
+				t.Errorf("Dir() = %q, want %q", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestEnsure(t *testing.T) {
+	t.Parallel()
+	dir := filepath.Join(t.TempDir(), DirName)
+
+	if err := Ensure(dir, CacheDir, AuditDir); err != nil {
+		t.Fatalf("Ensure() unexpected error = %v", err)
+	}
+	for _, sub := range []string{CacheDir, AuditDir} {
+		if info, err := os.Stat(filepath.Join(dir, sub)); err != nil || !info.IsDir() {
+			t.Errorf("Ensure() did not create %s: %v", sub, err)
+		}
+	}
+
+	ignorePath := filepath.Join(dir, ".gitignore")
+	data, err := os.ReadFile(ignorePath)
+	if err != nil || string(data) != gitignore {
+		t.Fatalf("Ensure() .gitignore = %q, %v", data, err)
+	}
+
+	// Edits to the .gitignore are kept
+	if err := os.WriteFile(ignorePath, []byte("custom\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write .gitignore: %v", err)
+	}
+	if err := Ensure(dir); err != nil {
+		t.Fatalf("Ensure() unexpected error = %v", err)
+	}
+	if data, _ := os.ReadFile(ignorePath); string(data) != "custom\n" {
+		t.Errorf("Ensure() overwrote .gitignore: %q", data)
+	}
+}
@@ -0,0 +1,120 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// syncRunTimeFormat names sync runs by their start time, so ids sort by age
+const syncRunTimeFormat = "20060102T150405Z"
+
+// SyncRun records the secrets a feller sync wrote to its targets and their state before, so
+// the run can be rolled back. Values are only kept encrypted, in Values.
+type SyncRun struct {
+	ID      string       `json:"id"`
+	Created time.Time    `json:"created"`
+	Changes []SyncChange `json:"changes"`
+	Values  string       `json:"values,omitempty"` // age encrypted values before and after the run, when backups are enabled
+}
+
+// SyncChange is a secret a sync run wrote to a target
+type SyncChange struct {
+	Target   string `json:"target"` // Name of the target in the config
+	Scope    string `json:"scope"`
+	Key      string `json:"key"`
+	Existed  bool   `json:"existed"`
+	Previous string `json:"previous,omitempty"` // Upload digest of the value before the run, when known
+	Written  string `json:"written,omitempty"`  // Upload digest of the value the run wrote
+}
+
+// NewSyncRun starts the record of a sync run at started, named by its time. A run started in
+// the same second as a recorded one is named by the next free second.
+func NewSyncRun(dir string, started time.Time) *SyncRun {
+	started = started.UTC().Truncate(time.Second)
+	for {
+		if _, err := os.Stat(syncRunPath(dir, started.Format(syncRunTimeFormat))); errors.Is(err, os.ErrNotExist) {
+			break
+		}
+		started = started.Add(time.Second)
+	}
+	return &SyncRun{ID: started.Format(syncRunTimeFormat), Created: started}
+}
+
+// syncRunPath returns the record of run id in the state directory dir
+func syncRunPath(dir, id string) string {
+	return filepath.Join(dir, SyncDir, id+".json")
+}
+
+// SaveSyncRun writes run to the state directory dir, replacing an earlier save of the same
+// run, and removes the oldest runs beyond keep
+func SaveSyncRun(dir string, run *SyncRun, keep int) error {
+	if err := Ensure(dir, SyncDir); err != nil {
+		return err
+	}
+	if err := writeJSON(dir, filepath.Join(SyncDir, run.ID+".json"), "sync run", run); err != nil {
+		return err
+	}
+
+	ids, err := SyncRuns(dir)
+	if err != nil {
+		return err
+	}
+	for len(ids) > keep {
+		if err := RemoveSyncRun(dir, ids[0]); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove old sync run: %w", err)
+		}
+		ids = ids[1:]
+	}
+	return nil
+}
+
+// SyncRuns returns the ids of the sync runs recorded in the state directory dir, oldest first
+func SyncRuns(dir string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(dir, SyncDir))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sync runs: %w", err)
+	}
+	var ids []string
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".json")
+		if _, err := time.Parse(syncRunTimeFormat, id); ok && err == nil && entry.Type().IsRegular() {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// LoadSyncRun reads the sync run id from the state directory dir
+func LoadSyncRun(dir, id string) (*SyncRun, error) {
+	if _, err := time.Parse(syncRunTimeFormat, id); err != nil {
+		return nil, fmt.Errorf("invalid sync run %q, run ids look like 20260102T150405Z", id)
+	}
+	// #nosec G304 - The path is inside the state directory and id is a timestamp
+	data, err := os.ReadFile(syncRunPath(dir, id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("no sync run %s was recorded", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sync run %s: %w", id, err)
+	}
+	var run SyncRun
+	if err := json.Unmarshal(data, &run); err != nil {
+		return nil, fmt.Errorf("failed to parse sync run %s: %w", id, err)
+	}
+	return &run, nil
+}
+
+// RemoveSyncRun removes the record of the sync run id from the state directory dir
+func RemoveSyncRun(dir, id string) error {
+	return os.Remove(syncRunPath(dir, id)) //nolint:wrapcheck // callers add context
+}
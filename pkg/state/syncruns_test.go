@@ -0,0 +1,56 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSyncRuns(t *testing.T) {
+	t.Parallel()
+	dir := filepath.Join(t.TempDir(), DirName)
+	if ids, err := SyncRuns(dir); err != nil || len(ids) != 0 {
+		t.Fatalf("SyncRuns(missing) = %v, %v, expected none", ids, err)
+	}
+
+	// Runs started in the same second get the next free one
+	start := time.Date(2026, 10, 16, 12, 0, 0, 500, time.UTC)
+	var started []string
+	for i := range 4 {
+		run := NewSyncRun(dir, start)
+		run.Changes = []SyncChange{{Target: "ci", Scope: "repository", Key: "K", Written: string(rune('a' + i))}}
+		if err := SaveSyncRun(dir, run, 3); err != nil {
+			t.Fatalf("SaveSyncRun() error = %v", err)
+		}
+		started = append(started, run.ID)
+	}
+	if started[0] != "20261016T120000Z" || started[3] != "20261016T120003Z" {
+		t.Errorf("NewSyncRun() ids = %v", started)
+	}
+
+	ids, err := SyncRuns(dir)
+	if err != nil || len(ids) != 3 || ids[0] != started[1] || ids[2] != started[3] {
+		t.Fatalf("SyncRuns() = %v, %v, expected the newest 3 of %v", ids, err, started)
+	}
+	run, err := LoadSyncRun(dir, ids[2])
+	if err != nil || run.ID != ids[2] || len(run.Changes) != 1 || run.Changes[0].Written != "d" {
+		t.Errorf("LoadSyncRun() = %+v, %v", run, err)
+	}
+	if info, err := os.Stat(syncRunPath(dir, ids[2])); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("sync run mode = %v, %v, expected 0600", info.Mode().Perm(), err)
+	}
+
+	if _, err := LoadSyncRun(dir, started[0]); err == nil || err.Error() != "no sync run 20261016T120000Z was recorded" {
+		t.Errorf("LoadSyncRun(removed) error = %v", err)
+	}
+	if _, err := LoadSyncRun(dir, "../uploads"); err == nil {
+		t.Error("LoadSyncRun() accepted a path")
+	}
+	if err := RemoveSyncRun(dir, ids[2]); err != nil {
+		t.Errorf("RemoveSyncRun() error = %v", err)
+	}
+	if ids, _ := SyncRuns(dir); len(ids) != 2 {
+		t.Errorf("SyncRuns() after removal = %v", ids)
+	}
+}
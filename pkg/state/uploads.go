@@ -0,0 +1,138 @@
+package state
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// UploadsFile is the file in the state directory recording the values last uploaded to
+// targets that never return them, such as GitHub secrets
+const UploadsFile = "uploads.json"
+
+// Digest parameters. The salt is the HMAC key and per state directory; the target and key
+// are mixed in, so equal values have different digests everywhere.
+const (
+	digestPrefix     = "hmac-sha256:"
+	uploadSaltLength = 32
+)
+
+// Uploads records salted HMACs of the values last uploaded, so unchanged values can be
+// skipped. A nil *Uploads records nothing and reports every value as changed.
+type Uploads struct {
+	Salt    string                       `json:"salt"`
+	Targets map[string]map[string]string `json:"targets"` // Digests by target, e.g. "github:owner/repo (repository)", and key
+
+	mu sync.Mutex // Targets are uploaded to concurrently
+}
+
+// LoadUploads reads the uploads recorded in the state directory dir. A missing file records
+// none and gets a fresh salt.
+func LoadUploads(dir string) (*Uploads, error) {
+	u := &Uploads{}
+	// #nosec G304 - The path is inside the state directory
+	data, err := os.ReadFile(filepath.Join(dir, UploadsFile))
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		salt := make([]byte, uploadSaltLength)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, fmt.Errorf("failed to generate salt: %w", err)
+		}
+		u.Salt = base64.StdEncoding.EncodeToString(salt)
+	case err != nil:
+		return nil, fmt.Errorf("failed to read uploads: %w", err)
+	default:
+		if err := json.Unmarshal(data, u); err != nil {
+			return nil, fmt.Errorf("failed to parse uploads: %w", err)
+		}
+		if _, err := base64.StdEncoding.DecodeString(u.Salt); err != nil || u.Salt == "" {
+			return nil, fmt.Errorf("invalid salt in %s, delete the file to upload every secret again", UploadsFile)
+		}
+	}
+	if u.Targets == nil {
+		u.Targets = make(map[string]map[string]string)
+	}
+	return u, nil
+}
+
+// digest returns the HMAC of the value of key uploaded to target
+func (u *Uploads) digest(target, key, value string) string {
+	salt, _ := base64.StdEncoding.DecodeString(u.Salt) // Checked by LoadUploads
+	mac := hmac.New(sha256.New, salt)
+	for _, part := range []string{target, key, value} {
+		mac.Write([]byte(part))
+		mac.Write([]byte{0})
+	}
+	return digestPrefix + base64.RawStdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Unchanged reports whether value is the value last uploaded as key to target
+func (u *Uploads) Unchanged(target, key, value string) bool {
+	if u == nil {
+		return false
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	recorded, ok := u.Targets[target][key]
+	return ok && hmac.Equal([]byte(recorded), []byte(u.digest(target, key, value)))
+}
+
+// Digest returns the digest of value uploaded as key to target, comparable with the digests
+// recorded in the same state directory, or "" when u is nil
+func (u *Uploads) Digest(target, key, value string) string {
+	if u == nil {
+		return ""
+	}
+	return u.digest(target, key, value)
+}
+
+// Recorded returns the digest of the value last uploaded as key to target, or "" when none
+// was recorded
+func (u *Uploads) Recorded(target, key string) string {
+	if u == nil {
+		return ""
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.Targets[target][key]
+}
+
+// Record stores the digest of value, uploaded as key to target
+func (u *Uploads) Record(target, key, value string) {
+	if u == nil {
+		return
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.Targets[target] == nil {
+		u.Targets[target] = make(map[string]string)
+	}
+	u.Targets[target][key] = u.digest(target, key, value)
+}
+
+// Forget removes the digest of key uploaded to target, so its value counts as changed
+func (u *Uploads) Forget(target, key string) {
+	if u == nil {
+		return
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	delete(u.Targets[target], key)
+}
+
+// Write saves the uploads in the state directory dir
+func (u *Uploads) Write(dir string) error {
+	if u == nil {
+		return nil
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return writeJSON(dir, UploadsFile, "uploads", u)
+}
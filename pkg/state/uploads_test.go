@@ -0,0 +1,80 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUploads(t *testing.T) {
+	t.Parallel()
+	dir := filepath.Join(t.TempDir(), DirName)
+	u, err := LoadUploads(dir)
+	if err != nil || u.Salt == "" || len(u.Targets) != 0 {
+		t.Fatalf("LoadUploads(missing) = %+v, %v, expected a salt and no uploads", u, err)
+	}
+
+	repo := "github:owner/repo (repository)"
+	if u.Unchanged(repo, "API_KEY", "abc") {
+		t.Error("Unchanged() of a value never uploaded = true")
+	}
+	u.Record(repo, "API_KEY", "abc")
+	if err := u.Write(dir); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	loaded, err := LoadUploads(dir)
+	if err != nil {
+		t.Fatalf("LoadUploads() error = %v", err)
+	}
+	tests := []struct {
+		target, key, value string
+		expected           bool
+	}{
+		{repo, "API_KEY", "abc", true},
+		{repo, "API_KEY", "abcd", false},
+		{repo, "OTHER", "abc", false},
+		{"github:owner/repo (Dependabot)", "API_KEY", "abc", false},
+	}
+	for _, tt := range tests {
+		if result := loaded.Unchanged(tt.target, tt.key, tt.value); result != tt.expected {
+			t.Errorf("Unchanged(%s, %s, %s) = %v, expected %v", tt.target, tt.key, tt.value, result, tt.expected)
+		}
+	}
+
+	if recorded := loaded.Recorded(repo, "API_KEY"); recorded == "" || recorded != loaded.Digest(repo, "API_KEY", "abc") {
+		t.Errorf("Recorded() = %q, expected the digest of the uploaded value", recorded)
+	}
+
+	loaded.Forget(repo, "API_KEY")
+	if loaded.Unchanged(repo, "API_KEY", "abc") {
+		t.Error("Unchanged() of a forgotten upload = true")
+	}
+
+	// Values never appear in the file
+	data, err := os.ReadFile(filepath.Join(dir, UploadsFile))
+	if err != nil || strings.Contains(string(data), `"abc"`) || !strings.Contains(string(data), digestPrefix) {
+		t.Errorf("uploads file = %s, %v", data, err)
+	}
+
+	// Another salt gives other digests
+	other, _ := LoadUploads(t.TempDir())
+	other.Targets = map[string]map[string]string{repo: {"API_KEY": u.Targets[repo]["API_KEY"]}}
+	if other.Unchanged(repo, "API_KEY", "abc") {
+		t.Error("Unchanged() with another salt = true")
+	}
+
+	var none *Uploads
+	none.Record(repo, "API_KEY", "abc")
+	if none.Unchanged(repo, "API_KEY", "abc") || none.Write(dir) != nil || none.Recorded(repo, "API_KEY") != "" || none.Digest(repo, "API_KEY", "abc") != "" {
+		t.Error("nil Uploads recorded an upload")
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, UploadsFile), []byte(`{"salt":""}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadUploads(dir); err == nil {
+		t.Error("LoadUploads() without a salt succeeded")
+	}
+}
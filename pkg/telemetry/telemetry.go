@@ -0,0 +1,317 @@
+// Package telemetry records strictly opt-in, anonymous usage events: the command run, the
+// provider kinds it used, its duration and outcome. Events never hold key names, values,
+// paths or provider names.
+package telemetry
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// DirEnv overrides the directory of the settings and spooled events
+	DirEnv = "FELLER_TELEMETRY_DIR"
+	// DisableEnv set to a false value, or DO_NOT_TRACK set to a true one, disables
+	// telemetry regardless of the settings
+	DisableEnv = "FELLER_TELEMETRY"
+
+	// SettingsFile holds the opt-in and the anonymous installation id
+	SettingsFile = "telemetry.yml"
+	// EventsFile spools the events not yet uploaded, one JSON object per line
+	EventsFile = "telemetry.jsonl"
+
+	// MaxEvents is the number of spooled events kept; older ones are dropped
+	MaxEvents = 1000
+	// UploadBatch is the number of spooled events that triggers an upload
+	UploadBatch = 20
+
+	// DefaultEndpoint is the URL the events are posted to unless the settings name another
+	DefaultEndpoint = "https://telemetry.containifyci.io/feller/v1/events"
+
+	uploadTimeout = 2 * time.Second
+)
+
+var (
+	// mu serializes the changes to the spooled events within the process; other processes
+	// only append to them or move them aside, which the file system keeps atomic
+	mu sync.Mutex
+
+	// httpClient posts the events, replaced in tests
+	httpClient = http.DefaultClient
+)
+
+// Settings is the content of the settings file
+type Settings struct {
+	Enabled  bool   `yaml:"enabled"`
+	ID       string `yaml:"id,omitempty"`       // Random installation id, not derived from the machine or user
+	Endpoint string `yaml:"endpoint,omitempty"` // HTTPS URL the spooled events are posted to; none keeps them local
+}
+
+// Event is a single command invocation
+type Event struct {
+	Time     time.Time `json:"time"`
+	ID       string    `json:"id"`
+	Command  string    `json:"command"`
+	Kinds    []string  `json:"kinds,omitempty"` // Provider kinds of the config, never provider names
+	Duration int64     `json:"duration_ms"`
+	Success  bool      `json:"success"`
+	OS       string    `json:"os"`
+	Arch     string    `json:"arch"`
+}
+
+// DefaultDir returns DirEnv when set, otherwise feller in the user's config directory
+func DefaultDir() (string, error) {
+	if dir := os.Getenv(DirEnv); dir != "" {
+		return dir, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate the user config directory: %w", err)
+	}
+	return filepath.Join(dir, "feller"), nil
+}
+
+// DisabledByEnv reports whether the environment disables telemetry: DO_NOT_TRACK set to a
+// true value or FELLER_TELEMETRY set to a false one
+func DisabledByEnv() bool {
+	switch strings.ToLower(os.Getenv("DO_NOT_TRACK")) {
+	case "1", "true", "yes":
+		return true
+	}
+	switch strings.ToLower(os.Getenv(DisableEnv)) {
+	case "0", "false", "no", "off":
+		return true
+	}
+	return false
+}
+
+// LoadSettings reads the settings in dir. Missing settings are disabled.
+func LoadSettings(dir string) (*Settings, error) {
+	s := &Settings{}
+	// #nosec G304 - The settings live in the user's own config directory
+	data, err := os.ReadFile(filepath.Join(dir, SettingsFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read telemetry settings: %w", err)
+	}
+	if err := yaml.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("failed to parse telemetry settings: %w", err)
+	}
+	return s, nil
+}
+
+// Write saves the settings in dir, creating it if needed
+func (s *Settings) Write(dir string) error {
+	data, err := yaml.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("failed to encode telemetry settings: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, SettingsFile), data, 0o600); err != nil {
+		return fmt.Errorf("failed to write telemetry settings: %w", err)
+	}
+	return nil
+}
+
+// Enable opts in, creating the installation id on first use
+func (s *Settings) Enable() error {
+	s.Enabled = true
+	if s.ID != "" {
+		return nil
+	}
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return fmt.Errorf("failed to create the installation id: %w", err)
+	}
+	s.ID = hex.EncodeToString(id)
+	return nil
+}
+
+// CheckEndpoint returns an error unless endpoint is an absolute https URL, so events never
+// travel in clear text
+func CheckEndpoint(endpoint string) error {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return fmt.Errorf("invalid telemetry endpoint: %w", err)
+	}
+	if u.Scheme != "https" || u.Host == "" {
+		return fmt.Errorf("telemetry endpoint %s must be an https URL", endpoint)
+	}
+	return nil
+}
+
+// Record appends event to the spooled events in dir, keeping the newest MaxEvents, and
+// returns the number of spooled events
+func Record(dir string, event Event) (int, error) {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode telemetry event: %w", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	return appendEvents(dir, [][]byte{line})
+}
+
+// Pending returns the spooled events in dir as JSON lines
+func Pending(dir string) ([][]byte, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	return readEvents(filepath.Join(dir, EventsFile))
+}
+
+// Clear removes the spooled events in dir
+func Clear(dir string) error {
+	mu.Lock()
+	defer mu.Unlock()
+	if err := os.Remove(filepath.Join(dir, EventsFile)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove telemetry events: %w", err)
+	}
+	return nil
+}
+
+// Upload posts the spooled events in dir to endpoint as a JSON array. The events are moved
+// aside first, so events recorded during the upload are kept for the next one, and put back
+// when the upload fails.
+func Upload(ctx context.Context, dir, endpoint string) error {
+	if err := CheckEndpoint(endpoint); err != nil {
+		return err
+	}
+	claimed := filepath.Join(dir, fmt.Sprintf("%s.%d", EventsFile, os.Getpid()))
+	events, err := claimEvents(dir, claimed)
+	if err != nil || len(events) == 0 {
+		return err
+	}
+	if err := post(ctx, endpoint, events); err != nil {
+		mu.Lock()
+		defer mu.Unlock()
+		if _, restoreErr := appendEvents(dir, events); restoreErr != nil {
+			return errors.Join(err, restoreErr)
+		}
+		_ = os.Remove(claimed)
+		return err
+	}
+	if err := os.Remove(claimed); err != nil {
+		return fmt.Errorf("failed to remove uploaded telemetry events: %w", err)
+	}
+	return nil
+}
+
+// post sends events to endpoint as a JSON array
+func post(ctx context.Context, endpoint string, events [][]byte) error {
+	body := append([]byte("["), bytes.Join(events, []byte(","))...)
+	body = append(body, ']')
+
+	ctx, cancel := context.WithTimeout(ctx, uploadTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create telemetry request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("telemetry upload failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("telemetry endpoint returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// claimEvents moves the spooled events in dir to claimed and returns them
+func claimEvents(dir, claimed string) ([][]byte, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	if err := os.Rename(filepath.Join(dir, EventsFile), claimed); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to claim telemetry events: %w", err)
+	}
+	return readEvents(claimed)
+}
+
+// appendEvents appends events to the spooled events in dir in a single write, so concurrent
+// feller processes do not overwrite each other, and drops all but the newest MaxEvents. It
+// returns the number of spooled events.
+func appendEvents(dir string, events [][]byte) (int, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return 0, fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	path := filepath.Join(dir, EventsFile)
+	// #nosec G304 - The events live in the user's own config directory
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return 0, fmt.Errorf("failed to write telemetry events: %w", err)
+	}
+	_, err = f.Write(append(bytes.Join(events, []byte("\n")), '\n'))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to write telemetry events: %w", err)
+	}
+
+	spooled, err := readEvents(path)
+	if err != nil || len(spooled) <= MaxEvents {
+		return len(spooled), err
+	}
+	if err := writeEvents(dir, spooled[len(spooled)-MaxEvents:]); err != nil {
+		return 0, err
+	}
+	return MaxEvents, nil
+}
+
+// readEvents returns the events in path as JSON lines
+func readEvents(path string) ([][]byte, error) {
+	// #nosec G304 - The events live in the user's own config directory
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read telemetry events: %w", err)
+	}
+	defer f.Close()
+
+	var events [][]byte
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if line := bytes.TrimSpace(scanner.Bytes()); len(line) > 0 {
+			events = append(events, append([]byte(nil), line...))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read telemetry events: %w", err)
+	}
+	return events, nil
+}
+
+// writeEvents replaces the spooled events in dir
+func writeEvents(dir string, events [][]byte) error {
+	data := append(bytes.Join(events, []byte("\n")), '\n')
+	if err := os.WriteFile(filepath.Join(dir, EventsFile), data, 0o600); err != nil {
+		return fmt.Errorf("failed to write telemetry events: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSettings(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+
+	settings, err := LoadSettings(dir)
+	if err != nil {
+		t.Fatalf("LoadSettings() error = %v", err)
+	}
+	if settings.Enabled || settings.ID != "" {
+		t.Errorf("LoadSettings() without a file = %+v, want disabled", settings)
+	}
+
+	if err := settings.Enable(); err != nil {
+		t.Fatalf("Enable() error = %v", err)
+	}
+	id := settings.ID
+	if len(id) != 32 {
+		t.Errorf("Enable() id = %q, want 32 hex digits", id)
+	}
+	if err := settings.Write(dir); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	loaded, err := LoadSettings(dir)
+	if err != nil {
+		t.Fatalf("LoadSettings() error = %v", err)
+	}
+	if !loaded.Enabled || loaded.ID != id {
+		t.Errorf("LoadSettings() = %+v, want enabled with id %s", loaded, id)
+	}
+	// Turning telemetry on again keeps the installation id
+	if err := loaded.Enable(); err != nil || loaded.ID != id {
+		t.Errorf("Enable() again = %s, %v, want id %s", loaded.ID, err, id)
+	}
+}
+
+//nolint:paralleltest // Sets environment variables
+func TestDisabledByEnv(t *testing.T) {
+	tests := []struct {
+		doNotTrack string
+		telemetry  string
+		expected   bool
+	}{
+		{expected: false},
+		{doNotTrack: "1", expected: true},
+		{doNotTrack: "0", expected: false},
+		{telemetry: "off", expected: true},
+		{telemetry: "1", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%q/%q", tt.doNotTrack, tt.telemetry), func(t *testing.T) {
+			t.Setenv("DO_NOT_TRACK", tt.doNotTrack)
+			t.Setenv(DisableEnv, tt.telemetry)
+			if got := DisabledByEnv(); got != tt.expected {
+				t.Errorf("DisabledByEnv() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRecord(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+
+	for i := range MaxEvents + 5 {
+		n, err := Record(dir, Event{Command: fmt.Sprintf("cmd%d", i)})
+		if err != nil {
+			t.Fatalf("Record() error = %v", err)
+		}
+		if want := min(i+1, MaxEvents); n != want {
+			t.Fatalf("Record() = %d, want %d", n, want)
+		}
+	}
+
+	events, err := Pending(dir)
+	if err != nil {
+		t.Fatalf("Pending() error = %v", err)
+	}
+	var first Event
+	if err := json.Unmarshal(events[0], &first); err != nil {
+		t.Fatal(err)
+	}
+	if first.Command != "cmd5" {
+		t.Errorf("oldest kept event = %s, want cmd5", first.Command)
+	}
+
+	if err := Clear(dir); err != nil {
+		t.Fatalf("Clear() error = %v", err)
+	}
+	if events, _ := Pending(dir); len(events) != 0 {
+		t.Errorf("Pending() after Clear() = %d events, want 0", len(events))
+	}
+}
+
+//nolint:paralleltest // Replaces the package HTTP client
+func TestUpload(t *testing.T) {
+	dir := t.TempDir()
+	var received []Event
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &received); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+		// Recorded while the upload runs, so kept for the next one
+		if _, err := Record(dir, Event{Command: "get"}); err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+	client := httpClient
+	httpClient = server.Client()
+	t.Cleanup(func() { httpClient = client })
+
+	for _, command := range []string{"show", "export"} {
+		if _, err := Record(dir, Event{Time: time.Unix(0, 0).UTC(), Command: command, Kinds: []string{"dotenv"}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := Upload(context.Background(), dir, server.URL); err != nil {
+		t.Fatalf("Upload() error = %v", err)
+	}
+	if len(received) != 2 || received[0].Command != "show" || received[1].Kinds[0] != "dotenv" {
+		t.Errorf("Upload() sent %+v", received)
+	}
+	if events, _ := Pending(dir); len(events) != 1 {
+		t.Errorf("Upload() kept %d events, want the 1 recorded during the upload", len(events))
+	}
+
+	// Failed uploads keep the events for the next attempt
+	if err := Upload(context.Background(), dir, server.URL+"/%zz"); err == nil {
+		t.Errorf("Upload() to an invalid URL should fail")
+	}
+	if events, _ := Pending(dir); len(events) != 1 {
+		t.Errorf("failed Upload() kept %d events, want 1", len(events))
+	}
+
+	// Events never travel in clear text
+	err := Upload(context.Background(), dir, "http://"+server.Listener.Addr().String())
+	if err == nil || !strings.Contains(err.Error(), "must be an https URL") {
+		t.Errorf("Upload() over http error = %v, want an https error", err)
+	}
+	if matches, _ := filepath.Glob(filepath.Join(dir, EventsFile+".*")); len(matches) != 0 {
+		t.Errorf("Upload() left claimed events behind: %v", matches)
+	}
+}
+
+func TestCheckEndpoint(t *testing.T) {
+	t.Parallel()
+	for endpoint, valid := range map[string]bool{
+		DefaultEndpoint:                        true,
+		"https://telemetry.example.com/feller": true,
+		"http://telemetry.example.com/feller":  false,
+		"telemetry.example.com/feller":         false,
+		"https:///feller":                      false,
+		"":                                     false,
+	} {
+		if err := CheckEndpoint(endpoint); (err == nil) != valid {
+			t.Errorf("CheckEndpoint(%q) error = %v, want valid %v", endpoint, err, valid)
+		}
+	}
+}
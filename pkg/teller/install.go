@@ -0,0 +1,367 @@
+// Package teller downloads and caches pinned teller releases for the fallback mode, so
+// teller does not have to be installed beforehand.
+package teller
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/containifyci/feller/pkg/logger"
+)
+
+// MirrorEnv names an https base URL to download releases from instead of GitHub, e.g. an
+// internal mirror
+const MirrorEnv = "FELLER_TELLER_MIRROR"
+
+// releaseURL is the base URL of teller releases; assets are at <base>/<version>/<asset>
+const releaseURL = "https://github.com/tellerops/teller/releases/download"
+
+// downloadTimeout bounds a release download
+const downloadTimeout = 5 * time.Minute
+
+var versionPattern = regexp.MustCompile(`^v?(\d+)\.\d+\.\d+$`)
+
+// httpClient downloads releases, replaced in tests
+var httpClient = http.DefaultClient
+
+// NormalizeVersion validates a release version and returns it with a leading v
+func NormalizeVersion(version string) (string, error) {
+	if !versionPattern.MatchString(version) {
+		return "", fmt.Errorf("invalid teller version %q (expected e.g. v2.0.7)", version)
+	}
+	return "v" + strings.TrimPrefix(version, "v"), nil
+}
+
+// BinaryName returns the file name of the teller binary on goos
+func BinaryName(goos string) string {
+	if goos == "windows" {
+		return "teller.exe"
+	}
+	return "teller"
+}
+
+// AssetName returns the release asset of version for the platform. Teller 1 was released
+// with goreleaser (teller_1.5.6_Linux_x86_64.tar.gz), teller 2 with cargo-dist
+// (teller-x86_64-linux.tar.xz).
+func AssetName(version, goos, goarch string) (string, error) {
+	version, err := NormalizeVersion(version)
+	if err != nil {
+		return "", err
+	}
+
+	arch, ok := map[string]string{"amd64": "x86_64", "arm64": "aarch64"}[goarch]
+	if !ok {
+		return "", fmt.Errorf("teller releases are not available for %s/%s", goos, goarch)
+	}
+
+	if strings.HasPrefix(version, "v1.") {
+		system, ok := map[string]string{"linux": "Linux", "darwin": "Darwin", "windows": "Windows"}[goos]
+		if !ok {
+			return "", fmt.Errorf("teller releases are not available for %s/%s", goos, goarch)
+		}
+		if goarch == "arm64" {
+			arch = "arm64"
+		}
+		ext := ".tar.gz"
+		if goos == "windows" {
+			ext = ".zip"
+		}
+		return fmt.Sprintf("teller_%s_%s_%s%s", strings.TrimPrefix(version, "v"), system, arch, ext), nil
+	}
+
+	system, ok := map[string]string{"linux": "linux", "darwin": "macos", "windows": "windows"}[goos]
+	if !ok {
+		return "", fmt.Errorf("teller releases are not available for %s/%s", goos, goarch)
+	}
+	ext := ".tar.xz"
+	if goos == "windows" {
+		ext = ".zip"
+	}
+	return fmt.Sprintf("teller-%s-%s%s", arch, system, ext), nil
+}
+
+// ChecksumsName returns the release asset of version that holds the sha256 checksum of
+// asset. Teller 1 publishes one goreleaser checksums.txt per release, teller 2 a cargo-dist
+// <asset>.sha256 file per asset.
+func ChecksumsName(version, asset string) string {
+	if strings.HasPrefix(version, "v1.") {
+		return "checksums.txt"
+	}
+	return asset + ".sha256"
+}
+
+// CachedPath returns where Install puts version below cacheDir
+func CachedPath(cacheDir, version string) string {
+	return filepath.Join(cacheDir, version, BinaryName(runtime.GOOS))
+}
+
+// Install returns the cached teller binary of version, downloading it into cacheDir first
+// when it is not cached yet
+func Install(ctx context.Context, cacheDir, version string) (string, error) {
+	version, err := NormalizeVersion(version)
+	if err != nil {
+		return "", err
+	}
+	target := CachedPath(cacheDir, version)
+	if info, err := os.Stat(target); err == nil && info.Mode().IsRegular() {
+		logger.Debug("Using cached teller %s at %s", version, target)
+		return target, nil
+	}
+
+	asset, err := AssetName(version, runtime.GOOS, runtime.GOARCH)
+	if err != nil {
+		return "", err
+	}
+	base := os.Getenv(MirrorEnv)
+	if base == "" {
+		base = releaseURL
+	}
+	// The checksums come from the same place, so only TLS keeps both from being replaced
+	if !strings.HasPrefix(base, "https://") {
+		return "", fmt.Errorf("%s must be an https URL, got %q", MirrorEnv, base)
+	}
+	release := fmt.Sprintf("%s/%s", strings.TrimSuffix(base, "/"), version)
+	url := release + "/" + asset
+
+	if err := os.MkdirAll(filepath.Dir(target), 0o700); err != nil {
+		return "", fmt.Errorf("failed to create teller cache: %w", err)
+	}
+	// Work in a temporary directory next to the target, so concurrent installs never see a
+	// partial binary and the final rename stays on one file system
+	work, err := os.MkdirTemp(filepath.Dir(target), ".download-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create teller cache: %w", err)
+	}
+	defer os.RemoveAll(work)
+
+	logger.Info("Downloading teller %s from %s", version, url)
+	archive := filepath.Join(work, asset)
+	if err := download(ctx, url, archive); err != nil {
+		return "", err
+	}
+	checksums := filepath.Join(work, "checksums")
+	if err := download(ctx, release+"/"+ChecksumsName(version, asset), checksums); err != nil {
+		return "", err
+	}
+	if err := verifyChecksum(archive, checksums, asset); err != nil {
+		return "", err
+	}
+	binary, err := extract(ctx, archive, work)
+	if err != nil {
+		return "", fmt.Errorf("failed to extract %s: %w", asset, err)
+	}
+	if err := os.Chmod(binary, 0o700); err != nil {
+		return "", fmt.Errorf("failed to make teller executable: %w", err)
+	}
+	if err := os.Rename(binary, target); err != nil {
+		return "", fmt.Errorf("failed to cache teller: %w", err)
+	}
+	logger.Verbose("Cached teller %s at %s", version, target)
+	return target, nil
+}
+
+// download writes the body of url to path
+func download(ctx context.Context, url, path string) error {
+	ctx, cancel := context.WithTimeout(ctx, downloadTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to download teller: %w", err)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to download teller: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to download teller from %s: %s", url, resp.Status)
+	}
+
+	// #nosec G304 - Path is inside a directory created by Install
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to download teller: %w", err)
+	}
+	if _, err := io.Copy(f, resp.Body); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to download teller: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to download teller: %w", err)
+	}
+	return nil
+}
+
+// verifyChecksum fails unless the sha256 of archive is the one checksums lists for asset.
+// Checksum files hold "<sha256>  <asset>" lines; cargo-dist marks binary mode with a *.
+func verifyChecksum(archive, checksums, asset string) error {
+	// #nosec G304 - Path is inside a directory created by Install
+	list, err := os.Open(checksums)
+	if err != nil {
+		return fmt.Errorf("failed to read teller checksums: %w", err)
+	}
+	defer list.Close()
+	var expected string
+	scanner := bufio.NewScanner(list)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == asset {
+			expected = strings.ToLower(fields[0])
+			break
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read teller checksums: %w", err)
+	}
+	if expected == "" {
+		return fmt.Errorf("the teller release publishes no checksum of %s", asset)
+	}
+
+	// #nosec G304 - Archive was downloaded by Install
+	f, err := os.Open(archive)
+	if err != nil {
+		return fmt.Errorf("failed to verify %s: %w", asset, err)
+	}
+	defer f.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return fmt.Errorf("failed to verify %s: %w", asset, err)
+	}
+	actual := hex.EncodeToString(hash.Sum(nil))
+	if subtle.ConstantTimeCompare([]byte(actual), []byte(expected)) != 1 {
+		return fmt.Errorf("checksum of %s does not match the release: got sha256 %s, expected %s", asset, actual, expected)
+	}
+	logger.Debug("Verified sha256 %s of %s", actual, asset)
+	return nil
+}
+
+// extract unpacks the teller binary of archive into dir and returns its path
+func extract(ctx context.Context, archive, dir string) (string, error) {
+	name := BinaryName(runtime.GOOS)
+	switch {
+	case strings.HasSuffix(archive, ".tar.gz"):
+		return extractTarGz(archive, dir, name)
+	case strings.HasSuffix(archive, ".zip"):
+		return extractZip(archive, dir, name)
+	case strings.HasSuffix(archive, ".tar.xz"):
+		return extractTarXz(ctx, archive, dir, name)
+	default:
+		return "", fmt.Errorf("unsupported archive %s", filepath.Base(archive))
+	}
+}
+
+func extractTarGz(archive, dir, name string) (string, error) {
+	// #nosec G304 - Archive was downloaded by Install
+	f, err := os.Open(archive)
+	if err != nil {
+		return "", err //nolint:wrapcheck // wrapped by Install
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return "", err //nolint:wrapcheck // wrapped by Install
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return "", fmt.Errorf("archive does not contain %s", name)
+		}
+		if err != nil {
+			return "", err //nolint:wrapcheck // wrapped by Install
+		}
+		if header.Typeflag == tar.TypeReg && filepath.Base(header.Name) == name {
+			return writeBinary(tr, dir, name)
+		}
+	}
+}
+
+func extractZip(archive, dir, name string) (string, error) {
+	zr, err := zip.OpenReader(archive)
+	if err != nil {
+		return "", err //nolint:wrapcheck // wrapped by Install
+	}
+	defer zr.Close()
+
+	for _, file := range zr.File {
+		if file.Mode().IsRegular() && filepath.Base(file.Name) == name {
+			rc, err := file.Open()
+			if err != nil {
+				return "", err //nolint:wrapcheck // wrapped by Install
+			}
+			defer rc.Close()
+			return writeBinary(rc, dir, name)
+		}
+	}
+	return "", fmt.Errorf("archive does not contain %s", name)
+}
+
+// extractTarXz unpacks archive with the system tar, since the standard library has no xz support
+func extractTarXz(ctx context.Context, archive, dir, name string) (string, error) {
+	out := filepath.Join(dir, "extracted")
+	if err := os.Mkdir(out, 0o700); err != nil {
+		return "", err //nolint:wrapcheck // wrapped by Install
+	}
+	// #nosec G204 - Arguments are paths created by Install
+	cmd := exec.CommandContext(ctx, "tar", "-xJf", archive, "-C", out)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return "", fmt.Errorf("tar failed: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	var found string
+	err := filepath.WalkDir(out, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if found == "" && d.Type().IsRegular() && d.Name() == name {
+			found = path
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err //nolint:wrapcheck // wrapped by Install
+	}
+	if found == "" {
+		return "", fmt.Errorf("archive does not contain %s", name)
+	}
+	return found, nil
+}
+
+// writeBinary copies the binary from r into dir
+func writeBinary(r io.Reader, dir, name string) (string, error) {
+	path := filepath.Join(dir, name)
+	// #nosec G304 - Path is inside a directory created by Install
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o700)
+	if err != nil {
+		return "", err //nolint:wrapcheck // wrapped by Install
+	}
+	// #nosec G110 - Archive is a release downloaded on request of the user
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return "", err //nolint:wrapcheck // wrapped by Install
+	}
+	if err := f.Close(); err != nil {
+		return "", err //nolint:wrapcheck // wrapped by Install
+	}
+	return path, nil
+}
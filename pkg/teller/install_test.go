@@ -0,0 +1,195 @@
+package teller
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestAssetName(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		version     string
+		goos        string
+		goarch      string
+		expected    string
+		errContains string
+	}{
+		{version: "v2.0.7", goos: "linux", goarch: "amd64", expected: "teller-x86_64-linux.tar.xz"},
+		{version: "2.0.7", goos: "darwin", goarch: "arm64", expected: "teller-aarch64-macos.tar.xz"},
+		{version: "v2.0.7", goos: "windows", goarch: "amd64", expected: "teller-x86_64-windows.zip"},
+		{version: "v1.5.6", goos: "linux", goarch: "amd64", expected: "teller_1.5.6_Linux_x86_64.tar.gz"},
+		{version: "v1.5.6", goos: "darwin", goarch: "arm64", expected: "teller_1.5.6_Darwin_arm64.tar.gz"},
+		{version: "v1.5.6", goos: "windows", goarch: "amd64", expected: "teller_1.5.6_Windows_x86_64.zip"},
+		{version: "v2.x", goos: "linux", goarch: "amd64", errContains: `invalid teller version "v2.x"`},
+		{version: "v2.0.7", goos: "linux", goarch: "386", errContains: "not available for linux/386"},
+		{version: "v2.0.7", goos: "plan9", goarch: "amd64", errContains: "not available for plan9/amd64"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.version+"/"+tt.goos+"/"+tt.goarch, func(t *testing.T) {
+			t.Parallel()
+			got, err := AssetName(tt.version, tt.goos, tt.goarch)
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Errorf("AssetName() error = %v, expected to contain %q", err, tt.errContains)
+				}
+				return
+			}
+			if err != nil || got != tt.expected {
+				t.Errorf("AssetName() = %q, %v, want %q", got, err, tt.expected)
+			}
+		})
+	}
+}
+
+// releaseArchive returns a teller 1 release archive for the current platform holding content
+func releaseArchive(t *testing.T, content string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	name := "teller_1.5.6/" + BinaryName(runtime.GOOS)
+	if runtime.GOOS == "windows" {
+		zw := zip.NewWriter(&buf)
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("Failed to create zip: %v", err)
+		}
+		_, _ = w.Write([]byte(content))
+		if err := zw.Close(); err != nil {
+			t.Fatalf("Failed to create zip: %v", err)
+		}
+		return buf.Bytes()
+	}
+
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	_ = tw.WriteHeader(&tar.Header{Name: "README.md", Mode: 0o644, Size: 2, Typeflag: tar.TypeReg})
+	_, _ = tw.Write([]byte("hi"))
+	_ = tw.WriteHeader(&tar.Header{Name: name, Mode: 0o755, Size: int64(len(content)), Typeflag: tar.TypeReg})
+	_, _ = tw.Write([]byte(content))
+	if err := tw.Close(); err != nil {
+		t.Fatalf("Failed to create tar: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("Failed to create tar: %v", err)
+	}
+	return buf.Bytes()
+}
+
+//nolint:paralleltest // modifies environment variables
+func TestInstall(t *testing.T) {
+	if runtime.GOARCH != "amd64" && runtime.GOARCH != "arm64" {
+		t.Skip("teller releases are only available for amd64 and arm64")
+	}
+	asset, err := AssetName("v1.5.6", runtime.GOOS, runtime.GOARCH)
+	if err != nil {
+		t.Skip(err)
+	}
+	tampered, _ := AssetName("v1.5.8", runtime.GOOS, runtime.GOARCH)
+	archive := releaseArchive(t, "#!/bin/sh\necho teller\n")
+	sum := sha256.Sum256(archive)
+	checksums := hex.EncodeToString(sum[:]) + "  " + asset + "\n"
+
+	requests := 0
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch r.URL.Path {
+		case "/v1.5.6/" + asset, "/v1.5.8/" + tampered:
+			_, _ = w.Write(archive)
+		case "/v1.5.6/checksums.txt":
+			_, _ = w.Write([]byte("0000  other.tar.gz\n" + checksums))
+		case "/v1.5.8/checksums.txt":
+			_, _ = w.Write([]byte(strings.Repeat("0", 64) + "  " + tampered + "\n"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	originalClient := httpClient
+	httpClient = server.Client()
+	t.Cleanup(func() { httpClient = originalClient })
+	t.Setenv(MirrorEnv, server.URL+"/")
+
+	cacheDir := t.TempDir()
+	path, err := Install(context.Background(), cacheDir, "1.5.6")
+	if err != nil {
+		t.Fatalf("Install() unexpected error = %v", err)
+	}
+	if path != CachedPath(cacheDir, "v1.5.6") {
+		t.Errorf("Install() = %s, want %s", path, CachedPath(cacheDir, "v1.5.6"))
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "#!/bin/sh\necho teller\n" {
+		t.Errorf("Install() wrote %q, %v", data, err)
+	}
+	if info, _ := os.Stat(path); runtime.GOOS != "windows" && info.Mode().Perm()&0o100 == 0 {
+		t.Errorf("Install() binary is not executable: %v", info.Mode())
+	}
+
+	// The second install is served from the cache
+	if _, err := Install(context.Background(), cacheDir, "v1.5.6"); err != nil || requests != 2 {
+		t.Errorf("Install() from cache = %v after %d requests, want 2 requests", err, requests)
+	}
+
+	_, err = Install(context.Background(), cacheDir, "v1.5.8")
+	if err == nil || !strings.Contains(err.Error(), "checksum of "+tampered+" does not match the release") {
+		t.Errorf("Install() of a tampered release error = %v", err)
+	}
+	if _, statErr := os.Stat(CachedPath(cacheDir, "v1.5.8")); statErr == nil {
+		t.Error("Install() cached a release that failed verification")
+	}
+
+	if _, err := Install(context.Background(), cacheDir, "v1.5.7"); err == nil || !strings.Contains(err.Error(), "404 Not Found") {
+		t.Errorf("Install() of a missing release error = %v, want 404", err)
+	}
+	entries, _ := os.ReadDir(filepath.Join(cacheDir, "v1.5.7"))
+	if len(entries) != 0 {
+		t.Errorf("Install() left %d entries behind after a failed download", len(entries))
+	}
+}
+
+//nolint:paralleltest // modifies environment variables
+func TestInstallRejectsInsecureMirror(t *testing.T) {
+	t.Setenv(MirrorEnv, "http://mirror.example.com/teller")
+	_, err := Install(context.Background(), t.TempDir(), "v2.0.7")
+	if err == nil || err.Error() != `FELLER_TELLER_MIRROR must be an https URL, got "http://mirror.example.com/teller"` {
+		t.Errorf("Install() error = %v", err)
+	}
+}
+
+func TestVerifyChecksum(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	archive, checksums := filepath.Join(dir, "teller-x86_64-linux.tar.xz"), filepath.Join(dir, "sha256")
+	if err := os.WriteFile(archive, []byte("release"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256([]byte("release"))
+	tests := []struct {
+		name, content, errContains string
+	}{
+		{name: "cargo-dist", content: strings.ToUpper(hex.EncodeToString(sum[:])) + " *teller-x86_64-linux.tar.xz\n"},
+		{name: "missing", content: hex.EncodeToString(sum[:]) + "  teller-aarch64-linux.tar.xz\n", errContains: "publishes no checksum of teller-x86_64-linux.tar.xz"},
+		{name: "mismatch", content: strings.Repeat("a", 64) + "  teller-x86_64-linux.tar.xz\n", errContains: "does not match the release"},
+	}
+	for _, tt := range tests {
+		if err := os.WriteFile(checksums, []byte(tt.content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		err := verifyChecksum(archive, checksums, "teller-x86_64-linux.tar.xz")
+		if (tt.errContains == "" && err != nil) || (tt.errContains != "" && (err == nil || !strings.Contains(err.Error(), tt.errContains))) {
+			t.Errorf("%s: verifyChecksum() error = %v, expected %q", tt.name, err, tt.errContains)
+		}
+	}
+}
@@ -0,0 +1,800 @@
+package validate
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/containifyci/feller/pkg/config"
+	"github.com/containifyci/feller/pkg/providers"
+	"gopkg.in/yaml.v3"
+)
+
+// Diagnostic severities
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Diagnostic is a single validation finding at a 1-based line and column of the config
+type Diagnostic struct {
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%d:%d: %s: %s", d.Line, d.Column, d.Severity, d.Message)
+}
+
+var yamlErrorLine = regexp.MustCompile(`line (\d+):`)
+
+// HasErrors reports whether any diagnostic has error severity
+func HasErrors(diagnostics []Diagnostic) bool {
+	for _, d := range diagnostics {
+		if d.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// Options adjust the checks of FileWithOptions and ConfigWithOptions
+type Options struct {
+	// StrictPermissions reports secret files readable by group or others as errors
+	// instead of warnings
+	StrictPermissions bool
+}
+
+// File validates the config file at path
+func File(path string) ([]Diagnostic, error) {
+	return FileWithOptions(path, Options{})
+}
+
+// FileWithOptions validates the config file at path with opts
+func FileWithOptions(path string, opts Options) ([]Diagnostic, error) {
+	// #nosec G304 - Config path is provided by the user
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+	return ConfigWithOptions(data, opts), nil
+}
+
+// Config validates raw config file content and returns diagnostics sorted by position
+func Config(data []byte) []Diagnostic {
+	return ConfigWithOptions(data, Options{})
+}
+
+// ConfigWithOptions validates raw config file content with opts
+func ConfigWithOptions(data []byte, opts Options) []Diagnostic {
+	v := &validator{opts: opts}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		line := 1
+		if match := yamlErrorLine.FindStringSubmatch(err.Error()); match != nil {
+			line, _ = strconv.Atoi(match[1])
+		}
+		v.add(SeverityError, line, 1, "%s", stripLinePrefix(strings.TrimPrefix(err.Error(), "yaml: ")))
+		return v.diagnostics
+	}
+
+	if len(doc.Content) == 0 {
+		v.add(SeverityWarning, 1, 1, "config is empty")
+		return v.diagnostics
+	}
+
+	v.root(doc.Content[0])
+	sort.SliceStable(v.diagnostics, func(i, j int) bool {
+		if v.diagnostics[i].Line != v.diagnostics[j].Line {
+			return v.diagnostics[i].Line < v.diagnostics[j].Line
+		}
+		return v.diagnostics[i].Column < v.diagnostics[j].Column
+	})
+	return v.diagnostics
+}
+
+type validator struct {
+	opts        Options
+	diagnostics []Diagnostic
+	deprecated  map[*yaml.Node]bool // Key nodes of deprecated fields, reported by deprecations
+	teller      bool                // Whether teller may resolve the providers, fallback is not false
+}
+
+func (v *validator) add(severity string, line, column int, format string, args ...any) {
+	v.diagnostics = append(v.diagnostics, Diagnostic{
+		Severity: severity,
+		Message:  fmt.Sprintf(format, args...),
+		Line:     line,
+		Column:   column,
+	})
+}
+
+func (v *validator) addAt(severity string, node *yaml.Node, format string, args ...any) {
+	v.add(severity, node.Line, node.Column, format, args...)
+}
+
+// mapping checks that node is a mapping, reporting unknown fields when known is set,
+// and returns its key and value nodes
+func (v *validator) mapping(node *yaml.Node, what string, known []string) ([]*yaml.Node, []*yaml.Node) {
+	if node.Kind != yaml.MappingNode {
+		v.addAt(SeverityError, node, "%s must be a mapping", what)
+		return nil, nil
+	}
+
+	var keys, values []*yaml.Node
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if known != nil && !contains(known, key.Value) && !v.deprecated[key] {
+			v.addAt(SeverityWarning, key, "unknown field %q in %s (expected one of: %s)", key.Value, what, strings.Join(known, ", "))
+		}
+		keys = append(keys, key)
+		values = append(values, value)
+	}
+	return keys, values
+}
+
+func (v *validator) root(node *yaml.Node) {
+	v.deprecations(node)
+	keys, values := v.mapping(node, "config", config.RootFields)
+	v.teller = true
+	for i, key := range keys {
+		if key.Value == "fallback" && values[i].Value == "false" {
+			v.teller = false
+		}
+	}
+	hasProviders := false
+	for i, key := range keys {
+		switch key.Value {
+		case "version":
+			// Checked by deprecations
+		case "providers":
+			hasProviders = true
+			v.providers(values[i])
+		case "hooks":
+			v.hooks(values[i])
+		case "transforms":
+			v.transforms(values[i])
+		case "schema":
+			v.schema(values[i])
+		case "aliases":
+			v.aliases(values[i])
+		case "messages":
+			v.messages(values[i])
+		case "fallback":
+			if values[i].ShortTag() != "!!bool" {
+				v.addAt(SeverityError, values[i], "fallback must be true or false")
+			}
+		case "targets":
+			v.targets(values[i], providerNames(node))
+		case "sync":
+			v.sync(values[i], targetNames(node))
+		case "backups":
+			v.backups(values[i])
+		case "collisions":
+			if err := providers.CheckCollisionStrategy(values[i].Value); err != nil || values[i].Kind != yaml.ScalarNode {
+				v.addAt(SeverityError, values[i], "collisions must be one of: %s", strings.Join(providers.CollisionStrategies, ", "))
+			}
+		}
+	}
+	if keys != nil && !hasProviders {
+		v.addAt(SeverityWarning, node, "no providers configured")
+	}
+}
+
+// deprecations checks the config version and reports deprecated fields: as warnings
+// suggesting their replacement before the version that removes them, as errors from it on
+func (v *validator) deprecations(node *yaml.Node) {
+	if node.Kind != yaml.MappingNode {
+		return
+	}
+	version := 1
+	var versionNode *yaml.Node
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == "version" {
+			versionNode = node.Content[i+1]
+		}
+	}
+	if versionNode != nil {
+		n, err := strconv.Atoi(versionNode.Value)
+		switch {
+		case versionNode.ShortTag() != "!!int" || err != nil || n < 1:
+			v.addAt(SeverityError, versionNode, "version must be a positive integer")
+			return
+		case n > config.CurrentVersion:
+			v.addAt(SeverityError, versionNode, "config version %d is not supported by this feller (supported: 1 to %d), upgrade feller", n, config.CurrentVersion)
+			return
+		}
+		version = n
+	}
+
+	v.deprecated = make(map[*yaml.Node]bool)
+	for _, f := range config.FindDeprecated(node) {
+		v.deprecated[f.Node] = true
+		if version >= f.Removed {
+			v.addAt(SeverityError, f.Node, "%s was removed in config version %d: %s", f.Field, f.Removed, f.Replacement)
+		} else {
+			v.addAt(SeverityWarning, f.Node, "%s", f.String())
+		}
+	}
+}
+
+func (v *validator) providers(node *yaml.Node) {
+	names, values := v.mapping(node, "providers", nil)
+	for i, name := range names {
+		v.provider(name, values[i])
+	}
+}
+
+// provider validates a single provider, reporting provider-level problems at its name
+func (v *validator) provider(name, node *yaml.Node) {
+	what := fmt.Sprintf("provider %q", name.Value)
+	keys, values := v.mapping(node, what, config.ProviderFields)
+	if keys == nil && node.Kind != yaml.MappingNode {
+		return
+	}
+
+	var kind *providers.KindInfo
+	var kindName string
+	var options *yaml.Node
+	hasKind, hasMaps := false, false
+	for i, key := range keys {
+		switch key.Value {
+		case "options":
+			options = values[i]
+		case "kind":
+			hasKind = true
+			kindName = values[i].Value
+			info, ok := providers.LookupKind(values[i].Value)
+			if !ok {
+				// Other teller kinds still work through the teller fallback, so this is not an error
+				v.addAt(SeverityWarning, values[i], "kind %q of %s is not supported natively and is ignored in GitHub Actions (supported: %s)",
+					values[i].Value, what, strings.Join(kindNames(), ", "))
+				continue
+			}
+			kind = &info
+		case "maps":
+			hasMaps = true
+		}
+	}
+	if !hasKind {
+		v.addAt(SeverityError, name, "%s is missing required field \"kind\"", what)
+	}
+	if options != nil {
+		var err error
+		switch kindName {
+		case providers.KindHashiCorpVault:
+			err = providers.CheckVaultOptions(config.Provider{Kind: kindName, Options: *options})
+		case providers.KindGitHub:
+			err = providers.CheckGitHubOptions(config.Provider{Kind: kindName, Options: *options})
+		}
+		if err == nil {
+			err = providers.CheckEndpoint(config.Provider{Kind: kindName, Options: *options})
+		}
+		// Only feller reaches Secret Manager through an endpoint
+		if err == nil && kindName == providers.KindGoogleSecretManager && v.teller && hasEndpoint(options) {
+			err = errors.New("teller cannot reach google_secretmanager providers through an endpoint, set fallback: false to resolve them with feller only")
+		}
+		if err != nil {
+			v.addAt(SeverityError, options, "invalid options of %s: %v", what, err)
+		}
+	}
+
+	for i, key := range keys {
+		if key.Value == "maps" {
+			v.maps(what, kindName, kind, values[i])
+		}
+	}
+	if !hasMaps {
+		v.addAt(SeverityWarning, name, "%s has no maps and supplies no secrets", what)
+	}
+}
+
+func (v *validator) maps(provider, kindName string, kind *providers.KindInfo, node *yaml.Node) {
+	if node.Kind != yaml.SequenceNode {
+		v.addAt(SeverityError, node, "maps of %s must be a list", provider)
+		return
+	}
+
+	seenIDs := make(map[string]bool)
+	for _, item := range node.Content {
+		keys, values := v.mapping(item, "map of "+provider, config.PathMapFields)
+		if item.Kind != yaml.MappingNode {
+			continue
+		}
+
+		fields := make(map[string]*yaml.Node)
+		for i, key := range keys {
+			fields[key.Value] = values[i]
+		}
+
+		if id, ok := fields["id"]; ok {
+			if seenIDs[id.Value] {
+				v.addAt(SeverityWarning, id, "duplicate map id %q in %s", id.Value, provider)
+			}
+			seenIDs[id.Value] = true
+		}
+		if keysNode, ok := fields["keys"]; ok && keysNode.Kind != yaml.MappingNode {
+			v.addAt(SeverityError, keysNode, "keys of %s must be a mapping of source to output names", provider)
+		}
+		v.discoveryFilter(provider, fields)
+		v.tags(provider, fields)
+		v.version(provider, kindName, fields)
+
+		if kind == nil {
+			continue
+		}
+		for _, required := range kind.RequiredMapFields {
+			// Include globs select the keys instead, as does listing a Secret Manager project
+			if _, ok := fields["include"]; ok && required == "keys" {
+				continue
+			}
+			if path, ok := fields["path"]; ok && required == "keys" && kind.Kind == providers.KindGoogleSecretManager &&
+				providers.ListsGSMProject(config.PathMap{Path: path.Value}) {
+				continue
+			}
+			if _, ok := fields[required]; !ok {
+				v.addAt(SeverityError, item, "map of %s is missing field %q required by kind %s", provider, required, kind.Kind)
+			}
+		}
+		if path, ok := fields["path"]; ok && kind.Kind == providers.KindDotenv && path.Value != "" {
+			v.dotenvFile(path)
+		}
+	}
+}
+
+// dotenvFile checks that the dotenv file at path exists and only its owner can read it
+func (v *validator) dotenvFile(path *yaml.Node) {
+	local := config.LocalPath(path.Value)
+	if _, err := os.Stat(local); errors.Is(err, os.ErrNotExist) {
+		v.addAt(SeverityWarning, path, "dotenv file %s does not exist", path.Value)
+		return
+	}
+	if mode, exposed := providers.ExposedMode(local); exposed {
+		severity := SeverityWarning
+		if v.opts.StrictPermissions {
+			severity = SeverityError
+		}
+		v.addAt(severity, path, "dotenv file %s is readable by group or others (mode %04o), restrict it with chmod 600 %s", path.Value, mode, path.Value)
+	}
+}
+
+// version checks the version and stage of a map for the kind of its provider
+func (v *validator) version(provider, kindName string, fields map[string]*yaml.Node) {
+	var pathMap config.PathMap
+	var at *yaml.Node
+	for _, name := range []string{"stage", "version"} {
+		node, ok := fields[name]
+		if !ok {
+			continue
+		}
+		if node.Kind != yaml.ScalarNode || node.Value == "" {
+			v.addAt(SeverityError, node, "%s of %s must be a non-empty string", name, provider)
+			return
+		}
+		if name == "stage" {
+			pathMap.Stage = node.Value
+		} else {
+			pathMap.Version = node.Value
+		}
+		at = node
+	}
+	if at == nil {
+		return
+	}
+	if err := providers.CheckVersion(kindName, pathMap); err != nil {
+		v.addAt(SeverityError, at, "invalid version of %s: %v", provider, err)
+	}
+}
+
+// discoveryFilter checks the include and exclude globs of a map
+func (v *validator) discoveryFilter(provider string, fields map[string]*yaml.Node) {
+	for _, name := range []string{"include", "exclude"} {
+		node, ok := fields[name]
+		if !ok {
+			continue
+		}
+		if _, hasKeys := fields["keys"]; hasKeys {
+			v.addAt(SeverityWarning, node, "%s of %s is ignored because the map has keys; it only filters discovery", name, provider)
+		}
+		if node.Kind != yaml.SequenceNode {
+			v.addAt(SeverityError, node, "%s of %s must be a list of globs", name, provider)
+			continue
+		}
+		for _, pattern := range node.Content {
+			if pattern.Kind != yaml.ScalarNode {
+				v.addAt(SeverityError, pattern, "%s of %s must be a list of globs", name, provider)
+				continue
+			}
+			if _, err := path.Match(pattern.Value, ""); err != nil {
+				v.addAt(SeverityError, pattern, "invalid glob %q in %s of %s", pattern.Value, name, provider)
+			}
+		}
+	}
+}
+
+// tags checks the tags and key_tags of a map; key_tags must name keys of the map
+func (v *validator) tags(provider string, fields map[string]*yaml.Node) {
+	if node, ok := fields["tags"]; ok {
+		v.tagList(node, "tags of "+provider)
+	}
+	node, ok := fields["key_tags"]
+	if !ok {
+		return
+	}
+	if node.Kind != yaml.MappingNode {
+		v.addAt(SeverityError, node, "key_tags of %s must be a mapping of source keys to tags", provider)
+		return
+	}
+	keys := make(map[string]bool)
+	if keysNode, ok := fields["keys"]; ok && keysNode.Kind == yaml.MappingNode {
+		for i := 0; i < len(keysNode.Content); i += 2 {
+			keys[keysNode.Content[i].Value] = true
+		}
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := node.Content[i]
+		if !keys[key.Value] {
+			v.addAt(SeverityWarning, key, "key_tags of %s names %q, which is not a key of the map", provider, key.Value)
+		}
+		v.tagList(node.Content[i+1], fmt.Sprintf("key_tags of %s", provider))
+	}
+}
+
+// tagList checks that node is a list of non-empty tags without commas
+func (v *validator) tagList(node *yaml.Node, what string) {
+	if node.Kind != yaml.SequenceNode {
+		v.addAt(SeverityError, node, "%s must be a list of tags", what)
+		return
+	}
+	for _, tag := range node.Content {
+		if tag.Kind != yaml.ScalarNode || tag.Value == "" || strings.Contains(tag.Value, ",") {
+			v.addAt(SeverityError, tag, "%s must be a list of non-empty tags without commas", what)
+		}
+	}
+}
+
+func (v *validator) hooks(node *yaml.Node) {
+	keys, values := v.mapping(node, "hooks", config.HookFields)
+	for i, key := range keys {
+		if values[i].Kind != yaml.SequenceNode {
+			v.addAt(SeverityError, values[i], "hooks.%s must be a list of commands", key.Value)
+			continue
+		}
+		for _, hook := range values[i].Content {
+			if hook.Kind != yaml.ScalarNode || hook.Value == "" {
+				v.addAt(SeverityError, hook, "hooks.%s entries must be non-empty commands", key.Value)
+			}
+		}
+	}
+}
+
+func (v *validator) transforms(node *yaml.Node) {
+	keys, values := v.mapping(node, "transforms", nil)
+	for i, key := range keys {
+		if values[i].Kind != yaml.SequenceNode {
+			v.addAt(SeverityError, values[i], "transforms of %s must be a list of steps", key.Value)
+			continue
+		}
+		for _, stepNode := range values[i].Content {
+			var step config.Transform
+			if err := stepNode.Decode(&step); err != nil {
+				v.addAt(SeverityError, stepNode, "invalid transform for %s: %s", key.Value, stripLinePrefix(err.Error()))
+				continue
+			}
+			if _, err := providers.BuildTransform(step, key.Value, nil); err != nil {
+				v.addAt(SeverityError, stepNode, "invalid transform for %s: %v", key.Value, err)
+			}
+		}
+	}
+}
+
+func (v *validator) schema(node *yaml.Node) {
+	keys, values := v.mapping(node, "schema", nil)
+	types := providers.SchemaTypes()
+	for i, key := range keys {
+		if !contains(types, values[i].Value) {
+			v.addAt(SeverityError, values[i], "unknown schema type %q for %s (supported: %s)", values[i].Value, key.Value, strings.Join(types, ", "))
+		}
+	}
+}
+
+func (v *validator) aliases(node *yaml.Node) {
+	keys, values := v.mapping(node, "aliases", nil)
+	for i, key := range keys {
+		if values[i].Kind != yaml.ScalarNode {
+			v.addAt(SeverityError, values[i], "alias %s must be a string of feller arguments", key.Value)
+			continue
+		}
+		args, err := config.SplitArgs(values[i].Value)
+		switch {
+		case err != nil:
+			v.addAt(SeverityError, values[i], "invalid alias %s: %v", key.Value, err)
+		case len(args) == 0:
+			v.addAt(SeverityError, values[i], "alias %s has no arguments", key.Value)
+		}
+	}
+}
+
+func (v *validator) targets(node *yaml.Node, providerNames []string) {
+	names, values := v.mapping(node, "targets", nil)
+	for i, name := range names {
+		what := fmt.Sprintf("target %q", name.Value)
+		keys, fields := v.mapping(values[i], what, config.TargetFields)
+		var kind, repo, project, account, script, app *yaml.Node
+		kindFields := make(map[string]*yaml.Node)
+		for j, key := range keys {
+			value := fields[j]
+			switch key.Value {
+			case "kind":
+				kind = value
+			case "repo":
+				repo = value
+				kindFields[key.Value] = key
+			case "project":
+				project = value
+				kindFields[key.Value] = key
+			case "team":
+				kindFields[key.Value] = key
+			case "account":
+				account = value
+				kindFields[key.Value] = key
+			case "script":
+				script = value
+				kindFields[key.Value] = key
+			case "app":
+				app = value
+				kindFields[key.Value] = key
+			case "dependabot":
+				kindFields[key.Value] = key
+				if value.ShortTag() != "!!bool" {
+					v.addAt(SeverityError, value, "dependabot of %s must be true or false", what)
+				}
+			case "environments":
+				kindFields[key.Value] = key
+				if value.Kind != yaml.SequenceNode {
+					v.addAt(SeverityError, value, "environments of %s must be a list", what)
+					continue
+				}
+				for _, item := range value.Content {
+					if !contains(config.VercelEnvironments, item.Value) {
+						v.addAt(SeverityError, item, "unknown Vercel environment %q (supported: %s)", item.Value, strings.Join(config.VercelEnvironments, ", "))
+					}
+				}
+			case "providers", "keys":
+				if value.Kind != yaml.SequenceNode {
+					v.addAt(SeverityError, value, "%s of %s must be a list", key.Value, what)
+					continue
+				}
+				for _, item := range value.Content {
+					switch {
+					case item.Kind != yaml.ScalarNode || item.Value == "":
+						v.addAt(SeverityError, item, "%s of %s must be non-empty names", key.Value, what)
+					case key.Value == "providers" && providerNames != nil && !contains(providerNames, item.Value):
+						v.addAt(SeverityError, item, "%s uses unknown provider %q", what, item.Value)
+					}
+				}
+			}
+		}
+		if keys == nil {
+			continue
+		}
+
+		switch {
+		case kind == nil:
+			v.addAt(SeverityError, name, "%s is missing required field \"kind\"", what)
+		case !contains(config.TargetKinds, kind.Value):
+			v.addAt(SeverityError, kind, "unknown target kind %q (supported: %s)", kind.Value, strings.Join(config.TargetKinds, ", "))
+		case kind.Value == config.TargetKindVercel:
+			v.kindFields(kindFields, what, kind.Value, "project", "team", "environments")
+			if project == nil || project.Value == "" {
+				v.addAt(SeverityError, name, "%s is missing required field \"project\"", what)
+			}
+		case kind.Value == config.TargetKindCloudflare:
+			v.kindFields(kindFields, what, kind.Value, "account", "script")
+			if account == nil || account.Value == "" {
+				v.addAt(SeverityError, name, "%s is missing required field \"account\"", what)
+			}
+			if script == nil || script.Value == "" {
+				v.addAt(SeverityError, name, "%s is missing required field \"script\"", what)
+			}
+		case kind.Value == config.TargetKindFly:
+			v.kindFields(kindFields, what, kind.Value, "app")
+			if app == nil || app.Value == "" {
+				v.addAt(SeverityError, name, "%s is missing required field \"app\"", what)
+			}
+		case repo == nil:
+			v.kindFields(kindFields, what, kind.Value, "repo", "dependabot")
+			v.addAt(SeverityError, name, "%s is missing required field \"repo\"", what)
+		default:
+			v.kindFields(kindFields, what, kind.Value, "repo", "dependabot")
+			if owner, repoName, ok := strings.Cut(repo.Value, "/"); !ok || owner == "" || repoName == "" || strings.Contains(repoName, "/") {
+				v.addAt(SeverityError, repo, "repo of %s must be a GitHub repository (owner/repo)", what)
+			}
+		}
+	}
+}
+
+// kindFields reports the fields of a target that only apply to other kinds than kind, which
+// uses the fields in allowed
+func (v *validator) kindFields(fields map[string]*yaml.Node, what, kind string, allowed ...string) {
+	for _, field := range []string{"repo", "dependabot", "project", "team", "environments", "account", "script", "app"} {
+		if key, ok := fields[field]; ok && !contains(allowed, field) {
+			v.addAt(SeverityError, key, "%s of %s does not apply to %s targets", field, what, kind)
+		}
+	}
+}
+
+// sync checks that the keys of the sync section name configured targets, and that no two
+// keys are written to one target under the same name
+func (v *validator) sync(node *yaml.Node, targetNames []string) {
+	keys, values := v.mapping(node, "sync", nil)
+	written := make(map[[2]string]string)
+	for i, key := range keys {
+		what := fmt.Sprintf("sync of %s", key.Value)
+		targets, names := v.mapping(values[i], what, nil)
+		for j, target := range targets {
+			name := names[j]
+			switch {
+			case targetNames != nil && !contains(targetNames, target.Value):
+				v.addAt(SeverityError, target, "%s uses unknown target %q", what, target.Value)
+			case name.Kind != yaml.ScalarNode:
+				v.addAt(SeverityError, name, "%s must map targets to the name of the key there", what)
+			default:
+				remote := name.Value
+				if remote == "" {
+					remote = key.Value
+				}
+				slot := [2]string{target.Value, remote}
+				if other, ok := written[slot]; ok {
+					v.addAt(SeverityError, name, "%s writes %s to target %q, which %s is written to already", what, remote, target.Value, other)
+					continue
+				}
+				written[slot] = key.Value
+			}
+		}
+	}
+}
+
+func (v *validator) backups(node *yaml.Node) {
+	keys, values := v.mapping(node, "backups", config.BackupFields)
+	for i, key := range keys {
+		value := values[i]
+		switch key.Value {
+		case "recipients":
+			if value.Kind != yaml.SequenceNode {
+				v.addAt(SeverityError, value, "recipients of backups must be a list of age recipients")
+				continue
+			}
+			for _, item := range value.Content {
+				if item.Kind != yaml.ScalarNode || (!strings.HasPrefix(item.Value, "age1") && !strings.HasPrefix(item.Value, "ssh-")) {
+					v.addAt(SeverityError, item, "backup recipients must be age (age1...) or SSH public keys")
+				}
+			}
+		case "identity":
+			if value.Kind != yaml.ScalarNode || value.Value == "" {
+				v.addAt(SeverityError, value, "identity of backups must be the path of an age identity file")
+			}
+		case "retention":
+			if n, err := strconv.Atoi(value.Value); value.ShortTag() != "!!int" || err != nil || n < 1 {
+				v.addAt(SeverityError, value, "retention of backups must be a positive number of backups")
+			}
+		}
+	}
+}
+
+// providerNames returns the names of the providers of the config root, or nil when it has
+// no providers mapping
+func providerNames(root *yaml.Node) []string {
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value != "providers" || root.Content[i+1].Kind != yaml.MappingNode {
+			continue
+		}
+		names := []string{}
+		for j := 0; j < len(root.Content[i+1].Content); j += 2 {
+			names = append(names, root.Content[i+1].Content[j].Value)
+		}
+		return names
+	}
+	return nil
+}
+
+// hasEndpoint reports whether provider options set the endpoint option
+func hasEndpoint(options *yaml.Node) bool {
+	for i := 0; i+1 < len(options.Content); i += 2 {
+		if options.Content[i].Value == "endpoint" && options.Content[i+1].Value != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// targetNames returns the names of the targets of the config, nil when its targets section
+// is not a mapping
+func targetNames(root *yaml.Node) []string {
+	names := []string{}
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value != "targets" {
+			continue
+		}
+		if root.Content[i+1].Kind != yaml.MappingNode {
+			return nil
+		}
+		for j := 0; j < len(root.Content[i+1].Content); j += 2 {
+			names = append(names, root.Content[i+1].Content[j].Value)
+		}
+	}
+	return names
+}
+
+func (v *validator) messages(node *yaml.Node) {
+	keys, values := v.mapping(node, "messages", config.MessageFields)
+	fields := make(map[string]*yaml.Node)
+	for i, key := range keys {
+		fields[key.Value] = values[i]
+		if !contains(config.MessageFields, key.Value) {
+			continue
+		}
+		if values[i].Kind != yaml.ScalarNode {
+			v.addAt(SeverityError, values[i], "messages.%s must be a string", key.Value)
+			delete(fields, key.Value)
+		}
+	}
+
+	if text, ok := fields["missing_variables"]; ok {
+		if _, err := providers.ParseMissingTemplate(text.Value); err != nil {
+			v.addAt(SeverityError, text, "invalid messages.missing_variables: %v", errors.Unwrap(err))
+		}
+		if file, ok := fields["missing_variables_file"]; ok {
+			v.addAt(SeverityWarning, file, "messages.missing_variables_file is ignored since messages.missing_variables is set")
+		}
+		return
+	}
+	if file, ok := fields["missing_variables_file"]; ok && file.Value != "" {
+		// #nosec G304 - Template path comes from the config being validated
+		data, err := os.ReadFile(config.LocalPath(file.Value))
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			v.addAt(SeverityWarning, file, "template file %s does not exist", file.Value)
+		case err != nil:
+			v.addAt(SeverityWarning, file, "failed to read template file %s: %v", file.Value, err)
+		default:
+			if _, err := providers.ParseMissingTemplate(string(data)); err != nil {
+				v.addAt(SeverityError, file, "invalid template in %s: %v", file.Value, errors.Unwrap(err))
+			}
+		}
+	}
+}
+
+func kindNames() []string {
+	kinds := providers.Kinds()
+	names := make([]string, len(kinds))
+	for i, kind := range kinds {
+		names[i] = kind.Kind
+	}
+	return names
+}
+
+// stripLinePrefix removes the "line N: " prefix of config decode errors, which diagnostics carry separately
+func stripLinePrefix(msg string) string {
+	if loc := yamlErrorLine.FindStringIndex(msg); loc != nil && loc[0] == 0 {
+		return strings.TrimSpace(msg[loc[1]:])
+	}
+	return msg
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
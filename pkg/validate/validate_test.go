@@ -0,0 +1,530 @@
+package validate
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestConfig(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		data     string
+		expected []string
+	}{
+		{
+			name: "valid config",
+			data: `providers:
+  gha:
+    kind: google_secretmanager
+    maps:
+      - id: ci
+        keys:
+          TOKEN: TOKEN
+hooks:
+  pre_run:
+    - echo start
+transforms:
+  TOKEN:
+    - trim
+schema:
+  TOKEN: string
+`,
+			expected: nil,
+		},
+		{
+			name:     "syntax error",
+			data:     "providers: [\n",
+			expected: []string{"1:1: error: did not find expected node content"},
+		},
+		{
+			name:     "empty config",
+			data:     "",
+			expected: []string{"1:1: warning: config is empty"},
+		},
+		{
+			name: "vault options",
+			data: `providers:
+  vault:
+    kind: hashicorp_vault
+    options:
+      auth: {method: kubernetes}
+    maps:
+      - id: app
+        path: secret/data/app
+        version: "3"
+`,
+			expected: []string{
+				`5:7: error: invalid options of provider "vault": auth method kubernetes needs a role`,
+			},
+		},
+		{
+			name: "collision strategy",
+			data: `collisions: newest
+providers:
+  actions:
+    kind: github
+    maps:
+      - id: ci
+`,
+			expected: []string{
+				`1:13: error: collisions must be one of: error, first-wins, last-wins, prefix-provider`,
+			},
+		},
+		{
+			name: "github options",
+			data: `providers:
+  actions:
+    kind: github
+    options:
+      strip_prefix: true
+    maps:
+      - id: ci
+`,
+			expected: []string{
+				`5:7: error: invalid options of provider "actions": strip_prefix needs a prefix`,
+			},
+		},
+		{
+			name: "endpoint option",
+			data: `providers:
+  gsm:
+    kind: google_secretmanager
+    options:
+      endpoint: localhost:8085
+    maps:
+      - id: app
+        path: projects/demo
+        keys: {API_KEY: API_KEY}
+`,
+			expected: []string{
+				`5:7: error: invalid options of provider "gsm": endpoint "localhost:8085" must be an http or https URL, e.g. http://localhost:8085`,
+			},
+		},
+		{
+			name: "gsm endpoint with teller",
+			data: `providers:
+  gsm:
+    kind: google_secretmanager
+    options:
+      endpoint: http://localhost:8085
+    maps:
+      - id: app
+        path: projects/demo
+        keys: {API_KEY: API_KEY}
+`,
+			expected: []string{
+				`5:7: error: invalid options of provider "gsm": teller cannot reach google_secretmanager providers through an endpoint, set fallback: false to resolve them with feller only`,
+			},
+		},
+		{
+			name: "gsm endpoint without teller",
+			data: `providers:
+  gsm:
+    kind: google_secretmanager
+    options:
+      endpoint: http://localhost:8085
+    maps:
+      - id: app
+        path: projects/demo
+        keys: {API_KEY: API_KEY}
+fallback: false
+`,
+		},
+		{
+			name: "provider problems",
+			data: `providers:
+  gha:
+    kind: google_secretmanager
+    maps:
+      - id: ci
+        path: x
+  aws:
+    kind: aws_secretsmanager
+  nokind:
+    maps: []
+`,
+			expected: []string{
+				`5:9: error: map of provider "gha" is missing field "keys" required by kind google_secretmanager`,
+				`7:3: warning: provider "aws" has no maps and supplies no secrets`,
+				`8:11: warning: kind "aws_secretsmanager" of provider "aws" is not supported natively`,
+				`9:3: error: provider "nokind" is missing required field "kind"`,
+			},
+		},
+		{
+			name: "map problems",
+			data: `providers:
+  local:
+    kind: dotenv
+    maps:
+      - id: a
+        keys: [A]
+      - id: a
+        path: /nonexistent/.env
+        extra: true
+`,
+			expected: []string{
+				`5:9: error: map of provider "local" is missing field "path" required by kind dotenv`,
+				`6:15: error: keys of provider "local" must be a mapping`,
+				`7:13: warning: duplicate map id "a" in provider "local"`,
+				`8:15: warning: dotenv file /nonexistent/.env does not exist`,
+				`9:9: warning: unknown field "extra" in map of provider "local"`,
+			},
+		},
+		{
+			name: "discovery globs",
+			data: `providers:
+  gha:
+    kind: google_secretmanager
+    maps:
+      - id: app
+        include: ["APP_*"]
+        exclude: ["APP_[", 1]
+      - id: keyed
+        keys: {A: A}
+        exclude: "*"
+      - id: listed
+        path: projects/demo
+      - id: unlisted
+        path: projects/demo/secrets
+`,
+			expected: []string{
+				`7:19: error: invalid glob "APP_[" in exclude of provider "gha"`,
+				`10:18: warning: exclude of provider "gha" is ignored because the map has keys; it only filters discovery`,
+				`10:18: error: exclude of provider "gha" must be a list of globs`,
+				`13:9: error: map of provider "gha" is missing field "keys" required by kind google_secretmanager`,
+			},
+		},
+		{
+			name: "tags",
+			data: `providers:
+  gha:
+    kind: google_secretmanager
+    maps:
+      - id: app
+        tags: [backend, "a,b"]
+        keys: {A: A}
+        key_tags:
+          A: [e2e]
+          B: backend
+      - id: other
+        tags: backend
+        keys: {C: C}
+`,
+			expected: []string{
+				`6:25: error: tags of provider "gha" must be a list of non-empty tags without commas`,
+				`10:11: warning: key_tags of provider "gha" names "B", which is not a key of the map`,
+				`10:14: error: key_tags of provider "gha" must be a list of tags`,
+				`12:15: error: tags of provider "gha" must be a list of tags`,
+			},
+		},
+		{
+			name: "versions",
+			data: `providers:
+  gha:
+    kind: google_secretmanager
+    maps:
+      - id: pinned
+        version: 3
+        keys: {A: A}
+      - id: staged
+        stage: AWSCURRENT
+        keys: {B: B}
+  local:
+    kind: dotenv
+    maps:
+      - id: env
+        keys: {C: C}
+        version: [2]
+`,
+			expected: []string{
+				`9:16: error: invalid version of provider "gha": stage is not supported by kind google_secretmanager; use version with a number or alias`,
+				`14:9: error: map of provider "local" is missing field "path" required by kind dotenv`,
+				`16:18: error: version of provider "local" must be a non-empty string`,
+			},
+		},
+		{
+			name: "hooks transforms and schema",
+			data: `providers: {}
+hooks:
+  pre_run: echo
+  on_fail: [echo]
+transforms:
+  A:
+    - rot13
+    - upper: x
+  B: trim
+schema:
+  A: port
+unknown: 1
+`,
+			expected: []string{
+				`3:12: error: hooks.pre_run must be a list of commands`,
+				`4:3: warning: unknown field "on_fail" in hooks`,
+				`7:7: error: invalid transform for A: unknown transform type: "rot13"`,
+				`8:7: error: invalid transform for A: transform "upper" does not take arguments`,
+				`9:6: error: transforms of B must be a list of steps`,
+				`11:6: error: unknown schema type "port" for A`,
+				`12:1: warning: unknown field "unknown" in config`,
+			},
+		},
+		{
+			name: "aliases",
+			data: `providers: {}
+aliases:
+  deploy: run -- ./deploy.sh "prod env"
+  broken: run -- 'echo
+  empty: ""
+  nested: [run]
+`,
+			expected: []string{
+				`4:11: error: invalid alias broken: unterminated ' quote`,
+				`5:10: error: alias empty has no arguments`,
+				`6:11: error: alias nested must be a string of feller arguments`,
+			},
+		},
+		{
+			name: "messages",
+			data: `providers: {}
+messages:
+  missing_variables: "{{ range .Variables }}{{ .VariableName }}"
+  footer: see the wiki
+`,
+			expected: []string{
+				`3:22: error: invalid messages.missing_variables: template: missing:1: unexpected EOF`,
+				`4:3: warning: unknown field "footer" in messages (expected one of: missing_variables, missing_variables_file)`,
+			},
+		},
+		{
+			name: "message template file",
+			data: `providers: {}
+messages:
+  missing_variables_file: does-not-exist.tmpl
+`,
+			expected: []string{
+				`3:27: warning: template file does-not-exist.tmpl does not exist`,
+			},
+		},
+		{
+			name: "inline template and file",
+			data: `providers: {}
+messages:
+  missing_variables: "{{ .Count }}"
+  missing_variables_file: [a]
+`,
+			expected: []string{
+				`4:27: error: messages.missing_variables_file must be a string`,
+			},
+		},
+		{
+			name: "fallback",
+			data: `providers: {}
+fallback: "no"
+`,
+			expected: []string{
+				`2:11: error: fallback must be true or false`,
+			},
+		},
+		{
+			name: "targets",
+			data: `providers:
+  env: {kind: dotenv, maps: [{id: a, path: p}]}
+targets:
+  ci:
+    kind: github
+    repo: owner/repo
+    providers: [env, nope]
+    keys: A
+    dependabot: "yes"
+  edge:
+    kind: cloudflare
+    script: worker
+  bad:
+    kind: github
+    repo: owner
+  norepo:
+    kind: github
+  web:
+    kind: vercel
+    repo: owner/repo
+    environments: [production, staging]
+  api:
+    kind: fly
+    project: web
+  other:
+    kind: netlify
+`,
+			expected: []string{
+				`2:44: warning: dotenv file p does not exist`,
+				`7:22: error: target "ci" uses unknown provider "nope"`,
+				`8:11: error: keys of target "ci" must be a list`,
+				`9:17: error: dependabot of target "ci" must be true or false`,
+				`10:3: error: target "edge" is missing required field "account"`,
+				`15:11: error: repo of target "bad" must be a GitHub repository (owner/repo)`,
+				`16:3: error: target "norepo" is missing required field "repo"`,
+				`18:3: error: target "web" is missing required field "project"`,
+				`20:5: error: repo of target "web" does not apply to vercel targets`,
+				`21:32: error: unknown Vercel environment "staging" (supported: production, preview, development)`,
+				`22:3: error: target "api" is missing required field "app"`,
+				`24:5: error: project of target "api" does not apply to fly targets`,
+				`26:11: error: unknown target kind "netlify" (supported: github, vercel, cloudflare, fly)`,
+			},
+		},
+		{
+			name: "sync",
+			data: `providers: {}
+targets:
+  ci: {kind: github, repo: owner/repo}
+  web: {kind: vercel, project: web}
+sync:
+  API_KEY:
+    ci: ""
+    web: NEXT_PUBLIC_API_KEY
+    edge: API_KEY
+  OTHER_KEY:
+    web: NEXT_PUBLIC_API_KEY
+  BAD: [ci]
+`,
+			expected: []string{
+				`9:5: error: sync of API_KEY uses unknown target "edge"`,
+				`11:10: error: sync of OTHER_KEY writes NEXT_PUBLIC_API_KEY to target "web", which API_KEY is written to already`,
+				`12:8: error: sync of BAD must be a mapping`,
+			},
+		},
+		{
+			name: "backups",
+			data: `providers: {}
+backups:
+  recipients: [age1abc, key]
+  identity: ""
+  retention: 0
+  keep: 3
+`,
+			expected: []string{
+				`3:25: error: backup recipients must be age (age1...) or SSH public keys`,
+				`4:13: error: identity of backups must be the path of an age identity file`,
+				`5:14: error: retention of backups must be a positive number of backups`,
+				`6:3: warning: unknown field "keep" in backups (expected one of: recipients, identity, retention)`,
+			},
+		},
+		{
+			name: "deprecated fields",
+			data: `project: demo
+providers:
+  local:
+    kind: dotenv
+    env_sync:
+      path: /nonexistent
+`,
+			expected: []string{
+				`1:1: warning: project is deprecated and removed in config version 2: remove it`,
+				`3:3: warning: provider "local" has no maps`,
+				`5:5: warning: providers.local.env_sync is deprecated and removed in config version 2: use a map without keys`,
+			},
+		},
+		{
+			name: "removed fields",
+			data: `version: 2
+opts:
+  stage: dev
+providers: {}
+`,
+			expected: []string{
+				`2:1: error: opts was removed in config version 2: move the values into the options of each provider`,
+			},
+		},
+		{
+			name: "unsupported version",
+			data: `version: 3
+providers: {}
+`,
+			expected: []string{
+				`1:10: error: config version 3 is not supported by this feller (supported: 1 to 2), upgrade feller`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			diagnostics := Config([]byte(tt.data))
+
+			if len(diagnostics) != len(tt.expected) {
+				t.Fatalf("Config() returned %d diagnostics, want %d: %v", len(diagnostics), len(tt.expected), diagnostics)
+			}
+			for i, want := range tt.expected {
+				if got := diagnostics[i].String(); !strings.HasPrefix(got, want) {
+					t.Errorf("Config() diagnostic %d = %q, want prefix %q", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestHasErrors(t *testing.T) {
+	t.Parallel()
+	if HasErrors([]Diagnostic{{Severity: SeverityWarning}}) {
+		t.Errorf("HasErrors() = true for warnings only")
+	}
+	if !HasErrors([]Diagnostic{{Severity: SeverityWarning}, {Severity: SeverityError}}) {
+		t.Errorf("HasErrors() = false with an error diagnostic")
+	}
+}
+
+func TestFile(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), ".teller.yml")
+	if err := os.WriteFile(path, []byte("providers:\n  x:\n    kind: dotenv\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	diagnostics, err := File(path)
+	if err != nil {
+		t.Fatalf("File() unexpected error = %v", err)
+	}
+	if len(diagnostics) != 1 || !strings.Contains(diagnostics[0].Message, "has no maps") {
+		t.Errorf("File() = %v, want a single no maps warning", diagnostics)
+	}
+
+	if _, err := File(filepath.Join(t.TempDir(), "missing.yml")); err == nil {
+		t.Errorf("File() expected error for missing file")
+	}
+}
+
+func TestDotenvPermissions(t *testing.T) {
+	t.Parallel()
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows has no group and other permission bits")
+	}
+	env := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(env, []byte("A=1\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(env, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	data := []byte("providers:\n  local:\n    kind: dotenv\n    maps:\n      - id: a\n        path: " + env + "\n")
+	message := "6:15: %s: dotenv file " + env + " is readable by group or others (mode 0644), restrict it with chmod 600 " + env
+
+	tests := []struct {
+		name     string
+		opts     Options
+		expected string
+	}{
+		{name: "warning", expected: fmt.Sprintf(message, SeverityWarning)},
+		{name: "strict", opts: Options{StrictPermissions: true}, expected: fmt.Sprintf(message, SeverityError)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			diagnostics := ConfigWithOptions(data, tt.opts)
+			if len(diagnostics) != 1 || diagnostics[0].String() != tt.expected {
+				t.Errorf("ConfigWithOptions() = %v, expected %s", diagnostics, tt.expected)
+			}
+		})
+	}
+}
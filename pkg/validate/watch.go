@@ -0,0 +1,124 @@
+package validate
+
+import (
+	"context"
+	"crypto/sha256"
+	"maps"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/containifyci/feller/pkg/config"
+	"github.com/containifyci/feller/pkg/providers"
+	"gopkg.in/yaml.v3"
+)
+
+// Result is the outcome of validating a config file once
+type Result struct {
+	Err         error
+	Diagnostics []Diagnostic
+}
+
+// fileState identifies a version of a watched file by its content and permissions, since
+// modification times are too coarse on some file systems to tell quick successive saves apart
+type fileState struct {
+	sum    [sha256.Size]byte
+	mode   os.FileMode
+	exists bool
+}
+
+// Watch validates the config at path with opts and again whenever it or one of the dotenv
+// files it references changes, calling report with every result. Files are polled at
+// interval so it works the same on every platform. Watch returns when ctx is done.
+func Watch(ctx context.Context, path string, interval time.Duration, opts Options, report func(Result)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var states map[string]fileState
+	for {
+		if current := snapshot(path); states == nil || !maps.Equal(states, current) {
+			states = current
+			diagnostics, err := FileWithOptions(path, opts)
+			report(Result{Diagnostics: diagnostics, Err: err})
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Diff returns the diagnostics of after that are not in before and those of before that
+// are no longer in after
+func Diff(before, after []Diagnostic) (added, resolved []Diagnostic) {
+	return subtract(after, before), subtract(before, after)
+}
+
+func subtract(a, b []Diagnostic) []Diagnostic {
+	seen := make(map[Diagnostic]bool, len(b))
+	for _, d := range b {
+		seen[d] = true
+	}
+	var result []Diagnostic
+	for _, d := range a {
+		if !seen[d] {
+			result = append(result, d)
+		}
+	}
+	return result
+}
+
+// DotenvPaths returns the files referenced by dotenv providers of a config, sorted. Content
+// that does not parse yields no paths.
+func DotenvPaths(data []byte) []string {
+	var cfg config.TellerConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	for _, provider := range cfg.Providers {
+		if provider.Kind != providers.KindDotenv {
+			continue
+		}
+		for _, pathMap := range provider.Maps {
+			if pathMap.Path != "" {
+				seen[config.LocalPath(pathMap.Path)] = true
+			}
+		}
+	}
+
+	paths := make([]string, 0, len(seen))
+	for p := range seen {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// snapshot records the state of the config and every dotenv file it references
+func snapshot(path string) map[string]fileState {
+	states := map[string]fileState{path: stat(path)}
+	// #nosec G304 - Config path is provided by the user
+	if data, err := os.ReadFile(path); err == nil {
+		for _, p := range DotenvPaths(data) {
+			states[p] = stat(p)
+		}
+	}
+	return states
+}
+
+func stat(path string) fileState {
+	// #nosec G304 - Dotenv paths come from the user's config
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fileState{}
+	}
+	state := fileState{sum: sha256.Sum256(data), exists: true}
+	if info, err := os.Stat(path); err == nil {
+		state.mode = info.Mode().Perm()
+	}
+	return state
+}
@@ -0,0 +1,146 @@
+package validate
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDiff(t *testing.T) {
+	t.Parallel()
+	unknown := Diagnostic{Severity: SeverityWarning, Message: "unknown field", Line: 1, Column: 1}
+	missing := Diagnostic{Severity: SeverityError, Message: "missing kind", Line: 2, Column: 3}
+	moved := Diagnostic{Severity: SeverityError, Message: "missing kind", Line: 3, Column: 3}
+
+	tests := []struct {
+		name             string
+		before           []Diagnostic
+		after            []Diagnostic
+		expectedAdded    []Diagnostic
+		expectedResolved []Diagnostic
+	}{
+		{name: "unchanged", before: []Diagnostic{unknown}, after: []Diagnostic{unknown}},
+		{name: "new finding", before: []Diagnostic{unknown}, after: []Diagnostic{unknown, missing}, expectedAdded: []Diagnostic{missing}},
+		{name: "fixed finding", before: []Diagnostic{unknown, missing}, after: []Diagnostic{unknown}, expectedResolved: []Diagnostic{missing}},
+		{name: "moved finding", before: []Diagnostic{missing}, after: []Diagnostic{moved}, expectedAdded: []Diagnostic{moved}, expectedResolved: []Diagnostic{missing}},
+		{name: "from nothing", after: []Diagnostic{missing}, expectedAdded: []Diagnostic{missing}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			added, resolved := Diff(tt.before, tt.after)
+			if !reflect.DeepEqual(added, tt.expectedAdded) {
+				t.Errorf("Diff() added = %v, want %v", added, tt.expectedAdded)
+			}
+			if !reflect.DeepEqual(resolved, tt.expectedResolved) {
+				t.Errorf("Diff() resolved = %v, want %v", resolved, tt.expectedResolved)
+			}
+		})
+	}
+}
+
+func TestDotenvPaths(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{
+			name: "dotenv maps",
+			input: `providers:
+  b:
+    kind: dotenv
+    maps:
+      - {id: one, path: b.env}
+      - {id: two, path: a.env}
+      - {id: three, path: b.env}
+  gsm:
+    kind: google_secretmanager
+    maps:
+      - {id: ci, path: ignored}
+`,
+			expected: []string{"a.env", "b.env"},
+		},
+		{name: "windows separators", input: "providers:\n  a:\n    kind: dotenv\n    maps: [{id: x, path: 'env\\dev.env'}]\n", expected: []string{filepath.Join("env", "dev.env")}},
+		{name: "no providers", input: "hooks: {}\n", expected: []string{}},
+		{name: "invalid yaml", input: "providers: [", expected: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if result := DotenvPaths([]byte(tt.input)); !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("DotenvPaths() = %#v, want %#v", result, tt.expected)
+			}
+		})
+	}
+}
+
+//nolint:paralleltest // changes the working directory so the relative dotenv path resolves
+func TestWatch(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	path := filepath.Join(dir, ".teller.yml")
+	content := "providers:\n  local:\n    kind: dotenv\n    maps:\n      - id: dev\n        path: dev.env\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	results := make(chan Result)
+	done := make(chan struct{})
+	go func() {
+		Watch(ctx, path, 5*time.Millisecond, Options{}, func(r Result) {
+			select {
+			case results <- r:
+			case <-ctx.Done():
+			}
+		})
+		close(done)
+	}()
+
+	next := func() Result {
+		t.Helper()
+		select {
+		case r := <-results:
+			return r
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for validation")
+			return Result{}
+		}
+	}
+
+	if r := next(); len(r.Diagnostics) != 1 || r.Diagnostics[0].Message != "dotenv file dev.env does not exist" {
+		t.Errorf("initial Watch() result = %+v, want missing dotenv warning", r)
+	}
+
+	// Creating the referenced dotenv file is a change even though the config is untouched
+	if err := os.WriteFile("dev.env", []byte("A=1\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write dotenv file: %v", err)
+	}
+	if r := next(); r.Err != nil || len(r.Diagnostics) != 0 {
+		t.Errorf("Watch() after creating dotenv file = %+v, want no diagnostics", r)
+	}
+
+	if err := os.WriteFile(path, []byte(content+"bogus: 1\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	if r := next(); len(r.Diagnostics) != 1 || r.Diagnostics[0].Line != 7 {
+		t.Errorf("Watch() after config edit = %+v, want unknown field warning on line 7", r)
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatalf("Failed to remove config: %v", err)
+	}
+	if r := next(); r.Err == nil {
+		t.Errorf("Watch() after removing config expected error but got none")
+	}
+
+	cancel()
+	<-done
+}
@@ -0,0 +1,159 @@
+// Package workspace manages the user-level registry of named teller configs, so commands
+// can switch between projects and environments by name instead of long --config paths.
+package workspace
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// PathEnv overrides the location of the registry file
+const PathEnv = "FELLER_WORKSPACES"
+
+// FileName is the name of the registry file in the user's config directory
+const FileName = "workspaces.yml"
+
+// Registry is the content of the registry file
+type Registry struct {
+	Current    string               `yaml:"current,omitempty"` // Workspace selected by 'feller workspace use'
+	Workspaces map[string]Workspace `yaml:"workspaces"`
+}
+
+// Workspace is a named config
+type Workspace struct {
+	Config string `yaml:"config"` // Absolute path of the teller config
+}
+
+// Dir returns the directory of the workspace's config, which relative paths in the config
+// are written against
+func (w Workspace) Dir() string {
+	return filepath.Dir(w.Config)
+}
+
+// DefaultPath returns the registry file: PathEnv when set, otherwise feller/workspaces.yml
+// in the user's config directory
+func DefaultPath() (string, error) {
+	if path := os.Getenv(PathEnv); path != "" {
+		return path, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate the user config directory: %w", err)
+	}
+	return filepath.Join(dir, "feller", FileName), nil
+}
+
+// Load reads the registry at path. A missing file is an empty registry.
+func Load(path string) (*Registry, error) {
+	r := &Registry{Workspaces: make(map[string]Workspace)}
+	// #nosec G304 - The registry path is the user's own config file
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read workspaces: %w", err)
+	}
+	if err := yaml.Unmarshal(data, r); err != nil {
+		return nil, fmt.Errorf("failed to parse workspaces %s: %w", path, err)
+	}
+	if r.Workspaces == nil {
+		r.Workspaces = make(map[string]Workspace)
+	}
+	return r, nil
+}
+
+// Write saves the registry at path, creating its directory if needed
+func (r *Registry) Write(path string) error {
+	data, err := yaml.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("failed to encode workspaces: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write workspaces: %w", err)
+	}
+	return nil
+}
+
+// CheckName validates a workspace name: letters, digits, '.', '-' and '_'
+func CheckName(name string) error {
+	if name == "" {
+		return errors.New("workspace name must not be empty")
+	}
+	if i := strings.IndexFunc(name, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune(".-_", r))
+	}); i >= 0 {
+		return fmt.Errorf("invalid workspace name %q: only letters, digits, '.', '-' and '_' are allowed", name)
+	}
+	return nil
+}
+
+// Add registers the config at configPath as the workspace name
+func (r *Registry) Add(name, configPath string) error {
+	if err := CheckName(name); err != nil {
+		return err
+	}
+	if existing, ok := r.Workspaces[name]; ok {
+		return fmt.Errorf("workspace %s already points to %s, remove it first", name, existing.Config)
+	}
+	abs, err := filepath.Abs(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", configPath, err)
+	}
+	r.Workspaces[name] = Workspace{Config: abs}
+	return nil
+}
+
+// Remove unregisters the workspace name, clearing the current workspace if it was
+func (r *Registry) Remove(name string) error {
+	if _, err := r.Lookup(name); err != nil {
+		return err
+	}
+	delete(r.Workspaces, name)
+	if r.Current == name {
+		r.Current = ""
+	}
+	return nil
+}
+
+// Use selects the workspace name as the current one. An empty name clears the selection.
+func (r *Registry) Use(name string) error {
+	if name != "" {
+		if _, err := r.Lookup(name); err != nil {
+			return err
+		}
+	}
+	r.Current = name
+	return nil
+}
+
+// Lookup returns the workspace name
+func (r *Registry) Lookup(name string) (Workspace, error) {
+	w, ok := r.Workspaces[name]
+	if !ok {
+		if names := r.Names(); len(names) > 0 {
+			return Workspace{}, fmt.Errorf("unknown workspace %q (registered: %s)", name, strings.Join(names, ", "))
+		}
+		return Workspace{}, fmt.Errorf("unknown workspace %q, register it with 'feller workspace add'", name)
+	}
+	return w, nil
+}
+
+// Names returns the registered workspace names sorted
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.Workspaces))
+	for name := range r.Workspaces {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
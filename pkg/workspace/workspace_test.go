@@ -0,0 +1,78 @@
+package workspace
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRegistry(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "feller", FileName)
+	r, err := Load(path)
+	if err != nil || len(r.Workspaces) != 0 {
+		t.Fatalf("Load(missing) = %+v, %v, expected an empty registry", r, err)
+	}
+
+	if err := r.Add("api", filepath.Join("projects", "api", ".teller.yml")); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if !filepath.IsAbs(r.Workspaces["api"].Config) {
+		t.Errorf("Add() stored %q, expected an absolute path", r.Workspaces["api"].Config)
+	}
+	if err := r.Add("api", "other.yml"); err == nil || !strings.Contains(err.Error(), "already points to") {
+		t.Errorf("Add(duplicate) error = %v", err)
+	}
+	if err := r.Add("billing", "/srv/billing/.teller.yml"); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if err := r.Use("billing"); err != nil {
+		t.Fatalf("Use() error = %v", err)
+	}
+	if err := r.Use("nope"); err == nil || !strings.Contains(err.Error(), `unknown workspace "nope" (registered: api, billing)`) {
+		t.Errorf("Use(unknown) error = %v", err)
+	}
+	if err := r.Write(path); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if loaded.Current != "billing" || len(loaded.Workspaces) != 2 || loaded.Workspaces["billing"].Dir() != filepath.Dir(r.Workspaces["billing"].Config) {
+		t.Errorf("Load() = %+v", loaded)
+	}
+	if err := loaded.Remove("billing"); err != nil || loaded.Current != "" {
+		t.Errorf("Remove(current) = %v, current %q, expected the selection cleared", err, loaded.Current)
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), FileName)
+	if err := os.WriteFile(path, []byte("workspaces: [\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "failed to parse workspaces") {
+		t.Errorf("Load() error = %v, expected a parse error", err)
+	}
+}
+
+func TestCheckName(t *testing.T) {
+	t.Parallel()
+	for name, valid := range map[string]bool{"api": true, "team.api-prod_2": true, "": false, "a b": false, "a/b": false} {
+		if err := CheckName(name); (err == nil) != valid {
+			t.Errorf("CheckName(%q) error = %v, expected valid %v", name, err, valid)
+		}
+	}
+}
+
+//nolint:paralleltest // Sets the environment
+func TestDefaultPath(t *testing.T) {
+	t.Setenv(PathEnv, "/tmp/ws.yml")
+	if path, err := DefaultPath(); err != nil || path != "/tmp/ws.yml" {
+		t.Errorf("DefaultPath() = %q, %v", path, err)
+	}
+}